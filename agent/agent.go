@@ -4,7 +4,9 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/mykhaliev/agent-benchmark/logger"
 	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/mykhaliev/agent-benchmark/tokenizer"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -33,8 +36,25 @@ type MCPAgent struct {
 	McpServers          []*server.MCPServer           `json:"-"`
 	Provider            string                        `json:"provider"`
 	LLMModel            llms.Model                    `json:"-"`
+	Model               string                        `json:"model,omitempty"` // Model override the LLM was created with, if any
 	AvailableTools      []string                      `json:"-"`
 	BuiltInToolHandlers map[string]BuiltInToolHandler `json:"-"` // Handlers for built-in tools (e.g., skill references)
+	// ToolCollisions lists, for every tool name exposed by more than one of the
+	// agent's servers, the servers exposing it. Those tools are registered
+	// under their prefixed names only.
+	ToolCollisions map[string][]string `json:"-"`
+	// ServerToolNames maps a prefixed tool name to the tool's name on its server
+	ServerToolNames map[string]string `json:"-"`
+}
+
+// ToolNameSeparator joins a server name and a tool name when a tool is prefixed
+// because several servers expose it. Dots are not used because OpenAI and
+// Anthropic only accept letters, digits, '_' and '-' in tool names.
+const ToolNameSeparator = "__"
+
+// PrefixedToolName returns the name a colliding tool of serverName is registered under.
+func PrefixedToolName(serverName, toolName string) string {
+	return serverName + ToolNameSeparator + toolName
 }
 
 // BuiltInToolHandler is a function that handles a built-in tool call.
@@ -55,10 +75,40 @@ type AgentConfig struct {
 	MaxIterations                 int
 	AddNotFinalResponses          bool
 	Verbose                       bool
-	ToolTimeout                   time.Duration
+	ToolTimeout                   time.Duration // Default per-tool-call timeout; zero means unlimited
+	TestToolTimeout               time.Duration // Per-test override; takes precedence over server and default timeouts
+	FailOnToolTimeout             bool          // Stop the agent loop after a tool call times out
 	ClarificationDetectionEnabled bool
 	ClarificationDetectionLevel   ClarificationLevel
 	ClarificationJudgeLLM         llms.Model // LLM used to classify if a response is asking for clarification
+	ToolChoice                    string     // Effective tool_choice mode recorded in the result; empty leaves the provider default
+	ToolChoiceOption              any        // Provider-specific llms.WithToolChoice value for ToolChoice; nil sends none
+	// Tokenizer estimates usage when the provider reports none; nil uses tokenizer.Default
+	Tokenizer tokenizer.Tokenizer
+	// Observer is called with each assistant message and finished tool call as
+	// the loop records them; it only observes and cannot change the result
+	Observer func(IterationEvent)
+}
+
+// IterationEvent is one step of the agent loop reported to AgentConfig.Observer.
+// Exactly one of Message and ToolCall is set.
+type IterationEvent struct {
+	Iteration int
+	Message   *model.Message
+	ToolCall  *model.ToolCall
+}
+
+// observe reports event to the configured Observer, if any
+func (c AgentConfig) observe(event IterationEvent) {
+	if c.Observer != nil {
+		c.Observer(event)
+	}
+}
+
+// log returns the logger with the agent's name attached, so the lines of
+// agents running concurrently stay attributable.
+func (m *MCPAgent) log() *slog.Logger {
+	return logger.Logger.With("agent", m.Name)
 }
 
 func NewMCPAgent(
@@ -78,7 +128,18 @@ func NewMCPAgent(
 		Provider:            provider,
 		LLMModel:            llmModel,
 		BuiltInToolHandlers: make(map[string]BuiltInToolHandler),
+		ToolCollisions:      make(map[string][]string),
+		ServerToolNames:     make(map[string]string),
+	}
+
+	// Allowed tools per server in configuration order, registered once all
+	// servers are listed so name collisions across servers are known
+	type serverTools struct {
+		name  string
+		tools []mcp.Tool
 	}
+	listed := make([]serverTools, 0, len(mcpServersForAgent))
+	toolServers := make(map[string][]string)
 
 	logger.Logger.Info("Creating agent",
 		"agent", name,
@@ -137,33 +198,46 @@ func NewMCPAgent(
 			logger.Logger.Warn("No allowed tools for server", "server", srv.Name)
 		}
 
-		if _, ok := ag.MCPServerTools[srv.Name]; !ok {
-			ag.MCPServerTools[srv.Name] = make([]mcp.Tool, 0)
+		listed = append(listed, serverTools{name: srv.Name, tools: allowedTools})
+		for _, tool := range allowedTools {
+			if !slices.Contains(toolServers[tool.Name], srv.Name) {
+				toolServers[tool.Name] = append(toolServers[tool.Name], srv.Name)
+			}
 		}
-		ag.MCPServerTools[srv.Name] = append(ag.MCPServerTools[srv.Name], allowedTools...)
+	}
 
-		// Populate the ToolToServer map
-		for _, tool := range allowedTools {
-			if existingServer, exists := ag.ToolToServer[tool.Name]; exists {
-				logger.Logger.Warn("Tool name collision detected",
-					"tool", tool.Name,
-					"existing_server", existingServer,
-					"new_server", srv.Name)
-				// You could handle this by prefixing in this case, or using a different strategy
-				// For now, we'll keep the first server that registered the tool
-			} else {
-				ag.ToolToServer[tool.Name] = srv.Name
+	for toolName, serverNames := range toolServers {
+		if len(serverNames) > 1 {
+			ag.ToolCollisions[toolName] = serverNames
+			logger.Logger.Warn("Tool name collision detected, prefixing the tool with its server name",
+				"agent", ag.Name,
+				"tool", toolName,
+				"servers", strings.Join(serverNames, ", "))
+		}
+	}
+
+	for _, st := range listed {
+		// Populate the ToolToServer map with the effective tool names
+		tools := make([]mcp.Tool, 0, len(st.tools))
+		for _, tool := range st.tools {
+			if _, collides := ag.ToolCollisions[tool.Name]; collides {
+				prefixed := PrefixedToolName(st.name, tool.Name)
+				ag.ServerToolNames[prefixed] = tool.Name
+				tool.Name = prefixed
 			}
+			tools = append(tools, tool)
+			ag.ToolToServer[tool.Name] = st.name
 			ag.AvailableTools = append(ag.AvailableTools, tool.Name)
 		}
+		ag.MCPServerTools[st.name] = append(ag.MCPServerTools[st.name], tools...)
 
-		allowedToolNames := slices.Map(allowedTools, func(tool mcp.Tool) string {
+		toolNames := slices.Map(tools, func(tool mcp.Tool) string {
 			return tool.Name
 		})
 		logger.Logger.Info("Agent tools configured",
 			"agent", ag.Name,
-			"server", srv.Name,
-			"tools", strings.Join(allowedToolNames, ", "))
+			"server", st.name,
+			"tools", strings.Join(toolNames, ", "))
 	}
 
 	logger.Logger.Info("Agent initialization complete",
@@ -231,6 +305,11 @@ func (m *MCPAgent) ExecuteTool(ctx context.Context, toolName, argumentsInJSON st
 	if arguments == nil || arguments == "{}" {
 		arguments = map[string]interface{}{}
 	}
+	// A prefixed tool is called by its name on the server
+	serverToolName := toolName
+	if name, ok := m.ServerToolNames[toolName]; ok {
+		serverToolName = name
+	}
 	result, err := toolServer.Client.CallTool(ctx, mcp.CallToolRequest{
 		Request: mcp.Request{
 			Method: "tools/call",
@@ -240,7 +319,7 @@ func (m *MCPAgent) ExecuteTool(ctx context.Context, toolName, argumentsInJSON st
 			Arguments any       `json:"arguments,omitempty"`
 			Meta      *mcp.Meta `json:"_meta,omitempty"`
 		}{
-			Name:      toolName,
+			Name:      serverToolName,
 			Arguments: arguments,
 		},
 	})
@@ -290,8 +369,7 @@ func (m *MCPAgent) GenerateContentWithConfig(
 	maxIterations := getMaxIterations(config.MaxIterations)
 
 	if config.Verbose {
-		logger.Logger.Info("Execution started",
-			"agent", m.Name,
+		m.log().Info("Execution started",
 			"provider", m.Provider,
 			"max_iterations", maxIterations,
 			"available_tools", countTotalTools(m.MCPServerTools))
@@ -308,10 +386,12 @@ func (m *MCPAgent) GenerateContentWithConfig(
 		}
 	}
 
+	result.ToolChoice = config.ToolChoice
+
 	recordUserMessages(msgs, &result, config.Verbose)
 
 	if config.Verbose {
-		logger.Logger.Debug("Tools extracted for LLM", "count", len(tools))
+		m.log().Debug("Tools extracted for LLM", "count", len(tools))
 	}
 
 	response := ""
@@ -321,7 +401,7 @@ func (m *MCPAgent) GenerateContentWithConfig(
 		iteration++
 
 		if config.Verbose {
-			logger.Logger.Debug("Starting LLM call",
+			m.log().Debug("Starting LLM call",
 				"iteration", iteration,
 				"max_iterations", maxIterations)
 		}
@@ -329,17 +409,18 @@ func (m *MCPAgent) GenerateContentWithConfig(
 		if ctx.Err() != nil {
 			errMsg := fmt.Sprintf("Context cancelled: %v", ctx.Err())
 			result.Errors = append(result.Errors, errMsg)
-			logger.Logger.Error("Context cancelled",
+			m.log().Error("Context cancelled",
 				"iteration", iteration,
 				"error", ctx.Err())
 			break
 		}
 
-		resp, err := m.LLMModel.GenerateContent(ctx, *msgs, llms.WithTools(tools))
+		promptLen := len(*msgs)
+		resp, err := m.LLMModel.GenerateContent(ctx, *msgs, toolCallOptions(tools, config, iteration)...)
 		if err != nil {
 			errMsg := fmt.Sprintf("LLM generation error (iteration %d): %v", iteration, err)
-			result.Errors = append(result.Errors, errMsg)
-			logger.Logger.Error("LLM generation failed",
+			recordGenerationError(&result, err, errMsg)
+			m.log().Error("LLM generation failed",
 				"iteration", iteration,
 				"error", err)
 			break
@@ -348,7 +429,7 @@ func (m *MCPAgent) GenerateContentWithConfig(
 		if len(resp.Choices) == 0 {
 			errMsg := fmt.Sprintf("LLM returned no choices (iteration %d)", iteration)
 			result.Errors = append(result.Errors, errMsg)
-			logger.Logger.Error("No choices returned from LLM", "iteration", iteration)
+			m.log().Error("No choices returned from LLM", "iteration", iteration)
 			break
 		}
 
@@ -356,7 +437,7 @@ func (m *MCPAgent) GenerateContentWithConfig(
 
 		if strings.TrimSpace(assistantText) != "" {
 			if config.Verbose {
-				logger.Logger.Debug("Assistant response",
+				m.log().Debug("Assistant response",
 					"iteration", iteration,
 					"text_preview", TruncateString(assistantText, LongResultLength))
 			}
@@ -365,7 +446,9 @@ func (m *MCPAgent) GenerateContentWithConfig(
 				Role:      "assistant",
 				Content:   assistantText,
 				Timestamp: time.Now(),
+				Iteration: iteration,
 			})
+			config.observe(IterationEvent{Iteration: iteration, Message: &result.Messages[len(result.Messages)-1]})
 
 			*msgs = append(*msgs, llms.MessageContent{
 				Role: llms.ChatMessageTypeAI,
@@ -376,7 +459,7 @@ func (m *MCPAgent) GenerateContentWithConfig(
 		}
 
 		toolCalls := resp.Choices[0].ToolCalls
-		tokens += GetTokenCount(resp)
+		tokens += addTokenUsage(&result, config.Tokenizer, (*msgs)[:promptLen], resp)
 		if len(toolCalls) == 0 {
 			response += assistantText
 			// Check if LLM is asking for clarification instead of acting (using LLM-based detection)
@@ -384,16 +467,16 @@ func (m *MCPAgent) GenerateContentWithConfig(
 				recordClarificationRequest(config.ClarificationDetectionLevel, iteration, assistantText, &result)
 			}
 			if config.Verbose {
-				logger.Logger.Debug("LLM finished conversation:",
+				m.log().Debug("LLM finished conversation:",
 					"reason", resp.Choices[0].StopReason,
 					"content", resp.Choices[0].Content)
-				logger.Logger.Info("Final answer received", "iteration", iteration)
+				m.log().Info("Final answer received", "iteration", iteration)
 			}
 			break
 		}
 
 		if config.Verbose {
-			logger.Logger.Debug("Processing tool calls",
+			m.log().Debug("Processing tool calls",
 				"iteration", iteration,
 				"tool_count", len(toolCalls))
 		}
@@ -403,9 +486,10 @@ func (m *MCPAgent) GenerateContentWithConfig(
 			response += header
 		}
 
+		stopOnToolTimeout := false
 		for toolIdx, suggestedTool := range toolCalls {
 			if config.Verbose {
-				logger.Logger.Debug("Executing tool",
+				m.log().Debug("Executing tool",
 					"iteration", iteration,
 					"tool_index", toolIdx+1,
 					"total_tools", len(toolCalls),
@@ -422,10 +506,12 @@ func (m *MCPAgent) GenerateContentWithConfig(
 				ctx, suggestedTool, config, iteration, toolIdx+1, len(toolCalls))
 
 			if toolErr != nil {
-				result.Errors = append(result.Errors, toolErr.Error())
+				recordToolError(&result, toolErr)
+				stopOnToolTimeout = config.FailOnToolTimeout && IsToolTimeout(toolErr)
 			}
 
 			result.ToolCalls = append(result.ToolCalls, toolCall)
+			config.observe(IterationEvent{Iteration: iteration, ToolCall: &toolCall})
 
 			*msgs = append(*msgs, llms.MessageContent{
 				Role: llms.ChatMessageTypeAI,
@@ -449,15 +535,23 @@ func (m *MCPAgent) GenerateContentWithConfig(
 				printRes := TruncateString(toolRes, LongResultLength)
 				response += fmt.Sprintf("\n[tool_response] %s\n", printRes)
 			}
+
+			if stopOnToolTimeout {
+				break
+			}
+		}
+
+		if stopOnToolTimeout {
+			m.log().Warn("Stopping after tool timeout", "iteration", iteration)
+			break
 		}
 	}
 
 	if iteration >= maxIterations {
 		msg := fmt.Sprintf("Reached maximum iterations (%d) without final answer", maxIterations)
 		result.Errors = append(result.Errors, msg)
-		logger.Logger.Warn("Max iterations reached",
-			"max_iterations", maxIterations,
-			"agent", m.Name)
+		m.log().Warn("Max iterations reached",
+			"max_iterations", maxIterations)
 	}
 
 	result.FinalOutput = response
@@ -469,7 +563,7 @@ func (m *MCPAgent) GenerateContentWithConfig(
 	result.RateLimitStats = m.collectRateLimitStats()
 
 	if config.Verbose {
-		logger.Logger.Info("Execution completed",
+		m.log().Info("Execution completed",
 			"iterations", iteration,
 			"max_iterations", maxIterations,
 			"duration_ms", result.LatencyMs,
@@ -477,7 +571,7 @@ func (m *MCPAgent) GenerateContentWithConfig(
 			"errors", len(result.Errors),
 			"approx_tokens", result.TokensUsed)
 		if result.RateLimitStats != nil && (result.RateLimitStats.ThrottleCount > 0 || result.RateLimitStats.RateLimitHits > 0) {
-			logger.Logger.Info("Rate limit stats",
+			m.log().Info("Rate limit stats",
 				"throttle_count", result.RateLimitStats.ThrottleCount,
 				"throttle_wait_ms", result.RateLimitStats.ThrottleWaitTimeMs,
 				"rate_limit_hits", result.RateLimitStats.RateLimitHits,
@@ -505,8 +599,7 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 		maxIterations := getMaxIterations(config.MaxIterations)
 
 		if config.Verbose {
-			logger.Logger.Info("Streaming execution started",
-				"agent", m.Name,
+			m.log().Info("Streaming execution started",
 				"provider", m.Provider,
 				"max_iterations", maxIterations)
 		}
@@ -522,11 +615,13 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 			}
 		}
 
+		result.ToolChoice = config.ToolChoice
+
 		recordUserMessages(msgs, &result, config.Verbose)
 
 		tools := m.ExtractToolsFromAgent()
 		if config.Verbose {
-			logger.Logger.Debug("Tools extracted for streaming", "count", len(tools))
+			m.log().Debug("Tools extracted for streaming", "count", len(tools))
 		}
 
 		response := ""
@@ -536,7 +631,7 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 			iteration++
 
 			if config.Verbose {
-				logger.Logger.Debug("Starting streaming iteration",
+				m.log().Debug("Starting streaming iteration",
 					"iteration", iteration,
 					"max_iterations", maxIterations)
 			}
@@ -544,17 +639,18 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 			if ctx.Err() != nil {
 				errMsg := fmt.Sprintf("Context cancelled: %v", ctx.Err())
 				result.Errors = append(result.Errors, errMsg)
-				logger.Logger.Error("Streaming context cancelled",
+				m.log().Error("Streaming context cancelled",
 					"iteration", iteration,
 					"error", ctx.Err())
 				streamingChan <- fmt.Sprintf("\n[Error] %s\n", errMsg)
 				break
 			}
 
-			resp, err := m.LLMModel.GenerateContent(ctx, *msgs, llms.WithTools(tools), llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			promptLen := len(*msgs)
+			callOpts := append(toolCallOptions(tools, config, iteration), llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
 				if isToolCallChunk(chunk) {
 					if config.Verbose {
-						logger.Logger.Debug("Filtered tool call chunk", "iteration", iteration)
+						m.log().Debug("Filtered tool call chunk", "iteration", iteration)
 					}
 					return nil
 				}
@@ -563,11 +659,12 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 				response += string(chunk)
 				return nil
 			}))
+			resp, err := m.LLMModel.GenerateContent(ctx, *msgs, callOpts...)
 
 			if err != nil {
 				errMsg := fmt.Sprintf("LLM generation error (iteration %d): %v", iteration, err)
-				result.Errors = append(result.Errors, errMsg)
-				logger.Logger.Error("Streaming LLM generation failed",
+				recordGenerationError(&result, err, errMsg)
+				m.log().Error("Streaming LLM generation failed",
 					"iteration", iteration,
 					"error", err)
 				streamingChan <- fmt.Sprintf("\n[Error] %s\n", errMsg)
@@ -577,7 +674,7 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 			if len(resp.Choices) == 0 {
 				errMsg := fmt.Sprintf("LLM returned no choices (iteration %d)", iteration)
 				result.Errors = append(result.Errors, errMsg)
-				logger.Logger.Error("No choices in streaming response", "iteration", iteration)
+				m.log().Error("No choices in streaming response", "iteration", iteration)
 				break
 			}
 
@@ -585,7 +682,7 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 
 			if strings.TrimSpace(assistantText) != "" {
 				if config.Verbose {
-					logger.Logger.Debug("Streaming assistant response",
+					m.log().Debug("Streaming assistant response",
 						"iteration", iteration,
 						"text_preview", TruncateString(assistantText, 150))
 				}
@@ -594,7 +691,9 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 					Role:      "assistant",
 					Content:   assistantText,
 					Timestamp: time.Now(),
+					Iteration: iteration,
 				})
+				config.observe(IterationEvent{Iteration: iteration, Message: &result.Messages[len(result.Messages)-1]})
 
 				*msgs = append(*msgs, llms.MessageContent{
 					Role: llms.ChatMessageTypeAI,
@@ -605,16 +704,16 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 			}
 
 			toolCalls := resp.Choices[0].ToolCalls
-			tokens += GetTokenCount(resp)
+			tokens += addTokenUsage(&result, config.Tokenizer, (*msgs)[:promptLen], resp)
 			if len(toolCalls) == 0 {
 				if config.Verbose {
-					logger.Logger.Info("Streaming final answer received", "iteration", iteration)
+					m.log().Info("Streaming final answer received", "iteration", iteration)
 				}
 				break
 			}
 
 			if config.Verbose {
-				logger.Logger.Debug("Processing streaming tool calls",
+				m.log().Debug("Processing streaming tool calls",
 					"iteration", iteration,
 					"tool_count", len(toolCalls))
 			}
@@ -625,9 +724,10 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 				response += header
 			}
 
+			stopOnToolTimeout := false
 			for toolIdx, suggestedTool := range toolCalls {
 				if config.Verbose {
-					logger.Logger.Debug("Executing streaming tool",
+					m.log().Debug("Executing streaming tool",
 						"iteration", iteration,
 						"tool_index", toolIdx+1,
 						"total_tools", len(toolCalls),
@@ -645,13 +745,15 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 					ctx, suggestedTool, config, iteration, toolIdx+1, len(toolCalls))
 
 				if toolErr != nil {
-					result.Errors = append(result.Errors, toolErr.Error())
+					recordToolError(&result, toolErr)
+					stopOnToolTimeout = config.FailOnToolTimeout && IsToolTimeout(toolErr)
 					if config.AddNotFinalResponses {
 						streamingChan <- fmt.Sprintf("\n[Error] %s\n", toolErr.Error())
 					}
 				}
 
 				result.ToolCalls = append(result.ToolCalls, toolCall)
+				config.observe(IterationEvent{Iteration: iteration, ToolCall: &toolCall})
 
 				*msgs = append(*msgs, llms.MessageContent{
 					Role: llms.ChatMessageTypeAI,
@@ -677,15 +779,23 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 					streamingChan <- toolResponse
 					response += toolResponse
 				}
+
+				if stopOnToolTimeout {
+					break
+				}
+			}
+
+			if stopOnToolTimeout {
+				m.log().Warn("Stopping streaming after tool timeout", "iteration", iteration)
+				break
 			}
 		}
 
 		if iteration >= maxIterations {
 			msg := fmt.Sprintf("Reached maximum iterations (%d) without final answer", maxIterations)
 			result.Errors = append(result.Errors, msg)
-			logger.Logger.Warn("Streaming max iterations reached",
-				"max_iterations", maxIterations,
-				"agent", m.Name)
+			m.log().Warn("Streaming max iterations reached",
+				"max_iterations", maxIterations)
 			streamingChan <- fmt.Sprintf("\n[Warning] %s\n", msg)
 		}
 
@@ -698,12 +808,12 @@ func (m *MCPAgent) GenerateContentAsStreaming(
 		result.RateLimitStats = m.collectRateLimitStats()
 
 		if config.Verbose {
-			logger.Logger.Info("Streaming execution completed",
+			m.log().Info("Streaming execution completed",
 				"iterations", iteration,
 				"duration_ms", result.LatencyMs,
 				"tool_calls", len(result.ToolCalls))
 			if result.RateLimitStats != nil && (result.RateLimitStats.ThrottleCount > 0 || result.RateLimitStats.RateLimitHits > 0) {
-				logger.Logger.Info("Rate limit stats",
+				m.log().Info("Rate limit stats",
 					"throttle_count", result.RateLimitStats.ThrottleCount,
 					"throttle_wait_ms", result.RateLimitStats.ThrottleWaitTimeMs,
 					"rate_limit_hits", result.RateLimitStats.RateLimitHits,
@@ -749,6 +859,24 @@ func (m *MCPAgent) ExtractToolsFromAgent() []llms.Tool {
 	return result
 }
 
+// ToolParameters returns the parameter names declared in each tool's input schema,
+// keyed by tool name. Used to detect hallucinated tool parameters.
+func (m *MCPAgent) ToolParameters() map[string][]string {
+	result := make(map[string][]string)
+
+	for _, agTs := range m.MCPServerTools {
+		for _, agT := range agTs {
+			params := make([]string, 0, len(agT.InputSchema.Properties))
+			for name := range agT.InputSchema.Properties {
+				params = append(params, name)
+			}
+			result[agT.Name] = params
+		}
+	}
+
+	return result
+}
+
 func ValidateAndParseArguments(argumentsInJSON string) (any, error) {
 	if argumentsInJSON == "" || argumentsInJSON == "{}" {
 		return nil, nil
@@ -782,6 +910,71 @@ func (m *MCPAgent) isToolAllowed(serverName, toolName string) bool {
 	return false
 }
 
+// ToolTimeoutError is returned when a tool call is abandoned after exceeding its timeout.
+type ToolTimeoutError struct {
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("tool '%s' timed out after %s", e.Tool, e.Timeout)
+}
+
+// IsToolTimeout reports whether err is (or wraps) a ToolTimeoutError.
+func IsToolTimeout(err error) bool {
+	var timeoutErr *ToolTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// toolTimeout resolves the timeout for a tool call: the test override wins,
+// then the owning server's tool_timeout, then the agent-wide default.
+func (m *MCPAgent) toolTimeout(toolName string, config AgentConfig) time.Duration {
+	if config.TestToolTimeout > 0 {
+		return config.TestToolTimeout
+	}
+	if serverName, ok := m.ToolToServer[toolName]; ok {
+		if srv, err := m.findServer(serverName); err == nil && srv.ToolTimeout > 0 {
+			return srv.ToolTimeout
+		}
+	}
+	return config.ToolTimeout
+}
+
+// executeToolWithDeadline runs the tool call in the background so a server that
+// ignores cancellation cannot hang the agent loop past its timeout. Zero means unlimited.
+func (m *MCPAgent) executeToolWithDeadline(ctx context.Context, toolName, arguments string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return m.ExecuteTool(ctx, toolName, arguments)
+	}
+
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type toolOutcome struct {
+		res string
+		err error
+	}
+	outcome := make(chan toolOutcome, 1)
+	go func() {
+		res, err := m.ExecuteTool(toolCtx, toolName, arguments)
+		outcome <- toolOutcome{res: res, err: err}
+	}()
+
+	var res string
+	var err error
+	select {
+	case out := <-outcome:
+		res, err = out.res, out.err
+	case <-toolCtx.Done():
+		err = toolCtx.Err()
+	}
+	// Only our own deadline counts as a tool timeout; a cancelled test or run is reported by the engine
+	if err != nil && ctx.Err() == nil && errors.Is(toolCtx.Err(), context.DeadlineExceeded) {
+		return "", &ToolTimeoutError{Tool: toolName, Timeout: timeout}
+	}
+	return res, err
+}
+
 func (m *MCPAgent) ExecuteToolWithTimeout(
 	ctx context.Context,
 	suggestedTool llms.ToolCall,
@@ -791,7 +984,7 @@ func (m *MCPAgent) ExecuteToolWithTimeout(
 	var params map[string]interface{}
 	if err := json.Unmarshal([]byte(suggestedTool.FunctionCall.Arguments), &params); err != nil {
 		if config.Verbose {
-			logger.Logger.Warn("Failed to parse tool arguments",
+			m.log().Warn("Failed to parse tool arguments",
 				"iteration", iteration,
 				"tool_index", toolIdx,
 				"total_tools", totalTools,
@@ -804,32 +997,23 @@ func (m *MCPAgent) ExecuteToolWithTimeout(
 		Name:       suggestedTool.FunctionCall.Name,
 		Parameters: params,
 		Timestamp:  time.Now(),
+		Iteration:  iteration,
 	}
 
-	toolCtx := ctx
-	var cancel context.CancelFunc
-	if config.ToolTimeout > 0 {
-		toolCtx, cancel = context.WithTimeout(ctx, config.ToolTimeout)
-		if config.Verbose {
-			logger.Logger.Debug("Tool timeout set",
-				"iteration", iteration,
-				"tool_index", toolIdx,
-				"timeout", config.ToolTimeout)
-		}
+	toolName := suggestedTool.FunctionCall.Name
+	timeout := m.toolTimeout(toolName, config)
+	if timeout > 0 && config.Verbose {
+		m.log().Debug("Tool timeout set",
+			"iteration", iteration,
+			"tool_index", toolIdx,
+			"timeout", timeout)
 	}
 
-	// Measure actual tool execution time
+	// Measure actual tool execution time, up to the point the call is abandoned
 	execStart := time.Now()
-	toolRes, toolErr := m.ExecuteTool(
-		toolCtx,
-		suggestedTool.FunctionCall.Name,
-		suggestedTool.FunctionCall.Arguments,
-	)
+	toolRes, toolErr := m.executeToolWithDeadline(ctx, toolName, suggestedTool.FunctionCall.Arguments, timeout)
 	toolCall.DurationMs = time.Since(execStart).Milliseconds()
-
-	if cancel != nil {
-		cancel()
-	}
+	toolCall.TimedOut = IsToolTimeout(toolErr)
 
 	if toolErr != nil {
 		errMsg := fmt.Sprintf("Tool execution error (iteration %d, tool %s): %v",
@@ -842,21 +1026,23 @@ func (m *MCPAgent) ExecuteToolWithTimeout(
 					Text: errMsg,
 				},
 			},
+			IsError: true,
 		}
 
-		logger.Logger.Error("Tool execution failed",
+		m.log().Error("Tool execution failed",
 			"iteration", iteration,
 			"tool_index", toolIdx,
 			"tool_name", suggestedTool.FunctionCall.Name,
 			"error", toolErr)
 
-		return toolCall, errMsg, fmt.Errorf("%s", errMsg)
+		return toolCall, errMsg, fmt.Errorf("Tool execution error (iteration %d, tool %s): %w",
+			iteration, suggestedTool.FunctionCall.Name, toolErr)
 	}
 
 	var resultData model.Result
 	if err := json.Unmarshal([]byte(toolRes), &resultData); err != nil {
 		if config.Verbose {
-			logger.Logger.Warn("Failed to parse tool result",
+			m.log().Warn("Failed to parse tool result",
 				"iteration", iteration,
 				"tool_index", toolIdx,
 				"error", err)
@@ -874,7 +1060,7 @@ func (m *MCPAgent) ExecuteToolWithTimeout(
 	}
 
 	if config.Verbose {
-		logger.Logger.Debug("Tool execution successful",
+		m.log().Debug("Tool execution successful",
 			"iteration", iteration,
 			"tool_index", toolIdx,
 			"result_preview", TruncateString(toolRes, ResultPreviewLength))
@@ -897,6 +1083,21 @@ func getMaxIterations(configValue int) int {
 	return configValue
 }
 
+// toolCallOptions returns the tool options for one LLM call. A forcing tool choice
+// (required or a named tool) only applies to the first call, otherwise the model
+// could never answer after the tool results come back.
+func toolCallOptions(tools []llms.Tool, config AgentConfig, iteration int) []llms.CallOption {
+	opts := []llms.CallOption{llms.WithTools(tools)}
+	if config.ToolChoiceOption == nil {
+		return opts
+	}
+	forcing := config.ToolChoice != model.ToolChoiceAuto && config.ToolChoice != model.ToolChoiceNone
+	if forcing && iteration > 1 {
+		return opts
+	}
+	return append(opts, llms.WithToolChoice(config.ToolChoiceOption))
+}
+
 func initializeExecutionResult(agentName, provider string, startTime time.Time) model.ExecutionResult {
 	return model.ExecutionResult{
 		AgentName:    agentName,
@@ -914,6 +1115,25 @@ type RateLimitStatsProvider interface {
 	ResetStats()
 }
 
+// recordGenerationError records an LLM error, classifying rate limit errors so
+// assertions do not have to match on provider-specific wording.
+func recordGenerationError(result *model.ExecutionResult, err error, errMsg string) {
+	if model.IsRateLimitError(err) {
+		result.AddError(model.ErrorKindRateLimit, errMsg)
+		return
+	}
+	result.Errors = append(result.Errors, errMsg)
+}
+
+// recordToolError records a tool execution error, classifying tool timeouts.
+func recordToolError(result *model.ExecutionResult, err error) {
+	if IsToolTimeout(err) {
+		result.AddError(model.ErrorKindToolTimeout, err.Error())
+		return
+	}
+	result.Errors = append(result.Errors, err.Error())
+}
+
 // collectRateLimitStats retrieves rate limit stats from the LLM if it supports them
 func (m *MCPAgent) collectRateLimitStats() *model.RateLimitStats {
 	if provider, ok := m.LLMModel.(RateLimitStatsProvider); ok {
@@ -987,9 +1207,21 @@ func GetTokenCount(response *llms.ContentResponse) int {
 	if len(response.Choices) == 0 {
 		return 0
 	}
+	if v := ReportedTokenCount(response); v > 0 {
+		return v
+	}
 
-	choice := response.Choices[0]
-	genInfo := choice.GenerationInfo
+	// Fallback: estimate using len(response)/4
+	return len(response.Choices[0].Content) / ApproxTokenDivisor
+}
+
+// ReportedTokenCount returns the total token count the provider reported in the
+// response's GenerationInfo, or 0 when it reported none.
+func ReportedTokenCount(response *llms.ContentResponse) int {
+	if response == nil || len(response.Choices) == 0 {
+		return 0
+	}
+	genInfo := response.Choices[0].GenerationInfo
 
 	// Try to parse based on common provider keys
 	if genInfo != nil {
@@ -1020,9 +1252,86 @@ func GetTokenCount(response *llms.ContentResponse) int {
 			return inputTokens + outputTokens
 		}
 	}
+	return 0
+}
 
-	// Fallback: estimate using len(response)/4
-	return len(choice.Content) / ApproxTokenDivisor
+// EstimateTokenCount estimates the prompt and completion tokens of a call from the
+// messages sent and the response received, for providers that report no usage.
+// Tool call names and arguments count toward the completion. A nil tokenizer
+// uses tokenizer.Default.
+func EstimateTokenCount(tok tokenizer.Tokenizer, prompt []llms.MessageContent, response *llms.ContentResponse) (promptTokens, completionTokens int) {
+	if tok == nil {
+		tok = tokenizer.Default
+	}
+	for _, msg := range prompt {
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				promptTokens += tok.Count(p.Text)
+			case llms.ToolCall:
+				if p.FunctionCall != nil {
+					promptTokens += tok.Count(p.FunctionCall.Name + p.FunctionCall.Arguments)
+				}
+			case llms.ToolCallResponse:
+				promptTokens += tok.Count(p.Content)
+			}
+		}
+	}
+
+	if response == nil || len(response.Choices) == 0 {
+		return promptTokens, 0
+	}
+	choice := response.Choices[0]
+	completionTokens = tok.Count(choice.Content)
+	for _, tc := range choice.ToolCalls {
+		if tc.FunctionCall != nil {
+			completionTokens += tok.Count(tc.FunctionCall.Name + tc.FunctionCall.Arguments)
+		}
+	}
+	return promptTokens, completionTokens
+}
+
+// addTokenUsage adds the tokens used by one call to result and returns the total.
+// Reported usage is used when the provider sends it; otherwise usage is estimated
+// from the prompt and response text with tok and the result is flagged as estimated.
+func addTokenUsage(result *model.ExecutionResult, tok tokenizer.Tokenizer, prompt []llms.MessageContent, response *llms.ContentResponse) int {
+	if reported := ReportedTokenCount(response); reported > 0 {
+		AddTokenBreakdown(result, response)
+		return reported
+	}
+
+	if tok == nil {
+		tok = tokenizer.Default
+	}
+	promptTokens, completionTokens := EstimateTokenCount(tok, prompt, response)
+	result.PromptTokens += promptTokens
+	result.CompletionTokens += completionTokens
+	result.TokensEstimated = true
+	result.Tokenizer = tok.Name()
+	return promptTokens + completionTokens
+}
+
+// AddTokenBreakdown adds the prompt, completion and prompt-cache token counts reported
+// in the response's GenerationInfo to the result. Missing keys are treated as zero.
+func AddTokenBreakdown(result *model.ExecutionResult, response *llms.ContentResponse) {
+	if response == nil || len(response.Choices) == 0 || response.Choices[0].GenerationInfo == nil {
+		return
+	}
+	genInfo := response.Choices[0].GenerationInfo
+
+	firstInt := func(keys ...string) int {
+		for _, key := range keys {
+			if v := extractInt(genInfo[key]); v > 0 {
+				return v
+			}
+		}
+		return 0
+	}
+
+	result.PromptTokens += firstInt("PromptTokens", "prompt_tokens", "InputTokens", "input_tokens")
+	result.CompletionTokens += firstInt("CompletionTokens", "completion_tokens", "OutputTokens", "output_tokens")
+	result.CacheReadTokens += firstInt("CacheReadInputTokens", "cache_read_input_tokens")
+	result.CacheWriteTokens += firstInt("CacheCreationInputTokens", "cache_creation_input_tokens")
 }
 
 // extractInt safely extracts an integer from an any/interface{} value
@@ -1085,8 +1394,9 @@ Respond ONLY "YES" or "NO".`
 // This is more accurate than pattern matching as it can understand context, nuance, and multiple languages.
 // Returns true if the response is detected as a clarification request.
 func CheckClarificationWithLLM(ctx context.Context, judgeLLM llms.Model, responseText string) bool {
+	log := logger.FromContext(ctx)
 	if judgeLLM == nil {
-		logger.Logger.Warn("Clarification judge LLM is nil, skipping detection")
+		log.Warn("Clarification judge LLM is nil, skipping detection")
 		return false
 	}
 
@@ -1113,12 +1423,12 @@ func CheckClarificationWithLLM(ctx context.Context, judgeLLM llms.Model, respons
 	// Call the judge LLM
 	resp, err := judgeLLM.GenerateContent(judgeCtx, msgs)
 	if err != nil {
-		logger.Logger.Warn("Clarification detection LLM call failed", "error", err)
+		log.Warn("Clarification detection LLM call failed", "error", err)
 		return false
 	}
 
 	if len(resp.Choices) == 0 {
-		logger.Logger.Warn("Clarification detection LLM returned no choices")
+		log.Warn("Clarification detection LLM returned no choices")
 		return false
 	}
 
@@ -1133,6 +1443,113 @@ func CheckClarificationWithLLM(ctx context.Context, judgeLLM llms.Model, respons
 	return false
 }
 
+// rubricJudgePrompt is the system prompt for the LLM that grades output against an llm_rubric
+const rubricJudgePrompt = `You grade an AI assistant's final answer against a rubric.
+
+Decide whether the answer satisfies the rubric and give a score from 0.0 (not at all) to 1.0 (fully).
+Judge only what the rubric asks for; ignore style unless the rubric mentions it.
+
+Respond ONLY with JSON: {"pass": true|false, "score": 0.0-1.0, "reason": "one sentence"}`
+
+// LLMJudge grades outputs against a rubric with an LLM. It implements model.Judge.
+type LLMJudge struct {
+	name string
+	llm  llms.Model
+}
+
+// NewLLMJudge creates a judge named name that asks llm for its verdicts.
+func NewLLMJudge(name string, llm llms.Model) *LLMJudge {
+	return &LLMJudge{name: name, llm: llm}
+}
+
+func (j *LLMJudge) Name() string {
+	return j.name
+}
+
+// Grade asks the judge LLM whether output satisfies rubric.
+func (j *LLMJudge) Grade(ctx context.Context, rubric, output string) (model.JudgeVerdict, error) {
+	judgeCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	msgs := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, rubricJudgePrompt),
+		llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("Rubric:\n%s\n\nAnswer:\n%s", rubric, output)),
+	}
+	resp, err := j.llm.GenerateContent(judgeCtx, msgs)
+	if err != nil {
+		return model.JudgeVerdict{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return model.JudgeVerdict{}, fmt.Errorf("judge returned no choices")
+	}
+
+	// Tolerate prose or code fences around the JSON object
+	content := resp.Choices[0].Content
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return model.JudgeVerdict{}, fmt.Errorf("judge response is not JSON: %s", TruncateString(content, 200))
+	}
+	var verdict model.JudgeVerdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &verdict); err != nil {
+		return model.JudgeVerdict{}, fmt.Errorf("invalid judge verdict: %w", err)
+	}
+	return verdict, nil
+}
+
+// DefaultPairwisePrompt is the system prompt for the LLM that compares two answers
+// head-to-head. pairwise.prompt replaces it; the response format is always appended.
+const DefaultPairwisePrompt = `You compare two AI assistants' final answers to the same request.
+
+Decide which answer better satisfies the request: correctness first, then completeness, then clarity.
+The order in which the answers are shown says nothing about their quality. Declare a tie only when
+neither answer is meaningfully better.`
+
+// pairwiseResponseFormat is appended to the pairwise judging instructions
+const pairwiseResponseFormat = `Respond ONLY with JSON: {"winner": "A"|"B"|"tie", "reason": "one sentence"}`
+
+// Compare asks the judge LLM which of two answers to prompt is better.
+// instructions replaces DefaultPairwisePrompt when set.
+func (j *LLMJudge) Compare(ctx context.Context, instructions, prompt, answerA, answerB string) (model.PairwiseVerdict, error) {
+	judgeCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	if strings.TrimSpace(instructions) == "" {
+		instructions = DefaultPairwisePrompt
+	}
+	msgs := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, instructions+"\n\n"+pairwiseResponseFormat),
+		llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf("Request:\n%s\n\nAnswer A:\n%s\n\nAnswer B:\n%s", prompt, answerA, answerB)),
+	}
+	resp, err := j.llm.GenerateContent(judgeCtx, msgs)
+	if err != nil {
+		return model.PairwiseVerdict{}, err
+	}
+	if len(resp.Choices) == 0 {
+		return model.PairwiseVerdict{}, fmt.Errorf("judge returned no choices")
+	}
+
+	content := resp.Choices[0].Content
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return model.PairwiseVerdict{}, fmt.Errorf("judge response is not JSON: %s", TruncateString(content, 200))
+	}
+	var verdict model.PairwiseVerdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &verdict); err != nil {
+		return model.PairwiseVerdict{}, fmt.Errorf("invalid pairwise verdict: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(verdict.Winner)) {
+	case "a":
+		verdict.Winner = "A"
+	case "b":
+		verdict.Winner = "B"
+	case "tie":
+		verdict.Winner = "tie"
+	default:
+		return model.PairwiseVerdict{}, fmt.Errorf("invalid pairwise winner %q", verdict.Winner)
+	}
+	return verdict, nil
+}
+
 // logClarificationRequest logs the clarification request at the configured level
 // and optionally adds it to the result errors based on the level.
 func logClarificationRequest(level ClarificationLevel, iteration int, text string, result *model.ExecutionResult) {
@@ -1218,6 +1635,22 @@ type AISummaryResult struct {
 	Guidance  string `json:"guidance,omitempty"`  // Actionable suggestion for the user
 }
 
+// GenerateAISummaryWithJudges tries the judges in order until one writes the AI
+// summary, and returns the last attempt, or nil without judges. A summary is
+// free text and cannot be averaged across judges.
+func GenerateAISummaryWithJudges(ctx context.Context, judges []llms.Model, results []model.TestRun) *AISummaryResult {
+	var result *AISummaryResult
+	for i, judgeLLM := range judges {
+		summary := GenerateAISummary(ctx, judgeLLM, results)
+		result = &summary
+		if summary.Success {
+			break
+		}
+		logger.FromContext(ctx).Warn("AI summary judge failed", "judge", i+1, "error", summary.Error)
+	}
+	return result
+}
+
 // GenerateAISummary uses an LLM to generate an executive summary of test results.
 // It takes the full test results and produces a markdown analysis.
 // Returns an AISummaryResult with either the analysis or error information.
@@ -1227,7 +1660,7 @@ func GenerateAISummary(ctx context.Context, judgeLLM llms.Model, results []model
 			Success:   false,
 			Error:     "AI summary LLM is nil",
 			Retryable: false,
-			Guidance:  "Configure a valid judge_provider in ai_summary settings. Use '$self' to reuse an agent's provider, or specify a provider name.",
+			Guidance:  "Configure a top-level judge provider to generate the AI summary.",
 		}
 	}
 
@@ -1270,7 +1703,7 @@ func GenerateAISummary(ctx context.Context, judgeLLM llms.Model, results []model
 			Success:   false,
 			Error:     fmt.Sprintf("LLM call failed: %v", err),
 			Retryable: true,
-			Guidance:  "Check API connectivity and credentials. Ensure the judge_provider is correctly configured.",
+			Guidance:  "Check API connectivity and credentials. Ensure the judge provider is correctly configured.",
 		}
 	}
 
@@ -1279,7 +1712,7 @@ func GenerateAISummary(ctx context.Context, judgeLLM llms.Model, results []model
 			Success:   false,
 			Error:     "LLM returned no response",
 			Retryable: true,
-			Guidance:  "The model may be overloaded. Try again, or use a different judge_provider.",
+			Guidance:  "The model may be overloaded. Try again, or use a different judge provider.",
 		}
 	}
 
@@ -1493,7 +1926,9 @@ func prepareResultsSummary(results []model.TestRun) string {
 
 		// Show failed assertions
 		for _, a := range r.Assertions {
-			if !a.Passed {
+			if a.IsWarning() {
+				sb.WriteString(fmt.Sprintf("- **WARNING %s**: %s\n", a.Type, a.Message))
+			} else if !a.Passed {
 				sb.WriteString(fmt.Sprintf("- **FAILED %s**: %s\n", a.Type, a.Message))
 			}
 		}
@@ -0,0 +1,169 @@
+// Package configschema builds a JSON Schema of the test and suite file formats
+// from the model structs and their yaml tags, so editors can offer completion
+// and validation. The schema follows the structs; only enum values are listed here.
+package configschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/generator"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// SchemaURI is the JSON Schema dialect of the generated schema.
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// templatePattern matches a value that is filled in from a template, such as
+// "{{AUTH_TYPE}}", which an enum cannot list.
+const templatePattern = `\{\{.+\}\}`
+
+// typeEnums lists the values of the named string types used in configurations.
+var typeEnums = map[reflect.Type][]string{
+	reflect.TypeOf(model.ProviderType("")): {
+		string(model.ProviderGroq), string(model.ProviderGoogle), string(model.ProviderVertex),
+		string(model.ProviderAnthropic), string(model.ProviderAmazonAnthropic), string(model.ProviderBedrock),
+		string(model.ProviderOpenAI), string(model.ProviderAzure), string(model.ProviderMistral),
+		string(model.ProviderOllama),
+	},
+	reflect.TypeOf(model.ServerType("")): {
+		string(model.Stdio), string(model.SSE), string(model.Http), string(model.CLI), string(model.Docker),
+	},
+	reflect.TypeOf(model.VariablePolicy("")): {
+		string(model.TestOnly), string(model.SuiteOnly), string(model.MergeTestPriority), string(model.MergeSuitePriority),
+	},
+	reflect.TypeOf(model.JudgeAggregation("")): {
+		string(model.JudgeAggregationMajority), string(model.JudgeAggregationMean), string(model.JudgeAggregationMedian),
+	},
+	reflect.TypeOf(model.ToolTimeoutPolicy("")): {
+		string(model.ToolTimeoutContinue), string(model.ToolTimeoutFail),
+	},
+	reflect.TypeOf(model.AssertionMode("")): {
+		string(model.AssertionModeAll), string(model.AssertionModeFirstFail),
+	},
+}
+
+// fieldEnums lists the values of plain string fields, keyed by struct name and
+// yaml key. Templated fields also accept a template.
+var fieldEnums = map[string]struct {
+	values    []string
+	templated bool
+}{
+	"Provider.auth_type": {values: []string{"api_key", "entra_id"}, templated: true},
+	"Assertion.type":     {values: generator.AssertionTypes()},
+	"Assertion.severity": {values: []string{model.SeverityError, model.SeverityWarning}},
+	"Assertion.match":    {values: []string{"any", "all"}},
+}
+
+// Generate returns the schema of a configuration file: either a test file
+// (with sessions) or a suite file (with test_files).
+func Generate() map[string]interface{} {
+	b := &builder{defs: make(map[string]interface{})}
+	test := b.ref(reflect.TypeOf(model.TestConfiguration{}))
+	suite := b.ref(reflect.TypeOf(model.TestSuiteConfiguration{}))
+	return map[string]interface{}{
+		"$schema":     SchemaURI,
+		"title":       "agent-benchmark configuration",
+		"description": "A test file (with sessions) or a suite file (with test_files)",
+		"anyOf":       []interface{}{test, suite},
+		"$defs":       b.defs,
+	}
+}
+
+// JSON returns the schema as indented JSON.
+func JSON() ([]byte, error) {
+	return json.MarshalIndent(Generate(), "", "  ")
+}
+
+type builder struct {
+	defs map[string]interface{}
+}
+
+// ref returns a reference to the definition of a struct type, adding it first.
+func (b *builder) ref(t reflect.Type) map[string]interface{} {
+	name := t.Name()
+	if _, ok := b.defs[name]; !ok {
+		// Reserve the name so recursive types (Assertion.anyOf) terminate
+		b.defs[name] = nil
+		b.defs[name] = b.object(t)
+	}
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
+
+// object describes a struct. Unknown keys are rejected so typos are caught,
+// even though the loader itself ignores them.
+func (b *builder) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	b.addFields(t, properties)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func (b *builder) addFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			b.addFields(field.Type, properties)
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+
+		if enum, ok := fieldEnums[t.Name()+"."+key]; ok {
+			properties[key] = enumSchema(enum.values, enum.templated)
+			continue
+		}
+		properties[key] = b.schema(field.Type)
+	}
+}
+
+// schema describes a field type.
+func (b *builder) schema(t reflect.Type) map[string]interface{} {
+	if values, ok := typeEnums[t]; ok {
+		return enumSchema(values, false)
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Struct:
+		return b.ref(t)
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		// YAML scalars such as 1000 or true decode into string fields
+		return map[string]interface{}{"type": []string{"string", "number", "boolean"}}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func enumSchema(values []string, templated bool) map[string]interface{} {
+	enum := map[string]interface{}{"type": "string", "enum": values}
+	if !templated {
+		return enum
+	}
+	return map[string]interface{}{
+		"anyOf": []interface{}{enum, map[string]interface{}{"type": "string", "pattern": templatePattern}},
+	}
+}
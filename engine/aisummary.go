@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// judgeConfig is the part of a test or suite file that selects the judges of
+// the AI summary.
+type judgeConfig struct {
+	path        string
+	summary     model.AISummary
+	judge       *model.Provider
+	judges      []model.Provider
+	aggregation model.JudgeAggregation
+	providers   []model.Provider
+	agents      []model.Agent
+	variables   map[string]string
+}
+
+// loadJudgeConfig reads the judge settings of a suite file, or of a test file
+// when path lists no test_files.
+func loadJudgeConfig(path string) (judgeConfig, error) {
+	if suite, err := model.ParseSuiteConfig(path); err == nil && len(suite.TestFiles) > 0 {
+		return judgeConfig{
+			path:        path,
+			summary:     suite.AISummary,
+			judge:       suite.Judge,
+			judges:      suite.Judges,
+			aggregation: suite.Aggregation,
+			providers:   suite.Providers,
+			agents:      suite.Agents,
+			variables:   suite.Variables,
+		}, nil
+	}
+	tc, err := model.ParseTestConfig(path)
+	if err != nil {
+		return judgeConfig{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return judgeConfig{
+		path:        path,
+		summary:     tc.AISummary,
+		judge:       tc.Judge,
+		judges:      tc.Judges,
+		aggregation: tc.Aggregation,
+		providers:   tc.Providers,
+		agents:      tc.Agents,
+		variables:   tc.Variables,
+	}, nil
+}
+
+// AISummaryJudges creates the judges that write the AI summary of results for
+// the test or suite file at configPath, the way a run of that file does. It
+// returns nil when the file does not enable the AI summary.
+func AISummaryJudges(ctx context.Context, configPath string, results []model.TestRun) ([]llms.Model, error) {
+	cfg, err := loadJudgeConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.summary.Enabled {
+		return nil, nil
+	}
+	templateCtx := CreateStaticTemplateContext(configPath, cfg.variables)
+	_, judges := InitJudgePanel(ctx, JudgeProviders(cfg.judge, cfg.judges), cfg.aggregation, templateCtx)
+	return summaryJudges(ctx, judges, cfg, results), nil
+}
+
+// summaryJudges returns the models tried in order for the AI summary: the
+// top-level judges when any were created, otherwise the provider named by the
+// deprecated ai_summary.judge_provider.
+func summaryJudges(ctx context.Context, judges []llms.Model, cfg judgeConfig, results []model.TestRun) []llms.Model {
+	log := logger.FromContext(ctx)
+	if len(judges) > 0 {
+		return judges
+	}
+	judgeProvider := cfg.summary.JudgeProvider
+	if judgeProvider == "" {
+		log.Warn("AI summary skipped: no judge configured. Add a top-level judge provider to enable it")
+		return nil
+	}
+	log.Warn("ai_summary.judge_provider is deprecated, configure a top-level judge provider instead")
+
+	provider, ok := summaryProvider(cfg, judgeProvider, results)
+	if !ok {
+		log.Error("AI summary judge provider not found", "judge_provider", judgeProvider)
+		return nil
+	}
+	templateCtx := CreateStaticTemplateContext(cfg.path, cfg.variables)
+	providers, err := InitProviders(ctx, []model.Provider{provider}, templateCtx)
+	if err != nil {
+		log.Error("Failed to initialize judge provider", "error", err)
+		return nil
+	}
+	log.Debug("Using provider for AI summary", "judge_provider", judgeProvider, "provider", provider.Name)
+	return []llms.Model{providers[provider.Name]}
+}
+
+// summaryProvider looks up the provider config named by judge_provider. "$self"
+// is the provider of the agent that ran the first executed test, with the
+// agent's model override applied.
+func summaryProvider(cfg judgeConfig, judgeProvider string, results []model.TestRun) (model.Provider, bool) {
+	name, modelName := judgeProvider, ""
+	if judgeProvider == "$self" {
+		executed := model.ExecutedRuns(results)
+		if len(executed) == 0 {
+			return model.Provider{}, false
+		}
+		ag, ok := runAgent(cfg.agents, executed[0].Execution.AgentName)
+		if !ok {
+			return model.Provider{}, false
+		}
+		name, modelName = ag.Provider, executed[0].Execution.Model
+		if modelName == "" {
+			modelName = ag.Model
+		}
+	}
+	for _, p := range cfg.providers {
+		if p.Name == name {
+			if modelName != "" {
+				p.Model = modelName
+			}
+			return p, true
+		}
+	}
+	return model.Provider{}, false
+}
+
+// runAgent finds the agent a run is reported under, including the
+// "agent (model)" runs of a test's model override.
+func runAgent(agents []model.Agent, runName string) (model.Agent, bool) {
+	for _, a := range agents {
+		if runName == a.Name {
+			return a, true
+		}
+	}
+	for _, a := range agents {
+		if strings.HasPrefix(runName, a.Name+" (") {
+			return a, true
+		}
+	}
+	return model.Agent{}, false
+}
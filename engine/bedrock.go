@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/bedrock"
+)
+
+// bedrockRegionPrefixes are the cross-region inference profile prefixes that can
+// precede the model family in a Bedrock model id (e.g. "us.meta.llama3-1-70b-instruct-v1:0").
+var bedrockRegionPrefixes = map[string]bool{
+	"us": true, "eu": true, "apac": true, "us-gov": true, "global": true,
+}
+
+// BedrockModelFamily returns the model family ("anthropic", "meta", "mistral",
+// "amazon", "cohere", ...) of a Bedrock model id, inference profile id or ARN.
+func BedrockModelFamily(modelID string) string {
+	id := modelID
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	parts := strings.Split(id, ".")
+	if len(parts) > 1 && bedrockRegionPrefixes[parts[0]] {
+		parts = parts[1:]
+	}
+	return strings.ToLower(parts[0])
+}
+
+// newBedrockRuntimeClient builds a Bedrock runtime client from the provider's
+// access key (token), secret and region (location). A non-nil httpClient is used
+// for all requests, e.g. to route them through a proxy.
+func newBedrockRuntimeClient(p model.Provider, httpClient *http.Client) (*bedrockruntime.Client, error) {
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(p.Location),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			p.Token,
+			p.Secret,
+			"",
+		)),
+	}
+	if httpClient != nil {
+		opts = append(opts, config.WithHTTPClient(httpClient))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return bedrockruntime.NewFromConfig(cfg), nil
+}
+
+// newBedrockModel routes a Bedrock model to the invocation path for its family:
+// Anthropic models use the langchaingo Bedrock client, every other family uses
+// the Converse API, which provides tool calling and token usage for all of them.
+func newBedrockModel(p model.Provider, httpClient *http.Client) (llms.Model, error) {
+	client, err := newBedrockRuntimeClient(p, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if BedrockModelFamily(p.Model) == "anthropic" {
+		return bedrock.New(
+			bedrock.WithClient(client),
+			bedrock.WithModel(p.Model),
+		)
+	}
+	return NewBedrockConverseLLM(client, p.Model, p.Temperature), nil
+}
+
+// BedrockConverseClient is the part of the Bedrock runtime client used by the Converse model.
+type BedrockConverseClient interface {
+	Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error)
+}
+
+// bedrockConverseLLM implements llms.Model on top of the Bedrock Converse API.
+type bedrockConverseLLM struct {
+	client  BedrockConverseClient
+	modelID string
+	// temperature is the provider's temperature. Call options cannot carry an
+	// explicit 0, so it is kept here to send temperature: 0 as set.
+	temperature *float64
+}
+
+// NewBedrockConverseLLM creates a model that calls modelID through the Converse API.
+// A non-nil temperature is sent with every request unless a call sets a higher one.
+func NewBedrockConverseLLM(client BedrockConverseClient, modelID string, temperature *float64) llms.Model {
+	return &bedrockConverseLLM{client: client, modelID: modelID, temperature: temperature}
+}
+
+func (b *bedrockConverseLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, b, prompt, options...)
+}
+
+func (b *bedrockConverseLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	input := &bedrockruntime.ConverseInput{ModelId: aws.String(b.modelID)}
+	for _, msg := range messages {
+		if msg.Role == llms.ChatMessageTypeSystem {
+			for _, part := range msg.Parts {
+				if text, ok := part.(llms.TextContent); ok {
+					input.System = append(input.System, &types.SystemContentBlockMemberText{Value: text.Text})
+				}
+			}
+			continue
+		}
+
+		role := types.ConversationRoleUser
+		if msg.Role == llms.ChatMessageTypeAI {
+			role = types.ConversationRoleAssistant
+		}
+		blocks, err := converseContentBlocks(msg.Parts)
+		if err != nil {
+			return nil, err
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		// Converse requires alternating roles, so consecutive messages of the same
+		// role (e.g. text followed by a tool call) are merged into one message
+		if n := len(input.Messages); n > 0 && input.Messages[n-1].Role == role {
+			input.Messages[n-1].Content = append(input.Messages[n-1].Content, blocks...)
+			continue
+		}
+		input.Messages = append(input.Messages, types.Message{Role: role, Content: blocks})
+	}
+
+	inference := &types.InferenceConfiguration{StopSequences: opts.StopWords}
+	if opts.MaxTokens > 0 {
+		inference.MaxTokens = aws.Int32(int32(opts.MaxTokens))
+	}
+	if opts.Temperature > 0 {
+		inference.Temperature = aws.Float32(float32(opts.Temperature))
+	} else if b.temperature != nil {
+		inference.Temperature = aws.Float32(float32(*b.temperature))
+	}
+	if opts.TopP > 0 {
+		inference.TopP = aws.Float32(float32(opts.TopP))
+	}
+	input.InferenceConfig = inference
+
+	if len(opts.Tools) > 0 {
+		toolConfig := &types.ToolConfiguration{}
+		for _, tool := range opts.Tools {
+			if tool.Function == nil {
+				continue
+			}
+			toolConfig.Tools = append(toolConfig.Tools, &types.ToolMemberToolSpec{Value: types.ToolSpecification{
+				Name:        aws.String(tool.Function.Name),
+				Description: aws.String(tool.Function.Description),
+				InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(tool.Function.Parameters)},
+			}})
+		}
+		toolConfig.ToolChoice = converseToolChoice(opts.ToolChoice)
+		input.ToolConfig = toolConfig
+	}
+
+	output, err := b.client.Converse(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	choice := &llms.ContentChoice{
+		StopReason:     string(output.StopReason),
+		GenerationInfo: map[string]any{},
+	}
+	if msg, ok := output.Output.(*types.ConverseOutputMemberMessage); ok {
+		for _, block := range msg.Value.Content {
+			switch v := block.(type) {
+			case *types.ContentBlockMemberText:
+				choice.Content += v.Value
+			case *types.ContentBlockMemberToolUse:
+				args := []byte("{}")
+				if v.Value.Input != nil {
+					if args, err = v.Value.Input.MarshalSmithyDocument(); err != nil {
+						return nil, fmt.Errorf("failed to decode tool input: %w", err)
+					}
+				}
+				choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+					ID:   aws.ToString(v.Value.ToolUseId),
+					Type: "function",
+					FunctionCall: &llms.FunctionCall{
+						Name:      aws.ToString(v.Value.Name),
+						Arguments: string(args),
+					},
+				})
+			}
+		}
+	}
+	if output.Usage != nil {
+		choice.GenerationInfo["input_tokens"] = int(aws.ToInt32(output.Usage.InputTokens))
+		choice.GenerationInfo["output_tokens"] = int(aws.ToInt32(output.Usage.OutputTokens))
+		choice.GenerationInfo["total_tokens"] = int(aws.ToInt32(output.Usage.TotalTokens))
+	}
+
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, nil
+}
+
+// converseToolChoice converts an llms tool choice into the Converse form; nil
+// leaves the choice to the model.
+func converseToolChoice(choice any) types.ToolChoice {
+	switch c := choice.(type) {
+	case string:
+		switch c {
+		case model.ToolChoiceAuto:
+			return &types.ToolChoiceMemberAuto{}
+		case model.ToolChoiceRequired:
+			return &types.ToolChoiceMemberAny{}
+		}
+	case llms.ToolChoice:
+		if c.Function != nil {
+			return &types.ToolChoiceMemberTool{Value: types.SpecificToolChoice{Name: aws.String(c.Function.Name)}}
+		}
+	}
+	return nil
+}
+
+// converseContentBlocks converts message parts into Converse content blocks.
+func converseContentBlocks(parts []llms.ContentPart) ([]types.ContentBlock, error) {
+	blocks := make([]types.ContentBlock, 0, len(parts))
+	for _, part := range parts {
+		switch p := part.(type) {
+		case llms.TextContent:
+			if p.Text != "" {
+				blocks = append(blocks, &types.ContentBlockMemberText{Value: p.Text})
+			}
+		case llms.ToolCall:
+			if p.FunctionCall == nil {
+				continue
+			}
+			args := map[string]any{}
+			if p.FunctionCall.Arguments != "" {
+				if err := json.Unmarshal([]byte(p.FunctionCall.Arguments), &args); err != nil {
+					return nil, fmt.Errorf("invalid arguments for tool call %s: %w", p.FunctionCall.Name, err)
+				}
+			}
+			blocks = append(blocks, &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+				ToolUseId: aws.String(p.ID),
+				Name:      aws.String(p.FunctionCall.Name),
+				Input:     document.NewLazyDocument(args),
+			}})
+		case llms.ToolCallResponse:
+			blocks = append(blocks, &types.ContentBlockMemberToolResult{Value: types.ToolResultBlock{
+				ToolUseId: aws.String(p.ToolCallID),
+				Content:   []types.ToolResultContentBlock{&types.ToolResultContentBlockMemberText{Value: p.Content}},
+			}})
+		default:
+			return nil, fmt.Errorf("unsupported content part for Bedrock Converse: %T", part)
+		}
+	}
+	return blocks, nil
+}
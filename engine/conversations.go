@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// RedactedSystemPrompt replaces an exported system prompt that contains a secret.
+const RedactedSystemPrompt = "[REDACTED: system prompt contains a secret]"
+
+// ConversationExport is one test run's conversation in the chat-completions
+// message format, so it can be replayed or analyzed by other tools.
+type ConversationExport struct {
+	Test                 string                `json:"test"`
+	Agent                string                `json:"agent"`
+	Provider             string                `json:"provider"`
+	Session              string                `json:"session,omitempty"`
+	SourceFile           string                `json:"source_file,omitempty"`
+	Passed               bool                  `json:"passed"`
+	SystemPromptRedacted bool                  `json:"system_prompt_redacted,omitempty"`
+	Messages             []ConversationMessage `json:"messages"`
+}
+
+// ConversationMessage is a chat-completions message: system, user, assistant
+// (optionally with tool calls) or tool (the result of one call).
+type ConversationMessage struct {
+	Role       string                 `json:"role"`
+	Content    string                 `json:"content"`
+	ToolCalls  []ConversationToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string                 `json:"tool_call_id,omitempty"`
+	Name       string                 `json:"name,omitempty"`
+}
+
+// ConversationToolCall is a function call requested by the assistant.
+type ConversationToolCall struct {
+	ID       string               `json:"id"`
+	Type     string               `json:"type"`
+	Function ConversationFunction `json:"function"`
+}
+
+// ConversationFunction names the called function; Arguments is a JSON string.
+type ConversationFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// BuildConversation converts a test run into the chat-completions format. Tool
+// calls are placed between messages by timestamp: consecutive calls become the
+// tool_calls of one assistant message, each followed by a tool message with its
+// result. Call IDs are numbered per run since providers' IDs are not recorded.
+func BuildConversation(run model.TestRun) ConversationExport {
+	exec := run.Execution
+	conv := ConversationExport{
+		Test:       exec.TestName,
+		Agent:      exec.AgentName,
+		Provider:   string(exec.ProviderType),
+		Session:    exec.SessionName,
+		SourceFile: exec.SourceFile,
+		Passed:     run.Passed,
+		Messages:   make([]ConversationMessage, 0, len(exec.Messages)+2*len(exec.ToolCalls)+1),
+	}
+
+	if exec.SystemPrompt != "" {
+		prompt := exec.SystemPrompt
+		if ContainsSecret(prompt) {
+			prompt = RedactedSystemPrompt
+			conv.SystemPromptRedacted = true
+		}
+		conv.Messages = append(conv.Messages, ConversationMessage{Role: "system", Content: prompt})
+	}
+
+	nextCall := 0
+	// flushToolCalls adds the tool calls made before the given message, if any
+	flushToolCalls := func(before *model.Message) {
+		start := nextCall
+		for nextCall < len(exec.ToolCalls) && (before == nil || exec.ToolCalls[nextCall].Timestamp.Before(before.Timestamp)) {
+			nextCall++
+		}
+		if start == nextCall {
+			return
+		}
+
+		// Calls made right after assistant text belong to that message
+		last := len(conv.Messages) - 1
+		if last < 0 || conv.Messages[last].Role != "assistant" || len(conv.Messages[last].ToolCalls) > 0 {
+			conv.Messages = append(conv.Messages, ConversationMessage{Role: "assistant"})
+			last = len(conv.Messages) - 1
+		}
+		results := make([]ConversationMessage, 0, nextCall-start)
+		for i := start; i < nextCall; i++ {
+			tc := exec.ToolCalls[i]
+			id := fmt.Sprintf("call_%d", i+1)
+			args, err := json.Marshal(tc.Parameters)
+			if err != nil || tc.Parameters == nil {
+				args = []byte("{}")
+			}
+			conv.Messages[last].ToolCalls = append(conv.Messages[last].ToolCalls, ConversationToolCall{
+				ID:       id,
+				Type:     "function",
+				Function: ConversationFunction{Name: tc.Name, Arguments: string(args)},
+			})
+			results = append(results, ConversationMessage{
+				Role:       "tool",
+				ToolCallID: id,
+				Name:       tc.Name,
+				Content:    toolResultText(tc.Result),
+			})
+		}
+		conv.Messages = append(conv.Messages, results...)
+	}
+
+	for i := range exec.Messages {
+		msg := &exec.Messages[i]
+		flushToolCalls(msg)
+		conv.Messages = append(conv.Messages, ConversationMessage{Role: msg.Role, Content: msg.Content})
+	}
+	flushToolCalls(nil)
+	return conv
+}
+
+// toolResultText joins the text items of a tool result.
+func toolResultText(result model.Result) string {
+	parts := make([]string, 0, len(result.Content))
+	for _, item := range result.Content {
+		if item.Text != "" {
+			parts = append(parts, item.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_-]{20,}`),                                            // OpenAI and Anthropic API keys
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),                                                 // AWS access key IDs
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),                                            // Google API keys
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}`),                                       // GitHub tokens
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),                               // PEM private keys
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}`),                            // Bearer tokens
+	regexp.MustCompile(`(?i)\b(api[_-]?key|secret|password|passwd|token)\s*[:=]\s*\S{8,}`), // key=value credentials
+}
+
+// secretEnvNameRegex matches environment variables whose values are treated as secrets.
+var secretEnvNameRegex = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|CREDENTIAL)`)
+
+// ContainsSecret reports whether text contains something that looks like a
+// credential: a well-known key format, a key=value credential, or the value of
+// an environment variable named like a secret (e.g. OPENAI_API_KEY).
+func ContainsSecret(text string) bool {
+	for _, re := range secretPatterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	for _, env := range os.Environ() {
+		name, value, ok := strings.Cut(env, "=")
+		if ok && len(value) >= 8 && secretEnvNameRegex.MatchString(name) && strings.Contains(text, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportConversations writes the conversation of every executed test run into
+// dir, one JSON file per test and agent, and returns the paths written.
+func ExportConversations(results []model.TestRun, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create conversation export directory: %w", err)
+	}
+
+	paths := make([]string, 0, len(results))
+	used := make(map[string]int)
+	for _, run := range model.ExecutedRuns(results) {
+		base := Slugify(run.Execution.TestName) + "--" + Slugify(run.Execution.AgentName)
+		used[base]++
+		if n := used[base]; n > 1 {
+			base = fmt.Sprintf("%s-%d", base, n)
+		}
+
+		data, err := json.MarshalIndent(BuildConversation(run), "", "  ")
+		if err != nil {
+			return paths, fmt.Errorf("failed to encode conversation of %q: %w", run.Execution.TestName, err)
+		}
+		path := filepath.Join(dir, base+".json")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return paths, fmt.Errorf("failed to write conversation: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	logger.Logger.Info("Exported conversations", "dir", dir, "count", len(paths))
+	return paths, nil
+}
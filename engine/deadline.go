@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+const (
+	// DeadlineExitCode is returned when -max-duration or -deadline cut the run
+	// short, following the timeout(1) convention.
+	DeadlineExitCode = 124
+	// DeadlineGracePeriod is how long tests still running at the deadline may take
+	// to finish before they are cancelled.
+	DeadlineGracePeriod = 2 * time.Minute
+	// NotExecutedDeadlineReason is the skip reason recorded for tests the deadline left unrun.
+	NotExecutedDeadlineReason = "not executed (deadline)"
+)
+
+// ErrRunDeadline is the cancellation cause once the grace period after the run
+// deadline has expired.
+var ErrRunDeadline = errors.New("run deadline exceeded")
+
+type runDeadlineKey struct{}
+
+// WithRunDeadline bounds a run to maxDuration of wall-clock time. Once the deadline
+// passes, RunDeadlineReached reports true and no new tests are started; the returned
+// context is cancelled with ErrRunDeadline after a further grace period so tests
+// still running are stopped too. A non-positive maxDuration leaves the run unbounded.
+// Deadlines nest: an earlier one set on parent stays in effect.
+func WithRunDeadline(parent context.Context, maxDuration, grace time.Duration) (context.Context, context.CancelFunc) {
+	if maxDuration <= 0 {
+		return context.WithCancel(parent)
+	}
+	deadline := time.Now().Add(maxDuration)
+	ctx, cancel := context.WithDeadlineCause(parent, deadline.Add(grace), ErrRunDeadline)
+	if earlier, ok := parent.Value(runDeadlineKey{}).(time.Time); ok && earlier.Before(deadline) {
+		return ctx, cancel
+	}
+	return context.WithValue(ctx, runDeadlineKey{}, deadline), cancel
+}
+
+// ParseDeadline parses a hard run deadline such as the suite's deadline field.
+// An empty value means no deadline; negative values are rejected.
+func ParseDeadline(deadlineStr string) (time.Duration, error) {
+	if deadlineStr == "" {
+		return 0, nil
+	}
+	dur, err := time.ParseDuration(deadlineStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid deadline %q: %w", deadlineStr, err)
+	}
+	if dur < 0 {
+		return 0, fmt.Errorf("invalid deadline %q (must not be negative)", deadlineStr)
+	}
+	return dur, nil
+}
+
+// RunDeadlineReached reports whether the run deadline carried by ctx has passed.
+func RunDeadlineReached(ctx context.Context) bool {
+	deadline, ok := ctx.Value(runDeadlineKey{}).(time.Time)
+	return ok && !time.Now().Before(deadline)
+}
+
+// runDeadlineExpired reports whether ctx was cancelled because the grace period
+// after the run deadline ran out.
+func runDeadlineExpired(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrRunDeadline)
+}
+
+// countNotExecuted returns how many tests the run deadline left unrun.
+func countNotExecuted(results []model.TestRun) int {
+	count := 0
+	for _, r := range results {
+		if r.Skipped && r.SkipReason == NotExecutedDeadlineReason {
+			count++
+		}
+	}
+	return count
+}
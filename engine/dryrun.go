@@ -0,0 +1,230 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// PlannedTest is one test a run would execute on one agent.
+type PlannedTest struct {
+	SourceFile string
+	Session    string
+	Test       string
+	Agent      string // Run name, "agent (model)" for a test's model override
+}
+
+// DryRun loads and validates a test file, a suite or both as a run would,
+// without sending a request to a provider or starting a server: provider
+// clients are created without warm-up, and servers and agents are only checked
+// for names and references. It returns the tests the run would execute, or the
+// first validation error.
+func DryRun(ctx context.Context, testPath, suitePath string) ([]PlannedTest, error) {
+	var plan []PlannedTest
+
+	if testPath != "" {
+		testConfig, err := loadDryRunTestConfig(testPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := ValidateTestConfig(testConfig, false); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+		staticCtx := CreateStaticTemplateContext(testPath, testConfig.Variables)
+		providers, err := dryRunProviders(ctx, testConfig.Providers, testConfig.Agents, testConfig.Sessions, staticCtx)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkServersAndAgents(testConfig.Servers, testConfig.Agents, providers, staticCtx); err != nil {
+			return nil, err
+		}
+		tests, err := planTests(testPath, testConfig.Sessions, testConfig.Agents)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, tests...)
+	}
+
+	if suitePath != "" {
+		if err := ValidateTestInputFile(suitePath); err != nil {
+			return nil, fmt.Errorf("invalid input file: %w", err)
+		}
+		suite, err := model.ParseSuiteConfig(suitePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse suite configuration: %w", err)
+		}
+		if err := ValidateSuiteConfig(suite); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+		if len(suite.TestFiles) == 0 {
+			return nil, fmt.Errorf("no test files found in suite configuration")
+		}
+		suite.Providers = withoutWarmup(suite.Providers)
+		staticCtx := CreateStaticTemplateContext(suitePath, suite.Variables)
+		providers, err := dryRunProviders(ctx, suite.Providers, suite.Agents, nil, staticCtx)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkServersAndAgents(suite.Servers, suite.Agents, providers, staticCtx); err != nil {
+			return nil, err
+		}
+
+		suiteDir := filepath.Dir(suitePath)
+		for _, testFile := range suite.TestFiles {
+			if !filepath.IsAbs(testFile) {
+				testFile = filepath.Join(suiteDir, testFile)
+			}
+			testConfig, err := loadDryRunTestConfig(testFile)
+			if err != nil {
+				return nil, err
+			}
+			testConfig.Settings = suite.Settings
+			testConfig.Variables = ResolveSuiteVariables(suite.Settings.VariablePolicy, suite.Variables, testConfig.Variables)
+			if err := ValidateTestConfig(testConfig, true); err != nil {
+				return nil, fmt.Errorf("invalid configuration in %s: %w", testFile, err)
+			}
+			if err := InitModelOverrides(ctx, providers, suite.Providers, suite.Agents, testConfig.Sessions, staticCtx); err != nil {
+				return nil, fmt.Errorf("failed to initialize model overrides in %s: %w", testFile, err)
+			}
+			tests, err := planTests(testFile, testConfig.Sessions, suite.Agents)
+			if err != nil {
+				return nil, err
+			}
+			plan = append(plan, tests...)
+		}
+	}
+
+	return plan, nil
+}
+
+// loadDryRunTestConfig parses a test file and expands its prompt files.
+func loadDryRunTestConfig(path string) (*model.TestConfiguration, error) {
+	if err := ValidateTestInputFile(path); err != nil {
+		return nil, fmt.Errorf("invalid input file: %w", err)
+	}
+	testConfig, err := model.ParseTestConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration %s: %w", path, err)
+	}
+	if err := ExpandPromptFiles(testConfig, path); err != nil {
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+	return testConfig, nil
+}
+
+// dryRunProviders creates the provider clients, including the clones for model
+// overrides, with warm-up turned off so no request is sent.
+func dryRunProviders(ctx context.Context, configs []model.Provider, agents []model.Agent, sessions []model.Session, templateCtx map[string]string) (map[string]llms.Model, error) {
+	configs = withoutWarmup(configs)
+	providers, err := InitProviders(ctx, configs, templateCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize providers: %w", err)
+	}
+	if err := InitModelOverrides(ctx, providers, configs, agents, sessions, templateCtx); err != nil {
+		return nil, fmt.Errorf("failed to initialize model overrides: %w", err)
+	}
+	return providers, nil
+}
+
+// withoutWarmup returns a copy of the provider configs with warm-up turned off.
+func withoutWarmup(configs []model.Provider) []model.Provider {
+	configs = slices.Clone(configs)
+	for i := range configs {
+		configs[i].Warmup = false
+	}
+	return configs
+}
+
+// checkServersAndAgents applies the name checks of InitServers and InitAgents
+// without starting any server: names must be set and unique, and agents must
+// reference existing providers.
+func checkServersAndAgents(servers []model.Server, agents []model.Agent, providers map[string]llms.Model, templateCtx map[string]string) error {
+	serverNames := make(map[string]bool, len(servers))
+	for i, s := range servers {
+		name := model.RenderTemplate(s.Name, templateCtx)
+		if name == "" {
+			return fmt.Errorf("server at index %d has empty name", i)
+		}
+		if serverNames[name] {
+			return fmt.Errorf("duplicate server name: %s", name)
+		}
+		serverNames[name] = true
+	}
+
+	agentNames := make(map[string]bool, len(agents))
+	for i, a := range agents {
+		if a.Name == "" {
+			return fmt.Errorf("agent at index %d has empty name", i)
+		}
+		if agentNames[a.Name] {
+			return fmt.Errorf("duplicate agent name: %s", a.Name)
+		}
+		agentNames[a.Name] = true
+
+		providerName := a.Provider
+		if a.Model != "" {
+			providerName = ModelProviderName(a.Provider, a.Model)
+		}
+		if _, ok := providers[providerName]; !ok {
+			return fmt.Errorf("provider '%s' not found for agent '%s'", providerName, a.Name)
+		}
+	}
+	return nil
+}
+
+// planTests lists the tests of sessions per agent in the order RunTests runs
+// them, and checks their prompts are valid templates.
+func planTests(sourceFile string, sessions []model.Session, agents []model.Agent) ([]PlannedTest, error) {
+	var plan []PlannedTest
+	for _, a := range agents {
+		for _, session := range sessions {
+			for _, test := range session.Tests {
+				if test.Agent != "" && !slices.ContainsFunc(agents, func(a model.Agent) bool { return a.Name == test.Agent }) {
+					return nil, fmt.Errorf("test '%s': agent '%s' not found", test.Name, test.Agent)
+				}
+				if test.Agent != "" && test.Agent != a.Name {
+					continue
+				}
+				prompts := []string{test.Prompt}
+				for _, turn := range test.Turns {
+					prompts = append(prompts, turn.Prompt)
+				}
+				for _, prompt := range prompts {
+					if err := model.ValidateTemplate(prompt); err != nil {
+						return nil, fmt.Errorf("test '%s': invalid prompt template: %w", test.Name, err)
+					}
+				}
+				runName := a.Name
+				if test.Model != "" {
+					runName = model.ModelRunName(a.Name, test.Model)
+				}
+				plan = append(plan, PlannedTest{
+					SourceFile: sourceFile,
+					Session:    session.Name,
+					Test:       test.Name,
+					Agent:      runName,
+				})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// PrintPlan writes the planned tests as a table followed by their count.
+func PrintPlan(w io.Writer, plan []PlannedTest) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSESSION\tTEST\tAGENT")
+	agents := make(map[string]bool)
+	for _, p := range plan {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", filepath.Base(p.SourceFile), p.Session, p.Test, p.Agent)
+		agents[p.Agent] = true
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nDry run OK: %d tests planned across %d agents, no providers called\n", len(plan), len(agents))
+}
@@ -2,19 +2,24 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/google/uuid"
 	"github.com/mykhaliev/agent-benchmark/agent"
 	"github.com/mykhaliev/agent-benchmark/logger"
@@ -22,12 +27,18 @@ import (
 	"github.com/mykhaliev/agent-benchmark/report"
 	"github.com/mykhaliev/agent-benchmark/server"
 	"github.com/mykhaliev/agent-benchmark/skill"
+	"github.com/mykhaliev/agent-benchmark/templates"
+	"github.com/mykhaliev/agent-benchmark/tokenizer"
+	"github.com/mykhaliev/agent-benchmark/version"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/anthropic"
 	"github.com/tmc/langchaingo/llms/bedrock"
 	"github.com/tmc/langchaingo/llms/googleai"
 	"github.com/tmc/langchaingo/llms/googleai/vertex"
+	"github.com/tmc/langchaingo/llms/mistral"
+	"github.com/tmc/langchaingo/llms/ollama"
 	"github.com/tmc/langchaingo/llms/openai"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -36,41 +47,187 @@ const (
 	DefaultTestDelay     = 0 * time.Second
 )
 
-func Run(testPath *string, verbose *bool, suitePath *string, reportFileName *string, reportTypes []string) {
+// Run executes the run cfg configures, writes the reports and exits the
+// process. The first SIGINT or SIGTERM stops the run and still writes a partial
+// report; a second one exits immediately.
+func Run(cfg RunConfig) {
+	ctx, stop := NotifyInterrupt(context.Background())
+	code := RunWithContext(ctx, cfg)
+	stop()
+	os.Exit(code)
+}
+
+// RunWithContext executes the run cfg configures and writes the reports,
+// returning the process exit code; see RunWithConfig for the run itself and
+// ExitCode for the codes.
+func RunWithContext(runCtx context.Context, cfg RunConfig) int {
+	if cfg.Logger != nil {
+		runCtx = logger.WithLogger(runCtx, cfg.Logger)
+	}
+	_, err := RunWithConfig(runCtx, cfg)
+	code := ExitCode(err)
+	if code == 1 && !errors.Is(err, ErrTestsFailed) && !errors.Is(err, ErrReports) {
+		logger.FromContext(runCtx).Error("Run failed", "error", err)
+	}
+	return code
+}
+
+// ExitCode maps the error returned by RunWithConfig to a process exit code:
+// 0 on success, InterruptedExitCode, DeadlineExitCode, or 1 for anything else.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrRunInterrupted):
+		return InterruptedExitCode
+	case errors.Is(err, ErrRunDeadline):
+		return DeadlineExitCode
+	default:
+		return 1
+	}
+}
+
+// RunConfig configures a run started with RunWithConfig.
+type RunConfig struct {
+	TestPath  string // Test file to run
+	SuitePath string // Suite file to run; with TestPath set, both run
+	Verbose   bool   // Enable verbose output of the agents, as settings.verbose does
+	// ReportTypes are the reports to write (see ValidateReportType); none writes
+	// no reports and skips the AI summary, which only appears in reports
+	ReportTypes    []string
+	ReportFileName string // Report path without extension; see ResolveReportBase
+	OutputDir      string // Report directory; see ResolveReportBase
+	// MaxDuration bounds the run: once it passes no new tests start, tests still
+	// running are cancelled after DeadlineGracePeriod and the unrun tests are
+	// reported as not executed
+	MaxDuration time.Duration
+	// Deadline is a hard ceiling on the run: tests still running when it passes
+	// are cancelled at once. It overrides the suite's deadline field
+	Deadline time.Duration
+	// UpdateGolden makes matches_golden assertions rewrite their golden files
+	// from this run instead of comparing against them
+	UpdateGolden bool
+	// KeepTemp leaves the directories holding test fixtures in place
+	KeepTemp bool
+	// ExportConversationsDir receives each test's conversation in the
+	// chat-completions message format
+	ExportConversationsDir string
+	// AssertionFilter selects the assertion types that are evaluated; the
+	// others are reported as disabled
+	AssertionFilter model.AssertionFilter
+	// Parallel overrides settings.concurrency of every test file in the run;
+	// 0 keeps each file's own setting
+	Parallel int
+	// Seed seeds the random template helpers; 0 uses a time-based seed. Either
+	// way it is logged and recorded in the run metadata. The helpers belong to
+	// the process, so runs started concurrently should share a seed
+	Seed int64
+	// Stream logs each iteration's tool calls and assistant messages as they
+	// happen
+	Stream bool
+	// GroupBy is what the HTML leaderboard and comparison matrix group runs by;
+	// empty groups by agent
+	GroupBy report.GroupBy
+	// MaxOutputLength clips final outputs and tool results in the HTML report
+	// to this many characters, expandable in the page; 0 keeps them whole
+	MaxOutputLength int
+	// Logger receives the run's log lines instead of logger.Logger
+	Logger *slog.Logger
+}
+
+var (
+	// ErrTestsFailed is returned when tests failed, or the pass rate missed the
+	// success_rate criteria.
+	ErrTestsFailed = errors.New("tests failed")
+	// ErrReports is returned when a requested report could not be written.
+	ErrReports = errors.New("failed to generate reports")
+)
+
+// RunWithConfig executes the configured tests and returns their results. It
+// never exits the process: configuration and initialization problems are
+// returned as errors. When ctx is cancelled, in-flight work is stopped,
+// servers are cleaned up and the tests completed so far are returned with
+// ErrRunInterrupted; a run cut short by MaxDuration or a deadline returns
+// ErrRunDeadline.
+// Otherwise a run whose tests failed returns ErrTestsFailed alongside the
+// results. Use ExitCode to map the error to the CLI's exit codes.
+func RunWithConfig(runCtx context.Context, cfg RunConfig) ([]model.TestRun, error) {
+	if cfg.Logger != nil {
+		runCtx = logger.WithLogger(runCtx, cfg.Logger)
+	}
+	log := logger.FromContext(runCtx)
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = NewRunSeed()
+	}
+	templates.SetSeed(seed)
+	log.Debug("Run seed", "seed", seed)
+	runCtx = WithParallel(runCtx, cfg.Parallel)
+	runCtx = WithStream(runCtx, cfg.Stream)
+	reportOptions := report.Options{GroupBy: cfg.GroupBy, MaxOutputLength: cfg.MaxOutputLength}
+
 	// Run tests
 	results := make([]model.TestRun, 0)
+	startTime := time.Now()
+
+	signalCtx := runCtx
+	runCtx, cancelDeadline := WithRunDeadline(runCtx, cfg.MaxDuration, DeadlineGracePeriod)
+	defer cancelDeadline()
+	if cfg.MaxDuration > 0 {
+		log.Info("Run deadline set", "max_duration", cfg.MaxDuration, "grace_period", DeadlineGracePeriod)
+	}
+	deadline := cfg.Deadline
+	runCtx, cancelHardDeadline := WithRunDeadline(runCtx, deadline, 0)
+	defer cancelHardDeadline()
+	if deadline > 0 {
+		log.Info("Run deadline set", "deadline", deadline)
+	}
+	runCtx = model.WithGoldenUpdate(runCtx, cfg.UpdateGolden)
+	runCtx = WithKeepTemp(runCtx, cfg.KeepTemp)
+	runCtx = model.WithAssertionFilter(runCtx, cfg.AssertionFilter)
+	if cfg.UpdateGolden {
+		log.Warn("Updating golden files: matches_golden assertions record this run instead of comparing")
+	}
+	if !cfg.AssertionFilter.IsZero() {
+		log.Warn("Assertion filter active: other assertions are reported as disabled",
+			"disabled", cfg.AssertionFilter.Disabled, "only", cfg.AssertionFilter.Only)
+	}
 
 	var criteria model.Criteria
-	if *testPath != "" {
+	var runMetadata *model.RunMetadata
+	var judgeLLMs []llms.Model
+	var judgePanel *model.JudgePanel
+	var pairwise model.PairwiseConfig
+	if cfg.TestPath != "" {
 		// Create a NEW context for each test file
-		ctx, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithCancel(runCtx)
 		defer cancel()
 		// Validate input file exists
-		if err := ValidateTestInputFile(*testPath); err != nil {
-			logger.Logger.Error("Invalid input file", "error", err)
-			os.Exit(1)
+		if err := ValidateTestInputFile(cfg.TestPath); err != nil {
+			return results, fmt.Errorf("invalid input file: %w", err)
 		}
 		// Load and validate test configuration
-		logger.Logger.Info("Loading test configuration")
-		testConfig, err := model.ParseTestConfig(*testPath)
+		log.Info("Loading test configuration")
+		testConfig, err := model.ParseTestConfig(cfg.TestPath)
 		if err != nil {
-			logger.Logger.Error("Failed to parse configuration", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to parse configuration: %w", err)
 		}
 		// Override verbose setting if command line flag is set
-		if *verbose {
+		if cfg.Verbose {
 			testConfig.Settings.Verbose = true
 		}
+		if err := ExpandPromptFiles(testConfig, cfg.TestPath); err != nil {
+			return results, fmt.Errorf("failed to load prompts: %w", err)
+		}
 		if err := ValidateTestConfig(testConfig, false); err != nil {
-			logger.Logger.Error("Invalid configuration", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("invalid configuration: %w", err)
 		}
 		totalTests := 0
 		for _, session := range testConfig.Sessions {
 			totalTests += len(session.Tests)
 		}
 
-		logger.Logger.Info("Configuration loaded",
+		log.Info("Configuration loaded",
 			"providers", len(testConfig.Providers),
 			"servers", len(testConfig.Servers),
 			"agents", len(testConfig.Agents),
@@ -79,29 +236,41 @@ func Run(testPath *string, verbose *bool, suitePath *string, reportFileName *str
 
 		// Create static template context early - includes env vars, TEST_DIR, user variables
 		// This enables templates like {{TEST_DIR}}/server.exe in server commands
-		staticCtx := CreateStaticTemplateContext(*testPath, testConfig.Variables)
+		staticCtx := CreateStaticTemplateContext(cfg.TestPath, testConfig.Variables)
+		// Run info lists the clones of model overrides next to their providers
+		overrideProviders, _ := ModelOverrideProviders(testConfig.Providers, testConfig.Agents, testConfig.Sessions)
+		runMetadata = BuildRunMetadata(startTime, seed, slices.Concat(testConfig.Providers, overrideProviders), staticCtx)
+		addJudgeMetadata(runMetadata, testConfig.Judge, testConfig.Judges, staticCtx)
+
+		// Judges are created apart from the agent providers and reach the
+		// assertion evaluator through the context
+		panel, llmJudges := InitJudgePanel(ctx, JudgeProviders(testConfig.Judge, testConfig.Judges), testConfig.Aggregation, staticCtx)
+		judgePanel, judgeLLMs = panel, llmJudges
+		ctx = model.WithJudgePanel(ctx, panel)
+		pairwise = testConfig.Pairwise
+		pairwise.CacheFile = PairwiseCachePath(pairwise.CacheFile, staticCtx)
 
 		// Initialize components using the passed context
 		providers, err := InitProviders(ctx, testConfig.Providers, staticCtx)
 		if err != nil {
-			logger.Logger.Error("Failed to initialize providers", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to initialize providers: %w", err)
+		}
+		if err := InitModelOverrides(ctx, providers, testConfig.Providers, testConfig.Agents, testConfig.Sessions, staticCtx); err != nil {
+			return results, fmt.Errorf("failed to initialize model overrides: %w", err)
 		}
 
 		// Collect required servers from agents
-		requiredServers := getRequiredServers(testConfig.Agents, testConfig.Servers)
+		requiredServers := getRequiredServers(ctx, testConfig.Agents, testConfig.Servers)
 		// Initialize only required servers
 		mcpServers, err := InitServers(ctx, requiredServers, staticCtx)
 		if err != nil {
-			logger.Logger.Error("Failed to initialize servers", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to initialize servers: %w", err)
 		}
 		defer CleanupServers(mcpServers)
 
 		agents, err := InitAgents(ctx, testConfig.Agents, mcpServers, providers)
 		if err != nil {
-			logger.Logger.Error("Failed to initialize agents", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to initialize agents: %w", err)
 		}
 
 		// Parse settings
@@ -110,75 +279,92 @@ func Run(testPath *string, verbose *bool, suitePath *string, reportFileName *str
 		sessionDelay := ParseDelay(testConfig.Settings.SessionDelay)
 		maxIterations := GetMaxIterations(testConfig.Settings.MaxIterations)
 
-		logger.Logger.Info("Test settings configured",
+		log.Info("Test settings configured",
 			"max_iterations", maxIterations,
 			"tool_timeout", toolTimeout,
 			"test_delay", testDelay,
 			"session_delay", sessionDelay,
+			"concurrency", RunConcurrency(ctx, testConfig.Settings.Concurrency),
 			"verbose", testConfig.Settings.Verbose)
 
 		// Run tests
-		logger.Logger.Info("Starting test execution")
-		testResults := RunTests(ctx, testConfig, agents, providers, maxIterations, toolTimeout, testDelay, sessionDelay, *testPath, "")
+		log.Info("Starting test execution")
+		testResults := RunTests(ctx, testConfig, agents, providers, maxIterations, toolTimeout, testDelay, sessionDelay, cfg.TestPath, "")
 		results = append(results, testResults...)
 		if len(testResults) > 0 {
 			criteria = testResults[0].TestCriteria
 		}
 	}
 
-	if *suitePath != "" {
-		if err := ValidateTestInputFile(*suitePath); err != nil {
-			logger.Logger.Error("Invalid input file", "error", err)
-			os.Exit(1)
+	if cfg.SuitePath != "" {
+		if err := ValidateTestInputFile(cfg.SuitePath); err != nil {
+			return results, fmt.Errorf("invalid input file: %w", err)
 		}
 
-		logger.Logger.Info("Loading test suite configuration")
-		testSuiteConfig, err := model.ParseSuiteConfig(*suitePath)
+		log.Info("Loading test suite configuration")
+		testSuiteConfig, err := model.ParseSuiteConfig(cfg.SuitePath)
 		if err != nil {
-			logger.Logger.Error("Failed to parse suite configuration", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to parse suite configuration: %w", err)
 		}
 		if err := ValidateSuiteConfig(testSuiteConfig); err != nil {
-			logger.Logger.Error("Invalid configuration", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("invalid configuration: %w", err)
 		}
 
 		if testSuiteConfig == nil || testSuiteConfig.TestFiles == nil {
-			logger.Logger.Error("No test files found in suite configuration")
-			os.Exit(1)
+			return results, errors.New("no test files found in suite configuration")
+		}
+		if deadline == 0 {
+			// Already validated by ValidateSuiteConfig
+			deadline, _ = ParseDeadline(testSuiteConfig.Deadline)
+			if deadline > 0 {
+				var cancelSuiteDeadline context.CancelFunc
+				runCtx, cancelSuiteDeadline = WithRunDeadline(runCtx, deadline, 0)
+				defer cancelSuiteDeadline()
+				log.Info("Run deadline set", "deadline", deadline, "source", "suite")
+			}
 		}
 		// Create a suite level context
-		ctx, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithCancel(runCtx)
 		defer cancel()
-		logger.Logger.Info("Running test suite", "name", testSuiteConfig.Name)
+		log.Info("Running test suite", "name", testSuiteConfig.Name)
 
 		// Create static template context early - includes env vars, TEST_DIR, user variables
 		// For suite, TEST_DIR is relative to the suite file (not individual test files)
 		// Test-level variables are not part of the static context.
-		staticCtx := CreateStaticTemplateContext(*suitePath, testSuiteConfig.Variables)
+		staticCtx := CreateStaticTemplateContext(cfg.SuitePath, testSuiteConfig.Variables)
+		// Run info lists the clones of model overrides next to their providers
+		overrideProviders, _ := ModelOverrideProviders(testSuiteConfig.Providers, testSuiteConfig.Agents, nil)
+		runMetadata = BuildRunMetadata(startTime, seed, slices.Concat(testSuiteConfig.Providers, overrideProviders), staticCtx)
+		addJudgeMetadata(runMetadata, testSuiteConfig.Judge, testSuiteConfig.Judges, staticCtx)
+
+		panel, llmJudges := InitJudgePanel(ctx, JudgeProviders(testSuiteConfig.Judge, testSuiteConfig.Judges), testSuiteConfig.Aggregation, staticCtx)
+		judgePanel, judgeLLMs = panel, llmJudges
+		ctx = model.WithJudgePanel(ctx, panel)
+		pairwise = testSuiteConfig.Pairwise
+		pairwise.CacheFile = PairwiseCachePath(pairwise.CacheFile, staticCtx)
 
 		// Initialize components using the passed context
 		providers, err := InitProviders(ctx, testSuiteConfig.Providers, staticCtx)
 		if err != nil {
-			logger.Logger.Error("Failed to initialize providers", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to initialize providers: %w", err)
+		}
+		if err := InitModelOverrides(ctx, providers, testSuiteConfig.Providers, testSuiteConfig.Agents, nil, staticCtx); err != nil {
+			return results, fmt.Errorf("failed to initialize model overrides: %w", err)
 		}
 
 		// Collect required servers from agents
-		requiredServers := getRequiredServers(testSuiteConfig.Agents, testSuiteConfig.Servers)
+		requiredServers := getRequiredServers(ctx, testSuiteConfig.Agents, testSuiteConfig.Servers)
 
 		// Initialize only required servers
 		mcpServers, err := InitServers(ctx, requiredServers, staticCtx)
 		if err != nil {
-			logger.Logger.Error("Failed to initialize servers", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to initialize servers: %w", err)
 		}
 		defer CleanupServers(mcpServers)
 
 		agents, err := InitAgents(ctx, testSuiteConfig.Agents, mcpServers, providers)
 		if err != nil {
-			logger.Logger.Error("Failed to initialize agents", "error", err)
-			os.Exit(1)
+			return results, fmt.Errorf("failed to initialize agents: %w", err)
 		}
 
 		// Parse settings
@@ -187,53 +373,49 @@ func Run(testPath *string, verbose *bool, suitePath *string, reportFileName *str
 		sessionDelay := ParseDelay(testSuiteConfig.Settings.SessionDelay)
 		maxIterations := GetMaxIterations(testSuiteConfig.Settings.MaxIterations)
 
-		logger.Logger.Info("Test settings configured",
+		log.Info("Test settings configured",
 			"max_iterations", maxIterations,
 			"tool_timeout", toolTimeout,
 			"test_delay", testDelay,
 			"session_delay", sessionDelay,
+			"concurrency", RunConcurrency(ctx, testSuiteConfig.Settings.Concurrency),
 			"verbose", testSuiteConfig.Settings.Verbose)
 
-		suiteDir := filepath.Dir(*suitePath)
+		suiteDir := filepath.Dir(cfg.SuitePath)
 		for _, testFile := range testSuiteConfig.TestFiles {
+			// Past the run deadline the remaining files still run through RunTests,
+			// which reports their tests as not executed
+			if ctx.Err() != nil && !RunDeadlineReached(ctx) {
+				log.Warn("Run interrupted, skipping remaining test files")
+				break
+			}
 			// Resolve relative paths against the suite file's directory.
 			if !filepath.IsAbs(testFile) {
 				testFile = filepath.Join(suiteDir, testFile)
 			}
 			// Validate input file exists
 			if err := ValidateTestInputFile(testFile); err != nil {
-				logger.Logger.Error("Invalid input file", "error", err)
-				os.Exit(1)
+				return results, fmt.Errorf("invalid input file: %w", err)
 			}
 			// Load and validate test configuration
-			logger.Logger.Info("Loading test configuration")
+			log.Info("Loading test configuration")
 			testConfig, err := model.ParseTestConfig(testFile)
 			if err != nil {
-				logger.Logger.Error("Failed to parse configuration", "error", err)
-				os.Exit(1)
+				return results, fmt.Errorf("failed to parse configuration: %w", err)
 			}
 			// Override verbose setting if command line flag is set
-			if *verbose {
+			if cfg.Verbose {
 				testConfig.Settings.Verbose = true
 			}
 			// override settings
 			testConfig.Settings = testSuiteConfig.Settings
-			// override variables
-			switch testSuiteConfig.Settings.VariablePolicy {
-			case model.MergeTestPriority:
-				testConfig.Variables = MergeVariables(testConfig.Variables, testSuiteConfig.Variables)
-			case model.MergeSuitePriority:
-				testConfig.Variables = MergeVariables(testSuiteConfig.Variables, testConfig.Variables)
-			case model.TestOnly:
-				break
-			case model.SuiteOnly, "":
-				fallthrough
-			default:
-				testConfig.Variables = testSuiteConfig.Variables
+			// combine suite-level and file-level variables
+			testConfig.Variables = ResolveSuiteVariables(testSuiteConfig.Settings.VariablePolicy, testSuiteConfig.Variables, testConfig.Variables)
+			if err := ExpandPromptFiles(testConfig, testFile); err != nil {
+				return results, fmt.Errorf("failed to load prompts: %w", err)
 			}
 			if err := ValidateTestConfig(testConfig, true); err != nil {
-				logger.Logger.Error("Invalid configuration", "error", err)
-				os.Exit(1)
+				return results, fmt.Errorf("invalid configuration: %w", err)
 			}
 
 			totalTests := 0
@@ -241,197 +423,188 @@ func Run(testPath *string, verbose *bool, suitePath *string, reportFileName *str
 				totalTests += len(session.Tests)
 			}
 
-			logger.Logger.Info("Configuration loaded",
+			log.Info("Configuration loaded",
 				"providers", len(testConfig.Providers),
 				"servers", len(testConfig.Servers),
 				"agents", len(testConfig.Agents),
 				"sessions", len(testConfig.Sessions),
 				"tests", totalTests)
+			// Tests of this file may override the model of the suite's agents
+			if err := InitModelOverrides(ctx, providers, testSuiteConfig.Providers, testSuiteConfig.Agents, testConfig.Sessions, staticCtx); err != nil {
+				return results, fmt.Errorf("failed to initialize model overrides: %w", err)
+			}
 			// Run tests
-			logger.Logger.Info("Starting test execution")
+			log.Info("Starting test execution")
 			testResults := RunTests(ctx, testConfig, agents, providers, maxIterations, toolTimeout, testDelay, sessionDelay, testFile, testSuiteConfig.Name)
 			results = append(results, testResults...)
 		}
 		criteria = testSuiteConfig.TestCriteria
 	}
 
+	interrupted := signalCtx.Err() != nil
+	truncated := !interrupted && RunDeadlineReached(runCtx) && (runDeadlineExpired(runCtx) || countNotExecuted(results) > 0)
+	if runMetadata != nil {
+		runMetadata.EndTime = time.Now()
+		runMetadata.Interrupted = interrupted
+		runMetadata.Truncated = truncated
+		runMetadata.AssertionDurationMs = model.TotalAssertionDurationMs(results)
+	}
+	if interrupted {
+		log.Warn("Run interrupted, writing a partial report", "completed_tests", len(results))
+	}
+	if truncated {
+		log.Warn("Run deadline reached, writing a partial report",
+			"max_duration", cfg.MaxDuration,
+			"deadline", deadline,
+			"not_executed", countNotExecuted(results))
+	}
+
 	// AI Summary (optional LLM-powered executive summary)
 	var aiSummaryResult *agent.AISummaryResult
-	aiSummaryConfig := getAISummaryConfig(*testPath, *suitePath)
-	if aiSummaryConfig != nil && aiSummaryConfig.Enabled {
-		logger.Logger.Info("Generating AI summary")
-
-		// Create a context for AI summary
-		analysisBaseCtx := context.Background()
-
-		// Resolve judge LLM for AI summary
-		var judgeLLM llms.Model
-		judgeProvider := aiSummaryConfig.JudgeProvider
-		if judgeProvider == "" {
-			logger.Logger.Error("AI summary enabled but judge_provider not specified")
-		} else if judgeProvider == "$self" {
-			// "$self" means use the same provider as the first agent that ran
-			// Extract from first test result
-			if len(results) > 0 {
-				firstProvider := string(results[0].Execution.ProviderType)
-				logger.Logger.Debug("Using first agent's provider for AI summary", "provider", firstProvider)
-				// Re-initialize just this provider for analysis
-				staticCtx := CreateStaticTemplateContext(*testPath, nil)
-				if *suitePath != "" {
-					staticCtx = CreateStaticTemplateContext(*suitePath, nil)
-				}
-				// Load config to get provider settings
-				var providerConfig []model.Provider
-				if *testPath != "" {
-					if tc, err := model.ParseTestConfig(*testPath); err == nil {
-						providerConfig = tc.Providers
-					}
-				} else if *suitePath != "" {
-					if sc, err := model.ParseSuiteConfig(*suitePath); err == nil {
-						providerConfig = sc.Providers
-					}
-				}
-				for _, p := range providerConfig {
-					if p.Name == firstProvider {
-						initProviders, err := InitProviders(analysisBaseCtx, []model.Provider{p}, staticCtx)
-						if err == nil {
-							judgeLLM = initProviders[p.Name]
-						}
-						break
-					}
-				}
-			}
+	// The summary is only shown in reports
+	var aiSummaryConfig *model.AISummary
+	if len(cfg.ReportTypes) > 0 {
+		aiSummaryConfig = getAISummaryConfig(cfg.TestPath, cfg.SuitePath)
+	}
+	if (interrupted || truncated) && aiSummaryConfig != nil && aiSummaryConfig.Enabled {
+		log.Info("AI summary skipped for partial run")
+	} else if aiSummaryConfig != nil && aiSummaryConfig.Enabled {
+		log.Info("Generating AI summary")
+
+		// Resolve the judges for the AI summary: the top-level judges win, the
+		// deprecated ai_summary.judge_provider is kept for existing configs
+		configPath := cfg.TestPath
+		if cfg.SuitePath != "" {
+			configPath = cfg.SuitePath
+		}
+		if judgeCfg, err := loadJudgeConfig(configPath); err != nil {
+			log.Error("Failed to load AI summary configuration", "error", err)
 		} else {
-			// Look up the specified provider by name and initialize it
-			staticCtx := CreateStaticTemplateContext(*testPath, nil)
-			if *suitePath != "" {
-				staticCtx = CreateStaticTemplateContext(*suitePath, nil)
-			}
-			var providerConfig []model.Provider
-			if *testPath != "" {
-				if tc, err := model.ParseTestConfig(*testPath); err == nil {
-					providerConfig = tc.Providers
-				}
-			} else if *suitePath != "" {
-				if sc, err := model.ParseSuiteConfig(*suitePath); err == nil {
-					providerConfig = sc.Providers
-				}
-			}
-			for _, p := range providerConfig {
-				if p.Name == judgeProvider {
-					initProviders, err := InitProviders(analysisBaseCtx, []model.Provider{p}, staticCtx)
-					if err == nil {
-						judgeLLM = initProviders[p.Name]
-						logger.Logger.Debug("Using separate provider for AI summary", "judge_provider", judgeProvider)
-					} else {
-						logger.Logger.Error("Failed to initialize judge provider", "error", err)
-					}
-					break
-				}
-			}
-			if judgeLLM == nil {
-				logger.Logger.Error("AI summary judge provider not found", "judge_provider", judgeProvider)
+			// Each judge's attempt is bounded by GenerateAISummary's own timeout
+			analysisCtx := context.WithoutCancel(runCtx)
+			judgeCfg.summary = *aiSummaryConfig
+			judges := summaryJudges(analysisCtx, judgeLLMs, judgeCfg, results)
+			aiSummaryResult = agent.GenerateAISummaryWithJudges(analysisCtx, judges, model.ExecutedRuns(results))
+		}
+		if aiSummaryResult != nil {
+			if aiSummaryResult.Success {
+				log.Info("AI summary completed successfully")
+			} else {
+				log.Warn("AI summary failed", "error", aiSummaryResult.Error)
 			}
 		}
+	}
 
-		if judgeLLM != nil {
-			analysisCtx, cancel := context.WithTimeout(analysisBaseCtx, 90*time.Second)
-			analysisResult := agent.GenerateAISummary(analysisCtx, judgeLLM, results)
-			cancel()
-			aiSummaryResult = &analysisResult
-			if analysisResult.Success {
-				logger.Logger.Info("AI summary completed successfully")
-			} else {
-				logger.Logger.Warn("AI summary failed", "error", analysisResult.Error)
-			}
+	// Pairwise comparison (optional head-to-head judging of the agents' answers)
+	if pairwise.Enabled {
+		if interrupted || truncated {
+			log.Info("Pairwise comparison skipped for partial run")
+		} else {
+			RunPairwiseAnalysis(runCtx, judgePanel, pairwise, results)
+		}
+	}
+
+	if cfg.ExportConversationsDir != "" {
+		if _, err := ExportConversations(results, cfg.ExportConversationsDir); err != nil {
+			log.Error("Failed to export conversations", "error", err)
 		}
 	}
 
 	// Generate and save reports
-	logger.Logger.Info("Generating reports")
+	var reportErr error
+	if len(cfg.ReportTypes) > 0 {
+		log.Info("Generating reports")
 
-	// Determine report output directory
-	// Default to test_results folder in the test file's directory
-	var reportDir string
-	if *reportFileName == "" {
-		var testDir string
-		if *testPath != "" {
-			absPath, err := filepath.Abs(*testPath)
-			if err == nil {
-				testDir = filepath.Dir(absPath)
-			}
-		} else if *suitePath != "" {
-			absPath, err := filepath.Abs(*suitePath)
-			if err == nil {
-				testDir = filepath.Dir(absPath)
-			}
+		// Determine report output path
+		runID := ""
+		if runMetadata != nil {
+			runID = runMetadata.RunID
 		}
-		if testDir != "" {
-			reportDir = filepath.Join(testDir, "test_results")
-			// Create the directory if it doesn't exist
-			if err := os.MkdirAll(reportDir, 0755); err != nil {
-				logger.Logger.Error("Failed to create test_results directory", "error", err)
-				os.Exit(1)
-			}
-			*reportFileName = filepath.Join(reportDir, "report")
-		} else {
-			*reportFileName = "report"
+		reportBase, err := ResolveReportBase(cfg.ReportFileName, cfg.OutputDir, cfg.TestPath, cfg.SuitePath, runID, startTime)
+		if err != nil {
+			return results, fmt.Errorf("%w: failed to prepare report directory: %w", ErrReports, err)
 		}
-	}
 
-	for _, rt := range reportTypes {
-		reportFileNameWithExt := *reportFileName + "." + rt
 		// Determine source test file path for JSON metadata
 		configFilePath := ""
-		if *testPath != "" {
-			configFilePath = *testPath
-		} else if *suitePath != "" {
-			configFilePath = *suitePath
+		if cfg.TestPath != "" {
+			configFilePath = cfg.TestPath
+		} else if cfg.SuitePath != "" {
+			configFilePath = cfg.SuitePath
+		}
+		reportPaths := make([]string, 0, len(cfg.ReportTypes))
+		for _, rt := range cfg.ReportTypes {
+			if rt == "mermaid" {
+				reportPaths = append(reportPaths, MermaidDiagramPaths(results, reportBase+".mmd")...)
+				continue
+			}
+			reportPaths = append(reportPaths, reportBase+"."+ReportExtension(rt))
 		}
-		if err := GenerateReports(results, rt, reportFileNameWithExt, aiSummaryResult, configFilePath); err != nil {
-			logger.Logger.Error("Failed to generate reports", "error", err)
-			os.Exit(1)
+		log.Info("Writing reports", "paths", strings.Join(reportPaths, ", "))
+		if err := GenerateAllReportsWithOptions(runCtx, results, cfg.ReportTypes, reportBase, aiSummaryResult, configFilePath, runMetadata, reportOptions); err != nil {
+			log.Error("Failed to generate reports", "error", err)
+			reportErr = fmt.Errorf("%w: %w", ErrReports, err)
 		}
 	}
 
-	// Exit with appropriate code
+	if interrupted {
+		return results, errors.Join(ErrRunInterrupted, reportErr)
+	}
+	if truncated {
+		return results, errors.Join(ErrRunDeadline, reportErr)
+	}
+	if reportErr != nil {
+		return results, reportErr
+	}
+	return results, checkCriteria(runCtx, criteria, results)
+}
+
+// checkCriteria returns an ErrTestsFailed error when the results miss the
+// success rate of criteria, or without one, when any executed test failed.
+func checkCriteria(ctx context.Context, criteria model.Criteria, results []model.TestRun) error {
+	log := logger.FromContext(ctx)
 	if criteria.SuccessRate == "" {
 		if HasFailures(results) {
-			logger.Logger.Warn("Tests completed with failures")
-			os.Exit(1)
+			log.Warn("Tests completed with failures")
+			return fmt.Errorf("%w: some tests failed", ErrTestsFailed)
 		}
 	} else {
 		successRate, err := strconv.ParseFloat(criteria.SuccessRate, 64)
 		if err != nil {
-			logger.Logger.Error("Failed to parse criteria success rate", "error", err)
+			log.Error("Failed to parse criteria success rate", "error", err)
 			if HasFailures(results) {
-				logger.Logger.Warn("Tests completed with failures")
-				os.Exit(1)
+				log.Warn("Tests completed with failures")
+				return fmt.Errorf("%w: some tests failed", ErrTestsFailed)
 			}
 		}
+		// Skipped tests count toward neither side of the success rate
+		executed := model.ExecutedRuns(results)
+		if len(executed) == 0 {
+			log.Info("All tests were skipped")
+			return nil
+		}
 		passedTests := 0
-		failedTests := 0
-		for _, result := range results {
+		for _, result := range executed {
 			if result.Passed {
 				passedTests++
-			} else {
-				failedTests++
 			}
 		}
-		passRate := float64(passedTests) / float64(len(results))
+		passRate := float64(passedTests) / float64(len(executed))
+		if criteria.Weighted {
+			passRate = model.WeightedPassRate(executed)
+		}
 		if successRate <= passRate {
-			logger.Logger.Info("Tests suite success rate matched", "criteria", successRate, "actual", passRate)
-			os.Exit(0)
-		} else {
-			logger.Logger.Warn("Tests suite success rate not matched", "criteria", successRate, "actual", passRate)
-			os.Exit(1)
+			log.Info("Tests suite success rate matched", "criteria", successRate, "actual", passRate, "weighted", criteria.Weighted)
+			return nil
 		}
+		log.Warn("Tests suite success rate not matched", "criteria", successRate, "actual", passRate, "weighted", criteria.Weighted)
+		return fmt.Errorf("%w: success rate %.2f below the criteria %.2f", ErrTestsFailed, passRate, successRate)
 	}
-	logger.Logger.Info("All tests passed successfully")
-	os.Exit(0)
+	log.Info("All tests passed successfully")
+	return nil
 }
 
-func getRequiredServers(agents []model.Agent, allServers []model.Server) []model.Server {
+func getRequiredServers(ctx context.Context, agents []model.Agent, allServers []model.Server) []model.Server {
 	// Collect unique server names used by agents
 	usedServerNames := make(map[string]bool)
 	for _, agent := range agents {
@@ -448,14 +621,14 @@ func getRequiredServers(agents []model.Agent, allServers []model.Server) []model
 		if usedServerNames[server.Name] {
 			requiredServers = append(requiredServers, server)
 		} else {
-			logger.Logger.Warn("Server defined but not used by any agent, will not be initialized",
+			logger.FromContext(ctx).Warn("Server defined but not used by any agent, will not be initialized",
 				"server_name", server.Name,
 				"server_type", server.Type)
 			unusedCount++
 		}
 	}
 
-	logger.Logger.Debug("Filtered servers",
+	logger.FromContext(ctx).Debug("Filtered servers",
 		"total_defined", len(allServers),
 		"required", len(requiredServers),
 		"unused", unusedCount)
@@ -524,6 +697,133 @@ func ValidateTestConfig(config *model.TestConfiguration, runningFromSuite bool)
 		return fmt.Errorf("no sessions configured")
 	}
 
+	if err := validateProviders(config.Providers); err != nil {
+		return err
+	}
+
+	switch config.Settings.ToolTimeoutPolicy {
+	case "", model.ToolTimeoutContinue, model.ToolTimeoutFail:
+	default:
+		return fmt.Errorf("invalid tool_timeout_policy %q (expected %q or %q)",
+			config.Settings.ToolTimeoutPolicy, model.ToolTimeoutContinue, model.ToolTimeoutFail)
+	}
+
+	if config.Settings.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d (must be 0 or greater)", config.Settings.Concurrency)
+	}
+
+	if err := validateJudgeAggregation(config.Aggregation); err != nil {
+		return err
+	}
+
+	for _, session := range config.Sessions {
+		if len(session.Assertions) > 0 && session.PromptsFile == "" {
+			return fmt.Errorf("session '%s': assertions are only applied to tests from prompts_file", session.Name)
+		}
+		if session.Retries < 0 {
+			return fmt.Errorf("session '%s': invalid retries %d (must be 0 or greater)", session.Name, session.Retries)
+		}
+		if err := validateAssertions(fmt.Sprintf("session '%s'", session.Name), session.Assertions); err != nil {
+			return err
+		}
+		for _, test := range session.Tests {
+			if err := validateAssertions(fmt.Sprintf("test '%s'", test.Name), test.Assertions); err != nil {
+				return err
+			}
+			for i, turn := range test.Turns {
+				if err := validateAssertions(fmt.Sprintf("test '%s' turn %d", test.Name, i+1), turn.Assertions); err != nil {
+					return err
+				}
+			}
+			if test.Retries != nil && *test.Retries < 0 {
+				return fmt.Errorf("test '%s': invalid retries %d (must be 0 or greater)", test.Name, *test.Retries)
+			}
+			if test.Weight != nil && *test.Weight <= 0 {
+				return fmt.Errorf("test '%s': invalid weight %g (must be greater than 0; omit it for the default of %g)", test.Name, *test.Weight, model.DefaultTestWeight)
+			}
+			switch test.AssertionMode {
+			case "", model.AssertionModeAll, model.AssertionModeFirstFail:
+			default:
+				return fmt.Errorf("test '%s': invalid assertion_mode %q (expected %q or %q)",
+					test.Name, test.AssertionMode, model.AssertionModeAll, model.AssertionModeFirstFail)
+			}
+		}
+	}
+
+	// Suite runs use the suite's agents, so only standalone files are cross-checked
+	if !runningFromSuite {
+		if err := validateAgentReferences(config.Agents, config.Providers, config.Servers, config.Variables); err != nil {
+			return err
+		}
+		_, err := ModelOverrideProviders(config.Providers, config.Agents, config.Sessions)
+		return err
+	}
+	return nil
+}
+
+// validateAssertions checks assertion settings that are wrong whatever the run
+// produces, including nested anyOf, allOf and not assertions.
+func validateAssertions(label string, assertions []model.Assertion) error {
+	for _, a := range assertions {
+		switch a.Type {
+		case "output_one_of":
+			for i, value := range a.Values {
+				if strings.TrimSpace(value) == "" {
+					return fmt.Errorf("%s: output_one_of value %d is empty (an empty candidate matches any output)", label, i+1)
+				}
+			}
+		case "tool_param_matches_regex", "tool_param_regex":
+			if a.Type == "tool_param_regex" && (a.Param == "" || a.Pattern == "") {
+				return fmt.Errorf("%s: tool_param_regex requires a 'param' and a 'pattern'", label)
+			}
+			if a.Match != "" && a.Match != "any" && a.Match != "all" {
+				return fmt.Errorf("%s: %s has invalid match '%s' (must be 'any' or 'all')", label, a.Type, a.Match)
+			}
+		case "tool_call_count":
+			if err := validateToolCallCountBounds(a); err != nil {
+				return fmt.Errorf("%s: tool_call_count for '%s': %w", label, a.Tool, err)
+			}
+		}
+		if err := validateAssertions(label, a.AnyOf); err != nil {
+			return err
+		}
+		if err := validateAssertions(label, a.AllOf); err != nil {
+			return err
+		}
+		if a.Not != nil {
+			if err := validateAssertions(label, []model.Assertion{*a.Not}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateToolCallCountBounds rejects tool_call_count bounds that no count can
+// satisfy: negative values, min above max, and exact outside min..max.
+func validateToolCallCountBounds(a model.Assertion) error {
+	if a.Count < 0 {
+		return fmt.Errorf("count must not be negative (got %d)", a.Count)
+	}
+	for _, bound := range []struct {
+		name  string
+		value *int
+	}{{"min", a.Min}, {"max", a.Max}, {"exact", a.Exact}} {
+		if bound.value != nil && *bound.value < 0 {
+			return fmt.Errorf("%s must not be negative (got %d)", bound.name, *bound.value)
+		}
+	}
+	if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+		return fmt.Errorf("min (%d) is greater than max (%d)", *a.Min, *a.Max)
+	}
+	if a.Exact != nil {
+		if a.Min != nil && *a.Exact < *a.Min {
+			return fmt.Errorf("exact (%d) is less than min (%d)", *a.Exact, *a.Min)
+		}
+		if a.Max != nil && *a.Exact > *a.Max {
+			return fmt.Errorf("exact (%d) is greater than max (%d)", *a.Exact, *a.Max)
+		}
+	}
 	return nil
 }
 
@@ -552,22 +852,125 @@ func ValidateSuiteConfig(config *model.TestSuiteConfiguration) error {
 		return fmt.Errorf("no agents configured")
 	}
 
+	if config.Settings.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d (must be 0 or greater)", config.Settings.Concurrency)
+	}
+
+	if err := validateJudgeAggregation(config.Aggregation); err != nil {
+		return err
+	}
+
+	if _, err := ParseDeadline(config.Deadline); err != nil {
+		return err
+	}
+
+	if err := validateProviders(config.Providers); err != nil {
+		return err
+	}
+
+	if err := validateAgentReferences(config.Agents, config.Providers, config.Servers, config.Variables); err != nil {
+		return err
+	}
+	_, err := ModelOverrideProviders(config.Providers, config.Agents, nil)
+	return err
+}
+
+// validateAgentReferences checks that every agent's provider and servers are
+// defined. Providers and servers are registered under their rendered names, so
+// both sides are compared after template rendering.
+func validateAgentReferences(agents []model.Agent, providers []model.Provider, servers []model.Server, variables map[string]string) error {
+	templateCtx := CreateStaticTemplateContext("", variables)
+
+	providerNames := make(map[string]bool, len(providers))
+	for _, p := range providers {
+		providerNames[model.RenderTemplate(p.Name, templateCtx)] = true
+	}
+	serverNames := make(map[string]bool, len(servers))
+	for _, srv := range servers {
+		serverNames[model.RenderTemplate(srv.Name, templateCtx)] = true
+	}
+
+	for _, a := range agents {
+		provider := model.RenderTemplate(a.Provider, templateCtx)
+		if provider == "" {
+			return fmt.Errorf("agent '%s' has no provider", a.Name)
+		}
+		if !providerNames[provider] {
+			return fmt.Errorf("agent '%s' references unknown provider '%s'", a.Name, provider)
+		}
+		for _, srv := range a.Servers {
+			if name := model.RenderTemplate(srv.Name, templateCtx); !serverNames[name] {
+				return fmt.Errorf("agent '%s' references unknown server '%s'", a.Name, name)
+			}
+		}
+		switch a.OnToolCollision {
+		case "", model.ToolCollisionPrefix, model.ToolCollisionError:
+		default:
+			return fmt.Errorf("agent '%s': invalid on_tool_collision %q (expected %q or %q)",
+				a.Name, a.OnToolCollision, model.ToolCollisionPrefix, model.ToolCollisionError)
+		}
+	}
+	return nil
+}
+
+// validateJudgeAggregation checks judge_aggregation names a known aggregation.
+func validateJudgeAggregation(aggregation model.JudgeAggregation) error {
+	switch aggregation {
+	case "", model.JudgeAggregationMajority, model.JudgeAggregationMean, model.JudgeAggregationMedian:
+		return nil
+	}
+	return fmt.Errorf("invalid judge_aggregation %q (expected %q, %q or %q)", aggregation,
+		model.JudgeAggregationMajority, model.JudgeAggregationMean, model.JudgeAggregationMedian)
+}
+
+// validateProviders checks every provider's retry settings and that the
+// options it sets are supported by its type. prompt_cache is not checked: it
+// is ignored with a warning where unsupported, so shared provider blocks can
+// set it for every type.
+func validateProviders(providers []model.Provider) error {
+	for _, p := range providers {
+		if _, err := ResolveRetryPolicy(p.Retry); err != nil {
+			return fmt.Errorf("provider '%s': %w", p.Name, err)
+		}
+		if p.Proxy != "" && !supportsProxy(p.Type) {
+			return fmt.Errorf("provider '%s': proxy is not supported by type %s; set HTTPS_PROXY in the environment instead", p.Name, p.Type)
+		}
+	}
 	return nil
 }
 
+// supportsProxy reports whether a provider type's client can be routed through
+// the provider's proxy. VERTEX (gRPC) and MISTRAL only honour the environment.
+func supportsProxy(providerType model.ProviderType) bool {
+	return providerType != model.ProviderVertex && providerType != model.ProviderMistral
+}
+
 func ValidateReportType(reportType string) error {
-	if reportType != "json" && reportType != "html" && reportType != "md" {
-		return fmt.Errorf("unknown type %s, supported types are: json, html, md", reportType)
+	if reportType != "json" && reportType != "html" && reportType != "md" && reportType != "txt" && reportType != "sarif" && reportType != "junit" && reportType != "csv" && reportType != "mermaid" {
+		return fmt.Errorf("unknown type %s, supported types are: json, html, md, txt, sarif, junit, csv, mermaid", reportType)
 	}
 	return nil
 }
 
+// ReportExtension returns the file extension of a report type; junit reports
+// are written as .xml and mermaid diagrams as .mmd, the others use the type name.
+func ReportExtension(reportType string) string {
+	switch reportType {
+	case "junit":
+		return "xml"
+	case "mermaid":
+		return "mmd"
+	}
+	return reportType
+}
+
 func InitProviders(ctx context.Context, providerConfigs []model.Provider, templateCtx map[string]string) (map[string]llms.Model, error) {
+	log := logger.FromContext(ctx)
 	if len(providerConfigs) == 0 {
 		return nil, fmt.Errorf("no providers to initialize")
 	}
 
-	logger.Logger.Info("Initializing providers", "count", len(providerConfigs))
+	log.Info("Initializing providers", "count", len(providerConfigs))
 	providers := make(map[string]llms.Model)
 
 	for i, p := range providerConfigs {
@@ -582,7 +985,8 @@ func InitProviders(ctx context.Context, providerConfigs []model.Provider, templa
 		p.Location = model.RenderTemplate(p.Location, templateCtx)
 		p.CredentialsPath = model.RenderTemplate(p.CredentialsPath, templateCtx)
 		p.AuthType = model.RenderTemplate(p.AuthType, templateCtx)
-		logger.Logger.Debug("Initializing provider",
+		p.Proxy = model.RenderTemplate(p.Proxy, templateCtx)
+		log.Debug("Initializing provider",
 			"index", i+1,
 			"total", len(providerConfigs),
 			"name", p.Name,
@@ -603,17 +1007,100 @@ func InitProviders(ctx context.Context, providerConfigs []model.Provider, templa
 		}
 
 		providers[p.Name] = llmModel
-		logger.Logger.Info("Provider initialized", "name", p.Name)
+		log.Info("Provider initialized", "name", p.Name)
+
+		if p.Warmup {
+			WarmUpProvider(ctx, p.Name, llmModel)
+		}
 	}
 
-	logger.Logger.Info("All providers initialized", "count", len(providers))
+	log.Info("All providers initialized", "count", len(providers))
 	return providers, nil
 }
 
+// InitJudge creates the judge LLM from the top-level judge provider config. It is
+// built on its own so the judge never shares a client with an agent under test.
+func InitJudge(ctx context.Context, judge model.Provider, templateCtx map[string]string) (llms.Model, error) {
+	if judge.Name == "" {
+		judge.Name = "judge"
+	}
+	providers, err := InitProviders(ctx, []model.Provider{judge}, templateCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize judge: %w", err)
+	}
+	for _, llm := range providers {
+		logger.FromContext(ctx).Info("Judge initialized", "type", judge.Type, "model", model.RenderTemplate(judge.Model, templateCtx))
+		return llm, nil
+	}
+	return nil, fmt.Errorf("judge provider was not created")
+}
+
+// JudgeProviders returns the configured judges: judge first, then judges.
+func JudgeProviders(judge *model.Provider, judges []model.Provider) []model.Provider {
+	var all []model.Provider
+	if judge != nil {
+		all = append(all, *judge)
+	}
+	for i, j := range judges {
+		if j.Name == "" {
+			j.Name = fmt.Sprintf("judge-%d", i+1)
+		}
+		all = append(all, j)
+	}
+	return all
+}
+
+// InitJudgePanel creates every judge and the panel used by judge-based assertions.
+// A judge that fails to initialize is logged and left out. It also returns the
+// judge models in configuration order for the AI summary.
+func InitJudgePanel(ctx context.Context, judges []model.Provider, aggregation model.JudgeAggregation, templateCtx map[string]string) (*model.JudgePanel, []llms.Model) {
+	if len(judges) == 0 {
+		return nil, nil
+	}
+	panel := &model.JudgePanel{Aggregation: aggregation}
+	var llmJudges []llms.Model
+	for _, j := range judges {
+		llm, err := InitJudge(ctx, j, templateCtx)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to initialize judge", "name", j.Name, "error", err)
+			continue
+		}
+		name := j.Name
+		if name == "" {
+			name = "judge"
+		}
+		panel.Judges = append(panel.Judges, agent.NewLLMJudge(model.RenderTemplate(name, templateCtx), llm))
+		llmJudges = append(llmJudges, llm)
+	}
+	return panel, llmJudges
+}
+
+// DefaultBaseURLs are the endpoints used by provider types that accept a baseUrl
+// when none is configured. AZURE has no default and requires one; GOOGLE, VERTEX,
+// BEDROCK and AMAZON-ANTHROPIC take no base URL.
+var DefaultBaseURLs = map[model.ProviderType]string{
+	model.ProviderOpenAI:    "https://api.openai.com/v1",
+	model.ProviderGroq:      "https://api.groq.com/openai/v1",
+	model.ProviderAnthropic: "https://api.anthropic.com/v1",
+	model.ProviderMistral:   "https://api.mistral.ai",
+	model.ProviderOllama:    "http://localhost:11434",
+}
+
+// ResolveBaseURL returns the base URL a provider sends requests to: its baseUrl
+// when set, otherwise the default for its type. overridden reports whether the
+// configured value was used. The URL is empty for types without a default.
+func ResolveBaseURL(p model.Provider) (url string, overridden bool) {
+	if p.BaseURL != "" {
+		return p.BaseURL, true
+	}
+	return DefaultBaseURLs[p.Type], false
+}
+
 func CreateProvider(ctx context.Context, p model.Provider) (llms.Model, error) {
-	// Token validation: required for all providers except Vertex and Azure with Entra ID auth
+	log := logger.FromContext(ctx)
+	// Token validation: required for all providers except Vertex, local Ollama and Azure with Entra ID auth
 	isEntraIdAuth := p.Type == model.ProviderAzure && strings.ToLower(p.AuthType) == "entra_id"
-	if p.Type != model.ProviderVertex && !isEntraIdAuth && p.Token == "" {
+	if p.Type != model.ProviderVertex && p.Type != model.ProviderOllama && !isEntraIdAuth && p.Token == "" {
 		return nil, fmt.Errorf("provider token is empty")
 	}
 
@@ -621,30 +1108,56 @@ func CreateProvider(ctx context.Context, p model.Provider) (llms.Model, error) {
 		return nil, fmt.Errorf("provider model is empty")
 	}
 
+	// Route provider traffic through the configured or environment proxy
+	if p.Proxy != "" && !supportsProxy(p.Type) {
+		return nil, fmt.Errorf("proxy is not supported by provider type %s; set HTTPS_PROXY in the environment instead", p.Type)
+	}
+	proxyClient, err := server.NewProxyHTTPClient(p.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	if proxyClient != nil {
+		log.Debug("Using proxy for provider", "provider", p.Name)
+	}
+
 	// Create custom HTTP client for Retry-After header capture if retry is enabled
 	var retryAfterClient *RetryAfterHTTPClient
 	if p.Retry.RetryOn429 {
-		retryAfterClient = NewRetryAfterHTTPClient(nil)
-		logger.Logger.Debug("Created Retry-After HTTP client for header capture", "provider", p.Name)
+		retryAfterClient = NewRetryAfterHTTPClient(proxyClient)
+		log.Debug("Created Retry-After HTTP client for header capture", "provider", p.Name)
+	}
+
+	// httpClient is the client handed to providers: the Retry-After wrapper when
+	// retry is enabled, otherwise the proxy client if one is configured
+	var httpClient httpDoer
+	if retryAfterClient != nil {
+		httpClient = retryAfterClient
+	} else if proxyClient != nil {
+		httpClient = proxyClient
+	}
+
+	// Log which endpoint is used so a wrong one stands out
+	baseURL, overridden := ResolveBaseURL(p)
+	if baseURL != "" {
+		source := "default"
+		if overridden {
+			source = "override"
+		}
+		log.Debug("Using provider base URL",
+			"provider", p.Name, "type", p.Type, "url", redactURL(baseURL), "source", source)
 	}
 
 	var llmModel llms.Model
-	var err error
 
 	switch p.Type {
 	case model.ProviderGroq:
 		opts := []openai.Option{
 			openai.WithToken(p.Token),
 			openai.WithModel(p.Model),
+			openai.WithBaseURL(baseURL),
 		}
-		if retryAfterClient != nil {
-			opts = append(opts, openai.WithHTTPClient(retryAfterClient))
-		}
-		if p.BaseURL != "" {
-			opts = append(opts, openai.WithBaseURL(p.BaseURL))
-			logger.Logger.Debug("Using custom base URL", "url", p.BaseURL)
-		} else {
-			opts = append(opts, openai.WithBaseURL("https://api.groq.com/openai/v1"))
+		if httpClient != nil {
+			opts = append(opts, openai.WithHTTPClient(httpClient))
 		}
 		llmModel, err = openai.New(opts...)
 	case model.ProviderGoogle:
@@ -654,57 +1167,84 @@ func CreateProvider(ctx context.Context, p model.Provider) (llms.Model, error) {
 		}
 		if retryAfterClient != nil {
 			googleOpts = append(googleOpts, googleai.WithHTTPClient(retryAfterClient.wrapped))
+		} else if proxyClient != nil {
+			googleOpts = append(googleOpts, googleai.WithHTTPClient(proxyClient))
 		}
 		llmModel, err = googleai.New(ctx, googleOpts...)
 	case model.ProviderVertex:
-		llmModel, err = vertex.New(
-			ctx,
+		vertexOpts := []googleai.Option{
 			googleai.WithDefaultModel(p.Model),
 			googleai.WithCloudProject(p.ProjectID),
 			googleai.WithCloudLocation(p.Location),
-			googleai.WithCredentialsFile(p.CredentialsPath),
-		)
+		}
+		// Credentials file wins; otherwise use the API key. Without either, fall back to
+		// application default credentials only when GOOGLE_APPLICATION_CREDENTIALS is set.
+		switch {
+		case p.CredentialsPath != "":
+			vertexOpts = append(vertexOpts, googleai.WithCredentialsFile(p.CredentialsPath))
+		case p.Token != "":
+			log.Debug("Using API key authentication for Vertex provider", "provider", p.Name)
+			vertexOpts = append(vertexOpts, googleai.WithAPIKey(p.Token))
+		case os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") == "":
+			return nil, fmt.Errorf("Vertex provider requires credentials_path or token (API key)")
+		}
+		llmModel, err = vertex.New(ctx, vertexOpts...)
 	case model.ProviderAnthropic:
 		opts := []anthropic.Option{
 			anthropic.WithModel(p.Model),
 			anthropic.WithToken(p.Token),
+			anthropic.WithBaseURL(baseURL),
 		}
-		if retryAfterClient != nil {
-			opts = append(opts, anthropic.WithHTTPClient(retryAfterClient))
+		if httpClient != nil {
+			opts = append(opts, anthropic.WithHTTPClient(httpClient))
 		}
 		llmModel, err = anthropic.New(opts...)
 	case model.ProviderAmazonAnthropic:
-		cfg, err := config.LoadDefaultConfig(context.Background(),
-			config.WithRegion(p.Location),
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-				p.Token,
-				p.Secret,
-				"",
-			)),
-		)
-		if err != nil {
-			log.Fatal(err)
+		brc, clientErr := newBedrockRuntimeClient(p, proxyClient)
+		if clientErr != nil {
+			return nil, clientErr
 		}
-		brc := bedrockruntime.NewFromConfig(cfg)
 		llmModel, err = bedrock.New(
 			bedrock.WithClient(brc),
 			bedrock.WithModel(p.Model),
 		)
+	case model.ProviderBedrock:
+		llmModel, err = newBedrockModel(p, proxyClient)
 	case model.ProviderOpenAI:
 		opts := []openai.Option{
 			openai.WithToken(p.Token),
 			openai.WithModel(p.Model),
+			openai.WithBaseURL(baseURL),
 		}
-		if retryAfterClient != nil {
-			opts = append(opts, openai.WithHTTPClient(retryAfterClient))
+		if httpClient != nil {
+			opts = append(opts, openai.WithHTTPClient(httpClient))
 		}
 
-		if p.BaseURL != "" {
-			opts = append(opts, openai.WithBaseURL(p.BaseURL))
-			logger.Logger.Debug("Using custom base URL", "url", p.BaseURL)
+		llmModel, err = openai.New(opts...)
+
+	case model.ProviderMistral:
+		opts := []mistral.Option{
+			mistral.WithAPIKey(p.Token),
+			mistral.WithModel(p.Model),
+			mistral.WithEndpoint(baseURL),
 		}
+		llmModel, err = mistral.New(opts...)
 
-		llmModel, err = openai.New(opts...)
+	case model.ProviderOllama:
+		// WithServerURL exits the process on a malformed URL, so check it first
+		if _, parseErr := url.Parse(baseURL); parseErr != nil {
+			return nil, fmt.Errorf("invalid Ollama base URL: %w", parseErr)
+		}
+		opts := []ollama.Option{
+			ollama.WithModel(p.Model),
+			ollama.WithServerURL(baseURL),
+		}
+		if retryAfterClient != nil {
+			opts = append(opts, ollama.WithHTTPClient(retryAfterClient.wrapped))
+		} else if proxyClient != nil {
+			opts = append(opts, ollama.WithHTTPClient(proxyClient))
+		}
+		llmModel, err = ollama.New(opts...)
 
 	case model.ProviderAzure:
 		if p.Version == "" {
@@ -720,14 +1260,13 @@ func CreateProvider(ctx context.Context, p model.Provider) (llms.Model, error) {
 			openai.WithAPIVersion(p.Version),
 			openai.WithBaseURL(p.BaseURL),
 		}
-		if retryAfterClient != nil {
-			opts = append(opts, openai.WithHTTPClient(retryAfterClient))
+		if httpClient != nil {
+			opts = append(opts, openai.WithHTTPClient(httpClient))
 		}
-		logger.Logger.Debug("Using Azure base URL", "url", p.BaseURL)
 
 		// Handle authentication type: "entra_id" uses DefaultAzureCredential, otherwise use API key
 		if strings.ToLower(p.AuthType) == "entra_id" {
-			logger.Logger.Debug("Using Entra ID authentication for Azure provider")
+			log.Debug("Using Entra ID authentication for Azure provider")
 			cred, err := azidentity.NewDefaultAzureCredential(nil)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create Azure credential: %w", err)
@@ -766,19 +1305,47 @@ func CreateProvider(ctx context.Context, p model.Provider) (llms.Model, error) {
 		return nil, fmt.Errorf("provider created but model is nil")
 	}
 
+	// Apply provider-level sampling parameters to every call
+	var samplingOpts []llms.CallOption
+	if p.Temperature != nil {
+		samplingOpts = append(samplingOpts, llms.WithTemperature(*p.Temperature))
+	}
+	if p.Seed != nil {
+		samplingOpts = append(samplingOpts, llms.WithSeed(*p.Seed))
+	}
+	if len(samplingOpts) > 0 {
+		llmModel = &samplingLLM{Model: llmModel, options: samplingOpts}
+	}
+
+	// Enable prompt caching where the client can send cache-control markers.
+	// The Bedrock clients (langchaingo's Anthropic-on-Bedrock provider and the
+	// Converse API of the pinned AWS SDK) have no way to send a cache point, so
+	// the flag is a no-op there as on every other type.
+	if p.PromptCache {
+		if p.Type == model.ProviderAnthropic {
+			llmModel = &promptCacheLLM{Model: llmModel}
+			log.Debug("Prompt caching enabled for provider", "name", p.Name)
+		} else {
+			log.Warn("prompt_cache is not supported for this provider type, ignoring",
+				"name", p.Name,
+				"type", p.Type)
+		}
+	}
+
 	// Wrap with rate limiter and/or retry handler if configured
 	if NeedsLLMWrapper(p.RateLimits, p.Retry) {
-		logger.Logger.Info("Wrapping provider with rate limiter/retry handler",
+		log.Info("Wrapping provider with rate limiter/retry handler",
 			"name", p.Name,
 			"tpm", p.RateLimits.TPM,
 			"rpm", p.RateLimits.RPM,
 			"retry_on_429", p.Retry.RetryOn429)
 		rateLimitedLLM := NewRateLimitedLLM(llmModel, p.RateLimits, p.Retry, p.Model)
+		rateLimitedLLM.SetTokenizer(tokenizer.ForModel(p.Type, p.Model))
 
 		// If we created a custom HTTP client for Retry-After header capture, link it
 		if retryAfterClient != nil {
 			rateLimitedLLM.SetRetryAfterProvider(retryAfterClient)
-			logger.Logger.Debug("Retry-After HTTP header capture enabled for provider", "name", p.Name)
+			log.Debug("Retry-After HTTP header capture enabled for provider", "name", p.Name)
 		}
 
 		llmModel = rateLimitedLLM
@@ -787,6 +1354,56 @@ func CreateProvider(ctx context.Context, p model.Provider) (llms.Model, error) {
 	return llmModel, nil
 }
 
+// samplingLLM prepends provider-level call options (temperature, seed) to every request.
+// Options passed by the caller are applied afterwards and therefore take precedence.
+type samplingLLM struct {
+	llms.Model
+	options []llms.CallOption
+}
+
+func (s *samplingLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := make([]llms.CallOption, 0, len(s.options)+len(options))
+	opts = append(opts, s.options...)
+	opts = append(opts, options...)
+	return s.Model.GenerateContent(ctx, messages, opts...)
+}
+
+// promptCacheLLM marks the first user message with an ephemeral Anthropic cache-control
+// breakpoint, so the prefix (tools, system prompt and first user message) is cached and
+// reused across iterations and tests. The caller's messages are not modified.
+type promptCacheLLM struct {
+	llms.Model
+}
+
+func (p *promptCacheLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	return p.Model.GenerateContent(ctx, WithPromptCacheBreakpoint(messages), append([]llms.CallOption{anthropic.WithPromptCaching()}, options...)...)
+}
+
+// WithPromptCacheBreakpoint returns a copy of messages where the last text part of the
+// first human message is wrapped with an ephemeral cache-control marker.
+func WithPromptCacheBreakpoint(messages []llms.MessageContent) []llms.MessageContent {
+	result := make([]llms.MessageContent, len(messages))
+	copy(result, messages)
+
+	for i, msg := range result {
+		if msg.Role != llms.ChatMessageTypeHuman {
+			continue
+		}
+		for j := len(msg.Parts) - 1; j >= 0; j-- {
+			if text, ok := msg.Parts[j].(llms.TextContent); ok {
+				parts := make([]llms.ContentPart, len(msg.Parts))
+				copy(parts, msg.Parts)
+				parts[j] = llms.WithCacheControl(text, anthropic.EphemeralCache())
+				result[i].Parts = parts
+				return result
+			}
+		}
+		break
+	}
+
+	return result
+}
+
 // ServerFactory creates MCP servers
 type ServerFactory interface {
 	NewMCPServer(ctx context.Context, config model.Server) (*server.MCPServer, error)
@@ -799,6 +1416,32 @@ func (f *DefaultServerFactory) NewMCPServer(ctx context.Context, config model.Se
 	return server.NewMCPServer(ctx, config)
 }
 
+// RecordingServerFactory creates servers through Base and records every tool
+// call they serve into Dir, one file per server, for later replay.
+type RecordingServerFactory struct {
+	Base ServerFactory
+	Dir  string
+}
+
+func (f *RecordingServerFactory) NewMCPServer(ctx context.Context, config model.Server) (*server.MCPServer, error) {
+	srv, err := f.Base.NewMCPServer(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	srv.Client = server.NewRecordingClient(srv.Client, f.Dir, srv.Name)
+	return srv, nil
+}
+
+// ReplayServerFactory serves tool calls from recordings in Dir instead of
+// starting live servers.
+type ReplayServerFactory struct {
+	Dir string
+}
+
+func (f *ReplayServerFactory) NewMCPServer(ctx context.Context, config model.Server) (*server.MCPServer, error) {
+	return server.NewReplayServer(config, f.Dir)
+}
+
 // Package-level variable for dependency injection
 var serverFactory ServerFactory = &DefaultServerFactory{}
 
@@ -807,12 +1450,25 @@ func SetServerFactory(factory ServerFactory) {
 	serverFactory = factory
 }
 
-func InitServers(ctx context.Context, serverConfigs []model.Server, templateCtx map[string]string) (map[string]*server.MCPServer, error) {
+// RandomStream names the random stream of the template helpers after the
+// identity of what is rendered, e.g. a test's source file, session, agent and
+// position, so its values depend on the run's seed and that identity alone.
+func RandomStream(identity ...string) string {
+	return strings.Join(identity, "\x00")
+}
+
+// NewRunSeed returns a time-based seed for runs started without a seed.
+func NewRunSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+func InitServers(ctx context.Context, serverConfigs []model.Server, templateCtx map[string]string) (map[string]*server.MCPServer, error) {
+	log := logger.FromContext(ctx)
 	if len(serverConfigs) == 0 {
 		return nil, fmt.Errorf("no servers to initialize")
 	}
 
-	logger.Logger.Info("Initializing servers", "count", len(serverConfigs))
+	log.Info("Initializing servers", "count", len(serverConfigs))
 	servers := make(map[string]*server.MCPServer)
 
 	for i, s := range serverConfigs {
@@ -821,8 +1477,10 @@ func InitServers(ctx context.Context, serverConfigs []model.Server, templateCtx
 		s.Name = model.RenderTemplate(s.Name, templateCtx)
 		s.Command = model.RenderTemplate(s.Command, templateCtx)
 		s.URL = model.RenderTemplate(s.URL, templateCtx)
+		s.Proxy = model.RenderTemplate(s.Proxy, templateCtx)
 		s.ServerDelay = model.RenderTemplate(s.ServerDelay, templateCtx)
 		s.ProcessDelay = model.RenderTemplate(s.ProcessDelay, templateCtx)
+		s.ToolTimeout = model.RenderTemplate(s.ToolTimeout, templateCtx)
 		s.WorkingDir = model.RenderTemplate(s.WorkingDir, templateCtx)
 		s.Shell = model.RenderTemplate(s.Shell, templateCtx)
 		s.ToolPrefix = model.RenderTemplate(s.ToolPrefix, templateCtx)
@@ -836,8 +1494,24 @@ func InitServers(ctx context.Context, serverConfigs []model.Server, templateCtx
 				s.Headers[k] = model.RenderTemplate(s.Headers[k], templateCtx)
 			}
 		}
+		s.Image = model.RenderTemplate(s.Image, templateCtx)
+		s.Path = model.RenderTemplate(s.Path, templateCtx)
+		if s.Args != nil {
+			args := make([]string, len(s.Args))
+			for j, arg := range s.Args {
+				args[j] = model.RenderTemplate(arg, templateCtx)
+			}
+			s.Args = args
+		}
+		if s.Env != nil {
+			env := make(map[string]string, len(s.Env))
+			for k, v := range s.Env {
+				env[k] = model.RenderTemplate(v, templateCtx)
+			}
+			s.Env = env
+		}
 
-		logger.Logger.Debug("Initializing server",
+		log.Debug("Initializing server",
 			"index", i+1,
 			"total", len(serverConfigs),
 			"name", s.Name,
@@ -858,10 +1532,10 @@ func InitServers(ctx context.Context, serverConfigs []model.Server, templateCtx
 			return nil, fmt.Errorf("failed to create server '%s': %w", s.Name, err)
 		}
 		servers[s.Name] = mcpServer
-		logger.Logger.Info("Server initialized", "name", s.Name)
+		log.Info("Server initialized", "name", s.Name)
 	}
 
-	logger.Logger.Info("All servers initialized", "count", len(servers))
+	log.Info("All servers initialized", "count", len(servers))
 	return servers, nil
 }
 
@@ -871,15 +1545,16 @@ func InitAgents(
 	mcpServers map[string]*server.MCPServer,
 	providers map[string]llms.Model,
 ) (map[string]*agent.MCPAgent, error) {
+	log := logger.FromContext(ctx)
 	if len(agentConfigs) == 0 {
 		return nil, fmt.Errorf("no agents to initialize")
 	}
 
-	logger.Logger.Info("Initializing agents", "count", len(agentConfigs))
+	log.Info("Initializing agents", "count", len(agentConfigs))
 	agents := make(map[string]*agent.MCPAgent)
 
 	for i, a := range agentConfigs {
-		logger.Logger.Debug("Initializing agent",
+		log.Debug("Initializing agent",
 			"index", i+1,
 			"total", len(agentConfigs),
 			"name", a.Name,
@@ -893,10 +1568,14 @@ func InitAgents(
 			return nil, fmt.Errorf("duplicate agent name: %s", a.Name)
 		}
 
-		// Get provider
-		llmModel, ok := providers[a.Provider]
+		// Get provider, or its clone running the agent's model
+		providerName := a.Provider
+		if a.Model != "" {
+			providerName = ModelProviderName(a.Provider, a.Model)
+		}
+		llmModel, ok := providers[providerName]
 		if !ok {
-			return nil, fmt.Errorf("provider '%s' not found for agent '%s'", a.Provider, a.Name)
+			return nil, fmt.Errorf("provider '%s' not found for agent '%s'", providerName, a.Name)
 		}
 
 		if llmModel == nil {
@@ -905,7 +1584,7 @@ func InitAgents(
 
 		// Allow agents without servers - they can work for simple tests or skill-only tests
 		if len(a.Servers) == 0 {
-			logger.Logger.Warn("Agent has no servers configured", "agent", a.Name)
+			log.Warn("Agent has no servers configured", "agent", a.Name)
 		}
 
 		// Build agent server list
@@ -933,7 +1612,7 @@ func InitAgents(
 			agentMCPServers = append(agentMCPServers, mcpServer)
 		}
 
-		logger.Logger.Debug("Agent server configuration",
+		log.Debug("Agent server configuration",
 			"agent", a.Name,
 			"server_count", len(agentServers),
 			"servers", GetServerNames(agentServers))
@@ -951,15 +1630,29 @@ func InitAgents(
 		if mcpAgent == nil {
 			return nil, fmt.Errorf("failed to create agent '%s': agent is nil", a.Name)
 		}
+		if a.OnToolCollision == model.ToolCollisionError && len(mcpAgent.ToolCollisions) > 0 {
+			return nil, fmt.Errorf("agent '%s': %s", a.Name, describeToolCollisions(mcpAgent.ToolCollisions))
+		}
+		mcpAgent.Model = a.Model
 
 		agents[a.Name] = mcpAgent
-		logger.Logger.Info("Agent initialized", "name", a.Name)
+		log.Info("Agent initialized", "name", a.Name)
 	}
 
-	logger.Logger.Info("All agents initialized", "count", len(agents))
+	log.Info("All agents initialized", "count", len(agents))
 	return agents, nil
 }
 
+// describeToolCollisions lists the tools exposed by several servers, sorted by name.
+func describeToolCollisions(collisions map[string][]string) string {
+	tools := slices.Sorted(maps.Keys(collisions))
+	parts := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		parts = append(parts, fmt.Sprintf("tool '%s' is exposed by servers %s", tool, strings.Join(collisions[tool], ", ")))
+	}
+	return strings.Join(parts, "; ") + " (set on_tool_collision: prefix to register them as <server>__<tool>)"
+}
+
 func RunTests(
 	ctx context.Context,
 	testConfig *model.TestConfiguration,
@@ -988,9 +1681,9 @@ func RunTests(
 			}
 		}
 	}
-	testCount := 0
+	var testCount atomic.Int64
 
-	logger.Logger.Info("Running tests",
+	logger.FromContext(ctx).Info("Running tests",
 		"total_tests", totalTests,
 		"agents", len(agents),
 		"sessions", len(testConfig.Sessions))
@@ -1007,41 +1700,48 @@ func RunTests(
 		providerDefMap[p.Name] = p
 	}
 
-	for _, agentConfig := range agents {
-		ag, ok := agents[agentConfig.Name]
-		if !ok {
-			logger.Logger.Warn("Agent not found, skipping tests", "agent", agentConfig.Name)
-			continue
-		}
+	// Servers with reset_between_agents are reset when another agent takes them over
+	serverDefMap := make(map[string]model.Server)
+	for _, srv := range testConfig.Servers {
+		serverDefMap[srv.Name] = srv
+	}
+	resetTracker := NewServerResetTracker()
+
+	// runAgentTests runs every session of one agent in order, so stateful
+	// setup-to-cleanup flows within a session keep their sequence
+	runAgentTests := func(agentName string) []model.TestRun {
+		results := make([]model.TestRun, 0)
+		ag := agents[agentName]
+		// Every line carries the agent, as agents may run concurrently
+		agentLog := logger.FromContext(ctx).With("agent", agentName)
 
 		// Find the original agent config from testConfig.Agents to get system_prompt
 		var originalAgentConfig *model.Agent
 		for i := range testConfig.Agents {
-			if testConfig.Agents[i].Name == agentConfig.Name {
+			if testConfig.Agents[i].Name == agentName {
 				originalAgentConfig = &testConfig.Agents[i]
 				break
 			}
 		}
 
-		logger.Logger.Info("Starting tests for agent",
-			"agent", agentConfig.Name,
+		agentLog.Info("Starting tests for agent",
 			"total", len(agents))
 
 		allAgentTools := ag.ExtractToolsFromAgent()
 		// Iterate through sessions
 		for sessionIdx, session := range testConfig.Sessions {
-			logger.Logger.Info("Starting session",
+			agentLog.Info("Starting session",
 				"session", session.Name,
-				"agent", agentConfig.Name,
 				"index", sessionIdx+1,
 				"total", len(testConfig.Sessions))
 
 			// Create static template context with TEST_DIR, env vars, and user variables
 			templateCtx := CreateStaticTemplateContext(sourceFile, testConfig.Variables)
 			// Add runtime variables for this session
-			templateCtx["AGENT_NAME"] = agentConfig.Name
+			templateCtx["AGENT_NAME"] = agentName
 			templateCtx["SESSION_NAME"] = session.Name
 			templateCtx["PROVIDER_NAME"] = ag.Provider
+			templateCtx[templates.RandomStreamVar] = RandomStream(sourceFile, session.Name, agentName)
 
 			// Initialize fresh message history for this session
 			msgs := make([]llms.MessageContent, 0)
@@ -1054,7 +1754,7 @@ func RunTests(
 				skillPath := model.RenderTemplate(originalAgentConfig.Skill.Path, templateCtx)
 				loadedSkill, err := skill.LoadSkill(skillPath)
 				if err != nil {
-					logger.Logger.Error("Failed to load skill",
+					agentLog.Error("Failed to load skill",
 						"path", skillPath,
 						"error", err)
 				} else {
@@ -1063,7 +1763,7 @@ func RunTests(
 
 					// Add skill content to system prompt
 					systemPromptParts = append(systemPromptParts, loadedSkill.GetContentForInjection())
-					logger.Logger.Info("Skill loaded and injected",
+					agentLog.Info("Skill loaded and injected",
 						"name", loadedSkill.Metadata.Name,
 						"path", loadedSkill.Path,
 						"content_length", len(loadedSkill.Content))
@@ -1073,29 +1773,34 @@ func RunTests(
 						registerSkillReferenceTools(ag, loadedSkill)
 						// Re-extract tools to include the new built-in tools
 						allAgentTools = ag.ExtractToolsFromAgent()
-						logger.Logger.Info("Skill reference tools enabled",
+						agentLog.Info("Skill reference tools enabled",
 							"skill", loadedSkill.Metadata.Name)
 					}
 				}
 			}
 
-			// Add custom system prompt if configured
+			// Add custom system prompt: the agent's own prompt overrides the settings default
+			customPrompt := testConfig.Settings.SystemPrompt
 			if originalAgentConfig != nil && originalAgentConfig.SystemPrompt != "" {
-				customPrompt := model.RenderTemplate(originalAgentConfig.SystemPrompt, templateCtx)
-				systemPromptParts = append(systemPromptParts, customPrompt)
+				customPrompt = originalAgentConfig.SystemPrompt
+			}
+			if customPrompt != "" {
+				systemPromptParts = append(systemPromptParts, model.RenderTemplate(customPrompt, templateCtx))
 			}
 
 			// Combine and add system message if any parts exist
+			combinedPrompt := ""
 			if len(systemPromptParts) > 0 {
-				combinedPrompt := strings.Join(systemPromptParts, "\n\n")
+				combinedPrompt = strings.Join(systemPromptParts, "\n\n")
 				msgs = append(msgs, llms.MessageContent{
 					Role: llms.ChatMessageTypeSystem,
 					Parts: []llms.ContentPart{
 						llms.TextContent{Text: combinedPrompt},
 					},
 				})
-				logger.Logger.Debug("System prompt added", "length", len(combinedPrompt), "parts", len(systemPromptParts))
+				agentLog.Debug("System prompt added", "length", len(combinedPrompt), "parts", len(systemPromptParts))
 			}
+			sessionStart := len(msgs)
 
 			sessionTools := allAgentTools // Don't mutate original
 			if session.AllowedTools != nil {
@@ -1109,28 +1814,119 @@ func RunTests(
 				}
 			}
 
+			// recordSkipped adds a test that is not run, with the reason reported for it
+			recordSkipped := func(test model.Test, reason string) {
+				skipped := newSkippedTestRun(test, agentName, ag.Provider, reason)
+				skipped.Execution.SourceFile = sourceFile
+				skipped.Execution.SuiteName = suiteName
+				skipped.Execution.SessionName = session.Name
+				skipped.TestCriteria = testConfig.TestCriteria
+				results = append(results, skipped)
+				agentLog.Info("Test SKIPPED", "test", test.Name, "reason", reason)
+			}
+
+			// Reset servers last used by another agent so this agent starts from a clean state
+			var stateReset []string
+			var resetErr error
+			for _, name := range resetTracker.SwitchAgent(agentName, ResettableServers(agentDefMap[agentName], testConfig.Servers)) {
+				if resetErr = RunResetCommand(ctx, serverDefMap[name], templateCtx); resetErr != nil {
+					break
+				}
+				stateReset = append(stateReset, name)
+			}
+			if resetErr != nil {
+				agentLog.Error("Failed to reset server state, failing session",
+					"session", session.Name,
+					"error", resetErr)
+				for _, test := range session.Tests {
+					if test.Agent != "" && test.Agent != agentName {
+						continue
+					}
+					failed := newFailedTestRun(test, agentName, ag.Provider, model.ErrorKindStateReset, resetErr.Error())
+					failed.Execution.SourceFile = sourceFile
+					failed.Execution.SuiteName = suiteName
+					failed.Execution.SessionName = session.Name
+					failed.Execution.StateReset = stateReset
+					failed.TestCriteria = testConfig.TestCriteria
+					results = append(results, failed)
+					agentLog.Warn("Test FAILED", "test", test.Name)
+				}
+				continue
+			}
+
 			// Run tests within this session
 			for testIdx, test := range session.Tests {
 				// Skip test if it specifies a different agent
-				if test.Agent != "" && test.Agent != agentConfig.Name {
-					logger.Logger.Debug("Skipping test for different agent",
+				if test.Agent != "" && test.Agent != agentName {
+					agentLog.Debug("Skipping test for different agent",
 						"test", test.Name,
 						"test_agent", test.Agent,
-						"current_agent", agentConfig.Name)
+						"current_agent", agentName)
+					continue
+				}
+
+				// Tests left when the run deadline passes are reported but not started
+				if RunDeadlineReached(ctx) {
+					recordSkipped(test, NotExecutedDeadlineReason)
 					continue
 				}
 
-				testCount++
+				if ctx.Err() != nil {
+					agentLog.Warn("Run interrupted, skipping remaining tests")
+					return results
+				}
+
+				testNumber := int(testCount.Add(1))
+
+				// A test's model override runs on a copy of the agent with the
+				// provider clone for that model
+				ag, runName := ag, agentName
+				if test.Model != "" {
+					llmModel, ok := providers[ModelProviderName(ag.Provider, test.Model)]
+					if !ok {
+						agentLog.Error("Model override not initialized", "test", test.Name, "model", test.Model)
+						failed := newTestRunStub(test, agentName, ag.Provider)
+						failed.Execution.Errors = append(failed.Execution.Errors,
+							fmt.Sprintf("provider '%s' has no clone for model %q", ag.Provider, test.Model))
+						failed.Execution.SourceFile = sourceFile
+						failed.Execution.SuiteName = suiteName
+						failed.Execution.SessionName = session.Name
+						failed.TestCriteria = testConfig.TestCriteria
+						results = append(results, failed)
+						continue
+					}
+					override := *ag
+					override.LLMModel = llmModel
+					override.Model = test.Model
+					ag, runName = &override, model.ModelRunName(agentName, test.Model)
+				}
+				templateCtx[templates.RandomStreamVar] = RandomStream(sourceFile, session.Name, runName, strconv.Itoa(testIdx), test.Name)
 
 				if test.Name == "" {
-					logger.Logger.Warn("Test has no name", "index", testIdx)
+					agentLog.Warn("Test has no name", "index", testIdx)
+				}
+
+				if test.SkipIf != "" {
+					skip, err := EvaluateSkipIf(test.SkipIf, templateCtx)
+					if err != nil {
+						agentLog.Warn("Invalid skip_if condition, running test",
+							"test", test.Name,
+							"skip_if", test.SkipIf,
+							"error", err)
+					} else if skip {
+						reason := model.RenderTemplate(test.SkipReason, templateCtx)
+						if reason == "" {
+							reason = "skip_if: " + test.SkipIf
+						}
+						recordSkipped(test, reason)
+						continue
+					}
 				}
 
-				logger.Logger.Info("Running test",
+				agentLog.Info("Running test",
 					"test", test.Name,
-					"number", testCount,
+					"number", testNumber,
 					"total", totalTests,
-					"agent", agentConfig.Name,
 					"session", session.Name)
 
 				testTools := sessionTools // Start from session tools
@@ -1147,145 +1943,485 @@ func RunTests(
 				// Start delay
 				if test.StartDelay != "" {
 					startDelay := ParseDelay(test.StartDelay)
-					logger.Logger.Debug("Delaying the test start", "delay", startDelay)
+					agentLog.Debug("Delaying the test start", "delay", startDelay)
 					time.Sleep(startDelay)
 				}
 
-				// Transform prompt with template context
-				prompt := model.RenderTemplate(test.Prompt, templateCtx)
-				logger.Logger.Debug("Test prompt prepared", "prompt", prompt)
-
-				// Create message from test prompt
-				msgs = append(msgs, llms.MessageContent{
-					Role: llms.ChatMessageTypeHuman,
-					Parts: []llms.ContentPart{
-						llms.TextContent{Text: prompt},
-					},
-				})
+				// Every test runs in a fresh directory exposed as {{TEST_TEMP_DIR}},
+				// holding its fixtures as {{FIXTURE_DIR}}
+				fixtureDir, err := PrepareFixtures(test.Fixtures, templateCtx)
+				if err != nil {
+					agentLog.Error("Failed to prepare test directory", "test", test.Name, "error", err)
+					failed := newFailedTestRun(test, agentName, ag.Provider, model.ErrorKindFixture, err.Error())
+					failed.Execution.SourceFile = sourceFile
+					failed.Execution.SuiteName = suiteName
+					failed.Execution.SessionName = session.Name
+					failed.TestCriteria = testConfig.TestCriteria
+					results = append(results, failed)
+					agentLog.Warn("Test FAILED", "test", test.Name)
+					continue
+				}
+				setTestDir(templateCtx, fixtureDir, len(test.Fixtures) > 0)
+				agentLog.Debug("Test directory prepared", "test", test.Name, "dir", fixtureDir, "fixtures", len(test.Fixtures))
+
+				// Columns of a prompts_file row are visible to this test only, and
+				// rows are independent samples that do not share the conversation
+				restoreVariables := setTestVariables(templateCtx, test.Variables)
+				if test.Variables != nil {
+					msgs = msgs[:sessionStart:sessionStart]
+				}
 
 				// Get agent definition for config
-				agentDef := agentDefMap[agentConfig.Name]
+				agentDef := agentDefMap[agentName]
+				providerDef := providerDefMap[ag.Provider]
+				if ag.Model != "" {
+					providerDef.Model = ag.Model
+				}
+
+				// The test's tool_choice overrides the agent's
+				requestedToolChoice := agentDef.ToolChoice
+				if test.ToolChoice != "" {
+					requestedToolChoice = test.ToolChoice
+				}
+				toolChoice, toolChoiceOption := ResolveToolChoice(providerDef.Type, requestedToolChoice, testTools)
 
 				// Resolve judge LLM for clarification detection
 				var judgeLLM llms.Model
 				if agentDef.ClarificationDetection.Enabled {
 					judgeProvider := agentDef.ClarificationDetection.JudgeProvider
 					if judgeProvider == "" {
-						logger.Logger.Error("Clarification detection enabled but judge_provider not specified",
-							"agent", agentConfig.Name)
+						agentLog.Error("Clarification detection enabled but judge_provider not specified")
 					} else if judgeProvider == "$self" {
 						// Use the agent's own LLM as the judge
 						judgeLLM = ag.LLMModel
-						logger.Logger.Debug("Using agent's LLM as clarification judge", "agent", agentConfig.Name)
+						agentLog.Debug("Using agent's LLM as clarification judge")
 					} else {
 						// Look up the specified provider
 						if providerLLM, ok := providers[judgeProvider]; ok {
 							judgeLLM = providerLLM
-							logger.Logger.Debug("Using separate provider for clarification judge",
-								"agent", agentConfig.Name,
+							agentLog.Debug("Using separate provider for clarification judge",
 								"judge_provider", judgeProvider)
 						} else {
-							logger.Logger.Error("Clarification judge provider not found",
-								"agent", agentConfig.Name,
+							agentLog.Error("Clarification judge provider not found",
 								"judge_provider", judgeProvider)
 						}
 					}
 				}
 
-				// Execute test
-				startTime := time.Now()
-				executionResult := ag.GenerateContentWithConfig(ctx, &msgs, agent.AgentConfig{
+				agentCfg := agent.AgentConfig{
 					MaxIterations:                 maxIterations,
 					ToolTimeout:                   toolTimeout,
+					TestToolTimeout:               ParseTimeout(test.ToolTimeout),
+					FailOnToolTimeout:             testConfig.Settings.ToolTimeoutPolicy == model.ToolTimeoutFail,
 					AddNotFinalResponses:          true,
 					Verbose:                       testConfig.Settings.Verbose,
 					ClarificationDetectionEnabled: agentDef.ClarificationDetection.Enabled,
 					ClarificationDetectionLevel:   agent.ClarificationLevel(agentDef.ClarificationDetection.Level),
 					ClarificationJudgeLLM:         judgeLLM,
-				}, testTools)
-				executionResult.TestName = test.Name
-				executionResult.SourceFile = sourceFile
-				executionResult.SuiteName = suiteName
-				executionResult.SessionName = session.Name
+					ToolChoice:                    toolChoice,
+					ToolChoiceOption:              toolChoiceOption,
+					Tokenizer:                     providerTokenizer(providerDef, templateCtx),
+					Observer:                      StreamObserver(ctx, test.Name, runName),
+				}
 
-				duration := time.Since(startTime)
+				testTimeout := ResolveTestTimeout(testConfig.Settings.TestTimeout, session.Timeout, test.Timeout)
+				retries, retryBackoff := ResolveTestRetries(session, test)
+
+				// Retries restart the conversation from the test prompt
+				attemptStart := len(msgs)
+				var (
+					executionResult                        model.ExecutionResult
+					failedAttempts                         model.ExecutionResult // Token usage of retried attempts
+					turnAssertions                         []model.AssertionResult
+					duration                               time.Duration
+					timedOut, interrupted, deadlineExpired bool
+				)
+			attempts:
+				for attempt := 1; ; attempt++ {
+					msgs = msgs[:attemptStart]
+
+					// Transform prompt with template context
+					prompt := model.RenderTemplate(test.Prompt, templateCtx)
+					agentLog.Debug("Test prompt prepared", "prompt", prompt)
+
+					// Create message from test prompt
+					msgs = append(msgs, llms.MessageContent{
+						Role: llms.ChatMessageTypeHuman,
+						Parts: []llms.ContentPart{
+							llms.TextContent{Text: prompt},
+						},
+					})
+
+					// Bound the whole test (including follow-up turns) by its resolved timeout
+					testCtx, cancelTest := ctx, context.CancelFunc(func() {})
+					if testTimeout > 0 {
+						testCtx, cancelTest = context.WithTimeout(ctx, testTimeout)
+					}
 
-				logger.Logger.Info("Test execution completed",
-					"test", test.Name,
-					"duration", duration,
-					"tool_calls", len(executionResult.ToolCalls),
-					"errors", len(executionResult.Errors))
-				//extract variables
-				if test.Extractors != nil {
+					// Execute test
+					startTime := time.Now()
+					executionResult = ag.GenerateContentWithConfig(testCtx, &msgs, agentCfg, testTools)
+					executionResult.TestName = test.Name
+					executionResult.AgentName = runName
+					executionResult.Model = ag.Model
+					executionResult.SourceFile = sourceFile
+					executionResult.SuiteName = suiteName
+					executionResult.SessionName = session.Name
+					executionResult.Tags = test.Tags
+					executionResult.SystemPrompt = combinedPrompt
+					if retries > 0 {
+						executionResult.RetryAttempts = attempt
+						executionResult.MaxAttempts = retries + 1
+					}
+
+					//extract variables
 					for _, extractor := range test.Extractors {
 						extractor.Extract(&executionResult, templateCtx)
 					}
+
+					// Feed scripted follow-up turns into the same conversation
+					turnAssertions = runFollowUpTurns(testCtx, ag, test, &msgs, agentCfg, testTools, templateCtx, &executionResult)
+
+					duration = time.Since(startTime)
+					deadlineExpired = runDeadlineExpired(ctx)
+					timedOut = testCtx.Err() == context.DeadlineExceeded && !deadlineExpired
+					interrupted = ctx.Err() != nil && !deadlineExpired
+					cancelTest()
+					if timedOut {
+						executionResult.AddError(model.ErrorKindTestTimeout, fmt.Sprintf("test timeout exceeded (%s)", testTimeout))
+						agentLog.Warn("Test timeout exceeded",
+							"test", test.Name,
+							"timeout", testTimeout)
+					}
+					if interrupted {
+						executionResult.AddError(model.ErrorKindInterrupted, "test interrupted before completion")
+						agentLog.Warn("Test interrupted", "test", test.Name)
+					}
+					if deadlineExpired {
+						executionResult.AddError(model.ErrorKindRunDeadline, "run deadline exceeded before the test completed")
+						agentLog.Warn("Test cancelled at run deadline", "test", test.Name)
+					}
+
+					if attempt > retries || !ShouldRetryTest(ctx, &executionResult) {
+						break
+					}
+					AddTokenUsage(&failedAttempts, &executionResult)
+					agentLog.Warn("Test execution failed, retrying",
+						"test", test.Name,
+						"attempt", attempt,
+						"max_attempts", retries+1,
+						"backoff", retryBackoff,
+						"errors", strings.Join(executionResult.Errors, "; "))
+					select {
+					case <-ctx.Done():
+						break attempts
+					case <-time.After(retryBackoff):
+					}
+
+					// The failed attempt may have changed its directory, so the
+					// next one gets a fresh copy of the fixtures
+					CleanupFixtures(fixtureDir, false)
+					dir, err := PrepareFixtures(test.Fixtures, templateCtx)
+					if err != nil {
+						fixtureDir = ""
+						setTestDir(templateCtx, "", false)
+						executionResult.AddError(model.ErrorKindFixture, err.Error())
+						agentLog.Error("Failed to prepare test directory", "test", test.Name, "error", err)
+						break
+					}
+					fixtureDir = dir
+					setTestDir(templateCtx, fixtureDir, len(test.Fixtures) > 0)
 				}
+				// Retried attempts count toward the test's cost
+				AddTokenUsage(&executionResult, &failedAttempts)
+
+				agentLog.Info("Test execution completed",
+					"test", test.Name,
+					"duration", duration,
+					"turns", len(test.Turns)+1,
+					"tool_calls", len(executionResult.ToolCalls),
+					"errors", len(executionResult.Errors))
 				// Evaluate assertions
-				logger.Logger.Debug("Evaluating assertions", "count", len(test.Assertions))
-				evaluator := model.NewAssertionEvaluator(&executionResult, templateCtx, ag.AvailableTools)
-				assertions := evaluator.Evaluate(test.Assertions)
+				agentLog.Debug("Evaluating assertions", "count", len(test.Assertions))
+				evaluator := model.NewAssertionEvaluator(&executionResult, templateCtx, ag.AvailableTools).
+					WithToolParameters(ag.ToolParameters()).
+					WithJudges(ctx, model.JudgePanelFromContext(ctx)).
+					WithGoldenWriter(model.GoldenWriterFromContext(ctx)).
+					WithAssertionFilter(model.AssertionFilterFromContext(ctx)).
+					WithAssertionMode(test.AssertionMode)
+				assertions := turnAssertions
+				if failed := model.FirstTestFailure(turnAssertions); failed >= 0 && test.AssertionMode == model.AssertionModeFirstFail {
+					// A turn assertion already failed the test; skip the final assertions
+					model.MarkNotEvaluated(&assertions[failed], len(test.Assertions))
+				} else {
+					evalStart := time.Now()
+					assertions = append(assertions, evaluator.Evaluate(test.Assertions)...)
+					executionResult.AssertionDurationMs += time.Since(evalStart).Milliseconds()
+				}
 
-				// Check if all assertions passed
-				allPassed := true
+				// Check if all assertions passed; a timed-out or interrupted test always fails,
+				// as does a tool timeout under the "fail" policy
+				toolTimedOut := agentCfg.FailOnToolTimeout && executionResult.HasErrorKind(model.ErrorKindToolTimeout)
+				allPassed := !timedOut && !interrupted && !deadlineExpired && !toolTimedOut
+				// Warning-level assertion failures are reported but do not fail the test
 				passedCount := 0
 				for _, a := range assertions {
-					if a.Passed {
+					if a.Passed && !a.Disabled {
 						passedCount++
-					} else {
+					} else if a.FailsTest() {
 						allPassed = false
 					}
 				}
 
-				logger.Logger.Info("Assertion results",
+				agentLog.Info("Assertion results",
 					"test", test.Name,
 					"passed", passedCount,
+					"warnings", model.CountWarnings(assertions),
 					"total", len(assertions))
 
+				keepTemp := KeepTempFromContext(ctx)
+				if fixtureDir != "" && keepTemp {
+					executionResult.TempDir = fixtureDir
+				}
+				executionResult.StateReset = stateReset
+
 				// Create test run
 				testRun := model.TestRun{
 					Execution:    &executionResult,
 					Assertions:   assertions,
 					Passed:       allPassed,
 					TestCriteria: testConfig.TestCriteria,
+					Weight:       test.EffectiveWeight(),
 				}
 
 				results = append(results, testRun)
 
+				setTestDir(templateCtx, "", false)
+				CleanupFixtures(fixtureDir, keepTemp)
+				restoreVariables()
+
 				if allPassed {
-					logger.Logger.Info("Test PASSED", "test", test.Name)
+					agentLog.Info("Test PASSED", "test", test.Name)
 				} else {
-					logger.Logger.Warn("Test FAILED", "test", test.Name)
+					agentLog.Warn("Test FAILED", "test", test.Name)
 				}
 
 				// Delay between tests if configured
-				if testDelay > 0 && testCount < totalTests {
-					logger.Logger.Debug("Waiting before next test", "delay", testDelay)
+				if testDelay > 0 && testNumber < totalTests {
+					agentLog.Debug("Waiting before next test", "delay", testDelay)
 					time.Sleep(testDelay)
 				}
 			}
 
-			logger.Logger.Info("Session completed",
-				"session", session.Name,
-				"agent", agentConfig.Name)
+			agentLog.Info("Session completed",
+				"session", session.Name)
 
 			// Delay between sessions if configured (allows external processes like Excel to clean up)
 			if sessionDelay > 0 && sessionIdx < len(testConfig.Sessions)-1 {
-				logger.Logger.Info("Waiting before next session", "delay", sessionDelay)
+				agentLog.Info("Waiting before next session", "delay", sessionDelay)
 				time.Sleep(sessionDelay)
 			}
 		}
+
+		return results
 	}
 
+	// Agents in different lanes run concurrently up to settings.concurrency; agents
+	// sharing a server stay in one lane. Results are merged in agent order.
+	agentNames := OrderedAgentNames(testConfig.Agents, agents)
+	lanes := AgentLanes(testConfig.Agents, agentNames)
+	concurrency := RunConcurrency(ctx, testConfig.Settings.Concurrency)
+	if concurrency > 1 {
+		logger.FromContext(ctx).Info("Running agents concurrently",
+			"concurrency", concurrency,
+			"lanes", len(lanes),
+			"agents", len(agentNames))
+		for _, lane := range lanes {
+			if len(lane) > 1 {
+				logger.FromContext(ctx).Info("Agents share servers and run sequentially", "agents", strings.Join(lane, ", "))
+			}
+		}
+	}
+
+	agentResults := make(map[string][]model.TestRun, len(agentNames))
+	var mu sync.Mutex
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for _, lane := range lanes {
+		g.Go(func() error {
+			for _, agentName := range lane {
+				laneResults := runAgentTests(agentName)
+				mu.Lock()
+				agentResults[agentName] = laneResults
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	for _, agentName := range agentNames {
+		results = append(results, agentResults[agentName]...)
+	}
 	return results
 }
 
+// runFollowUpTurns sends each scripted turn of a test as a new user message in the
+// conversation held by msgs and merges the turn executions into result.
+// Per-turn assertions are evaluated against the turn's own execution; their
+// results are returned labelled with the turn number (the initial prompt is turn 1).
+func runFollowUpTurns(
+	ctx context.Context,
+	ag *agent.MCPAgent,
+	test model.Test,
+	msgs *[]llms.MessageContent,
+	config agent.AgentConfig,
+	tools []llms.Tool,
+	templateCtx map[string]string,
+	result *model.ExecutionResult,
+) []model.AssertionResult {
+	turnAssertions := make([]model.AssertionResult, 0)
+	for i, turn := range test.Turns {
+		turnNumber := i + 2
+		prompt := model.RenderTemplate(turn.Prompt, templateCtx)
+		logger.FromContext(ctx).Debug("Follow-up turn prepared",
+			"test", test.Name,
+			"turn", turnNumber,
+			"prompt", prompt)
+
+		*msgs = append(*msgs, llms.MessageContent{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.TextContent{Text: prompt},
+			},
+		})
+
+		turnResult := ag.GenerateContentWithConfig(ctx, msgs, config, tools)
+		turnResult.TestName = result.TestName
+		turnResult.SourceFile = result.SourceFile
+		turnResult.SuiteName = result.SuiteName
+		turnResult.SessionName = result.SessionName
+		turnResult.Tags = result.Tags
+		turnResult.SystemPrompt = result.SystemPrompt
+
+		failed := -1
+		if test.AssertionMode == model.AssertionModeFirstFail {
+			failed = model.FirstTestFailure(turnAssertions)
+		}
+		if failed >= 0 {
+			// An earlier turn failed the test under first_fail; the turn still runs
+			model.MarkNotEvaluated(&turnAssertions[failed], len(turn.Assertions))
+		} else if len(turn.Assertions) > 0 {
+			evaluator := model.NewAssertionEvaluator(&turnResult, templateCtx, ag.AvailableTools).
+				WithToolParameters(ag.ToolParameters()).
+				WithJudges(ctx, model.JudgePanelFromContext(ctx)).
+				WithGoldenWriter(model.GoldenWriterFromContext(ctx)).
+				WithAssertionFilter(model.AssertionFilterFromContext(ctx)).
+				WithAssertionMode(test.AssertionMode)
+			evalStart := time.Now()
+			evaluated := evaluator.Evaluate(turn.Assertions)
+			result.AssertionDurationMs += time.Since(evalStart).Milliseconds()
+			for _, a := range evaluated {
+				a.Message = fmt.Sprintf("[turn %d] %s", turnNumber, a.Message)
+				if a.Details == nil {
+					a.Details = make(map[string]interface{})
+				}
+				a.Details["turn"] = turnNumber
+				turnAssertions = append(turnAssertions, a)
+			}
+		}
+
+		result.AppendTurn(turnResult)
+
+		for _, extractor := range test.Extractors {
+			extractor.Extract(result, templateCtx)
+		}
+	}
+	return turnAssertions
+}
+
+// EvaluateSkipIf renders a skip_if condition and reports whether it holds.
+// The rendered condition is either a comparison ("a == b", "a != b"; quotes
+// around either side are optional) or a boolean value. An empty result is false.
+func EvaluateSkipIf(condition string, templateCtx map[string]string) (bool, error) {
+	rendered := strings.TrimSpace(model.RenderTemplate(condition, templateCtx))
+	for _, op := range []string{"!=", "=="} {
+		if left, right, found := strings.Cut(rendered, op); found {
+			equal := unquoteOperand(left) == unquoteOperand(right)
+			return equal == (op == "=="), nil
+		}
+	}
+	if rendered == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(strings.ToLower(rendered))
+	if err != nil {
+		return false, fmt.Errorf("condition %q rendered to %q: expected a comparison or a boolean", condition, rendered)
+	}
+	return value, nil
+}
+
+func unquoteOperand(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// newSkippedTestRun records a test that was not run, because its skip_if condition
+// held or the run deadline passed. The run has an execution stub so reports can
+// place it, but no assertions and no verdict.
+func newSkippedTestRun(test model.Test, agentName, provider, reason string) model.TestRun {
+	run := newTestRunStub(test, agentName, provider)
+	run.Skipped = true
+	run.SkipReason = reason
+	return run
+}
+
+// newFailedTestRun records a test that failed before it could be started.
+func newFailedTestRun(test model.Test, agentName, provider string, kind model.ErrorKind, message string) model.TestRun {
+	run := newTestRunStub(test, agentName, provider)
+	run.Execution.AddError(kind, message)
+	return run
+}
+
+// newTestRunStub returns a run for a test that was never executed: an execution
+// stub that places it in reports, and no assertions.
+func newTestRunStub(test model.Test, agentName, provider string) model.TestRun {
+	now := time.Now()
+	return model.TestRun{
+		Execution: &model.ExecutionResult{
+			TestName:     test.Name,
+			AgentName:    model.ModelRunName(agentName, test.Model),
+			ProviderType: model.ProviderType(provider),
+			Model:        test.Model,
+			Tags:         test.Tags,
+			StartTime:    now,
+			EndTime:      now,
+			Messages:     make([]model.Message, 0),
+			ToolCalls:    make([]model.ToolCall, 0),
+			Errors:       make([]string, 0),
+		},
+		Assertions: make([]model.AssertionResult, 0),
+		Weight:     test.EffectiveWeight(),
+	}
+}
+
+// VariableEnvPrefix prefixes the environment variables that override config
+// variables of the same name, e.g. AGENT_BENCHMARK_VAR_BASE_URL for BASE_URL.
+const VariableEnvPrefix = "AGENT_BENCHMARK_VAR_"
+
 // CreateStaticTemplateContext creates a template context with all "static" variables
 // that are available before test execution begins. This includes:
 // - Environment variables
 // - TEST_DIR (directory containing the source file)
 // - User-defined variables from the config
 //
+// A user-defined variable is replaced by the environment variable with its name
+// prefixed by VariableEnvPrefix, so CI can override config values (e.g. BASE_URL)
+// without editing files. Unprefixed environment variables never override them.
+//
 // This context is used during provider and server initialization,
 // enabling templates like {{TEST_DIR}}/server.exe in server commands.
 // Runtime variables (AGENT_NAME, SESSION_NAME, PROVIDER_NAME) are added later
@@ -1297,6 +2433,10 @@ func CreateStaticTemplateContext(sourceFile string, variables map[string]string)
 	// Useful for creating unique file names, directories, etc.
 	templateCtx["RUN_ID"] = uuid.New().String()
 
+	// Add OS: the host operating system as reported by Go (linux, darwin, windows)
+	// Useful in skip_if conditions, e.g. skip_if: "{{OS}} != windows"
+	templateCtx["OS"] = runtime.GOOS
+
 	// Add TEMP_DIR: system temporary directory (cross-platform)
 	// Windows: %TEMP% or %TMP%, Linux/macOS: /tmp or $TMPDIR
 	templateCtx["TEMP_DIR"] = os.TempDir()
@@ -1316,11 +2456,61 @@ func CreateStaticTemplateContext(sourceFile string, variables map[string]string)
 	// Pre-transform variables if they contain templates
 	// This allows variables to reference other variables or TEST_DIR
 	for k, v := range variables {
+		if override, ok := os.LookupEnv(VariableEnvPrefix + k); ok {
+			templateCtx[k] = override
+			continue
+		}
 		templateCtx[k] = model.RenderTemplate(v, templateCtx)
 	}
 	return templateCtx
 }
 
+// ResolveReportBase returns the report path without extension and creates its directory.
+//
+// Without outputDir, reportFileName is used as given, defaulting to test_results/report
+// next to the test or suite file. With outputDir, a relative reportFileName is placed
+// inside it, and an empty one becomes agent-bench-<RUN_ID>-<timestamp> so repeated
+// runs never overwrite each other.
+func ResolveReportBase(reportFileName, outputDir, testPath, suitePath, runID string, startTime time.Time) (string, error) {
+	if outputDir != "" {
+		if reportFileName == "" {
+			if runID == "" {
+				runID = uuid.New().String()
+			}
+			reportFileName = fmt.Sprintf("agent-bench-%s-%s", runID, startTime.Format("20060102-150405"))
+		}
+		if !filepath.IsAbs(reportFileName) {
+			reportFileName = filepath.Join(outputDir, reportFileName)
+		}
+		if err := os.MkdirAll(filepath.Dir(reportFileName), 0755); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return reportFileName, nil
+	}
+
+	if reportFileName != "" {
+		return reportFileName, nil
+	}
+
+	// Default to test_results folder in the test file's directory
+	configPath := testPath
+	if configPath == "" {
+		configPath = suitePath
+	}
+	if configPath == "" {
+		return "report", nil
+	}
+	absPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return "report", nil
+	}
+	reportDir := filepath.Join(filepath.Dir(absPath), "test_results")
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create test_results directory: %w", err)
+	}
+	return filepath.Join(reportDir, "report"), nil
+}
+
 // CreateTemplateContext creates the full template context for test execution.
 // It builds on static context and adds runtime variables.
 // This function is kept for backward compatibility.
@@ -1328,13 +2518,168 @@ func CreateTemplateContext(variables map[string]string) map[string]string {
 	return CreateStaticTemplateContext("", variables)
 }
 
+// BuildRunMetadata collects reproducibility metadata for a run: build info, RUN_ID,
+// the seed, host platform and the resolved (templated) provider parameters. Tokens and secrets
+// are never copied, and credentials embedded in base URLs are stripped.
+func BuildRunMetadata(startTime time.Time, seed int64, providers []model.Provider, templateCtx map[string]string) *model.RunMetadata {
+	metadata := &model.RunMetadata{
+		Version:   version.Version,
+		Commit:    version.Commit,
+		BuildDate: version.BuildDate,
+		RunID:     templateCtx["RUN_ID"],
+		Seed:      seed,
+		StartTime: startTime,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	for _, p := range providers {
+		metadata.Providers = append(metadata.Providers, *BuildProviderMetadata(p, templateCtx))
+	}
+
+	// Keep provider order stable so metadata can be diffed between runs
+	sort.Slice(metadata.Providers, func(i, j int) bool {
+		return metadata.Providers[i].Name < metadata.Providers[j].Name
+	})
+
+	return metadata
+}
+
+// addJudgeMetadata records the judge models in the run metadata.
+func addJudgeMetadata(metadata *model.RunMetadata, judge *model.Provider, judges []model.Provider, templateCtx map[string]string) {
+	if judge != nil {
+		metadata.Judge = BuildProviderMetadata(*judge, templateCtx)
+	}
+	for _, j := range JudgeProviders(nil, judges) {
+		metadata.Judges = append(metadata.Judges, *BuildProviderMetadata(j, templateCtx))
+	}
+}
+
+// WarmupTimeout bounds the warm-up request of a provider.
+const WarmupTimeout = 2 * time.Minute
+
+// WarmUpProvider sends one throwaway request to a provider so that cold starts and
+// connection setup do not count against the first timed test. The request is not
+// part of any test or statistic; its latency is only logged, and a failure is a
+// warning since the tests report their own errors. Returns the latency.
+func WarmUpProvider(ctx context.Context, name string, llm llms.Model) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, WarmupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := llm.GenerateContent(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "Reply with OK."),
+	})
+	latency := time.Since(start)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Provider warm-up failed", "provider", name, "latency", latency, "error", err)
+		return latency
+	}
+	logger.FromContext(ctx).Info("Provider warmed up", "provider", name, "latency", latency)
+	return latency
+}
+
+// BuildProviderMetadata returns the resolved, non-secret parameters of a provider.
+func BuildProviderMetadata(p model.Provider, templateCtx map[string]string) *model.ProviderMetadata {
+	return &model.ProviderMetadata{
+		Name:        model.RenderTemplate(p.Name, templateCtx),
+		Type:        p.Type,
+		Model:       model.RenderTemplate(p.Model, templateCtx),
+		BaseURL:     redactURL(model.RenderTemplate(p.BaseURL, templateCtx)),
+		Version:     model.RenderTemplate(p.Version, templateCtx),
+		Location:    model.RenderTemplate(p.Location, templateCtx),
+		AuthType:    model.RenderTemplate(p.AuthType, templateCtx),
+		Temperature: p.Temperature,
+		Seed:        p.Seed,
+		Tokenizer:   providerTokenizer(p, templateCtx).Name(),
+	}
+}
+
+// providerTokenizer returns the local tokenizer matching a provider's model family.
+func providerTokenizer(p model.Provider, templateCtx map[string]string) tokenizer.Tokenizer {
+	return tokenizer.ForModel(p.Type, model.RenderTemplate(p.Model, templateCtx))
+}
+
+// redactURL removes user credentials and query parameters (which may carry API keys) from a URL
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[REDACTED]"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
 func GenerateReports(results []model.TestRun, reportType, outputPath string, aiSummary *agent.AISummaryResult, testFilePath string) error {
+	return GenerateReportsWithMetadata(results, reportType, outputPath, aiSummary, testFilePath, nil)
+}
+
+// GenerateReportsWithMetadata generates a report that also carries reproducibility metadata
+// (JSON: run_metadata section, HTML: footer). metadata may be nil.
+func GenerateReportsWithMetadata(results []model.TestRun, reportType, outputPath string, aiSummary *agent.AISummaryResult, testFilePath string, metadata *model.RunMetadata) error {
 	if len(results) == 0 {
 		return fmt.Errorf("no test results to generate report")
 	}
 
+	printConsoleReport(results, aiSummary, testFilePath, metadata)
+
+	inputs, err := newReportInputs(results, []string{reportType}, aiSummary, testFilePath, metadata, report.Options{})
+	if err != nil {
+		return err
+	}
+	return inputs.write(reportType, outputPath)
+}
+
+// GenerateAllReports writes one file per report type to basePath + "." + its
+// ReportExtension.
+// The console report and the shared view model are produced once, and the
+// renderers run concurrently. A failing renderer does not stop the others;
+// all failures are joined into the returned error.
+func GenerateAllReports(results []model.TestRun, reportTypes []string, basePath string, aiSummary *agent.AISummaryResult, testFilePath string, metadata *model.RunMetadata) error {
+	return GenerateAllReportsWithOptions(context.Background(), results, reportTypes, basePath, aiSummary, testFilePath, metadata, report.Options{})
+}
+
+// GenerateAllReportsWithOptions is GenerateAllReports with the presentation
+// options of the HTML report, logging to the logger of ctx.
+func GenerateAllReportsWithOptions(ctx context.Context, results []model.TestRun, reportTypes []string, basePath string, aiSummary *agent.AISummaryResult, testFilePath string, metadata *model.RunMetadata, options report.Options) error {
+	if len(results) == 0 {
+		return fmt.Errorf("no test results to generate report")
+	}
+
+	printConsoleReport(results, aiSummary, testFilePath, metadata)
+
+	inputs, err := newReportInputs(results, reportTypes, aiSummary, testFilePath, metadata, options)
+	if err != nil {
+		return err
+	}
+	inputs.log = logger.FromContext(ctx)
+
+	errs := make([]error, len(reportTypes))
+	var g errgroup.Group
+	for i, rt := range reportTypes {
+		g.Go(func() error {
+			if err := inputs.write(rt, basePath+"."+ReportExtension(rt)); err != nil {
+				errs[i] = fmt.Errorf("%s report: %w", rt, err)
+			}
+			// Errors are collected in errs so one failure does not hide the others.
+			return nil
+		})
+	}
+	g.Wait()
+
+	return errors.Join(errs...)
+}
+
+// printConsoleReport prints the console report, the summary and the AI summary.
+func printConsoleReport(results []model.TestRun, aiSummary *agent.AISummaryResult, testFilePath string, metadata *model.RunMetadata) {
 	reporter := model.NewReportGenerator()
 	reporter.TestFile = testFilePath
+	reporter.RunMetadata = metadata
 
 	// Generate console report
 	fmt.Println("\n" + strings.Repeat("=", 80))
@@ -1350,38 +2695,87 @@ func GenerateReports(results []model.TestRun, reportType, outputPath string, aiS
 		fmt.Println(aiSummary.Analysis)
 		fmt.Println(strings.Repeat("=", 80))
 	}
+}
 
-	reportContent := ""
-	switch reportType {
-	case "json":
-		// Convert agent.AISummaryResult to model.AISummaryData (avoiding circular import)
-		var analysisData *model.AISummaryData
-		if aiSummary != nil {
-			analysisData = &model.AISummaryData{
-				Success:   aiSummary.Success,
-				Analysis:  aiSummary.Analysis,
-				Error:     aiSummary.Error,
-				Retryable: aiSummary.Retryable,
-				Guidance:  aiSummary.Guidance,
-			}
+// reportInputs holds everything the renderers share. It is read-only once built,
+// so renderers can use it concurrently.
+type reportInputs struct {
+	log      *slog.Logger
+	results  []model.TestRun
+	reporter *model.ReportGenerator
+	analysis *model.AISummaryData
+	html     *report.Generator
+	htmlData report.ReportData
+}
+
+func newReportInputs(results []model.TestRun, reportTypes []string, aiSummary *agent.AISummaryResult, testFilePath string, metadata *model.RunMetadata, options report.Options) (*reportInputs, error) {
+	in := &reportInputs{
+		log:      logger.Logger,
+		results:  results,
+		reporter: model.NewReportGenerator(),
+	}
+	in.reporter.TestFile = testFilePath
+	in.reporter.RunMetadata = metadata
+
+	// Convert agent.AISummaryResult to model.AISummaryData (avoiding circular import)
+	if aiSummary != nil {
+		in.analysis = &model.AISummaryData{
+			Success:   aiSummary.Success,
+			Analysis:  aiSummary.Analysis,
+			Error:     aiSummary.Error,
+			Retryable: aiSummary.Retryable,
+			Guidance:  aiSummary.Guidance,
 		}
-		reportContent = reporter.GenerateJSONReportWithAnalysis(results, analysisData)
-	case "html":
-		// Use the new template-based HTML generator
-		gen, err := report.NewGenerator()
+	}
+
+	if slices.Contains(reportTypes, "html") {
+		gen, err := report.NewGeneratorWithOptions(options)
 		if err != nil {
-			return fmt.Errorf("failed to create report generator: %w", err)
+			return nil, fmt.Errorf("failed to create report generator: %w", err)
 		}
-		// Pass AI summary to HTML generator
-		htmlContent, err := gen.GenerateHTMLWithAnalysis(results, aiSummary)
+		gen.RunMetadata = metadata
+		in.html = gen
+		in.htmlData = gen.BuildReportData(results, aiSummary)
+	}
+
+	return in, nil
+}
+
+// render produces the content of a single report type.
+func (in *reportInputs) render(reportType string) (string, error) {
+	switch reportType {
+	case "json":
+		return in.reporter.GenerateJSONReportWithAnalysis(in.results, in.analysis), nil
+	case "html":
+		htmlContent, err := in.html.Render(in.htmlData)
 		if err != nil {
-			return fmt.Errorf("failed to generate HTML report: %w", err)
+			return "", fmt.Errorf("failed to generate HTML report: %w", err)
 		}
-		reportContent = htmlContent
+		return htmlContent, nil
 	case "md":
-		reportContent = reporter.GenerateMarkdownReport(results)
+		return in.reporter.GenerateMarkdownReport(in.results), nil
+	case "txt":
+		return in.reporter.GenerateTextReport(in.results), nil
+	case "sarif":
+		return in.reporter.GenerateSARIFReport(in.results), nil
+	case "junit":
+		return in.reporter.GenerateJUnitReport(in.results), nil
+	case "csv":
+		return in.reporter.GenerateCSVReport(in.results), nil
 	default:
-		return fmt.Errorf("Unknown report type")
+		return "", fmt.Errorf("Unknown report type")
+	}
+}
+
+// write renders a report type and writes it to outputPath.
+func (in *reportInputs) write(reportType, outputPath string) error {
+	if reportType == "mermaid" {
+		_, err := writeMermaidDiagrams(in.log, in.results, outputPath)
+		return err
+	}
+	reportContent, err := in.render(reportType)
+	if err != nil {
+		return err
 	}
 
 	if reportContent == "" {
@@ -1397,8 +2791,7 @@ func GenerateReports(results []model.TestRun, reportType, outputPath string, aiS
 	}
 
 	// Write report to file
-	err := os.WriteFile(outputPath, []byte(reportContent), logger.FilePermission)
-	if err != nil {
+	if err := os.WriteFile(outputPath, []byte(reportContent), logger.FilePermission); err != nil {
 		return fmt.Errorf("failed to write report file: %w", err)
 	}
 
@@ -1408,7 +2801,7 @@ func GenerateReports(results []model.TestRun, reportType, outputPath string, aiS
 		return fmt.Errorf("failed to verify output file: %w", err)
 	}
 
-	logger.Logger.Info("Report generated successfully", "size", info.Size())
+	in.log.Info("Report generated successfully", "type", reportType, "path", outputPath, "size", info.Size())
 	return nil
 }
 
@@ -1441,13 +2834,23 @@ func PrintTestSummary(results []model.TestRun) {
 		return
 	}
 
+	// Skipped tests are listed but excluded from the rates and averages
+	skippedTests := len(results) - len(model.ExecutedRuns(results))
+	results = model.ExecutedRuns(results)
+	if len(results) == 0 {
+		logger.Logger.Info("All tests were skipped", "skipped", skippedTests)
+		return
+	}
+
 	totalTests := len(results)
 	passedTests := 0
 	failedTests := 0
 	totalToolCalls := 0
 	totalErrors := 0
+	totalWarnings := 0
 	var totalDuration int64
 	totalTokens := 0
+	tokensEstimated := false
 
 	for _, result := range results {
 		if result.Passed {
@@ -1455,12 +2858,14 @@ func PrintTestSummary(results []model.TestRun) {
 		} else {
 			failedTests++
 		}
+		totalWarnings += model.CountWarnings(result.Assertions)
 
 		if result.Execution != nil {
 			totalToolCalls += len(result.Execution.ToolCalls)
 			totalErrors += len(result.Execution.Errors)
 			totalDuration += result.Execution.LatencyMs
 			totalTokens += result.Execution.TokensUsed
+			tokensEstimated = tokensEstimated || result.Execution.TokensEstimated
 		}
 	}
 
@@ -1474,16 +2879,40 @@ func PrintTestSummary(results []model.TestRun) {
 	fmt.Printf("  Total Tests:      %d\n", totalTests)
 	fmt.Printf("  Passed:           %d (%.1f%%)\n", passedTests, passRate)
 	fmt.Printf("  Failed:           %d (%.1f%%)\n", failedTests, failRate)
+	if skippedTests > 0 {
+		fmt.Printf("  Skipped:          %d (not counted in pass rate)\n", skippedTests)
+	}
+	if model.HasCustomWeights(results) {
+		fmt.Printf("  Weighted Pass:    %.1f%% (tests counted by weight)\n", model.WeightedPassRate(results)*100)
+	}
 	fmt.Printf("  Total Tool Calls: %d\n", totalToolCalls)
 	fmt.Printf("  Total Errors:     %d\n", totalErrors)
+	if totalWarnings > 0 {
+		fmt.Printf("  Warnings:         %d (warning-level assertion failures)\n", totalWarnings)
+	}
 	fmt.Printf("  Total Duration:   %dms (avg: %dms per test)\n", totalDuration, avgDuration)
-	fmt.Printf("  Total Tokens:     %d\n", totalTokens)
+	if tokensEstimated {
+		fmt.Printf("  Total Tokens:     ~%d (includes estimates for providers that reported no usage)\n", totalTokens)
+	} else {
+		fmt.Printf("  Total Tokens:     %d\n", totalTokens)
+	}
+	printFileSummary(results)
+	printAgentRanking(results)
+	printIdenticalOutputs(results)
+	printKeptTempDirs(results)
+	fmt.Println(strings.Repeat("=", 80))
+	verdict := "PASS"
+	if failedTests > 0 {
+		verdict = "FAIL"
+	}
+	fmt.Printf("  RESULT: %s (%d/%d passed, %.1f%%)\n", verdict, passedTests, totalTests, passRate)
 	fmt.Println(strings.Repeat("=", 80))
 
 	logger.Logger.Info("Test execution summary",
 		"total_tests", totalTests,
 		"passed", passedTests,
 		"failed", failedTests,
+		"skipped", skippedTests,
 		"pass_rate", fmt.Sprintf("%.1f%%", passRate),
 		"tool_calls", totalToolCalls,
 		"errors", totalErrors,
@@ -1492,9 +2921,94 @@ func PrintTestSummary(results []model.TestRun) {
 		"tokens", totalTokens)
 }
 
+// printFileSummary prints pass/fail counts per source file for suite runs.
+// It reuses the HTML report grouping so both show the same numbers.
+func printFileSummary(results []model.TestRun) {
+	executed := make([]model.TestRun, 0, len(results))
+	isSuiteRun := false
+	for _, result := range results {
+		if result.Execution == nil {
+			continue
+		}
+		executed = append(executed, result)
+		if result.Execution.SourceFile != "" {
+			isSuiteRun = true
+		}
+	}
+	if !isSuiteRun {
+		return
+	}
+
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Println("  By File:")
+	for _, fg := range report.FileGroups(executed) {
+		verdict := "PASS"
+		if fg.FailedTests > 0 {
+			verdict = "FAIL"
+		}
+		fmt.Printf("    [%s] %s: %d/%d passed (%.1f%%)\n",
+			verdict, fg.FileName, fg.PassedTests, fg.TotalTests, fg.SuccessRate)
+	}
+}
+
+// printAgentRanking prints the leaderboard order when several agents ran,
+// using the same ranking as the HTML report.
+func printAgentRanking(results []model.TestRun) {
+	executed := make([]model.TestRun, 0, len(results))
+	agents := make(map[string]bool)
+	for _, result := range results {
+		if result.Execution == nil {
+			continue
+		}
+		executed = append(executed, result)
+		agents[result.Execution.AgentName] = true
+	}
+	if len(agents) < 2 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Println("  Agent Ranking:")
+	for _, stats := range report.AgentRanking(executed) {
+		fmt.Printf("    %-3s %s: %d/%d passed (%.1f%%)\n",
+			stats.RankDisplay, stats.AgentName, stats.PassedTests, stats.TotalTests, stats.SuccessRate)
+	}
+}
+
+// printIdenticalOutputs warns about tests where different agents produced the same
+// final output, which usually points at a duplicated provider or a response cache.
+func printIdenticalOutputs(results []model.TestRun) {
+	groups := report.IdenticalOutputs(results)
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("-", 80))
+	fmt.Println("  Identical Outputs (check for a shared provider or response cache):")
+	for _, g := range groups {
+		fmt.Printf("    %s: %s\n", g.TestName, strings.Join(g.Agents, ", "))
+	}
+}
+
+// printKeptTempDirs lists the per-test directories left in place by -keep-temp.
+func printKeptTempDirs(results []model.TestRun) {
+	header := false
+	for _, r := range results {
+		if r.Execution == nil || r.Execution.TempDir == "" {
+			continue
+		}
+		if !header {
+			fmt.Println(strings.Repeat("-", 80))
+			fmt.Println("  Artifacts kept (-keep-temp):")
+			header = true
+		}
+		fmt.Printf("    %s [%s]: %s\n", r.Execution.TestName, r.Execution.AgentName, r.Execution.TempDir)
+	}
+}
+
 func HasFailures(results []model.TestRun) bool {
 	for _, result := range results {
-		if !result.Passed {
+		if !result.Passed && !result.Skipped {
 			return true
 		}
 	}
@@ -1523,6 +3037,52 @@ func ParseTimeout(timeoutStr string) time.Duration {
 	return dur
 }
 
+// ResolveTestTimeout returns the timeout for a single test. The first non-empty
+// value wins in order test > session > global; zero means no timeout.
+func ResolveTestTimeout(global, session, test string) time.Duration {
+	for _, timeout := range []string{test, session, global} {
+		if timeout != "" {
+			return ParseTimeout(timeout)
+		}
+	}
+	return DefaultTimeout
+}
+
+// ResolveTestRetries returns how many times a test is retried after an
+// execution error and the backoff between attempts; the test's values override
+// the session's.
+func ResolveTestRetries(session model.Session, test model.Test) (int, time.Duration) {
+	retries, backoff := session.Retries, session.RetryBackoff
+	if test.Retries != nil {
+		retries = *test.Retries
+	}
+	if test.RetryBackoff != "" {
+		backoff = test.RetryBackoff
+	}
+	return retries, ParseDelay(backoff)
+}
+
+// AddTokenUsage adds the token counts of from to r, so that the attempts of a
+// retried test count toward its cost.
+func AddTokenUsage(r, from *model.ExecutionResult) {
+	r.TokensUsed += from.TokensUsed
+	r.TokensEstimated = r.TokensEstimated || from.TokensEstimated
+	r.PromptTokens += from.PromptTokens
+	r.CompletionTokens += from.CompletionTokens
+	r.CacheReadTokens += from.CacheReadTokens
+	r.CacheWriteTokens += from.CacheWriteTokens
+}
+
+// ShouldRetryTest reports whether an attempt that produced result is retried:
+// only execution errors are, and not when the run was interrupted or reached
+// its deadline, since another attempt could not complete either.
+func ShouldRetryTest(ctx context.Context, result *model.ExecutionResult) bool {
+	if len(result.Errors) == 0 || ctx.Err() != nil {
+		return false
+	}
+	return !result.HasErrorKind(model.ErrorKindInterrupted) && !result.HasErrorKind(model.ErrorKindRunDeadline)
+}
+
 func ParseDelay(delayStr string) time.Duration {
 	if delayStr == "" {
 		return DefaultTestDelay
@@ -1565,6 +3125,24 @@ func GetServerNames(servers []model.AgentServer) []string {
 	return names
 }
 
+// ResolveSuiteVariables combines suite-level and file-level variables according to the
+// suite's variable policy. By default (empty or unknown policy) only the suite variables
+// are used.
+func ResolveSuiteVariables(policy model.VariablePolicy, suiteVariables, fileVariables map[string]string) map[string]string {
+	switch policy {
+	case model.TestOnly:
+		return fileVariables
+	case model.MergeTestPriority:
+		return MergeVariables(fileVariables, suiteVariables)
+	case model.MergeSuitePriority:
+		return MergeVariables(suiteVariables, fileVariables)
+	case model.SuiteOnly, "":
+		fallthrough
+	default:
+		return suiteVariables
+	}
+}
+
 func MergeVariables(primary map[string]string, secondary map[string]string) map[string]string {
 	merged := make(map[string]string)
 	if secondary != nil {
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// TestTempDirVar is the template variable holding the fresh directory every
+// test gets while it runs.
+const TestTempDirVar = "TEST_TEMP_DIR"
+
+// FixtureDirVar is the template variable holding the directory a test's fixtures
+// were copied into, the test's TestTempDirVar. It is only set while a test with
+// fixtures runs.
+const FixtureDirVar = "FIXTURE_DIR"
+
+type keepTempKey struct{}
+
+// WithKeepTemp returns a context telling the engine whether to leave per-test
+// directories in place after the tests finish.
+func WithKeepTemp(ctx context.Context, keep bool) context.Context {
+	return context.WithValue(ctx, keepTempKey{}, keep)
+}
+
+// KeepTempFromContext reports whether per-test directories should be kept.
+func KeepTempFromContext(ctx context.Context) bool {
+	keep, _ := ctx.Value(keepTempKey{}).(bool)
+	return keep
+}
+
+// PrepareFixtures creates a test's directory under the system temporary
+// directory, copies the test's fixtures into it and returns its path; without
+// fixtures the directory is empty. Fixture paths are rendered with the
+// template context and resolved against TEST_DIR when relative. Files and
+// directories keep their base name, so two fixtures with the same name conflict.
+// On error nothing is left behind.
+func PrepareFixtures(fixtures []string, templateCtx map[string]string) (string, error) {
+	dir, err := os.MkdirTemp("", "agent-bench-test-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create test directory: %w", err)
+	}
+
+	seen := make(map[string]string, len(fixtures))
+	for _, fixture := range fixtures {
+		src := model.RenderTemplate(fixture, templateCtx)
+		if !filepath.IsAbs(src) && templateCtx["TEST_DIR"] != "" {
+			src = filepath.Join(templateCtx["TEST_DIR"], src)
+		}
+		name := filepath.Base(src)
+		if prev, ok := seen[name]; ok {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("fixtures %q and %q both copy to %q", prev, fixture, name)
+		}
+		seen[name] = fixture
+
+		if err := copyFixture(src, filepath.Join(dir, name)); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to copy fixture %q: %w", fixture, err)
+		}
+	}
+	return dir, nil
+}
+
+// setTestDir exposes dir as the running test's TestTempDirVar, and as its
+// FixtureDirVar when it holds fixtures; an empty dir removes both.
+func setTestDir(templateCtx map[string]string, dir string, fixtures bool) {
+	delete(templateCtx, TestTempDirVar)
+	delete(templateCtx, FixtureDirVar)
+	if dir == "" {
+		return
+	}
+	templateCtx[TestTempDirVar] = dir
+	if fixtures {
+		templateCtx[FixtureDirVar] = dir
+	}
+}
+
+// CleanupFixtures removes a test's directory unless keep is set, in which case
+// its location is logged for inspection.
+func CleanupFixtures(dir string, keep bool) {
+	if dir == "" {
+		return
+	}
+	if keep {
+		logger.Logger.Info("Keeping test directory", "path", dir)
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Logger.Warn("Failed to remove test directory", "path", dir, "error", err)
+	}
+}
+
+func copyFixture(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return os.CopyFS(dst, os.DirFS(src))
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -10,6 +10,11 @@ import (
 	"github.com/mykhaliev/agent-benchmark/logger"
 )
 
+// httpDoer is the HTTP client interface accepted by the LangChainGo provider clients.
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
 // RetryAfterHTTPClient wraps an http.Client to capture Retry-After headers from 429 responses.
 // This is needed because LangChainGo doesn't expose HTTP headers in errors, only the error message.
 // By intercepting the response, we can extract the actual Retry-After header value.
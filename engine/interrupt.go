@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+)
+
+// InterruptedExitCode is returned when a run is stopped by SIGINT or SIGTERM,
+// following the shell convention of 128 + SIGINT.
+const InterruptedExitCode = 130
+
+// ErrRunInterrupted is returned by RunWithConfig when the run was stopped
+// before all tests ran.
+var ErrRunInterrupted = errors.New("run interrupted")
+
+// NotifyInterrupt returns a context that is cancelled on the first SIGINT or
+// SIGTERM, so in-flight work stops and a partial report can still be written.
+// A second signal exits the process immediately. stop releases the handler.
+func NotifyInterrupt(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.FromContext(parent).Warn("Interrupt received, stopping run and writing partial report (send again to force exit)",
+				"signal", sig.String())
+			cancel()
+		case <-done:
+			return
+		}
+
+		select {
+		case sig := <-signals:
+			logger.FromContext(parent).Error("Second interrupt received, exiting immediately", "signal", sig.String())
+			os.Exit(InterruptedExitCode)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
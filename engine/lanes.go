@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// OrderedAgentNames returns the names of the initialized agents in the order they
+// are configured, followed by any agents missing from the configuration sorted by
+// name, so that scheduling and merged results do not depend on map iteration.
+func OrderedAgentNames(agentConfigs []model.Agent, agents map[string]*agent.MCPAgent) []string {
+	names := make([]string, 0, len(agents))
+	seen := make(map[string]bool, len(agents))
+	for _, a := range agentConfigs {
+		if _, ok := agents[a.Name]; ok && !seen[a.Name] {
+			names = append(names, a.Name)
+			seen[a.Name] = true
+		}
+	}
+	extra := make([]string, 0)
+	for name := range agents {
+		if !seen[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+// AgentLanes groups agents into lanes that may run concurrently. Agents talk to a
+// server through one shared MCP connection, so agents that use the same server
+// (directly or through another agent) end up in the same lane and run one after
+// the other. Lanes and the agents within them keep the order of names.
+func AgentLanes(agentConfigs []model.Agent, names []string) [][]string {
+	agentServers := make(map[string][]string, len(agentConfigs))
+	for _, a := range agentConfigs {
+		for _, srv := range a.Servers {
+			agentServers[a.Name] = append(agentServers[a.Name], srv.Name)
+		}
+	}
+
+	// Union agents that share a server; the lane root is the earliest agent
+	parent := make([]int, len(names))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	serverOwner := make(map[string]int)
+	for i, name := range names {
+		for _, srv := range agentServers[name] {
+			owner, ok := serverOwner[srv]
+			if !ok {
+				serverOwner[srv] = i
+				continue
+			}
+			a, b := find(owner), find(i)
+			if a > b {
+				a, b = b, a
+			}
+			parent[b] = a
+		}
+	}
+
+	laneIndex := make(map[int]int)
+	lanes := make([][]string, 0)
+	for i, name := range names {
+		root := find(i)
+		idx, ok := laneIndex[root]
+		if !ok {
+			idx = len(lanes)
+			laneIndex[root] = idx
+			lanes = append(lanes, nil)
+		}
+		lanes[idx] = append(lanes[idx], name)
+	}
+	return lanes
+}
+
+// ResolveConcurrency returns how many agent lanes may run at once; anything
+// below 1 means the agents run one at a time.
+func ResolveConcurrency(concurrency int) int {
+	if concurrency < 1 {
+		return 1
+	}
+	return concurrency
+}
+
+type parallelKey struct{}
+
+// WithParallel returns a context overriding settings.concurrency of every test
+// file run with it; 0 keeps each file's own setting.
+func WithParallel(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, parallelKey{}, n)
+}
+
+// RunConcurrency resolves the concurrency of a run from the WithParallel
+// override and the file's settings.concurrency.
+func RunConcurrency(ctx context.Context, settingsConcurrency int) int {
+	if parallel, _ := ctx.Value(parallelKey{}).(int); parallel > 0 {
+		return ResolveConcurrency(parallel)
+	}
+	return ResolveConcurrency(settingsConcurrency)
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/report"
+)
+
+// mermaidFile is a session diagram and the path it is written to
+type mermaidFile struct {
+	path    string
+	diagram string
+}
+
+// mermaidFiles names the session diagrams of results next to outputPath:
+// <outputPath without .mmd>.<session>--<agent>.mmd, prefixed with the test
+// file name (<file>--<session>--<agent>) in suite runs.
+func mermaidFiles(results []model.TestRun, outputPath string) []mermaidFile {
+	base := strings.TrimSuffix(outputPath, ".mmd")
+	diagrams := report.SessionSequenceDiagrams(results)
+	files := make([]mermaidFile, 0, len(diagrams))
+	used := make(map[string]int)
+	for _, d := range diagrams {
+		name := Slugify(d.Session) + "--" + Slugify(d.Agent)
+		if d.SourceFile != "" {
+			file := filepath.Base(d.SourceFile)
+			name = Slugify(strings.TrimSuffix(file, filepath.Ext(file))) + "--" + name
+		}
+		used[name]++
+		if n := used[name]; n > 1 {
+			name = fmt.Sprintf("%s-%d", name, n)
+		}
+		files = append(files, mermaidFile{path: base + "." + name + ".mmd", diagram: d.Diagram})
+	}
+	return files
+}
+
+// MermaidDiagramPaths returns the paths WriteMermaidDiagrams writes for results.
+func MermaidDiagramPaths(results []model.TestRun, outputPath string) []string {
+	files := mermaidFiles(results, outputPath)
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	return paths
+}
+
+// WriteMermaidDiagrams writes the session sequence diagrams of the HTML report
+// next to outputPath, one .mmd file per session and agent named as described
+// on mermaidFiles, and returns the paths written, logging to the logger of ctx.
+func WriteMermaidDiagrams(ctx context.Context, results []model.TestRun, outputPath string) ([]string, error) {
+	return writeMermaidDiagrams(logger.FromContext(ctx), results, outputPath)
+}
+
+func writeMermaidDiagrams(log *slog.Logger, results []model.TestRun, outputPath string) ([]string, error) {
+	files := mermaidFiles(results, outputPath)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no executed tests to draw")
+	}
+	if dir := filepath.Dir(outputPath); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := os.WriteFile(f.path, []byte(f.diagram), logger.FilePermission); err != nil {
+			return paths, fmt.Errorf("failed to write diagram: %w", err)
+		}
+		paths = append(paths, f.path)
+	}
+	log.Info("Report generated successfully", "type", "mermaid", "files", len(paths), "paths", strings.Join(paths, ", "))
+	return paths, nil
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ModelProviderName names the clone of a provider that runs modelName, under
+// which it is registered with the other providers.
+func ModelProviderName(provider, modelName string) string {
+	return provider + "@" + modelName
+}
+
+// ModelOverrideProviders returns a clone of the referenced provider for every
+// distinct model override of the agents and of the tests in sessions. A test
+// override clones the provider of each agent that runs the test. Overrides of
+// unknown providers or agents are left to the reference checks.
+func ModelOverrideProviders(providers []model.Provider, agents []model.Agent, sessions []model.Session) ([]model.Provider, error) {
+	providerDefs := make(map[string]model.Provider, len(providers))
+	for _, p := range providers {
+		providerDefs[p.Name] = p
+	}
+
+	clones := make([]model.Provider, 0)
+	seen := make(map[string]bool)
+	add := func(providerName, modelName, owner string) error {
+		p, ok := providerDefs[providerName]
+		if !ok {
+			return nil
+		}
+		if err := validateModelOverride(p, modelName); err != nil {
+			return fmt.Errorf("%s: %w", owner, err)
+		}
+		name := ModelProviderName(providerName, modelName)
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+		p.Name = name
+		p.Model = modelName
+		clones = append(clones, p)
+		return nil
+	}
+
+	for _, a := range agents {
+		if a.Model == "" {
+			continue
+		}
+		if err := add(a.Provider, a.Model, fmt.Sprintf("agent '%s'", a.Name)); err != nil {
+			return nil, err
+		}
+	}
+	for _, session := range sessions {
+		for _, test := range session.Tests {
+			if test.Model == "" {
+				continue
+			}
+			for _, a := range agents {
+				if test.Agent != "" && test.Agent != a.Name {
+					continue
+				}
+				if err := add(a.Provider, test.Model, fmt.Sprintf("test '%s'", test.Name)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return clones, nil
+}
+
+// validateModelOverride rejects a model that the provider's type cannot run.
+// Templated models are only known at run time and are not checked.
+func validateModelOverride(p model.Provider, modelName string) error {
+	if strings.Contains(modelName, "{{") {
+		return nil
+	}
+	if p.Type == model.ProviderAmazonAnthropic && BedrockModelFamily(modelName) != "anthropic" {
+		return fmt.Errorf("model %q cannot run on provider '%s': %s providers only run Anthropic models (use a %s provider for other models)",
+			modelName, p.Name, p.Type, model.ProviderBedrock)
+	}
+	return nil
+}
+
+// InitModelOverrides creates the provider clones needed by the model overrides of
+// agents and sessions and adds the ones not created yet to providers.
+func InitModelOverrides(
+	ctx context.Context,
+	providers map[string]llms.Model,
+	providerConfigs []model.Provider,
+	agents []model.Agent,
+	sessions []model.Session,
+	templateCtx map[string]string,
+) error {
+	clones, err := ModelOverrideProviders(providerConfigs, agents, sessions)
+	if err != nil {
+		return err
+	}
+	missing := make([]model.Provider, 0, len(clones))
+	for _, p := range clones {
+		if _, ok := providers[p.Name]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	logger.FromContext(ctx).Info("Initializing model overrides", "count", len(missing))
+	created, err := InitProviders(ctx, missing, templateCtx)
+	if err != nil {
+		return err
+	}
+	for name, llm := range created {
+		providers[name] = llm
+	}
+	return nil
+}
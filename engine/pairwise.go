@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// PairwiseJudge compares two answers to the same request. agent.LLMJudge
+// implements it.
+type PairwiseJudge interface {
+	Name() string
+	Compare(ctx context.Context, instructions, prompt, answerA, answerB string) (model.PairwiseVerdict, error)
+}
+
+// PairwiseCache keeps pairwise judgments by judge, instructions, request and
+// both answers, so unchanged comparisons are not judged again. With a path it
+// is loaded from and saved to a JSON file; otherwise it lives for one run.
+type PairwiseCache struct {
+	path    string
+	entries map[string]model.PairwiseVerdict
+	dirty   bool
+}
+
+// LoadPairwiseCache opens the cache stored at path. A missing file is an empty
+// cache, and an empty path gives a cache that is never saved.
+func LoadPairwiseCache(path string) (*PairwiseCache, error) {
+	cache := &PairwiseCache{path: path, entries: make(map[string]model.PairwiseVerdict)}
+	if path == "" {
+		return cache, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pairwise cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse pairwise cache %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// Len returns the number of cached judgments.
+func (c *PairwiseCache) Len() int {
+	return len(c.entries)
+}
+
+// Save writes the cache to its file if judgments were added.
+func (c *PairwiseCache) Save() error {
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pairwise cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create pairwise cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pairwise cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+func pairwiseCacheKey(judge, instructions, prompt, answerA, answerB string) string {
+	h := sha256.New()
+	for _, part := range []string{judge, instructions, prompt, answerA, answerB} {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// PairwiseCachePath renders the configured cache file and resolves it against
+// TEST_DIR when relative.
+func PairwiseCachePath(file string, templateCtx map[string]string) string {
+	if file == "" {
+		return ""
+	}
+	path := model.RenderTemplate(file, templateCtx)
+	if !filepath.IsAbs(path) && templateCtx["TEST_DIR"] != "" {
+		path = filepath.Join(templateCtx["TEST_DIR"], path)
+	}
+	return path
+}
+
+// RunPairwiseComparison judges, for every test, the final answers of each two
+// agents that ran it, and records the outcome on both runs. Judges are tried in
+// order until one answers. A pair no judge could compare is left out. Returns
+// the number of comparisons made, including cached ones.
+func RunPairwiseComparison(ctx context.Context, judges []PairwiseJudge, instructions string, cache *PairwiseCache, results []model.TestRun) int {
+	if len(judges) == 0 {
+		return 0
+	}
+
+	// Group each agent's first executed run by test
+	type testKey struct{ sourceFile, session, test string }
+	groups := make(map[testKey]map[string]int)
+	order := make([]testKey, 0)
+	for i, run := range results {
+		if run.Skipped || run.Execution == nil {
+			continue
+		}
+		key := testKey{run.Execution.SourceFile, run.Execution.SessionName, run.Execution.TestName}
+		if groups[key] == nil {
+			groups[key] = make(map[string]int)
+			order = append(order, key)
+		}
+		if _, seen := groups[key][run.Execution.AgentName]; !seen {
+			groups[key][run.Execution.AgentName] = i
+		}
+	}
+
+	compared := 0
+	for _, key := range order {
+		agents := make([]string, 0, len(groups[key]))
+		for name := range groups[key] {
+			agents = append(agents, name)
+		}
+		sort.Strings(agents)
+
+		for a := 0; a < len(agents); a++ {
+			for b := a + 1; b < len(agents); b++ {
+				runA, runB := &results[groups[key][agents[a]]], &results[groups[key][agents[b]]]
+				verdict, cached, err := judgeBothOrders(ctx, judges, instructions, cache,
+					pairwiseRequest(runA.Execution), runA.Execution.FinalOutput, runB.Execution.FinalOutput)
+				if err != nil {
+					logger.FromContext(ctx).Warn("Pairwise comparison failed",
+						"test", key.test, "agent_a", agents[a], "agent_b", agents[b], "error", err)
+					continue
+				}
+
+				resultA, resultB := model.PairwiseTie, model.PairwiseTie
+				switch verdict.Winner {
+				case "A":
+					resultA, resultB = model.PairwiseWin, model.PairwiseLoss
+				case "B":
+					resultA, resultB = model.PairwiseLoss, model.PairwiseWin
+				}
+				runA.Pairwise = append(runA.Pairwise, model.PairwiseOutcome{
+					Opponent: agents[b], Result: resultA, Reason: verdict.Reason, Cached: cached,
+				})
+				runB.Pairwise = append(runB.Pairwise, model.PairwiseOutcome{
+					Opponent: agents[a], Result: resultB, Reason: verdict.Reason, Cached: cached,
+				})
+				compared++
+			}
+		}
+	}
+	return compared
+}
+
+// RunPairwiseAnalysis runs the configured pairwise comparison with the judges of
+// panel and saves the cache. Problems are logged; the run's reports are still written.
+func RunPairwiseAnalysis(ctx context.Context, panel *model.JudgePanel, config model.PairwiseConfig, results []model.TestRun) {
+	log := logger.FromContext(ctx)
+	var judges []PairwiseJudge
+	if panel != nil {
+		for _, j := range panel.Judges {
+			if pj, ok := j.(PairwiseJudge); ok {
+				judges = append(judges, pj)
+			}
+		}
+	}
+	if len(judges) == 0 {
+		log.Warn("Pairwise comparison skipped: no judge configured. Add a top-level judge provider to enable it")
+		return
+	}
+
+	cache, err := LoadPairwiseCache(config.CacheFile)
+	if err != nil {
+		log.Warn("Ignoring pairwise cache", "error", err)
+		cache, _ = LoadPairwiseCache("")
+	}
+	log.Info("Running pairwise comparison", "cached_judgments", cache.Len())
+	compared := RunPairwiseComparison(ctx, judges, config.Prompt, cache, results)
+	if err := cache.Save(); err != nil {
+		log.Warn("Failed to save pairwise cache", "error", err)
+	}
+	log.Info("Pairwise comparison completed", "comparisons", compared)
+}
+
+// judgeBothOrders compares the answers once in each order so that neither
+// agent benefits from being shown first. The winner is kept only when both
+// orders agree; otherwise the pair is a tie. The verdict's Winner refers to
+// answerA and answerB as given.
+func judgeBothOrders(ctx context.Context, judges []PairwiseJudge, instructions string, cache *PairwiseCache, prompt, answerA, answerB string) (model.PairwiseVerdict, bool, error) {
+	first, firstCached, err := comparePair(ctx, judges, instructions, cache, prompt, answerA, answerB)
+	if err != nil {
+		return model.PairwiseVerdict{}, false, err
+	}
+	second, secondCached, err := comparePair(ctx, judges, instructions, cache, prompt, answerB, answerA)
+	if err != nil {
+		return model.PairwiseVerdict{}, false, err
+	}
+	cached := firstCached && secondCached
+
+	swapped := "tie"
+	switch second.Winner {
+	case "A":
+		swapped = "B"
+	case "B":
+		swapped = "A"
+	}
+	if first.Winner == swapped {
+		return first, cached, nil
+	}
+	return model.PairwiseVerdict{
+		Winner: "tie",
+		Reason: "verdict changed when the answer order was swapped",
+	}, cached, nil
+}
+
+// comparePair returns the cached judgment of a pair or asks the judges for one.
+func comparePair(ctx context.Context, judges []PairwiseJudge, instructions string, cache *PairwiseCache, prompt, answerA, answerB string) (model.PairwiseVerdict, bool, error) {
+	var errs []error
+	for _, judge := range judges {
+		key := pairwiseCacheKey(judge.Name(), instructions, prompt, answerA, answerB)
+		if verdict, ok := cache.entries[key]; ok {
+			return verdict, true, nil
+		}
+		verdict, err := judge.Compare(ctx, instructions, prompt, answerA, answerB)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", judge.Name(), err))
+			continue
+		}
+		cache.entries[key] = verdict
+		cache.dirty = true
+		return verdict, false, nil
+	}
+	return model.PairwiseVerdict{}, false, errors.Join(errs...)
+}
+
+// pairwiseRequest returns what the user asked in a run: its user messages,
+// which covers the follow-up turns of multi-turn tests.
+func pairwiseRequest(exec *model.ExecutionResult) string {
+	parts := make([]string, 0, 1)
+	for _, m := range exec.Messages {
+		if m.Role == "user" {
+			parts = append(parts, m.Content)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
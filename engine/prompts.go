@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// ExpandPromptFiles appends one test per row of each session's prompts_file to
+// the session's tests. The path is rendered with the static template context of
+// sourceFile and resolved against TEST_DIR when relative. Each generated test
+// runs the row's prompt with the session's assertions, and every column of the
+// row is available to them as a variable named after the upper-cased column,
+// e.g. {{EXPECTED}}.
+func ExpandPromptFiles(config *model.TestConfiguration, sourceFile string) error {
+	var templateCtx map[string]string
+	for i := range config.Sessions {
+		session := &config.Sessions[i]
+		if session.PromptsFile == "" {
+			continue
+		}
+		if templateCtx == nil {
+			templateCtx = CreateStaticTemplateContext(sourceFile, config.Variables)
+		}
+
+		path := model.RenderTemplate(session.PromptsFile, templateCtx)
+		if !filepath.IsAbs(path) && templateCtx["TEST_DIR"] != "" {
+			path = filepath.Join(templateCtx["TEST_DIR"], path)
+		}
+		rows, err := LoadPromptRows(path)
+		if err != nil {
+			return fmt.Errorf("session '%s': %w", session.Name, err)
+		}
+
+		for n, row := range rows {
+			name := row["name"]
+			if name == "" {
+				name = fmt.Sprintf("%s #%d", session.Name, n+1)
+			}
+			variables := make(map[string]string, len(row))
+			for column, value := range row {
+				variables[strings.ToUpper(column)] = value
+			}
+			session.Tests = append(session.Tests, model.Test{
+				Name:       name,
+				Prompt:     row["prompt"],
+				Assertions: cloneAssertions(session.Assertions),
+				Variables:  variables,
+			})
+		}
+		logger.Logger.Info("Loaded prompts file", "session", session.Name, "path", path, "tests", len(rows))
+	}
+	return nil
+}
+
+// cloneAssertions deep-copies assertions, since evaluation renders params in place
+// and each generated test renders them with its own row.
+func cloneAssertions(assertions []model.Assertion) []model.Assertion {
+	if assertions == nil {
+		return nil
+	}
+	clones := slices.Clone(assertions)
+	for i := range clones {
+		clones[i].Params = maps.Clone(clones[i].Params)
+		clones[i].AnyOf = cloneAssertions(clones[i].AnyOf)
+		clones[i].AllOf = cloneAssertions(clones[i].AllOf)
+		if clones[i].Not != nil {
+			not := cloneAssertions([]model.Assertion{*clones[i].Not})[0]
+			clones[i].Not = &not
+		}
+	}
+	return clones
+}
+
+// setTestVariables adds a test's variables to the template context and returns
+// a function that restores the values they replaced.
+func setTestVariables(templateCtx, variables map[string]string) func() {
+	previous := make(map[string]string, len(variables))
+	for name, value := range variables {
+		if old, ok := templateCtx[name]; ok {
+			previous[name] = old
+		}
+		templateCtx[name] = value
+	}
+	return func() {
+		for name := range variables {
+			if old, ok := previous[name]; ok {
+				templateCtx[name] = old
+			} else {
+				delete(templateCtx, name)
+			}
+		}
+	}
+}
+
+const utf8BOM = "\ufeff"
+
+// LoadPromptRows reads a prompt corpus: a CSV file with a header row, or a JSONL
+// file with one object per line. A leading byte order mark is skipped and
+// column names are lower-cased. Every row must have a non-empty prompt; other
+// columns such as expected are optional.
+func LoadPromptRows(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompts file: %w", err)
+	}
+	defer f.Close()
+
+	// Spreadsheet exports often start with a UTF-8 byte order mark, which would
+	// otherwise end up in the first column name
+	r := bufio.NewReader(f)
+	if bom, err := r.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+		_, _ = r.Discard(len(utf8BOM))
+	}
+
+	var rows []map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = readPromptCSV(r)
+	case ".jsonl", ".ndjson":
+		rows, err = readPromptJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported prompts file %q (expected .csv or .jsonl)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts file %q: %w", path, err)
+	}
+
+	for i, row := range rows {
+		if strings.TrimSpace(row["prompt"]) == "" {
+			return nil, fmt.Errorf("prompts file %q: row %d has no prompt", path, i+1)
+		}
+	}
+	return rows, nil
+}
+
+func readPromptCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, value := range record {
+			if i < len(header) && header[i] != "" {
+				row[header[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readPromptJSONL(r io.Reader) ([]map[string]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	rows := make([]map[string]string, 0)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		row := make(map[string]string, len(object))
+		for key, value := range object {
+			switch v := value.(type) {
+			case nil:
+			case string:
+				row[strings.ToLower(key)] = v
+			default:
+				// Numbers, booleans and nested values keep their JSON form
+				data, err := json.Marshal(v)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				row[strings.ToLower(key)] = string(data)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
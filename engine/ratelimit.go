@@ -2,27 +2,115 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"regexp"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/mykhaliev/agent-benchmark/logger"
 	"github.com/mykhaliev/agent-benchmark/model"
-	"github.com/pkoukk/tiktoken-go"
+	"github.com/mykhaliev/agent-benchmark/tokenizer"
 	"github.com/tmc/langchaingo/llms"
 	"golang.org/x/time/rate"
 )
 
 const (
 	// Default retry configuration
-	defaultMaxRetries     = 5
-	defaultInitialBackoff = 1 * time.Second
-	defaultMaxBackoff     = 60 * time.Second
+	defaultMaxRetries        = 5
+	defaultInitialBackoff    = 1 * time.Second
+	defaultMaxBackoff        = 60 * time.Second
+	defaultBackoffMultiplier = 2.0
+	// defaultRetryOn429Retries applies when retry_on_429 is enabled without a retry count
+	defaultRetryOn429Retries = 3
 )
 
+// RetryPolicy is a provider's resolved 429 retry schedule.
+type RetryPolicy struct {
+	MaxRetries     int           // Retries after the first call
+	InitialBackoff time.Duration // Wait before the first retry
+	MaxBackoff     time.Duration // Upper bound for every wait
+	Multiplier     float64       // Growth factor between waits without Retry-After
+}
+
+// ResolveRetryPolicy applies defaults to a provider retry config and rejects
+// nonsensical values: negative counts or durations, a multiplier below 1,
+// max_backoff below initial_backoff, or both max_retries and max_attempts set.
+func ResolveRetryPolicy(config model.RetryConfig) (RetryPolicy, error) {
+	policy := RetryPolicy{
+		MaxRetries:     config.MaxRetries,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		Multiplier:     defaultBackoffMultiplier,
+	}
+
+	if config.MaxRetries < 0 {
+		return policy, fmt.Errorf("retry.max_retries must not be negative, got %d", config.MaxRetries)
+	}
+	if config.MaxAttempts < 0 {
+		return policy, fmt.Errorf("retry.max_attempts must not be negative, got %d", config.MaxAttempts)
+	}
+	if config.MaxAttempts > 0 {
+		if config.MaxRetries > 0 {
+			return policy, fmt.Errorf("retry.max_retries and retry.max_attempts cannot both be set")
+		}
+		policy.MaxRetries = config.MaxAttempts - 1
+	} else if policy.MaxRetries == 0 {
+		policy.MaxRetries = defaultRetryOn429Retries
+	}
+
+	var err error
+	if config.InitialBackoff != "" {
+		if policy.InitialBackoff, err = time.ParseDuration(config.InitialBackoff); err != nil {
+			return policy, fmt.Errorf("invalid retry.initial_backoff %q: %w", config.InitialBackoff, err)
+		}
+		if policy.InitialBackoff < 0 {
+			return policy, fmt.Errorf("retry.initial_backoff must not be negative, got %s", config.InitialBackoff)
+		}
+	}
+	if config.MaxBackoff != "" {
+		if policy.MaxBackoff, err = time.ParseDuration(config.MaxBackoff); err != nil {
+			return policy, fmt.Errorf("invalid retry.max_backoff %q: %w", config.MaxBackoff, err)
+		}
+		if policy.MaxBackoff < 0 {
+			return policy, fmt.Errorf("retry.max_backoff must not be negative, got %s", config.MaxBackoff)
+		}
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		return policy, fmt.Errorf("retry.max_backoff (%s) must not be less than retry.initial_backoff (%s)",
+			policy.MaxBackoff, policy.InitialBackoff)
+	}
+	if config.Multiplier != 0 {
+		if config.Multiplier < 1 {
+			return policy, fmt.Errorf("retry.multiplier must be at least 1, got %g", config.Multiplier)
+		}
+		policy.Multiplier = config.Multiplier
+	}
+
+	return policy, nil
+}
+
+// Schedule returns the waits before each retry when the server sends no Retry-After.
+func (p RetryPolicy) Schedule() []time.Duration {
+	schedule := make([]time.Duration, 0, p.MaxRetries)
+	backoff := p.InitialBackoff
+	for i := 0; i < p.MaxRetries; i++ {
+		schedule = append(schedule, min(backoff, p.MaxBackoff))
+		backoff = p.next(backoff)
+	}
+	return schedule
+}
+
+// next grows backoff by the multiplier, saturating at MaxBackoff.
+func (p RetryPolicy) next(backoff time.Duration) time.Duration {
+	grown := float64(backoff) * p.Multiplier
+	if grown >= float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(grown)
+}
+
 // RateLimitStats tracks statistics about rate limiting and 429 handling
 type RateLimitStats struct {
 	mu sync.Mutex
@@ -30,10 +118,11 @@ type RateLimitStats struct {
 	ThrottleCount    int           `json:"throttleCount"`    // Number of times request was throttled
 	ThrottleWaitTime time.Duration `json:"throttleWaitTime"` // Total time spent waiting due to throttling
 	// Reactive 429 handling stats
-	RateLimitHits     int           `json:"rateLimitHits"`     // Number of 429 errors received
-	RetryCount        int           `json:"retryCount"`        // Number of retry attempts made
-	RetryWaitTime     time.Duration `json:"retryWaitTime"`     // Total time spent waiting for retries
-	RetrySuccessCount int           `json:"retrySuccessCount"` // Number of successful retries
+	RateLimitHits     int             `json:"rateLimitHits"`     // Number of 429 errors received
+	RetryCount        int             `json:"retryCount"`        // Number of retry attempts made
+	RetryWaitTime     time.Duration   `json:"retryWaitTime"`     // Total time spent waiting for retries
+	RetrySuccessCount int             `json:"retrySuccessCount"` // Number of successful retries
+	RetryBackoffs     []time.Duration `json:"retryBackoffs"`     // Backoff applied before each retry
 }
 
 // RateLimitedLLM wraps an llms.Model with rate limiting and optional 429 retry capabilities.
@@ -68,6 +157,8 @@ type RateLimitedLLM struct {
 	tpmLimit   int
 	rpmLimit   int
 	modelName  string // Model name for accurate tokenization
+	// Tokenizer for TPM budgeting; nil falls back to ~4 characters per token
+	tokenizer tokenizer.Tokenizer
 	// Calibration (in-memory per run)
 	calibrationMu          sync.Mutex
 	calibrationRatio       float64
@@ -75,6 +166,7 @@ type RateLimitedLLM struct {
 	// 429 retry handling (reactive) - separate from rate limiting
 	retryOn429         bool               // Whether to retry on 429 errors (default: false)
 	maxRetries         int                // Max number of 429 retries (only used if retryOn429 is true)
+	retryPolicy        RetryPolicy        // Backoff schedule for 429 retries
 	retryAfterProvider RetryAfterProvider // Optional provider for Retry-After header values
 	// Statistics tracking
 	stats RateLimitStats
@@ -85,19 +177,25 @@ type RateLimitedLLM struct {
 // retryConfig: reactive error handling (429 retry behavior)
 // modelName: model identifier for accurate tokenization (e.g., "gpt-4", "gpt-3.5-turbo")
 func NewRateLimitedLLM(wrapped llms.Model, rateLimitConfig model.RateLimitConfig, retryConfig model.RetryConfig, modelName string) *RateLimitedLLM {
-	// Default max retries to 3 if retry is enabled but max not specified
-	maxRetries := retryConfig.MaxRetries
-	if retryConfig.RetryOn429 && maxRetries <= 0 {
-		maxRetries = 3
+	// Configs are validated up front; fall back to the defaults if an invalid one gets here
+	retryPolicy, err := ResolveRetryPolicy(retryConfig)
+	if err != nil {
+		logger.Logger.Warn("Invalid retry configuration, using defaults", "error", err)
+		retryPolicy, _ = ResolveRetryPolicy(model.RetryConfig{})
 	}
+	maxRetries := retryPolicy.MaxRetries
 
 	rl := &RateLimitedLLM{
-		wrapped:    wrapped,
-		tpmLimit:   rateLimitConfig.TPM,
-		rpmLimit:   rateLimitConfig.RPM,
-		retryOn429: retryConfig.RetryOn429,
-		maxRetries: maxRetries,
-		modelName:  modelName,
+		wrapped:     wrapped,
+		tpmLimit:    rateLimitConfig.TPM,
+		rpmLimit:    rateLimitConfig.RPM,
+		retryOn429:  retryConfig.RetryOn429,
+		maxRetries:  maxRetries,
+		retryPolicy: retryPolicy,
+		modelName:   modelName,
+	}
+	if modelName != "" {
+		rl.tokenizer = tokenizer.ForModel("", modelName)
 	}
 
 	// Create TPM limiter if configured (proactive rate limiting)
@@ -118,12 +216,23 @@ func NewRateLimitedLLM(wrapped llms.Model, rateLimitConfig model.RateLimitConfig
 
 	// Log 429 retry configuration if enabled
 	if retryConfig.RetryOn429 {
-		logger.Logger.Info("429 retry handling enabled", "max_retries", maxRetries)
+		logger.Logger.Info("429 retry handling enabled",
+			"max_retries", maxRetries,
+			"initial_backoff", retryPolicy.InitialBackoff,
+			"max_backoff", retryPolicy.MaxBackoff,
+			"multiplier", retryPolicy.Multiplier)
 	}
 
 	return rl
 }
 
+// SetTokenizer sets the tokenizer used to budget requests against the TPM limit.
+// NewRateLimitedLLM picks one from the model name; callers that know the provider
+// type can choose a better match.
+func (rl *RateLimitedLLM) SetTokenizer(tok tokenizer.Tokenizer) {
+	rl.tokenizer = tok
+}
+
 // SetRetryAfterProvider sets the provider for Retry-After header values.
 // This should be called after construction if using a custom HTTP client that captures headers.
 func (rl *RateLimitedLLM) SetRetryAfterProvider(provider RetryAfterProvider) {
@@ -135,9 +244,10 @@ func (rl *RateLimitedLLM) SetRetryAfterProvider(provider RetryAfterProvider) {
 
 // GenerateContent implements llms.Model interface with rate limiting and retry logic
 func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	log := logger.FromContext(ctx)
 	// Wait for RPM limit (one request) - track throttle time
 	if rl.rpmLimiter != nil {
-		logger.Logger.Debug("Waiting for RPM rate limit")
+		log.Debug("Waiting for RPM rate limit")
 		throttleStart := time.Now()
 		if err := rl.rpmLimiter.Wait(ctx); err != nil {
 			return nil, err
@@ -157,7 +267,7 @@ func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.M
 		// NOTE: This is BEST-EFFORT based on token estimates, not guaranteed.
 		// Actual API token consumption may differ, so 429 errors can still occur.
 		// See RateLimitedLLM type comment for details on why this is best-effort.
-		logger.Logger.Debug("Waiting for TPM rate limit",
+		log.Debug("Waiting for TPM rate limit",
 			"base_estimated_tokens", baseEstimatedTokens,
 			"calibrated_tokens", calibratedTokens,
 			"calibration_ratio", rl.getCalibrationRatio())
@@ -185,7 +295,7 @@ func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.M
 				additional := actualTokens - calibratedTokens
 				reservation := rl.tpmLimiter.ReserveN(time.Now(), additional)
 				if reservation.OK() {
-					logger.Logger.Debug("Reserved additional tokens",
+					log.Debug("Reserved additional tokens",
 						"base_estimated", baseEstimatedTokens,
 						"calibrated", calibratedTokens,
 						"actual", actualTokens,
@@ -193,7 +303,7 @@ func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.M
 						"delay", reservation.Delay())
 				}
 			}
-			logger.Logger.Debug("Request completed",
+			log.Debug("Request completed",
 				"base_estimated_tokens", baseEstimatedTokens,
 				"calibrated_tokens", calibratedTokens,
 				"actual_tokens", actualTokens,
@@ -216,7 +326,7 @@ func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.M
 	// 429 retry handling is enabled - record the hit and attempt retries
 	rl.recordRateLimitHit()
 
-	backoff := defaultInitialBackoff
+	backoff := rl.retryPolicy.InitialBackoff
 	for attempt := 1; attempt <= rl.maxRetries; attempt++ {
 		// Extract retry-after duration from error message
 		retryAfter := rl.extractRetryAfter(err)
@@ -225,11 +335,11 @@ func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.M
 		}
 
 		// Cap backoff at max
-		if backoff > defaultMaxBackoff {
-			backoff = defaultMaxBackoff
+		if backoff > rl.retryPolicy.MaxBackoff {
+			backoff = rl.retryPolicy.MaxBackoff
 		}
 
-		logger.Logger.Warn("429 rate limit hit, retrying",
+		log.Warn("429 rate limit hit, retrying",
 			"attempt", attempt,
 			"max_retries", rl.maxRetries,
 			"wait_seconds", backoff.Seconds(),
@@ -243,12 +353,12 @@ func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.M
 		case <-time.After(backoff):
 		}
 		retryWaitTime := time.Since(retryWaitStart)
-		rl.recordRetry(retryWaitTime)
+		rl.recordRetry(backoff, retryWaitTime)
 
 		// Retry the request
 		response, err = rl.wrapped.GenerateContent(ctx, messages, options...)
 		if err == nil {
-			logger.Logger.Info("Request succeeded after 429 retry", "attempt", attempt)
+			log.Info("Request succeeded after 429 retry", "attempt", attempt)
 			rl.recordRetrySuccess()
 			return response, nil
 		}
@@ -263,12 +373,12 @@ func (rl *RateLimitedLLM) GenerateContent(ctx context.Context, messages []llms.M
 
 		// Exponential backoff for next attempt (if retry-after wasn't specified)
 		if retryAfter == 0 {
-			backoff *= 2
+			backoff = rl.retryPolicy.next(backoff)
 		}
 	}
 
 	// All retries exhausted
-	logger.Logger.Error("429 retries exhausted", "max_retries", rl.maxRetries, "error", err.Error())
+	log.Error("429 retries exhausted", "max_retries", rl.maxRetries, "error", err.Error())
 	return nil, err
 }
 
@@ -288,11 +398,12 @@ func (rl *RateLimitedLLM) recordRateLimitHit() {
 	logger.Logger.Debug("429 hit recorded", "total_hits", rl.stats.RateLimitHits)
 }
 
-func (rl *RateLimitedLLM) recordRetry(waitTime time.Duration) {
+func (rl *RateLimitedLLM) recordRetry(backoff, waitTime time.Duration) {
 	rl.stats.mu.Lock()
 	defer rl.stats.mu.Unlock()
 	rl.stats.RetryCount++
 	rl.stats.RetryWaitTime += waitTime
+	rl.stats.RetryBackoffs = append(rl.stats.RetryBackoffs, backoff)
 }
 
 func (rl *RateLimitedLLM) recordRetrySuccess() {
@@ -305,6 +416,10 @@ func (rl *RateLimitedLLM) recordRetrySuccess() {
 func (rl *RateLimitedLLM) GetStats() model.RateLimitStats {
 	rl.stats.mu.Lock()
 	defer rl.stats.mu.Unlock()
+	var backoffsMs []int64
+	for _, b := range rl.stats.RetryBackoffs {
+		backoffsMs = append(backoffsMs, b.Milliseconds())
+	}
 	return model.RateLimitStats{
 		ThrottleCount:      rl.stats.ThrottleCount,
 		ThrottleWaitTimeMs: rl.stats.ThrottleWaitTime.Milliseconds(),
@@ -312,6 +427,7 @@ func (rl *RateLimitedLLM) GetStats() model.RateLimitStats {
 		RetryCount:         rl.stats.RetryCount,
 		RetryWaitTimeMs:    rl.stats.RetryWaitTime.Milliseconds(),
 		RetrySuccessCount:  rl.stats.RetrySuccessCount,
+		RetryBackoffsMs:    backoffsMs,
 	}
 }
 
@@ -325,6 +441,7 @@ func (rl *RateLimitedLLM) ResetStats() {
 	rl.stats.RetryCount = 0
 	rl.stats.RetryWaitTime = 0
 	rl.stats.RetrySuccessCount = 0
+	rl.stats.RetryBackoffs = nil
 }
 
 // RateLimitStatsProvider is an interface for LLMs that can provide rate limit statistics
@@ -335,15 +452,7 @@ type RateLimitStatsProvider interface {
 
 // isRateLimitError checks if the error is a 429 rate limit error
 func (rl *RateLimitedLLM) isRateLimitError(err error) bool {
-	if err == nil {
-		return false
-	}
-	errStr := err.Error()
-	return strings.Contains(errStr, "429") ||
-		strings.Contains(errStr, "rate limit") ||
-		strings.Contains(errStr, "Rate limit") ||
-		strings.Contains(errStr, "too many requests") ||
-		strings.Contains(errStr, "Too Many Requests")
+	return model.IsRateLimitError(err)
 }
 
 // extractRetryAfter extracts the retry-after duration from multiple sources:
@@ -394,8 +503,8 @@ func (rl *RateLimitedLLM) extractRetryAfter(err error) time.Duration {
 // estimateInputTokens provides accurate token estimation using tiktoken
 // Falls back to simple heuristic if tokenizer is unavailable
 func (rl *RateLimitedLLM) estimateInputTokens(messages []llms.MessageContent) int {
-	// Try accurate tokenization first if model name is available
-	if rl.modelName != "" {
+	// Try accurate tokenization first if a tokenizer is available
+	if rl.tokenizer != nil {
 		if tokens := rl.estimateInputTokensAccurate(messages); tokens > 0 {
 			return tokens
 		}
@@ -485,73 +594,16 @@ func (rl *RateLimitedLLM) estimateInputTokensSimple(messages []llms.MessageConte
 	return tokens
 }
 
-// getEncodingForModel returns the appropriate tiktoken encoding for a model.
-// For OpenAI models, it uses the native encoding. For other providers (Claude, Gemini, etc.),
-// it falls back to cl100k_base which provides reasonable accuracy (~80-90%) since most modern
-// LLMs use similar BPE tokenization. The calibration mechanism compensates for differences.
-//
-// Model family mapping:
-//   - GPT-4/GPT-4o/GPT-3.5: Native tiktoken encoding (~100% accuracy)
-//   - Claude (all versions): cl100k_base fallback (~85-90% accuracy)
-//   - Gemini (all versions): cl100k_base fallback (~80-85% accuracy)
-//   - Llama/Mistral/Mixtral: cl100k_base fallback (~80-90% accuracy)
-//   - Other models: cl100k_base fallback (~75-85% accuracy)
-//
-// See docs/rate-limiting.md for detailed explanation of tokenization strategy.
-func (rl *RateLimitedLLM) getEncodingForModel() (*tiktoken.Tiktoken, error) {
-	// First, try the native encoding for the model (works for OpenAI models)
-	tkm, err := tiktoken.EncodingForModel(rl.modelName)
-	if err == nil {
-		logger.Logger.Debug("Using native tiktoken encoding for model", "model", rl.modelName)
-		return tkm, nil
-	}
-
-	// For non-OpenAI models, use cl100k_base as a reasonable fallback.
-	// cl100k_base is the encoding used by GPT-4 and provides good approximation
-	// for other modern LLMs that use similar BPE tokenization:
-	// - Claude uses a custom BPE tokenizer with similar vocabulary size
-	// - Gemini uses SentencePiece BPE, comparable token density
-	// - Llama/Mistral use SentencePiece BPE with similar characteristics
-	//
-	// The calibration mechanism will adjust for any systematic differences
-	// between our estimates and actual API token counts.
-	logger.Logger.Debug("Model not directly supported by tiktoken, using cl100k_base fallback",
-		"model", rl.modelName,
-		"original_error", err.Error())
-
-	tkm, err = tiktoken.GetEncoding("cl100k_base")
-	if err != nil {
-		logger.Logger.Debug("Failed to get cl100k_base encoding",
-			"error", err.Error())
-		return nil, err
-	}
-
-	logger.Logger.Debug("Using cl100k_base fallback encoding for non-OpenAI model",
-		"model", rl.modelName)
-	return tkm, nil
-}
-
-// estimateInputTokensAccurate provides accurate token estimation using tiktoken.
-// For OpenAI models, uses native encodings. For other providers (Claude, Gemini, etc.),
-// uses cl100k_base as a reasonable approximation.
-// Returns 0 if tokenization fails (caller should fall back to simple estimation).
+// estimateInputTokensAccurate estimates tokens with the tokenizer for the model family:
+// native tiktoken encodings for OpenAI models and approximations for Claude, Gemini
+// and others (see the tokenizer package).
 func (rl *RateLimitedLLM) estimateInputTokensAccurate(messages []llms.MessageContent) int {
-	// Get the tokenizer for this model (with fallback for non-OpenAI models)
-	tkm, err := rl.getEncodingForModel()
-	if err != nil {
-		logger.Logger.Debug("All tiktoken encodings failed, falling back to simple estimation",
-			"model", rl.modelName,
-			"error", err.Error())
-		return 0
-	}
-
 	// Count input tokens
 	inputTokens := 0
 	for _, msg := range messages {
 		for _, part := range msg.Parts {
 			if textPart, ok := part.(llms.TextContent); ok {
-				tokens := tkm.Encode(textPart.Text, nil, nil)
-				inputTokens += len(tokens)
+				inputTokens += rl.tokenizer.Count(textPart.Text)
 			}
 		}
 	}
@@ -573,6 +625,7 @@ func (rl *RateLimitedLLM) estimateInputTokensAccurate(messages []llms.MessageCon
 
 	logger.Logger.Debug("Accurate token estimation",
 		"model", rl.modelName,
+		"tokenizer", rl.tokenizer.Name(),
 		"input_tokens", inputTokens,
 		"estimated_completion", estimatedCompletion,
 		"safety_margin_percent", 50,
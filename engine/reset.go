@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// ResetCommandTimeout bounds how long a server's reset_between_agents command may run.
+const ResetCommandTimeout = 2 * time.Minute
+
+// ServerResetTracker remembers which agent last ran a session against each server,
+// so a server's reset_between_agents command runs only when a different agent
+// takes over. It is shared by all lanes of a run.
+type ServerResetTracker struct {
+	mu        sync.Mutex
+	lastAgent map[string]string
+}
+
+// NewServerResetTracker returns a tracker that has seen no agent yet.
+func NewServerResetTracker() *ServerResetTracker {
+	return &ServerResetTracker{lastAgent: make(map[string]string)}
+}
+
+// SwitchAgent records agentName as the user of servers and returns those that were
+// last used by another agent, in the given order. The first agent to use a server
+// finds it in whatever state the run started with and triggers no reset.
+func (t *ServerResetTracker) SwitchAgent(agentName string, servers []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var switched []string
+	for _, name := range servers {
+		if last, ok := t.lastAgent[name]; ok && last != agentName {
+			switched = append(switched, name)
+		}
+		t.lastAgent[name] = agentName
+	}
+	return switched
+}
+
+// ResettableServers returns the names of the agent's servers that configure
+// reset_between_agents, in the order the agent lists them.
+func ResettableServers(agentDef model.Agent, servers []model.Server) []string {
+	commands := make(map[string]bool, len(servers))
+	for _, srv := range servers {
+		if strings.TrimSpace(srv.ResetBetweenAgents) != "" {
+			commands[srv.Name] = true
+		}
+	}
+	var names []string
+	for _, srv := range agentDef.Servers {
+		if commands[srv.Name] {
+			names = append(names, srv.Name)
+		}
+	}
+	return names
+}
+
+// RunResetCommand runs a server's reset_between_agents command, rendered with the
+// template context, through the server's shell (the platform default when unset)
+// in its working directory. Output is returned in the error when the command fails.
+func RunResetCommand(ctx context.Context, srv model.Server, templateCtx map[string]string) error {
+	command := model.RenderTemplate(srv.ResetBetweenAgents, templateCtx)
+	ctx, cancel := context.WithTimeout(ctx, ResetCommandTimeout)
+	defer cancel()
+
+	shell := strings.ToLower(srv.Shell)
+	if shell == "" {
+		if runtime.GOOS == "windows" {
+			shell = "powershell"
+		} else {
+			shell = "sh"
+		}
+	}
+	var cmd *exec.Cmd
+	switch shell {
+	case "powershell", "pwsh":
+		cmd = exec.CommandContext(ctx, shell, "-NoProfile", "-NonInteractive", "-Command", command)
+	case "cmd":
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	case "bash", "sh", "zsh":
+		cmd = exec.CommandContext(ctx, shell, "-c", command)
+	default:
+		return fmt.Errorf("unsupported shell for reset_between_agents: %s", srv.Shell)
+	}
+	cmd.Dir = model.RenderTemplate(srv.WorkingDir, templateCtx)
+
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("reset of server %q timed out after %s", srv.Name, ResetCommandTimeout)
+		}
+		return fmt.Errorf("reset of server %q failed: %w: %s", srv.Name, err, strings.TrimSpace(string(output)))
+	}
+	logger.FromContext(ctx).Info("Server state reset",
+		"server", srv.Name,
+		"agent", templateCtx["AGENT_NAME"],
+		"duration", time.Since(start))
+	return nil
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/logger"
+)
+
+// streamPreviewLength bounds the text of each streamed message and tool result
+const streamPreviewLength = 500
+
+type streamKey struct{}
+
+// WithStream returns a context telling the engine whether to log each
+// iteration's tool calls and assistant messages as they happen (-stream).
+func WithStream(ctx context.Context, stream bool) context.Context {
+	return context.WithValue(ctx, streamKey{}, stream)
+}
+
+// StreamObserver returns an agent.AgentConfig Observer that logs each step of
+// the agent loop for the given test and agent, or nil when streaming is off in
+// ctx. Steps go through the run's logger at info level, so -quiet hides them
+// and -json-logs emits them as structured records.
+func StreamObserver(ctx context.Context, testName, agentName string) func(agent.IterationEvent) {
+	if stream, _ := ctx.Value(streamKey{}).(bool); !stream {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+	return func(event agent.IterationEvent) {
+		switch {
+		case event.Message != nil:
+			log.Info("Agent message",
+				"test", testName,
+				"agent", agentName,
+				"iteration", event.Iteration,
+				"content", agent.TruncateString(event.Message.Content, streamPreviewLength))
+		case event.ToolCall != nil:
+			call := event.ToolCall
+			args, _ := json.Marshal(call.Parameters)
+			attrs := []any{
+				"test", testName,
+				"agent", agentName,
+				"iteration", event.Iteration,
+				"tool", call.Name,
+				"arguments", agent.TruncateString(string(args), streamPreviewLength),
+				"duration_ms", call.DurationMs,
+			}
+			if len(call.Result.Content) > 0 {
+				attrs = append(attrs, "result", agent.TruncateString(call.Result.Content[0].Text, streamPreviewLength))
+			}
+			if call.Result.IsError {
+				attrs = append(attrs, "is_error", true)
+			}
+			log.Info("Agent tool call", attrs...)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ResolveToolChoice maps a tool_choice setting onto the llms.WithToolChoice value
+// the provider understands. It returns the effective mode to record and the option
+// to send; a nil option leaves the choice to the model. Modes the provider cannot
+// express, and tools not available to the test, fall back to auto with a warning.
+func ResolveToolChoice(providerType model.ProviderType, choice string, tools []llms.Tool) (string, any) {
+	if choice == "" {
+		return "", nil
+	}
+
+	forcedTool := ""
+	switch choice {
+	case model.ToolChoiceAuto, model.ToolChoiceNone, model.ToolChoiceRequired:
+	default:
+		forcedTool = choice
+		available := false
+		for _, t := range tools {
+			if t.Function != nil && t.Function.Name == forcedTool {
+				available = true
+				break
+			}
+		}
+		if !available {
+			logger.Logger.Warn("tool_choice names a tool not available to the test, falling back to auto",
+				"tool_choice", choice)
+			return model.ToolChoiceAuto, nil
+		}
+	}
+
+	switch providerType {
+	case model.ProviderOpenAI, model.ProviderAzure, model.ProviderGroq:
+		if forcedTool != "" {
+			return choice, llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: forcedTool}}
+		}
+		return choice, choice
+	case model.ProviderBedrock:
+		// The Converse API has no "none" mode
+		if choice != model.ToolChoiceNone {
+			if forcedTool != "" {
+				return choice, llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: forcedTool}}
+			}
+			return choice, choice
+		}
+	case model.ProviderAmazonAnthropic:
+		// langchaingo's Bedrock client drops "none" and expects a forced tool as a map
+		if choice != model.ToolChoiceNone {
+			if forcedTool != "" {
+				return choice, map[string]interface{}{
+					"type":     "function",
+					"function": map[string]interface{}{"name": forcedTool},
+				}
+			}
+			return choice, choice
+		}
+	}
+
+	logger.Logger.Warn("tool_choice is not supported by the provider, falling back to auto",
+		"provider_type", providerType,
+		"tool_choice", choice)
+	return model.ToolChoiceAuto, nil
+}
@@ -170,11 +170,8 @@ func writeExplorationOutput(
 	}
 
 	// Generate reports into the folder.
-	for _, rt := range reportTypes {
-		reportPath := filepath.Join(subdir, reportFileName+"."+rt)
-		if err := engine.GenerateReports(allResults, rt, reportPath, nil, configPath); err != nil {
-			logger.Logger.Error("Failed to generate report", "type", rt, "error", err)
-		}
+	if err := engine.GenerateAllReports(allResults, reportTypes, filepath.Join(subdir, reportFileName), nil, configPath, nil); err != nil {
+		logger.Logger.Error("Failed to generate reports", "error", err)
 	}
 
 	// Count passes, failures, and bug findings.
@@ -198,7 +195,11 @@ func writeExplorationOutput(
 	if len(reportTypes) > 0 {
 		fmt.Println("Reports:")
 		for _, rt := range reportTypes {
-			fmt.Printf("  %s\n", filepath.ToSlash(filepath.Join(subdir, reportFileName+"."+rt)))
+			name := reportFileName + "." + engine.ReportExtension(rt)
+			if rt == "mermaid" {
+				name = reportFileName + ".*.mmd" // One file per session and agent
+			}
+			fmt.Printf("  %s\n", filepath.ToSlash(filepath.Join(subdir, name)))
 		}
 		fmt.Println()
 	}
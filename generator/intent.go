@@ -22,15 +22,19 @@ type TestIntent struct {
 
 // Check is a flat assertion descriptor. anyOf/allOf/not are forbidden.
 type Check struct {
-	Type     string                     `json:"type"`
-	Tool     string                     `json:"tool,omitempty"`
-	Value    string                     `json:"value,omitempty"`
-	Pattern  string                     `json:"pattern,omitempty"`
-	Params   map[string]json.RawMessage `json:"params,omitempty"`
-	Sequence []string                   `json:"sequence,omitempty"`
-	Count    int                        `json:"count,omitempty"`
-	Path     string                     `json:"path,omitempty"`
-	Expected int                        `json:"expected,omitempty"`
+	Type       string                     `json:"type"`
+	Tool       string                     `json:"tool,omitempty"`
+	Value      string                     `json:"value,omitempty"`
+	Pattern    string                     `json:"pattern,omitempty"`
+	Params     map[string]json.RawMessage `json:"params,omitempty"`
+	Sequence   []string                   `json:"sequence,omitempty"`
+	Count      int                        `json:"count,omitempty"`
+	Path       string                     `json:"path,omitempty"`
+	Field      string                     `json:"field,omitempty"`
+	Expected   int                        `json:"expected,omitempty"`
+	Values     []string                   `json:"values,omitempty"`
+	IgnoreCase bool                       `json:"ignore_case,omitempty"`
+	Threshold  float64                    `json:"threshold,omitempty"`
 }
 
 // ExtractorIntent is a JSONPath extractor descriptor.
@@ -126,15 +130,22 @@ func BuildAssertion(c Check) model.Assertion {
 
 		params[k] = string(raw) // bool/number/array/null → keep as-is
 	}
-	return model.Assertion{
-		Type:     c.Type,
-		Tool:     c.Tool,
-		Value:    c.Value,
-		Pattern:  c.Pattern,
-		Params:   params,
-		Sequence: c.Sequence,
-		Count:    c.Count,
-		Path:     c.Path,
-		Expected: c.Expected,
+	a := model.Assertion{
+		Type:       c.Type,
+		Tool:       c.Tool,
+		Value:      c.Value,
+		Pattern:    c.Pattern,
+		Params:     params,
+		Sequence:   c.Sequence,
+		Count:      c.Count,
+		Path:       c.Path,
+		Field:      c.Field,
+		Values:     c.Values,
+		IgnoreCase: c.IgnoreCase,
+		Threshold:  c.Threshold,
 	}
+	if c.Expected != 0 || c.Type == "cli_exit_code_equals" {
+		a.Expected = c.Expected
+	}
+	return a
 }
@@ -82,8 +82,8 @@ func ValidateTestIntent(
 			}
 		}
 
-		// tool_call_order sequence validation.
-		if check.Type == "tool_call_order" {
+		// tool_call_order / tool_subsequence sequence validation.
+		if check.Type == "tool_call_order" || check.Type == "tool_subsequence" {
 			for _, toolName := range check.Sequence {
 				if len(allToolNames) > 0 && !allToolNames[toolName] {
 					errs = append(errs, fmt.Sprintf("%s: tool %q in sequence not found in any agent's tool list", checkLabel, toolName))
@@ -1,5 +1,7 @@
 package generator
 
+import "slices"
+
 // sessionSchema documents the YAML structure that must be emitted by the LLM.
 const sessionSchema = `
 variables:                       # Optional: static values available in all tests (map[string]string)
@@ -29,16 +31,27 @@ Tool assertions:
                          Required: type, tool (string)
   tool_not_called      - Asserts a specific tool was NOT called.
                          Required: type, tool (string)
-  tool_call_count      - Asserts a tool was called exactly N times.
-                         Required: type, tool (string), count (int)
+  tool_call_count      - Asserts how many times a tool was called: exactly count, or within min/max.
+                         Required: type, tool (string), count (int) or min/max/exact (int)
   tool_call_order      - Asserts tools were called in this order.
                          Required: type, sequence (list of strings)
+  tool_subsequence     - Asserts the tools appear in this relative order, other calls may be interleaved.
+                         Required: type, sequence (list of strings)
   tool_param_equals    - Asserts a tool was called with specific parameters.
                          Required: type, tool (string), params (map[string]string)
   tool_param_matches_regex - Asserts a tool parameter matches a regex.
                          Required: type, tool (string), params (map[string]string - values are regexes)
+                         Optional: match ("any" default, or "all" calls must match)
+  tool_param_regex     - Alias of tool_param_matches_regex for a single parameter.
+                         Required: type, tool (string), param (dotted path), pattern (string). Optional: match ("any" default, or "all" calls)
   tool_result_matches_json - Asserts the tool result matches JSON path/value.
                          Required: type, tool (string), path (string), value (string)
+  tool_succeeded       - Asserts every call to a tool returned a JSON result with a true success flag.
+                         Required: type, tool (string). Optional: field (string, default "ok")
+  tool_returned_content - Asserts every call to a tool returned a non-error result with non-empty text.
+                         Required: type, tool (string). Optional: field (string, must also be true)
+  json_path            - Checks the value at a JSONPath or dotted path in a tool's JSON result.
+                         Required: type, tool (string), path (string, e.g. "bytes" or "$.items[0].id"). Optional: one of equals (JSON value), contains (substring or array element), exists (bool, default true)
 
 Output assertions:
   output_contains      - Asserts the final output contains a substring.
@@ -47,20 +60,48 @@ Output assertions:
                          Required: type, value (string)
   output_regex         - Asserts the final output matches a regex.
                          Required: type, pattern (string)
+  output_not_regex     - Asserts the final output does NOT match a regex.
+                         Required: type, pattern (string)
+  output_one_of        - Asserts the final output contains at least one of the listed values.
+                         Required: type, values (list of strings). Optional: ignore_case (bool)
+  output_json_schema   - Asserts the final output is JSON that validates against a JSON Schema.
+                         Required: type, one of schema (object, inline JSON Schema) or schema_file (string, path relative to the test file)
+  output_reflects_tool_result - Asserts a value a tool returned (JSONPath into its JSON result) appears in the final output.
+                         Required: type, tool (string), path (string, e.g. "config.port")
+  output_language      - Asserts the final output is clean UTF-8 in the given language.
+                         Required: type, value (ISO 639-1 or 639-3 code, e.g. "en"). Optional: threshold (float, min confidence)
+  llm_rubric           - Asks the configured judge LLM(s) whether the final output satisfies a rubric.
+                         Required: type, value (string, the rubric). Optional: threshold (float, 0-1)
+  has_final_answer     - Asserts the agent ended with a non-empty answer, ignoring iteration scaffolding.
+                         Required: type only. Optional: value (string, integer minimum length)
 
 Performance assertions:
   max_tokens           - Asserts total tokens used is at most N.
                          Required: type, count (int)
+  max_tokens_used      - Asserts token usage is within a budget, reporting actual vs budget.
+                         Required: type, value (int budget). Optional: field (total, prompt or completion; default total)
   max_latency_ms       - Asserts total latency is at most N milliseconds.
                          Required: type, count (int)
+  first_tool_within_ms - Asserts the first tool call started at most N milliseconds after the test started.
+                         Required: type, value (string, integer). Fails when no tool is called.
+  max_assistant_messages - Asserts the agent produced at most N assistant messages.
+                         Required: type, value (string, integer)
 
 Behaviour assertions:
   no_error_messages    - Asserts no error messages occurred during execution.
                          Required: type only
+  expected_error       - Asserts an execution error matching a regex occurred (negative tests). Fails without errors.
+                         Required: type, pattern (string). Optional: require_error (bool, default true; false also passes without errors)
   no_hallucinated_tools - Asserts the agent did not call tools that don't exist.
                          Required: type only
+  no_hallucinated_params - Asserts tool calls only used parameters declared in the tool schema.
+                         Required: type only. Optional: tool (string) to check a single tool
+  no_empty_params      - Asserts no tool call passed an empty string or unresolved {{...}} token as a parameter.
+                         Required: type only. Optional: tool (string) to check a single tool
   no_clarification_questions - Asserts the agent did not ask the user for clarification.
                          Required: type only
+  confirms_before_tool - Asserts the agent asked the user for confirmation before its first call to a tool.
+                         Required: tool (string). Needs clarification_detection on the agent
   no_rate_limit_errors - Asserts no rate limit errors occurred.
                          Required: type only
 
@@ -74,6 +115,10 @@ CLI-specific assertions (only for CLI server type):
   cli_stderr_contains  - Asserts CLI stderr contains a substring.
                          Required: type, value (string)
 
+Regression assertions:
+  matches_golden       - Asserts tool calls (names + params) and final output match a recorded golden transcript.
+                         Required: type, value (golden JSON path, relative to TEST_DIR)
+
 Boolean combinators:
   anyOf                - Pass if ANY child assertion passes (OR logic).
                          Required: type, anyOf (list of assertions)
@@ -81,6 +126,9 @@ Boolean combinators:
                          Required: type, allOf (list of assertions)
   not                  - Pass if the child assertion FAILS (NOT logic).
                          Required: type, not (single assertion object)
+
+Any top-level assertion may set severity: warning to flag a failure without failing the test
+(default: error).
 `
 
 // variablesAndExtractorsDoc explains static variables and dynamic extractors to the LLM.
@@ -173,22 +221,43 @@ var validIntentCheckTypes = []string{
 	"tool_not_called",
 	"tool_call_count",
 	"tool_call_order",
+	"tool_subsequence",
 	"tool_param_equals",
 	"tool_param_matches_regex",
+	"tool_param_regex",
 	"tool_result_matches_json",
+	"tool_succeeded",
+	"tool_returned_content",
+	"json_path",
 	"output_contains",
 	"output_not_contains",
 	"output_regex",
+	"output_not_regex",
+	"output_one_of",
+	"output_field_equals",
+	"output_json_schema",
+	"output_reflects_tool_result",
+	"output_language",
+	"llm_rubric",
+	"has_final_answer",
 	"max_tokens",
+	"max_tokens_used",
 	"max_latency_ms",
+	"first_tool_within_ms",
+	"max_assistant_messages",
 	"no_error_messages",
+	"expected_error",
 	"no_hallucinated_tools",
+	"no_hallucinated_params",
+	"no_empty_params",
 	"no_clarification_questions",
+	"confirms_before_tool",
 	"no_rate_limit_errors",
 	"cli_exit_code_equals",
 	"cli_stdout_contains",
 	"cli_stdout_regex",
 	"cli_stderr_contains",
+	"matches_golden",
 }
 
 // testIntentSchema is a JSON example shown to the LLM in BuildTestIntentPrompt.
@@ -219,28 +288,55 @@ const testIntentSchema = `{
   "allowed_tools": ["tool_name"]
 }`
 
+// AssertionTypes returns the values accepted in an assertion's type field. The
+// boolean combinators are keys of their own and not included.
+func AssertionTypes() []string {
+	return slices.Clone(validIntentCheckTypes)
+}
+
 // validAssertionTypes is the complete list of assertion type strings.
 var validAssertionTypes = []string{
 	"tool_called",
 	"tool_not_called",
 	"tool_call_count",
 	"tool_call_order",
+	"tool_subsequence",
 	"tool_param_equals",
 	"tool_param_matches_regex",
+	"tool_param_regex",
 	"tool_result_matches_json",
+	"tool_succeeded",
+	"tool_returned_content",
+	"json_path",
 	"output_contains",
 	"output_not_contains",
 	"output_regex",
+	"output_not_regex",
+	"output_one_of",
+	"output_field_equals",
+	"output_json_schema",
+	"output_reflects_tool_result",
+	"output_language",
+	"llm_rubric",
+	"has_final_answer",
 	"max_tokens",
+	"max_tokens_used",
 	"max_latency_ms",
+	"first_tool_within_ms",
+	"max_assistant_messages",
 	"no_error_messages",
+	"expected_error",
 	"no_hallucinated_tools",
+	"no_hallucinated_params",
+	"no_empty_params",
 	"no_clarification_questions",
+	"confirms_before_tool",
 	"no_rate_limit_errors",
 	"cli_exit_code_equals",
 	"cli_stdout_contains",
 	"cli_stdout_regex",
 	"cli_stderr_contains",
+	"matches_golden",
 	"anyOf",
 	"allOf",
 	"not",
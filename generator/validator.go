@@ -51,6 +51,8 @@ var toolAssertionTypes = map[string]bool{
 	"tool_param_equals":        true,
 	"tool_param_matches_regex": true,
 	"tool_result_matches_json": true,
+	"tool_succeeded":           true,
+	"tool_returned_content":    true,
 }
 
 // paramAssertionTypes is the set of assertion types whose "params" keys must
@@ -69,6 +71,9 @@ var builtinTemplateVars = map[string]bool{
 	"TEMP_DIR":  true,
 	"RUN_ID":    true,
 	"SKILL_DIR": true,
+	// Engine-provided per-test vars
+	"TEST_TEMP_DIR": true,
+	"FIXTURE_DIR":   true,
 	// Engine-provided runtime vars
 	"AGENT_NAME":    true,
 	"SESSION_NAME":  true,
@@ -295,8 +300,8 @@ func validateAssertion(
 		}
 	}
 
-	// tool_call_order: check every name in the sequence.
-	if assertion.Type == "tool_call_order" {
+	// tool_call_order / tool_subsequence: check every name in the sequence.
+	if assertion.Type == "tool_call_order" || assertion.Type == "tool_subsequence" {
 		for _, toolName := range assertion.Sequence {
 			if len(allToolNames) > 0 && !allToolNames[toolName] {
 				errs = append(errs, fmt.Sprintf(
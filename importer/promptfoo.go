@@ -0,0 +1,562 @@
+// Package importer implements the import modes (-import-promptfoo flag).
+// It converts test definitions written for other evaluation tools into an
+// agent-benchmark test configuration, warning about anything it cannot map.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"gopkg.in/yaml.v3"
+)
+
+// promptfooConfig is the subset of a promptfoo config file that can be mapped.
+// Prompts, providers and tests accept several shapes, so they are decoded lazily.
+type promptfooConfig struct {
+	Description string        `yaml:"description"`
+	Prompts     yaml.Node     `yaml:"prompts"`
+	Providers   yaml.Node     `yaml:"providers"`
+	DefaultTest promptfooTest `yaml:"defaultTest"`
+	Tests       yaml.Node     `yaml:"tests"`
+}
+
+type promptfooTest struct {
+	Description string                 `yaml:"description"`
+	Vars        map[string]interface{} `yaml:"vars"`
+	Assert      []promptfooAssertion   `yaml:"assert"`
+	Options     map[string]interface{} `yaml:"options"`
+}
+
+type promptfooAssertion struct {
+	Type      string      `yaml:"type"`
+	Value     interface{} `yaml:"value"`
+	Threshold *float64    `yaml:"threshold"`
+	Transform string      `yaml:"transform"`
+}
+
+// promptfooPrompt is a resolved prompt: plain text or a chat message list.
+type promptfooPrompt struct {
+	Label    string
+	Text     string
+	Messages []promptfooMessage
+}
+
+type promptfooMessage struct {
+	Role    string `json:"role" yaml:"role"`
+	Content string `json:"content" yaml:"content"`
+}
+
+// promptfooVarPattern matches nunjucks variable references such as {{ topic }}.
+var promptfooVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// RunPromptfoo is the main entry point for the promptfoo import mode.
+// It converts inputPath and writes the resulting test configuration to outputPath.
+func RunPromptfoo(inputPath, outputPath string) {
+	config, warnings, err := ImportPromptfoo(inputPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to import promptfoo config: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, w := range warnings {
+		logger.Logger.Warn("promptfoo import", "warning", w)
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to marshal test configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputPath == "" {
+		base := strings.TrimSuffix(inputPath, filepath.Ext(inputPath))
+		outputPath = base + ".agent-benchmark.yaml"
+	} else if filepath.Ext(outputPath) == "" {
+		outputPath += ".yaml"
+	}
+
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", outputPath, err)
+		os.Exit(1)
+	}
+
+	tests := 0
+	for _, s := range config.Sessions {
+		tests += len(s.Tests)
+	}
+	fmt.Printf("Imported %d tests (%d warnings): %s\n", tests, len(warnings), outputPath)
+}
+
+// ImportPromptfoo reads a promptfoo config file and converts it.
+// File references in prompts are resolved relative to the config file's directory.
+func ImportPromptfoo(path string) (*model.TestConfiguration, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ParsePromptfoo(data, filepath.Dir(path))
+}
+
+// ParsePromptfoo converts promptfoo config data into a test configuration.
+// Each promptfoo test becomes one test per prompt; chat prompts become the test
+// prompt plus follow-up turns. The returned warnings list every feature that was
+// dropped or needs manual follow-up.
+func ParsePromptfoo(data []byte, baseDir string) (*model.TestConfiguration, []string, error) {
+	var pf promptfooConfig
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse promptfoo config: %w", err)
+	}
+
+	imp := &promptfooImporter{baseDir: baseDir}
+
+	prompts := imp.prompts(&pf.Prompts)
+	if len(prompts) == 0 {
+		return nil, imp.warnings, fmt.Errorf("no usable prompts found")
+	}
+
+	tests := imp.tests(&pf.Tests)
+	if len(tests) == 0 {
+		// promptfoo runs every prompt once when no tests are defined
+		tests = []promptfooTest{{}}
+	}
+
+	config := &model.TestConfiguration{}
+	imp.providers(&pf.Providers, config)
+	if len(pf.DefaultTest.Options) > 0 {
+		imp.warn("defaultTest.options are not supported")
+	}
+
+	sessionName := pf.Description
+	if sessionName == "" {
+		sessionName = "promptfoo import"
+	}
+	session := model.Session{Name: sessionName}
+
+	for i, t := range tests {
+		name := t.Description
+		if name == "" {
+			name = fmt.Sprintf("Test %d", i+1)
+		}
+		if len(t.Options) > 0 {
+			imp.warn(fmt.Sprintf("%s: test options are not supported", name))
+		}
+
+		vars := make(map[string]string)
+		for k, v := range pf.DefaultTest.Vars {
+			vars[k] = imp.varValue(name, k, v)
+		}
+		for k, v := range t.Vars {
+			vars[k] = imp.varValue(name, k, v)
+		}
+
+		asserts := append(append([]promptfooAssertion{}, pf.DefaultTest.Assert...), t.Assert...)
+		assertions := make([]model.Assertion, 0, len(asserts))
+		for _, a := range asserts {
+			if assertion, ok := imp.assertion(name, a, vars); ok {
+				assertions = append(assertions, assertion)
+			}
+		}
+
+		for j, p := range prompts {
+			testName := name
+			if len(prompts) > 1 {
+				label := p.Label
+				if label == "" {
+					label = fmt.Sprintf("prompt %d", j+1)
+				}
+				testName = fmt.Sprintf("%s [%s]", name, label)
+			}
+
+			test, ok := imp.test(testName, p, vars, config)
+			if !ok {
+				continue
+			}
+			test.Assertions = cloneAssertions(assertions)
+			session.Tests = append(session.Tests, test)
+		}
+	}
+
+	config.Sessions = []model.Session{session}
+	if imp.usesJudge {
+		imp.warn("llm-rubric assertions were mapped to llm_rubric; add a top-level judge provider to the generated file, otherwise they are skipped")
+	}
+	return config, imp.warnings, nil
+}
+
+type promptfooImporter struct {
+	baseDir   string
+	warnings  []string
+	usesJudge bool
+}
+
+func (imp *promptfooImporter) warn(msg string) {
+	imp.warnings = append(imp.warnings, msg)
+}
+
+// prompts resolves the prompts section, which may be a single string or a list
+// of strings and {raw|id, label} objects.
+func (imp *promptfooImporter) prompts(node *yaml.Node) []promptfooPrompt {
+	var items []interface{}
+	switch node.Kind {
+	case 0:
+		return nil
+	case yaml.ScalarNode:
+		var s string
+		if err := node.Decode(&s); err == nil {
+			items = []interface{}{s}
+		}
+	case yaml.SequenceNode:
+		if err := node.Decode(&items); err != nil {
+			imp.warn(fmt.Sprintf("prompts could not be parsed: %v", err))
+			return nil
+		}
+	default:
+		imp.warn("prompts must be a string or a list; keyed prompt maps are not supported")
+		return nil
+	}
+
+	result := make([]promptfooPrompt, 0, len(items))
+	for i, item := range items {
+		var raw, label string
+		switch v := item.(type) {
+		case string:
+			raw = v
+		case map[string]interface{}:
+			label, _ = v["label"].(string)
+			if r, ok := v["raw"].(string); ok {
+				raw = r
+			} else if id, ok := v["id"].(string); ok {
+				raw = id
+			}
+		}
+		if raw == "" {
+			imp.warn(fmt.Sprintf("prompt %d is not a string or {raw, label} object, skipped", i+1))
+			continue
+		}
+		if p, ok := imp.resolvePrompt(raw, label); ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// resolvePrompt loads file:// references and detects chat-format prompts.
+func (imp *promptfooImporter) resolvePrompt(raw, label string) (promptfooPrompt, bool) {
+	text := raw
+	if ref, ok := strings.CutPrefix(raw, "file://"); ok {
+		if strings.ContainsAny(ref, "*?:") {
+			imp.warn(fmt.Sprintf("prompt %s: globs and function references are not supported, skipped", raw))
+			return promptfooPrompt{}, false
+		}
+		path := ref
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(imp.baseDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			imp.warn(fmt.Sprintf("prompt %s: %v, skipped", raw, err))
+			return promptfooPrompt{}, false
+		}
+		text = string(data)
+		if label == "" {
+			label = filepath.Base(ref)
+		}
+		if ext := strings.ToLower(filepath.Ext(ref)); ext == ".yaml" || ext == ".yml" {
+			var messages []promptfooMessage
+			if err := yaml.Unmarshal(data, &messages); err == nil && len(messages) > 0 {
+				return promptfooPrompt{Label: label, Messages: messages}, true
+			}
+		}
+	}
+
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "[") {
+		var messages []promptfooMessage
+		if err := json.Unmarshal([]byte(trimmed), &messages); err == nil && len(messages) > 0 {
+			return promptfooPrompt{Label: label, Messages: messages}, true
+		}
+	}
+	return promptfooPrompt{Label: label, Text: text}, true
+}
+
+// tests decodes the tests section; external test files are not supported.
+func (imp *promptfooImporter) tests(node *yaml.Node) []promptfooTest {
+	switch node.Kind {
+	case 0:
+		return nil
+	case yaml.SequenceNode:
+		var tests []promptfooTest
+		if err := node.Decode(&tests); err != nil {
+			imp.warn(fmt.Sprintf("tests could not be parsed: %v", err))
+			return nil
+		}
+		return tests
+	default:
+		imp.warn("tests loaded from external files (CSV, globs) are not supported; inline the tests in the config")
+		return nil
+	}
+}
+
+// providers maps promptfoo provider ids onto providers and one agent per provider.
+func (imp *promptfooImporter) providers(node *yaml.Node, config *model.TestConfiguration) {
+	var items []interface{}
+	switch node.Kind {
+	case 0:
+	case yaml.ScalarNode:
+		var s string
+		if err := node.Decode(&s); err == nil {
+			items = []interface{}{s}
+		}
+	case yaml.SequenceNode:
+		if err := node.Decode(&items); err != nil {
+			imp.warn(fmt.Sprintf("providers could not be parsed: %v", err))
+		}
+	default:
+		imp.warn("providers must be a string or a list")
+	}
+
+	for _, item := range items {
+		var id, label string
+		var options map[string]interface{}
+		switch v := item.(type) {
+		case string:
+			id = v
+		case map[string]interface{}:
+			id, _ = v["id"].(string)
+			label, _ = v["label"].(string)
+			options, _ = v["config"].(map[string]interface{})
+		}
+
+		provider, ok := imp.provider(id, options)
+		if !ok {
+			continue
+		}
+		provider.Name = label
+		if provider.Name == "" {
+			provider.Name = strings.NewReplacer(":", "-", "/", "-").Replace(id)
+		}
+		config.Providers = append(config.Providers, provider)
+		config.Agents = append(config.Agents, model.Agent{
+			Name:     provider.Name,
+			Provider: provider.Name,
+		})
+	}
+
+	if len(config.Providers) == 0 {
+		imp.warn("no supported providers found; add providers and agents to the generated file before running it")
+	}
+}
+
+func (imp *promptfooImporter) provider(id string, options map[string]interface{}) (model.Provider, bool) {
+	parts := strings.Split(id, ":")
+	modelName := parts[len(parts)-1]
+	if parts[0] == "bedrock" {
+		// Bedrock model ids contain colons themselves (e.g. "...-v1:0")
+		modelName = strings.TrimPrefix(strings.TrimPrefix(id, "bedrock:"), "converse:")
+	}
+	if len(parts) < 2 || modelName == "" {
+		imp.warn(fmt.Sprintf("provider %q is not supported, skipped", id))
+		return model.Provider{}, false
+	}
+
+	var provider model.Provider
+	switch parts[0] {
+	case "openai":
+		provider = model.Provider{Type: model.ProviderOpenAI, Token: "{{OPENAI_API_KEY}}", Model: modelName}
+	case "anthropic":
+		provider = model.Provider{Type: model.ProviderAnthropic, Token: "{{ANTHROPIC_API_KEY}}", Model: modelName}
+	case "mistral":
+		provider = model.Provider{Type: model.ProviderMistral, Token: "{{MISTRAL_API_KEY}}", Model: modelName}
+	case "bedrock":
+		provider = model.Provider{Type: model.ProviderBedrock, Token: "{{AWS_ACCESS_KEY_ID}}", Secret: "{{AWS_SECRET_ACCESS_KEY}}", Model: modelName}
+		if region, ok := options["region"].(string); ok {
+			provider.Location = region
+		} else {
+			imp.warn(fmt.Sprintf("provider %q: set location to the AWS region", id))
+		}
+	case "groq":
+		provider = model.Provider{Type: model.ProviderGroq, Token: "{{GROQ_API_KEY}}", Model: modelName}
+	case "google":
+		provider = model.Provider{Type: model.ProviderGoogle, Token: "{{GOOGLE_API_KEY}}", Model: modelName}
+	case "azure", "azureopenai":
+		provider = model.Provider{Type: model.ProviderAzure, Token: "{{AZURE_OPENAI_API_KEY}}", Model: modelName}
+		if host, ok := options["apiHost"].(string); ok {
+			provider.BaseURL = "https://" + strings.TrimPrefix(host, "https://")
+		} else {
+			imp.warn(fmt.Sprintf("provider %q: set baseUrl to the Azure OpenAI endpoint", id))
+		}
+	default:
+		imp.warn(fmt.Sprintf("provider %q is not supported, skipped", id))
+		return model.Provider{}, false
+	}
+
+	unsupported := make([]string, 0)
+	for k, v := range options {
+		switch k {
+		case "temperature":
+			if f, ok := toFloat(v); ok {
+				provider.Temperature = &f
+			}
+		case "seed":
+			if f, ok := toFloat(v); ok {
+				seed := int(f)
+				provider.Seed = &seed
+			}
+		case "apiHost", "region":
+		default:
+			unsupported = append(unsupported, k)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		imp.warn(fmt.Sprintf("provider %q: config options %s are not supported", id, strings.Join(unsupported, ", ")))
+	}
+	return provider, true
+}
+
+// test builds a test for one prompt: a plain prompt, or the user messages of a
+// chat prompt as the prompt followed by turns.
+func (imp *promptfooImporter) test(name string, p promptfooPrompt, vars map[string]string, config *model.TestConfiguration) (model.Test, bool) {
+	test := model.Test{Name: name}
+	if p.Messages == nil {
+		test.Prompt = substituteVars(p.Text, vars)
+		return test, true
+	}
+
+	for _, m := range p.Messages {
+		content := substituteVars(m.Content, vars)
+		switch m.Role {
+		case "user":
+			if test.Prompt == "" {
+				test.Prompt = content
+			} else {
+				test.Turns = append(test.Turns, model.Turn{Prompt: content})
+			}
+		case "system":
+			if config.Settings.SystemPrompt == "" {
+				config.Settings.SystemPrompt = content
+			} else if config.Settings.SystemPrompt != content {
+				imp.warn(fmt.Sprintf("%s: only one system prompt is supported, extra system message dropped", name))
+			}
+		default:
+			imp.warn(fmt.Sprintf("%s: %s messages cannot be replayed, dropped", name, m.Role))
+		}
+	}
+
+	if test.Prompt == "" {
+		imp.warn(fmt.Sprintf("%s: chat prompt has no user message, skipped", name))
+		return test, false
+	}
+	return test, true
+}
+
+// assertion maps one promptfoo assert onto an assertion. A "not-" prefix negates it.
+func (imp *promptfooImporter) assertion(testName string, a promptfooAssertion, vars map[string]string) (model.Assertion, bool) {
+	if a.Transform != "" {
+		imp.warn(fmt.Sprintf("%s: %s transform is not supported, assertion checks the raw output", testName, a.Type))
+	}
+
+	kind, negate := strings.CutPrefix(a.Type, "not-")
+	value, isString := a.Value.(string)
+	value = substituteVars(value, vars)
+	values := make([]string, 0)
+	if list, ok := a.Value.([]interface{}); ok {
+		for _, v := range list {
+			values = append(values, substituteVars(fmt.Sprintf("%v", v), vars))
+		}
+	}
+
+	var assertion model.Assertion
+	switch {
+	case kind == "contains" && isString && negate:
+		return model.Assertion{Type: "output_not_contains", Value: value}, true
+	case kind == "contains" && isString:
+		assertion = model.Assertion{Type: "output_contains", Value: value}
+	case kind == "icontains" && isString:
+		assertion = model.Assertion{Type: "output_one_of", Values: []string{value}, IgnoreCase: true}
+	case (kind == "contains-any" || kind == "icontains-any") && len(values) > 0:
+		assertion = model.Assertion{Type: "output_one_of", Values: values, IgnoreCase: kind == "icontains-any"}
+	case (kind == "contains-all" || kind == "icontains-all") && len(values) > 0:
+		for _, v := range values {
+			child := model.Assertion{Type: "output_contains", Value: v}
+			if kind == "icontains-all" {
+				child = model.Assertion{Type: "output_one_of", Values: []string{v}, IgnoreCase: true}
+			}
+			assertion.AllOf = append(assertion.AllOf, child)
+		}
+	case kind == "regex" && isString:
+		assertion = model.Assertion{Type: "output_regex", Pattern: value}
+	case kind == "equals" && isString:
+		assertion = model.Assertion{Type: "output_regex", Pattern: "^" + regexp.QuoteMeta(value) + "$"}
+	case kind == "starts-with" && isString:
+		assertion = model.Assertion{Type: "output_regex", Pattern: "^" + regexp.QuoteMeta(value)}
+	case kind == "llm-rubric" && isString:
+		assertion = model.Assertion{Type: "llm_rubric", Value: value}
+		if a.Threshold != nil {
+			assertion.Threshold = *a.Threshold
+		}
+		imp.usesJudge = true
+	case kind == "latency" && a.Threshold != nil:
+		assertion = model.Assertion{Type: "max_latency_ms", Value: fmt.Sprintf("%d", int64(*a.Threshold))}
+	default:
+		imp.warn(fmt.Sprintf("%s: assertion type %q is not supported, dropped", testName, a.Type))
+		return model.Assertion{}, false
+	}
+
+	if negate {
+		return model.Assertion{Not: &assertion}, true
+	}
+	return assertion, true
+}
+
+// varValue renders a promptfoo var as a string, warning about values that
+// promptfoo would expand or load.
+func (imp *promptfooImporter) varValue(testName, name string, v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		if strings.HasPrefix(val, "file://") {
+			imp.warn(fmt.Sprintf("%s: var %s loads a file, the reference is kept as text", testName, name))
+		}
+		return val
+	case []interface{}:
+		imp.warn(fmt.Sprintf("%s: var %s is a list; promptfoo would expand it into several tests, the list is kept as text", testName, name))
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// substituteVars replaces {{ name }} references with test vars; unknown
+// references are left for the template engine.
+func substituteVars(text string, vars map[string]string) string {
+	return promptfooVarPattern.ReplaceAllStringFunc(text, func(m string) string {
+		name := promptfooVarPattern.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+func cloneAssertions(assertions []model.Assertion) []model.Assertion {
+	result := make([]model.Assertion, len(assertions))
+	for i, a := range assertions {
+		result[i] = a.Clone()
+	}
+	return result
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const promptfooSample = `
+description: Translation checks
+prompts:
+  - "Translate to {{ language }}: {{input}}"
+providers:
+  - openai:gpt-4o-mini
+  - id: bedrock:us.meta.llama3-1-70b-instruct-v1:0
+    config:
+      region: us-west-2
+  - id: anthropic:messages:claude-sonnet-4-20250514
+    label: claude
+    config:
+      temperature: 0
+      max_tokens: 512
+defaultTest:
+  assert:
+    - type: not-contains
+      value: "As an AI"
+tests:
+  - description: French greeting
+    vars:
+      language: French
+      input: Hello
+    assert:
+      - type: icontains
+        value: bonjour
+      - type: contains-any
+        value: ["Bonjour", "Salut"]
+      - type: regex
+        value: "^[A-Z]"
+      - type: equals
+        value: "Bonjour"
+      - type: not-regex
+        value: "\\?"
+      - type: llm-rubric
+        value: is polite
+        threshold: 0.8
+  - vars:
+      language: German
+      input: Thanks
+    assert:
+      - type: latency
+        threshold: 5000
+`
+
+func TestParsePromptfoo(t *testing.T) {
+	config, warnings, err := ParsePromptfoo([]byte(promptfooSample), t.TempDir())
+	require.NoError(t, err)
+
+	require.Len(t, config.Providers, 3)
+	assert.Equal(t, model.ProviderOpenAI, config.Providers[0].Type)
+	assert.Equal(t, "gpt-4o-mini", config.Providers[0].Model)
+	assert.Equal(t, "openai-gpt-4o-mini", config.Providers[0].Name)
+	assert.Equal(t, "{{OPENAI_API_KEY}}", config.Providers[0].Token)
+	assert.Equal(t, model.ProviderBedrock, config.Providers[1].Type)
+	assert.Equal(t, "us.meta.llama3-1-70b-instruct-v1:0", config.Providers[1].Model)
+	assert.Equal(t, "us-west-2", config.Providers[1].Location)
+	assert.Equal(t, "claude", config.Providers[2].Name)
+	assert.Equal(t, model.ProviderAnthropic, config.Providers[2].Type)
+	require.NotNil(t, config.Providers[2].Temperature)
+	assert.Equal(t, 0.0, *config.Providers[2].Temperature)
+	require.Len(t, config.Agents, 3)
+	assert.Equal(t, "claude", config.Agents[2].Provider)
+
+	require.Len(t, config.Sessions, 1)
+	assert.Equal(t, "Translation checks", config.Sessions[0].Name)
+	tests := config.Sessions[0].Tests
+	require.Len(t, tests, 2)
+
+	first := tests[0]
+	assert.Equal(t, "French greeting", first.Name)
+	assert.Equal(t, "Translate to French: Hello", first.Prompt)
+	require.Len(t, first.Assertions, 7)
+	assert.Equal(t, model.Assertion{Type: "output_not_contains", Value: "As an AI"}, first.Assertions[0])
+	assert.Equal(t, model.Assertion{Type: "output_one_of", Values: []string{"bonjour"}, IgnoreCase: true}, first.Assertions[1])
+	assert.Equal(t, model.Assertion{Type: "output_one_of", Values: []string{"Bonjour", "Salut"}}, first.Assertions[2])
+	assert.Equal(t, model.Assertion{Type: "output_regex", Pattern: "^[A-Z]"}, first.Assertions[3])
+	assert.Equal(t, model.Assertion{Type: "output_regex", Pattern: "^Bonjour$"}, first.Assertions[4])
+	require.NotNil(t, first.Assertions[5].Not)
+	assert.Equal(t, "output_regex", first.Assertions[5].Not.Type)
+	assert.Equal(t, model.Assertion{Type: "llm_rubric", Value: "is polite", Threshold: 0.8}, first.Assertions[6])
+
+	second := tests[1]
+	assert.Equal(t, "Test 2", second.Name)
+	assert.Equal(t, "Translate to German: Thanks", second.Prompt)
+	require.Len(t, second.Assertions, 2)
+	assert.Equal(t, model.Assertion{Type: "max_latency_ms", Value: "5000"}, second.Assertions[1])
+
+	joined := strings.Join(warnings, "\n")
+	assert.Contains(t, joined, "add a top-level judge provider")
+	assert.Contains(t, joined, "max_tokens are not supported")
+
+	// The result must round-trip through the regular config parser
+	data, err := yaml.Marshal(config)
+	require.NoError(t, err)
+	parsed, err := model.ParseTestConfigFromString(string(data))
+	require.NoError(t, err)
+	assert.Len(t, parsed.Sessions[0].Tests, 2)
+}
+
+func TestParsePromptfoo_ChatPromptFile(t *testing.T) {
+	dir := t.TempDir()
+	chat := `[
+  {"role": "system", "content": "You are a travel agent."},
+  {"role": "user", "content": "Plan a trip to {{city}}"},
+  {"role": "assistant", "content": "Sure"},
+  {"role": "user", "content": "Make it cheaper"}
+]`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "chat.json"), []byte(chat), 0644))
+
+	input := `
+prompts:
+  - file://chat.json
+  - "Short: {{city}}"
+tests:
+  - vars:
+      city: Paris
+`
+	config, warnings, err := ParsePromptfoo([]byte(input), dir)
+	require.NoError(t, err)
+
+	tests := config.Sessions[0].Tests
+	require.Len(t, tests, 2)
+	assert.Equal(t, "Test 1 [chat.json]", tests[0].Name)
+	assert.Equal(t, "Plan a trip to Paris", tests[0].Prompt)
+	require.Len(t, tests[0].Turns, 1)
+	assert.Equal(t, "Make it cheaper", tests[0].Turns[0].Prompt)
+	assert.Equal(t, "You are a travel agent.", config.Settings.SystemPrompt)
+	assert.Equal(t, "Test 1 [prompt 2]", tests[1].Name)
+	assert.Equal(t, "Short: Paris", tests[1].Prompt)
+
+	joined := strings.Join(warnings, "\n")
+	assert.Contains(t, joined, "assistant messages cannot be replayed")
+	assert.Contains(t, joined, "no supported providers found")
+}
+
+func TestParsePromptfoo_Errors(t *testing.T) {
+	_, _, err := ParsePromptfoo([]byte("prompts: [\n"), "")
+	assert.Error(t, err)
+
+	_, _, err = ParsePromptfoo([]byte("tests: []\n"), "")
+	assert.ErrorContains(t, err, "no usable prompts")
+
+	_, warnings, err := ParsePromptfoo([]byte("prompts: [\"hi\"]\ntests: file://tests.csv\nproviders: [\"ollama:llama3\"]\n"), "")
+	require.NoError(t, err)
+	joined := strings.Join(warnings, "\n")
+	assert.Contains(t, joined, "external files")
+	assert.Contains(t, joined, `provider "ollama:llama3" is not supported`)
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,22 +13,57 @@ import (
 
 var Logger *slog.Logger
 
+type loggerKey struct{}
+
+// WithLogger returns a context whose holders log to l instead of Logger.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger set with WithLogger, or Logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return Logger
+}
+
 const (
 	FilePermission = 0644
 )
 
+// Options controls log level and output format
+type Options struct {
+	Verbose bool // Enable debug level
+	Quiet   bool // Only log errors (takes precedence over Verbose)
+	JSON    bool // Emit structured slog JSON instead of colored text
+	NoColor bool // Disable ANSI colors in text output (-no-color, NO_COLOR)
+}
+
 func SetupLogger(w io.Writer, verbose bool) {
+	SetupLoggerWithOptions(w, Options{Verbose: verbose})
+}
+
+// SetupLoggerWithOptions configures the global Logger with the given level and format
+func SetupLoggerWithOptions(w io.Writer, options Options) {
 	logLevel := slog.LevelInfo
-	if verbose {
+	if options.Verbose {
 		logLevel = slog.LevelDebug
 	}
-
-	opts := &tint.Options{
-		Level:      logLevel,
-		TimeFormat: "2006-01-02 15:04:05",
+	if options.Quiet {
+		logLevel = slog.LevelError
 	}
 
-	handler := tint.NewHandler(w, opts)
+	var handler slog.Handler
+	if options.JSON {
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: logLevel})
+	} else {
+		handler = tint.NewHandler(w, &tint.Options{
+			Level:      logLevel,
+			TimeFormat: "2006-01-02 15:04:05",
+			NoColor:    options.NoColor,
+		})
+	}
 
 	Logger = slog.New(handler)
 }
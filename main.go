@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/mykhaliev/agent-benchmark/configschema"
 	"github.com/mykhaliev/agent-benchmark/engine"
 	"github.com/mykhaliev/agent-benchmark/explorer"
 	"github.com/mykhaliev/agent-benchmark/generator"
+	"github.com/mykhaliev/agent-benchmark/importer"
 	"github.com/mykhaliev/agent-benchmark/logger"
 	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/mykhaliev/agent-benchmark/report"
@@ -21,6 +24,8 @@ import (
 
 const (
 	AppName = "agent-bench"
+
+	defaultGeneratedTestsDir = "./generated_tests"
 )
 
 func main() {
@@ -29,20 +34,53 @@ func main() {
 	reportFileName := flag.String("o", "", "Report file name (without extension)")
 	logPath := flag.String("l", "", "Path to the log file (if not set, logs to stdout)")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging")
+	quiet := flag.Bool("quiet", false, "Only log errors")
+	jsonLogs := flag.Bool("json-logs", false, "Emit structured JSON logs")
+	stream := flag.Bool("stream", false, "Print each iteration's tool calls and assistant messages to the console as they happen (hidden by -quiet, structured with -json-logs)")
 	showVersion := flag.Bool("v", false, "Show version and exit")
-	reportTypes := flag.String("reportType", "html", "Report type(s) (comma-separated): html, json, markdown, txt")
+	printSchema := flag.Bool("print-schema", false, "Print the JSON Schema of test and suite files and exit")
+	reportTypes := flag.String("reportType", "html", "Report type(s) (comma-separated): html, json, md, txt, sarif, junit, csv, mermaid")
 	generateFromJSON := flag.String("generate-report", "", "Generate report from existing JSON results file (use with -f to get AI summary config)")
 	generateConfig := flag.String("g", "", "Path to the generator config file (enables test generation mode)")
-	generateDryRun := flag.Bool("dry-run", false, "Preview generated YAML without saving (requires -g)")
-	generateOutputDir := flag.String("output-dir", "./generated_tests", "Output directory for generated or exploration test files")
-	generateSeed := flag.Int64("seed", 0, "Random seed for deterministic generation (requires -g)")
+	generateDryRun := flag.Bool("dry-run", false, "Validate -f/-s config and list planned tests without calling providers, or preview generated YAML without saving with -g")
+	outputDir := flag.String("output-dir", "", "Output directory for reports (auto-named when -o is omitted), or for generated/exploration test files (default ./generated_tests)")
+	seed := flag.Int64("seed", 0, "Random seed for random template values and test generation; runs without it use a time-based seed that is logged and recorded in the report")
 	exploreConfig := flag.String("e", "", "Path to explorer config file (enables exploratory testing mode)")
+	importPromptfoo := flag.String("import-promptfoo", "", "Convert a promptfoo config file into a test file (output path from -o)")
+	recordDir := flag.String("record", "", "Record every MCP tool call and result into this directory")
+	replayDir := flag.String("replay", "", "Serve MCP tool calls from recordings in this directory instead of live servers")
+	updateGolden := flag.Bool("update-golden", false, "Rewrite the golden files of matches_golden assertions from this run")
+	maxDuration := flag.Duration("max-duration", 0, "Stop starting new tests after this long (e.g. 30m) and report the rest as not executed")
+	deadline := flag.Duration("deadline", 0, "Hard ceiling on the whole run (e.g. 45m): cancel running tests when it passes, overriding the suite's deadline")
+	keepTemp := flag.Bool("keep-temp", false, "Keep the per-test directories instead of deleting them after each test")
+	exportConversations := flag.String("export-conversations", "", "Write each test's conversation as chat-completions message JSON into this directory, one file per test and agent")
+	maxOutputLength := flag.Int("max-output-length", 0, "Clip final outputs and tool results in HTML reports to this many characters, expandable in the page (0 = no limit)")
+	noColor := flag.Bool("no-color", false, "Plain ASCII console output without colors or emoji (default when stdout is not a terminal or NO_COLOR is set)")
+	asciiOutput := flag.Bool("ascii", false, "Same as -no-color")
+	groupBy := flag.String("group-by", "agent", "Group the HTML leaderboard and comparison matrix by agent, provider, model or tag")
+	disableAssertions := flag.String("disable-assertions", "", "Assertion types (comma-separated) to skip in this run; they are reported as disabled and do not affect pass/fail")
+	parallel := flag.Int("parallel", 0, "Run up to this many agents concurrently, overriding settings.concurrency (0 = use the setting)")
+	onlyAssertions := flag.String("only-assertions", "", "Evaluate only these assertion types (comma-separated); the others are reported as disabled")
+	compareReport := flag.String("compare", "", "Compare this older JSON report with the newer one given as argument (-compare old.json new.json); exits 1 on regressions")
+	regressionThreshold := flag.Float64("regression-threshold", report.DefaultRegressionThreshold*100, "Latency or token increase in percent that -compare counts as a regression")
 
 	flag.Parse()
 
-	fmt.Printf("Version: %s\nCommit: %s\nBuildDate: %s\n",
-		version.Version, version.Commit, version.BuildDate)
+	// Only print the version banner on request so stdout stays clean for pipelines
 	if *showVersion {
+		fmt.Printf("Version: %s\nCommit: %s\nBuildDate: %s\n",
+			version.Version, version.Commit, version.BuildDate)
+		return
+	}
+
+	// The schema goes to stdout alone so it can be redirected into a file
+	if *printSchema {
+		data, err := configschema.JSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to build schema: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
 		return
 	}
 
@@ -56,21 +94,104 @@ func main() {
 		defer logFile.Close()
 	}
 
-	logger.SetupLogger(logWriter, *verbose)
+	plainConsole := *noColor || *asciiOutput || model.DetectPlainConsole(os.Stdout)
+	model.SetPlainConsole(plainConsole)
+	logger.SetupLoggerWithOptions(logWriter, logger.Options{
+		Verbose: *verbose,
+		Quiet:   *quiet,
+		JSON:    *jsonLogs,
+		NoColor: plainConsole,
+	})
 	templates.NewTemplateEngine()
+	reportGroupBy, err := report.ParseGroupBy(*groupBy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	reportOptions := report.Options{MaxOutputLength: *maxOutputLength, GroupBy: reportGroupBy}
+	if *deadline < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -deadline must not be negative\n")
+		os.Exit(1)
+	}
+	if *parallel < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -parallel must be 0 or greater\n")
+		os.Exit(1)
+	}
+
+	// Swap the server factory before any mode initializes servers
+	switch {
+	case *recordDir != "" && *replayDir != "":
+		fmt.Fprintf(os.Stderr, "Error: -record and -replay cannot be used together\n")
+		os.Exit(1)
+	case *recordDir != "":
+		engine.SetServerFactory(&engine.RecordingServerFactory{Base: &engine.DefaultServerFactory{}, Dir: *recordDir})
+	case *replayDir != "":
+		engine.SetServerFactory(&engine.ReplayServerFactory{Dir: *replayDir})
+	}
+
+	// Generated and explored tests keep their own default directory
+	generateOutputDir := *outputDir
+	if generateOutputDir == "" {
+		generateOutputDir = defaultGeneratedTestsDir
+	}
 
 	// Handle test generation mode (-g)
 	if *generateConfig != "" {
 		ctx := context.Background()
-		generator.Run(ctx, *generateConfig, *generateOutputDir, *generateDryRun, *generateSeed)
+		generator.Run(ctx, *generateConfig, generateOutputDir, *generateDryRun, *seed)
 		return
 	}
 
 	// Handle exploratory testing mode (-e)
 	if *exploreConfig != "" {
 		ctx := context.Background()
-		reportTypesArray := parseReportTypes(*reportTypes)
-		explorer.Run(ctx, *exploreConfig, *generateOutputDir, *reportFileName, reportTypesArray)
+		reportTypesArray := parseCommaList(*reportTypes)
+		explorer.Run(ctx, *exploreConfig, generateOutputDir, *reportFileName, reportTypesArray)
+		return
+	}
+
+	// Handle promptfoo import mode (-import-promptfoo)
+	if *importPromptfoo != "" {
+		importer.RunPromptfoo(*importPromptfoo, *reportFileName)
+		return
+	}
+
+	// Handle report comparison (-compare old.json new.json)
+	if *compareReport != "" {
+		if flag.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "Error: -compare needs the new JSON report as argument: -compare old.json new.json\n")
+			os.Exit(1)
+		}
+		if *regressionThreshold < 0 {
+			fmt.Fprintf(os.Stderr, "Error: -regression-threshold must not be negative\n")
+			os.Exit(1)
+		}
+		comparison, err := report.CompareReports(*compareReport, flag.Arg(0), *regressionThreshold/100)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		comparison.WriteText(os.Stdout)
+
+		if *reportFileName != "" {
+			outputPath := *reportFileName
+			if !strings.HasSuffix(outputPath, ".html") {
+				outputPath = outputPath + ".html"
+			}
+			html, err := report.GenerateComparisonHTML(comparison)
+			if err == nil {
+				err = os.WriteFile(outputPath, []byte(html), 0644)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to write comparison report: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Comparison report generated: %s\n", outputPath)
+		}
+
+		if comparison.Regressions() > 0 {
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -94,52 +215,19 @@ func main() {
 			os.Exit(1)
 		}
 
-		var judgeLLM llms.Model
-
-		// Use test_file from JSON to get AI summary configuration
+		// Resolve the AI summary judges from the test or suite file the report
+		// was generated from, as the run did
+		ctx := context.Background()
+		var judges []llms.Model
 		if reportData.TestFile != "" {
-			testConfig, err := model.ParseTestConfig(reportData.TestFile)
+			judges, err = engine.AISummaryJudges(ctx, reportData.TestFile, reportData.Results)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to parse test config %s: %v\n", reportData.TestFile, err)
-			} else if testConfig.AISummary.Enabled {
-				judgeProvider := testConfig.AISummary.JudgeProvider
-				if judgeProvider == "" {
-					fmt.Fprintf(os.Stderr, "Warning: AI summary enabled but no judge_provider specified\n")
-				} else {
-					ctx := context.Background()
-					staticCtx := engine.CreateStaticTemplateContext(reportData.TestFile, nil)
-
-					// Find the provider config
-					var targetProvider *model.Provider
-					if judgeProvider == "$self" && len(testConfig.Providers) > 0 {
-						targetProvider = &testConfig.Providers[0]
-					} else {
-						for i := range testConfig.Providers {
-							if testConfig.Providers[i].Name == judgeProvider {
-								targetProvider = &testConfig.Providers[i]
-								break
-							}
-						}
-					}
-
-					if targetProvider != nil {
-						providers, err := engine.InitProviders(ctx, []model.Provider{*targetProvider}, staticCtx)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "Warning: Failed to initialize AI summary provider: %v\n", err)
-						} else {
-							for _, llm := range providers {
-								judgeLLM = llm
-								break
-							}
-						}
-					}
-				}
+				fmt.Fprintf(os.Stderr, "Warning: Failed to load AI summary judges: %v\n", err)
 			}
 		}
 
-		// Generate HTML with AI summary (if judgeLLM is available)
-		ctx := context.Background()
-		if err := report.GenerateReportFromJSONWithSummary(ctx, *generateFromJSON, outputPath, judgeLLM); err != nil {
+		// Generate HTML with AI summary (if a judge is available)
+		if err := report.GenerateReportFromJSONWithSummary(ctx, *generateFromJSON, outputPath, judges, reportOptions); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to generate report: %v\n", err)
 			os.Exit(1)
 		}
@@ -155,8 +243,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Handle dry run: validate and print the test plan without calling providers
+	if *generateDryRun {
+		plan, err := engine.DryRun(context.Background(), *testPath, *suitePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		engine.PrintPlan(os.Stdout, plan)
+		return
+	}
+
 	// Parse and validate report types
-	reportTypesArray := parseReportTypes(*reportTypes)
+	reportTypesArray := parseCommaList(*reportTypes)
 	if len(reportTypesArray) == 0 {
 		logger.Logger.Error("No valid report types specified")
 		os.Exit(1)
@@ -169,25 +268,76 @@ func main() {
 		}
 	}
 
+	assertionFilter, err := parseAssertionFilter(*disableAssertions, *onlyAssertions)
+	if err != nil {
+		logger.Logger.Error("Invalid assertion filter", "error", err)
+		os.Exit(1)
+	}
+
+	runSeed := *seed
+	if runSeed == 0 {
+		runSeed = engine.NewRunSeed()
+	}
+
 	logger.Logger.Info("Starting application",
 		"app", AppName,
+		"seed", runSeed,
 		"config", *testPath,
 		"suite", *suitePath,
 		"output", *reportFileName,
+		"outputDir", *outputDir,
+		"maxDuration", *maxDuration,
+		"deadline", *deadline,
+		"updateGolden", *updateGolden,
 		"reportTypes", strings.Join(reportTypesArray, ", "),
 		"logfile", *logPath,
 		"verbose", *verbose)
 
-	engine.Run(testPath, verbose, suitePath, reportFileName, reportTypesArray)
+	engine.Run(engine.RunConfig{
+		TestPath:               *testPath,
+		SuitePath:              *suitePath,
+		Verbose:                *verbose,
+		ReportTypes:            reportTypesArray,
+		ReportFileName:         *reportFileName,
+		OutputDir:              *outputDir,
+		MaxDuration:            *maxDuration,
+		Deadline:               *deadline,
+		UpdateGolden:           *updateGolden,
+		KeepTemp:               *keepTemp,
+		ExportConversationsDir: *exportConversations,
+		AssertionFilter:        assertionFilter,
+		Parallel:               *parallel,
+		Seed:                   runSeed,
+		Stream:                 *stream,
+		GroupBy:                reportOptions.GroupBy,
+		MaxOutputLength:        reportOptions.MaxOutputLength,
+	})
+}
+
+// parseAssertionFilter builds the assertion filter from the -disable-assertions
+// and -only-assertions flags, rejecting unknown assertion types.
+func parseAssertionFilter(disabled, only string) (model.AssertionFilter, error) {
+	filter := model.AssertionFilter{
+		Disabled: parseCommaList(disabled),
+		Only:     parseCommaList(only),
+	}
+	known := append(generator.AssertionTypes(), "anyOf", "allOf", "not")
+	for _, t := range append(slices.Clone(filter.Disabled), filter.Only...) {
+		if !slices.Contains(known, t) {
+			return model.AssertionFilter{}, fmt.Errorf("unknown assertion type %q", t)
+		}
+	}
+	return filter, nil
 }
 
-func parseReportTypes(reportTypes string) []string {
-	parts := strings.Split(reportTypes, ",")
+// parseCommaList splits a comma-separated flag value, dropping blanks and duplicates.
+func parseCommaList(value string) []string {
+	parts := strings.Split(value, ",")
 	seen := make(map[string]bool)
 	result := make([]string, 0, len(parts))
 
-	for _, rt := range parts {
-		trimmed := strings.TrimSpace(rt)
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
 		if trimmed != "" && !seen[trimmed] {
 			seen[trimmed] = true
 			result = append(result, trimmed)
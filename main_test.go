@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// runMainEnv makes the test binary behave like the CLI when re-executed by runMain
+const runMainEnv = "AGENT_BENCHMARK_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain re-executes the test binary as the CLI and returns its stdout
+func runMain(t *testing.T, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	_ = cmd.Run() // exit code is irrelevant here, only stdout is inspected
+
+	return stdout.String()
+}
+
+func TestVersionBanner(t *testing.T) {
+	t.Run("Normal run does not print banner", func(t *testing.T) {
+		jsonPath := t.TempDir() + "/missing.json"
+		out := runMain(t, "-generate-report", jsonPath)
+
+		if strings.Contains(out, "Version:") || strings.Contains(out, "BuildDate:") {
+			t.Errorf("stdout should not contain version banner, got:\n%s", out)
+		}
+	})
+
+	t.Run("-v prints banner", func(t *testing.T) {
+		out := runMain(t, "-v")
+
+		for _, want := range []string{"Version:", "Commit:", "BuildDate:"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("stdout should contain %q, got:\n%s", want, out)
+			}
+		}
+	})
+}
+
+func TestParseAssertionFilter(t *testing.T) {
+	filter, err := parseAssertionFilter("output_regex, llm_rubric,output_regex", "")
+	if err != nil {
+		t.Fatalf("parseAssertionFilter() failed: %v", err)
+	}
+	if got := strings.Join(filter.Disabled, ","); got != "output_regex,llm_rubric" {
+		t.Errorf("Disabled = %q, want blanks and duplicates dropped", got)
+	}
+	if len(filter.Only) != 0 {
+		t.Errorf("Only = %v, want empty", filter.Only)
+	}
+
+	filter, err = parseAssertionFilter("", "anyOf,tool_called")
+	if err != nil {
+		t.Fatalf("parseAssertionFilter() failed: %v", err)
+	}
+	if len(filter.Only) != 2 {
+		t.Errorf("Only = %v, want combinator keys accepted", filter.Only)
+	}
+
+	if _, err := parseAssertionFilter("output_regexp", ""); err == nil || !strings.Contains(err.Error(), "output_regexp") {
+		t.Errorf("expected an error naming the unknown type, got %v", err)
+	}
+}
@@ -1,20 +1,39 @@
 package model
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"math"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/abadojack/whatlanggo"
 	"github.com/aymerick/raymond"
 	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/templates"
 	"github.com/mykhaliev/agent-benchmark/version"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
 	"github.com/yalp/jsonpath"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,8 +42,10 @@ import (
 // ============================================================================
 
 type TestSuiteConfiguration struct {
+	Include      []string          `yaml:"include,omitempty"` // Files whose providers, servers, agents and variables are merged in
 	Name         string            `yaml:"name"`
 	TestFiles    []string          `yaml:"test_files"`
+	Deadline     string            `yaml:"deadline,omitempty"` // Hard ceiling on the whole suite run, e.g. "45m"; -deadline overrides it
 	Providers    []Provider        `yaml:"providers"`
 	Servers      []Server          `yaml:"servers"`
 	Agents       []Agent           `yaml:"agents"`
@@ -32,6 +53,10 @@ type TestSuiteConfiguration struct {
 	Variables    map[string]string `yaml:"variables,omitempty"`
 	TestCriteria Criteria          `yaml:"criteria"`
 	AISummary    AISummary         `yaml:"ai_summary,omitempty"`
+	Pairwise     PairwiseConfig    `yaml:"pairwise,omitempty"`
+	Judge        *Provider         `yaml:"judge,omitempty"`             // LLM used for AI summary and llm_rubric, independent of the agent providers
+	Judges       []Provider        `yaml:"judges,omitempty"`            // Additional judges whose verdicts are combined with judge_aggregation
+	Aggregation  JudgeAggregation  `yaml:"judge_aggregation,omitempty"` // How judge verdicts are combined: majority (default), mean or median
 }
 
 // ============================================================================
@@ -39,6 +64,7 @@ type TestSuiteConfiguration struct {
 // ============================================================================
 
 type TestConfiguration struct {
+	Include      []string          `yaml:"include,omitempty"` // Files whose providers, servers, agents and variables are merged in
 	Providers    []Provider        `yaml:"providers"`
 	Servers      []Server          `yaml:"servers"`
 	Agents       []Agent           `yaml:"agents"`
@@ -47,6 +73,10 @@ type TestConfiguration struct {
 	Variables    map[string]string `yaml:"variables,omitempty"`
 	TestCriteria Criteria          `yaml:"criteria"`
 	AISummary    AISummary         `yaml:"ai_summary,omitempty"`
+	Pairwise     PairwiseConfig    `yaml:"pairwise,omitempty"`
+	Judge        *Provider         `yaml:"judge,omitempty"`             // LLM used for AI summary and llm_rubric, independent of the agent providers
+	Judges       []Provider        `yaml:"judges,omitempty"`            // Additional judges whose verdicts are combined with judge_aggregation
+	Aggregation  JudgeAggregation  `yaml:"judge_aggregation,omitempty"` // How judge verdicts are combined: majority (default), mean or median
 }
 
 // ============================================================================
@@ -70,6 +100,15 @@ type RetryConfig struct {
 	// MaxRetries is the maximum number of retry attempts for 429 errors.
 	// Only used when RetryOn429 is enabled. Default: 3
 	MaxRetries int `yaml:"max_retries"`
+	// MaxAttempts is the total number of calls including the first one, an alternative
+	// to MaxRetries (max_attempts = max_retries + 1). Setting both is an error.
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// InitialBackoff is the wait before the first retry, as a Go duration. Default: 1s
+	InitialBackoff string `yaml:"initial_backoff,omitempty"`
+	// MaxBackoff caps every wait, including server-provided Retry-After values. Default: 60s
+	MaxBackoff string `yaml:"max_backoff,omitempty"`
+	// Multiplier grows the wait after each retry that had no Retry-After value. Must be >= 1. Default: 2
+	Multiplier float64 `yaml:"multiplier,omitempty"`
 }
 
 type Provider struct {
@@ -84,8 +123,13 @@ type Provider struct {
 	Location        string          `yaml:"location,omitempty"`         // e.g., 2025-01-01-preview
 	CredentialsPath string          `yaml:"credentials_path,omitempty"` // e.g., 2025-01-01-preview
 	AuthType        string          `yaml:"auth_type,omitempty"`        // For AZURE: "api_key" (default) or "entra_id"
+	Proxy           string          `yaml:"proxy,omitempty"`            // Optional proxy URL; overrides HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	Temperature     *float64        `yaml:"temperature,omitempty"`      // Optional sampling temperature applied to every call
+	Seed            *int            `yaml:"seed,omitempty"`             // Optional sampling seed applied to every call
+	PromptCache     bool            `yaml:"prompt_cache,omitempty"`     // ANTHROPIC only: cache the conversation prefix (system prompt, tools, first user message)
 	RateLimits      RateLimitConfig `yaml:"rate_limits,omitempty"`      // Optional proactive rate limiting
 	Retry           RetryConfig     `yaml:"retry,omitempty"`            // Optional reactive error handling (e.g., 429 retries)
+	Warmup          bool            `yaml:"warmup,omitempty"`           // Send one untimed request before the tests to absorb cold-start latency
 }
 
 type ProviderType string
@@ -96,8 +140,11 @@ const (
 	ProviderVertex          ProviderType = "VERTEX"
 	ProviderAnthropic       ProviderType = "ANTHROPIC"
 	ProviderAmazonAnthropic ProviderType = "AMAZON-ANTHROPIC"
+	ProviderBedrock         ProviderType = "BEDROCK"
 	ProviderOpenAI          ProviderType = "OPENAI"
 	ProviderAzure           ProviderType = "AZURE"
+	ProviderMistral         ProviderType = "MISTRAL"
+	ProviderOllama          ProviderType = "OLLAMA"
 )
 
 // ============================================================================
@@ -112,6 +159,8 @@ type Server struct {
 	Headers      []string   `yaml:"headers"`
 	ServerDelay  string     `yaml:"server_delay,omitempty"`
 	ProcessDelay string     `yaml:"process_delay,omitempty"`
+	ToolTimeout  string     `yaml:"tool_timeout,omitempty"` // Overrides settings.tool_timeout for this server's tools
+	Proxy        string     `yaml:"proxy,omitempty"`        // Optional proxy URL for sse/http servers; overrides HTTP_PROXY/HTTPS_PROXY/NO_PROXY
 	// CLI server type specific fields
 	Shell                    string   `yaml:"shell,omitempty"`                       // Shell to use (powershell, cmd, bash). Default: powershell on Windows, bash on Unix
 	WorkingDir               string   `yaml:"working_dir,omitempty"`                 // Working directory for CLI commands. Default: current directory
@@ -120,15 +169,25 @@ type Server struct {
 	HelpCommand              string   `yaml:"help_command,omitempty"`                // DEPRECATED: Use help_commands instead. Single help command.
 	HelpCommands             []string `yaml:"help_commands,omitempty"`               // Commands to run at startup to get CLI help (outputs concatenated and injected into tool description)
 	DisableHelpAutoDiscovery bool     `yaml:"disable_help_auto_discovery,omitempty"` // If true, disable automatic help discovery when no help_command is configured
+	// Docker server type specific fields
+	Image string            `yaml:"image,omitempty"` // Container image running the MCP server
+	Args  []string          `yaml:"args,omitempty"`  // Arguments passed to the container entrypoint
+	Env   map[string]string `yaml:"env,omitempty"`   // Environment variables set in the container
+	Port  int               `yaml:"port,omitempty"`  // Container port serving streamable HTTP; 0 talks to the container over stdio
+	Path  string            `yaml:"path,omitempty"`  // HTTP endpoint path when port is set (default /mcp)
+	// ResetBetweenAgents is a shell command that restores the server's state when a
+	// different agent starts a session on it, e.g. closing windows left open
+	ResetBetweenAgents string `yaml:"reset_between_agents,omitempty"`
 }
 
 type ServerType string
 
 const (
-	Stdio ServerType = "stdio"
-	SSE   ServerType = "sse"
-	Http  ServerType = "http"
-	CLI   ServerType = "cli"
+	Stdio  ServerType = "stdio"
+	SSE    ServerType = "sse"
+	Http   ServerType = "http"
+	CLI    ServerType = "cli"
+	Docker ServerType = "docker"
 )
 
 // ============================================================================
@@ -149,7 +208,16 @@ type ClarificationDetection struct {
 // The analysis appears as the first section in generated reports.
 type AISummary struct {
 	Enabled       bool   `yaml:"enabled"`                  // Enable AI summary (default: false)
-	JudgeProvider string `yaml:"judge_provider,omitempty"` // Provider name for the judge LLM. Use "$self" to reuse a test agent's provider, or specify a provider name (required when enabled)
+	JudgeProvider string `yaml:"judge_provider,omitempty"` // DEPRECATED: use the top-level judge provider. Provider name for the judge LLM, or "$self" to reuse a test agent's provider
+}
+
+// PairwiseConfig configures head-to-head judging. After the run the judge
+// compares the answers of every two agents to each test, and the report ranks
+// the agents by their wins.
+type PairwiseConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Prompt    string `yaml:"prompt,omitempty"`     // Judging instructions, replacing the built-in ones
+	CacheFile string `yaml:"cache_file,omitempty"` // JSON file keeping judgments across runs; relative to TEST_DIR
 }
 
 // SkillConfig configures an Agent Skill to be loaded for this agent.
@@ -165,11 +233,25 @@ type Agent struct {
 	Settings               Settings               `yaml:"settings"`
 	Servers                []AgentServer          `yaml:"servers"`
 	Provider               string                 `yaml:"provider"`
+	Model                  string                 `yaml:"model,omitempty"` // Runs the provider with this model instead of its own
 	Skill                  *SkillConfig           `yaml:"skill,omitempty"`
 	SystemPrompt           string                 `yaml:"system_prompt,omitempty"`
+	ToolChoice             string                 `yaml:"tool_choice,omitempty"` // auto, none, required or a tool name; tests may override
 	ClarificationDetection ClarificationDetection `yaml:"clarification_detection,omitempty"`
+	OnToolCollision        ToolCollisionPolicy    `yaml:"on_tool_collision,omitempty"` // prefix (default) or error
 }
 
+// ToolCollisionPolicy decides what happens when several of an agent's servers
+// expose a tool with the same name.
+type ToolCollisionPolicy string
+
+const (
+	// ToolCollisionPrefix registers each colliding tool as "<server>__<tool>" (default).
+	ToolCollisionPrefix ToolCollisionPolicy = "prefix"
+	// ToolCollisionError fails agent initialization.
+	ToolCollisionError ToolCollisionPolicy = "error"
+)
+
 type AgentServer struct {
 	Name         string   `yaml:"name"`
 	AllowedTools []string `yaml:"allowed_tools,omitempty"`
@@ -181,6 +263,7 @@ type AgentServer struct {
 
 type Criteria struct {
 	SuccessRate string `yaml:"success_rate" json:"successRate"`
+	Weighted    bool   `yaml:"weighted,omitempty" json:"weighted,omitempty"` // Compare success_rate against the weighted pass rate
 }
 
 // ============================================================================
@@ -188,14 +271,35 @@ type Criteria struct {
 // ============================================================================
 
 type Settings struct {
-	Verbose        bool           `yaml:"verbose"`
-	ToolTimeout    string         `yaml:"tool_tool_timeout"`
-	MaxIterations  int            `yaml:"max_iterations"`
-	TestDelay      string         `yaml:"test_delay"`
-	SessionDelay   string         `yaml:"session_delay"`
-	VariablePolicy VariablePolicy `yaml:"variable_policy"`
+	Verbose           bool              `yaml:"verbose"`
+	ToolTimeout       string            `yaml:"tool_timeout"` // Default per-tool-call timeout (unlimited when empty)
+	ToolTimeoutPolicy ToolTimeoutPolicy `yaml:"tool_timeout_policy,omitempty"`
+	MaxIterations     int               `yaml:"max_iterations"`
+	TestDelay         string            `yaml:"test_delay"`
+	TestTimeout       string            `yaml:"test_timeout,omitempty"` // Default per-test timeout (unlimited when empty)
+	SessionDelay      string            `yaml:"session_delay"`
+	VariablePolicy    VariablePolicy    `yaml:"variable_policy"`
+	SystemPrompt      string            `yaml:"system_prompt,omitempty"` // Default system prompt for agents without their own
+	Concurrency       int               `yaml:"concurrency,omitempty"`   // Agents run at once; agents sharing a server always run in turn
 }
 
+// Tool choice modes for tool_choice; any other value names the tool to force.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// ToolTimeoutPolicy decides what happens to a test when a tool call times out.
+type ToolTimeoutPolicy string
+
+const (
+	// ToolTimeoutContinue records the timeout and lets the agent keep iterating (default).
+	ToolTimeoutContinue ToolTimeoutPolicy = "continue"
+	// ToolTimeoutFail stops the agent loop and fails the test.
+	ToolTimeoutFail ToolTimeoutPolicy = "fail"
+)
+
 type VariablePolicy string
 
 const (
@@ -213,6 +317,16 @@ type Session struct {
 	Name         string   `yaml:"name"`
 	Tests        []Test   `yaml:"tests"`
 	AllowedTools []string `yaml:"allowed_tools,omitempty"`
+	Timeout      string   `yaml:"timeout,omitempty"` // Per-test timeout for tests in this session, overrides settings.test_timeout
+	// PromptsFile is a CSV or JSONL corpus expanded into one test per row, each
+	// checked with the session's Assertions
+	PromptsFile string      `yaml:"prompts_file,omitempty"`
+	Assertions  []Assertion `yaml:"assertions,omitempty"`
+	// Retries re-runs a test whose execution recorded errors up to this many
+	// more times, waiting RetryBackoff between attempts; assertion failures
+	// are not retried
+	Retries      int    `yaml:"retries,omitempty"`
+	RetryBackoff string `yaml:"retry_backoff,omitempty"`
 }
 
 // ============================================================================
@@ -225,21 +339,92 @@ type Test struct {
 	Agent        string          `yaml:"agent,omitempty"`
 	Prompt       string          `yaml:"prompt"`
 	StartDelay   string          `yaml:"start_delay,omitempty"`
+	Timeout      string          `yaml:"timeout,omitempty"`      // Overrides the session and settings timeout
+	ToolTimeout  string          `yaml:"tool_timeout,omitempty"` // Overrides the server and settings tool timeout
 	Assertions   []Assertion     `yaml:"assertions"`
 	Extractors   []DataExtractor `yaml:"extractors,omitempty"`
 	AllowedTools []string        `yaml:"allowed_tools,omitempty"`
+	Turns        []Turn          `yaml:"turns,omitempty"`       // Follow-up user turns sent after prompt in the same conversation
+	SkipIf       string          `yaml:"skip_if,omitempty"`     // Condition evaluated before execution; the test is skipped when it holds
+	SkipReason   string          `yaml:"skip_reason,omitempty"` // Reason reported for a skip (defaults to the condition)
+	ToolChoice   string          `yaml:"tool_choice,omitempty"` // Overrides the agent's tool_choice: auto, none, required or a tool name
+	Model        string          `yaml:"model,omitempty"`       // Overrides the agent's model; reported as "agent (model)"
+	Weight       *float64        `yaml:"weight,omitempty"`      // Relative importance in weighted pass rates (default 1, must be greater than 0)
+	Fixtures     []string        `yaml:"fixtures,omitempty"`    // Files or directories copied into {{FIXTURE_DIR}} before the test runs
+	Tags         []string        `yaml:"tags,omitempty"`        // Free-form labels; reports can be grouped by them with -group-by tag
+	// AssertionMode is "all" (default) or "first_fail", which stops evaluating at
+	// the first assertion that fails the test to save judge calls
+	AssertionMode AssertionMode `yaml:"assertion_mode,omitempty"`
+	// Retries and RetryBackoff override the session's retries and retry_backoff;
+	// retries: 0 turns off the session's retries for this test
+	Retries      *int   `yaml:"retries,omitempty"`
+	RetryBackoff string `yaml:"retry_backoff,omitempty"`
+	// Variables are the columns of the prompts_file row a test was generated from
+	Variables map[string]string `yaml:"-"`
+}
+
+// AssertionMode decides whether a test's assertions are all evaluated.
+type AssertionMode string
+
+const (
+	// AssertionModeAll evaluates and reports every assertion (default).
+	AssertionModeAll AssertionMode = "all"
+	// AssertionModeFirstFail stops at the first assertion that fails the test.
+	AssertionModeFirstFail AssertionMode = "first_fail"
+)
+
+// DefaultTestWeight is the weight of a test that does not set one.
+const DefaultTestWeight = 1.0
+
+// EffectiveWeight returns the test's weight, defaulting to DefaultTestWeight.
+func (t Test) EffectiveWeight() float64 {
+	if t.Weight == nil {
+		return DefaultTestWeight
+	}
+	return *t.Weight
+}
+
+// Turn is a scripted follow-up user message within a multi-turn test.
+// Assertions listed on a turn are evaluated against that turn's execution only.
+type Turn struct {
+	Prompt     string      `yaml:"prompt"`
+	Assertions []Assertion `yaml:"assertions,omitempty"`
 }
 
 type Assertion struct {
-	Type     string            `yaml:"type"`
-	Tool     string            `yaml:"tool,omitempty"`
-	Value    string            `yaml:"value,omitempty"`
-	Expected int               `yaml:"expected,omitempty"` // For cli_exit_code_equals
-	Params   map[string]string `yaml:"params,omitempty"`
-	Sequence []string          `yaml:"sequence,omitempty"`
-	Pattern  string            `yaml:"pattern,omitempty"`
-	Count    int               `yaml:"count,omitempty"`
-	Path     string            `yaml:"path,omitempty"`
+	Type       string            `yaml:"type"`
+	Tool       string            `yaml:"tool,omitempty"`
+	Value      string            `yaml:"value,omitempty"`
+	Expected   interface{}       `yaml:"expected,omitempty"` // Exit code of cli_exit_code_equals, value of output_field_equals
+	Params     map[string]string `yaml:"params,omitempty"`
+	Sequence   []string          `yaml:"sequence,omitempty"`
+	Pattern    string            `yaml:"pattern,omitempty"`
+	Count      int               `yaml:"count,omitempty"`
+	Path       string            `yaml:"path,omitempty"`
+	Field      string            `yaml:"field,omitempty"`       // For tool_succeeded (defaults to "ok") and tool_returned_content; max_tokens_used count (total, prompt or completion)
+	Values     []string          `yaml:"values,omitempty"`      // For output_one_of
+	IgnoreCase bool              `yaml:"ignore_case,omitempty"` // For output_one_of
+	Threshold  float64           `yaml:"threshold,omitempty"`   // For llm_rubric with mean/median aggregation (default 0.5); minimum confidence for output_language
+	Severity   string            `yaml:"severity,omitempty"`    // "error" (default) fails the test; "warning" only flags it
+	// Bounds of tool_call_count; without any of them count is the exact number
+	Min   *int `yaml:"min,omitempty"`
+	Max   *int `yaml:"max,omitempty"`
+	Exact *int `yaml:"exact,omitempty"`
+	// Checks of json_path on the value at path; without any of them the value
+	// must exist
+	Equals   string `yaml:"equals,omitempty"`
+	Contains string `yaml:"contains,omitempty"`
+	Exists   *bool  `yaml:"exists,omitempty"`
+	// Parameter (dotted path) of tool_param_regex, and whether "any" (default)
+	// or "all" calls to the tool must match, for tool_param_matches_regex and
+	// tool_param_regex
+	Param string `yaml:"param,omitempty"`
+	Match string `yaml:"match,omitempty"`
+	// Whether expected_error fails when no error occurred (default true)
+	RequireError *bool `yaml:"require_error,omitempty"`
+	// JSON Schema of output_json_schema, inline or in a file relative to TEST_DIR
+	Schema     map[string]interface{} `yaml:"schema,omitempty"`
+	SchemaFile string                 `yaml:"schema_file,omitempty"`
 
 	// Boolean combinators (JSON Schema style)
 	AnyOf []Assertion `yaml:"anyOf,omitempty"` // OR - pass if ANY child passes
@@ -247,6 +432,20 @@ type Assertion struct {
 	Not   *Assertion  `yaml:"not,omitempty"`   // NOT - pass if child FAILS
 }
 
+// TypeName returns the assertion's type, or the key of its combinator (anyOf,
+// allOf or not), which takes precedence over type.
+func (a Assertion) TypeName() string {
+	switch {
+	case len(a.AnyOf) > 0:
+		return "anyOf"
+	case len(a.AllOf) > 0:
+		return "allOf"
+	case a.Not != nil:
+		return "not"
+	}
+	return a.Type
+}
+
 func (a Assertion) Clone() Assertion {
 	// Copy map
 	var params map[string]string
@@ -264,6 +463,12 @@ func (a Assertion) Clone() Assertion {
 		copy(sequence, a.Sequence)
 	}
 
+	var values []string
+	if a.Values != nil {
+		values = make([]string, len(a.Values))
+		copy(values, a.Values)
+	}
+
 	// Copy anyOf slice
 	var anyOf []Assertion
 	if a.AnyOf != nil {
@@ -290,17 +495,34 @@ func (a Assertion) Clone() Assertion {
 	}
 
 	return Assertion{
-		Type:     a.Type,
-		Tool:     a.Tool,
-		Value:    a.Value,
-		Params:   params,
-		Sequence: sequence,
-		Pattern:  a.Pattern,
-		Count:    a.Count,
-		Path:     a.Path,
-		AnyOf:    anyOf,
-		AllOf:    allOf,
-		Not:      notAssertion,
+		Type:         a.Type,
+		Tool:         a.Tool,
+		Value:        a.Value,
+		Expected:     a.Expected,
+		Params:       params,
+		Sequence:     sequence,
+		Pattern:      a.Pattern,
+		Count:        a.Count,
+		Path:         a.Path,
+		Field:        a.Field,
+		Values:       values,
+		IgnoreCase:   a.IgnoreCase,
+		Threshold:    a.Threshold,
+		Severity:     a.Severity,
+		Min:          a.Min,
+		Max:          a.Max,
+		Exact:        a.Exact,
+		Equals:       a.Equals,
+		Contains:     a.Contains,
+		Exists:       a.Exists,
+		Param:        a.Param,
+		Match:        a.Match,
+		RequireError: a.RequireError,
+		Schema:       a.Schema,
+		SchemaFile:   a.SchemaFile,
+		AnyOf:        anyOf,
+		AllOf:        allOf,
+		Not:          notAssertion,
 	}
 }
 
@@ -312,20 +534,208 @@ type ExecutionResult struct {
 	TestName           string              `json:"testName"`
 	AgentName          string              `json:"agentName"`
 	ProviderType       ProviderType        `json:"providerType"`
+	Model              string              `json:"model,omitempty"` // Set when an agent or test overrides the provider's model
 	StartTime          time.Time           `json:"startTime"`
 	EndTime            time.Time           `json:"endTime"`
 	Messages           []Message           `json:"messages"`
 	ToolCalls          []ToolCall          `json:"toolCalls"`
 	FinalOutput        string              `json:"finalOutput"`
 	TokensUsed         int                 `json:"tokensUsed"`
+	TokensEstimated    bool                `json:"tokensEstimated,omitempty"`  // Token counts were estimated because the provider reported no usage
+	Tokenizer          string              `json:"tokenizer,omitempty"`        // Tokenizer used for the estimate, e.g. "tiktoken/cl100k_base" or "claude-approx"
+	PromptTokens       int                 `json:"promptTokens,omitempty"`     // Input tokens reported by the provider
+	CompletionTokens   int                 `json:"completionTokens,omitempty"` // Output tokens reported by the provider
+	CacheReadTokens    int                 `json:"cacheReadTokens,omitempty"`  // Input tokens served from the prompt cache
+	CacheWriteTokens   int                 `json:"cacheWriteTokens,omitempty"` // Input tokens written to the prompt cache
 	LatencyMs          int64               `json:"latencyMs"`
 	Errors             []string            `json:"errors"`
+	ErrorDetails       []ExecutionError    `json:"errorDetails,omitempty"`       // Classified errors, a subset of Errors
 	SourceFile         string              `json:"sourceFile,omitempty"`         // Source test file (for suite runs)
 	SuiteName          string              `json:"suiteName,omitempty"`          // Suite name (for suite runs)
 	SessionName        string              `json:"sessionName,omitempty"`        // Session name
+	Tags               []string            `json:"tags,omitempty"`               // Tags of the test, for grouping reports by tag
+	SystemPrompt       string              `json:"systemPrompt,omitempty"`       // Effective system prompt the agent was primed with
+	ToolChoice         string              `json:"toolChoice,omitempty"`         // Effective tool_choice sent to the provider
+	TempDir            string              `json:"tempDir,omitempty"`            // Per-test directory kept by -keep-temp for inspection
+	StateReset         []string            `json:"stateReset,omitempty"`         // Servers reset with reset_between_agents before this agent's session
 	RateLimitStats     *RateLimitStats     `json:"rateLimitStats,omitempty"`     // Rate limiting and 429 stats
 	ClarificationStats *ClarificationStats `json:"clarificationStats,omitempty"` // Clarification detection stats
 	BugFindings        []BugFinding        `json:"bugFindings,omitempty"`        // MCP server-side bugs detected in tool responses
+	// AssertionDurationMs is the time spent evaluating the test's assertions,
+	// including LLM judge calls; it is not part of LatencyMs
+	AssertionDurationMs int64 `json:"assertionDurationMs,omitempty"`
+	// RetryAttempts is the attempt that produced this result, out of
+	// MaxAttempts; both are set only for tests with retries
+	RetryAttempts int `json:"retryAttempts,omitempty"`
+	MaxAttempts   int `json:"maxAttempts,omitempty"`
+}
+
+// ErrorKind classifies an execution error so checks do not depend on message wording.
+type ErrorKind string
+
+const (
+	ErrorKindTestTimeout ErrorKind = "test_timeout"
+	ErrorKindRateLimit   ErrorKind = "rate_limit"
+	ErrorKindInterrupted ErrorKind = "interrupted"
+	ErrorKindToolTimeout ErrorKind = "tool_timeout"
+	ErrorKindRunDeadline ErrorKind = "run_deadline"
+	ErrorKindFixture     ErrorKind = "fixture"
+	ErrorKindStateReset  ErrorKind = "state_reset"
+)
+
+// IsRateLimitError reports whether err is a provider rate limit (HTTP 429) error.
+// This is the single place that inspects error wording; callers record the
+// result as ErrorKindRateLimit so assertions can rely on the kind instead.
+func IsRateLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := err.Error()
+	return strings.Contains(errStr, "429") ||
+		strings.Contains(errStr, "rate limit") ||
+		strings.Contains(errStr, "Rate limit") ||
+		strings.Contains(errStr, "too many requests") ||
+		strings.Contains(errStr, "Too Many Requests")
+}
+
+// ExecutionError is an error recorded with its kind.
+type ExecutionError struct {
+	Kind    ErrorKind `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// AddError records a classified error in both Errors and ErrorDetails.
+func (r *ExecutionResult) AddError(kind ErrorKind, message string) {
+	r.Errors = append(r.Errors, message)
+	r.ErrorDetails = append(r.ErrorDetails, ExecutionError{Kind: kind, Message: message})
+}
+
+// HasErrorKind reports whether an error of the given kind was recorded.
+func (r *ExecutionResult) HasErrorKind(kind ErrorKind) bool {
+	return r.CountErrorKind(kind) > 0
+}
+
+// CountErrorKind returns how many errors of the given kind were recorded.
+func (r *ExecutionResult) CountErrorKind(kind ErrorKind) int {
+	count := 0
+	for _, e := range r.ErrorDetails {
+		if e.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// ProviderLabel returns the provider a test ran with, followed by the model in
+// parentheses when the provider's model was overridden.
+func (r *ExecutionResult) ProviderLabel() string {
+	if r.Model == "" {
+		return string(r.ProviderType)
+	}
+	return fmt.Sprintf("%s (%s)", r.ProviderType, r.Model)
+}
+
+// TokenBreakdown describes the prompt, completion and prompt cache token counts
+// the provider reported, e.g. "prompt 1200, completion 80, cache read 1000", or
+// returns "" when it reported none.
+func (r *ExecutionResult) TokenBreakdown() string {
+	var parts []string
+	for _, c := range []struct {
+		label string
+		n     int
+	}{
+		{"prompt", r.PromptTokens},
+		{"completion", r.CompletionTokens},
+		{"cache read", r.CacheReadTokens},
+		{"cache write", r.CacheWriteTokens},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", c.label, c.n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ModelRunName returns the agent name a test run is reported under: a test that
+// overrides the model runs as its own "agent (model)" column.
+func ModelRunName(agentName, modelName string) string {
+	if modelName == "" {
+		return agentName
+	}
+	return fmt.Sprintf("%s (%s)", agentName, modelName)
+}
+
+// LastIteration returns the highest agent loop iteration recorded on a message
+// or tool call, or 0 when none was.
+func (r *ExecutionResult) LastIteration() int {
+	last := 0
+	for _, m := range r.Messages {
+		last = max(last, m.Iteration)
+	}
+	for _, tc := range r.ToolCalls {
+		last = max(last, tc.Iteration)
+	}
+	return last
+}
+
+// AppendTurn merges the execution of a follow-up turn into r.
+// Only the newest user message of the turn is recorded, because the agent
+// re-records the whole conversation history on every call. The turn's
+// iterations continue the numbering of r, so they stay distinct per test.
+func (r *ExecutionResult) AppendTurn(turn ExecutionResult) {
+	offset := r.LastIteration()
+	firstReply := 0
+	for firstReply < len(turn.Messages) && turn.Messages[firstReply].Role == "user" {
+		firstReply++
+	}
+	start := len(r.Messages)
+	if firstReply > 0 {
+		r.Messages = append(r.Messages, turn.Messages[firstReply-1:]...)
+	} else {
+		r.Messages = append(r.Messages, turn.Messages...)
+	}
+	for i := start; i < len(r.Messages); i++ {
+		if r.Messages[i].Iteration > 0 {
+			r.Messages[i].Iteration += offset
+		}
+	}
+
+	start = len(r.ToolCalls)
+	r.ToolCalls = append(r.ToolCalls, turn.ToolCalls...)
+	for i := start; i < len(r.ToolCalls); i++ {
+		if r.ToolCalls[i].Iteration > 0 {
+			r.ToolCalls[i].Iteration += offset
+		}
+	}
+	r.Errors = append(r.Errors, turn.Errors...)
+	r.ErrorDetails = append(r.ErrorDetails, turn.ErrorDetails...)
+	r.BugFindings = append(r.BugFindings, turn.BugFindings...)
+	r.TokensUsed += turn.TokensUsed
+	r.TokensEstimated = r.TokensEstimated || turn.TokensEstimated
+	if turn.Tokenizer != "" {
+		r.Tokenizer = turn.Tokenizer
+	}
+	r.PromptTokens += turn.PromptTokens
+	r.CompletionTokens += turn.CompletionTokens
+	r.CacheReadTokens += turn.CacheReadTokens
+	r.CacheWriteTokens += turn.CacheWriteTokens
+	r.FinalOutput = turn.FinalOutput
+	r.EndTime = turn.EndTime
+	r.LatencyMs = r.EndTime.Sub(r.StartTime).Milliseconds()
+
+	if turn.RateLimitStats != nil {
+		r.RateLimitStats = turn.RateLimitStats
+	}
+	if turn.ClarificationStats != nil {
+		if r.ClarificationStats == nil {
+			r.ClarificationStats = &ClarificationStats{Iterations: []int{}, Examples: []string{}}
+		}
+		r.ClarificationStats.Count += turn.ClarificationStats.Count
+		for _, iteration := range turn.ClarificationStats.Iterations {
+			r.ClarificationStats.Iterations = append(r.ClarificationStats.Iterations, iteration+offset)
+		}
+		r.ClarificationStats.Examples = append(r.ClarificationStats.Examples, turn.ClarificationStats.Examples...)
+	}
 }
 
 // ClarificationStats tracks when the LLM asks for clarification instead of acting
@@ -353,25 +763,31 @@ type RateLimitStats struct {
 	RetryCount        int   `json:"retryCount"`        // Number of retry attempts made
 	RetryWaitTimeMs   int64 `json:"retryWaitTimeMs"`   // Total time spent waiting for retries (ms)
 	RetrySuccessCount int   `json:"retrySuccessCount"` // Number of successful retries
+	// Backoff applied before each retry (ms), in order, after Retry-After and max_backoff
+	RetryBackoffsMs []int64 `json:"retryBackoffsMs,omitempty"`
 }
 
 type Message struct {
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	Iteration int       `json:"iteration,omitempty"` // Agent loop iteration that produced the message; 0 for prompts
 }
 
 type ToolCall struct {
 	Name       string                 `json:"name"`
 	Parameters map[string]interface{} `json:"parameters"`
 	Timestamp  time.Time              `json:"timestamp"`
+	Iteration  int                    `json:"iteration,omitempty"` // Agent loop iteration that requested the call
 	DurationMs int64                  `json:"duration_ms,omitempty"`
+	TimedOut   bool                   `json:"timed_out,omitempty"` // DurationMs is the time spent before the call was abandoned
 	Result     Result                 `json:"result,omitempty"`
 }
 
 type Result struct {
 	Content           []ContentItem     `json:"content"`
 	StructuredContent StructuredContent `json:"structuredContent"`
+	IsError           bool              `json:"isError,omitempty"` // The tool reported an error, or the call itself failed
 }
 
 type ContentItem struct {
@@ -394,11 +810,40 @@ type StructuredEntry struct {
 // TEST RESULT
 // ============================================================================
 
+// Assertion severities. A failed warning is reported but does not fail the test.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
 type AssertionResult struct {
-	Type    string                 `json:"type"`
-	Passed  bool                   `json:"passed"`
-	Message string                 `json:"message"`
-	Details map[string]interface{} `json:"details"`
+	Type     string                 `json:"type"`
+	Passed   bool                   `json:"passed"`
+	Disabled bool                   `json:"disabled,omitempty"` // Not evaluated because of the assertion filter; counts as passed
+	Severity string                 `json:"severity,omitempty"`
+	Message  string                 `json:"message"`
+	Details  map[string]interface{} `json:"details"`
+}
+
+// IsWarning reports whether the assertion failed at warning level.
+func (r AssertionResult) IsWarning() bool {
+	return !r.Passed && r.Severity == SeverityWarning
+}
+
+// FailsTest reports whether the assertion failed at error level.
+func (r AssertionResult) FailsTest() bool {
+	return !r.Passed && r.Severity != SeverityWarning
+}
+
+// CountWarnings returns how many assertions failed at warning level.
+func CountWarnings(results []AssertionResult) int {
+	count := 0
+	for _, r := range results {
+		if r.IsWarning() {
+			count++
+		}
+	}
+	return count
 }
 
 // ============================================================================
@@ -441,22 +886,322 @@ func (e *DataExtractor) Extract(result *ExecutionResult, templateContext map[str
 
 }
 
+// ============================================================================
+// JUDGES
+// ============================================================================
+
+// JudgeAggregation selects how the verdicts of several judges are combined.
+type JudgeAggregation string
+
+const (
+	JudgeAggregationMajority JudgeAggregation = "majority" // Pass when more than half of the judges pass
+	JudgeAggregationMean     JudgeAggregation = "mean"     // Pass when the mean score reaches the threshold
+	JudgeAggregationMedian   JudgeAggregation = "median"   // Pass when the median score reaches the threshold
+)
+
+// DefaultJudgeThreshold is the score an aggregated mean or median must reach to pass.
+const DefaultJudgeThreshold = 0.5
+
+// JudgeVerdict is a single judge's grading of an output against a rubric.
+type JudgeVerdict struct {
+	Pass   bool    `json:"pass"`
+	Score  float64 `json:"score"` // 0.0 - 1.0
+	Reason string  `json:"reason"`
+}
+
+// Pairwise comparison results, from the point of view of the run they are recorded on.
+const (
+	PairwiseWin  = "win"
+	PairwiseLoss = "loss"
+	PairwiseTie  = "tie"
+)
+
+// PairwiseVerdict is a judge's preference between two answers, A and B.
+type PairwiseVerdict struct {
+	Winner string `json:"winner"` // "A", "B" or "tie"
+	Reason string `json:"reason"`
+}
+
+// PairwiseOutcome is a run's result in a head-to-head comparison with the run
+// of another agent on the same test.
+type PairwiseOutcome struct {
+	Opponent string `json:"opponent"` // Agent the run was compared with
+	Result   string `json:"result"`   // win, loss or tie
+	Reason   string `json:"reason,omitempty"`
+	Cached   bool   `json:"cached,omitempty"` // The judgment came from the pairwise cache
+}
+
+// Judge grades agent output against a rubric, typically by asking an LLM.
+type Judge interface {
+	Name() string
+	Grade(ctx context.Context, rubric, output string) (JudgeVerdict, error)
+}
+
+// JudgePanel is the set of judges used by judge-based assertions.
+type JudgePanel struct {
+	Judges      []Judge
+	Aggregation JudgeAggregation
+}
+
+type judgePanelKey struct{}
+
+type goldenUpdateKey struct{}
+
+type assertionFilterKey struct{}
+
+// AssertionFilter selects the assertion types that are evaluated (the
+// -disable-assertions and -only-assertions flags). Assertions it leaves out are
+// reported as disabled and do not affect whether a test passes. Combinators are
+// matched by their key: anyOf, allOf or not.
+type AssertionFilter struct {
+	Disabled []string // Types that are never evaluated
+	Only     []string // When set, the only types that are evaluated
+}
+
+// Allows reports whether assertions of the given type are evaluated.
+func (f AssertionFilter) Allows(assertionType string) bool {
+	if slices.Contains(f.Disabled, assertionType) {
+		return false
+	}
+	return len(f.Only) == 0 || slices.Contains(f.Only, assertionType)
+}
+
+// IsZero reports whether the filter lets every assertion through.
+func (f AssertionFilter) IsZero() bool {
+	return len(f.Disabled) == 0 && len(f.Only) == 0
+}
+
+// WithAssertionFilter returns a context carrying the assertion filter of the run.
+func WithAssertionFilter(ctx context.Context, filter AssertionFilter) context.Context {
+	return context.WithValue(ctx, assertionFilterKey{}, filter)
+}
+
+// AssertionFilterFromContext returns the assertion filter stored in ctx, which
+// allows everything when none is set.
+func AssertionFilterFromContext(ctx context.Context) AssertionFilter {
+	filter, _ := ctx.Value(assertionFilterKey{}).(AssertionFilter)
+	return filter
+}
+
+// WithGoldenUpdate returns a context that makes matches_golden assertions rewrite
+// their golden files (the -update-golden flag), through one GoldenWriter shared
+// by the run's tests.
+func WithGoldenUpdate(ctx context.Context, update bool) context.Context {
+	if !update {
+		return context.WithValue(ctx, goldenUpdateKey{}, (*GoldenWriter)(nil))
+	}
+	return context.WithValue(ctx, goldenUpdateKey{}, NewGoldenWriter())
+}
+
+// GoldenWriterFromContext returns the golden writer of the run, or nil when ctx
+// does not ask for golden files to be rewritten.
+func GoldenWriterFromContext(ctx context.Context) *GoldenWriter {
+	writer, _ := ctx.Value(goldenUpdateKey{}).(*GoldenWriter)
+	return writer
+}
+
+// WithJudgePanel returns a context carrying the judge panel for assertion evaluation.
+func WithJudgePanel(ctx context.Context, panel *JudgePanel) context.Context {
+	return context.WithValue(ctx, judgePanelKey{}, panel)
+}
+
+// JudgePanelFromContext returns the judge panel stored in ctx, or nil.
+func JudgePanelFromContext(ctx context.Context) *JudgePanel {
+	panel, _ := ctx.Value(judgePanelKey{}).(*JudgePanel)
+	return panel
+}
+
+// Grade asks every judge for a verdict and combines them. A judge that errors is
+// reported in the details and excluded from the aggregate; an error is returned
+// only when no judge produced a verdict.
+func (p *JudgePanel) Grade(ctx context.Context, rubric, output string, threshold float64) (bool, map[string]interface{}, error) {
+	if threshold <= 0 {
+		threshold = DefaultJudgeThreshold
+	}
+	aggregation := p.Aggregation
+	if aggregation == "" {
+		aggregation = JudgeAggregationMajority
+	}
+
+	// Judges are listed in panel order, since two judges may share a name
+	perJudge := make([]interface{}, 0, len(p.Judges))
+	var scores []float64
+	passVotes := 0
+	for _, judge := range p.Judges {
+		verdict, err := judge.Grade(ctx, rubric, output)
+		if err != nil {
+			logger.Logger.Warn("Judge failed, excluding it from the aggregate", "judge", judge.Name(), "error", err)
+			perJudge = append(perJudge, map[string]interface{}{"judge": judge.Name(), "error": err.Error()})
+			continue
+		}
+		perJudge = append(perJudge, map[string]interface{}{
+			"judge":  judge.Name(),
+			"pass":   verdict.Pass,
+			"score":  verdict.Score,
+			"reason": verdict.Reason,
+		})
+		scores = append(scores, verdict.Score)
+		if verdict.Pass {
+			passVotes++
+		}
+	}
+
+	details := map[string]interface{}{
+		"judges":      perJudge,
+		"aggregation": string(aggregation),
+		"votes":       len(scores),
+		"pass_votes":  passVotes,
+	}
+	if len(scores) == 0 {
+		return false, details, fmt.Errorf("all %d judges failed", len(p.Judges))
+	}
+
+	sort.Float64s(scores)
+	var sum float64
+	for _, score := range scores {
+		sum += score
+	}
+	mean := sum / float64(len(scores))
+	median := scores[len(scores)/2]
+	if len(scores)%2 == 0 {
+		median = (scores[len(scores)/2-1] + scores[len(scores)/2]) / 2
+	}
+
+	switch aggregation {
+	case JudgeAggregationMean:
+		details["score"] = mean
+		details["threshold"] = threshold
+		return mean >= threshold, details, nil
+	case JudgeAggregationMedian:
+		details["score"] = median
+		details["threshold"] = threshold
+		return median >= threshold, details, nil
+	case JudgeAggregationMajority:
+		details["score"] = mean
+		return passVotes*2 > len(scores), details, nil
+	default:
+		return false, details, fmt.Errorf("unknown judge aggregation %q (use majority, mean or median)", aggregation)
+	}
+}
+
 // ============================================================================
 // ASSERTION EVALUATOR
 // ============================================================================
 
 type AssertionEvaluator struct {
 	knownTools      []string
+	toolParameters  map[string][]string // Declared parameter names per tool (from server schemas)
 	result          *ExecutionResult
 	templateContext map[string]string
+	judges          *JudgePanel
+	judgeCtx        context.Context
+	golden          *GoldenWriter // matches_golden rewrites golden files instead of comparing
+	failFast        bool          // Stop at the first assertion that fails the test
+	filter          AssertionFilter
 }
 
 func NewAssertionEvaluator(result *ExecutionResult, templateContext map[string]string, knownTools []string) *AssertionEvaluator {
 	return &AssertionEvaluator{result: result, templateContext: templateContext, knownTools: knownTools}
 }
 
+// WithJudges sets the judge panel used by the llm_rubric assertion. ctx bounds the judge calls.
+func (e *AssertionEvaluator) WithJudges(ctx context.Context, judges *JudgePanel) *AssertionEvaluator {
+	e.judgeCtx = ctx
+	e.judges = judges
+	return e
+}
+
+// WithGoldenWriter makes matches_golden record the run as the new golden
+// transcript through writer instead of comparing against it. A nil writer
+// compares.
+func (e *AssertionEvaluator) WithGoldenWriter(writer *GoldenWriter) *AssertionEvaluator {
+	e.golden = writer
+	return e
+}
+
+// WithToolParameters sets the declared parameter names per tool, as advertised by the
+// servers' tool schemas. Required by the no_hallucinated_params assertion.
+func (e *AssertionEvaluator) WithToolParameters(toolParameters map[string][]string) *AssertionEvaluator {
+	e.toolParameters = toolParameters
+	return e
+}
+
+// WithAssertionMode sets whether Evaluate stops at the first assertion that
+// fails the test (AssertionModeFirstFail) or evaluates them all.
+func (e *AssertionEvaluator) WithAssertionMode(mode AssertionMode) *AssertionEvaluator {
+	e.failFast = mode == AssertionModeFirstFail
+	return e
+}
+
+// WithAssertionFilter sets which top-level assertion types are evaluated; the
+// others are reported as disabled.
+func (e *AssertionEvaluator) WithAssertionFilter(filter AssertionFilter) *AssertionEvaluator {
+	e.filter = filter
+	return e
+}
+
 func (e *AssertionEvaluator) Evaluate(assertions []Assertion) []AssertionResult {
-	return e.evaluateWithDepth(assertions, 0)
+	results := make([]AssertionResult, 0, len(assertions))
+	for i := range assertions {
+		if assertionType := assertions[i].TypeName(); !e.filter.Allows(assertionType) {
+			results = append(results, AssertionResult{
+				Type:     assertionType,
+				Passed:   true,
+				Disabled: true,
+				Message:  "Disabled by the assertion filter",
+			})
+			continue
+		}
+
+		result := e.evaluateWithDepth(assertions[i:i+1], 0)[0]
+		applySeverity(assertions[i], &result)
+		results = append(results, result)
+		// Warning-level failures do not fail the test, so evaluation carries on past them
+		if e.failFast && result.FailsTest() {
+			MarkNotEvaluated(&results[i], len(assertions)-i-1)
+			break
+		}
+	}
+	return results
+}
+
+// MarkNotEvaluated records on a failed assertion how many assertions after it
+// were not evaluated under AssertionModeFirstFail.
+func MarkNotEvaluated(result *AssertionResult, count int) {
+	if count <= 0 {
+		return
+	}
+	if result.Details == nil {
+		result.Details = make(map[string]interface{})
+	}
+	previous, _ := result.Details["not_evaluated"].(int)
+	result.Details["not_evaluated"] = previous + count
+}
+
+// FirstTestFailure returns the index of the first result that fails the test, or -1.
+func FirstTestFailure(results []AssertionResult) int {
+	for i, r := range results {
+		if r.FailsTest() {
+			return i
+		}
+	}
+	return -1
+}
+
+// applySeverity sets the severity of a top-level assertion result; combinator
+// children only feed their parent.
+func applySeverity(a Assertion, result *AssertionResult) {
+	switch a.Severity {
+	case "", SeverityError:
+		result.Severity = SeverityError
+	case SeverityWarning:
+		result.Severity = SeverityWarning
+	default:
+		result.Severity = SeverityError
+		result.Passed = false
+		result.Message = fmt.Sprintf("Invalid severity: %s (expected %s or %s)",
+			a.Severity, SeverityError, SeverityWarning)
+	}
 }
 
 const maxCombinatorDepth = 10 // Prevent infinite recursion
@@ -494,7 +1239,7 @@ func (e *AssertionEvaluator) evaluateWithDepth(assertions []Assertion, depth int
 			for k, v := range assertion.Params {
 				t, err := raymond.Parse(v)
 				if err == nil {
-					transformed, err := t.Exec(e.templateContext)
+					transformed, err := execTemplate(t, e.templateContext)
 					if err == nil {
 						assertion.Params[k] = transformed
 					}
@@ -506,7 +1251,7 @@ func (e *AssertionEvaluator) evaluateWithDepth(assertions []Assertion, depth int
 		if assertion.Value != "" {
 			t, err := raymond.Parse(assertion.Value)
 			if err == nil {
-				transformed, err := t.Exec(e.templateContext)
+				transformed, err := execTemplate(t, e.templateContext)
 				if err == nil {
 					assertion.Value = transformed
 				}
@@ -522,28 +1267,68 @@ func (e *AssertionEvaluator) evaluateWithDepth(assertions []Assertion, depth int
 			result = e.evalToolCallCount(assertion)
 		case "tool_call_order":
 			result = e.evalToolCallOrder(assertion)
+		case "tool_subsequence":
+			result = e.evalToolSubsequence(assertion)
 		case "tool_param_matches_regex":
 			result = e.evalToolParamMatchesRegex(assertion)
+		case "tool_param_regex":
+			result = e.evalToolParamRegex(assertion)
 		case "tool_param_equals":
 			result = e.evalToolParamEquals(assertion)
 		case "tool_result_matches_json":
 			result = e.evalToolResultMatchesJson(assertion)
+		case "tool_succeeded":
+			result = e.evalToolSucceeded(assertion)
+		case "tool_returned_content":
+			result = e.evalToolReturnedContent(assertion)
+		case "output_reflects_tool_result":
+			result = e.evalOutputReflectsToolResult(assertion)
+		case "json_path":
+			result = e.evalJSONPath(assertion)
 		case "output_contains":
 			result = e.evalOutputContains(assertion)
 		case "output_not_contains":
 			result = e.evalOutputNotContains(assertion)
+		case "output_one_of":
+			result = e.evalOutputOneOf(assertion)
 		case "output_regex":
 			result = e.evalOutputRegex(assertion)
+		case "output_not_regex":
+			result = e.evalOutputNotRegex(assertion)
+		case "output_language":
+			result = e.evalOutputLanguage(assertion)
+		case "output_field_equals":
+			result = e.evalOutputFieldEquals(assertion)
+		case "output_json_schema":
+			result = e.evalOutputJSONSchema(assertion)
+		case "has_final_answer":
+			result = e.evalHasFinalAnswer(assertion)
+		case "llm_rubric":
+			result = e.evalLLMRubric(assertion)
 		case "max_tokens":
 			result = e.evalMaxTokens(assertion)
+		case "max_tokens_used":
+			result = e.evalMaxTokensUsed(assertion)
 		case "max_latency_ms":
 			result = e.evalMaxLatency(assertion)
+		case "first_tool_within_ms":
+			result = e.evalFirstToolWithin(assertion)
+		case "max_assistant_messages":
+			result = e.evalMaxAssistantMessages(assertion)
 		case "no_error_messages":
 			result = e.evalNoErrorMessages(assertion)
+		case "expected_error":
+			result = e.evalExpectedError(assertion)
 		case "no_hallucinated_tools":
 			result = e.evalNoHallucinatedTools(assertion)
+		case "no_hallucinated_params":
+			result = e.evalNoHallucinatedParams(assertion)
+		case "no_empty_params":
+			result = e.evalNoEmptyParams(assertion)
 		case "no_clarification_questions":
 			result = e.evalNoClarificationQuestions(assertion)
+		case "confirms_before_tool":
+			result = e.evalConfirmsBeforeTool(assertion)
 		case "no_rate_limit_errors":
 			result = e.evalNoRateLimitErrors(assertion)
 		case "cli_exit_code_equals":
@@ -554,6 +1339,8 @@ func (e *AssertionEvaluator) evaluateWithDepth(assertions []Assertion, depth int
 			result = e.evalCLIStdoutRegex(assertion)
 		case "cli_stderr_contains":
 			result = e.evalCLIStderrContains(assertion)
+		case "matches_golden":
+			result = e.evalMatchesGolden(assertion)
 		default:
 			result = AssertionResult{
 				Type:    assertion.Type,
@@ -673,15 +1460,14 @@ func (e *AssertionEvaluator) evalToolResultMatchesJson(a Assertion) AssertionRes
 		}
 		found = true
 
-		var data interface{}
-		if err := json.Unmarshal([]byte(fmt.Sprint(tc.Result.Content[0].Text)), &data); err != nil {
-			mismatchesAll = append(mismatchesAll, fmt.Sprintf("Failed to parse JSON: %s", err))
-			continue
-		}
-
-		res, err := jsonpath.Read(data, a.Path)
+		res, err := readToolResultPath(tc, a.Path)
 		if err != nil {
-			mismatchesAll = append(mismatchesAll, fmt.Sprintf("Invalid JSONPath pattern: %s", err))
+			var pathErr *jsonPathError
+			if errors.As(err, &pathErr) {
+				mismatchesAll = append(mismatchesAll, fmt.Sprintf("Invalid JSONPath pattern: %s", err))
+			} else {
+				mismatchesAll = append(mismatchesAll, err.Error())
+			}
 			continue
 		}
 
@@ -713,213 +1499,1549 @@ func (e *AssertionEvaluator) evalToolResultMatchesJson(a Assertion) AssertionRes
 	}
 }
 
-// getNestedValue retrieves a value from a nested map using dot notation
-// e.g., "args.inner.ininner" will traverse m["args"]["inner"]["ininner"]
-func getNestedValue(m map[string]interface{}, path string) (interface{}, bool) {
-	keys := strings.Split(path, ".")
-	var current interface{} = m
+// evalToolSucceeded checks that every call to a tool returned a JSON result
+// whose success flag (a.Field, default "ok") is true
+func (e *AssertionEvaluator) evalToolSucceeded(a Assertion) AssertionResult {
+	field := a.Field
+	if field == "" {
+		field = "ok"
+	}
 
-	for _, key := range keys {
-		currentMap, ok := current.(map[string]interface{})
-		if !ok {
-			return nil, false
+	calls := 0
+	var failures []map[string]interface{}
+
+	for i, tc := range e.result.ToolCalls {
+		if tc.Name != a.Tool {
+			continue
 		}
+		calls++
 
-		value, exists := currentMap[key]
-		if !exists {
-			return nil, false
+		reason := ""
+		if len(tc.Result.Content) == 0 {
+			reason = "no content in tool result"
+		} else {
+			reason = resultFlagFailure(tc.Result.Content[0].Text, field)
 		}
 
-		current = value
+		if reason != "" {
+			failures = append(failures, map[string]interface{}{
+				"invocation": calls,
+				"call_index": i,
+				"reason":     reason,
+			})
+		}
 	}
 
-	return current, true
-}
-func (e *AssertionEvaluator) evalToolCallCount(a Assertion) AssertionResult {
-	count := 0
-	for _, tc := range e.result.ToolCalls {
-		if tc.Name == a.Tool || a.Tool == "" {
-			count++
+	if calls == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Tool '%s' was not called", a.Tool),
 		}
 	}
 
-	expected := a.Count
-	passed := count == expected
+	if len(failures) > 0 {
+		return AssertionResult{
+			Type:   a.Type,
+			Passed: false,
+			Message: fmt.Sprintf("Tool '%s' failed in %d of %d calls (first failure: invocation %d, %s)",
+				a.Tool, len(failures), calls, failures[0]["invocation"], failures[0]["reason"]),
+			Details: map[string]interface{}{
+				"field":    field,
+				"calls":    calls,
+				"failures": failures,
+			},
+		}
+	}
 
 	return AssertionResult{
 		Type:    a.Type,
-		Passed:  passed,
-		Message: fmt.Sprintf("Tool '%s' called %d times (expected %d)", a.Tool, count, expected),
+		Passed:  true,
+		Message: fmt.Sprintf("Tool '%s' succeeded in all %d calls ('%s' is true)", a.Tool, calls, field),
 		Details: map[string]interface{}{
-			"actual":   count,
-			"expected": expected,
+			"field": field,
+			"calls": calls,
 		},
 	}
 }
 
-func (e *AssertionEvaluator) evalToolCallOrder(a Assertion) AssertionResult {
-	if len(a.Sequence) == 0 {
-		return AssertionResult{
-			Type:    a.Type,
-			Passed:  false,
-			Message: "No sequence specified",
+// evalToolReturnedContent checks that every call to a tool returned a non-error
+// result with at least one non-empty text item. With field set, the first such
+// item must also be JSON with that field true, as for tool_succeeded.
+func (e *AssertionEvaluator) evalToolReturnedContent(a Assertion) AssertionResult {
+	calls := 0
+	var failures []map[string]interface{}
+
+	for i, tc := range e.result.ToolCalls {
+		if tc.Name != a.Tool {
+			continue
 		}
-	}
+		calls++
 
-	seqIdx := 0
-	for _, tc := range e.result.ToolCalls {
-		if seqIdx < len(a.Sequence) && tc.Name == a.Sequence[seqIdx] {
-			seqIdx++
+		text := ""
+		for _, item := range tc.Result.Content {
+			if strings.TrimSpace(item.Text) != "" {
+				text = item.Text
+				break
+			}
 		}
-	}
 
-	passed := seqIdx == len(a.Sequence)
+		reason := ""
+		switch {
+		case tc.Result.IsError:
+			reason = "tool returned an error"
+		case text == "":
+			reason = "no non-empty text content in tool result"
+		case a.Field != "":
+			reason = resultFlagFailure(text, a.Field)
+		}
 
-	if passed {
+		if reason != "" {
+			raw, _ := json.Marshal(tc.Result.Content)
+			failures = append(failures, map[string]interface{}{
+				"invocation": calls,
+				"call_index": i,
+				"reason":     reason,
+				"is_error":   tc.Result.IsError,
+				"result":     string(raw),
+			})
+		}
+	}
+
+	if calls == 0 {
 		return AssertionResult{
 			Type:    a.Type,
-			Passed:  true,
-			Message: fmt.Sprintf("Tools called in correct order: %v", a.Sequence),
+			Passed:  false,
+			Message: fmt.Sprintf("Tool '%s' was not called", a.Tool),
 		}
 	}
 
-	return AssertionResult{
-		Type:   a.Type,
-		Passed: false,
-		Message: fmt.Sprintf("Tool order mismatch: expected %v, matched %d/%d",
-			a.Sequence, seqIdx, len(a.Sequence)),
+	if len(failures) > 0 {
+		return AssertionResult{
+			Type:   a.Type,
+			Passed: false,
+			Message: fmt.Sprintf("Tool '%s' returned no usable content in %d of %d calls (first failure: invocation %d, %s)",
+				a.Tool, len(failures), calls, failures[0]["invocation"], failures[0]["reason"]),
+			Details: map[string]interface{}{
+				"calls":    calls,
+				"failures": failures,
+			},
+		}
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: fmt.Sprintf("Tool '%s' returned content in all %d calls", a.Tool, calls),
+		Details: map[string]interface{}{
+			"calls": calls,
+		},
 	}
 }
 
-func (e *AssertionEvaluator) evalToolParamMatchesRegex(a Assertion) AssertionResult {
-	var mismatchesAll [][]string
+// evalOutputReflectsToolResult checks that a value a tool returned, read from its
+// JSON result at a.Path, appears in the final output, so an agent that calls the
+// right tool but makes up the answer fails. Any call of the tool may supply the
+// value; the comparison ignores case.
+func (e *AssertionEvaluator) evalOutputReflectsToolResult(a Assertion) AssertionResult {
+	if a.Tool == "" || a.Path == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "output_reflects_tool_result requires a 'tool' and a 'path'",
+		}
+	}
+	calls := 0
+	values := make([]string, 0)
+	var problems []string
+	output := strings.ToLower(e.result.FinalOutput)
+	for _, tc := range e.result.ToolCalls {
+		if tc.Name != a.Tool {
+			continue
+		}
+		calls++
+		if tc.Result.IsError {
+			problems = append(problems, fmt.Sprintf("call %d returned an error", calls))
+			continue
+		}
 
-	found := false
+		resolved, err := readToolResultPath(tc, a.Path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("call %d: %s", calls, err))
+			continue
+		}
+
+		var value string
+		switch v := resolved.(type) {
+		case string:
+			value = v
+		case float64, bool:
+			value = jsonLiteral(v)
+		default:
+			problems = append(problems, fmt.Sprintf("call %d: value at '%s' is not a string, number or boolean", calls, a.Path))
+			continue
+		}
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, fmt.Sprintf("call %d: value at '%s' is empty", calls, a.Path))
+			continue
+		}
+		values = append(values, value)
+
+		if containsWholeValue(output, strings.ToLower(value)) {
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  true,
+				Message: fmt.Sprintf("Final output reflects '%s' returned by tool '%s': %s", a.Path, a.Tool, value),
+				Details: map[string]interface{}{
+					"path":       a.Path,
+					"value":      value,
+					"invocation": calls,
+				},
+			}
+		}
+	}
+
+	if calls == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Tool '%s' was not called", a.Tool),
+		}
+	}
+
+	details := map[string]interface{}{
+		"path":  a.Path,
+		"calls": calls,
+	}
+	if len(problems) > 0 {
+		details["problems"] = problems
+	}
+	if len(values) == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("No result of tool '%s' has a value at '%s'", a.Tool, a.Path),
+			Details: details,
+		}
+	}
+	details["values"] = values
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  false,
+		Message: fmt.Sprintf("Final output does not contain the '%s' value returned by tool '%s' (%s)", a.Path, a.Tool, strings.Join(values, ", ")),
+		Details: details,
+	}
+}
+
+// evalJSONPath checks the value at Path in the JSON result of Tool. It passes
+// when any call's value equals Equals, contains Contains (a substring of a
+// string, or an element of an array) or, by default, exists. With exists:
+// false no call may have a value at Path.
+func (e *AssertionEvaluator) evalJSONPath(a Assertion) AssertionResult {
+	if a.Tool == "" || a.Path == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "json_path requires a 'tool' and a 'path'",
+		}
+	}
+	checks := 0
+	for _, set := range []bool{a.Equals != "", a.Contains != "", a.Exists != nil} {
+		if set {
+			checks++
+		}
+	}
+	if checks > 1 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "json_path takes only one of 'equals', 'contains' or 'exists'",
+		}
+	}
+	mustExist := a.Exists == nil || *a.Exists
+
+	var expected interface{}
+	switch {
+	case a.Equals != "":
+		expected = decodeJSONValue(RenderTemplate(a.Equals, e.templateContext))
+	case a.Contains != "":
+		expected = decodeJSONValue(RenderTemplate(a.Contains, e.templateContext))
+	}
 
+	details := map[string]interface{}{
+		"tool": a.Tool,
+		"path": a.Path,
+	}
+	if expected != nil {
+		details["expected"] = expected
+	}
+
+	calls := 0
+	var problems []string
 	for _, tc := range e.result.ToolCalls {
 		if tc.Name != a.Tool {
 			continue
 		}
-		found = true
-		matches := true
-		mismatches := []string{}
+		calls++
 
-		for key, expectedPattern := range a.Params {
-			actualVal, exists := getNestedValue(tc.Parameters, key)
-			if !exists {
-				matches = false
-				mismatches = append(mismatches, fmt.Sprintf("missing param '%s'", key))
+		value, err := readToolResultPath(tc, a.Path)
+		if err != nil {
+			var pathErr *jsonPathError
+			if errors.As(err, &pathErr) && !mustExist {
 				continue
 			}
+			problems = append(problems, fmt.Sprintf("call %d: %s", calls, err))
+			continue
+		}
+		details["value"] = value
+		details["invocation"] = calls
 
-			// Convert expectedPattern to string and compile regex
-			patternStr := fmt.Sprintf("%v", expectedPattern)
-			re, err := regexp.Compile(patternStr)
-			if err != nil {
-				matches = false
-				mismatches = append(mismatches,
-					fmt.Sprintf("param '%s': invalid regex pattern '%s': %v", key, patternStr, err))
+		switch {
+		case !mustExist:
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  false,
+				Message: fmt.Sprintf("Tool '%s' result has a value at '%s': %s", a.Tool, a.Path, jsonLiteral(value)),
+				Details: details,
+			}
+		case a.Equals != "":
+			if reflect.DeepEqual(value, expected) {
+				return AssertionResult{
+					Type:    a.Type,
+					Passed:  true,
+					Message: fmt.Sprintf("Tool '%s' result '%s' equals %s", a.Tool, a.Path, jsonLiteral(expected)),
+					Details: details,
+				}
+			}
+			problems = append(problems, fmt.Sprintf("call %d: value is %s", calls, jsonLiteral(value)))
+		case a.Contains != "":
+			if problem := jsonContainsFailure(value, expected); problem != "" {
+				problems = append(problems, fmt.Sprintf("call %d: %s", calls, problem))
 				continue
 			}
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  true,
+				Message: fmt.Sprintf("Tool '%s' result '%s' contains %s", a.Tool, a.Path, jsonLiteral(expected)),
+				Details: details,
+			}
+		default:
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  true,
+				Message: fmt.Sprintf("Tool '%s' result has a value at '%s': %s", a.Tool, a.Path, jsonLiteral(value)),
+				Details: details,
+			}
+		}
+	}
 
-			// Convert actual value to string and match against regex
-			strVal := fmt.Sprintf("%v", actualVal)
-			if !re.MatchString(strVal) {
-				matches = false
-				mismatches = append(mismatches,
-					fmt.Sprintf("param '%s': value '%s' does not match regex '%s'", key, strVal, patternStr))
+	if calls == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Tool '%s' was not called", a.Tool),
+			Details: details,
+		}
+	}
+	details["calls"] = calls
+	if len(problems) > 0 {
+		details["problems"] = problems
+	}
+	if !mustExist && len(problems) == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("No result of tool '%s' has a value at '%s'", a.Tool, a.Path),
+			Details: details,
+		}
+	}
+
+	var message string
+	switch {
+	case !mustExist:
+		message = fmt.Sprintf("Tool '%s' result could not be checked for '%s'", a.Tool, a.Path)
+	case a.Equals != "":
+		message = fmt.Sprintf("No result of tool '%s' has '%s' equal to %s", a.Tool, a.Path, jsonLiteral(expected))
+	case a.Contains != "":
+		message = fmt.Sprintf("No result of tool '%s' has '%s' containing %s", a.Tool, a.Path, jsonLiteral(expected))
+	default:
+		message = fmt.Sprintf("No result of tool '%s' has a value at '%s'", a.Tool, a.Path)
+	}
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  false,
+		Message: fmt.Sprintf("%s: %s", message, strings.Join(problems, "; ")),
+		Details: details,
+	}
+}
+
+// containsWholeValue reports whether value appears in text as a whole word or
+// number: a value starting or ending with a letter or digit must not continue
+// into one, so "80" is not found in "8080" and "8.5" is not found in "18.55".
+// A number is also not found inside a larger decimal ("5" in "5.2" or "0.5").
+func containsWholeValue(text, value string) bool {
+	if value == "" {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(value)
+	last, _ := utf8.DecodeLastRuneInString(value)
+	for offset := 0; offset <= len(text)-len(value); {
+		i := strings.Index(text[offset:], value)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(value)
+		if !joinsValue(first, text[:start], true) && !joinsValue(last, text[end:], false) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+	return false
+}
+
+// joinsValue reports whether the text next to a match (before it, or after it
+// when before is false) continues the word or number that edge starts or ends.
+func joinsValue(edge rune, side string, before bool) bool {
+	if side == "" || !isWordRune(edge) {
+		return false
+	}
+	var next, beyond rune
+	if before {
+		var size int
+		next, size = utf8.DecodeLastRuneInString(side)
+		beyond, _ = utf8.DecodeLastRuneInString(side[:len(side)-size])
+	} else {
+		var size int
+		next, size = utf8.DecodeRuneInString(side)
+		beyond, _ = utf8.DecodeRuneInString(side[size:])
+	}
+	if isWordRune(next) {
+		return true
+	}
+	// A decimal point between digits continues a number
+	return unicode.IsDigit(edge) && next == '.' && unicode.IsDigit(beyond)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// jsonPathError reports that a path did not resolve in a tool result that is
+// valid JSON.
+type jsonPathError struct {
+	err error
+}
+
+func (e *jsonPathError) Error() string {
+	return e.err.Error()
+}
+
+// readToolResultPath reads the value at path from the JSON in the first
+// non-empty text item of a tool call's result. path is a JSONPath
+// ("$.items[0]") or a dotted path ("bytes", ".bytes", "config.port"). A path
+// that does not resolve is returned as a *jsonPathError.
+func readToolResultPath(tc ToolCall, path string) (interface{}, error) {
+	text := ""
+	for _, item := range tc.Result.Content {
+		if strings.TrimSpace(item.Text) != "" {
+			text = item.Text
+			break
+		}
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		return nil, fmt.Errorf("result is not valid JSON: %w", err)
+	}
+
+	switch {
+	case strings.HasPrefix(path, "$"):
+	case strings.HasPrefix(path, "."):
+		path = "$" + path
+	default:
+		path = "$." + path
+	}
+	value, err := jsonpath.Read(data, path)
+	if err != nil {
+		return nil, &jsonPathError{err: err}
+	}
+	return value, nil
+}
+
+// decodeJSONValue decodes raw as JSON so 11 and true compare as a number and
+// a boolean, falling back to the string itself.
+func decodeJSONValue(raw string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return raw
+	}
+	return v
+}
+
+// jsonContainsFailure explains why value does not contain expected, or returns
+// "" when it does.
+func jsonContainsFailure(value, expected interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if strings.Contains(v, fmt.Sprint(expected)) {
+			return ""
+		}
+		return fmt.Sprintf("value is %s", jsonLiteral(v))
+	case []interface{}:
+		for _, item := range v {
+			if reflect.DeepEqual(item, expected) {
+				return ""
 			}
 		}
+		return fmt.Sprintf("value is %s", jsonLiteral(v))
+	default:
+		return fmt.Sprintf("value %s is not a string or an array", jsonLiteral(v))
+	}
+}
+
+// resultFlagFailure explains why a JSON tool result does not have field set to
+// true, or returns "" when it does.
+func resultFlagFailure(text, field string) string {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		return fmt.Sprintf("failed to parse result JSON: %s", err)
+	}
+	value, exists := getNestedValue(data, field)
+	if !exists {
+		return fmt.Sprintf("field '%s' missing from result", field)
+	}
+	if ok, isBool := value.(bool); !isBool || !ok {
+		return fmt.Sprintf("field '%s' is %v", field, value)
+	}
+	return ""
+}
+
+// getNestedValue retrieves a value from a nested map using dot notation
+// e.g., "args.inner.ininner" will traverse m["args"]["inner"]["ininner"]
+func getNestedValue(m map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	var current interface{} = m
+
+	for _, key := range keys {
+		currentMap, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		value, exists := currentMap[key]
+		if !exists {
+			return nil, false
+		}
+
+		current = value
+	}
+
+	return current, true
+}
+
+// evalToolCallCount checks how often Tool (any tool when empty) was called
+// against the exact, min and max bounds. count is the exact number when no
+// bound is set, so count: 0 asserts the tool was not called.
+func (e *AssertionEvaluator) evalToolCallCount(a Assertion) AssertionResult {
+	count := 0
+	for _, tc := range e.result.ToolCalls {
+		if tc.Name == a.Tool || a.Tool == "" {
+			count++
+		}
+	}
+
+	exact := a.Exact
+	if exact == nil && (a.Count != 0 || a.Min == nil && a.Max == nil) {
+		exact = &a.Count
+	}
+
+	details := map[string]interface{}{
+		"tool":     a.Tool,
+		"observed": count,
+		"actual":   count,
+	}
+	var bounds []string
+	failed := ""
+	if exact != nil {
+		details["exact"] = *exact
+		bounds = append(bounds, fmt.Sprintf("exactly %d", *exact))
+		if count != *exact {
+			failed = bounds[len(bounds)-1]
+		}
+	}
+	if a.Min != nil {
+		details["min"] = *a.Min
+		bounds = append(bounds, fmt.Sprintf("at least %d", *a.Min))
+		if failed == "" && count < *a.Min {
+			failed = bounds[len(bounds)-1]
+		}
+	}
+	if a.Max != nil {
+		details["max"] = *a.Max
+		bounds = append(bounds, fmt.Sprintf("at most %d", *a.Max))
+		if failed == "" && count > *a.Max {
+			failed = bounds[len(bounds)-1]
+		}
+	}
+
+	if exact != nil {
+		details["expected"] = *exact
+	} else {
+		details["expected"] = strings.Join(bounds, " and ")
+	}
+
+	expected := strings.Join(bounds, " and ")
+	if failed != "" {
+		expected = failed
+	}
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  failed == "",
+		Message: fmt.Sprintf("Tool '%s' called %d times (expected %s)", a.Tool, count, expected),
+		Details: details,
+	}
+}
+
+func (e *AssertionEvaluator) evalToolCallOrder(a Assertion) AssertionResult {
+	if len(a.Sequence) == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "No sequence specified",
+		}
+	}
+
+	seqIdx := 0
+	for _, tc := range e.result.ToolCalls {
+		if seqIdx < len(a.Sequence) && tc.Name == a.Sequence[seqIdx] {
+			seqIdx++
+		}
+	}
+
+	passed := seqIdx == len(a.Sequence)
+
+	if passed {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("Tools called in correct order: %v", a.Sequence),
+		}
+	}
+
+	return AssertionResult{
+		Type:   a.Type,
+		Passed: false,
+		Message: fmt.Sprintf("Tool order mismatch: expected %v, matched %d/%d",
+			a.Sequence, seqIdx, len(a.Sequence)),
+	}
+}
+
+// evalToolSubsequence checks that the sequence appears in order within the tool
+// calls, allowing any other calls in between. Each step is placed at its earliest
+// call after the previous step, which finds a match whenever one exists.
+func (e *AssertionEvaluator) evalToolSubsequence(a Assertion) AssertionResult {
+	if len(a.Sequence) == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "No sequence specified",
+		}
+	}
+
+	positions := make([]int, 0, len(a.Sequence))
+	next := 0
+	for step, name := range a.Sequence {
+		found := -1
+		for i := next; i < len(e.result.ToolCalls); i++ {
+			if e.result.ToolCalls[i].Name == name {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			details := map[string]interface{}{
+				"positions":   positions,
+				"failed_step": step,
+				"failed_tool": name,
+			}
+			if step == 0 {
+				return AssertionResult{
+					Type:    a.Type,
+					Passed:  false,
+					Message: fmt.Sprintf("Tool subsequence broken at step 1: '%s' was never called", name),
+					Details: details,
+				}
+			}
+			details["previous_position"] = positions[step-1]
+			return AssertionResult{
+				Type:   a.Type,
+				Passed: false,
+				Message: fmt.Sprintf("Tool subsequence broken at step %d: '%s' was not called after '%s' (call %d)",
+					step+1, name, a.Sequence[step-1], positions[step-1]+1),
+				Details: details,
+			}
+		}
+		positions = append(positions, found)
+		next = found + 1
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: fmt.Sprintf("Tools called in relative order: %v", a.Sequence),
+		Details: map[string]interface{}{
+			"positions": positions,
+		},
+	}
+}
+
+// evalToolParamRegex is tool_param_matches_regex with a single parameter,
+// given as Param and Pattern instead of a Params map.
+func (e *AssertionEvaluator) evalToolParamRegex(a Assertion) AssertionResult {
+	if a.Tool == "" || a.Param == "" || a.Pattern == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "tool_param_regex requires a 'tool', a 'param' and a 'pattern'",
+		}
+	}
+	a.Params = map[string]string{a.Param: a.Pattern}
+	return e.evalToolParamMatchesRegex(a)
+}
+
+// evalToolParamMatchesRegex matches the Params of calls to Tool against their
+// regex patterns. String values are matched as they are, other values as JSON.
+// With Match "any" (the default) one call matching every pattern passes; with
+// "all" every call must match.
+//
+// The details carry the values of each call. With a single parameter they are
+// plain strings next to "param" and "pattern"; with several they are maps
+// keyed by parameter next to "patterns".
+func (e *AssertionEvaluator) evalToolParamMatchesRegex(a Assertion) AssertionResult {
+	matchAll := false
+	switch a.Match {
+	case "", "any":
+	case "all":
+		matchAll = true
+	default:
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid match '%s' (must be 'any' or 'all')", a.Match),
+		}
+	}
+
+	details := map[string]interface{}{"tool": a.Tool}
+	single := ""
+	if len(a.Params) == 1 {
+		for key, pattern := range a.Params {
+			single = key
+			details["param"] = key
+			details["pattern"] = pattern
+		}
+	} else {
+		details["patterns"] = a.Params
+	}
+	// callValue returns what the details show for the values of one call
+	callValue := func(values map[string]string) interface{} {
+		if single != "" {
+			return values[single]
+		}
+		return values
+	}
+
+	var mismatchesAll [][]string
+	var callValues []interface{}
+
+	calls := 0
+
+	for _, tc := range e.result.ToolCalls {
+		if tc.Name != a.Tool {
+			continue
+		}
+		calls++
+		matches := true
+		mismatches := []string{}
+		values := map[string]string{}
+
+		for key, expectedPattern := range a.Params {
+			actualVal, exists := getNestedValue(tc.Parameters, key)
+			if !exists {
+				matches = false
+				mismatches = append(mismatches, fmt.Sprintf("missing param '%s'", key))
+				continue
+			}
+
+			// Convert expectedPattern to string and compile regex
+			patternStr := fmt.Sprintf("%v", expectedPattern)
+			re, err := regexp.Compile(patternStr)
+			if err != nil {
+				matches = false
+				mismatches = append(mismatches,
+					fmt.Sprintf("param '%s': invalid regex pattern '%s': %v", key, patternStr, err))
+				continue
+			}
+
+			// Strings are matched as they are, other values as JSON
+			strVal, ok := actualVal.(string)
+			if !ok {
+				strVal = jsonLiteral(actualVal)
+			}
+			values[key] = strVal
+			if !re.MatchString(strVal) {
+				matches = false
+				mismatches = append(mismatches,
+					fmt.Sprintf("param '%s': value '%s' does not match regex '%s'", key, strVal, patternStr))
+			}
+		}
+		callValues = append(callValues, callValue(values))
+
+		if matches && !matchAll {
+			// At least one call matches → assertion passed
+			details["value"] = callValue(values)
+			details["invocation"] = calls
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  true,
+				Message: fmt.Sprintf("Tool '%s' called with parameters matching regex patterns", a.Tool),
+				Details: details,
+			}
+		}
+
+		if !matches {
+			if matchAll {
+				for i := range mismatches {
+					mismatches[i] = fmt.Sprintf("call %d: %s", calls, mismatches[i])
+				}
+			}
+			mismatchesAll = append(mismatchesAll, mismatches)
+		}
+	}
+
+	if calls == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Tool '%s' was not called", a.Tool),
+			Details: details,
+		}
+	}
+	details["calls"] = calls
+	details["values"] = callValues
+
+	if matchAll && len(mismatchesAll) == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("All %d calls to tool '%s' have parameters matching regex patterns", calls, a.Tool),
+			Details: details,
+		}
+	}
+
+	// None (or, with match all, not every one) of the calls matched → collect all mismatches
+	allMismatches := []string{}
+	for _, mm := range mismatchesAll {
+		allMismatches = append(allMismatches, mm...)
+	}
+	details["mismatches"] = allMismatches
+
+	message := fmt.Sprintf("Tool '%s' called with parameters not matching regex: %v", a.Tool, allMismatches)
+	if matchAll {
+		message = fmt.Sprintf("%d of %d calls to tool '%s' have parameters not matching regex: %v", len(mismatchesAll), calls, a.Tool, allMismatches)
+	}
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  false,
+		Message: message,
+		Details: details,
+	}
+}
+
+// Output assertions
+func (e *AssertionEvaluator) evalOutputContains(a Assertion) AssertionResult {
+	needle := fmt.Sprintf("%v", a.Value)
+	contains := regexp.MustCompile(regexp.QuoteMeta(needle)).MatchString(e.result.FinalOutput)
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  contains,
+		Message: fmt.Sprintf("Output contains '%s': %v", needle, contains),
+	}
+}
+
+func (e *AssertionEvaluator) evalOutputNotContains(a Assertion) AssertionResult {
+	needle := fmt.Sprintf("%v", a.Value)
+	contains := regexp.MustCompile(regexp.QuoteMeta(needle)).MatchString(e.result.FinalOutput)
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  !contains,
+		Message: fmt.Sprintf("Output does not contain '%s': %v", needle, !contains),
+	}
+}
+
+// evalOutputOneOf checks that the final output contains at least one of the candidate values
+func (e *AssertionEvaluator) evalOutputOneOf(a Assertion) AssertionResult {
+	if len(a.Values) == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "output_one_of requires a non-empty 'values' list",
+		}
+	}
+
+	output := e.result.FinalOutput
+	if a.IgnoreCase {
+		output = strings.ToLower(output)
+	}
+
+	candidates := make([]string, len(a.Values))
+	for i, v := range a.Values {
+		candidates[i] = RenderTemplate(v, e.templateContext)
+		needle := candidates[i]
+		if strings.TrimSpace(needle) == "" {
+			// A variable that rendered empty would match any output
+			continue
+		}
+		if a.IgnoreCase {
+			needle = strings.ToLower(needle)
+		}
+		if strings.Contains(output, needle) {
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  true,
+				Message: fmt.Sprintf("Output contains '%s'", candidates[i]),
+				Details: map[string]interface{}{
+					"matched": candidates[i],
+				},
+			}
+		}
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  false,
+		Message: fmt.Sprintf("Output contains none of %d candidate values", len(candidates)),
+		Details: map[string]interface{}{
+			"candidates":  candidates,
+			"ignore_case": a.IgnoreCase,
+		},
+	}
+}
+
+func (e *AssertionEvaluator) evalLLMRubric(a Assertion) AssertionResult {
+	if a.Value == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "llm_rubric requires a rubric in 'value'",
+		}
+	}
+	if e.judges == nil || len(e.judges.Judges) == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "llm_rubric requires a judge: configure a top-level judge or judges",
+		}
+	}
+
+	ctx := e.judgeCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	passed, details, err := e.judges.Grade(ctx, a.Value, e.result.FinalOutput, a.Threshold)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Judge evaluation failed: %v", err),
+			Details: details,
+		}
+	}
+	message := "Judges rejected the output against the rubric"
+	if passed {
+		message = "Judges accepted the output against the rubric"
+	}
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  passed,
+		Message: fmt.Sprintf("%s (%d/%d passing votes, %s)", message, details["pass_votes"], details["votes"], details["aggregation"]),
+		Details: details,
+	}
+}
+
+func (e *AssertionEvaluator) evalOutputRegex(a Assertion) AssertionResult {
+	pattern := a.Pattern
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid regex: %s", err),
+		}
+	}
+
+	matches := re.MatchString(e.result.FinalOutput)
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  matches,
+		Message: fmt.Sprintf("Output matches regex '%s': %v", pattern, matches),
+	}
+}
+
+// evalOutputNotRegex passes when Pattern matches nowhere in the final output.
+// The first unexpected match is reported in the details.
+func (e *AssertionEvaluator) evalOutputNotRegex(a Assertion) AssertionResult {
+	if a.Pattern == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "output_not_regex requires a 'pattern'",
+		}
+	}
+	re, err := regexp.Compile(a.Pattern)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid regex '%s': %s", a.Pattern, err),
+		}
+	}
+
+	loc := re.FindStringIndex(e.result.FinalOutput)
+	if loc == nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("Output does not match regex '%s'", a.Pattern),
+		}
+	}
+	match := e.result.FinalOutput[loc[0]:loc[1]]
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  false,
+		Message: fmt.Sprintf("Output matches regex '%s': %q", a.Pattern, match),
+		Details: map[string]interface{}{
+			"pattern":  a.Pattern,
+			"match":    match,
+			"position": loc[0],
+		},
+	}
+}
+
+const (
+	// minLanguageDetectionLetters is the fewest letters output_language will try to classify
+	minLanguageDetectionLetters = 20
+	// shortLanguageDetectionLetters marks outputs short enough that the default confidence is relaxed
+	shortLanguageDetectionLetters = 100
+	shortLanguageConfidence       = 0.5
+)
+
+// mojibakeRegex matches UTF-8 text that was decoded as Latin-1/Windows-1252 somewhere
+// along the way, e.g. "Ã©" for "é" or "â€™" for "’".
+var mojibakeRegex = regexp.MustCompile(`Ã[\x{80}-\x{BF}]|â€|Â[\x{A0}-\x{BF}]`)
+
+// evalOutputLanguage checks the final output is clean UTF-8 in the language given by
+// Value (ISO 639-1 or 639-3 code). Threshold sets the minimum detection confidence.
+func (e *AssertionEvaluator) evalOutputLanguage(a Assertion) AssertionResult {
+	output := e.result.FinalOutput
+
+	if !utf8.ValidString(output) {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "Output is not valid UTF-8",
+		}
+	}
+	if strings.ContainsRune(output, utf8.RuneError) || mojibakeRegex.MatchString(output) {
+		sample := mojibakeRegex.FindString(output)
+		if sample == "" {
+			sample = string(utf8.RuneError)
+		}
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Output contains mis-encoded text: %q", sample),
+			Details: map[string]interface{}{
+				"sample": sample,
+			},
+		}
+	}
+
+	expected := strings.ToLower(strings.TrimSpace(a.Value))
+	if expected == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "output_language requires a value (ISO 639-1 or 639-3 language code)",
+		}
+	}
+
+	letters := 0
+	for _, r := range output {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLanguageDetectionLetters {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("Warning: output too short to detect language (%d letters) - assertion skipped", letters),
+			Details: map[string]interface{}{
+				"expected":  expected,
+				"too_short": true,
+				"letters":   letters,
+			},
+		}
+	}
+
+	minConfidence := whatlanggo.ReliableConfidenceThreshold
+	if a.Threshold > 0 {
+		minConfidence = a.Threshold
+	} else if letters < shortLanguageDetectionLetters {
+		minConfidence = shortLanguageConfidence
+	}
+
+	info := whatlanggo.Detect(output)
+	detected := info.Lang.Iso6391()
+	if detected == "" {
+		detected = info.Lang.Iso6393()
+	}
+	matches := expected == info.Lang.Iso6391() || expected == info.Lang.Iso6393()
+	passed := matches && info.Confidence >= minConfidence
+
+	details := map[string]interface{}{
+		"expected":       expected,
+		"detected":       detected,
+		"language":       info.Lang.String(),
+		"confidence":     info.Confidence,
+		"min_confidence": minConfidence,
+	}
+
+	var message string
+	switch {
+	case passed:
+		message = fmt.Sprintf("Output language is '%s' (confidence %.2f)", detected, info.Confidence)
+	case matches:
+		message = fmt.Sprintf("Output language '%s' detected with confidence %.2f, below %.2f", detected, info.Confidence, minConfidence)
+	default:
+		message = fmt.Sprintf("Output language is '%s' (confidence %.2f), expected '%s'", detected, info.Confidence, expected)
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  passed,
+		Message: message,
+		Details: details,
+	}
+}
+
+// iterationScaffoldingRegex matches the progress lines the agent adds to the
+// final output when intermediate responses are included.
+var iterationScaffoldingRegex = regexp.MustCompile(`(?m)^\[(Iteration \d+:[^\]]*\]|tool_usage \d+/\d+\]|tool_response\]).*$`)
+
+// evalHasFinalAnswer checks that the agent concluded with real content rather
+// than stopping after tool calls. Value optionally sets the minimum length.
+func (e *AssertionEvaluator) evalHasFinalAnswer(a Assertion) AssertionResult {
+	minLength := 1
+	if a.Value != "" {
+		n, err := strconv.Atoi(a.Value)
+		if err != nil || n < 1 {
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  false,
+				Message: fmt.Sprintf("Invalid minimum length: %s", a.Value),
+			}
+		}
+		minLength = n
+	}
+
+	answer := strings.TrimSpace(iterationScaffoldingRegex.ReplaceAllString(e.result.FinalOutput, ""))
+	length := utf8.RuneCountInString(answer)
+	details := map[string]interface{}{
+		"length":     length,
+		"min_length": minLength,
+	}
+
+	if length == 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "Agent stopped without a final answer",
+			Details: details,
+		}
+	}
+	if length < minLength {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Final answer too short: %d characters (min: %d)", length, minLength),
+			Details: details,
+		}
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: fmt.Sprintf("Final answer present (%d characters)", length),
+		Details: details,
+	}
+}
+
+// jsonFenceRegex matches a fenced ```json block wrapping structured output.
+var jsonFenceRegex = regexp.MustCompile("(?s)^```(?:json)?\\s*(.*?)\\s*```$")
+
+// finalOutputJSON returns the final output without iteration scaffolding and
+// an enclosing ```json fence, ready to be parsed as JSON.
+func (e *AssertionEvaluator) finalOutputJSON() string {
+	output := strings.TrimSpace(iterationScaffoldingRegex.ReplaceAllString(e.result.FinalOutput, ""))
+	if m := jsonFenceRegex.FindStringSubmatch(output); m != nil {
+		output = m[1]
+	}
+	return output
+}
+
+// evalOutputFieldEquals parses the final output as JSON and compares the value at
+// a.Path with a.Value. The expected value is read as a JSON literal when possible,
+// so "200" matches the number 200 and '"200"' matches the string; anything that is
+// not valid JSON is compared as a plain string.
+func (e *AssertionEvaluator) evalOutputFieldEquals(a Assertion) AssertionResult {
+	if a.Path == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "output_field_equals requires a 'path'",
+		}
+	}
+	path := a.Path
+	if !strings.HasPrefix(path, "$") {
+		path = "$." + path
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(e.finalOutputJSON()), &data); err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Final output is not valid JSON: %s", err),
+			Details: map[string]interface{}{
+				"path": a.Path,
+			},
+		}
+	}
+
+	actual, err := jsonpath.Read(data, path)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Path '%s' not found in final output: %s", a.Path, err),
+			Details: map[string]interface{}{
+				"path": a.Path,
+			},
+		}
+	}
+
+	expected := e.expectedJSONValue(a.Expected)
+
+	details := map[string]interface{}{
+		"path":     a.Path,
+		"actual":   actual,
+		"expected": expected,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Field '%s' is %s, expected %s", a.Path, jsonLiteral(actual), jsonLiteral(expected)),
+			Details: details,
+		}
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: fmt.Sprintf("Field '%s' equals %s", a.Path, jsonLiteral(expected)),
+		Details: details,
+	}
+}
+
+// expectedJSONValue converts the expected value of an assertion to the form
+// json.Unmarshal produces so it compares with decoded JSON. Strings are
+// template-expanded and read as JSON literals, so "200" is the number 200 and
+// '"200"' the string; strings that are not valid JSON stay as they are.
+func (e *AssertionEvaluator) expectedJSONValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		return decodeJSONValue(RenderTemplate(s, e.templateContext))
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return v
+	}
+	return decoded
+}
+
+// evalOutputJSONSchema validates the final output, parsed as JSON, against the
+// inline Schema or the schema in SchemaFile. Each violation is listed with the
+// JSON pointer of the offending value.
+func (e *AssertionEvaluator) evalOutputJSONSchema(a Assertion) AssertionResult {
+	if (a.Schema == nil) == (a.SchemaFile == "") {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "output_json_schema requires exactly one of 'schema' or 'schema_file'",
+		}
+	}
+
+	details := map[string]interface{}{}
+	var doc interface{}
+	var err error
+	// The schema is registered under its own location so relative $refs
+	// resolve next to the schema file (inline schemas: next to the test file)
+	location := e.testDirPath("inline-schema.json")
+	if a.SchemaFile != "" {
+		path := e.testDirPath(RenderTemplate(a.SchemaFile, e.templateContext))
+		details["schema_file"] = path
+		location = path
+		var f *os.File
+		if f, err = os.Open(path); err == nil {
+			doc, err = jsonschema.UnmarshalJSON(f)
+			f.Close()
+		}
+	} else {
+		// Round-trip through JSON so YAML integers become JSON numbers
+		var raw []byte
+		if raw, err = json.Marshal(a.Schema); err == nil {
+			doc, err = jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		}
+	}
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Failed to load JSON schema: %v", err),
+			Details: details,
+		}
+	}
+	if abs, err := filepath.Abs(location); err == nil {
+		location = abs
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(location, doc); err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid JSON schema: %v", err),
+			Details: details,
+		}
+	}
+	schema, err := compiler.Compile(location)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid JSON schema: %v", err),
+			Details: details,
+		}
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(strings.NewReader(e.finalOutputJSON()))
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Final output is not valid JSON: %s", err),
+			Details: details,
+		}
+	}
+
+	err = schema.Validate(instance)
+	if err == nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: "Final output matches the JSON schema",
+			Details: details,
+		}
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("JSON schema validation failed: %v", err),
+			Details: details,
+		}
+	}
+	violations := schemaViolations(validationErr, message.NewPrinter(language.English))
+	details["violations"] = violations
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  false,
+		Message: fmt.Sprintf("Final output does not match the JSON schema: %s", strings.Join(violations, "; ")),
+		Details: details,
+	}
+}
+
+// schemaViolations flattens a validation error into "<instance path>: <error>"
+// lines. anyOf and oneOf failures are reported once rather than per branch,
+// since no single branch was required.
+func schemaViolations(err *jsonschema.ValidationError, p *message.Printer) []string {
+	switch err.ErrorKind.(type) {
+	case *kind.AnyOf, *kind.OneOf:
+	default:
+		if len(err.Causes) > 0 {
+			var violations []string
+			for _, cause := range err.Causes {
+				violations = append(violations, schemaViolations(cause, p)...)
+			}
+			return violations
+		}
+	}
+	path := "/"
+	if len(err.InstanceLocation) > 0 {
+		escape := strings.NewReplacer("~", "~0", "/", "~1")
+		var b strings.Builder
+		for _, token := range err.InstanceLocation {
+			b.WriteString("/" + escape.Replace(token))
+		}
+		path = b.String()
+	}
+	return []string{fmt.Sprintf("%s: %s", path, err.ErrorKind.LocalizedString(p))}
+}
+
+// jsonLiteral renders a decoded JSON value the way it would appear in JSON, so
+// messages distinguish 200 from "200".
+func jsonLiteral(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Performance assertions
+func (e *AssertionEvaluator) evalMaxTokens(a Assertion) AssertionResult {
+	maxTokens, err := strconv.Atoi(a.Value)
+	passed := false
+	if err == nil {
+		passed = e.result.TokensUsed <= maxTokens
+	}
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  passed,
+		Message: fmt.Sprintf("Tokens used: %d (max: %d)", e.result.TokensUsed, maxTokens),
+		Details: map[string]interface{}{
+			"actual": e.result.TokensUsed,
+			"max":    maxTokens,
+		},
+	}
+}
 
-		if matches {
-			// At least one call matches → assertion passed
-			return AssertionResult{
-				Type:    a.Type,
-				Passed:  true,
-				Message: fmt.Sprintf("Tool '%s' called with parameters matching regex patterns", a.Tool),
-			}
+// evalMaxTokensUsed checks token usage against a budget. Field selects which count is
+// compared: "total" (default), "prompt" or "completion".
+func (e *AssertionEvaluator) evalMaxTokensUsed(a Assertion) AssertionResult {
+	budget, err := strconv.Atoi(a.Value)
+	if err != nil || budget < 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid token budget: %s", a.Value),
 		}
-
-		mismatchesAll = append(mismatchesAll, mismatches)
 	}
 
-	if !found {
+	field := a.Field
+	if field == "" {
+		field = "total"
+	}
+	var actual int
+	switch field {
+	case "total":
+		actual = e.result.TokensUsed
+	case "prompt":
+		actual = e.result.PromptTokens
+	case "completion":
+		actual = e.result.CompletionTokens
+	default:
 		return AssertionResult{
 			Type:    a.Type,
 			Passed:  false,
-			Message: fmt.Sprintf("Tool '%s' was not called", a.Tool),
+			Message: fmt.Sprintf("Invalid token field: %s (expected total, prompt or completion)", field),
 		}
 	}
 
-	// None of the calls matched → collect all mismatches
-	allMismatches := []string{}
-	for _, mm := range mismatchesAll {
-		allMismatches = append(allMismatches, mm...)
+	details := map[string]interface{}{
+		"field":             field,
+		"actual":            actual,
+		"budget":            budget,
+		"total_tokens":      e.result.TokensUsed,
+		"prompt_tokens":     e.result.PromptTokens,
+		"completion_tokens": e.result.CompletionTokens,
 	}
-
-	return AssertionResult{
-		Type:    a.Type,
-		Passed:  false,
-		Message: fmt.Sprintf("Tool '%s' called with parameters not matching regex: %v", a.Tool, allMismatches),
+	if e.result.TokensEstimated {
+		details["estimated"] = true
 	}
-}
-
-// Output assertions
-func (e *AssertionEvaluator) evalOutputContains(a Assertion) AssertionResult {
-	needle := fmt.Sprintf("%v", a.Value)
-	contains := regexp.MustCompile(regexp.QuoteMeta(needle)).MatchString(e.result.FinalOutput)
 
-	return AssertionResult{
-		Type:    a.Type,
-		Passed:  contains,
-		Message: fmt.Sprintf("Output contains '%s': %v", needle, contains),
+	// Providers without a usage breakdown only report a total
+	if field != "total" && e.result.PromptTokens == 0 && e.result.CompletionTokens == 0 && e.result.TokensUsed > 0 {
+		details["warning"] = "Provider did not report a prompt/completion token breakdown"
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("Warning: no %s token count reported - assertion skipped", field),
+			Details: details,
+		}
 	}
-}
-
-func (e *AssertionEvaluator) evalOutputNotContains(a Assertion) AssertionResult {
-	needle := fmt.Sprintf("%v", a.Value)
-	contains := regexp.MustCompile(regexp.QuoteMeta(needle)).MatchString(e.result.FinalOutput)
 
+	label := strings.ToUpper(field[:1]) + field[1:]
+	passed := actual <= budget
+	approx := ""
+	if e.result.TokensEstimated {
+		approx = "~"
+	}
+	message := fmt.Sprintf("%s tokens used: %s%d (budget: %d)", label, approx, actual, budget)
+	if !passed {
+		message = fmt.Sprintf("%s tokens used: %s%d exceeds budget %d by %d", label, approx, actual, budget, actual-budget)
+	}
 	return AssertionResult{
 		Type:    a.Type,
-		Passed:  !contains,
-		Message: fmt.Sprintf("Output does not contain '%s': %v", needle, !contains),
+		Passed:  passed,
+		Message: message,
+		Details: details,
 	}
 }
 
-func (e *AssertionEvaluator) evalOutputRegex(a Assertion) AssertionResult {
-	pattern := a.Pattern
-	re, err := regexp.Compile(pattern)
+// evalMaxAssistantMessages checks that the agent produced at most N assistant messages
+func (e *AssertionEvaluator) evalMaxAssistantMessages(a Assertion) AssertionResult {
+	maxMessages, err := strconv.Atoi(a.Value)
 	if err != nil {
 		return AssertionResult{
 			Type:    a.Type,
 			Passed:  false,
-			Message: fmt.Sprintf("Invalid regex: %s", err),
+			Message: fmt.Sprintf("Invalid max assistant messages value: %s", a.Value),
 		}
 	}
 
-	matches := re.MatchString(e.result.FinalOutput)
-	return AssertionResult{
-		Type:    a.Type,
-		Passed:  matches,
-		Message: fmt.Sprintf("Output matches regex '%s': %v", pattern, matches),
+	count := 0
+	for _, msg := range e.result.Messages {
+		if msg.Role == "assistant" {
+			count++
+		}
 	}
-}
 
-// Performance assertions
-func (e *AssertionEvaluator) evalMaxTokens(a Assertion) AssertionResult {
-	maxTokens, err := strconv.Atoi(a.Value)
-	passed := false
-	if err == nil {
-		passed = e.result.TokensUsed <= maxTokens
-	}
 	return AssertionResult{
 		Type:    a.Type,
-		Passed:  passed,
-		Message: fmt.Sprintf("Tokens used: %d (max: %d)", e.result.TokensUsed, maxTokens),
+		Passed:  count <= maxMessages,
+		Message: fmt.Sprintf("Assistant messages: %d (max: %d)", count, maxMessages),
 		Details: map[string]interface{}{
-			"actual": e.result.TokensUsed,
-			"max":    maxTokens,
+			"actual": count,
+			"max":    maxMessages,
 		},
 	}
 }
@@ -942,6 +3064,50 @@ func (e *AssertionEvaluator) evalMaxLatency(a Assertion) AssertionResult {
 	}
 }
 
+// evalFirstToolWithin checks how quickly the agent started acting: the time from
+// the start of the test to the earliest tool call must not exceed the limit.
+func (e *AssertionEvaluator) evalFirstToolWithin(a Assertion) AssertionResult {
+	maxMs, err := strconv.ParseInt(a.Value, 10, 64)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid first tool time limit: %s", a.Value),
+		}
+	}
+
+	var first *ToolCall
+	for i := range e.result.ToolCalls {
+		tc := &e.result.ToolCalls[i]
+		if first == nil || tc.Timestamp.Before(first.Timestamp) {
+			first = tc
+		}
+	}
+	if first == nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("No tool was ever called (limit: %dms)", maxMs),
+			Details: map[string]interface{}{
+				"max":        maxMs,
+				"tool_calls": 0,
+			},
+		}
+	}
+
+	elapsedMs := first.Timestamp.Sub(e.result.StartTime).Milliseconds()
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  elapsedMs <= maxMs,
+		Message: fmt.Sprintf("Time to first tool: %dms (max: %dms)", elapsedMs, maxMs),
+		Details: map[string]interface{}{
+			"time_to_first_tool_ms": elapsedMs,
+			"max":                   maxMs,
+			"first_tool":            first.Name,
+		},
+	}
+}
+
 func (e *AssertionEvaluator) evalNoHallucinatedTools(a Assertion) AssertionResult {
 	if e.result.ToolCalls != nil {
 		for i := range e.result.ToolCalls {
@@ -962,6 +3128,138 @@ func (e *AssertionEvaluator) evalNoHallucinatedTools(a Assertion) AssertionResul
 	}
 }
 
+// evalNoHallucinatedParams checks that every tool call only passed parameters declared
+// in the tool's schema. Calls to tools without a known schema are skipped
+// (use no_hallucinated_tools to catch unknown tools).
+func (e *AssertionEvaluator) evalNoHallucinatedParams(a Assertion) AssertionResult {
+	var offending []map[string]interface{}
+
+	for _, tc := range e.result.ToolCalls {
+		if a.Tool != "" && tc.Name != a.Tool {
+			continue
+		}
+		declared, known := e.toolParameters[tc.Name]
+		if !known {
+			continue
+		}
+
+		var unknown []string
+		for param := range tc.Parameters {
+			if !contains(declared, param) {
+				unknown = append(unknown, param)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			offending = append(offending, map[string]interface{}{
+				"tool":           tc.Name,
+				"unknown_params": unknown,
+			})
+		}
+	}
+
+	if len(offending) > 0 {
+		parts := make([]string, 0, len(offending))
+		for _, o := range offending {
+			parts = append(parts, fmt.Sprintf("%s(%s)", o["tool"], strings.Join(o["unknown_params"].([]string), ", ")))
+		}
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Has hallucinated parameters: %s", strings.Join(parts, "; ")),
+			Details: map[string]interface{}{
+				"violations": offending,
+			},
+		}
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: "No hallucinated parameters",
+	}
+}
+
+var unresolvedTemplateRegex = regexp.MustCompile(`\{\{[^}]*\}\}`)
+
+func (e *AssertionEvaluator) evalNoEmptyParams(a Assertion) AssertionResult {
+	var offending []map[string]interface{}
+
+	for _, tc := range e.result.ToolCalls {
+		if a.Tool != "" && tc.Name != a.Tool {
+			continue
+		}
+		names := make([]string, 0, len(tc.Parameters))
+		for name := range tc.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if reason := emptyParamReason(tc.Parameters[name]); reason != "" {
+				offending = append(offending, map[string]interface{}{
+					"tool":   tc.Name,
+					"param":  name,
+					"reason": reason,
+				})
+			}
+		}
+	}
+
+	if len(offending) > 0 {
+		parts := make([]string, 0, len(offending))
+		for _, o := range offending {
+			parts = append(parts, fmt.Sprintf("%s.%s (%s)", o["tool"], o["param"], o["reason"]))
+		}
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Has empty or placeholder parameters: %s", strings.Join(parts, "; ")),
+			Details: map[string]interface{}{
+				"violations": offending,
+			},
+		}
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: "No empty or placeholder parameters",
+	}
+}
+
+// emptyParamReason reports why a parameter value looks unset: an empty string or an
+// unresolved {{...}} template token, searched through nested objects and arrays.
+// It returns "" for a value that looks fine.
+func emptyParamReason(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "empty"
+		}
+		if unresolvedTemplateRegex.MatchString(v) {
+			return "unresolved template " + unresolvedTemplateRegex.FindString(v)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if reason := emptyParamReason(v[k]); reason != "" {
+				return reason
+			}
+		}
+	case []interface{}:
+		for _, nested := range v {
+			if reason := emptyParamReason(nested); reason != "" {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
 func (e *AssertionEvaluator) evalNoErrorMessages(a Assertion) AssertionResult {
 	hasErrors := len(e.result.Errors) > 0
 
@@ -975,53 +3273,205 @@ func (e *AssertionEvaluator) evalNoErrorMessages(a Assertion) AssertionResult {
 	}
 }
 
+// evalExpectedError passes when an execution error matches Pattern, for negative
+// tests whose correct outcome is a controlled failure. It fails when none of the
+// errors matches, and when no error occurred unless RequireError is false.
+func (e *AssertionEvaluator) evalExpectedError(a Assertion) AssertionResult {
+	if a.Pattern == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "expected_error requires a pattern",
+		}
+	}
+	re, err := regexp.Compile(a.Pattern)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Invalid regex: %s", err),
+		}
+	}
+
+	if len(e.result.Errors) == 0 {
+		if a.RequireError != nil && !*a.RequireError {
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  true,
+				Message: "No error occurred (require_error: false)",
+				Details: map[string]interface{}{
+					"pattern": a.Pattern,
+				},
+			}
+		}
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Expected an error matching '%s', but no error occurred", a.Pattern),
+			Details: map[string]interface{}{
+				"pattern": a.Pattern,
+			},
+		}
+	}
+
+	matched := make([]string, 0)
+	for _, msg := range e.result.Errors {
+		if re.MatchString(msg) {
+			matched = append(matched, msg)
+		}
+	}
+	details := map[string]interface{}{
+		"pattern": a.Pattern,
+		"matched": matched,
+	}
+	if len(matched) == 0 {
+		details["errors"] = e.result.Errors
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("None of %d error(s) matches '%s'", len(e.result.Errors), a.Pattern),
+			Details: details,
+		}
+	}
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: fmt.Sprintf("Expected error occurred: %s", matched[0]),
+		Details: details,
+	}
+}
+
 func (e *AssertionEvaluator) evalNoClarificationQuestions(a Assertion) AssertionResult {
 	// Check if clarification detection was enabled
 	if e.result.ClarificationStats == nil {
 		return AssertionResult{
 			Type:    a.Type,
 			Passed:  true,
-			Message: "Warning: clarification_detection not enabled on agent - assertion skipped",
+			Message: "Warning: clarification_detection not enabled on agent - assertion skipped",
+			Details: map[string]interface{}{
+				"warning": "Enable clarification_detection on the agent for this assertion to work",
+			},
+		}
+	}
+
+	// Check if clarification detection found any requests
+	if e.result.ClarificationStats.Count > 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Agent asked for clarification %d time(s)", e.result.ClarificationStats.Count),
+			Details: map[string]interface{}{
+				"count":      e.result.ClarificationStats.Count,
+				"iterations": e.result.ClarificationStats.Iterations,
+				"examples":   e.result.ClarificationStats.Examples,
+			},
+		}
+	}
+
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  true,
+		Message: "No clarification questions detected",
+	}
+}
+
+// evalConfirmsBeforeTool passes when the agent asked for confirmation before its
+// first call to a.Tool. Confirmation requests are the final answers classified
+// by clarification detection, so a request counts only when the agent stopped
+// and waited for the user in an earlier iteration than the call.
+func (e *AssertionEvaluator) evalConfirmsBeforeTool(a Assertion) AssertionResult {
+	if a.Tool == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "confirms_before_tool requires a 'tool'",
+		}
+	}
+	if e.result.ClarificationStats == nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "confirms_before_tool requires clarification_detection to be enabled on the agent",
+			Details: map[string]interface{}{
+				"tool": a.Tool,
+			},
+		}
+	}
+
+	callIdx := slices.IndexFunc(e.result.ToolCalls, func(tc ToolCall) bool { return tc.Name == a.Tool })
+	if callIdx < 0 {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("Tool '%s' was not called", a.Tool),
 			Details: map[string]interface{}{
-				"warning": "Enable clarification_detection on the agent for this assertion to work",
+				"tool": a.Tool,
 			},
 		}
 	}
+	call := e.result.ToolCalls[callIdx]
+	details := map[string]interface{}{
+		"tool":                a.Tool,
+		"tool_call_timestamp": call.Timestamp.Format(time.RFC3339Nano),
+		"tool_call_iteration": call.Iteration,
+	}
 
-	// Check if clarification detection found any requests
-	if e.result.ClarificationStats.Count > 0 {
+	// The last confirmation request before the call is the one it answered
+	var confirmation *Message
+	for i, msg := range e.result.Messages {
+		if msg.Role != "assistant" || msg.Iteration >= call.Iteration {
+			continue
+		}
+		if slices.Contains(e.result.ClarificationStats.Iterations, msg.Iteration) {
+			confirmation = &e.result.Messages[i]
+		}
+	}
+	if confirmation == nil {
+		details["confirmation_requests"] = e.result.ClarificationStats.Count
 		return AssertionResult{
 			Type:    a.Type,
 			Passed:  false,
-			Message: fmt.Sprintf("Agent asked for clarification %d time(s)", e.result.ClarificationStats.Count),
-			Details: map[string]interface{}{
-				"count":      e.result.ClarificationStats.Count,
-				"iterations": e.result.ClarificationStats.Iterations,
-				"examples":   e.result.ClarificationStats.Examples,
-			},
+			Message: fmt.Sprintf("Agent called '%s' without asking for confirmation first", a.Tool),
+			Details: details,
 		}
 	}
 
+	details["confirming_message"] = truncateString(confirmation.Content, 500)
+	details["confirming_message_timestamp"] = confirmation.Timestamp.Format(time.RFC3339Nano)
 	return AssertionResult{
 		Type:    a.Type,
 		Passed:  true,
-		Message: "No clarification questions detected",
+		Message: fmt.Sprintf("Agent asked for confirmation before calling '%s'", a.Tool),
+		Details: details,
 	}
 }
 
 func (e *AssertionEvaluator) evalNoRateLimitErrors(a Assertion) AssertionResult {
-	// Check if rate limit stats exist and have any 429 errors
-	if e.result.RateLimitStats != nil && e.result.RateLimitStats.RateLimitHits > 0 {
+	// Rely on the 429 counter and structured error kinds rather than error wording.
+	// Rate limit errors that surfaced to the agent were usually also counted as hits
+	// by the rate limiter, so the larger of the two is reported.
+	stats := RateLimitStats{}
+	if e.result.RateLimitStats != nil {
+		stats = *e.result.RateLimitStats
+	}
+	rateLimitErrors := e.result.CountErrorKind(ErrorKindRateLimit)
+	hits := max(stats.RateLimitHits, rateLimitErrors)
+
+	details := map[string]interface{}{
+		"rate_limit_hits":    stats.RateLimitHits,
+		"rate_limit_errors":  rateLimitErrors,
+		"retry_count":        stats.RetryCount,
+		"retry_wait_time_ms": stats.RetryWaitTimeMs,
+		"retry_success":      stats.RetrySuccessCount,
+		"retry_backoffs_ms":  stats.RetryBackoffsMs,
+	}
+
+	if hits > 0 {
 		return AssertionResult{
 			Type:    a.Type,
 			Passed:  false,
-			Message: fmt.Sprintf("Received %d rate limit error(s) (HTTP 429)", e.result.RateLimitStats.RateLimitHits),
-			Details: map[string]interface{}{
-				"rate_limit_hits":    e.result.RateLimitStats.RateLimitHits,
-				"retry_count":        e.result.RateLimitStats.RetryCount,
-				"retry_wait_time_ms": e.result.RateLimitStats.RetryWaitTimeMs,
-				"retry_success":      e.result.RateLimitStats.RetrySuccessCount,
-			},
+			Message: fmt.Sprintf("Received %d rate limit error(s) (HTTP 429)", hits),
+			Details: details,
 		}
 	}
 
@@ -1029,6 +3479,7 @@ func (e *AssertionEvaluator) evalNoRateLimitErrors(a Assertion) AssertionResult
 		Type:    a.Type,
 		Passed:  true,
 		Message: "No rate limit errors (HTTP 429)",
+		Details: details,
 	}
 }
 
@@ -1077,7 +3528,22 @@ func (e *AssertionEvaluator) findCLIToolCall(toolName string) (*ToolCall, *CLIRe
 // Supports both 'expected: 0' (int) and 'value: "0"' (string) formats
 func (e *AssertionEvaluator) evalCLIExitCodeEquals(a Assertion) AssertionResult {
 	// Use Expected field if set, otherwise parse Value for backwards compatibility
-	expectedCode := a.Expected
+	expectedCode := 0
+	switch v := a.Expected.(type) {
+	case nil:
+	case int:
+		expectedCode = v
+	default:
+		parsed, err := strconv.Atoi(fmt.Sprint(v))
+		if err != nil {
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  false,
+				Message: fmt.Sprintf("Invalid expected exit code value: %v", v),
+			}
+		}
+		expectedCode = parsed
+	}
 	if a.Value != "" {
 		parsed, err := strconv.Atoi(a.Value)
 		if err != nil {
@@ -1236,6 +3702,351 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
+// ============================================================================
+// GOLDEN TRANSCRIPT ASSERTION
+// ============================================================================
+
+// GoldenWriter writes the golden files of one -update-golden run. Tests run
+// concurrently, so writes are serialized, and a file written twice in the run
+// with different transcripts, typically by several agents sharing one path,
+// is an error instead of the last write silently winning.
+type GoldenWriter struct {
+	mu      sync.Mutex
+	written map[string]goldenWrite
+}
+
+type goldenWrite struct {
+	data  []byte
+	owner string
+}
+
+// NewGoldenWriter returns a writer for one run.
+func NewGoldenWriter() *GoldenWriter {
+	return &GoldenWriter{written: make(map[string]goldenWrite)}
+}
+
+// Write writes data to path, creating directories as needed. owner (the agent)
+// names the first writer when a later write of the run conflicts with it.
+func (w *GoldenWriter) Write(path string, data []byte, owner string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if previous, ok := w.written[path]; ok {
+		if bytes.Equal(previous.data, data) {
+			return nil
+		}
+		by := ""
+		if previous.owner != "" {
+			by = fmt.Sprintf(" by agent '%s'", previous.owner)
+		}
+		return fmt.Errorf("already written in this run%s with a different transcript; include {{AGENT_NAME}} in the path so each agent has its own golden", by)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	w.written[path] = goldenWrite{data: data, owner: owner}
+	return nil
+}
+
+// GoldenTranscript is the normalized tool-call sequence and final output that
+// matches_golden compares a run against.
+type GoldenTranscript struct {
+	ToolCalls   []GoldenToolCall `json:"toolCalls"`
+	FinalOutput string           `json:"finalOutput"`
+}
+
+// GoldenToolCall is a tool call as recorded in a golden transcript.
+type GoldenToolCall struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// goldenTimestampRegex matches ISO-8601 date-times, masked so that runs at
+// different times compare equal.
+var goldenTimestampRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?`)
+
+const goldenTimestampMask = "<timestamp>"
+
+// NewGoldenTranscript normalizes an execution into a golden transcript. Only tool
+// names, parameters and the final output are kept; timestamps inside them are
+// masked and surrounding whitespace is trimmed from the output.
+func NewGoldenTranscript(result *ExecutionResult) GoldenTranscript {
+	golden := GoldenTranscript{
+		ToolCalls:   make([]GoldenToolCall, 0, len(result.ToolCalls)),
+		FinalOutput: normalizeGoldenText(result.FinalOutput),
+	}
+	for _, tc := range result.ToolCalls {
+		golden.ToolCalls = append(golden.ToolCalls, GoldenToolCall{
+			Name:       tc.Name,
+			Parameters: normalizeGoldenParams(tc.Parameters),
+		})
+	}
+	return golden
+}
+
+// normalize applies the golden normalization to a transcript read from disk, so
+// hand-edited goldens compare the same way as recorded ones.
+func (g GoldenTranscript) normalize() GoldenTranscript {
+	normalized := GoldenTranscript{
+		ToolCalls:   make([]GoldenToolCall, 0, len(g.ToolCalls)),
+		FinalOutput: normalizeGoldenText(g.FinalOutput),
+	}
+	for _, tc := range g.ToolCalls {
+		normalized.ToolCalls = append(normalized.ToolCalls, GoldenToolCall{
+			Name:       tc.Name,
+			Parameters: normalizeGoldenParams(tc.Parameters),
+		})
+	}
+	return normalized
+}
+
+// marshal encodes the transcript as indented JSON without HTML escaping, so golden
+// files stay readable in review.
+func (g GoldenTranscript) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func normalizeGoldenText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return goldenTimestampRegex.ReplaceAllString(strings.TrimSpace(text), goldenTimestampMask)
+}
+
+// normalizeGoldenParams round-trips params through JSON so numbers and nested
+// values have one representation, then masks timestamps in string values.
+func normalizeGoldenParams(params map[string]interface{}) map[string]interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return params
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return params
+	}
+	return maskGoldenTimestamps(normalized).(map[string]interface{})
+}
+
+func maskGoldenTimestamps(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return goldenTimestampRegex.ReplaceAllString(val, goldenTimestampMask)
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = maskGoldenTimestamps(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = maskGoldenTimestamps(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// testDirPath resolves a file named by an assertion; relative paths are taken
+// from TEST_DIR.
+func (e *AssertionEvaluator) testDirPath(value string) string {
+	if filepath.IsAbs(value) {
+		return value
+	}
+	if testDir := e.templateContext["TEST_DIR"]; testDir != "" {
+		return filepath.Join(testDir, value)
+	}
+	return value
+}
+
+// evalMatchesGolden compares the normalized tool calls and final output with the
+// golden transcript at a.Value, or rewrites the golden when updating goldens.
+func (e *AssertionEvaluator) evalMatchesGolden(a Assertion) AssertionResult {
+	if a.Value == "" {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: "matches_golden requires 'value' with the golden file path",
+		}
+	}
+	path := e.testDirPath(a.Value)
+	actual := NewGoldenTranscript(e.result)
+	actualJSON, err := actual.marshal()
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Failed to encode transcript: %v", err),
+		}
+	}
+
+	if e.golden != nil {
+		if err := e.golden.Write(path, actualJSON, e.templateContext["AGENT_NAME"]); err != nil {
+			return AssertionResult{
+				Type:    a.Type,
+				Passed:  false,
+				Message: fmt.Sprintf("Failed to update golden file %s: %v", path, err),
+			}
+		}
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("Golden file updated: %s", path),
+			Details: map[string]interface{}{
+				"golden":     path,
+				"updated":    true,
+				"tool_calls": len(actual.ToolCalls),
+			},
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Failed to read golden file %s (record it with -update-golden): %v", path, err),
+		}
+	}
+	var golden GoldenTranscript
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Failed to parse golden file %s: %v", path, err),
+		}
+	}
+	expected := golden.normalize()
+	expectedJSON, err := expected.marshal()
+	if err != nil {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  false,
+			Message: fmt.Sprintf("Failed to encode golden transcript: %v", err),
+		}
+	}
+
+	if string(expectedJSON) == string(actualJSON) {
+		return AssertionResult{
+			Type:    a.Type,
+			Passed:  true,
+			Message: fmt.Sprintf("Transcript matches golden file %s", a.Value),
+			Details: map[string]interface{}{
+				"golden":     path,
+				"tool_calls": len(actual.ToolCalls),
+			},
+		}
+	}
+
+	var differs []string
+	if !reflect.DeepEqual(expected.ToolCalls, actual.ToolCalls) {
+		differs = append(differs, "tool calls")
+	}
+	if expected.FinalOutput != actual.FinalOutput {
+		differs = append(differs, "final output")
+	}
+	return AssertionResult{
+		Type:    a.Type,
+		Passed:  false,
+		Message: fmt.Sprintf("Transcript differs from golden file %s (%s)", a.Value, strings.Join(differs, ", ")),
+		Details: map[string]interface{}{
+			"golden":              path,
+			"diff":                lineDiff(string(expectedJSON), string(actualJSON)),
+			"tool_calls_expected": len(expected.ToolCalls),
+			"tool_calls_actual":   len(actual.ToolCalls),
+		},
+	}
+}
+
+// maxDiffCells bounds the line-diff table; larger inputs fall back to a plain
+// expected/actual listing.
+const maxDiffCells = 4_000_000
+
+// lineDiff returns a line diff of expected against actual: removed lines start
+// with "- ", added lines with "+ " and unchanged lines near a change with "  ".
+func lineDiff(expected, actual string) string {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+	if len(a)*len(b) > maxDiffCells {
+		return "--- expected\n" + expected + "\n+++ actual\n" + actual
+	}
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte
+		text string
+	}
+	lines := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		default:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		}
+	}
+
+	// Keep two lines of context around each change
+	const context = 2
+	keep := make([]bool, len(lines))
+	for idx, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for k := max(0, idx-context); k <= min(len(lines)-1, idx+context); k++ {
+			keep[k] = true
+		}
+	}
+	var sb strings.Builder
+	skipped := false
+	for idx, l := range lines {
+		if !keep[idx] {
+			skipped = true
+			continue
+		}
+		if skipped && sb.Len() > 0 {
+			sb.WriteString("  ...\n")
+		}
+		skipped = false
+		sb.WriteByte(l.op)
+		sb.WriteByte(' ')
+		sb.WriteString(l.text)
+		sb.WriteByte('\n')
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
 // ============================================================================
 // BOOLEAN COMBINATOR FUNCTIONS (anyOf, allOf, not)
 // ============================================================================
@@ -1466,15 +4277,25 @@ func ParseTestConfig(filename string) (*TestConfiguration, error) {
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
 
+	if err := suite.applyIncludes(filepath.Dir(filename), includeChain(filename)); err != nil {
+		return nil, err
+	}
+
 	return &suite, nil
 }
 
+// ParseTestConfigFromString parses a test configuration; include paths resolve
+// relative to the working directory.
 func ParseTestConfigFromString(definition string) (*TestConfiguration, error) {
 	var config TestConfiguration
 	if err := yaml.Unmarshal([]byte(definition), &config); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
 
+	if err := config.applyIncludes(".", nil); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
@@ -1489,18 +4310,150 @@ func ParseSuiteConfig(filename string) (*TestSuiteConfiguration, error) {
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
 
+	if err := suite.applyIncludes(filepath.Dir(filename), includeChain(filename)); err != nil {
+		return nil, err
+	}
+
 	return &suite, nil
 }
 
+// ParseTestSuiteConfigFromString parses a suite configuration; include paths
+// resolve relative to the working directory.
 func ParseTestSuiteConfigFromString(definition string) (*TestSuiteConfiguration, error) {
 	var config TestSuiteConfiguration
 	if err := yaml.Unmarshal([]byte(definition), &config); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
 
+	if err := config.applyIncludes(".", nil); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// IncludedConfig is the part of a config file that an include directive merges
+// into the including file. Included files may include further files.
+type IncludedConfig struct {
+	Include   []string          `yaml:"include,omitempty"`
+	Providers []Provider        `yaml:"providers"`
+	Servers   []Server          `yaml:"servers"`
+	Agents    []Agent           `yaml:"agents"`
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+func (c *TestConfiguration) applyIncludes(baseDir string, chain []string) error {
+	if len(c.Include) == 0 {
+		return nil
+	}
+	included, err := loadIncludes(baseDir, c.Include, chain)
+	if err != nil {
+		return err
+	}
+	merged := included.overlay(IncludedConfig{Providers: c.Providers, Servers: c.Servers, Agents: c.Agents, Variables: c.Variables})
+	c.Providers, c.Servers, c.Agents, c.Variables = merged.Providers, merged.Servers, merged.Agents, merged.Variables
+	return nil
+}
+
+func (c *TestSuiteConfiguration) applyIncludes(baseDir string, chain []string) error {
+	if len(c.Include) == 0 {
+		return nil
+	}
+	included, err := loadIncludes(baseDir, c.Include, chain)
+	if err != nil {
+		return err
+	}
+	merged := included.overlay(IncludedConfig{Providers: c.Providers, Servers: c.Servers, Agents: c.Agents, Variables: c.Variables})
+	c.Providers, c.Servers, c.Agents, c.Variables = merged.Providers, merged.Servers, merged.Agents, merged.Variables
+	return nil
+}
+
+// includeChain starts the include chain used to detect cycles at filename.
+func includeChain(filename string) []string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		return []string{abs}
+	}
+	return []string{filename}
+}
+
+// loadIncludes reads the include files in order, each resolved relative to
+// baseDir, and merges their definitions; a later file overrides an earlier one.
+// chain holds the files currently being included and catches circular includes.
+func loadIncludes(baseDir string, includes []string, chain []string) (IncludedConfig, error) {
+	var merged IncludedConfig
+	for _, include := range includes {
+		path := include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if slices.Contains(chain, path) {
+			return IncludedConfig{}, fmt.Errorf("circular include: %s", strings.Join(append(slices.Clone(chain), path), " -> "))
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return IncludedConfig{}, fmt.Errorf("failed to read include %q: %w", include, err)
+		}
+		var cfg IncludedConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return IncludedConfig{}, fmt.Errorf("failed to parse include %q: %w", include, err)
+		}
+
+		// The included file's own definitions override what it includes
+		if len(cfg.Include) > 0 {
+			nested, err := loadIncludes(filepath.Dir(path), cfg.Include, append(slices.Clone(chain), path))
+			if err != nil {
+				return IncludedConfig{}, err
+			}
+			cfg = nested.overlay(cfg)
+		}
+
+		merged = merged.overlay(cfg)
+	}
+	return merged, nil
+}
+
+// overlay returns c with the definitions in o added, replacing those of the same name.
+func (c IncludedConfig) overlay(o IncludedConfig) IncludedConfig {
+	return IncludedConfig{
+		Providers: mergeNamed(c.Providers, o.Providers, func(p Provider) string { return p.Name }),
+		Servers:   mergeNamed(c.Servers, o.Servers, func(s Server) string { return s.Name }),
+		Agents:    mergeNamed(c.Agents, o.Agents, func(a Agent) string { return a.Name }),
+		Variables: mergeVariables(c.Variables, o.Variables),
+	}
+}
+
+// mergeNamed returns base with every override replacing the entry of the same
+// name in place; overrides with new names are appended.
+func mergeNamed[T any](base, overrides []T, name func(T) string) []T {
+	if len(base) == 0 {
+		return overrides
+	}
+	merged := slices.Clone(base)
+	for _, o := range overrides {
+		idx := slices.IndexFunc(merged, func(b T) bool { return name(b) == name(o) })
+		if idx >= 0 {
+			merged[idx] = o
+		} else {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// mergeVariables returns base overlaid with overrides.
+func mergeVariables(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 {
+		return overrides
+	}
+	merged := maps.Clone(base)
+	maps.Copy(merged, overrides)
+	return merged
+}
+
 // ProviderOnlyConfig is a minimal config file containing just provider definitions
 // Used for regenerating AI summaries without needing a full test configuration
 type ProviderOnlyConfig struct {
@@ -1546,39 +4499,226 @@ type TestComparison struct {
 	FailedRuns    int                         `json:"failedRuns"`
 }
 
-// AgentStats holds aggregated statistics for an agent
-type AgentStats struct {
-	AgentName     string
-	Provider      ProviderType
-	TotalTests    int
-	PassedTests   int
-	FailedTests   int
-	TotalTokens   int
-	AvgTokens     int
-	TotalDuration float64
-	AvgDuration   float64
+// AgentStats holds aggregated statistics for an agent
+type AgentStats struct {
+	AgentName      string       `json:"agentName"`
+	Provider       ProviderType `json:"provider"`
+	TotalTests     int          `json:"totalTests"`
+	PassedTests    int          `json:"passedTests"`
+	FailedTests    int          `json:"failedTests"`
+	TotalTokens    int          `json:"totalTokens"`
+	AvgTokens      int          `json:"avgTokens"`
+	TotalDuration  float64      `json:"totalDuration"`
+	AvgDuration    float64      `json:"avgDuration"`
+	TotalToolCalls int          `json:"totalToolCalls"`
+	AvgToolCalls   float64      `json:"avgToolCalls"`  // Tool calls per test
+	TotalToolTime  float64      `json:"totalToolTime"` // Sum of tool call durations in seconds
+	// TotalAssertionTime is the sum of assertion evaluation durations in seconds
+	TotalAssertionTime float64 `json:"totalAssertionTime"`
+	// Latency percentiles of the agent's tests in milliseconds
+	P50LatencyMs float64 `json:"p50LatencyMs"`
+	P90LatencyMs float64 `json:"p90LatencyMs"`
+	P95LatencyMs float64 `json:"p95LatencyMs"`
+}
+type ReportGenerator struct {
+	TestFile    string       // Path to the original test configuration file
+	RunMetadata *RunMetadata // Reproducibility metadata (optional)
+}
+
+// RunMetadata describes how a run was produced so that two runs can be diffed.
+// It never contains provider tokens or secrets.
+type RunMetadata struct {
+	Version   string             `json:"version"`
+	Commit    string             `json:"commit"`
+	BuildDate string             `json:"buildDate"`
+	RunID     string             `json:"runId"`
+	Seed      int64              `json:"seed,omitempty"` // -seed of the run; re-pass it to reproduce random template values
+	StartTime time.Time          `json:"startTime"`
+	EndTime   time.Time          `json:"endTime"`
+	OS        string             `json:"os"`
+	Arch      string             `json:"arch"`
+	Providers []ProviderMetadata `json:"providers,omitempty"`
+	Judge     *ProviderMetadata  `json:"judge,omitempty"`
+	Judges    []ProviderMetadata `json:"judges,omitempty"`
+	// Interrupted is set when the run was stopped by a signal and the report
+	// only holds the tests completed before it.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Truncated is set when -max-duration ended the run early; tests it left
+	// unrun are reported as skipped with a "not executed (deadline)" reason.
+	Truncated bool `json:"truncated,omitempty"`
+	// AssertionDurationMs is the time all tests spent evaluating assertions.
+	AssertionDurationMs int64 `json:"assertionDurationMs,omitempty"`
+}
+
+// ProviderMetadata holds the resolved, non-secret parameters of a provider
+type ProviderMetadata struct {
+	Name        string       `json:"name"`
+	Type        ProviderType `json:"type"`
+	Model       string       `json:"model"`
+	BaseURL     string       `json:"baseUrl,omitempty"`
+	Version     string       `json:"version,omitempty"`
+	Location    string       `json:"location,omitempty"`
+	AuthType    string       `json:"authType,omitempty"`
+	Temperature *float64     `json:"temperature,omitempty"`
+	Seed        *int         `json:"seed,omitempty"`
+	Tokenizer   string       `json:"tokenizer,omitempty"` // Local tokenizer used for usage estimates and rate-limit budgeting
+}
+
+func NewReportGenerator() *ReportGenerator {
+	return &ReportGenerator{}
+}
+
+// TestRun combines execution result with evaluated assertions
+type TestRun struct {
+	Execution    *ExecutionResult  `json:"execution"`
+	Assertions   []AssertionResult `json:"assertions"`
+	Passed       bool              `json:"passed"`
+	Skipped      bool              `json:"skipped"`
+	SkipReason   string            `json:"skipReason,omitempty"`
+	TestCriteria Criteria          `json:"testCriteria"`
+	Weight       float64           `json:"weight,omitempty"`
+	Pairwise     []PairwiseOutcome `json:"pairwise,omitempty"` // Head-to-head results against other agents' runs of the test
+}
+
+// EffectiveWeight returns the run's weight. Runs loaded from reports written
+// before weights existed count as DefaultTestWeight.
+func (r TestRun) EffectiveWeight() float64 {
+	if r.Weight <= 0 {
+		return DefaultTestWeight
+	}
+	return r.Weight
+}
+
+// WeightedPassRate returns the share (0-1) of the executed runs' total weight
+// that passed. Skipped runs are ignored; with no executed runs the rate is 0.
+func WeightedPassRate(results []TestRun) float64 {
+	total := 0.0
+	passed := 0.0
+	for _, r := range ExecutedRuns(results) {
+		w := r.EffectiveWeight()
+		total += w
+		if r.Passed {
+			passed += w
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return passed / total
+}
+
+// HasCustomWeights reports whether any run carries a weight other than the default,
+// in which case the weighted pass rate differs from the raw one and is worth showing.
+func HasCustomWeights(results []TestRun) bool {
+	for _, r := range results {
+		if r.EffectiveWeight() != DefaultTestWeight {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecutedRuns returns the runs that were not skipped. Skipped runs neither
+// pass nor fail, so pass-rate math works on this subset.
+func ExecutedRuns(results []TestRun) []TestRun {
+	executed := make([]TestRun, 0, len(results))
+	for _, r := range results {
+		if !r.Skipped {
+			executed = append(executed, r)
+		}
+	}
+	return executed
 }
-type ReportGenerator struct {
-	TestFile string // Path to the original test configuration file
+
+// Elo parameters of pairwise standings: every agent starts at PairwiseEloBase
+// and each comparison moves the ratings by up to PairwiseEloK points.
+const (
+	PairwiseEloBase = 1000.0
+	PairwiseEloK    = 32.0
+)
+
+// PairwiseStanding is an agent's record over all of its head-to-head comparisons.
+type PairwiseStanding struct {
+	Agent  string  `json:"agent"`
+	Wins   int     `json:"wins"`
+	Losses int     `json:"losses"`
+	Ties   int     `json:"ties"`
+	Elo    float64 `json:"elo"`
 }
 
-func NewReportGenerator() *ReportGenerator {
-	return &ReportGenerator{}
+// Games returns the number of comparisons the agent took part in.
+func (s PairwiseStanding) Games() int {
+	return s.Wins + s.Losses + s.Ties
 }
 
-// TestRun combines execution result with evaluated assertions
-type TestRun struct {
-	Execution    *ExecutionResult  `json:"execution"`
-	Assertions   []AssertionResult `json:"assertions"`
-	Passed       bool              `json:"passed"`
-	TestCriteria Criteria          `json:"testCriteria"`
+// WinRate returns the share of comparisons won, counting a tie as half a win.
+func (s PairwiseStanding) WinRate() float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	return (float64(s.Wins) + float64(s.Ties)/2) / float64(s.Games())
+}
+
+// PairwiseStandings ranks the agents by the head-to-head results recorded on
+// the runs, highest Elo rating first. Each comparison is recorded on both runs;
+// ratings are updated once per comparison, in the order of the runs.
+func PairwiseStandings(results []TestRun) []PairwiseStanding {
+	standings := make(map[string]*PairwiseStanding)
+	standing := func(agent string) *PairwiseStanding {
+		if standings[agent] == nil {
+			standings[agent] = &PairwiseStanding{Agent: agent, Elo: PairwiseEloBase}
+		}
+		return standings[agent]
+	}
+
+	for _, run := range results {
+		if run.Execution == nil {
+			continue
+		}
+		for _, outcome := range run.Pairwise {
+			self := standing(run.Execution.AgentName)
+			score := 0.5
+			switch outcome.Result {
+			case PairwiseWin:
+				self.Wins++
+				score = 1
+			case PairwiseLoss:
+				self.Losses++
+				score = 0
+			default:
+				self.Ties++
+			}
+
+			// The opponent's run records the same comparison
+			if run.Execution.AgentName > outcome.Opponent {
+				continue
+			}
+			other := standing(outcome.Opponent)
+			expected := 1 / (1 + math.Pow(10, (other.Elo-self.Elo)/400))
+			delta := PairwiseEloK * (score - expected)
+			self.Elo += delta
+			other.Elo -= delta
+		}
+	}
+
+	ranked := make([]PairwiseStanding, 0, len(standings))
+	for _, s := range standings {
+		ranked = append(ranked, *s)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Elo != ranked[j].Elo {
+			return ranked[i].Elo > ranked[j].Elo
+		}
+		return ranked[i].Agent < ranked[j].Agent
+	})
+	return ranked
 }
 
 // GenerateComparisonSummary generates a comparison report across servers
 func (rg *ReportGenerator) GenerateComparisonSummary(results []TestRun) map[string]TestComparison {
 	comparisons := make(map[string]TestComparison)
 
-	for _, run := range results {
+	for _, run := range ExecutedRuns(results) {
 		testName := run.Execution.TestName
 
 		if _, exists := comparisons[testName]; !exists {
@@ -1618,61 +4758,152 @@ func (rg *ReportGenerator) GenerateComparisonSummary(results []TestRun) map[stri
 	return comparisons
 }
 
+// plainConsole switches console output to ASCII without ANSI colors (-no-color).
+var plainConsole bool
+
+// SetPlainConsole selects plain ASCII console output for CI log viewers and
+// consoles that mangle emoji, box drawing and color codes. HTML reports are unaffected.
+func SetPlainConsole(plain bool) {
+	plainConsole = plain
+}
+
+// PlainConsole reports whether console output is plain ASCII.
+func PlainConsole() bool {
+	return plainConsole
+}
+
+// DetectPlainConsole reports whether output to f should be plain: the NO_COLOR
+// convention is set (https://no-color.org) or f is not a terminal.
+func DetectPlainConsole(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// consoleGlyphs holds the symbols used by the console report
+type consoleGlyphs struct {
+	Pass, Fail, Warn, Skip, Bullet, Test string
+	Rule, Branch                         string
+	BoxTop, BoxSep, BoxBottom, BoxSide   string
+}
+
+var (
+	unicodeGlyphs = consoleGlyphs{
+		Pass: "✓", Fail: "✗", Warn: "⚠", Skip: "⊘", Bullet: "•", Test: "📋 Test",
+		Rule:      "═══════════════════════════════════════════════════════════════",
+		Branch:    "└─",
+		BoxTop:    "┌─────────────────────────────────────────────────────────────┐",
+		BoxSep:    "├─────────────────────────────────────────────────────────────┤",
+		BoxBottom: "└─────────────────────────────────────────────────────────────┘",
+		BoxSide:   "│",
+	}
+	asciiGlyphs = consoleGlyphs{
+		Pass: "PASS", Fail: "FAIL", Warn: "WARN", Skip: "SKIP", Bullet: "-", Test: "Test",
+		Rule:      "===============================================================",
+		Branch:    "`-",
+		BoxTop:    "+-------------------------------------------------------------+",
+		BoxSep:    "+-------------------------------------------------------------+",
+		BoxBottom: "+-------------------------------------------------------------+",
+		BoxSide:   "|",
+	}
+)
+
+func glyphs() consoleGlyphs {
+	if plainConsole {
+		return asciiGlyphs
+	}
+	return unicodeGlyphs
+}
+
+// Console colors; colorize drops them in plain mode
+const (
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorRed    = "\033[31m"
+	colorGray   = "\033[90m"
+	colorReset  = "\033[0m"
+)
+
+func colorize(color, text string) string {
+	if plainConsole {
+		return text
+	}
+	return color + text + colorReset
+}
+
 // printComparisonSummary prints the comparison summary section
 func (rg *ReportGenerator) printComparisonSummary(results []TestRun) {
 	comparisons := rg.GenerateComparisonSummary(results)
+	g := glyphs()
 
-	fmt.Println("\n" + "═══════════════════════════════════════════════════════════════")
+	fmt.Println("\n" + g.Rule)
 	fmt.Println("                    SERVER COMPARISON SUMMARY")
-	fmt.Println("═══════════════════════════════════════════════════════════════")
+	fmt.Println(g.Rule)
 	fmt.Println()
 
 	for testName, comp := range comparisons {
 		successRate := float64(comp.PassedRuns) / float64(comp.TotalRuns) * 100
-		rateColor := "\033[32m" // green
+		rateColor := colorGreen
 		if successRate < 100 && successRate >= 50 {
-			rateColor = "\033[33m" // yellow
+			rateColor = colorYellow
 		} else if successRate < 50 {
-			rateColor = "\033[31m" // red
+			rateColor = colorRed
 		}
 
-		fmt.Printf("📋 Test: %s ", testName)
-		fmt.Printf("%s[%.0f%% passed]\033[0m\n", rateColor, successRate)
+		fmt.Printf("%s: %s ", g.Test, testName)
+		fmt.Println(colorize(rateColor, fmt.Sprintf("[%.0f%% passed]", successRate)))
 		fmt.Printf("   Summary: %d/%d servers passed\n\n", comp.PassedRuns, comp.TotalRuns)
 
 		// Create a table-like view
-		fmt.Println("   ┌─────────────────────────────────────────────────────────────┐")
-		fmt.Printf("   │ %-25s │ %-10s │ %-10s │\n", "Server/Agent", "Status", "Duration")
-		fmt.Println("   ├─────────────────────────────────────────────────────────────┤")
+		fmt.Println("   " + g.BoxTop)
+		fmt.Printf("   %s %-25s %s %-10s %s %-10s %s\n", g.BoxSide, "Server/Agent", g.BoxSide, "Status", g.BoxSide, "Duration", g.BoxSide)
+		fmt.Println("   " + g.BoxSep)
 
 		for serverName, result := range comp.ServerResults {
-			status := "\033[32m✓ PASS\033[0m"
+			symbol, label, color := g.Pass, "PASS", colorGreen
 			if !result.Passed {
-				status = "\033[31m✗ FAIL\033[0m"
+				symbol, label, color = g.Fail, "FAIL", colorRed
+			}
+			if !plainConsole {
+				label = symbol + " " + label
 			}
+			// Pad before coloring so escape codes don't break the column width
+			status := colorize(color, fmt.Sprintf("%-10s", label))
 
-			fmt.Printf("   │ %-25s │ %-19s │ %8.2fs │\n",
+			fmt.Printf("   %s %-25s %s %s %s %8.2fs %s\n",
+				g.BoxSide,
 				truncate(serverName, 25),
+				g.BoxSide,
 				status,
-				float64(result.DurationMs)/1000.0)
+				g.BoxSide,
+				float64(result.DurationMs)/1000.0,
+				g.BoxSide)
 
 			// Show provider
-			fmt.Printf("   │   └─ [%s]%-16s │            │          │\n",
+			fmt.Printf("   %s   %s [%s]%-16s %s            %s          %s\n",
+				g.BoxSide,
+				g.Branch,
 				result.Provider,
-				"")
+				"",
+				g.BoxSide, g.BoxSide, g.BoxSide)
 		}
 
-		fmt.Println("   └─────────────────────────────────────────────────────────────┘")
+		fmt.Println("   " + g.BoxBottom)
 
 		// Show which servers failed
 		if comp.FailedRuns > 0 {
-			fmt.Println("\n   \033[31mFailed on:\033[0m")
+			fmt.Println("\n   " + colorize(colorRed, "Failed on:"))
 			for serverName, result := range comp.ServerResults {
 				if !result.Passed {
-					fmt.Printf("   • %s [%s]\n", serverName, result.Provider)
+					fmt.Printf("   %s %s [%s]\n", g.Bullet, serverName, result.Provider)
 					if len(result.Errors) > 0 {
 						for _, err := range result.Errors {
-							fmt.Printf("     └─ %s\n", err)
+							fmt.Printf("     %s %s\n", g.Branch, err)
 						}
 					}
 				}
@@ -1687,15 +4918,17 @@ func (rg *ReportGenerator) printComparisonSummary(results []TestRun) {
 func (rg *ReportGenerator) GenerateConsoleReport(results []TestRun) {
 	// First show the comparison summary
 	rg.printComparisonSummary(results)
+	g := glyphs()
 
 	// Then show detailed results
-	fmt.Println("\n" + "═══════════════════════════════════════════════════════════════")
+	fmt.Println("\n" + g.Rule)
 	fmt.Println("                     DETAILED TEST RESULTS")
-	fmt.Println("═══════════════════════════════════════════════════════════════")
+	fmt.Println(g.Rule)
 	fmt.Println()
 
 	passed := 0
 	failed := 0
+	skipped := 0
 
 	// Group results by test name
 	testGroups := make(map[string][]TestRun)
@@ -1704,20 +4937,32 @@ func (rg *ReportGenerator) GenerateConsoleReport(results []TestRun) {
 	}
 
 	for testName, testRuns := range testGroups {
-		fmt.Printf("📋 Test: %s\n", testName)
+		fmt.Printf("%s: %s\n", g.Test, testName)
 
 		for _, run := range testRuns {
 			duration := run.Execution.EndTime.Sub(run.Execution.StartTime)
 
+			if run.Skipped {
+				skipped++
+				fmt.Printf("  %s\n\n", colorize(colorGray, fmt.Sprintf("%s %s [%s] skipped: %s",
+					g.Skip,
+					run.Execution.AgentName,
+					run.Execution.ProviderType,
+					run.SkipReason)))
+				continue
+			}
+
 			if run.Passed {
 				passed++
-				fmt.Printf("  ✓ %s [%s] (%.2fs)\n",
+				fmt.Printf("  %s %s [%s] (%.2fs)\n",
+					g.Pass,
 					run.Execution.AgentName,
 					run.Execution.ProviderType,
 					duration.Seconds())
 			} else {
 				failed++
-				fmt.Printf("  ✗ %s [%s] (%.2fs)\n",
+				fmt.Printf("  %s %s [%s] (%.2fs)\n",
+					g.Fail,
 					run.Execution.AgentName,
 					run.Execution.ProviderType,
 					duration.Seconds())
@@ -1725,37 +4970,50 @@ func (rg *ReportGenerator) GenerateConsoleReport(results []TestRun) {
 
 			// Show assertion details
 			for _, assertion := range run.Assertions {
-				symbol := "✓"
-				color := "\033[32m" // green
-				if !assertion.Passed {
-					symbol = "✗"
-					color = "\033[31m" // red
+				symbol := g.Pass
+				color := colorGreen
+				if assertion.Disabled {
+					symbol = g.Skip
+					color = colorGray
+				} else if assertion.IsWarning() {
+					symbol = g.Warn
+					color = colorYellow
+				} else if !assertion.Passed {
+					symbol = g.Fail
+					color = colorRed
 				}
-				fmt.Printf("    %s%s\033[0m %s: %s\n", color, symbol, assertion.Type, assertion.Message)
+				fmt.Printf("    %s %s: %s\n", colorize(color, symbol), assertion.Type, assertion.Message)
 
 				// Show additional details if available
 				if len(assertion.Details) > 0 {
 					for k, v := range assertion.Details {
-						fmt.Printf("      • %s: %v\n", k, v)
+						fmt.Printf("      %s %s: %v\n", g.Bullet, k, v)
 					}
 				}
 			}
 
 			// Show errors
 			if len(run.Execution.Errors) > 0 {
-				fmt.Println("    \033[31mErrors:\033[0m")
+				fmt.Println("    " + colorize(colorRed, "Errors:"))
 				for _, err := range run.Execution.Errors {
-					fmt.Printf("      • %s\n", err)
+					fmt.Printf("      %s %s\n", g.Bullet, err)
 				}
 			}
 			fmt.Println()
 		}
 	}
 
-	fmt.Println("═══════════════════════════════════════════════════════════════")
-	fmt.Printf("Total: %d | \033[32mPassed: %d\033[0m | \033[31mFailed: %d\033[0m\n",
-		passed+failed, passed, failed)
-	fmt.Println("═══════════════════════════════════════════════════════════════")
+	fmt.Println(g.Rule)
+	fmt.Printf("Total: %d | %s | %s",
+		passed+failed, colorize(colorGreen, fmt.Sprintf("Passed: %d", passed)), colorize(colorRed, fmt.Sprintf("Failed: %d", failed)))
+	if skipped > 0 {
+		fmt.Printf(" | %s", colorize(colorGray, fmt.Sprintf("Skipped: %d", skipped)))
+	}
+	if ms := TotalAssertionDurationMs(results); ms > 0 {
+		fmt.Printf(" | Assertion time: %.2fs", float64(ms)/1000)
+	}
+	fmt.Println()
+	fmt.Println(g.Rule)
 	fmt.Println()
 }
 
@@ -1775,7 +5033,7 @@ func (rg *ReportGenerator) GenerateMarkdownReport(results []TestRun) string {
 		testGroups[result.Execution.TestName] = append(testGroups[result.Execution.TestName], result)
 		if result.Passed {
 			passed++
-		} else {
+		} else if !result.Skipped {
 			failed++
 		}
 	}
@@ -1783,7 +5041,14 @@ func (rg *ReportGenerator) GenerateMarkdownReport(results []TestRun) string {
 	md += "## Summary\n\n"
 	md += fmt.Sprintf("- **Total:** %d\n", passed+failed)
 	md += fmt.Sprintf("- **Passed:** %d\n", passed)
-	md += fmt.Sprintf("- **Failed:** %d\n\n", failed)
+	md += fmt.Sprintf("- **Failed:** %d\n", failed)
+	if skipped := countSkipped(results); skipped > 0 {
+		md += fmt.Sprintf("- **Skipped:** %d\n", skipped)
+	}
+	if ms := TotalAssertionDurationMs(results); ms > 0 {
+		md += fmt.Sprintf("- **Assertion Time:** %.2fs\n", float64(ms)/1000)
+	}
+	md += "\n"
 
 	// Add comparison summary
 	md += "## Server Comparison Summary\n\n"
@@ -1834,6 +5099,12 @@ func (rg *ReportGenerator) GenerateMarkdownReport(results []TestRun) string {
 		md += fmt.Sprintf("### %s\n\n", testName)
 
 		for _, run := range testRuns {
+			if run.Skipped {
+				md += fmt.Sprintf("#### ⏭️ %s [%s]\n\n", run.Execution.AgentName, run.Execution.ProviderType)
+				md += fmt.Sprintf("- **Skipped:** %s\n\n", run.SkipReason)
+				continue
+			}
+
 			status := "✅"
 			if !run.Passed {
 				status = "❌"
@@ -1842,12 +5113,21 @@ func (rg *ReportGenerator) GenerateMarkdownReport(results []TestRun) string {
 			duration := run.Execution.EndTime.Sub(run.Execution.StartTime)
 			md += fmt.Sprintf("#### %s %s [%s]\n\n", status, run.Execution.AgentName, run.Execution.ProviderType)
 			md += fmt.Sprintf("- **Duration:** %.2fs\n", duration.Seconds())
+			md += fmt.Sprintf("- **Tokens:** %d", run.Execution.TokensUsed)
+			if breakdown := run.Execution.TokenBreakdown(); breakdown != "" {
+				md += fmt.Sprintf(" (%s)", breakdown)
+			}
+			md += "\n"
 
 			if len(run.Assertions) > 0 {
 				md += "- **Tests:**\n"
 				for _, assertion := range run.Assertions {
 					assertStatus := "✅"
-					if !assertion.Passed {
+					if assertion.Disabled {
+						assertStatus = "⊘"
+					} else if assertion.IsWarning() {
+						assertStatus = "⚠️"
+					} else if !assertion.Passed {
 						assertStatus = "❌"
 					}
 					md += fmt.Sprintf("  - %s `%s`: %s\n", assertStatus, assertion.Type, assertion.Message)
@@ -1868,6 +5148,95 @@ func (rg *ReportGenerator) GenerateMarkdownReport(results []TestRun) string {
 	return md
 }
 
+// GenerateTextReport renders the detailed results as plain text without the
+// ANSI colors used on the console, for logs and CI artifacts.
+func (rg *ReportGenerator) GenerateTextReport(results []TestRun) string {
+	var txt string
+	rule := strings.Repeat("=", 63) + "\n"
+
+	txt += rule
+	txt += "TEST RESULTS\n"
+	txt += rule
+	txt += fmt.Sprintf("Agent Benchmark Version: %s\n", version.Version)
+	txt += fmt.Sprintf("Generated: %s\n\n", time.Now().Format(time.RFC3339))
+
+	passed := 0
+	failed := 0
+
+	// Group results by test name
+	testGroups := make(map[string][]TestRun)
+	for _, result := range results {
+		testGroups[result.Execution.TestName] = append(testGroups[result.Execution.TestName], result)
+		if result.Passed {
+			passed++
+		} else if !result.Skipped {
+			failed++
+		}
+	}
+
+	for testName, testRuns := range testGroups {
+		txt += fmt.Sprintf("Test: %s\n", testName)
+
+		for _, run := range testRuns {
+			if run.Skipped {
+				txt += fmt.Sprintf("  [SKIP] %s [%s]: %s\n\n",
+					run.Execution.AgentName,
+					run.Execution.ProviderType,
+					run.SkipReason)
+				continue
+			}
+			status := "PASS"
+			if !run.Passed {
+				status = "FAIL"
+			}
+			duration := run.Execution.EndTime.Sub(run.Execution.StartTime)
+			txt += fmt.Sprintf("  [%s] %s [%s] (%.2fs)\n",
+				status,
+				run.Execution.AgentName,
+				run.Execution.ProviderType,
+				duration.Seconds())
+			txt += fmt.Sprintf("    Tokens: %d", run.Execution.TokensUsed)
+			if breakdown := run.Execution.TokenBreakdown(); breakdown != "" {
+				txt += fmt.Sprintf(" (%s)", breakdown)
+			}
+			txt += "\n"
+
+			for _, assertion := range run.Assertions {
+				assertStatus := "PASS"
+				if assertion.Disabled {
+					assertStatus = "DISABLED"
+				} else if assertion.IsWarning() {
+					assertStatus = "WARN"
+				} else if !assertion.Passed {
+					assertStatus = "FAIL"
+				}
+				txt += fmt.Sprintf("    [%s] %s: %s\n", assertStatus, assertion.Type, assertion.Message)
+			}
+
+			if len(run.Execution.Errors) > 0 {
+				txt += "    Errors:\n"
+				for _, err := range run.Execution.Errors {
+					txt += fmt.Sprintf("      - %s\n", err)
+				}
+			}
+			txt += "\n"
+		}
+	}
+
+	txt += rule
+	txt += fmt.Sprintf("Total: %d | Passed: %d | Failed: %d", passed+failed, passed, failed)
+	if skipped := countSkipped(results); skipped > 0 {
+		txt += fmt.Sprintf(" | Skipped: %d", skipped)
+	}
+	if ms := TotalAssertionDurationMs(results); ms > 0 {
+		txt += fmt.Sprintf(" | Assertion time: %.2fs", float64(ms)/1000)
+	}
+	txt += "\n"
+	txt += rule
+
+	return txt
+}
+
 // AISummaryData represents the AI summary to include in reports.
 // This is a simple struct to avoid circular imports with the agent package.
 type AISummaryData struct {
@@ -1882,21 +5251,43 @@ func (rg *ReportGenerator) GenerateJSONReport(results []TestRun) string {
 	return rg.GenerateJSONReportWithAnalysis(results, nil)
 }
 
+// JSONReportSchemaVersion is the layout version written as schema_version in
+// JSON reports. Bump it when a change needs loaders to migrate older reports.
+//
+//	1: reports without schema_version; errors only as messages
+//	2: classified errors (errorDetails) and the token breakdown
+const JSONReportSchemaVersion = 2
+
 func (rg *ReportGenerator) GenerateJSONReportWithAnalysis(results []TestRun, aiSummary *AISummaryData) string {
 	comparisons := rg.GenerateComparisonSummary(results)
 
+	summary := map[string]interface{}{
+		"total":   len(results),
+		"passed":  countPassed(results),
+		"failed":  countFailed(results),
+		"skipped": countSkipped(results),
+	}
+	if HasCustomWeights(results) {
+		// Percentage 0-100; only present when some test sets a weight
+		summary["weighted_pass_rate"] = WeightedPassRate(results) * 100
+	}
+	if ms := TotalAssertionDurationMs(results); ms > 0 {
+		summary["assertion_duration_ms"] = ms
+	}
+
 	// Create a structured report with comparison
 	reportData := map[string]interface{}{
+		"schema_version":          JSONReportSchemaVersion,
 		"agent_benchmark_version": version.Version,
 		"generated_at":            time.Now().Format(time.RFC3339),
 		"test_file":               rg.TestFile,
-		"summary": map[string]interface{}{
-			"total":  len(results),
-			"passed": countPassed(results),
-			"failed": countFailed(results),
-		},
-		"comparison_summary": comparisons,
-		"detailed_results":   results,
+		"summary":                 summary,
+		"comparison_summary":      comparisons,
+		"agent_stats":             generateAgentStats(results),
+		"detailed_results":        results,
+	}
+	if rg.RunMetadata != nil {
+		reportData["run_metadata"] = rg.RunMetadata
 	}
 
 	// NOTE: ai_summary is NOT included in JSON output
@@ -1911,11 +5302,388 @@ func (rg *ReportGenerator) GenerateJSONReportWithAnalysis(results []TestRun, aiS
 	return string(report)
 }
 
+// SARIF 2.1.0 document types. Only the fields the report uses are modelled.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+const sarifInformationURI = "https://github.com/mykhaliev/agent-benchmark"
+
+// GenerateSARIFReport maps failed assertions to SARIF results for code-scanning
+// dashboards. Each assertion type is a rule; results point at the test file that
+// defines the failing test. Warning-severity failures are reported at warning level.
+func (rg *ReportGenerator) GenerateSARIFReport(results []TestRun) string {
+	type finding struct {
+		run       TestRun
+		assertion AssertionResult
+	}
+	var findings []finding
+	ruleSet := make(map[string]bool)
+	for _, run := range results {
+		for _, a := range run.Assertions {
+			if a.Passed {
+				continue
+			}
+			findings = append(findings, finding{run: run, assertion: a})
+			ruleSet[a.Type] = true
+		}
+	}
+
+	ruleTypes := make([]string, 0, len(ruleSet))
+	for t := range ruleSet {
+		ruleTypes = append(ruleTypes, t)
+	}
+	sort.Strings(ruleTypes)
+	rules := make([]sarifRule, 0, len(ruleTypes))
+	ruleIndex := make(map[string]int, len(ruleTypes))
+	for i, t := range ruleTypes {
+		ruleIndex[t] = i
+		rules = append(rules, sarifRule{
+			ID:               "agent-benchmark/" + t,
+			Name:             t,
+			ShortDescription: sarifMessage{Text: fmt.Sprintf("agent-benchmark %s assertion failed", t)},
+			HelpURI:          sarifInformationURI + "#" + t,
+		})
+	}
+
+	sarifResults := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		exec := f.run.Execution
+		level := "error"
+		if f.assertion.IsWarning() {
+			level = "warning"
+		}
+		result := sarifResult{
+			RuleID:    "agent-benchmark/" + f.assertion.Type,
+			RuleIndex: ruleIndex[f.assertion.Type],
+			Level:     level,
+			Message: sarifMessage{Text: fmt.Sprintf("%s [%s]: %s",
+				exec.TestName, exec.AgentName, f.assertion.Message)},
+			Properties: map[string]interface{}{
+				"test":  exec.TestName,
+				"agent": exec.AgentName,
+			},
+		}
+		if exec.SessionName != "" {
+			result.Properties["session"] = exec.SessionName
+		}
+		if exec.ProviderType != "" {
+			result.Properties["provider"] = exec.ProviderType
+		}
+
+		sourceFile := exec.SourceFile
+		if sourceFile == "" {
+			sourceFile = rg.TestFile
+		}
+		if sourceFile != "" {
+			result.Locations = []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(sourceFile)},
+				},
+			}}
+		}
+		sarifResults = append(sarifResults, result)
+	}
+
+	doc := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "agent-benchmark",
+				Version:        version.Version,
+				InformationURI: sarifInformationURI,
+				Rules:          rules,
+			}},
+			Results: sarifResults,
+		}},
+	}
+
+	report, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		logger.Logger.Warn("Failed to generate SARIF report")
+		return "{}"
+	}
+	return string(report)
+}
+
+// JUnit XML document types, in the shape GitLab and Jenkins read.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// junitSeconds formats a duration in milliseconds as JUnit seconds
+func junitSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64)
+}
+
+// GenerateJUnitReport maps each run to a JUnit testcase (classname = agent,
+// name = "session / test") for CI test dashboards. Testcases are grouped into one testsuite
+// per source file. Failed runs carry the first failing assertion as the failure
+// message, with every failing assertion and execution error in its body;
+// skipped runs and runs without an execution are reported as skipped.
+func (rg *ReportGenerator) GenerateJUnitReport(results []TestRun) string {
+	doc := junitTestSuites{Name: "agent-benchmark"}
+	suiteIndex := make(map[string]int)
+	suiteMs := make(map[string]int64)
+	var totalMs int64
+
+	for _, run := range results {
+		exec := run.Execution
+		suiteName := rg.TestFile
+		if exec != nil && exec.SourceFile != "" {
+			suiteName = exec.SourceFile
+		}
+		if suiteName == "" {
+			suiteName = "agent-benchmark"
+		}
+		suiteName = filepath.ToSlash(suiteName)
+		idx, ok := suiteIndex[suiteName]
+		if !ok {
+			idx = len(doc.Suites)
+			suiteIndex[suiteName] = idx
+			doc.Suites = append(doc.Suites, junitTestSuite{Name: suiteName})
+		}
+		suite := &doc.Suites[idx]
+
+		tc := junitTestCase{Time: junitSeconds(0)}
+		if exec != nil {
+			tc.ClassName = exec.AgentName
+			tc.Name = exec.TestName
+			if exec.SessionName != "" {
+				// Tests of different sessions may share a name
+				tc.Name = exec.SessionName + " / " + exec.TestName
+			}
+			tc.Time = junitSeconds(exec.LatencyMs)
+			suiteMs[suiteName] += exec.LatencyMs
+			totalMs += exec.LatencyMs
+		}
+
+		switch {
+		case run.Skipped || exec == nil:
+			tc.Skipped = &junitSkipped{Message: run.SkipReason}
+			suite.Skipped++
+			doc.Skipped++
+		case !run.Passed:
+			tc.Failure = junitRunFailure(run)
+			suite.Failures++
+			doc.Failures++
+		}
+		suite.Tests++
+		doc.Tests++
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	for i := range doc.Suites {
+		doc.Suites[i].Time = junitSeconds(suiteMs[doc.Suites[i].Name])
+	}
+	doc.Time = junitSeconds(totalMs)
+
+	report, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		logger.Logger.Warn("Failed to generate JUnit report")
+		return xml.Header + "<testsuites></testsuites>\n"
+	}
+	return xml.Header + string(report) + "\n"
+}
+
+// junitRunFailure describes why a run failed: the first failing assertion is
+// the message, and the body lists all failing assertions and execution errors.
+func junitRunFailure(run TestRun) *junitFailure {
+	failure := &junitFailure{}
+	var lines []string
+	for _, a := range run.Assertions {
+		if a.Passed || a.IsWarning() {
+			continue
+		}
+		if failure.Message == "" {
+			failure.Message = a.Message
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", a.Type, a.Message))
+	}
+	for _, e := range run.Execution.Errors {
+		if failure.Message == "" {
+			failure.Message = e
+		}
+		lines = append(lines, "error: "+e)
+	}
+	if failure.Message == "" {
+		failure.Message = "test failed"
+	}
+	failure.Text = strings.Join(lines, "\n")
+	return failure
+}
+
+// csvReportHeader lists the columns of the CSV report, one row per test run
+var csvReportHeader = []string{
+	"source_file", "session", "test_name", "agent_name", "provider", "passed",
+	"latency_ms", "tokens_used", "tool_call_count",
+	"assertion_pass_count", "assertion_fail_count", "assertion_warning_count", "error_count",
+}
+
+// GenerateCSVReport writes one row of per-test metrics per run, after a header
+// row, for spreadsheets and long-term tracking. Runs without a per-test source
+// file report the run's test file.
+func (rg *ReportGenerator) GenerateCSVReport(results []TestRun) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write(csvReportHeader)
+
+	for _, run := range results {
+		exec := run.Execution
+		if exec == nil {
+			exec = &ExecutionResult{}
+		}
+		sourceFile := exec.SourceFile
+		if sourceFile == "" {
+			sourceFile = rg.TestFile
+		}
+		passedCount, failedCount, warningCount := 0, 0, 0
+		for _, a := range run.Assertions {
+			switch {
+			case a.Passed && !a.Disabled:
+				passedCount++
+			case a.IsWarning():
+				warningCount++
+			case a.FailsTest():
+				failedCount++
+			}
+		}
+		_ = w.Write([]string{
+			filepath.ToSlash(sourceFile),
+			exec.SessionName,
+			exec.TestName,
+			exec.AgentName,
+			exec.ProviderLabel(),
+			strconv.FormatBool(run.Passed),
+			strconv.FormatInt(exec.LatencyMs, 10),
+			strconv.Itoa(exec.TokensUsed),
+			strconv.Itoa(len(exec.ToolCalls)),
+			strconv.Itoa(passedCount),
+			strconv.Itoa(failedCount),
+			strconv.Itoa(warningCount),
+			strconv.Itoa(len(exec.Errors)),
+		})
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		logger.Logger.Warn("Failed to generate CSV report", "error", err)
+	}
+	return sb.String()
+}
+
+// Percentile returns the p-th percentile (0-100) of values sorted in ascending
+// order, interpolating linearly between the closest ranks. It is 0 without
+// values and the value itself for a single one.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// LatencyPercentiles returns the p50, p90 and p95 of the runs' LatencyMs.
+func LatencyPercentiles(runs []TestRun) (p50, p90, p95 float64) {
+	latencies := make([]float64, 0, len(runs))
+	for _, r := range runs {
+		latencies = append(latencies, float64(r.Execution.LatencyMs))
+	}
+	sort.Float64s(latencies)
+	return Percentile(latencies, 50), Percentile(latencies, 90), Percentile(latencies, 95)
+}
+
 // generateAgentStats aggregates statistics by agent
 func generateAgentStats(results []TestRun) []AgentStats {
 	statsMap := make(map[string]*AgentStats)
+	agentRuns := make(map[string][]TestRun)
 
-	for _, result := range results {
+	for _, result := range ExecutedRuns(results) {
 		agentName := result.Execution.AgentName
 
 		if _, exists := statsMap[agentName]; !exists {
@@ -1927,6 +5695,7 @@ func generateAgentStats(results []TestRun) []AgentStats {
 
 		stats := statsMap[agentName]
 		stats.TotalTests++
+		agentRuns[agentName] = append(agentRuns[agentName], result)
 
 		if result.Passed {
 			stats.PassedTests++
@@ -1937,6 +5706,12 @@ func generateAgentStats(results []TestRun) []AgentStats {
 		stats.TotalTokens += result.Execution.TokensUsed
 		duration := result.Execution.EndTime.Sub(result.Execution.StartTime).Seconds()
 		stats.TotalDuration += duration
+
+		stats.TotalToolCalls += len(result.Execution.ToolCalls)
+		for _, tc := range result.Execution.ToolCalls {
+			stats.TotalToolTime += float64(tc.DurationMs) / 1000
+		}
+		stats.TotalAssertionTime += float64(result.Execution.AssertionDurationMs) / 1000
 	}
 
 	// Calculate averages and convert to slice
@@ -1945,10 +5720,16 @@ func generateAgentStats(results []TestRun) []AgentStats {
 		if stats.TotalTests > 0 {
 			stats.AvgTokens = stats.TotalTokens / stats.TotalTests
 			stats.AvgDuration = stats.TotalDuration / float64(stats.TotalTests)
+			stats.AvgToolCalls = float64(stats.TotalToolCalls) / float64(stats.TotalTests)
 		}
+		stats.P50LatencyMs, stats.P90LatencyMs, stats.P95LatencyMs = LatencyPercentiles(agentRuns[stats.AgentName])
 		statsList = append(statsList, *stats)
 	}
 
+	sort.Slice(statsList, func(i, j int) bool {
+		return statsList[i].AgentName < statsList[j].AgentName
+	})
+
 	return statsList
 }
 
@@ -1983,13 +5764,28 @@ func countPassed(results []TestRun) int {
 func countFailed(results []TestRun) int {
 	count := 0
 	for _, r := range results {
-		if !r.Passed {
+		if !r.Passed && !r.Skipped {
 			count++
 		}
 	}
 	return count
 }
 
+func countSkipped(results []TestRun) int {
+	return len(results) - len(ExecutedRuns(results))
+}
+
+// TotalAssertionDurationMs returns the time the runs spent evaluating assertions.
+func TotalAssertionDurationMs(results []TestRun) int64 {
+	var total int64
+	for _, r := range results {
+		if r.Execution != nil {
+			total += r.Execution.AssertionDurationMs
+		}
+	}
+	return total
+}
+
 // Helper function to truncate strings
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -2018,6 +5814,21 @@ func GetAllEnv() map[string]string {
 	return envMap
 }
 
+// ValidateTemplate reports a syntax error in a template, which RenderTemplate
+// would only log before using the input unchanged.
+func ValidateTemplate(input string) error {
+	_, err := raymond.Parse(input)
+	return err
+}
+
+// execTemplate executes tmpl, drawing random helper values from the stream
+// named by the context's templates.RandomStreamVar.
+func execTemplate(tmpl *raymond.Template, context map[string]string) (string, error) {
+	data := raymond.NewDataFrame()
+	data.Set(templates.RandomStreamData, context[templates.RandomStreamVar])
+	return tmpl.ExecWith(context, data)
+}
+
 // RenderTemplate safely parses and executes a Raymond template.
 // If parsing or execution fails, it returns the input string unchanged.
 func RenderTemplate(input string, context map[string]string) string {
@@ -2027,7 +5838,7 @@ func RenderTemplate(input string, context map[string]string) string {
 		return input
 	}
 
-	output, err := tmpl.Exec(context)
+	output, err := execTemplate(tmpl, context)
 	if err != nil {
 		log.Printf("Failed to execute template: %v", err)
 		return input
@@ -0,0 +1,358 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/version"
+)
+
+// DefaultRegressionThreshold is the relative increase in latency or tokens
+// (0.2 = 20%) above which a run counts as a regression.
+const DefaultRegressionThreshold = 0.2
+
+// RunKey identifies a run across reports of the same tests.
+type RunKey struct {
+	SourceFile string
+	Session    string
+	Test       string
+	Agent      string
+}
+
+func (k RunKey) String() string {
+	name := k.Test
+	if k.Session != "" {
+		name = k.Session + " / " + name
+	}
+	if k.SourceFile != "" {
+		name = k.SourceFile + " / " + name
+	}
+	return fmt.Sprintf("%s [%s]", name, k.Agent)
+}
+
+// ChangeKind classifies a difference between two reports.
+type ChangeKind string
+
+const (
+	ChangeNewFailure        ChangeKind = "new_failure"
+	ChangeNewPass           ChangeKind = "new_pass"
+	ChangeLatencyRegression ChangeKind = "latency_regression"
+	ChangeTokenRegression   ChangeKind = "token_regression"
+	ChangeAdded             ChangeKind = "added"
+	ChangeRemoved           ChangeKind = "removed"
+)
+
+// changeKindOrder lists the kinds in the order they are reported.
+var changeKindOrder = []ChangeKind{
+	ChangeNewFailure, ChangeLatencyRegression, ChangeTokenRegression,
+	ChangeNewPass, ChangeAdded, ChangeRemoved,
+}
+
+var changeKindTitles = map[ChangeKind]string{
+	ChangeNewFailure:        "New failures",
+	ChangeNewPass:           "New passes",
+	ChangeLatencyRegression: "Latency regressions",
+	ChangeTokenRegression:   "Token regressions",
+	ChangeAdded:             "Added runs",
+	ChangeRemoved:           "Removed runs",
+}
+
+// RunChange is one difference between the old and the new run of a test.
+type RunChange struct {
+	Key          RunKey
+	Kind         ChangeKind
+	OldPassed    bool
+	NewPassed    bool
+	OldLatencyMs int64
+	NewLatencyMs int64
+	OldTokens    int
+	NewTokens    int
+}
+
+// Regression reports whether the change should fail a CI gate.
+func (c RunChange) Regression() bool {
+	switch c.Kind {
+	case ChangeNewFailure, ChangeLatencyRegression, ChangeTokenRegression:
+		return true
+	}
+	return false
+}
+
+// Increase returns the relative increase of the compared metric, e.g. 0.5 for
+// 50% slower, or 0 for changes that do not compare a metric.
+func (c RunChange) Increase() float64 {
+	switch c.Kind {
+	case ChangeLatencyRegression:
+		return float64(c.NewLatencyMs-c.OldLatencyMs) / float64(c.OldLatencyMs)
+	case ChangeTokenRegression:
+		return float64(c.NewTokens-c.OldTokens) / float64(c.OldTokens)
+	}
+	return 0
+}
+
+// Comparison is the difference between two reports of the same tests.
+type Comparison struct {
+	OldReport   string
+	NewReport   string
+	Threshold   float64     // Relative latency/token increase counted as a regression
+	Changes     []RunChange // Ordered by kind, then by key
+	Unchanged   int         // Runs in both reports without a change
+	NotCompared int         // Runs in both reports but skipped in either
+}
+
+// Regressions returns the number of changes that should fail a CI gate.
+func (c Comparison) Regressions() int {
+	n := 0
+	for _, change := range c.Changes {
+		if change.Regression() {
+			n++
+		}
+	}
+	return n
+}
+
+// ChangesOf returns the changes of one kind.
+func (c Comparison) ChangesOf(kind ChangeKind) []RunChange {
+	var changes []RunChange
+	for _, change := range c.Changes {
+		if change.Kind == kind {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// CompareReports loads two JSON reports and compares their runs. Source files
+// are matched relative to the suite each report was run from, so reports of
+// the same suite run from different directories still line up.
+func CompareReports(oldPath, newPath string, threshold float64) (Comparison, error) {
+	oldReport, err := LoadFullReportFromJSON(oldPath)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("failed to load %s: %w", oldPath, err)
+	}
+	newReport, err := LoadFullReportFromJSON(newPath)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("failed to load %s: %w", newPath, err)
+	}
+	c := compareRuns(runsByKey(oldReport.Results, oldReport.TestFile), runsByKey(newReport.Results, newReport.TestFile), threshold)
+	c.OldReport = oldPath
+	c.NewReport = newPath
+	return c, nil
+}
+
+// CompareRuns matches runs by source file (its base name), session, test and
+// agent and lists what changed: tests that started failing or passing,
+// latency and token increases above threshold in runs that executed in both,
+// and runs present in only one of them. A key repeated within one report
+// keeps its last run.
+func CompareRuns(oldRuns, newRuns []model.TestRun, threshold float64) Comparison {
+	return compareRuns(runsByKey(oldRuns, ""), runsByKey(newRuns, ""), threshold)
+}
+
+func compareRuns(oldByKey, newByKey map[RunKey]model.TestRun, threshold float64) Comparison {
+	c := Comparison{Threshold: threshold}
+
+	for key, newRun := range newByKey {
+		oldRun, ok := oldByKey[key]
+		if !ok {
+			c.Changes = append(c.Changes, newRunChange(key, ChangeAdded, model.TestRun{}, newRun))
+			continue
+		}
+		if oldRun.Skipped || newRun.Skipped {
+			c.NotCompared++
+			continue
+		}
+
+		changed := false
+		switch {
+		case oldRun.Passed && !newRun.Passed:
+			c.Changes = append(c.Changes, newRunChange(key, ChangeNewFailure, oldRun, newRun))
+			changed = true
+		case !oldRun.Passed && newRun.Passed:
+			c.Changes = append(c.Changes, newRunChange(key, ChangeNewPass, oldRun, newRun))
+			changed = true
+		}
+		oldExec, newExec := oldRun.Execution, newRun.Execution
+		if exceeds(float64(oldExec.LatencyMs), float64(newExec.LatencyMs), threshold) {
+			c.Changes = append(c.Changes, newRunChange(key, ChangeLatencyRegression, oldRun, newRun))
+			changed = true
+		}
+		if exceeds(float64(oldExec.TokensUsed), float64(newExec.TokensUsed), threshold) {
+			c.Changes = append(c.Changes, newRunChange(key, ChangeTokenRegression, oldRun, newRun))
+			changed = true
+		}
+		if !changed {
+			c.Unchanged++
+		}
+	}
+	for key, oldRun := range oldByKey {
+		if _, ok := newByKey[key]; !ok {
+			c.Changes = append(c.Changes, newRunChange(key, ChangeRemoved, oldRun, model.TestRun{}))
+		}
+	}
+
+	rank := make(map[ChangeKind]int, len(changeKindOrder))
+	for i, kind := range changeKindOrder {
+		rank[kind] = i
+	}
+	sort.Slice(c.Changes, func(i, j int) bool {
+		a, b := c.Changes[i], c.Changes[j]
+		if a.Kind != b.Kind {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		return a.Key.String() < b.Key.String()
+	})
+	return c
+}
+
+// runsByKey indexes the runs that have an execution by their RunKey, with
+// source files named as by comparableSourceFile.
+func runsByKey(runs []model.TestRun, suitePath string) map[RunKey]model.TestRun {
+	byKey := make(map[RunKey]model.TestRun, len(runs))
+	for _, r := range runs {
+		if r.Execution == nil {
+			continue
+		}
+		byKey[RunKey{
+			SourceFile: comparableSourceFile(r.Execution.SourceFile, suitePath),
+			Session:    r.Execution.SessionName,
+			Test:       r.Execution.TestName,
+			Agent:      r.Execution.AgentName,
+		}] = r
+	}
+	return byKey
+}
+
+// comparableSourceFile names a run's source file the same way in reports run
+// from different directories: relative to the directory of suitePath when the
+// file is inside it, its base name otherwise. Separators are normalized to
+// "/" so reports from Windows and Unix match.
+func comparableSourceFile(sourceFile, suitePath string) string {
+	if sourceFile == "" {
+		return ""
+	}
+	file := path.Clean(strings.ReplaceAll(sourceFile, "\\", "/"))
+	if suitePath != "" {
+		dir := path.Dir(path.Clean(strings.ReplaceAll(suitePath, "\\", "/")))
+		if rel, ok := strings.CutPrefix(file, dir+"/"); ok && dir != "." {
+			return rel
+		}
+		if dir == "." && !path.IsAbs(file) && !strings.HasPrefix(file, "../") {
+			return file
+		}
+	}
+	return path.Base(file)
+}
+
+func newRunChange(key RunKey, kind ChangeKind, oldRun, newRun model.TestRun) RunChange {
+	change := RunChange{Key: key, Kind: kind, OldPassed: oldRun.Passed, NewPassed: newRun.Passed}
+	if oldRun.Execution != nil {
+		change.OldLatencyMs = oldRun.Execution.LatencyMs
+		change.OldTokens = oldRun.Execution.TokensUsed
+	}
+	if newRun.Execution != nil {
+		change.NewLatencyMs = newRun.Execution.LatencyMs
+		change.NewTokens = newRun.Execution.TokensUsed
+	}
+	return change
+}
+
+// exceeds reports whether newValue is more than threshold above a non-zero
+// oldValue.
+func exceeds(oldValue, newValue, threshold float64) bool {
+	return oldValue > 0 && newValue > oldValue*(1+threshold)
+}
+
+// WriteText writes the comparison as plain text, grouped by kind of change.
+func (c Comparison) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Comparing %s -> %s (regression threshold %.0f%%)\n", c.OldReport, c.NewReport, c.Threshold*100)
+	for _, kind := range changeKindOrder {
+		changes := c.ChangesOf(kind)
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", changeKindTitles[kind], len(changes))
+		for _, change := range changes {
+			switch kind {
+			case ChangeLatencyRegression:
+				fmt.Fprintf(w, "  %s: %.2fs -> %.2fs (+%.0f%%)\n", change.Key,
+					float64(change.OldLatencyMs)/1000, float64(change.NewLatencyMs)/1000, change.Increase()*100)
+			case ChangeTokenRegression:
+				fmt.Fprintf(w, "  %s: %d -> %d tokens (+%.0f%%)\n", change.Key,
+					change.OldTokens, change.NewTokens, change.Increase()*100)
+			default:
+				fmt.Fprintf(w, "  %s\n", change.Key)
+			}
+		}
+	}
+	fmt.Fprintf(w, "\n%d regressions, %d new passes, %d added, %d removed, %d unchanged",
+		c.Regressions(), len(c.ChangesOf(ChangeNewPass)), len(c.ChangesOf(ChangeAdded)),
+		len(c.ChangesOf(ChangeRemoved)), c.Unchanged)
+	if c.NotCompared > 0 {
+		fmt.Fprintf(w, ", %d skipped", c.NotCompared)
+	}
+	fmt.Fprintln(w)
+}
+
+// comparisonSection is one kind of change in the HTML comparison.
+type comparisonSection struct {
+	Kind       ChangeKind
+	Title      string
+	Regression bool
+	Changes    []RunChange
+}
+
+// GenerateComparisonHTML renders the comparison as a standalone HTML page in
+// the style of the report.
+func GenerateComparisonHTML(c Comparison) (string, error) {
+	funcMap := template.FuncMap{
+		"seconds": func(ms int64) string { return fmt.Sprintf("%.2fs", float64(ms)/1000) },
+		"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	}
+	tmpl, err := template.New("compare.html").Funcs(funcMap).ParseFS(templateFS, "templates/compare.html")
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	cssBytes, err := templateFS.ReadFile("templates/report.css")
+	if err != nil {
+		cssBytes = []byte("/* CSS load error */")
+	}
+
+	var sections []comparisonSection
+	for _, kind := range changeKindOrder {
+		if changes := c.ChangesOf(kind); len(changes) > 0 {
+			sections = append(sections, comparisonSection{
+				Kind:       kind,
+				Title:      changeKindTitles[kind],
+				Regression: changes[0].Regression(),
+				Changes:    changes,
+			})
+		}
+	}
+	data := struct {
+		CSS         template.CSS
+		Version     string
+		GeneratedAt string
+		Comparison
+		Sections []comparisonSection
+	}{
+		CSS:         template.CSS(cssBytes),
+		Version:     version.Version,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Comparison:  c,
+		Sections:    sections,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -6,18 +6,25 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"html/template"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/microcosm-cc/bluemonday"
 	"github.com/mykhaliev/agent-benchmark/agent"
 	"github.com/mykhaliev/agent-benchmark/logger"
 	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/mykhaliev/agent-benchmark/version"
 	"github.com/tmc/langchaingo/llms"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 //go:embed templates/*.html templates/*.css
@@ -43,11 +50,82 @@ type ReportData struct {
 	// Unified adaptive view
 	Adaptive AdaptiveView
 	// AI Summary - LLM-generated executive summary (optional)
-	AISummary    string // Markdown content from LLM analysis
-	HasAISummary bool   // Whether AI summary is available
+	AISummary    template.HTML // LLM analysis rendered from Markdown to sanitized HTML
+	HasAISummary bool          // Whether AI summary is available
 	// Error Overview - aggregated failure details
 	ErrorOverview    ErrorOverview
 	HasErrorOverview bool
+	// Run metadata - reproducibility footer (optional)
+	RunMetadata *model.RunMetadata
+	// Skipped tests - listed separately, excluded from every other section
+	SkippedTests []SkippedTestView
+	// Tool inventory - every distinct tool called during the run
+	ToolInventory []ToolInventoryRow
+	// Identical outputs - tests where different agents answered byte-for-byte alike
+	IdenticalOutputs []IdenticalOutputGroup
+	// Head-to-head ranking from pairwise judging (nil when it did not run)
+	Pairwise *PairwiseView
+	// Heading of the leaderboard groups: "Agent" unless -group-by regrouped them
+	GroupBy string
+}
+
+// PairwiseView ranks the agents by judge preferences between their answers
+type PairwiseView struct {
+	Standings []PairwiseStandingView
+	Agents    []string         // Matrix rows and columns, in ranking order
+	Matrix    [][]PairwiseCell // Matrix[i][j] is the record of Agents[i] against Agents[j]
+}
+
+// PairwiseStandingView is one row of the head-to-head ranking
+type PairwiseStandingView struct {
+	Rank    int
+	Agent   string
+	Elo     float64
+	WinRate float64 // Percentage; a tie counts as half a win
+	Wins    int
+	Losses  int
+	Ties    int
+}
+
+// PairwiseCell is the record of one agent against another
+type PairwiseCell struct {
+	Self   bool // Diagonal cell
+	Wins   int
+	Losses int
+	Ties   int
+}
+
+// Games returns the number of comparisons between the two agents
+func (c PairwiseCell) Games() int {
+	return c.Wins + c.Losses + c.Ties
+}
+
+// IdenticalOutputGroup is a set of distinct agents that produced the same final
+// output for one test. That usually means two agent names share a provider
+// configuration or responses are being served from a cache.
+type IdenticalOutputGroup struct {
+	TestName   string
+	SourceFile string
+	AnchorID   string
+	Agents     []string // Sorted by name
+	Output     string   // Normalized output, truncated for display
+}
+
+// ToolInventoryRow summarizes how one tool was used across all executed tests
+type ToolInventoryRow struct {
+	Name          string
+	Calls         int
+	Agents        []string // Agents that called the tool, sorted by name
+	TimedCalls    int      // Calls with a recorded duration
+	AvgDurationMs float64  // Average over the timed calls
+}
+
+// SkippedTestView is a test that was not run because its skip_if condition held
+type SkippedTestView struct {
+	TestName   string
+	AgentName  string
+	SourceFile string
+	Reason     string
 }
 
 // AdaptiveView is the unified hierarchical structure for all report sections
@@ -65,6 +143,7 @@ type AdaptiveFlags struct {
 	ShowFileHeaders    bool // len(files) > 1
 	ShowSessionHeaders bool // len(sessions) > 1
 	ShowInlineAgents   bool // len(agents) > 1 (show all agents per test row)
+	SideBySideDiagrams bool // len(agents) > 1 (per-agent session diagrams in columns)
 
 	// Layout modes
 	SingleTestMode  bool // len(tests) == 1 (show details directly)
@@ -130,9 +209,12 @@ type SummaryData struct {
 	Total           int
 	Passed          int
 	Failed          int
+	Skipped         int // Tests not run because of skip_if (excluded from Total and PassRate)
+	Warnings        int // Warning-level assertion failures (tests still pass)
 	AgentCount      int
 	PassRate        float64 // Percentage 0-100
 	TotalTokens     int     // Total tokens used across all tests
+	TokensEstimated bool    // At least one test's token count was estimated
 	AvgTokensPassed int     // Average tokens used by passing tests
 	MinTokens       int     // Minimum tokens used in a single test
 	MaxTokens       int     // Maximum tokens used in a single test
@@ -140,6 +222,10 @@ type SummaryData struct {
 	AvgDuration     float64
 	MinDuration     float64 // Minimum duration of a single test
 	MaxDuration     float64 // Maximum duration of a single test
+	AssertionTime   float64 // Seconds spent evaluating assertions, not part of the durations above
+	// Weighted pass rate - each test counts by its weight (percentage 0-100)
+	WeightedPassRate float64
+	HasWeights       bool // Some test sets a non-default weight
 }
 
 // TestOverviewView represents the grouped test overview table
@@ -173,14 +259,16 @@ type TestOverviewSessionGroup struct {
 
 // TestOverviewRow represents a single test in the overview table
 type TestOverviewRow struct {
-	TestName   string
-	AnchorID   string
-	Passed     bool
-	DurationMs float64
-	TokensUsed int
-	ToolCalls  int
-	Assertions int
-	ErrorCount int
+	TestName        string
+	AnchorID        string
+	Passed          bool
+	DurationMs      float64
+	TokensUsed      int
+	TokensEstimated bool
+	TokenBreakdown  string
+	ToolCalls       int
+	Assertions      int
+	ErrorCount      int
 }
 
 // ErrorOverviewRow represents one failed (or bug-bearing) test in the error overview table
@@ -253,16 +341,41 @@ type MatrixTestRow struct {
 
 // MatrixCell represents a single cell in the comparison matrix
 type MatrixCell struct {
-	Passed     bool
-	HasResult  bool
-	DurationMs float64
-	Tokens     int
+	Passed          bool
+	HasResult       bool
+	DurationMs      float64
+	Tokens          int
+	TokensEstimated bool
+	// Failure summary shown on demand in the cell tooltip
+	FirstFailure     string // "[type] message" of the first failed assertion
+	FailedAssertions int
+	ErrorCount       int
+	// Runs aggregated into the cell when -group-by puts several agents in one
+	// column; Passed then means all of them passed
+	Runs       int
+	PassedRuns int
+}
+
+// merge adds another run's cell to c: durations, tokens and failure counts are
+// summed and the first failure is kept.
+func (c *MatrixCell) merge(other MatrixCell) {
+	c.Passed = c.Passed && other.Passed
+	c.DurationMs += other.DurationMs
+	c.Tokens += other.Tokens
+	c.TokensEstimated = c.TokensEstimated || other.TokensEstimated
+	if c.FirstFailure == "" {
+		c.FirstFailure = other.FirstFailure
+	}
+	c.FailedAssertions += other.FailedAssertions
+	c.ErrorCount += other.ErrorCount
+	c.Runs += other.Runs
+	c.PassedRuns += other.PassedRuns
 }
 
 // AgentStatsView is a view model for agent statistics
 type AgentStatsView struct {
 	Rank             int    // 1, 2, 3... or 0 for disqualified
-	RankDisplay      string // "🥇", "🥈", "🥉", "4", "DQ"
+	RankDisplay      string // "🥇", "🥈", "🥉", "4", "DQ" ("1", "2", "3" in plain console mode)
 	AgentName        string
 	Provider         string
 	TotalTests       int
@@ -273,16 +386,26 @@ type AgentStatsView struct {
 	SuccessRateClass string
 	TotalDuration    float64
 	AvgDuration      float64
+	P50LatencyMs     float64 // Latency percentiles of the agent's tests
+	P90LatencyMs     float64
+	P95LatencyMs     float64
 	TotalTokens      int
 	AvgTokens        int
 	Efficiency       int    // Tokens per passed test (lower = better)
 	EfficiencyStr    string // Display string ("125 tok/✓" or "—")
 	IsDisqualified   bool   // 0% success rate
 	RowClass         string // CSS class for row styling
+	TotalToolCalls   int
+	AvgToolCalls     float64
+	TotalToolTime    float64 // Sum of tool call durations (seconds)
 	// Session coverage (only populated when sessions > 1)
 	TotalSessions       int     // Total number of unique sessions
 	SessionsCovered     int     // Sessions where agent passed at least one test
 	SessionCoverageRate float64 // Percentage 0-100
+	// Weighted scoring - each test counts by its weight
+	TotalWeight         float64 // Sum of test weights
+	PassedWeight        float64 // Sum of passed test weights
+	WeightedSuccessRate float64 // Percentage 0-100
 }
 
 // TestGroupView groups test runs by test name
@@ -361,10 +484,19 @@ type TestRunView struct {
 	Errors          []string
 	// Enhanced fields for detailed view
 	Prompt             string // The user prompt that was sent to the agent
+	SystemPrompt       string // Effective system prompt the agent was primed with
 	TokensUsed         int
+	TokensEstimated    bool   // TokensUsed was estimated because the provider reported no usage
+	Tokenizer          string // Local tokenizer behind the estimate
+	TokenBreakdown     string // Prompt, completion and cache token counts, when reported
 	FinalOutput        string
+	TempDir            string   // Kept per-test directory, shown as the artifacts location
+	StateReset         []string // Servers reset with reset_between_agents before the session
+	RetryAttempts      int      // Attempt that produced the result, out of MaxAttempts (tests with retries)
+	MaxAttempts        int
 	Messages           []MessageView
 	ToolCalls          []ToolCallView          // Tool call timeline
+	LazyTranscript     *LazyTranscriptView     // Set when the transcript is too large to render inline
 	SequenceDiagram    string                  // Mermaid syntax
 	RateLimitStats     *RateLimitStatsView     // Rate limiting and 429 stats
 	ClarificationStats *ClarificationStatsView // Clarification detection stats
@@ -378,6 +510,7 @@ type RateLimitStatsView struct {
 	RetryCount        int     // Number of retry attempts made
 	RetryWaitSec      float64 // Total time spent waiting for retries (seconds)
 	RetrySuccessCount int     // Number of successful retries
+	RetryBackoffs     string  // Backoff before each retry, e.g. "1s → 2s → 4s"
 }
 
 // ClarificationStatsView is a view model for clarification detection display
@@ -392,6 +525,46 @@ type MessageView struct {
 	Role      string
 	Content   string
 	Timestamp string
+	Iteration int // Agent loop iteration that produced the message; 0 for prompts
+}
+
+// LazyTranscriptThreshold is the transcript size in bytes (message content plus tool
+// parameters and results) above which a run's conversation and tool calls are left
+// out of the initial page and rendered on demand from an inlined JSON blob.
+const LazyTranscriptThreshold = 256 * 1024
+
+// LazyTranscriptView holds a large transcript that the report renders on demand
+type LazyTranscriptView struct {
+	ID        string         // Element id of the inlined JSON blob
+	SizeBytes int            // Transcript size that triggered lazy loading
+	Data      transcriptData // Serialized into the page as JSON
+}
+
+// SizeKB is the transcript size in kilobytes, for display
+func (v *LazyTranscriptView) SizeKB() float64 {
+	return float64(v.SizeBytes) / 1024
+}
+
+type transcriptData struct {
+	ToolCalls []transcriptToolCall `json:"toolCalls"`
+	Messages  []transcriptMessage  `json:"messages"`
+}
+
+type transcriptToolCall struct {
+	Name       string `json:"name"`
+	Parameters string `json:"parameters,omitempty"` // Pretty-printed JSON
+	Result     string `json:"result,omitempty"`     // Pretty-printed JSON
+	Timestamp  string `json:"timestamp"`
+	DurationMs int64  `json:"durationMs"`
+	TimedOut   bool   `json:"timedOut,omitempty"`
+	Iteration  int    `json:"iteration,omitempty"`
+}
+
+type transcriptMessage struct {
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Timestamp string `json:"timestamp"`
+	Iteration int    `json:"iteration,omitempty"`
 }
 
 // ToolCallView is a view model for tool invocations
@@ -401,24 +574,121 @@ type ToolCallView struct {
 	Result     string // JSON string
 	Timestamp  string
 	DurationMs int64 // Execution time in milliseconds
+	TimedOut   bool  // Call was abandoned after exceeding its tool timeout
+	Iteration  int   // Agent loop iteration that requested the call
+	// First call of its iteration, where the timeline starts a new group
+	IterationStart bool
 }
 
 // AssertionView is a view model for assertions
 type AssertionView struct {
-	Type    string
-	Passed  bool
-	Message string
-	Details string // JSON string of assertion details
+	Type     string
+	Passed   bool
+	Disabled bool // Left out by the assertion filter
+	Warning  bool // Failed at warning level; does not fail the test
+	Message  string
+	Details  string // JSON string of assertion details
 }
 
 // Generator handles HTML report generation
 type Generator struct {
-	tmpl *template.Template
+	tmpl        *template.Template
+	RunMetadata *model.RunMetadata // Reproducibility metadata rendered in the footer (optional)
+	// MaxOutputLength clips final outputs and tool results to this many characters,
+	// with the rest revealed on demand. 0 shows everything.
+	MaxOutputLength int
+	// GroupBy is the key the leaderboard and the comparison matrix aggregate
+	// runs by. Empty groups by agent.
+	GroupBy GroupBy
+}
+
+// GroupBy selects what the leaderboard and the comparison matrix group runs by.
+type GroupBy string
+
+const (
+	// GroupByAgent keeps one row and column per agent (default).
+	GroupByAgent GroupBy = "agent"
+	// GroupByProvider aggregates the agents of each provider.
+	GroupByProvider GroupBy = "provider"
+	// GroupByModel aggregates the runs of each model, across providers and agents.
+	GroupByModel GroupBy = "model"
+	// GroupByTag aggregates the runs of each test tag; a run counts once per tag.
+	GroupByTag GroupBy = "tag"
+)
+
+// UntaggedGroup collects the runs of untagged tests when grouping by tag.
+const UntaggedGroup = "untagged"
+
+// ParseGroupBy validates a -group-by value. An empty value groups by agent.
+func ParseGroupBy(s string) (GroupBy, error) {
+	switch g := GroupBy(strings.ToLower(strings.TrimSpace(s))); g {
+	case "":
+		return GroupByAgent, nil
+	case GroupByAgent, GroupByProvider, GroupByModel, GroupByTag:
+		return g, nil
+	default:
+		return "", fmt.Errorf("unknown group-by %q (expected agent, provider, model or tag)", s)
+	}
+}
+
+// Title is the column heading of a group in the leaderboard.
+func (g GroupBy) Title() string {
+	switch g {
+	case GroupByProvider:
+		return "Provider"
+	case GroupByModel:
+		return "Model"
+	case GroupByTag:
+		return "Tag"
+	default:
+		return "Agent"
+	}
+}
+
+// Options are the presentation settings of a generator.
+type Options struct {
+	MaxOutputLength int     // See Generator.MaxOutputLength
+	GroupBy         GroupBy // See Generator.GroupBy
+}
+
+// ClippedText is text split for display: Shown is rendered and Rest, if any,
+// stays in the page hidden behind an expand control.
+type ClippedText struct {
+	Shown     string
+	Rest      string
+	RestChars int // Characters in Rest
+}
+
+// clipText splits s after limit characters. Characters are runes, so multi-byte
+// text is never cut in the middle. A limit of 0 or less keeps s whole.
+func clipText(s string, limit int) ClippedText {
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return ClippedText{Shown: s}
+	}
+	cut := 0
+	for i := 0; i < limit; i++ {
+		_, size := utf8.DecodeRuneInString(s[cut:])
+		cut += size
+	}
+	return ClippedText{
+		Shown:     s[:cut],
+		Rest:      s[cut:],
+		RestChars: utf8.RuneCountInString(s[cut:]),
+	}
 }
 
 // NewGenerator creates a new report generator with embedded templates
 func NewGenerator() (*Generator, error) {
+	return NewGeneratorWithOptions(Options{})
+}
+
+// NewGeneratorWithOptions creates a report generator with the given options
+func NewGeneratorWithOptions(options Options) (*Generator, error) {
+	g := &Generator{MaxOutputLength: options.MaxOutputLength, GroupBy: options.GroupBy}
 	funcMap := template.FuncMap{
+		"clip": func(s string) ClippedText {
+			return clipText(s, g.MaxOutputLength)
+		},
 		"formatNumber": formatNumber,
 		"lower":        strings.ToLower,
 		"getMatrixCell": func(cells map[string]map[string]MatrixCell, testKey, agentName string) MatrixCell {
@@ -443,32 +713,16 @@ func NewGenerator() (*Generator, error) {
 			}
 			return nil
 		},
-		"truncate": func(s string, max int) string {
-			if len(s) <= max {
-				return s
-			}
-			return s[:max-3] + "..."
-		},
+		"truncate": truncateText,
 		"safeJSON": func(s string) template.HTML {
-			// Return JSON as safe HTML to avoid double-escaping
+			// Return JSON as safe HTML to avoid double-escaping; only for JSON built by the generator
 			return template.HTML(s)
 		},
 		"safeHTML": func(s string) template.HTML {
-			// Return string as safe HTML to preserve markdown for client-side rendering
-			return template.HTML(s)
-		},
-		"prettyJSON": func(s string) template.HTML {
-			// Pretty print JSON for display
-			var obj interface{}
-			if err := json.Unmarshal([]byte(s), &obj); err != nil {
-				return template.HTML(s)
-			}
-			pretty, err := json.MarshalIndent(obj, "", "  ")
-			if err != nil {
-				return template.HTML(s)
-			}
-			return template.HTML(pretty)
+			// Content may come from an LLM, so only allowlisted formatting markup is kept
+			return SanitizeHTML(s)
 		},
+		"prettyJSON": prettyJSON,
 		"hasDetails": func(s string) bool {
 			return s != "" && s != "{}" && s != "null"
 		},
@@ -519,31 +773,80 @@ func NewGenerator() (*Generator, error) {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	return &Generator{tmpl: tmpl}, nil
+	g.tmpl = tmpl
+	return g, nil
 }
 
 // GenerateHTML generates an HTML report from test results
 func (g *Generator) GenerateHTML(results []model.TestRun) (string, error) {
-	data := buildReportData(results)
+	return g.Render(g.BuildReportData(results, nil))
+}
+
+// markdownRenderer converts LLM Markdown to HTML. goldmark's defaults are safe for
+// untrusted input: raw HTML is omitted and dangerous link schemes are dropped.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
 
+// htmlSanitizer keeps formatting markup (headings, lists, tables, code, links) and
+// strips scripts, styles, event handlers and unsafe URLs.
+var htmlSanitizer = bluemonday.UGCPolicy()
+
+// SanitizeHTML marks LLM-sourced HTML as safe after reducing it to allowlisted formatting.
+func SanitizeHTML(s string) template.HTML {
+	return template.HTML(htmlSanitizer.Sanitize(s))
+}
+
+// RenderMarkdown renders Markdown from an LLM to sanitized HTML, so the content
+// is readable without client-side JavaScript (email previews, PDF export).
+func RenderMarkdown(markdown string) template.HTML {
 	var buf bytes.Buffer
-	if err := g.tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	if err := markdownRenderer.Convert([]byte(markdown), &buf); err != nil {
+		logger.Logger.Warn("Failed to render Markdown, falling back to escaped text", "error", err)
+		return template.HTML("<pre>" + template.HTMLEscapeString(markdown) + "</pre>")
 	}
-
-	return buf.String(), nil
+	return SanitizeHTML(buf.String())
 }
 
 // GenerateHTMLWithAnalysis generates an HTML report with optional LLM-generated analysis
 func (g *Generator) GenerateHTMLWithAnalysis(results []model.TestRun, analysis *agent.AISummaryResult) (string, error) {
+	return g.Render(g.BuildReportData(results, analysis))
+}
+
+// BuildReportData computes the template view model, including the rendered AI
+// summary, so it can be built once and rendered separately.
+func BuildReportData(results []model.TestRun, analysis *agent.AISummaryResult) ReportData {
 	data := buildReportData(results)
 
 	// Add AI summary if available
 	if analysis != nil && analysis.Analysis != "" {
-		data.AISummary = analysis.Analysis
+		data.AISummary = RenderMarkdown(analysis.Analysis)
 		data.HasAISummary = true
 	}
 
+	return data
+}
+
+// BuildReportData computes the view model like the BuildReportData function and
+// regroups the leaderboard and the comparison matrix by the generator's GroupBy.
+func (g *Generator) BuildReportData(results []model.TestRun, analysis *agent.AISummaryResult) ReportData {
+	data := BuildReportData(results, analysis)
+	if g.GroupBy == "" || g.GroupBy == GroupByAgent {
+		return data
+	}
+
+	grouped := GroupRuns(model.ExecutedRuns(results), g.GroupBy, g.RunMetadata)
+	data.GroupBy = g.GroupBy.Title()
+	data.AgentStats = buildAgentStats(grouped)
+	data.Matrix = buildMatrix(grouped)
+	groups := len(data.AgentStats)
+	data.Adaptive.Flags.ShowMatrix = groups > 1
+	data.Adaptive.Flags.ShowLeaderboard = groups > 1
+	return data
+}
+
+// Render executes the HTML template with a prebuilt view model
+func (g *Generator) Render(data ReportData) (string, error) {
+	data.RunMetadata = g.RunMetadata
+
 	var buf bytes.Buffer
 	if err := g.tmpl.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
@@ -568,32 +871,19 @@ func (g *Generator) GenerateHTMLToFile(results []model.TestRun, outputPath strin
 
 // LoadResultsFromJSON loads test results from a JSON file
 func LoadResultsFromJSON(jsonPath string) ([]model.TestRun, error) {
-	data, err := os.ReadFile(jsonPath)
+	reportData, err := LoadFullReportFromJSON(jsonPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read JSON file: %w", err)
-	}
-
-	// The JSON structure has detailed_results containing the TestRun array
-	var reportData struct {
-		DetailedResults []model.TestRun `json:"detailed_results"`
+		return nil, err
 	}
-
-	if err := json.Unmarshal(data, &reportData); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	if len(reportData.DetailedResults) == 0 {
-		return nil, fmt.Errorf("no test results found in JSON file")
-	}
-
-	return reportData.DetailedResults, nil
+	return reportData.Results, nil
 }
 
 // JSONReportData holds the full JSON report including AI summary
 type JSONReportData struct {
-	Results   []model.TestRun
-	AISummary *agent.AISummaryResult
-	TestFile  string // Path to the original test configuration file
+	Results     []model.TestRun
+	AISummary   *agent.AISummaryResult
+	TestFile    string             // Path to the original test configuration file
+	RunMetadata *model.RunMetadata // Reproducibility metadata (nil for older reports)
 }
 
 // LoadFullReportFromJSON loads test results and existing AI summary from a JSON file
@@ -605,8 +895,10 @@ func LoadFullReportFromJSON(jsonPath string) (*JSONReportData, error) {
 
 	// Parse the full report structure including ai_summary
 	var reportData struct {
-		DetailedResults []model.TestRun `json:"detailed_results"`
-		TestFile        string          `json:"test_file,omitempty"`
+		SchemaVersion   int                `json:"schema_version"`
+		DetailedResults []model.TestRun    `json:"detailed_results"`
+		TestFile        string             `json:"test_file,omitempty"`
+		RunMetadata     *model.RunMetadata `json:"run_metadata,omitempty"`
 		AISummary       *struct {
 			Success   bool   `json:"success"`
 			Analysis  string `json:"analysis,omitempty"`
@@ -620,13 +912,18 @@ func LoadFullReportFromJSON(jsonPath string) (*JSONReportData, error) {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
+	if err := MigrateResults(reportData.SchemaVersion, reportData.DetailedResults); err != nil {
+		return nil, fmt.Errorf("%s: %w", jsonPath, err)
+	}
+
 	if len(reportData.DetailedResults) == 0 {
 		return nil, fmt.Errorf("no test results found in JSON file")
 	}
 
 	result := &JSONReportData{
-		Results:  reportData.DetailedResults,
-		TestFile: reportData.TestFile,
+		Results:     reportData.DetailedResults,
+		TestFile:    reportData.TestFile,
+		RunMetadata: reportData.RunMetadata,
 	}
 
 	// Convert existing AI summary if present
@@ -643,6 +940,61 @@ func LoadFullReportFromJSON(jsonPath string) (*JSONReportData, error) {
 	return result, nil
 }
 
+// MigrateResults brings results read from a report with the given schema_version
+// up to the current schema in place. A missing version (0) is version 1. Reports
+// written by a newer build are rejected rather than read partially.
+func MigrateResults(schemaVersion int, results []model.TestRun) error {
+	if schemaVersion == 0 {
+		schemaVersion = 1
+	}
+	if schemaVersion < 1 || schemaVersion > model.JSONReportSchemaVersion {
+		return fmt.Errorf("unsupported schema version %d (this build reads versions 1 to %d; upgrade agent-benchmark to load it)",
+			schemaVersion, model.JSONReportSchemaVersion)
+	}
+	if schemaVersion < 2 {
+		for _, r := range results {
+			if r.Execution != nil {
+				classifyLegacyErrors(r.Execution)
+			}
+		}
+	}
+	return nil
+}
+
+// legacyErrorKinds maps the message prefixes of errors that version 1 reports
+// recorded without a kind.
+var legacyErrorKinds = []struct {
+	prefix string
+	kind   model.ErrorKind
+}{
+	{"test timeout exceeded", model.ErrorKindTestTimeout},
+	{"test interrupted", model.ErrorKindInterrupted},
+	{"run deadline exceeded", model.ErrorKindRunDeadline},
+}
+
+// classifyLegacyErrors fills in errorDetails for a version 1 execution, which
+// only has error messages, so kind-based assertions and stats see its errors.
+func classifyLegacyErrors(exec *model.ExecutionResult) {
+	if len(exec.ErrorDetails) > 0 {
+		return
+	}
+	for _, msg := range exec.Errors {
+		kind := model.ErrorKind("")
+		for _, legacy := range legacyErrorKinds {
+			if strings.HasPrefix(msg, legacy.prefix) {
+				kind = legacy.kind
+				break
+			}
+		}
+		if kind == "" && model.IsRateLimitError(errors.New(msg)) {
+			kind = model.ErrorKindRateLimit
+		}
+		if kind != "" {
+			exec.ErrorDetails = append(exec.ErrorDetails, model.ExecutionError{Kind: kind, Message: msg})
+		}
+	}
+}
+
 // GenerateReportFromJSON generates an HTML report from an existing JSON file
 func GenerateReportFromJSON(jsonPath, outputPath string) error {
 	results, err := LoadResultsFromJSON(jsonPath)
@@ -664,35 +1016,37 @@ func GenerateReportFromJSON(jsonPath, outputPath string) error {
 }
 
 // GenerateReportFromJSONWithSummary generates an HTML report with AI summary generation
-// If judgeLLM is nil, uses existing AI summary from JSON (if any)
-// If judgeLLM is provided, regenerates the AI summary using the LLM
-func GenerateReportFromJSONWithSummary(ctx context.Context, jsonPath, outputPath string, judgeLLM llms.Model) error {
+// Without judges, uses existing AI summary from JSON (if any)
+// With judges, regenerates the AI summary, trying them in order
+func GenerateReportFromJSONWithSummary(ctx context.Context, jsonPath, outputPath string, judges []llms.Model, options Options) error {
+	log := logger.FromContext(ctx)
 	reportData, err := LoadFullReportFromJSON(jsonPath)
 	if err != nil {
 		return err
 	}
 
-	gen, err := NewGenerator()
+	gen, err := NewGeneratorWithOptions(options)
 	if err != nil {
 		return err
 	}
+	gen.RunMetadata = reportData.RunMetadata
 
 	var aiSummary *agent.AISummaryResult
 
-	// If judgeLLM is provided, regenerate AI summary
-	if judgeLLM != nil {
-		logger.Logger.Info("Regenerating AI summary")
-		result := agent.GenerateAISummary(ctx, judgeLLM, reportData.Results)
-		aiSummary = &result
+	// If judges are provided, regenerate AI summary
+	if len(judges) > 0 {
+		log.Info("Regenerating AI summary")
+		result := agent.GenerateAISummaryWithJudges(ctx, judges, model.ExecutedRuns(reportData.Results))
+		aiSummary = result
 		if result.Success {
-			logger.Logger.Info("AI summary regenerated successfully")
+			log.Info("AI summary regenerated successfully")
 		} else {
-			logger.Logger.Warn("AI summary regeneration failed", "error", result.Error)
+			log.Warn("AI summary regeneration failed", "error", result.Error)
 		}
 	} else if reportData.AISummary != nil {
 		// Use existing AI summary from JSON
 		aiSummary = reportData.AISummary
-		logger.Logger.Info("Using existing AI summary from JSON")
+		log.Info("Using existing AI summary from JSON")
 	}
 
 	// Generate HTML with AI summary
@@ -705,16 +1059,21 @@ func GenerateReportFromJSONWithSummary(ctx context.Context, jsonPath, outputPath
 		return fmt.Errorf("failed to write report file: %w", err)
 	}
 
-	logger.Logger.Info("Report generated from JSON with AI summary", "input", jsonPath, "output", outputPath)
+	log.Info("Report generated from JSON with AI summary", "input", jsonPath, "output", outputPath)
 	return nil
 }
 
 // buildReportData transforms TestRun results into the template view model
 func buildReportData(results []model.TestRun) ReportData {
+	skippedTests := buildSkippedTests(results)
+	results = model.ExecutedRuns(results)
+
 	passed := 0
 	failed := 0
+	warnings := 0
 	totalTokens := 0
 	totalTokensPassed := 0
+	tokensEstimated := false
 	totalDuration := 0.0
 
 	// Collect unique values for adaptive rendering
@@ -746,6 +1105,7 @@ func buildReportData(results []model.TestRun) ReportData {
 	maxDuration := 0.0
 	for i, r := range results {
 		totalTokens += r.Execution.TokensUsed
+		tokensEstimated = tokensEstimated || r.Execution.TokensEstimated
 		duration := r.Execution.EndTime.Sub(r.Execution.StartTime).Seconds()
 
 		// Track min/max tokens
@@ -770,6 +1130,7 @@ func buildReportData(results []model.TestRun) ReportData {
 		} else {
 			failed++
 		}
+		warnings += model.CountWarnings(r.Assertions)
 		totalDuration += duration
 	}
 
@@ -811,16 +1172,23 @@ func buildReportData(results []model.TestRun) ReportData {
 			Total:           totalTests,
 			Passed:          passed,
 			Failed:          failed,
+			Skipped:         len(skippedTests),
+			Warnings:        warnings,
 			AgentCount:      len(agents),
 			PassRate:        passRate,
 			TotalTokens:     totalTokens,
+			TokensEstimated: tokensEstimated,
 			AvgTokensPassed: avgTokensPassed,
 			MinTokens:       minTokens,
 			MaxTokens:       maxTokens,
 			TotalDuration:   totalDuration,
 			AvgDuration:     avgDuration,
+			AssertionTime:   float64(model.TotalAssertionDurationMs(results)) / 1000,
 			MinDuration:     minDuration,
 			MaxDuration:     maxDuration,
+
+			WeightedPassRate: model.WeightedPassRate(results) * 100,
+			HasWeights:       model.HasCustomWeights(results),
 		},
 		AgentStats:       buildAgentStats(results),
 		Matrix:           matrix,
@@ -833,9 +1201,190 @@ func buildReportData(results []model.TestRun) ReportData {
 		Adaptive:         adaptiveView,
 		ErrorOverview:    errorOverview,
 		HasErrorOverview: errorOverview.TotalFailed > 0,
+		SkippedTests:     skippedTests,
+		ToolInventory:    buildToolInventory(results),
+		IdenticalOutputs: buildIdenticalOutputs(results, anchorMap),
+		Pairwise:         buildPairwiseView(results),
+		GroupBy:          GroupByAgent.Title(),
 	}
 }
 
+// buildPairwiseView ranks agents by the pairwise outcomes recorded on the runs,
+// or returns nil when no comparison was made.
+func buildPairwiseView(results []model.TestRun) *PairwiseView {
+	standings := model.PairwiseStandings(results)
+	if len(standings) == 0 {
+		return nil
+	}
+
+	view := &PairwiseView{
+		Standings: make([]PairwiseStandingView, len(standings)),
+		Agents:    make([]string, len(standings)),
+		Matrix:    make([][]PairwiseCell, len(standings)),
+	}
+	index := make(map[string]int, len(standings))
+	for i, s := range standings {
+		view.Standings[i] = PairwiseStandingView{
+			Rank:    i + 1,
+			Agent:   s.Agent,
+			Elo:     s.Elo,
+			WinRate: s.WinRate() * 100,
+			Wins:    s.Wins,
+			Losses:  s.Losses,
+			Ties:    s.Ties,
+		}
+		view.Agents[i] = s.Agent
+		view.Matrix[i] = make([]PairwiseCell, len(standings))
+		view.Matrix[i][i].Self = true
+		index[s.Agent] = i
+	}
+
+	for _, r := range results {
+		if r.Execution == nil {
+			continue
+		}
+		row, ok := index[r.Execution.AgentName]
+		if !ok {
+			continue
+		}
+		for _, outcome := range r.Pairwise {
+			col, ok := index[outcome.Opponent]
+			if !ok {
+				continue
+			}
+			cell := &view.Matrix[row][col]
+			switch outcome.Result {
+			case model.PairwiseWin:
+				cell.Wins++
+			case model.PairwiseLoss:
+				cell.Losses++
+			default:
+				cell.Ties++
+			}
+		}
+	}
+	return view
+}
+
+// IdenticalOutputs finds tests where two or more distinct agents produced the
+// same final output, after trimming and collapsing whitespace.
+func IdenticalOutputs(results []model.TestRun) []IdenticalOutputGroup {
+	return buildIdenticalOutputs(model.ExecutedRuns(results), nil)
+}
+
+func buildIdenticalOutputs(results []model.TestRun, anchorMap map[string]string) []IdenticalOutputGroup {
+	type outputKey struct {
+		test   string
+		output string
+	}
+	groups := make(map[outputKey]*IdenticalOutputGroup)
+	agents := make(map[outputKey]map[string]bool)
+	var order []outputKey
+	for _, r := range results {
+		if r.Execution == nil {
+			continue
+		}
+		output := strings.Join(strings.Fields(r.Execution.FinalOutput), " ")
+		if output == "" {
+			continue
+		}
+		key := outputKey{test: getUniqueTestKey(r), output: output}
+		if _, ok := groups[key]; !ok {
+			groups[key] = &IdenticalOutputGroup{
+				TestName:   r.Execution.TestName,
+				SourceFile: r.Execution.SourceFile,
+				AnchorID:   anchorMap[key.test],
+				Output:     truncateText(output, 200),
+			}
+			agents[key] = make(map[string]bool)
+			order = append(order, key)
+		}
+		agents[key][r.Execution.AgentName] = true
+	}
+
+	var identical []IdenticalOutputGroup
+	for _, key := range order {
+		if len(agents[key]) < 2 {
+			continue
+		}
+		g := groups[key]
+		for a := range agents[key] {
+			g.Agents = append(g.Agents, a)
+		}
+		sort.Strings(g.Agents)
+		identical = append(identical, *g)
+	}
+	return identical
+}
+
+// buildToolInventory aggregates tool calls by tool name, most called first.
+// Calls without a recorded duration do not count towards the average.
+func buildToolInventory(results []model.TestRun) []ToolInventoryRow {
+	type toolUsage struct {
+		calls      int
+		timedCalls int
+		totalMs    int64
+		agents     map[string]bool
+	}
+	usage := make(map[string]*toolUsage)
+	for _, r := range results {
+		for _, tc := range r.Execution.ToolCalls {
+			u, ok := usage[tc.Name]
+			if !ok {
+				u = &toolUsage{agents: make(map[string]bool)}
+				usage[tc.Name] = u
+			}
+			u.calls++
+			u.agents[r.Execution.AgentName] = true
+			if tc.DurationMs > 0 {
+				u.timedCalls++
+				u.totalMs += tc.DurationMs
+			}
+		}
+	}
+
+	rows := make([]ToolInventoryRow, 0, len(usage))
+	for name, u := range usage {
+		row := ToolInventoryRow{
+			Name:       name,
+			Calls:      u.calls,
+			Agents:     make([]string, 0, len(u.agents)),
+			TimedCalls: u.timedCalls,
+		}
+		for a := range u.agents {
+			row.Agents = append(row.Agents, a)
+		}
+		sort.Strings(row.Agents)
+		if u.timedCalls > 0 {
+			row.AvgDurationMs = float64(u.totalMs) / float64(u.timedCalls)
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Calls != rows[j].Calls {
+			return rows[i].Calls > rows[j].Calls
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+func buildSkippedTests(results []model.TestRun) []SkippedTestView {
+	var skipped []SkippedTestView
+	for _, r := range results {
+		if !r.Skipped {
+			continue
+		}
+		skipped = append(skipped, SkippedTestView{
+			TestName:   r.Execution.TestName,
+			AgentName:  r.Execution.AgentName,
+			SourceFile: r.Execution.SourceFile,
+			Reason:     r.SkipReason,
+		})
+	}
+	return skipped
+}
+
 func buildTestOverview(results []model.TestRun, anchorMap map[string]string) TestOverviewView {
 	// Track unique files and sessions, maintaining execution order
 	fileSet := make(map[string]bool)
@@ -876,14 +1425,16 @@ func buildTestOverview(results []model.TestRun, anchorMap map[string]string) Tes
 
 		key := groupKey{file: file, session: session}
 		groupedTests[key] = append(groupedTests[key], TestOverviewRow{
-			TestName:   r.Execution.TestName,
-			AnchorID:   anchorMap[getUniqueTestKey(r)],
-			Passed:     r.Passed,
-			DurationMs: float64(r.Execution.LatencyMs),
-			TokensUsed: r.Execution.TokensUsed,
-			ToolCalls:  len(r.Execution.ToolCalls),
-			Assertions: len(r.Assertions),
-			ErrorCount: len(r.Execution.Errors),
+			TestName:        r.Execution.TestName,
+			AnchorID:        anchorMap[getUniqueTestKey(r)],
+			Passed:          r.Passed,
+			DurationMs:      float64(r.Execution.LatencyMs),
+			TokensUsed:      r.Execution.TokensUsed,
+			TokensEstimated: r.Execution.TokensEstimated,
+			TokenBreakdown:  r.Execution.TokenBreakdown(),
+			ToolCalls:       len(r.Execution.ToolCalls),
+			Assertions:      len(r.Assertions),
+			ErrorCount:      len(r.Execution.Errors),
 		})
 	}
 
@@ -1203,7 +1754,7 @@ func buildAdaptiveView(results []model.TestRun) AdaptiveView {
 		}
 		// Get provider from first result
 		if len(results) > 0 {
-			singleAgentProvider = string(results[0].Execution.ProviderType)
+			singleAgentProvider = results[0].Execution.ProviderLabel()
 		}
 	}
 
@@ -1214,6 +1765,7 @@ func buildAdaptiveView(results []model.TestRun) AdaptiveView {
 		ShowFileHeaders:     showFileHeaders,
 		ShowSessionHeaders:  showSessionHeaders,
 		ShowInlineAgents:    agentCount > 1,
+		SideBySideDiagrams:  agentCount > 1,
 		SingleTestMode:      testCount == 1,
 		SingleAgentMode:     agentCount == 1,
 		SingleAgentName:     singleAgentName,
@@ -1253,7 +1805,7 @@ func buildErrorOverview(results []model.TestRun, anchorMap map[string]string) Er
 		}
 		var failedAssertions []string
 		for _, a := range r.Assertions {
-			if !a.Passed {
+			if a.FailsTest() {
 				failedAssertions = append(failedAssertions, fmt.Sprintf("[%s] %s", a.Type, a.Message))
 			}
 		}
@@ -1294,6 +1846,80 @@ func buildErrorOverview(results []model.TestRun, anchorMap map[string]string) Er
 }
 
 // buildTestRunView creates a TestRunView from a TestRun
+func truncateText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
+
+// prettyJSON pretty prints JSON for display. Tool parameters and results are untrusted,
+// so the text is returned unmarked and escaped by the template.
+func prettyJSON(s string) string {
+	var obj interface{}
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		return s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(obj); err != nil {
+		return s
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// buildLazyTranscript returns the on-demand transcript for a run whose messages and
+// tool calls exceed LazyTranscriptThreshold, or nil when they can be rendered inline.
+// Message content is truncated exactly as the inline template does.
+func buildLazyTranscript(run model.TestRun, messages []MessageView, toolCalls []ToolCallView) *LazyTranscriptView {
+	size := 0
+	for _, m := range messages {
+		size += len(m.Content)
+	}
+	for _, tc := range toolCalls {
+		size += len(tc.Parameters) + len(tc.Result)
+	}
+	if size <= LazyTranscriptThreshold {
+		return nil
+	}
+
+	data := transcriptData{
+		ToolCalls: make([]transcriptToolCall, len(toolCalls)),
+		Messages:  make([]transcriptMessage, len(messages)),
+	}
+	for i, tc := range toolCalls {
+		data.ToolCalls[i] = transcriptToolCall{
+			Name:       tc.Name,
+			Parameters: prettyJSON(tc.Parameters),
+			Result:     prettyJSON(tc.Result),
+			Timestamp:  tc.Timestamp,
+			DurationMs: tc.DurationMs,
+			TimedOut:   tc.TimedOut,
+			Iteration:  tc.Iteration,
+		}
+	}
+	for i, m := range messages {
+		data.Messages[i] = transcriptMessage{
+			Role:      m.Role,
+			Content:   truncateText(m.Content, 1000),
+			Timestamp: m.Timestamp,
+			Iteration: m.Iteration,
+		}
+	}
+
+	// Stable across renders so a regenerated report diffs cleanly
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%d", run.Execution.SourceFile, run.Execution.SessionName,
+		run.Execution.TestName, run.Execution.AgentName, run.Execution.StartTime.UnixNano())
+	return &LazyTranscriptView{
+		ID:        fmt.Sprintf("transcript-%016x", h.Sum64()),
+		SizeBytes: size,
+		Data:      data,
+	}
+}
+
 func buildTestRunView(run model.TestRun) TestRunView {
 	duration := run.Execution.EndTime.Sub(run.Execution.StartTime)
 
@@ -1306,10 +1932,12 @@ func buildTestRunView(run model.TestRun) TestRunView {
 			}
 		}
 		assertions[i] = AssertionView{
-			Type:    a.Type,
-			Passed:  a.Passed,
-			Message: a.Message,
-			Details: detailsJSON,
+			Type:     a.Type,
+			Passed:   a.Passed,
+			Disabled: a.Disabled,
+			Warning:  a.IsWarning(),
+			Message:  a.Message,
+			Details:  detailsJSON,
 		}
 	}
 
@@ -1320,6 +1948,7 @@ func buildTestRunView(run model.TestRun) TestRunView {
 			Role:      m.Role,
 			Content:   m.Content,
 			Timestamp: m.Timestamp.Format("15:04:05.000"),
+			Iteration: m.Iteration,
 		}
 	}
 
@@ -1349,11 +1978,14 @@ func buildTestRunView(run model.TestRun) TestRunView {
 			}
 		}
 		toolCalls[i] = ToolCallView{
-			Name:       tc.Name,
-			Parameters: paramsJSON,
-			Result:     resultJSON,
-			Timestamp:  relativeTime,
-			DurationMs: tc.DurationMs,
+			Name:           tc.Name,
+			Parameters:     paramsJSON,
+			Result:         resultJSON,
+			Timestamp:      relativeTime,
+			DurationMs:     tc.DurationMs,
+			TimedOut:       tc.TimedOut,
+			Iteration:      tc.Iteration,
+			IterationStart: tc.Iteration > 0 && (i == 0 || run.Execution.ToolCalls[i-1].Iteration != tc.Iteration),
 		}
 	}
 
@@ -1367,28 +1999,87 @@ func buildTestRunView(run model.TestRun) TestRunView {
 
 	return TestRunView{
 		AgentName:          run.Execution.AgentName,
-		Provider:           string(run.Execution.ProviderType),
+		Provider:           run.Execution.ProviderLabel(),
 		Passed:             run.Passed,
 		DurationSeconds:    duration.Seconds(),
 		Assertions:         assertions,
 		Errors:             run.Execution.Errors,
 		Prompt:             prompt,
+		SystemPrompt:       run.Execution.SystemPrompt,
 		TokensUsed:         run.Execution.TokensUsed,
+		TokensEstimated:    run.Execution.TokensEstimated,
+		Tokenizer:          run.Execution.Tokenizer,
+		TokenBreakdown:     run.Execution.TokenBreakdown(),
 		FinalOutput:        run.Execution.FinalOutput,
+		TempDir:            run.Execution.TempDir,
+		StateReset:         run.Execution.StateReset,
+		RetryAttempts:      run.Execution.RetryAttempts,
+		MaxAttempts:        run.Execution.MaxAttempts,
 		Messages:           messages,
 		ToolCalls:          toolCalls,
+		LazyTranscript:     buildLazyTranscript(run, messages, toolCalls),
 		SequenceDiagram:    buildSequenceDiagram(run),
 		RateLimitStats:     buildRateLimitStatsView(run.Execution.RateLimitStats),
 		ClarificationStats: buildClarificationStatsView(run.Execution.ClarificationStats),
 	}
 }
 
+// GroupRuns returns the runs keyed by group instead of by agent: each copy
+// carries its group name as AgentName, so the leaderboard and the matrix
+// aggregate the group. The model of a run without an override is looked up
+// in the run metadata. Grouping by tag copies a run once per tag.
+func GroupRuns(results []model.TestRun, by GroupBy, metadata *model.RunMetadata) []model.TestRun {
+	if by == "" || by == GroupByAgent {
+		return results
+	}
+
+	providerModels := make(map[string]string)
+	if metadata != nil {
+		for _, p := range metadata.Providers {
+			providerModels[p.Name] = p.Model
+		}
+	}
+
+	grouped := make([]model.TestRun, 0, len(results))
+	regroup := func(run model.TestRun, group string) {
+		execution := *run.Execution
+		execution.AgentName = group
+		run.Execution = &execution
+		grouped = append(grouped, run)
+	}
+	for _, run := range results {
+		provider := string(run.Execution.ProviderType)
+		switch by {
+		case GroupByProvider:
+			regroup(run, provider)
+		case GroupByModel:
+			modelName := run.Execution.Model
+			if modelName == "" {
+				modelName = providerModels[provider]
+			}
+			if modelName == "" {
+				modelName = provider
+			}
+			regroup(run, modelName)
+		case GroupByTag:
+			if len(run.Execution.Tags) == 0 {
+				regroup(run, UntaggedGroup)
+			}
+			for _, tag := range run.Execution.Tags {
+				regroup(run, tag)
+			}
+		}
+	}
+	return grouped
+}
+
 func buildAgentStats(results []model.TestRun) []AgentStatsView {
 	statsMap := make(map[string]*AgentStatsView)
 	// Track sessions where agent passed at least one test: agent -> set of session names
 	agentSessionsPassed := make(map[string]map[string]bool)
 	// Track all unique sessions
 	allSessions := make(map[string]bool)
+	agentRuns := make(map[string][]model.TestRun)
 
 	for _, result := range results {
 		agentName := result.Execution.AgentName
@@ -1402,16 +2093,23 @@ func buildAgentStats(results []model.TestRun) []AgentStatsView {
 		if _, exists := statsMap[agentName]; !exists {
 			statsMap[agentName] = &AgentStatsView{
 				AgentName: agentName,
-				Provider:  string(result.Execution.ProviderType),
+				Provider:  result.Execution.ProviderLabel(),
 			}
 			agentSessionsPassed[agentName] = make(map[string]bool)
 		}
 
 		stats := statsMap[agentName]
+		// A regrouped row can hold runs of several providers
+		if label := result.Execution.ProviderLabel(); !slices.Contains(strings.Split(stats.Provider, ", "), label) {
+			stats.Provider += ", " + label
+		}
 		stats.TotalTests++
+		stats.TotalWeight += result.EffectiveWeight()
+		agentRuns[agentName] = append(agentRuns[agentName], result)
 
 		if result.Passed {
 			stats.PassedTests++
+			stats.PassedWeight += result.EffectiveWeight()
 			// Track session coverage
 			if sessionName != "" {
 				agentSessionsPassed[agentName][sessionName] = true
@@ -1427,6 +2125,11 @@ func buildAgentStats(results []model.TestRun) []AgentStatsView {
 		stats.TotalTokens += result.Execution.TokensUsed
 		duration := result.Execution.EndTime.Sub(result.Execution.StartTime).Seconds()
 		stats.TotalDuration += duration
+
+		stats.TotalToolCalls += len(result.Execution.ToolCalls)
+		for _, tc := range result.Execution.ToolCalls {
+			stats.TotalToolTime += float64(tc.DurationMs) / 1000
+		}
 	}
 
 	totalSessions := len(allSessions)
@@ -1437,8 +2140,11 @@ func buildAgentStats(results []model.TestRun) []AgentStatsView {
 		if stats.TotalTests > 0 {
 			stats.AvgTokens = stats.TotalTokens / stats.TotalTests
 			stats.AvgDuration = stats.TotalDuration / float64(stats.TotalTests)
+			stats.AvgToolCalls = float64(stats.TotalToolCalls) / float64(stats.TotalTests)
 			stats.SuccessRate = float64(stats.PassedTests) / float64(stats.TotalTests) * 100
 			stats.SuccessRateClass = getSuccessRateClass(stats.SuccessRate)
+			stats.P50LatencyMs, stats.P90LatencyMs, stats.P95LatencyMs = model.LatencyPercentiles(agentRuns[agentName])
+			stats.WeightedSuccessRate = stats.PassedWeight / stats.TotalWeight * 100
 
 			// Calculate efficiency (tokens per passed test)
 			if stats.PassedTests > 0 {
@@ -1513,6 +2219,26 @@ func buildAgentStats(results []model.TestRun) []AgentStatsView {
 	return statsList
 }
 
+// AgentRanking ranks agents the same way as the HTML leaderboard. In plain
+// console mode the medals are replaced by rank numbers.
+func AgentRanking(results []model.TestRun) []AgentStatsView {
+	stats := buildAgentStats(results)
+	if model.PlainConsole() {
+		for i := range stats {
+			if !stats[i].IsDisqualified {
+				stats[i].RankDisplay = fmt.Sprintf("%d", stats[i].Rank)
+			}
+		}
+	}
+	return stats
+}
+
+// FileGroups groups test results by source file with the same counting as the
+// HTML report, so other summaries agree with it.
+func FileGroups(results []model.TestRun) []FileGroupView {
+	return buildFileGroups(results)
+}
+
 // buildFileGroups groups test results by source file (for suite runs)
 func buildFileGroups(results []model.TestRun) []FileGroupView {
 	fileMap := make(map[string]*FileGroupView)
@@ -1585,10 +2311,12 @@ func buildFileGroups(results []model.TestRun) []FileGroupView {
 				}
 			}
 			assertions[i] = AssertionView{
-				Type:    a.Type,
-				Passed:  a.Passed,
-				Message: a.Message,
-				Details: detailsJSON,
+				Type:     a.Type,
+				Passed:   a.Passed,
+				Disabled: a.Disabled,
+				Warning:  a.IsWarning(),
+				Message:  a.Message,
+				Details:  detailsJSON,
 			}
 		}
 
@@ -1602,14 +2330,20 @@ func buildFileGroups(results []model.TestRun) []FileGroupView {
 
 		runView := TestRunView{
 			AgentName:          run.Execution.AgentName,
-			Provider:           string(run.Execution.ProviderType),
+			Provider:           run.Execution.ProviderLabel(),
 			Passed:             run.Passed,
 			DurationSeconds:    duration.Seconds(),
 			Assertions:         assertions,
 			Errors:             run.Execution.Errors,
 			Prompt:             prompt,
 			TokensUsed:         run.Execution.TokensUsed,
+			TokensEstimated:    run.Execution.TokensEstimated,
+			Tokenizer:          run.Execution.Tokenizer,
 			FinalOutput:        run.Execution.FinalOutput,
+			TempDir:            run.Execution.TempDir,
+			StateReset:         run.Execution.StateReset,
+			RetryAttempts:      run.Execution.RetryAttempts,
+			MaxAttempts:        run.Execution.MaxAttempts,
 			RateLimitStats:     buildRateLimitStatsView(run.Execution.RateLimitStats),
 			ClarificationStats: buildClarificationStatsView(run.Execution.ClarificationStats),
 		}
@@ -1761,10 +2495,12 @@ func buildSessionGroups(results []model.TestRun) []SessionGroupView {
 				}
 			}
 			assertions[i] = AssertionView{
-				Type:    a.Type,
-				Passed:  a.Passed,
-				Message: a.Message,
-				Details: detailsJSON,
+				Type:     a.Type,
+				Passed:   a.Passed,
+				Disabled: a.Disabled,
+				Warning:  a.IsWarning(),
+				Message:  a.Message,
+				Details:  detailsJSON,
 			}
 		}
 
@@ -1778,14 +2514,20 @@ func buildSessionGroups(results []model.TestRun) []SessionGroupView {
 
 		runView := TestRunView{
 			AgentName:          run.Execution.AgentName,
-			Provider:           string(run.Execution.ProviderType),
+			Provider:           run.Execution.ProviderLabel(),
 			Passed:             run.Passed,
 			DurationSeconds:    duration.Seconds(),
 			Assertions:         assertions,
 			Errors:             run.Execution.Errors,
 			Prompt:             prompt,
 			TokensUsed:         run.Execution.TokensUsed,
+			TokensEstimated:    run.Execution.TokensEstimated,
+			Tokenizer:          run.Execution.Tokenizer,
 			FinalOutput:        run.Execution.FinalOutput,
+			TempDir:            run.Execution.TempDir,
+			StateReset:         run.Execution.StateReset,
+			RetryAttempts:      run.Execution.RetryAttempts,
+			MaxAttempts:        run.Execution.MaxAttempts,
 			RateLimitStats:     buildRateLimitStatsView(run.Execution.RateLimitStats),
 			ClarificationStats: buildClarificationStatsView(run.Execution.ClarificationStats),
 		}
@@ -1848,7 +2590,7 @@ func buildSessionGroups(results []model.TestRun) []SessionGroupView {
 					first := true
 					for _, run := range agentRuns {
 						if stats.Provider == "" {
-							stats.Provider = string(run.Execution.ProviderType)
+							stats.Provider = run.Execution.ProviderLabel()
 						}
 						duration := run.Execution.EndTime.Sub(run.Execution.StartTime).Seconds()
 						tokens := run.Execution.TokensUsed
@@ -1937,7 +2679,17 @@ func buildRateLimitStatsView(stats *model.RateLimitStats) *RateLimitStatsView {
 		RetryCount:        stats.RetryCount,
 		RetryWaitSec:      float64(stats.RetryWaitTimeMs) / 1000.0,
 		RetrySuccessCount: stats.RetrySuccessCount,
+		RetryBackoffs:     formatBackoffs(stats.RetryBackoffsMs),
+	}
+}
+
+// formatBackoffs renders a retry backoff schedule as "1s → 2s → 4s".
+func formatBackoffs(backoffsMs []int64) string {
+	parts := make([]string, 0, len(backoffsMs))
+	for _, ms := range backoffsMs {
+		parts = append(parts, (time.Duration(ms) * time.Millisecond).String())
 	}
+	return strings.Join(parts, " → ")
 }
 
 // buildClarificationStatsView converts model.ClarificationStats to ClarificationStatsView
@@ -2022,12 +2774,32 @@ func buildMatrix(results []model.TestRun) MatrixView {
 		}
 
 		duration := run.Execution.EndTime.Sub(run.Execution.StartTime)
-		cells[testKey][agentName] = MatrixCell{
-			Passed:     run.Passed,
-			HasResult:  true,
-			DurationMs: float64(duration.Milliseconds()),
-			Tokens:     run.Execution.TokensUsed,
+		cell := MatrixCell{
+			Passed:          run.Passed,
+			HasResult:       true,
+			DurationMs:      float64(duration.Milliseconds()),
+			Tokens:          run.Execution.TokensUsed,
+			TokensEstimated: run.Execution.TokensEstimated,
+			ErrorCount:      len(run.Execution.Errors),
+			Runs:            1,
+		}
+		if run.Passed {
+			cell.PassedRuns = 1
+		}
+		for _, a := range run.Assertions {
+			if !a.FailsTest() {
+				continue
+			}
+			if cell.FailedAssertions == 0 {
+				cell.FirstFailure = fmt.Sprintf("[%s] %s", a.Type, a.Message)
+			}
+			cell.FailedAssertions++
+		}
+		if existing, ok := cells[testKey][agentName]; ok {
+			existing.merge(cell)
+			cell = existing
 		}
+		cells[testKey][agentName] = cell
 
 		// Build grouped structure (only add each test once per file/session)
 		if fileSessionTests[sourceFile] == nil {
@@ -2327,6 +3099,57 @@ func escapeMermaid(s string) string {
 	return s
 }
 
+// SessionDiagram is the Mermaid sequence diagram of one agent's tests in a session
+type SessionDiagram struct {
+	SourceFile string // Test file of the session in suite runs, empty otherwise
+	Session    string
+	Agent      string
+	Diagram    string // Mermaid syntax, as rendered in the HTML report
+}
+
+// SessionSequenceDiagrams returns the session diagrams of the HTML report, one
+// per session and agent, for exporting them on their own. Sessions of
+// different test files are kept apart even when they share a name. Sessions
+// keep their execution order and agents are sorted by name; skipped runs are
+// left out.
+func SessionSequenceDiagrams(results []model.TestRun) []SessionDiagram {
+	type sessionKey struct {
+		sourceFile string
+		session    string
+	}
+	var sessions []sessionKey
+	agentRuns := make(map[sessionKey]map[string][]model.TestRun) // [session][agent]
+	for _, run := range model.ExecutedRuns(results) {
+		key := sessionKey{sourceFile: run.Execution.SourceFile, session: run.Execution.SessionName}
+		if key.session == "" {
+			key.session = "default" // Single session run
+		}
+		if agentRuns[key] == nil {
+			agentRuns[key] = make(map[string][]model.TestRun)
+			sessions = append(sessions, key)
+		}
+		agentRuns[key][run.Execution.AgentName] = append(agentRuns[key][run.Execution.AgentName], run)
+	}
+
+	var diagrams []SessionDiagram
+	for _, key := range sessions {
+		agentNames := make([]string, 0, len(agentRuns[key]))
+		for name := range agentRuns[key] {
+			agentNames = append(agentNames, name)
+		}
+		sort.Strings(agentNames)
+		for _, agentName := range agentNames {
+			diagrams = append(diagrams, SessionDiagram{
+				SourceFile: key.sourceFile,
+				Session:    key.session,
+				Agent:      agentName,
+				Diagram:    buildSessionSequenceDiagram(agentRuns[key][agentName]),
+			})
+		}
+	}
+	return diagrams
+}
+
 // buildSessionSequenceDiagram generates a Mermaid diagram for an entire session
 func buildSessionSequenceDiagram(runs []model.TestRun) string {
 	if len(runs) == 0 {
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+const (
+	// DockerCommand is the container CLI used for docker servers
+	DockerCommand = "docker"
+	// DefaultDockerPath is the HTTP endpoint used when a docker server sets a port
+	DefaultDockerPath = "/mcp"
+	// dockerStderrLimit caps the container output kept for error messages
+	dockerStderrLimit = 4096
+)
+
+var containerNameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// NewDockerServer starts an MCP server in a container and connects to it, over
+// stdio by default or over streamable HTTP on a mapped port when config.Port is set.
+// The container is stopped and removed when the server is closed.
+func NewDockerServer(ctx context.Context, serverConfig model.Server) (*MCPServer, error) {
+	if serverConfig.Image == "" {
+		return nil, fmt.Errorf("invalid server configuration for %s: image is required for docker server type", serverConfig.Name)
+	}
+	if serverConfig.Port < 0 || serverConfig.Port > 65535 {
+		return nil, fmt.Errorf("invalid server configuration for %s: port %d out of range", serverConfig.Name, serverConfig.Port)
+	}
+	toolTimeout, err := parseToolTimeout(serverConfig.ToolTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server configuration for %s: %w", serverConfig.Name, err)
+	}
+	if _, err := exec.LookPath(DockerCommand); err != nil {
+		return nil, fmt.Errorf("docker server %s: %s CLI not found in PATH: %w", serverConfig.Name, DockerCommand, err)
+	}
+
+	if err := ensureDockerImage(ctx, serverConfig.Image); err != nil {
+		return nil, fmt.Errorf("docker server %s: %w", serverConfig.Name, err)
+	}
+
+	container := dockerContainerName(serverConfig.Name)
+	s := &MCPServer{
+		Name:         serverConfig.Name,
+		Type:         serverConfig.Type,
+		Command:      serverConfig.Image,
+		ServerDelay:  serverConfig.ServerDelay,
+		ProcessDelay: serverConfig.ProcessDelay,
+		ToolTimeout:  toolTimeout,
+		stop:         func() error { return removeDockerContainer(container) },
+	}
+
+	initDelay := DefaultServerInitDelay
+	if serverConfig.ServerDelay != "" {
+		initDelay, err = time.ParseDuration(serverConfig.ServerDelay)
+		if err != nil {
+			logger.Logger.Error("Failed to parse server delay")
+			initDelay = DefaultServerInitDelay
+		}
+	}
+	initCtx, cancel := context.WithTimeout(ctx, initDelay)
+	defer cancel()
+
+	logger.Logger.Info("Starting docker container",
+		"server_name", s.Name,
+		"image", serverConfig.Image,
+		"container", container,
+		"port", serverConfig.Port,
+	)
+
+	stderr := &tailBuffer{limit: dockerStderrLimit}
+	if serverConfig.Port == 0 {
+		stdioClient, err := mcpclient.NewStdioMCPClient(DockerCommand, nil, dockerRunArgs(serverConfig, container)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start container from image %s: %w", serverConfig.Image, err)
+		}
+		if r, ok := mcpclient.GetStderr(stdioClient); ok {
+			go io.Copy(stderr, r)
+		}
+		s.Client = stdioClient
+	} else {
+		if out, err := exec.CommandContext(ctx, DockerCommand, dockerRunArgs(serverConfig, container)...).CombinedOutput(); err != nil {
+			s.stopContainer()
+			return nil, fmt.Errorf("failed to start container from image %s: %w: %s", serverConfig.Image, err, strings.TrimSpace(string(out)))
+		}
+		hostPort, err := dockerHostPort(ctx, container, serverConfig.Port)
+		if err != nil {
+			s.stopContainer()
+			return nil, fmt.Errorf("container from image %s: %w", serverConfig.Image, err)
+		}
+		path := serverConfig.Path
+		if path == "" {
+			path = DefaultDockerPath
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		s.URL = "http://" + net.JoinHostPort("127.0.0.1", hostPort) + path
+		if err := waitForPort(initCtx, net.JoinHostPort("127.0.0.1", hostPort)); err != nil {
+			s.stopContainer()
+			return nil, fmt.Errorf("container from image %s did not open port %d: %w", serverConfig.Image, serverConfig.Port, err)
+		}
+		httpClient, err := mcpclient.NewStreamableHttpClient(s.URL)
+		if err != nil {
+			s.stopContainer()
+			return nil, fmt.Errorf("failed to create HTTP client for container from image %s: %w", serverConfig.Image, err)
+		}
+		s.Client = httpClient
+	}
+
+	if err := s.initializeClient(initCtx); err != nil {
+		logger.Logger.Error("MCP client initialization failed",
+			"server_name", s.Name,
+			"image", serverConfig.Image,
+			"error", err,
+		)
+		s.cleanup()
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return nil, fmt.Errorf("failed to initialize MCP server in container from image %s: %w: %s", serverConfig.Image, err, output)
+		}
+		return nil, fmt.Errorf("failed to initialize MCP server in container from image %s: %w", serverConfig.Image, err)
+	}
+
+	logger.Logger.Info("MCP server successfully initialized", "server_name", s.Name, "image", serverConfig.Image)
+	return s, nil
+}
+
+// dockerRunArgs builds the docker run arguments for a server. Stdio servers keep
+// stdin attached; port servers run detached with the port published on loopback.
+func dockerRunArgs(serverConfig model.Server, container string) []string {
+	args := []string{"run", "--rm", "--name", container}
+	if serverConfig.Port == 0 {
+		args = append(args, "-i")
+	} else {
+		args = append(args, "-d", "-p", "127.0.0.1::"+strconv.Itoa(serverConfig.Port))
+	}
+
+	// Sorted so the command line is stable across runs
+	keys := make([]string, 0, len(serverConfig.Env))
+	for k := range serverConfig.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+serverConfig.Env[k])
+	}
+
+	args = append(args, serverConfig.Image)
+	return append(args, serverConfig.Args...)
+}
+
+// ensureDockerImage pulls image unless it is already present locally.
+func ensureDockerImage(ctx context.Context, image string) error {
+	if err := exec.CommandContext(ctx, DockerCommand, "image", "inspect", image).Run(); err == nil {
+		return nil
+	}
+
+	logger.Logger.Info("Pulling docker image", "image", image)
+	out, err := exec.CommandContext(ctx, DockerCommand, "pull", image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w: %s", image, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// dockerHostPort returns the loopback port docker mapped to containerPort.
+func dockerHostPort(ctx context.Context, container string, containerPort int) (string, error) {
+	out, err := exec.CommandContext(ctx, DockerCommand, "port", container, strconv.Itoa(containerPort)).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to look up mapped port %d: %w: %s", containerPort, err, strings.TrimSpace(string(out)))
+	}
+	// Output is one mapping per line, e.g. "127.0.0.1:49153"
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if _, port, err := net.SplitHostPort(strings.TrimSpace(line)); err == nil && port != "" {
+			return port, nil
+		}
+	}
+	return "", fmt.Errorf("no mapping for port %d in %q", containerPort, strings.TrimSpace(string(out)))
+}
+
+// waitForPort polls addr until it accepts TCP connections or ctx is done.
+func waitForPort(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not reachable: %w", addr, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// removeDockerContainer force-removes a container. A container that is already
+// gone (stdio containers exit and remove themselves when stdin closes) is not an error.
+func removeDockerContainer(container string) error {
+	out, err := exec.Command(DockerCommand, "rm", "-f", container).CombinedOutput()
+	if err != nil && !strings.Contains(strings.ToLower(string(out)), "no such container") {
+		return fmt.Errorf("failed to remove container %s: %w: %s", container, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func dockerContainerName(serverName string) string {
+	name := strings.Trim(containerNameUnsafe.ReplaceAllString(serverName, "-"), "-._")
+	if name == "" {
+		name = "server"
+	}
+	return fmt.Sprintf("agent-bench-%s-%s", name, uuid.New().String()[:8])
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	limit int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf.Write(p)
+	if extra := t.buf.Len() - t.limit; extra > 0 {
+		t.buf.Next(extra)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.String()
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// NewProxyHTTPClient returns an HTTP client that sends requests through proxy.
+// When proxy is empty, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are read from the
+// environment at call time. It returns nil when no proxy is configured, so
+// callers can keep their library's default client.
+func NewProxyHTTPClient(proxy string) (*http.Client, error) {
+	proxyFunc, err := resolveProxyFunc(proxy)
+	if err != nil || proxyFunc == nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+	return &http.Client{Transport: transport}, nil
+}
+
+func resolveProxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+		}
+		return http.ProxyURL(proxyURL), nil
+	}
+
+	cfg := httpproxy.FromEnvironment()
+	if cfg.HTTPProxy == "" && cfg.HTTPSProxy == "" {
+		return nil, nil
+	}
+	envProxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return envProxy(req.URL)
+	}, nil
+}
@@ -0,0 +1,327 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+)
+
+// ToolRecording is the on-disk record of one server's tool list and tool calls.
+// Recordings are written by RecordingClient and served back by ReplayClient.
+type ToolRecording struct {
+	Server string         `json:"server"`
+	Tools  []mcp.Tool     `json:"tools"`
+	Calls  []RecordedCall `json:"calls"`
+}
+
+// RecordedCall is a single tool request and the response the server gave.
+type RecordedCall struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Result     json.RawMessage        `json:"result,omitempty"` // Raw mcp.CallToolResult
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// RecordingPath returns the file that holds the recording for a server.
+func RecordingPath(dir, serverName string) string {
+	return filepath.Join(dir, unsafeFileChars.ReplaceAllString(serverName, "_")+".json")
+}
+
+// LoadRecording reads the recording for a server from dir.
+func LoadRecording(dir, serverName string) (*ToolRecording, error) {
+	path := RecordingPath(dir, serverName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no recording for server '%s' (expected %s)", serverName, path)
+		}
+		return nil, fmt.Errorf("failed to read recording for server '%s': %w", serverName, err)
+	}
+	var recording ToolRecording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+	}
+	return &recording, nil
+}
+
+// callKey identifies a tool call by name and canonical JSON parameters, so
+// recorded and replayed calls match regardless of key order or whitespace.
+func callKey(name string, params map[string]interface{}) string {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	canonical, _ := json.Marshal(params)
+	return name + " " + string(canonical)
+}
+
+// requestParameters decodes the arguments of a tool request into a map.
+func requestParameters(request mcp.CallToolRequest) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if request.Params.Arguments == nil {
+		return params, nil
+	}
+	raw, err := json.Marshal(request.Params.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments for tool '%s': %w", request.Params.Name, err)
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("failed to decode arguments for tool '%s': %w", request.Params.Name, err)
+	}
+	return params, nil
+}
+
+// RecordingClient wraps a live MCP client and records its tool list and every
+// tool call. The recording is written to disk when the client is closed.
+type RecordingClient struct {
+	mcpclient.MCPClient
+	path      string
+	mu        sync.Mutex
+	recording ToolRecording
+}
+
+// NewRecordingClient wraps client so its tool traffic is recorded into dir.
+func NewRecordingClient(client mcpclient.MCPClient, dir, serverName string) *RecordingClient {
+	return &RecordingClient{
+		MCPClient: client,
+		path:      RecordingPath(dir, serverName),
+		recording: ToolRecording{Server: serverName, Tools: []mcp.Tool{}, Calls: []RecordedCall{}},
+	}
+}
+
+func (c *RecordingClient) ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	result, err := c.MCPClient.ListTools(ctx, request)
+	if err == nil && result != nil {
+		c.mu.Lock()
+		c.recording.Tools = result.Tools
+		c.mu.Unlock()
+	}
+	return result, err
+}
+
+func (c *RecordingClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	start := time.Now()
+	result, callErr := c.MCPClient.CallTool(ctx, request)
+
+	call := RecordedCall{
+		Name:       request.Params.Name,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	params, err := requestParameters(request)
+	if err != nil {
+		logger.Logger.Warn("Tool call not recorded", "tool", request.Params.Name, "error", err)
+		return result, callErr
+	}
+	call.Parameters = params
+	if callErr != nil {
+		call.Error = callErr.Error()
+	} else if result != nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			logger.Logger.Warn("Tool call not recorded", "tool", request.Params.Name, "error", err)
+			return result, callErr
+		}
+		call.Result = raw
+	}
+
+	c.mu.Lock()
+	c.recording.Calls = append(c.recording.Calls, call)
+	c.mu.Unlock()
+	return result, callErr
+}
+
+// Save writes the recording collected so far.
+func (c *RecordingClient) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.recording, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode recording for server '%s': %w", c.recording.Server, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recording %s: %w", c.path, err)
+	}
+	logger.Logger.Info("Tool recording saved", "server_name", c.recording.Server, "path", c.path,
+		"calls", len(c.recording.Calls))
+	return nil
+}
+
+// Close saves the recording and closes the wrapped client.
+func (c *RecordingClient) Close() error {
+	return errors.Join(c.Save(), c.MCPClient.Close())
+}
+
+// ReplayClient serves tool calls from a recording instead of a live server.
+// Calls are matched by tool name and parameters; repeated calls with the same
+// key are answered in recorded order, reusing the last response once exhausted.
+type ReplayClient struct {
+	server string
+	tools  []mcp.Tool
+	mu     sync.Mutex
+	calls  map[string][]RecordedCall
+	served map[string]int
+}
+
+// NewReplayClient builds a client that answers from recording.
+func NewReplayClient(recording *ToolRecording) *ReplayClient {
+	c := &ReplayClient{
+		server: recording.Server,
+		tools:  recording.Tools,
+		calls:  make(map[string][]RecordedCall),
+		served: make(map[string]int),
+	}
+	for _, call := range recording.Calls {
+		key := callKey(call.Name, call.Parameters)
+		c.calls[key] = append(c.calls[key], call)
+	}
+	return c
+}
+
+// NewReplayServer creates an MCPServer that serves tool calls recorded in dir
+// for serverConfig.Name without starting or connecting to the real server.
+func NewReplayServer(serverConfig model.Server, dir string) (*MCPServer, error) {
+	toolTimeout, err := parseToolTimeout(serverConfig.ToolTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server configuration for %s: %w", serverConfig.Name, err)
+	}
+	recording, err := LoadRecording(dir, serverConfig.Name)
+	if err != nil {
+		return nil, err
+	}
+	logger.Logger.Info("Replaying recorded MCP server",
+		"server_name", serverConfig.Name,
+		"tools", len(recording.Tools),
+		"calls", len(recording.Calls))
+	return &MCPServer{
+		Name:        serverConfig.Name,
+		Type:        serverConfig.Type,
+		Command:     serverConfig.Command,
+		URL:         serverConfig.URL,
+		Client:      NewReplayClient(recording),
+		ToolTimeout: toolTimeout,
+	}, nil
+}
+
+func (c *ReplayClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	params, err := requestParameters(request)
+	if err != nil {
+		return nil, err
+	}
+	key := callKey(request.Params.Name, params)
+
+	c.mu.Lock()
+	recorded, ok := c.calls[key]
+	idx := c.served[key]
+	if ok && idx < len(recorded)-1 {
+		c.served[key] = idx + 1
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		canonical, _ := json.Marshal(params)
+		return nil, fmt.Errorf("replay: no recorded call to tool '%s' with parameters %s on server '%s'",
+			request.Params.Name, canonical, c.server)
+	}
+	call := recorded[min(idx, len(recorded)-1)]
+	if call.Error != "" {
+		return nil, errors.New(call.Error)
+	}
+	var result mcp.CallToolResult
+	if err := json.Unmarshal(call.Result, &result); err != nil {
+		return nil, fmt.Errorf("replay: invalid recorded result for tool '%s': %w", call.Name, err)
+	}
+	return &result, nil
+}
+
+func (c *ReplayClient) ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	return &mcp.ListToolsResult{Tools: c.tools}, nil
+}
+
+func (c *ReplayClient) ListToolsByPage(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	return c.ListTools(ctx, request)
+}
+
+// Initialize is a no-op; there is no live server to handshake with
+func (c *ReplayClient) Initialize(ctx context.Context, request mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return &mcp.InitializeResult{
+		ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+		ServerInfo: mcp.Implementation{
+			Name:    c.server,
+			Version: "replay",
+		},
+	}, nil
+}
+
+func (c *ReplayClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *ReplayClient) ListResources(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+	return &mcp.ListResourcesResult{Resources: []mcp.Resource{}}, nil
+}
+
+func (c *ReplayClient) ListResourcesByPage(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+	return c.ListResources(ctx, request)
+}
+
+func (c *ReplayClient) ListResourceTemplates(ctx context.Context, request mcp.ListResourceTemplatesRequest) (*mcp.ListResourceTemplatesResult, error) {
+	return &mcp.ListResourceTemplatesResult{ResourceTemplates: []mcp.ResourceTemplate{}}, nil
+}
+
+func (c *ReplayClient) ListResourceTemplatesByPage(ctx context.Context, request mcp.ListResourceTemplatesRequest) (*mcp.ListResourceTemplatesResult, error) {
+	return c.ListResourceTemplates(ctx, request)
+}
+
+func (c *ReplayClient) ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	return nil, fmt.Errorf("replay: resources are not recorded")
+}
+
+func (c *ReplayClient) Subscribe(ctx context.Context, request mcp.SubscribeRequest) error {
+	return fmt.Errorf("replay: subscriptions are not supported")
+}
+
+func (c *ReplayClient) Unsubscribe(ctx context.Context, request mcp.UnsubscribeRequest) error {
+	return fmt.Errorf("replay: subscriptions are not supported")
+}
+
+func (c *ReplayClient) ListPrompts(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error) {
+	return &mcp.ListPromptsResult{Prompts: []mcp.Prompt{}}, nil
+}
+
+func (c *ReplayClient) ListPromptsByPage(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error) {
+	return c.ListPrompts(ctx, request)
+}
+
+func (c *ReplayClient) GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return nil, fmt.Errorf("replay: prompts are not recorded")
+}
+
+func (c *ReplayClient) SetLevel(ctx context.Context, request mcp.SetLevelRequest) error {
+	return nil
+}
+
+func (c *ReplayClient) Complete(ctx context.Context, request mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	return nil, fmt.Errorf("replay: completions are not recorded")
+}
+
+func (c *ReplayClient) OnNotification(handler func(notification mcp.JSONRPCNotification)) {}
+
+func (c *ReplayClient) Close() error {
+	return nil
+}
@@ -29,9 +29,14 @@ type MCPServer struct {
 	Command      string              `json:"command,omitempty"`
 	URL          string              `json:"url,omitempty"`
 	Headers      []string            `json:"headers,omitempty"`
+	Proxy        string              `json:"proxy,omitempty"`
 	Client       mcpclient.MCPClient `json:"-"`
 	ServerDelay  string
 	ProcessDelay string
+	ToolTimeout  time.Duration `json:"-"` // Default timeout for this server's tool calls; zero defers to the agent config
+
+	// stop releases resources the server owns beyond its client, e.g. a docker container
+	stop func() error
 }
 
 func NewMCPServer(ctx context.Context, serverConfig model.Server) (*MCPServer, error) {
@@ -44,10 +49,16 @@ func NewMCPServer(ctx context.Context, serverConfig model.Server) (*MCPServer, e
 		return nil, fmt.Errorf("context cannot be nil")
 	}
 
+	// Server types are matched case-insensitively, so "DOCKER" works like "docker"
+	serverConfig.Type = model.ServerType(strings.ToLower(strings.TrimSpace(string(serverConfig.Type))))
+
 	// Handle CLI server type separately
 	if serverConfig.Type == model.CLI {
 		return NewMCPServerFromCLI(ctx, serverConfig)
 	}
+	if serverConfig.Type == model.Docker {
+		return NewDockerServer(ctx, serverConfig)
+	}
 
 	s := &MCPServer{
 		Name:         serverConfig.Name,
@@ -55,10 +66,17 @@ func NewMCPServer(ctx context.Context, serverConfig model.Server) (*MCPServer, e
 		Command:      serverConfig.Command,
 		URL:          serverConfig.URL,
 		Headers:      serverConfig.Headers,
+		Proxy:        serverConfig.Proxy,
 		ServerDelay:  serverConfig.ServerDelay,
 		ProcessDelay: serverConfig.ProcessDelay,
 	}
 
+	toolTimeout, err := parseToolTimeout(serverConfig.ToolTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server configuration for %s: %w", serverConfig.Name, err)
+	}
+	s.ToolTimeout = toolTimeout
+
 	// Validate configuration
 	if err := s.validate(); err != nil {
 		logger.Logger.Error("Server configuration validation failed",
@@ -179,7 +197,7 @@ func (s *MCPServer) validate() error {
 		}
 
 	default:
-		return fmt.Errorf("unsupported server type: %s (expected: stdio, local, sse, http, or cli)", s.Type)
+		return fmt.Errorf("unsupported server type: %s (expected: stdio, local, sse, http, cli, or docker)", s.Type)
 	}
 
 	return nil
@@ -356,6 +374,15 @@ func (s *MCPServer) createSSEClient(ctx context.Context) (mcpclient.MCPClient, e
 		}
 	}
 
+	httpClient, err := NewProxyHTTPClient(s.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		options = append(options, transport.WithHTTPClient(httpClient))
+		logger.Logger.Debug("Using proxy for SSE client", "server_name", s.Name)
+	}
+
 	sseClient, err := client.NewSSEMCPClient(s.URL, options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSE client: %w", err)
@@ -376,7 +403,16 @@ func (s *MCPServer) createStreamableHttpClient() (mcpclient.MCPClient, error) {
 		"server_name", s.Name,
 		"url", s.URL,
 	)
-	httpClient, err := mcpclient.NewStreamableHttpClient(s.URL, nil)
+	proxyClient, err := NewProxyHTTPClient(s.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	var options []transport.StreamableHTTPCOption
+	if proxyClient != nil {
+		options = append(options, transport.WithHTTPBasicClient(proxyClient))
+		logger.Logger.Debug("Using proxy for Streamable HTTP client", "server_name", s.Name)
+	}
+	httpClient, err := mcpclient.NewStreamableHttpClient(s.URL, options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stdio client: %w", err)
 	}
@@ -394,6 +430,7 @@ func (s *MCPServer) createStreamableHttpClient() (mcpclient.MCPClient, error) {
 }
 
 func (s *MCPServer) cleanup() {
+	defer s.stopContainer()
 	if s.Client == nil {
 		return
 	}
@@ -420,6 +457,7 @@ func (s *MCPServer) Close() error {
 	}
 
 	logger.Logger.Info("Closing MCP server", "server_name", s.Name)
+	defer s.stopContainer()
 
 	if closer, ok := s.Client.(interface{ Close() error }); ok {
 		err := closer.Close()
@@ -439,6 +477,18 @@ func (s *MCPServer) Close() error {
 	return fmt.Errorf("client does not implement Close() interface")
 }
 
+// stopContainer runs the server's stop hook once, logging failures.
+func (s *MCPServer) stopContainer() {
+	if s.stop == nil {
+		return
+	}
+	stop := s.stop
+	s.stop = nil
+	if err := stop(); err != nil {
+		logger.Logger.Warn("Failed to stop server container", "server_name", s.Name, "error", err)
+	}
+}
+
 func (s *MCPServer) IsHealthy(ctx context.Context) bool {
 	if s.Client == nil {
 		logger.Logger.Debug("Health check failed: client is nil", "server_name", s.Name)
@@ -477,11 +527,31 @@ func (s *MCPServer) GetInfo() map[string]interface{} {
 	case model.CLI:
 		info["command"] = s.Command
 		info["cli"] = true
+	case model.Docker:
+		info["image"] = s.Command
+		if s.URL != "" {
+			info["url"] = s.URL
+		}
 	}
 
 	return info
 }
 
+// parseToolTimeout parses a server's tool_timeout. Empty means no server-level timeout.
+func parseToolTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tool_timeout %q: %w", value, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid tool_timeout %q: must not be negative", value)
+	}
+	return timeout, nil
+}
+
 // NewMCPServerFromCLI creates an MCPServer that wraps a CLI tool.
 // This allows CLI-based tools to be tested using the same framework as MCP servers.
 func NewMCPServerFromCLI(ctx context.Context, serverConfig model.Server) (*MCPServer, error) {
@@ -490,6 +560,11 @@ func NewMCPServerFromCLI(ctx context.Context, serverConfig model.Server) (*MCPSe
 		"command", serverConfig.Command,
 	)
 
+	toolTimeout, err := parseToolTimeout(serverConfig.ToolTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server configuration for %s: %w", serverConfig.Name, err)
+	}
+
 	// Create the CLI server
 	cliServer, err := NewCLIServer(ctx, serverConfig)
 	if err != nil {
@@ -504,6 +579,7 @@ func NewMCPServerFromCLI(ctx context.Context, serverConfig model.Server) (*MCPSe
 		Client:       cliServer.GetClient(),
 		ServerDelay:  serverConfig.ServerDelay,
 		ProcessDelay: serverConfig.ProcessDelay,
+		ToolTimeout:  toolTimeout,
 	}
 
 	logger.Logger.Info("CLI server wrapper created successfully",
@@ -1,9 +1,9 @@
 package templates
 
 import (
-	"crypto/rand"
 	"fmt"
-	"math/big"
+	"hash/fnv"
+	"math/rand/v2"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,6 +29,91 @@ var (
 	templateEngineOnce     sync.Once
 )
 
+// RandomStreamVar is the template variable naming the random stream the
+// helpers draw from. The engine sets it to the identity of the running test, so
+// a test's values depend only on the seed and the test, not on what else runs
+// concurrently. Templates rendered without it share the unnamed stream.
+const RandomStreamVar = "RANDOM_STREAM"
+
+// RandomStreamData is the private template data (@randomStream) that carries
+// RandomStreamVar into the helpers, including inside blocks.
+const RandomStreamData = "randomStream"
+
+// randomStreams are the sources of the random helpers, one per stream name,
+// each derived from the run seed and the name. Helpers may be called from
+// concurrent agents.
+var (
+	randomMu      sync.Mutex
+	randomSeed    = uint64(time.Now().UnixNano())
+	randomStreams = make(map[string]*rand.Rand)
+)
+
+// SetSeed reseeds the random helpers (randomValue, randomInt, randomDecimal and
+// faker). Each stream then produces the same values, in the same order, for the
+// same seed.
+func SetSeed(seed int64) {
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	randomSeed = uint64(seed)
+	randomStreams = make(map[string]*rand.Rand)
+}
+
+// randomSource is a named random stream.
+type randomSource string
+
+// randomSourceOf returns the stream a helper call draws from.
+func randomSourceOf(options *raymond.Options) randomSource {
+	name, _ := options.Data(RandomStreamData).(string)
+	return randomSource(name)
+}
+
+// with runs fn with the stream's generator, creating it on first use.
+func (s randomSource) with(fn func(r *rand.Rand)) {
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	r, ok := randomStreams[string(s)]
+	if !ok {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		r = rand.New(rand.NewPCG(randomSeed, h.Sum64()))
+		randomStreams[string(s)] = r
+	}
+	fn(r)
+}
+
+// IntN returns a random number in [0, n).
+func (s randomSource) IntN(n int) (v int) {
+	s.with(func(r *rand.Rand) { v = r.IntN(n) })
+	return v
+}
+
+// Float64 returns a random number in [0, 1).
+func (s randomSource) Float64() (v float64) {
+	s.with(func(r *rand.Rand) { v = r.Float64() })
+	return v
+}
+
+// Uint64 returns a random non-zero number, e.g. to seed a faker (0 would make
+// gofakeit pick its own random seed).
+func (s randomSource) Uint64() (v uint64) {
+	s.with(func(r *rand.Rand) {
+		for v == 0 {
+			v = r.Uint64()
+		}
+	})
+	return v
+}
+
+// Read reads bytes from the stream, for UUIDs.
+func (s randomSource) Read(p []byte) (int, error) {
+	s.with(func(r *rand.Rand) {
+		for i := range p {
+			p[i] = byte(r.Uint32())
+		}
+	})
+	return len(p), nil
+}
+
 // NewTemplateEngine returns the singleton instance of TemplateEngine
 func NewTemplateEngine() *TemplateEngine {
 	templateEngineOnce.Do(func() {
@@ -43,6 +128,8 @@ func NewTemplateEngine() *TemplateEngine {
 func RegisterHelpers() {
 	// Register random value helper
 	raymond.RegisterHelper("randomValue", func(options *raymond.Options) string {
+		random := randomSourceOf(options)
+
 		// Get type from hash arguments (default: ALPHANUMERIC)
 		randomType := strings.ToUpper(options.HashStr("type"))
 		if randomType == "" {
@@ -51,7 +138,7 @@ func RegisterHelpers() {
 
 		// Handle UUID separately
 		if randomType == "UUID" {
-			return uuid.New().String()
+			return uuid.Must(uuid.NewRandomFromReader(random)).String()
 		}
 
 		// Get length from hash arguments (default: 10)
@@ -79,18 +166,18 @@ func RegisterHelpers() {
 		var result string
 		switch randomType {
 		case "ALPHANUMERIC":
-			result = generateRandomString(alphanumericChars, length)
+			result = generateRandomString(random, alphanumericChars, length)
 		case "ALPHABETIC":
-			result = generateRandomString(alphabeticChars, length)
+			result = generateRandomString(random, alphabeticChars, length)
 		case "NUMERIC":
-			result = generateRandomString(numericChars, length)
+			result = generateRandomString(random, numericChars, length)
 		case "HEXADECIMAL":
-			result = generateRandomString(hexChars, length)
+			result = generateRandomString(random, hexChars, length)
 		case "ALPHANUMERIC_AND_SYMBOLS":
 			chars := alphanumericChars + symbolChars
-			result = generateRandomString(chars, length)
+			result = generateRandomString(random, chars, length)
 		default:
-			result = generateRandomString(alphanumericChars, length)
+			result = generateRandomString(random, alphanumericChars, length)
 		}
 
 		// Apply uppercase if requested
@@ -123,12 +210,7 @@ func RegisterHelpers() {
 
 		// Generate random integer in range [lower, upper]
 		rangeSize := upper - lower + 1
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(rangeSize)))
-		if err != nil {
-			return "0"
-		}
-
-		result := int(num.Int64()) + lower
+		result := randomSourceOf(options).IntN(rangeSize) + lower
 		return fmt.Sprintf("%d", result)
 	})
 	// Register randomDecimal helper
@@ -155,24 +237,8 @@ func RegisterHelpers() {
 		// Generate random decimal in range [lower, upper]
 		rangeSize := upper - lower
 
-		// Generate random bytes for precision
-		randomBytes := make([]byte, 8)
-		_, err := rand.Read(randomBytes)
-		if err != nil {
-			return "0"
-		}
-
-		// Convert to uint64 and normalize to [0, 1]
-		var randomUint64 uint64
-		for i := 0; i < 8; i++ {
-			randomUint64 = (randomUint64 << 8) | uint64(randomBytes[i])
-		}
-
-		// Normalize to [0, 1]
-		normalized := float64(randomUint64) / float64(^uint64(0))
-
-		// Scale to [lower, upper]
-		result := lower + (normalized * rangeSize)
+		// Scale [0, 1) to [lower, upper]
+		result := lower + (randomSourceOf(options).Float64() * rangeSize)
 
 		return fmt.Sprintf("%.2f", result)
 	})
@@ -215,8 +281,8 @@ func RegisterHelpers() {
 		}
 	})
 	// faker helper
-	raymond.RegisterHelper("faker", func(key string) string {
-		r := gofakeit.New(0)
+	raymond.RegisterHelper("faker", func(key string, options *raymond.Options) string {
+		r := gofakeit.New(randomSourceOf(options).Uint64())
 
 		parts := strings.Split(key, ".")
 		category := parts[0]
@@ -428,17 +494,12 @@ func RegisterHelpers() {
 	})
 }
 
-// generateRandomString generates a cryptographically secure random string
-func generateRandomString(charset string, length int) string {
+// generateRandomString generates a random string from the seeded source.
+// The values are test data and are not suitable as secrets.
+func generateRandomString(random randomSource, charset string, length int) string {
 	result := make([]byte, length)
-	charsetLen := big.NewInt(int64(len(charset)))
-
 	for i := 0; i < length; i++ {
-		num, err := rand.Int(rand.Reader, charsetLen)
-		if err != nil {
-			return ""
-		}
-		result[i] = charset[num.Int64()]
+		result[i] = charset[random.IntN(len(charset))]
 	}
 
 	return string(result)
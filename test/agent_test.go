@@ -15,8 +15,10 @@ import (
 	"github.com/mykhaliev/agent-benchmark/logger"
 	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/mykhaliev/agent-benchmark/tokenizer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/openai"
 )
@@ -251,6 +253,37 @@ func TestExtractToolsFromAgent(t *testing.T) {
 	assert.Contains(t, required, "param")
 }
 
+func TestToolParameters(t *testing.T) {
+	agent := &agent.MCPAgent{
+		MCPServerTools: map[string][]mcp.Tool{
+			"server1": {
+				{
+					Name: "tool1",
+					InputSchema: mcp.ToolInputSchema{
+						Type: "object",
+						Properties: map[string]interface{}{
+							"path":    map[string]interface{}{"type": "string"},
+							"content": map[string]interface{}{"type": "string"},
+						},
+					},
+				},
+			},
+			"server2": {
+				{
+					Name:        "tool2",
+					InputSchema: mcp.ToolInputSchema{Type: "object"},
+				},
+			},
+		},
+	}
+
+	params := agent.ToolParameters()
+
+	assert.ElementsMatch(t, []string{"path", "content"}, params["tool1"])
+	assert.Contains(t, params, "tool2")
+	assert.Empty(t, params["tool2"])
+}
+
 func TestGenerateContentWithConfig_Success(t *testing.T) {
 	logger.SetupLogger(NewDummyWriter(), true)
 	ctx := context.Background()
@@ -308,10 +341,72 @@ func TestGenerateContentWithConfig_Success(t *testing.T) {
 	assert.Equal(t, "This is the final answer", result.FinalOutput)
 	assert.Equal(t, 0, len(result.Errors))
 	assert.Equal(t, 50, result.TokensUsed)
+	assert.False(t, result.TokensEstimated)
 
 	mockLLM.AssertExpectations(t)
 }
 
+func TestGenerateContentWithConfig_EstimatesMissingUsage(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	mockLLM := new(MockLLMModel)
+	mockClient := new(MockMCPClient)
+
+	testTools := createTestTools()
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mcpServer := createMockServer("test_server", testTools)
+	mcpServer.Client = mockClient
+
+	mcpAgent := agent.NewMCPAgent(ctx, "test_agent", []model.AgentServer{{Name: "test_server"}},
+		[]*server.MCPServer{mcpServer}, "test_provider", mockLLM)
+
+	// No GenerationInfo: the provider reported no usage
+	mockLLM.On("GenerateContent", ctx, mock.Anything, mock.Anything).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "The weather in Paris is sunny today.", StopReason: "stop"}},
+	}, nil)
+
+	msgs := []llms.MessageContent{
+		{
+			Role:  llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{llms.TextContent{Text: "What is the weather in Paris?"}},
+		},
+	}
+
+	result := mcpAgent.GenerateContentWithConfig(ctx, &msgs, agent.AgentConfig{MaxIterations: 5}, mcpAgent.ExtractToolsFromAgent())
+
+	assert.True(t, result.TokensEstimated)
+	assert.Equal(t, tokenizer.Default.Name(), result.Tokenizer)
+	assert.Greater(t, result.PromptTokens, 0)
+	assert.Greater(t, result.CompletionTokens, 0)
+	assert.Equal(t, result.PromptTokens+result.CompletionTokens, result.TokensUsed)
+
+	// The estimate covers only the prompt that was sent, not the answer appended afterwards
+	promptTokens, _ := agent.EstimateTokenCount(nil, msgs[:1], nil)
+	assert.Equal(t, promptTokens, result.PromptTokens)
+}
+
+func TestEstimateTokenCount(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	prompt := []llms.MessageContent{
+		{Role: llms.ChatMessageTypeHuman, Parts: []llms.ContentPart{llms.TextContent{Text: "List the files"}}},
+		{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{llms.ToolCallResponse{Name: "list", Content: "a.txt b.txt"}}},
+	}
+	response := &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			ToolCalls: []llms.ToolCall{{FunctionCall: &llms.FunctionCall{Name: "read_file", Arguments: `{"path":"a.txt"}`}}},
+		}},
+	}
+
+	promptTokens, completionTokens := agent.EstimateTokenCount(nil, prompt, response)
+	assert.Greater(t, promptTokens, 0)
+	assert.Greater(t, completionTokens, 0, "tool call arguments count toward the completion")
+
+	promptOnly, noCompletion := agent.EstimateTokenCount(nil, prompt, nil)
+	assert.Equal(t, promptTokens, promptOnly)
+	assert.Equal(t, 0, noCompletion)
+	assert.Equal(t, 0, agent.ReportedTokenCount(response))
+}
+
 func TestGenerateContentWithConfig_WithToolCalls(t *testing.T) {
 	logger.SetupLogger(NewDummyWriter(), true)
 	ctx := context.Background()
@@ -408,6 +503,57 @@ func TestGenerateContentWithConfig_WithToolCalls(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestGenerateContentWithConfig_ToolChoice(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	mockLLM := new(MockLLMModel)
+	mockClient := new(MockMCPClient)
+
+	testTools := createTestTools()
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mockClient.On("CallTool", ctx, mock.Anything).Return(&mcp.CallToolResult{
+		Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Success"}},
+	}, nil)
+	mcpServer := createMockServer("test_server", testTools)
+	mcpServer.Client = mockClient
+	mcpAgent := agent.NewMCPAgent(ctx, "test_agent", []model.AgentServer{{Name: "test_server"}},
+		[]*server.MCPServer{mcpServer}, "test_provider", mockLLM)
+
+	mockLLM.On("GenerateContent", ctx, mock.Anything, mock.Anything).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			ToolCalls: []llms.ToolCall{{
+				ID:           "call_1",
+				FunctionCall: &llms.FunctionCall{Name: "test_tool_1", Arguments: `{"param1": "x"}`},
+			}},
+		}},
+	}, nil).Once()
+	mockLLM.On("GenerateContent", ctx, mock.Anything, mock.Anything).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "done", StopReason: "stop"}},
+	}, nil).Once()
+
+	msgs := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Use the tool")}
+	config := agent.AgentConfig{
+		MaxIterations:    5,
+		ToolChoice:       model.ToolChoiceRequired,
+		ToolChoiceOption: model.ToolChoiceRequired,
+	}
+	result := mcpAgent.GenerateContentWithConfig(ctx, &msgs, config, mcpAgent.ExtractToolsFromAgent())
+
+	assert.Equal(t, "done", result.FinalOutput)
+	assert.Equal(t, model.ToolChoiceRequired, result.ToolChoice)
+	require.Len(t, mockLLM.Calls, 2)
+	callOptions := func(call mock.Call) llms.CallOptions {
+		var opts llms.CallOptions
+		for _, opt := range call.Arguments.Get(2).([]llms.CallOption) {
+			opt(&opts)
+		}
+		return opts
+	}
+	// The tool is forced on the first call only, so the model can answer afterwards
+	assert.Equal(t, model.ToolChoiceRequired, callOptions(mockLLM.Calls[0]).ToolChoice)
+	assert.Nil(t, callOptions(mockLLM.Calls[1]).ToolChoice)
+}
+
 func TestGenerateContentWithConfig_MaxIterations(t *testing.T) {
 	logger.SetupLogger(NewDummyWriter(), true)
 	ctx := context.Background()
@@ -569,6 +715,35 @@ func TestGetTokenCount(t *testing.T) {
 	}
 }
 
+func TestAddTokenBreakdown(t *testing.T) {
+	result := model.ExecutionResult{}
+	resp := &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				GenerationInfo: map[string]interface{}{
+					"InputTokens":              120,
+					"OutputTokens":             30,
+					"CacheReadInputTokens":     100,
+					"CacheCreationInputTokens": 20,
+				},
+			},
+		},
+	}
+
+	agent.AddTokenBreakdown(&result, resp)
+	agent.AddTokenBreakdown(&result, &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{GenerationInfo: map[string]interface{}{"PromptTokens": 10, "CompletionTokens": 5}},
+		},
+	})
+	agent.AddTokenBreakdown(&result, nil)
+
+	assert.Equal(t, 130, result.PromptTokens)
+	assert.Equal(t, 35, result.CompletionTokens)
+	assert.Equal(t, 100, result.CacheReadTokens)
+	assert.Equal(t, 20, result.CacheWriteTokens)
+}
+
 func TestValidateAndParseArguments(t *testing.T) {
 	logger.SetupLogger(NewDummyWriter(), true)
 	tests := []struct {
@@ -708,6 +883,155 @@ func TestExecuteToolWithTimeout(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+// newHangingToolAgent returns an agent whose only server never answers a tool
+// call, ignoring cancellation like a hung MCP server would.
+func newHangingToolAgent(t *testing.T, ctx context.Context, serverTimeout time.Duration) (*agent.MCPAgent, *MockLLMModel) {
+	mockLLM := new(MockLLMModel)
+	mockClient := new(MockMCPClient)
+	testTools := createTestTools()
+
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{
+		Tools: testTools,
+	}, nil)
+	mockClient.On("CallTool", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		<-release
+	}).Return(&mcp.CallToolResult{}, nil)
+
+	mcpServer := createMockServer("test_server", testTools)
+	mcpServer.Client = mockClient
+	mcpServer.ToolTimeout = serverTimeout
+
+	agentServers := []model.AgentServer{
+		{
+			Name:         "test_server",
+			AllowedTools: []string{"test_tool_1"},
+		},
+	}
+
+	return agent.NewMCPAgent(ctx, "test_agent", agentServers, []*server.MCPServer{mcpServer}, "test_provider", mockLLM), mockLLM
+}
+
+func TestExecuteToolWithTimeout_HangingTool(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		serverTimeout time.Duration
+		config        agent.AgentConfig
+		expected      time.Duration
+	}{
+		{
+			name:     "default timeout",
+			config:   agent.AgentConfig{ToolTimeout: 50 * time.Millisecond},
+			expected: 50 * time.Millisecond,
+		},
+		{
+			name:          "server timeout overrides default",
+			serverTimeout: 60 * time.Millisecond,
+			config:        agent.AgentConfig{ToolTimeout: time.Hour},
+			expected:      60 * time.Millisecond,
+		},
+		{
+			name:          "test timeout overrides server",
+			serverTimeout: time.Hour,
+			config:        agent.AgentConfig{ToolTimeout: time.Hour, TestToolTimeout: 70 * time.Millisecond},
+			expected:      70 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mcpAgent, _ := newHangingToolAgent(t, ctx, tt.serverTimeout)
+			toolCall := llms.ToolCall{
+				ID: "test_call",
+				FunctionCall: &llms.FunctionCall{
+					Name:      "test_tool_1",
+					Arguments: `{"param1": "value"}`,
+				},
+			}
+
+			result, response, err := mcpAgent.ExecuteToolWithTimeout(ctx, toolCall, tt.config, 1, 1, 1)
+
+			require.Error(t, err)
+			assert.True(t, agent.IsToolTimeout(err))
+			assert.Contains(t, err.Error(), "timed out after "+tt.expected.String())
+			assert.Contains(t, response, "timed out")
+			assert.True(t, result.TimedOut)
+			assert.Equal(t, "test_tool_1", result.Name)
+			assert.GreaterOrEqual(t, result.DurationMs, tt.expected.Milliseconds())
+		})
+	}
+}
+
+func TestGenerateContentWithConfig_ToolTimeoutPolicy(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+
+	toolCallResponse := &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				ToolCalls: []llms.ToolCall{
+					{
+						ID: "call_1",
+						FunctionCall: &llms.FunctionCall{
+							Name:      "test_tool_1",
+							Arguments: `{"param1": "test_value"}`,
+						},
+					},
+				},
+			},
+		},
+	}
+	finalResponse := &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{Content: "The tool did not answer", StopReason: "stop"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		failOnTimeout bool
+		llmCalls      int
+	}{
+		{name: "continue after timeout", failOnTimeout: false, llmCalls: 2},
+		{name: "stop after timeout", failOnTimeout: true, llmCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mcpAgent, mockLLM := newHangingToolAgent(t, ctx, 0)
+			mockLLM.On("GenerateContent", ctx, mock.Anything, mock.Anything).Return(toolCallResponse, nil).Once()
+			if tt.llmCalls > 1 {
+				mockLLM.On("GenerateContent", ctx, mock.Anything, mock.Anything).Return(finalResponse, nil).Once()
+			}
+
+			msgs := []llms.MessageContent{
+				{
+					Role:  llms.ChatMessageTypeHuman,
+					Parts: []llms.ContentPart{llms.TextContent{Text: "Use the tool"}},
+				},
+			}
+			config := agent.AgentConfig{
+				MaxIterations:     5,
+				ToolTimeout:       50 * time.Millisecond,
+				FailOnToolTimeout: tt.failOnTimeout,
+			}
+
+			result := mcpAgent.GenerateContentWithConfig(ctx, &msgs, config, mcpAgent.ExtractToolsFromAgent())
+
+			require.Len(t, result.ToolCalls, 1)
+			assert.True(t, result.ToolCalls[0].TimedOut)
+			assert.Equal(t, 1, result.ToolCalls[0].Iteration)
+			assert.Equal(t, 1, result.CountErrorKind(model.ErrorKindToolTimeout))
+			mockLLM.AssertNumberOfCalls(t, "GenerateContent", tt.llmCalls)
+		})
+	}
+}
+
 // TestCheckClarificationWithLLM tests the LLM-based clarification detection function
 func TestCheckClarificationWithLLM(t *testing.T) {
 	logger.SetupLogger(NewDummyWriter(), true)
@@ -1159,3 +1483,82 @@ Let me know if you need to save the changes or do more.`,
 	})
 }
 
+func TestLLMJudge_Grade(t *testing.T) {
+	ctx := context.Background()
+	respond := func(content string) *llms.ContentResponse {
+		return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: content}}}
+	}
+
+	t.Run("Parses fenced JSON verdict", func(t *testing.T) {
+		mockLLM := new(MockLLMModel)
+		mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+			Return(respond("```json\n{\"pass\": true, \"score\": 0.9, \"reason\": \"correct\"}\n```"), nil)
+
+		judge := agent.NewLLMJudge("gpt", mockLLM)
+		verdict, err := judge.Grade(ctx, "names the capital", "Paris")
+		assert.NoError(t, err)
+		assert.Equal(t, "gpt", judge.Name())
+		assert.Equal(t, model.JudgeVerdict{Pass: true, Score: 0.9, Reason: "correct"}, verdict)
+	})
+
+	t.Run("Rejects non-JSON response", func(t *testing.T) {
+		mockLLM := new(MockLLMModel)
+		mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).Return(respond("Looks good"), nil)
+
+		_, err := agent.NewLLMJudge("gpt", mockLLM).Grade(ctx, "rubric", "output")
+		assert.ErrorContains(t, err, "not JSON")
+	})
+
+	t.Run("Propagates LLM errors", func(t *testing.T) {
+		mockLLM := new(MockLLMModel)
+		mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("unavailable"))
+
+		_, err := agent.NewLLMJudge("gpt", mockLLM).Grade(ctx, "rubric", "output")
+		assert.ErrorContains(t, err, "unavailable")
+	})
+}
+
+func TestLLMJudge_Compare(t *testing.T) {
+	ctx := context.Background()
+	respond := func(content string) *llms.ContentResponse {
+		return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: content}}}
+	}
+
+	t.Run("Normalizes the winner", func(t *testing.T) {
+		var prompt []llms.MessageContent
+		mockLLM := new(MockLLMModel)
+		mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { prompt = args.Get(1).([]llms.MessageContent) }).
+			Return(respond(`{"winner": "b", "reason": "more complete"}`), nil)
+
+		verdict, err := agent.NewLLMJudge("gpt", mockLLM).Compare(ctx, "", "Capital of France?", "Lyon", "Paris")
+		assert.NoError(t, err)
+		assert.Equal(t, model.PairwiseVerdict{Winner: "B", Reason: "more complete"}, verdict)
+		require.Len(t, prompt, 2)
+		assert.Contains(t, prompt[0].Parts[0].(llms.TextContent).Text, agent.DefaultPairwisePrompt)
+		assert.Contains(t, prompt[1].Parts[0].(llms.TextContent).Text, "Answer B:\nParis")
+	})
+
+	t.Run("Uses custom instructions", func(t *testing.T) {
+		var prompt []llms.MessageContent
+		mockLLM := new(MockLLMModel)
+		mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { prompt = args.Get(1).([]llms.MessageContent) }).
+			Return(respond(`{"winner": "TIE"}`), nil)
+
+		verdict, err := agent.NewLLMJudge("gpt", mockLLM).Compare(ctx, "Prefer the shorter answer.", "Hi", "Hello", "Hey")
+		assert.NoError(t, err)
+		assert.Equal(t, "tie", verdict.Winner)
+		assert.Contains(t, prompt[0].Parts[0].(llms.TextContent).Text, "Prefer the shorter answer.")
+		assert.NotContains(t, prompt[0].Parts[0].(llms.TextContent).Text, agent.DefaultPairwisePrompt)
+	})
+
+	t.Run("Rejects an unknown winner", func(t *testing.T) {
+		mockLLM := new(MockLLMModel)
+		mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+			Return(respond(`{"winner": "both"}`), nil)
+
+		_, err := agent.NewLLMJudge("gpt", mockLLM).Compare(ctx, "", "Hi", "a", "b")
+		assert.ErrorContains(t, err, "invalid pairwise winner")
+	})
+}
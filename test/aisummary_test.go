@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestAISummaryJudges(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		models = append(models, body.Model)
+		mu.Unlock()
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	providers := `
+providers:
+  - name: agent-provider
+    type: OPENAI
+    token: t
+    model: agent-model
+    baseUrl: ` + srv.URL + `/v1
+agents:
+  - name: a1
+    provider: agent-provider
+    model: override-model
+sessions:
+  - name: s
+    tests:
+      - name: t
+        prompt: hi
+`
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "tests.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	results := []model.TestRun{{Execution: &model.ExecutionResult{TestName: "t", AgentName: "a1"}}}
+	modelOf := func(judges []llms.Model) string {
+		require.Len(t, judges, 1)
+		mu.Lock()
+		models = nil
+		mu.Unlock()
+		_, _ = judges[0].GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")})
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, models)
+		return models[0]
+	}
+
+	t.Run("top-level judge", func(t *testing.T) {
+		path := write(providers + `
+ai_summary:
+  enabled: true
+  judge_provider: agent-provider
+judge:
+  type: OPENAI
+  token: t
+  model: judge-model
+  baseUrl: ` + srv.URL + `/v1
+`)
+		judges, err := engine.AISummaryJudges(ctx, path, results)
+		require.NoError(t, err)
+		assert.Equal(t, "judge-model", modelOf(judges), "the top-level judge wins over judge_provider")
+	})
+
+	t.Run("$self uses the agent's model", func(t *testing.T) {
+		path := write(providers + `
+ai_summary:
+  enabled: true
+  judge_provider: $self
+`)
+		judges, err := engine.AISummaryJudges(ctx, path, results)
+		require.NoError(t, err)
+		assert.Equal(t, "override-model", modelOf(judges))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		judges, err := engine.AISummaryJudges(ctx, write(providers), results)
+		require.NoError(t, err)
+		assert.Empty(t, judges)
+	})
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mykhaliev/agent-benchmark/configschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// schemaDef follows $ref to the definition it names.
+func schemaDef(t *testing.T, schema, node map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		def, ok := schema["$defs"].(map[string]interface{})[strings.TrimPrefix(ref, "#/$defs/")]
+		require.True(t, ok, "dangling reference %s", ref)
+		node = def.(map[string]interface{})
+	}
+}
+
+// unknownKeys lists the keys of value that the schema node does not declare.
+func unknownKeys(t *testing.T, schema, node map[string]interface{}, value interface{}, path string) []string {
+	node = schemaDef(t, schema, node)
+	var unknown []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, ok := node["properties"].(map[string]interface{})
+		if !ok {
+			return nil // free-form map such as variables
+		}
+		for key, child := range v {
+			prop, ok := properties[key]
+			if !ok {
+				unknown = append(unknown, path+"."+key)
+				continue
+			}
+			unknown = append(unknown, unknownKeys(t, schema, prop.(map[string]interface{}), child, path+"."+key)...)
+		}
+	case []interface{}:
+		if items, ok := node["items"].(map[string]interface{}); ok {
+			for i, child := range v {
+				unknown = append(unknown, unknownKeys(t, schema, items, child, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return unknown
+}
+
+func TestConfigSchema(t *testing.T) {
+	data, err := configschema.JSON()
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, configschema.SchemaURI, schema["$schema"])
+
+	defs := schema["$defs"].(map[string]interface{})
+	property := func(def, key string) map[string]interface{} {
+		t.Helper()
+		props := defs[def].(map[string]interface{})["properties"].(map[string]interface{})
+		require.Contains(t, props, key, "%s.%s", def, key)
+		return props[key].(map[string]interface{})
+	}
+
+	assert.Contains(t, property("Provider", "type")["enum"], "AZURE")
+	assert.Contains(t, property("Server", "type")["enum"], "docker")
+	assert.Contains(t, property("Assertion", "type")["enum"], "tool_returned_content")
+	assert.NotContains(t, property("Assertion", "type")["enum"], "anyOf", "combinators are keys, not types")
+	assert.Equal(t, []interface{}{"all", "first_fail"}, property("Test", "assertion_mode")["enum"])
+
+	authType := property("Provider", "auth_type")["anyOf"].([]interface{})
+	require.Len(t, authType, 2, "templated enums also accept a template")
+	assert.Equal(t, []interface{}{"api_key", "entra_id"}, authType[0].(map[string]interface{})["enum"])
+
+	// Combinators refer back to the assertion definition
+	assert.Equal(t, "#/$defs/Assertion", property("Assertion", "anyOf")["items"].(map[string]interface{})["$ref"])
+	assert.Equal(t, "#/$defs/Assertion", property("Assertion", "not")["$ref"])
+	assert.Equal(t, false, defs["Server"].(map[string]interface{})["additionalProperties"])
+	assert.Equal(t, "integer", property("Server", "port")["type"])
+	assert.Contains(t, property("Test", "prompt")["type"], "number", "YAML numbers decode into string fields")
+
+	// Every key used by the example test files is in the schema
+	files, err := filepath.Glob("../examples/*-test.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	testFile := defs["TestConfiguration"].(map[string]interface{})
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		require.NoError(t, err)
+		var config map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(raw, &config), file)
+		assert.Empty(t, unknownKeys(t, schema, testFile, config, "$"), file)
+	}
+}
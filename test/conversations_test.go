@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conversationRun(testName, systemPrompt string) model.TestRun {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	return model.TestRun{
+		Passed: true,
+		Execution: &model.ExecutionResult{
+			TestName:     testName,
+			AgentName:    "gpt agent",
+			ProviderType: model.ProviderOpenAI,
+			SessionName:  "files",
+			SystemPrompt: systemPrompt,
+			Messages: []model.Message{
+				{Role: "user", Content: "Create hello.txt and list files", Timestamp: at(0)},
+				{Role: "assistant", Content: "Creating the file.", Timestamp: at(1)},
+				{Role: "assistant", Content: "Done.", Timestamp: at(5)},
+			},
+			ToolCalls: []model.ToolCall{
+				{Name: "write_file", Parameters: map[string]interface{}{"path": "hello.txt"}, Timestamp: at(2),
+					Result: model.Result{Content: []model.ContentItem{{Type: "text", Text: `{"ok": true}`}}}},
+				{Name: "list_files", Timestamp: at(3),
+					Result: model.Result{Content: []model.ContentItem{{Type: "text", Text: "hello.txt"}}}},
+				{Name: "read_file", Parameters: map[string]interface{}{"path": "hello.txt"}, Timestamp: at(6)},
+			},
+		},
+	}
+}
+
+func TestBuildConversation(t *testing.T) {
+	conv := engine.BuildConversation(conversationRun("Create file", "You are a file assistant."))
+
+	assert.Equal(t, "Create file", conv.Test)
+	assert.Equal(t, "gpt agent", conv.Agent)
+	assert.Equal(t, "OPENAI", conv.Provider)
+	assert.False(t, conv.SystemPromptRedacted)
+
+	roles := make([]string, 0, len(conv.Messages))
+	for _, m := range conv.Messages {
+		roles = append(roles, m.Role)
+	}
+	assert.Equal(t, []string{"system", "user", "assistant", "tool", "tool", "assistant", "tool"}, roles)
+
+	// Calls after assistant text are attached to it, in order
+	calls := conv.Messages[2].ToolCalls
+	require.Len(t, calls, 2)
+	assert.Equal(t, "Creating the file.", conv.Messages[2].Content)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.Equal(t, "function", calls[0].Type)
+	assert.Equal(t, "write_file", calls[0].Function.Name)
+	assert.JSONEq(t, `{"path": "hello.txt"}`, calls[0].Function.Arguments)
+	assert.Equal(t, "{}", calls[1].Function.Arguments)
+	assert.Equal(t, "call_1", conv.Messages[3].ToolCallID)
+	assert.Equal(t, `{"ok": true}`, conv.Messages[3].Content)
+	assert.Equal(t, "hello.txt", conv.Messages[4].Content)
+
+	// Calls after the last message still follow it
+	assert.Equal(t, "Done.", conv.Messages[5].Content)
+	require.Len(t, conv.Messages[5].ToolCalls, 1)
+	assert.Equal(t, "call_3", conv.Messages[6].ToolCallID)
+
+	// A call with no assistant text before it gets an empty assistant message
+	run := conversationRun("t", "")
+	run.Execution.Messages = run.Execution.Messages[:1]
+	run.Execution.Messages[0].Timestamp = run.Execution.ToolCalls[0].Timestamp.Add(-time.Second)
+	conv = engine.BuildConversation(run)
+	require.Len(t, conv.Messages, 5)
+	assert.Equal(t, "assistant", conv.Messages[1].Role)
+	assert.Empty(t, conv.Messages[1].Content)
+	assert.Len(t, conv.Messages[1].ToolCalls, 3)
+}
+
+func TestBuildConversation_RedactsSecretSystemPrompt(t *testing.T) {
+	conv := engine.BuildConversation(conversationRun("t", "Use api_key=abcd1234efgh5678 for the billing API."))
+	assert.True(t, conv.SystemPromptRedacted)
+	assert.Equal(t, engine.RedactedSystemPrompt, conv.Messages[0].Content)
+
+	t.Setenv("MY_SERVICE_TOKEN", "tok-4f8a9c2e")
+	assert.True(t, engine.ContainsSecret("Authenticate with tok-4f8a9c2e"))
+	assert.False(t, engine.ContainsSecret("You are a helpful assistant. Never share tokens."))
+	assert.True(t, engine.ContainsSecret("key: sk-ant-REDACTED"))
+}
+
+func TestExportConversations(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	dir := filepath.Join(t.TempDir(), "conversations")
+	skipped := conversationRun("Skipped", "")
+	skipped.Skipped = true
+
+	paths, err := engine.ExportConversations([]model.TestRun{
+		conversationRun("Create file", ""),
+		conversationRun("Create file", ""),
+		skipped,
+	}, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "create-file--gpt-agent.json"),
+		filepath.Join(dir, "create-file--gpt-agent-2.json"),
+	}, paths)
+
+	data, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	var conv engine.ConversationExport
+	require.NoError(t, json.Unmarshal(data, &conv))
+	assert.Equal(t, "user", conv.Messages[0].Role, "no system message without a system prompt")
+	assert.Len(t, conv.Messages, 6)
+}
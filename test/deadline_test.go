@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRunDeadline(t *testing.T) {
+	t.Run("unbounded", func(t *testing.T) {
+		ctx, cancel := engine.WithRunDeadline(context.Background(), 0, time.Minute)
+		defer cancel()
+		assert.False(t, engine.RunDeadlineReached(ctx))
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+	})
+
+	t.Run("deadline reached before grace expires", func(t *testing.T) {
+		ctx, cancel := engine.WithRunDeadline(context.Background(), time.Nanosecond, time.Minute)
+		defer cancel()
+		assert.Eventually(t, func() bool { return engine.RunDeadlineReached(ctx) }, time.Second, time.Millisecond)
+		assert.NoError(t, ctx.Err(), "tests in flight keep running during the grace period")
+	})
+
+	t.Run("grace expiry cancels with cause", func(t *testing.T) {
+		ctx, cancel := engine.WithRunDeadline(context.Background(), time.Nanosecond, time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		assert.True(t, engine.RunDeadlineReached(ctx))
+		assert.True(t, errors.Is(context.Cause(ctx), engine.ErrRunDeadline))
+	})
+
+	t.Run("earlier parent deadline stays in effect", func(t *testing.T) {
+		parent, cancelParent := engine.WithRunDeadline(context.Background(), time.Nanosecond, time.Minute)
+		defer cancelParent()
+		ctx, cancel := engine.WithRunDeadline(parent, time.Hour, 0)
+		defer cancel()
+		assert.Eventually(t, func() bool { return engine.RunDeadlineReached(ctx) }, time.Second, time.Millisecond)
+	})
+}
+
+func TestParseDeadline(t *testing.T) {
+	d, err := engine.ParseDeadline("")
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	d, err = engine.ParseDeadline("45m")
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Minute, d)
+
+	_, err = engine.ParseDeadline("soon")
+	assert.ErrorContains(t, err, `invalid deadline "soon"`)
+	_, err = engine.ParseDeadline("-1m")
+	assert.ErrorContains(t, err, "must not be negative")
+}
+
+func TestRunWithConfig_DeadlineCancelsRunningTests(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	engine.SetServerFactory(&engine.DefaultServerFactory{})
+	mcpSrv, closes := newClosableMCPServer(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tests.yaml"), []byte(`
+sessions:
+  - name: session
+    tests:
+      - name: completes
+        prompt: "first"
+      - name: cut off
+        prompt: "second"
+      - name: never runs
+        prompt: "third"
+`), 0644))
+	// Each suite gets its own model server, which answers the first request only
+	writeSuite := func(deadline string) string {
+		llm, _ := newBlockingLLM(t)
+		suitePath := filepath.Join(dir, "suite-"+deadline+".yaml")
+		require.NoError(t, os.WriteFile(suitePath, []byte(`
+name: suite
+deadline: `+deadline+`
+test_files: [tests.yaml]
+
+providers:
+  - name: fake
+    type: OPENAI
+    model: gpt-4o-mini
+    token: test-token
+    baseUrl: `+llm.URL+`/v1
+
+servers:
+  - name: mcp
+    type: http
+    url: `+mcpSrv.URL+`/mcp
+
+agents:
+  - name: agent
+    provider: fake
+    servers:
+      - name: mcp
+`), 0644))
+		return suitePath
+	}
+
+	tests := []struct {
+		name string
+		cfg  engine.RunConfig
+	}{
+		{name: "suite deadline", cfg: engine.RunConfig{SuitePath: writeSuite("1s")}},
+		{name: "flag overrides suite", cfg: engine.RunConfig{SuitePath: writeSuite("1h"), Deadline: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := engine.RunWithConfig(context.Background(), tt.cfg)
+			require.ErrorIs(t, err, engine.ErrRunDeadline)
+			require.Len(t, results, 3)
+
+			assert.Equal(t, "completes", results[0].Execution.TestName)
+			assert.True(t, results[0].Passed)
+			assert.Equal(t, "cut off", results[1].Execution.TestName)
+			assert.False(t, results[1].Passed)
+			assert.True(t, results[1].Execution.HasErrorKind(model.ErrorKindRunDeadline))
+			assert.True(t, results[2].Skipped)
+			assert.Equal(t, engine.NotExecutedDeadlineReason, results[2].SkipReason)
+		})
+	}
+
+	assert.Eventually(t, func() bool { return closes.Load() > 0 }, 5*time.Second, 10*time.Millisecond,
+		"MCP server session should be closed")
+}
+
+func TestRunWithContext_DeadlineMarksTestsNotExecuted(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	engine.SetServerFactory(&engine.DefaultServerFactory{})
+	llm, _ := newBlockingLLM(t)
+	mcpSrv, _ := newClosableMCPServer(t)
+
+	dir := t.TempDir()
+	testPath := filepath.Join(dir, "tests.yaml")
+	require.NoError(t, os.WriteFile(testPath, []byte(`
+providers:
+  - name: fake
+    type: OPENAI
+    model: gpt-4o-mini
+    token: test-token
+    baseUrl: `+llm.URL+`/v1
+
+servers:
+  - name: mcp
+    type: http
+    url: `+mcpSrv.URL+`/mcp
+
+agents:
+  - name: agent
+    provider: fake
+    servers:
+      - name: mcp
+
+sessions:
+  - name: session
+    tests:
+      - name: first
+        prompt: "first"
+      - name: second
+        prompt: "second"
+`), 0644))
+
+	reportBase := filepath.Join(dir, "report")
+	code := engine.RunWithContext(context.Background(), engine.RunConfig{
+		TestPath:       testPath,
+		ReportTypes:    []string{"json"},
+		ReportFileName: reportBase,
+		MaxDuration:    time.Nanosecond,
+	})
+	assert.Equal(t, engine.DeadlineExitCode, code)
+
+	full, err := report.LoadFullReportFromJSON(reportBase + ".json")
+	require.NoError(t, err)
+	require.Len(t, full.Results, 2)
+	for _, r := range full.Results {
+		assert.True(t, r.Skipped, r.Execution.TestName)
+		assert.Equal(t, engine.NotExecutedDeadlineReason, r.SkipReason)
+	}
+	require.NotNil(t, full.RunMetadata)
+	assert.True(t, full.RunMetadata.Truncated)
+	assert.False(t, full.RunMetadata.Interrupted)
+}
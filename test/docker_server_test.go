@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDockerScript stands in for the docker CLI. It logs every invocation, reports
+// images as missing unless FAKE_DOCKER_IMAGE_PRESENT is set, fails pulls, and runs
+// this test binary as the containerized stdio MCP server.
+const fakeDockerScript = `#!/bin/sh
+echo "$@" >> "$FAKE_DOCKER_LOG"
+case "$1" in
+  image) [ "$FAKE_DOCKER_IMAGE_PRESENT" = "1" ] && exit 0; exit 1 ;;
+  pull) echo "Error response from daemon: pull access denied for $2" >&2; exit 1 ;;
+  run) AGENT_BENCH_DOCKER_HELPER=1 exec "$FAKE_DOCKER_HELPER" -test.run='^TestDockerHelperProcess$' ;;
+  rm) exit 0 ;;
+esac
+exit 1
+`
+
+// TestDockerHelperProcess is the MCP server the fake docker CLI starts; it does
+// nothing when run as a normal test.
+func TestDockerHelperProcess(t *testing.T) {
+	if os.Getenv("AGENT_BENCH_DOCKER_HELPER") != "1" {
+		return
+	}
+	srv := mcpserver.NewMCPServer("docker-helper", "1.0.0", mcpserver.WithToolCapabilities(true))
+	srv.AddTool(mcp.NewTool("whoami", mcp.WithDescription("Report the container environment")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(os.Getenv("GREETING")), nil
+		})
+	mcpserver.ServeStdio(srv)
+	os.Exit(0)
+}
+
+// installFakeDocker puts the fake docker CLI first on PATH and returns its log file.
+func installFakeDocker(t *testing.T, imagePresent bool) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake docker CLI is a shell script")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker"), []byte(fakeDockerScript), 0755))
+	logPath := filepath.Join(dir, "docker.log")
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_DOCKER_LOG", logPath)
+	t.Setenv("FAKE_DOCKER_HELPER", os.Args[0])
+	if imagePresent {
+		t.Setenv("FAKE_DOCKER_IMAGE_PRESENT", "1")
+	} else {
+		t.Setenv("FAKE_DOCKER_IMAGE_PRESENT", "0")
+	}
+	return logPath
+}
+
+func readDockerLog(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestNewDockerServer(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+
+	t.Run("Image is required", func(t *testing.T) {
+		_, err := server.NewMCPServer(context.Background(), model.Server{Name: "box", Type: model.Docker})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "image is required")
+	})
+
+	t.Run("Type is matched case-insensitively", func(t *testing.T) {
+		_, err := server.NewMCPServer(context.Background(), model.Server{Name: "box", Type: "DOCKER"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "image is required")
+	})
+
+	t.Run("Pull failure names the image", func(t *testing.T) {
+		installFakeDocker(t, false)
+		_, err := server.NewMCPServer(context.Background(), model.Server{
+			Name:  "box",
+			Type:  model.Docker,
+			Image: "example/missing-mcp:latest",
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to pull image example/missing-mcp:latest")
+		assert.Contains(t, err.Error(), "pull access denied")
+	})
+
+	t.Run("Stdio container serves tools and is removed on close", func(t *testing.T) {
+		logPath := installFakeDocker(t, true)
+		srv, err := server.NewMCPServer(context.Background(), model.Server{
+			Name:  "my box",
+			Type:  model.Docker,
+			Image: "example/mcp:1.0",
+			Args:  []string{"--verbose"},
+			Env:   map[string]string{"GREETING": "hello", "A": "1"},
+		})
+		require.NoError(t, err)
+
+		tools, err := srv.Client.ListTools(context.Background(), mcp.ListToolsRequest{})
+		require.NoError(t, err)
+		require.Len(t, tools.Tools, 1)
+		assert.Equal(t, "whoami", tools.Tools[0].Name)
+
+		require.NoError(t, srv.Close())
+
+		calls := readDockerLog(t, logPath)
+		require.Len(t, calls, 3)
+		assert.Equal(t, "image inspect example/mcp:1.0", calls[0])
+		assert.Regexp(t, `^run --rm --name agent-bench-my-box-[0-9a-f]{8} -i -e A=1 -e GREETING=hello example/mcp:1.0 --verbose$`, calls[1])
+		assert.Regexp(t, `^rm -f agent-bench-my-box-[0-9a-f]{8}$`, calls[2])
+	})
+}
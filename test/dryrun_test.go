@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "dry run must not call out", http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	providers := `
+providers:
+  - name: fake
+    type: OPENAI
+    model: gpt-4o-mini
+    token: test-token
+    baseUrl: ` + srv.URL + `/v1
+    warmup: true
+`
+	servers := `
+servers:
+  - name: mcp
+    type: http
+    url: ` + srv.URL + `/mcp
+`
+	agents := `
+agents:
+  - name: a1
+    provider: fake
+    servers:
+      - name: mcp
+  - name: a2
+    provider: fake
+    servers:
+      - name: mcp
+`
+	sessions := `
+sessions:
+  - name: session
+    tests:
+      - name: both
+        prompt: "hello"
+      - name: only-a2
+        agent: a2
+        prompt: "hi"
+      - name: override
+        model: gpt-4o
+        prompt: "{{randomValue type='UUID'}}"
+`
+
+	t.Run("plans tests without calling providers or servers", func(t *testing.T) {
+		testPath := write("tests.yaml", providers+servers+agents+sessions)
+		plan, err := engine.DryRun(context.Background(), testPath, "")
+		require.NoError(t, err)
+
+		var runs []string
+		for _, p := range plan {
+			assert.Equal(t, testPath, p.SourceFile)
+			assert.Equal(t, "session", p.Session)
+			runs = append(runs, p.Test+"@"+p.Agent)
+		}
+		assert.Equal(t, []string{
+			"both@a1", "override@a1 (gpt-4o)",
+			"both@a2", "only-a2@a2", "override@a2 (gpt-4o)",
+		}, runs)
+		assert.Zero(t, requests.Load(), "no warm-up, LLM or MCP request is sent")
+
+		var buf bytes.Buffer
+		engine.PrintPlan(&buf, plan)
+		assert.Contains(t, buf.String(), "FILE")
+		assert.Contains(t, buf.String(), "override  a2 (gpt-4o)")
+		assert.Contains(t, buf.String(), "5 tests planned across 4 agents")
+	})
+
+	t.Run("plans suite test files", func(t *testing.T) {
+		write("suite-tests.yaml", sessions)
+		suitePath := write("suite.yaml", `
+name: suite
+test_files:
+  - suite-tests.yaml
+`+providers+servers+agents)
+		plan, err := engine.DryRun(context.Background(), "", suitePath)
+		require.NoError(t, err)
+		require.Len(t, plan, 5)
+		assert.Equal(t, filepath.Join(dir, "suite-tests.yaml"), plan[0].SourceFile)
+		assert.Zero(t, requests.Load())
+	})
+
+	t.Run("reports validation errors", func(t *testing.T) {
+		cases := []struct {
+			name, config, wantErr string
+		}{
+			{
+				name: "duplicate server",
+				config: providers + servers + `
+  - name: mcp
+    type: http
+    url: http://localhost:1/mcp
+` + agents + sessions,
+				wantErr: "duplicate server name: mcp",
+			},
+			{
+				name:    "unknown server",
+				config:  providers + strings.Replace(servers, "name: mcp", "name: other", 1) + agents + sessions,
+				wantErr: "agent 'a1' references unknown server 'mcp'",
+			},
+			{
+				name: "unknown test agent",
+				config: providers + servers + agents + `
+sessions:
+  - name: session
+    tests:
+      - name: missing
+        agent: a3
+        prompt: "hello"
+`,
+				wantErr: "test 'missing': agent 'a3' not found",
+			},
+			{
+				name: "invalid prompt template",
+				config: providers + servers + agents + `
+sessions:
+  - name: session
+    tests:
+      - name: broken
+        prompt: "{{#if x}}unclosed"
+`,
+				wantErr: "test 'broken': invalid prompt template",
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				testPath := write("invalid.yaml", tc.config)
+				_, err := engine.DryRun(context.Background(), testPath, "")
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+			})
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/report"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestPrepareFixtures(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	testDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "config.yaml"), []byte("port: 8080\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(testDir, "data", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(testDir, "data", "nested", "rows.csv"), []byte("a,b\n"), 0644))
+	templateCtx := map[string]string{"TEST_DIR": testDir, "NAME": "config"}
+
+	t.Run("copies files and directories", func(t *testing.T) {
+		dir, err := engine.PrepareFixtures([]string{"{{NAME}}.yaml", "data"}, templateCtx)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "port: 8080\n", string(content))
+		content, err = os.ReadFile(filepath.Join(dir, "data", "nested", "rows.csv"))
+		require.NoError(t, err)
+		assert.Equal(t, "a,b\n", string(content))
+
+		engine.CleanupFixtures(dir, true)
+		assert.DirExists(t, dir, "kept directories stay for inspection")
+		engine.CleanupFixtures(dir, false)
+		assert.NoDirExists(t, dir)
+	})
+
+	t.Run("missing fixture", func(t *testing.T) {
+		_, err := engine.PrepareFixtures([]string{"missing.txt"}, templateCtx)
+		assert.ErrorContains(t, err, `failed to copy fixture "missing.txt"`)
+	})
+
+	t.Run("conflicting names", func(t *testing.T) {
+		_, err := engine.PrepareFixtures([]string{"config.yaml", filepath.Join(testDir, "config.yaml")}, templateCtx)
+		assert.ErrorContains(t, err, `both copy to "config.yaml"`)
+	})
+}
+
+func TestRunTests_Fixtures(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	testTools := createTestTools()
+
+	fixture := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(fixture, []byte("hello"), 0644))
+
+	// The fixture must be in place while the agent runs
+	var fixtureDir, seenContent string
+	mockLLM := new(MockLLMModel)
+	mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			msgs := args.Get(1).([]llms.MessageContent)
+			prompt := msgs[len(msgs)-1].Parts[0].(llms.TextContent).Text
+			fixtureDir = strings.TrimPrefix(prompt, "Read ")
+			if b, err := os.ReadFile(filepath.Join(fixtureDir, "input.txt")); err == nil {
+				seenContent = string(b)
+			}
+		}).
+		Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "done", StopReason: "stop"}},
+		}, nil)
+	mockClient := new(MockMCPClient)
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mcpServer := createMockServer("server", testTools)
+	mcpServer.Client = mockClient
+	ag := agent.NewMCPAgent(ctx, "agent", []model.AgentServer{{Name: "server"}},
+		[]*server.MCPServer{mcpServer}, "provider", mockLLM)
+
+	testConfig := &model.TestConfiguration{
+		Agents: []model.Agent{{Name: "agent", Provider: "provider", Servers: []model.AgentServer{{Name: "server"}}}},
+		Sessions: []model.Session{{
+			Name: "session",
+			Tests: []model.Test{
+				{Name: "reads fixture", Prompt: "Read {{FIXTURE_DIR}}", Fixtures: []string{fixture}},
+				{Name: "missing fixture", Prompt: "Read", Fixtures: []string{fixture + ".missing"}},
+			},
+		}},
+	}
+
+	results := engine.RunTests(ctx, testConfig,
+		map[string]*agent.MCPAgent{"agent": ag},
+		map[string]llms.Model{"provider": mockLLM}, 5, 0, 0, 0, "", "")
+
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, "hello", seenContent)
+	assert.NoDirExists(t, fixtureDir, "fixtures are removed after the test")
+
+	assert.False(t, results[1].Passed)
+	assert.False(t, results[1].Skipped)
+	assert.True(t, results[1].Execution.HasErrorKind(model.ErrorKindFixture))
+	mockLLM.AssertNumberOfCalls(t, "GenerateContent", 1)
+	assert.Empty(t, results[0].Execution.TempDir, "removed directories are not reported")
+
+	// With -keep-temp the directory stays and its path is recorded for the report
+	results = engine.RunTests(engine.WithKeepTemp(ctx, true), testConfig,
+		map[string]*agent.MCPAgent{"agent": ag},
+		map[string]llms.Model{"provider": mockLLM}, 5, 0, 0, 0, "", "")
+	require.Len(t, results, 2)
+	assert.Equal(t, fixtureDir, results[0].Execution.TempDir)
+	assert.FileExists(t, filepath.Join(fixtureDir, "input.txt"))
+	engine.CleanupFixtures(fixtureDir, false)
+
+	gen, err := report.NewGenerator()
+	require.NoError(t, err)
+	html, err := gen.GenerateHTML(results[:1])
+	require.NoError(t, err)
+	assert.Contains(t, html, "Artifacts at:")
+	assert.Contains(t, html, fixtureDir)
+}
+
+func TestRunTests_FixturesRecreatedOnRetry(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	testTools := createTestTools()
+
+	fixture := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(fixture, []byte("hello"), 0644))
+
+	// The first attempt changes its fixture and fails; the retry must see the original
+	var dirs, contents []string
+	readFixture := func(args mock.Arguments) {
+		msgs := args.Get(1).([]llms.MessageContent)
+		dir := strings.TrimPrefix(msgs[len(msgs)-1].Parts[0].(llms.TextContent).Text, "Read ")
+		b, _ := os.ReadFile(filepath.Join(dir, "input.txt"))
+		dirs = append(dirs, dir)
+		contents = append(contents, string(b))
+		_ = os.WriteFile(filepath.Join(dir, "input.txt"), []byte("changed"), 0644)
+	}
+	mockLLM := new(MockLLMModel)
+	mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+		Run(readFixture).Return(nil, errors.New("connection reset by peer")).Once()
+	mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+		Run(readFixture).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "done", StopReason: "stop"}},
+	}, nil)
+	mockClient := new(MockMCPClient)
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mcpServer := createMockServer("server", testTools)
+	mcpServer.Client = mockClient
+	ag := agent.NewMCPAgent(ctx, "agent", []model.AgentServer{{Name: "server"}},
+		[]*server.MCPServer{mcpServer}, "provider", mockLLM)
+
+	testConfig := &model.TestConfiguration{
+		Agents: []model.Agent{{Name: "agent", Provider: "provider", Servers: []model.AgentServer{{Name: "server"}}}},
+		Sessions: []model.Session{{
+			Name:         "session",
+			Retries:      1,
+			RetryBackoff: "1ms",
+			Tests:        []model.Test{{Name: "flaky", Prompt: "Read {{FIXTURE_DIR}}", Fixtures: []string{fixture}}},
+		}},
+	}
+	results := engine.RunTests(ctx, testConfig,
+		map[string]*agent.MCPAgent{"agent": ag},
+		map[string]llms.Model{"provider": mockLLM}, 5, 0, 0, 0, "", "")
+
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, []string{"hello", "hello"}, contents, "each attempt starts from the original fixtures")
+	require.Len(t, dirs, 2)
+	assert.NotEqual(t, dirs[0], dirs[1])
+	assert.NoDirExists(t, dirs[0])
+	assert.NoDirExists(t, dirs[1])
+}
+
+func TestRunTests_TestTempDir(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	testTools := createTestTools()
+
+	// Tests without fixtures get their own directory too
+	var prompts []string
+	var existed []bool
+	mockLLM := new(MockLLMModel)
+	mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			msgs := args.Get(1).([]llms.MessageContent)
+			prompt := msgs[len(msgs)-1].Parts[0].(llms.TextContent).Text
+			prompts = append(prompts, prompt)
+			_, err := os.Stat(strings.Fields(prompt)[1])
+			existed = append(existed, err == nil)
+		}).
+		Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "done", StopReason: "stop"}},
+		}, nil)
+	mockClient := new(MockMCPClient)
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mcpServer := createMockServer("server", testTools)
+	mcpServer.Client = mockClient
+	ag := agent.NewMCPAgent(ctx, "agent", []model.AgentServer{{Name: "server"}},
+		[]*server.MCPServer{mcpServer}, "provider", mockLLM)
+
+	testConfig := &model.TestConfiguration{
+		Agents: []model.Agent{{Name: "agent", Provider: "provider", Servers: []model.AgentServer{{Name: "server"}}}},
+		Sessions: []model.Session{{
+			Name: "session",
+			Tests: []model.Test{
+				{Name: "first", Prompt: "Write {{TEST_TEMP_DIR}} fixtures={{FIXTURE_DIR}}"},
+				{Name: "second", Prompt: "Write {{TEST_TEMP_DIR}} fixtures={{FIXTURE_DIR}}"},
+			},
+		}},
+	}
+
+	results := engine.RunTests(engine.WithKeepTemp(ctx, true), testConfig,
+		map[string]*agent.MCPAgent{"agent": ag},
+		map[string]llms.Model{"provider": mockLLM}, 5, 0, 0, 0, "", "")
+
+	require.Len(t, results, 2)
+	require.Len(t, prompts, 2)
+	assert.Equal(t, []bool{true, true}, existed)
+	dirs := []string{strings.Fields(prompts[0])[1], strings.Fields(prompts[1])[1]}
+	assert.NotEqual(t, dirs[0], dirs[1], "each test gets its own directory")
+	assert.NotEqual(t, os.TempDir(), dirs[0])
+	assert.True(t, strings.HasSuffix(prompts[0], "fixtures="), "FIXTURE_DIR is only set for tests with fixtures")
+	for i, r := range results {
+		assert.Equal(t, dirs[i], r.Execution.TempDir, "-keep-temp records every test's directory")
+		assert.DirExists(t, dirs[i])
+		engine.CleanupFixtures(dirs[i], false)
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBlockingLLM answers the first chat completion and blocks every later one
+// until the client gives up. blocked is closed when the second request arrives.
+func newBlockingLLM(t *testing.T) (srv *httptest.Server, blocked <-chan struct{}) {
+	var calls atomic.Int32
+	blockedCh := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) > 1 {
+			once.Do(func() { close(blockedCh) })
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"done"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	// Cleanups run last-in first-out, so blocked handlers return before Close waits on them
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	return srv, blockedCh
+}
+
+// newClosableMCPServer serves a streamable HTTP MCP server and counts the
+// session DELETE requests clients send when they close.
+func newClosableMCPServer(t *testing.T) (srv *httptest.Server, closes *atomic.Int32) {
+	mcpSrv := mcpserver.NewMCPServer("interrupt-test", "1.0.0", mcpserver.WithToolCapabilities(true))
+	mcpSrv.AddTool(mcp.NewTool("echo", mcp.WithDescription("Echo a fixed value")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})
+	handler := mcpserver.NewStreamableHTTPServer(mcpSrv)
+
+	closes = &atomic.Int32{}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			closes.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, closes
+}
+
+func TestRunWithContext_InterruptWritesPartialReport(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	// Other tests leave a mock factory installed; this run needs real clients
+	engine.SetServerFactory(&engine.DefaultServerFactory{})
+	llm, blocked := newBlockingLLM(t)
+	mcpSrv, closes := newClosableMCPServer(t)
+
+	dir := t.TempDir()
+	testPath := filepath.Join(dir, "tests.yaml")
+	require.NoError(t, os.WriteFile(testPath, []byte(`
+providers:
+  - name: fake
+    type: OPENAI
+    model: gpt-4o-mini
+    token: test-token
+    baseUrl: `+llm.URL+`/v1
+
+servers:
+  - name: mcp
+    type: http
+    url: `+mcpSrv.URL+`/mcp
+
+agents:
+  - name: agent
+    provider: fake
+    servers:
+      - name: mcp
+
+sessions:
+  - name: session
+    tests:
+      - name: completes
+        prompt: "first"
+      - name: interrupted
+        prompt: "second"
+      - name: never runs
+        prompt: "third"
+`), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-blocked
+		cancel()
+	}()
+
+	reportBase := filepath.Join(dir, "report")
+	code := engine.RunWithContext(ctx, engine.RunConfig{
+		TestPath:       testPath,
+		ReportTypes:    []string{"json"},
+		ReportFileName: reportBase,
+	})
+	assert.Equal(t, engine.InterruptedExitCode, code)
+
+	full, err := report.LoadFullReportFromJSON(reportBase + ".json")
+	require.NoError(t, err)
+	require.Len(t, full.Results, 2)
+	assert.Equal(t, "completes", full.Results[0].Execution.TestName)
+	assert.True(t, full.Results[0].Passed)
+	assert.Equal(t, "interrupted", full.Results[1].Execution.TestName)
+	assert.False(t, full.Results[1].Passed)
+	assert.True(t, full.Results[1].Execution.HasErrorKind(model.ErrorKindInterrupted))
+	require.NotNil(t, full.RunMetadata)
+	assert.True(t, full.RunMetadata.Interrupted)
+
+	assert.Eventually(t, func() bool { return closes.Load() > 0 }, 5*time.Second, 10*time.Millisecond,
+		"MCP server session should be closed")
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupLoggerWithOptions(t *testing.T) {
+	defer logger.SetupLogger(NewDummyWriter(), true)
+
+	t.Run("Quiet logs errors only", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger.SetupLoggerWithOptions(&buf, logger.Options{Quiet: true, Verbose: true})
+
+		logger.Logger.Info("info message")
+		logger.Logger.Warn("warn message")
+		logger.Logger.Error("error message")
+
+		out := buf.String()
+		assert.NotContains(t, out, "info message")
+		assert.NotContains(t, out, "warn message")
+		assert.Contains(t, out, "error message")
+	})
+
+	t.Run("Verbose enables debug", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger.SetupLoggerWithOptions(&buf, logger.Options{Verbose: true})
+
+		logger.Logger.Debug("debug message")
+		assert.Contains(t, buf.String(), "debug message")
+	})
+
+	t.Run("JSON logs are valid JSON lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger.SetupLoggerWithOptions(&buf, logger.Options{JSON: true})
+
+		logger.Logger.Info("json message", "key", "value")
+		logger.Logger.Debug("hidden debug")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 1)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "json message", entry["msg"])
+		assert.Equal(t, "INFO", entry["level"])
+		assert.Equal(t, "value", entry["key"])
+	})
+}
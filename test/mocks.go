@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/mykhaliev/agent-benchmark/server"
@@ -126,8 +127,8 @@ func (m *MockMCPClient) Complete(ctx context.Context, request mcp.CompleteReques
 }
 
 func (m *MockMCPClient) Close() error {
-	//TODO implement me
-	panic("implement me")
+	args := m.Called()
+	return args.Error(0)
 }
 
 func (m *MockMCPClient) OnNotification(handler func(notification mcp.JSONRPCNotification)) {
@@ -203,3 +204,16 @@ func (m *MockLLMModel) Call(ctx context.Context, prompt string, options ...llms.
 	args := m.Called(ctx, prompt, options)
 	return args.String(0), args.Error(1)
 }
+
+// MockBedrockConverseClient mocks the Bedrock runtime Converse API
+type MockBedrockConverseClient struct {
+	mock.Mock
+}
+
+func (m *MockBedrockConverseClient) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*bedrockruntime.ConverseOutput), args.Error(1)
+}
@@ -1,12 +1,23 @@
 package tests
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/mykhaliev/agent-benchmark/logger"
 	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 // ============================================================================
@@ -178,6 +189,36 @@ sessions:
 		assert.True(t, config.Providers[0].Retry.RetryOn429)
 		assert.Equal(t, 3, config.Providers[0].Retry.MaxRetries)
 	})
+
+	t.Run("Tool timeouts at settings, server and test level", func(t *testing.T) {
+		yamlContent := `
+settings:
+  tool_timeout: 30s
+  tool_timeout_policy: fail
+
+servers:
+  - name: test-server
+    type: stdio
+    command: "node server.js"
+    tool_timeout: 10s
+
+sessions:
+  - name: test-session
+    tests:
+      - name: test-1
+        prompt: "Test prompt"
+        tool_timeout: 5s
+`
+		tmpfile := createTempYAML(t, yamlContent)
+
+		config, err := model.ParseTestConfig(tmpfile)
+		require.NoError(t, err)
+
+		assert.Equal(t, "30s", config.Settings.ToolTimeout)
+		assert.Equal(t, model.ToolTimeoutFail, config.Settings.ToolTimeoutPolicy)
+		assert.Equal(t, "10s", config.Servers[0].ToolTimeout)
+		assert.Equal(t, "5s", config.Sessions[0].Tests[0].ToolTimeout)
+	})
 }
 
 func TestParseTestConfigFromString(t *testing.T) {
@@ -199,6 +240,134 @@ providers:
 	})
 }
 
+func TestParseConfigInclude(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("Merges included definitions with local overrides", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "common", "servers.yaml"), `
+servers:
+  - name: fs
+    type: stdio
+    command: "node fs.js"
+`)
+		writeFile(t, filepath.Join(dir, "common", "providers.yaml"), `
+include: [servers.yaml]
+providers:
+  - name: fast
+    type: OPENAI
+    model: gpt-4o-mini
+  - name: smart
+    type: OPENAI
+    model: gpt-4o
+agents:
+  - name: assistant
+    provider: fast
+    servers:
+      - name: fs
+variables:
+  REGION: eu
+  TEAM: core
+`)
+		testFile := filepath.Join(dir, "tests", "files.yaml")
+		writeFile(t, testFile, `
+include:
+  - ../common/providers.yaml
+providers:
+  - name: smart
+    type: ANTHROPIC
+    model: claude
+variables:
+  REGION: us
+sessions:
+  - name: session
+    tests:
+      - name: test
+        prompt: "List files"
+`)
+
+		config, err := model.ParseTestConfig(testFile)
+		require.NoError(t, err)
+
+		require.Len(t, config.Providers, 2)
+		assert.Equal(t, "fast", config.Providers[0].Name)
+		assert.Equal(t, "smart", config.Providers[1].Name)
+		assert.Equal(t, model.ProviderType("ANTHROPIC"), config.Providers[1].Type)
+		require.Len(t, config.Servers, 1)
+		assert.Equal(t, "fs", config.Servers[0].Name)
+		require.Len(t, config.Agents, 1)
+		assert.Equal(t, map[string]string{"REGION": "us", "TEAM": "core"}, config.Variables)
+		assert.Len(t, config.Sessions, 1)
+	})
+
+	t.Run("Suite include", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "providers.yaml"), `
+providers:
+  - name: fast
+    type: OPENAI
+    model: gpt-4o-mini
+`)
+		suiteFile := filepath.Join(dir, "suite.yaml")
+		writeFile(t, suiteFile, `
+name: suite
+include: [providers.yaml]
+test_files: [a.yaml]
+`)
+
+		suite, err := model.ParseSuiteConfig(suiteFile)
+		require.NoError(t, err)
+		require.Len(t, suite.Providers, 1)
+		assert.Equal(t, "fast", suite.Providers[0].Name)
+	})
+
+	t.Run("Circular include", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.yaml"), "include: [b.yaml]\n")
+		writeFile(t, filepath.Join(dir, "b.yaml"), "include: [a.yaml]\n")
+		testFile := filepath.Join(dir, "test.yaml")
+		writeFile(t, testFile, "include: [a.yaml]\n")
+
+		_, err := model.ParseTestConfig(testFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "circular include")
+	})
+
+	t.Run("Missing include", func(t *testing.T) {
+		dir := t.TempDir()
+		testFile := filepath.Join(dir, "test.yaml")
+		writeFile(t, testFile, "include: [missing.yaml]\n")
+
+		_, err := model.ParseTestConfig(testFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `failed to read include "missing.yaml"`)
+	})
+}
+
+func TestParseJudgeConfig(t *testing.T) {
+	config, err := model.ParseTestConfigFromString(`
+judge:
+  type: ANTHROPIC
+  token: "{{ANTHROPIC_API_KEY}}"
+  model: claude-sonnet-4-20250514
+ai_summary:
+  enabled: true
+`)
+	require.NoError(t, err)
+	require.NotNil(t, config.Judge)
+	assert.Equal(t, model.ProviderAnthropic, config.Judge.Type)
+	assert.Equal(t, "claude-sonnet-4-20250514", config.Judge.Model)
+	assert.Empty(t, config.AISummary.JudgeProvider)
+
+	config, err = model.ParseTestConfigFromString("ai_summary:\n  enabled: true\n")
+	require.NoError(t, err)
+	assert.Nil(t, config.Judge)
+}
+
 func TestParseAgentClarificationDetection(t *testing.T) {
 	tests := []struct {
 		name                  string
@@ -308,6 +477,87 @@ agents:
 	}
 }
 
+func TestParseTestTurns(t *testing.T) {
+	yamlStr := `
+sessions:
+  - name: follow-ups
+    tests:
+      - name: create then rename
+        prompt: "Create a file called draft.txt"
+        turns:
+          - prompt: "Now rename it to final.txt"
+            assertions:
+              - type: tool_called
+                tool: move_file
+          - prompt: "What is the file called now?"
+        assertions:
+          - type: output_contains
+            value: "final.txt"
+`
+	config, err := model.ParseTestConfigFromString(yamlStr)
+	require.NoError(t, err)
+	require.Len(t, config.Sessions, 1)
+	require.Len(t, config.Sessions[0].Tests, 1)
+
+	test := config.Sessions[0].Tests[0]
+	require.Len(t, test.Turns, 2)
+	assert.Equal(t, "Now rename it to final.txt", test.Turns[0].Prompt)
+	require.Len(t, test.Turns[0].Assertions, 1)
+	assert.Equal(t, "move_file", test.Turns[0].Assertions[0].Tool)
+	assert.Equal(t, "What is the file called now?", test.Turns[1].Prompt)
+	assert.Empty(t, test.Turns[1].Assertions)
+	require.Len(t, test.Assertions, 1)
+}
+
+func TestExecutionResultAppendTurn(t *testing.T) {
+	start := time.Now()
+	result := model.ExecutionResult{
+		StartTime: start,
+		Messages: []model.Message{
+			{Role: "user", Content: "first"},
+			{Role: "assistant", Content: "first answer", Iteration: 2},
+		},
+		ToolCalls:   []model.ToolCall{{Name: "create_file", Iteration: 1}},
+		FinalOutput: "first answer",
+		TokensUsed:  10,
+		Errors:      []string{},
+	}
+
+	turn := model.ExecutionResult{
+		StartTime: start.Add(time.Second),
+		EndTime:   start.Add(3 * time.Second),
+		Messages: []model.Message{
+			{Role: "user", Content: "first"},
+			{Role: "user", Content: "second"},
+			{Role: "assistant", Content: "second answer", Iteration: 2},
+		},
+		ToolCalls:          []model.ToolCall{{Name: "move_file", Iteration: 1}},
+		FinalOutput:        "second answer",
+		TokensUsed:         5,
+		Errors:             []string{"tool failed"},
+		ClarificationStats: &model.ClarificationStats{Count: 1, Iterations: []int{1}, Examples: []string{"which file?"}},
+	}
+
+	result.AppendTurn(turn)
+
+	require.Len(t, result.Messages, 4)
+	assert.Equal(t, "second", result.Messages[2].Content)
+	assert.Equal(t, "second answer", result.Messages[3].Content)
+	require.Len(t, result.ToolCalls, 2)
+	assert.Equal(t, "move_file", result.ToolCalls[1].Name)
+	// Iterations of the turn continue the numbering of the first execution
+	assert.Equal(t, 0, result.Messages[2].Iteration)
+	assert.Equal(t, 4, result.Messages[3].Iteration)
+	assert.Equal(t, 3, result.ToolCalls[1].Iteration)
+	assert.Equal(t, "second answer", result.FinalOutput)
+	assert.Equal(t, 15, result.TokensUsed)
+	assert.Equal(t, []string{"tool failed"}, result.Errors)
+	assert.Equal(t, int64(3000), result.LatencyMs)
+	require.NotNil(t, result.ClarificationStats)
+	assert.Equal(t, 1, result.ClarificationStats.Count)
+	assert.Equal(t, []int{3}, result.ClarificationStats.Iterations)
+}
+
 // boolPtr is a helper function to create a pointer to a bool
 func boolPtr(b bool) *bool {
 	return &b
@@ -445,6 +695,101 @@ func TestAssertionEvaluator_ToolCallCount(t *testing.T) {
 	}
 }
 
+func TestAssertionEvaluator_ToolCallCountBounds(t *testing.T) {
+	result := &model.ExecutionResult{
+		ToolCalls: []model.ToolCall{
+			{Name: "write_file"},
+			{Name: "screenshot_control"},
+			{Name: "screenshot_control"},
+			{Name: "screenshot_control"},
+		},
+	}
+	evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+	n := func(v int) *int { return &v }
+
+	tests := []struct {
+		name        string
+		assertion   model.Assertion
+		wantPassed  bool
+		wantMessage string
+	}{
+		{
+			name:        "Exact",
+			assertion:   model.Assertion{Tool: "write_file", Exact: n(1)},
+			wantPassed:  true,
+			wantMessage: "Tool 'write_file' called 1 times (expected exactly 1)",
+		},
+		{
+			name:        "Max exceeded",
+			assertion:   model.Assertion{Tool: "screenshot_control", Max: n(2)},
+			wantMessage: "Tool 'screenshot_control' called 3 times (expected at most 2)",
+		},
+		{
+			name:        "Min not reached",
+			assertion:   model.Assertion{Tool: "write_file", Min: n(2), Max: n(5)},
+			wantMessage: "Tool 'write_file' called 1 times (expected at least 2)",
+		},
+		{
+			name:        "Within range",
+			assertion:   model.Assertion{Tool: "screenshot_control", Min: n(1), Max: n(3)},
+			wantPassed:  true,
+			wantMessage: "Tool 'screenshot_control' called 3 times (expected at least 1 and at most 3)",
+		},
+		{
+			name:        "Exact zero",
+			assertion:   model.Assertion{Tool: "delete_file", Exact: n(0)},
+			wantPassed:  true,
+			wantMessage: "Tool 'delete_file' called 0 times (expected exactly 0)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.assertion.Type = "tool_call_count"
+			results := evaluator.Evaluate([]model.Assertion{tt.assertion})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed)
+			assert.Equal(t, tt.wantMessage, results[0].Message)
+		})
+	}
+
+	t.Run("Details", func(t *testing.T) {
+		results := evaluator.Evaluate([]model.Assertion{{Type: "tool_call_count", Tool: "screenshot_control", Min: n(1), Max: n(2)}})
+		require.Len(t, results, 1)
+		assert.Equal(t, map[string]interface{}{
+			"tool":     "screenshot_control",
+			"observed": 3,
+			"actual":   3,
+			"expected": "at least 1 and at most 2",
+			"min":      1,
+			"max":      2,
+		}, results[0].Details)
+
+		results = evaluator.Evaluate([]model.Assertion{{Type: "tool_call_count", Tool: "screenshot_control", Count: 3}})
+		require.Len(t, results, 1)
+		assert.Equal(t, 3, results[0].Details["actual"])
+		assert.Equal(t, 3, results[0].Details["expected"])
+	})
+
+	t.Run("Combinators", func(t *testing.T) {
+		results := evaluator.Evaluate([]model.Assertion{
+			{AnyOf: []model.Assertion{
+				{Type: "tool_call_count", Tool: "write_file", Exact: n(2)},
+				{Type: "tool_call_count", Tool: "screenshot_control", Min: n(3)},
+			}},
+			{AllOf: []model.Assertion{
+				{Type: "tool_call_count", Tool: "write_file", Max: n(1)},
+				{Type: "tool_call_count", Tool: "screenshot_control", Max: n(2)},
+			}},
+			{Not: &model.Assertion{Type: "tool_call_count", Tool: "screenshot_control", Max: n(2)}},
+		})
+		require.Len(t, results, 3)
+		assert.True(t, results[0].Passed, "anyOf")
+		assert.False(t, results[1].Passed, "allOf")
+		assert.True(t, results[2].Passed, "not")
+	})
+}
+
 func TestAssertionEvaluator_ToolCallOrder(t *testing.T) {
 	result := &model.ExecutionResult{
 		ToolCalls: []model.ToolCall{
@@ -492,6 +837,50 @@ func TestAssertionEvaluator_ToolCallOrder(t *testing.T) {
 	}
 }
 
+func TestAssertionEvaluator_ToolSubsequence(t *testing.T) {
+	result := &model.ExecutionResult{
+		ToolCalls: []model.ToolCall{
+			{Name: "app"},
+			{Name: "screenshot"},
+			{Name: "ui_type"},
+			{Name: "screenshot"},
+			{Name: "window_management"},
+		},
+	}
+	evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+	tests := []struct {
+		name          string
+		sequence      []string
+		wantPassed    bool
+		wantPositions []int
+		wantFailed    string
+	}{
+		{name: "Interleaved calls", sequence: []string{"app", "ui_type", "window_management"}, wantPassed: true, wantPositions: []int{0, 2, 4}},
+		{name: "Repeated tool", sequence: []string{"screenshot", "screenshot"}, wantPassed: true, wantPositions: []int{1, 3}},
+		{name: "Out of order", sequence: []string{"ui_type", "app"}, wantPassed: false, wantPositions: []int{2}, wantFailed: "app"},
+		{name: "Never called", sequence: []string{"close_app"}, wantPassed: false, wantPositions: []int{}, wantFailed: "close_app"},
+		{name: "Empty sequence", sequence: nil, wantPassed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := evaluator.Evaluate([]model.Assertion{{Type: "tool_subsequence", Sequence: tt.sequence}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantPositions != nil {
+				assert.Equal(t, tt.wantPositions, results[0].Details["positions"])
+			}
+			if tt.wantFailed != "" {
+				assert.Equal(t, tt.wantFailed, results[0].Details["failed_tool"])
+			}
+		})
+	}
+
+	results := evaluator.Evaluate([]model.Assertion{{Type: "tool_subsequence", Sequence: []string{"window_management", "app"}}})
+	assert.Equal(t, "Tool subsequence broken at step 2: 'app' was not called after 'window_management' (call 5)", results[0].Message)
+}
+
 func TestAssertionEvaluator_ToolParamEquals(t *testing.T) {
 	result := &model.ExecutionResult{
 		ToolCalls: []model.ToolCall{
@@ -600,6 +989,93 @@ func TestAssertionEvaluator_ToolParamMatchesRegex(t *testing.T) {
 	}
 }
 
+func TestAssertionEvaluator_ToolParamMatchesRegex_Match(t *testing.T) {
+	calls := []model.ToolCall{
+		{Name: "run_bash", Parameters: map[string]interface{}{"command": "git status"}},
+		{Name: "run_bash", Parameters: map[string]interface{}{"command": "git commit -m fix", "options": map[string]interface{}{"timeout": 30}}},
+		{Name: "run_bash", Parameters: map[string]interface{}{"command": "git push"}},
+		{Name: "read_file", Parameters: map[string]interface{}{"path": "/tmp/a"}},
+	}
+	evaluate := func(params map[string]string, match string) model.AssertionResult {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{ToolCalls: calls}, map[string]string{}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{{Type: "tool_param_matches_regex", Tool: "run_bash", Params: params, Match: match}})
+		require.Len(t, results, 1)
+		return results[0]
+	}
+
+	t.Run("Any call matches", func(t *testing.T) {
+		result := evaluate(map[string]string{"command": "^git (commit|push)"}, "")
+		assert.True(t, result.Passed, result.Message)
+		result = evaluate(map[string]string{"command": "^git (commit|push)"}, "any")
+		assert.True(t, result.Passed, result.Message)
+	})
+
+	t.Run("All calls must match", func(t *testing.T) {
+		result := evaluate(map[string]string{"command": "^git (commit|push)"}, "all")
+		assert.False(t, result.Passed)
+		assert.Equal(t, "1 of 3 calls to tool 'run_bash' have parameters not matching regex: [call 1: param 'command': value 'git status' does not match regex '^git (commit|push)']", result.Message)
+
+		result = evaluate(map[string]string{"command": "^git "}, "all")
+		assert.True(t, result.Passed, result.Message)
+		assert.Equal(t, "All 3 calls to tool 'run_bash' have parameters matching regex patterns", result.Message)
+	})
+
+	t.Run("Nested param missing from some calls", func(t *testing.T) {
+		result := evaluate(map[string]string{"options.timeout": `^\d+$`}, "any")
+		assert.True(t, result.Passed, result.Message)
+
+		result = evaluate(map[string]string{"options.timeout": `^\d+$`}, "all")
+		assert.False(t, result.Passed)
+		assert.Contains(t, result.Message, "call 1: missing param 'options.timeout'")
+		assert.Contains(t, result.Message, "call 3: missing param 'options.timeout'")
+	})
+
+	t.Run("Details", func(t *testing.T) {
+		result := evaluate(map[string]string{"command": "^git (commit|push)"}, "any")
+		assert.Equal(t, "command", result.Details["param"])
+		assert.Equal(t, "^git (commit|push)", result.Details["pattern"])
+		assert.Equal(t, "git commit -m fix", result.Details["value"])
+		assert.Equal(t, 2, result.Details["invocation"])
+
+		result = evaluate(map[string]string{"command": "^git (commit|push)"}, "all")
+		assert.Equal(t, []interface{}{"git status", "git commit -m fix", "git push"}, result.Details["values"])
+		assert.Equal(t, []string{"call 1: param 'command': value 'git status' does not match regex '^git (commit|push)'"}, result.Details["mismatches"])
+
+		result = evaluate(map[string]string{"command": "^git commit", "options.timeout": "^30$"}, "any")
+		assert.True(t, result.Passed, result.Message)
+		assert.Equal(t, map[string]string{"command": "git commit -m fix", "options.timeout": "30"}, result.Details["value"])
+		assert.Equal(t, map[string]string{"command": "^git commit", "options.timeout": "^30$"}, result.Details["patterns"])
+	})
+
+	t.Run("Non-string values are matched as JSON", func(t *testing.T) {
+		result := evaluate(map[string]string{"options": `^\{"timeout":30\}$`}, "any")
+		assert.True(t, result.Passed, result.Message)
+		assert.Equal(t, `{"timeout":30}`, result.Details["value"])
+	})
+
+	t.Run("tool_param_regex alias", func(t *testing.T) {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{ToolCalls: calls}, map[string]string{}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{
+			{Type: "tool_param_regex", Tool: "run_bash", Param: "command", Pattern: "^git (commit|push)"},
+			{Type: "tool_param_regex", Tool: "run_bash", Param: "command", Pattern: "^git (commit|push)", Match: "all"},
+			{Type: "tool_param_regex", Tool: "run_bash", Pattern: "git"},
+		})
+		require.Len(t, results, 3)
+		assert.True(t, results[0].Passed, results[0].Message)
+		assert.Equal(t, "tool_param_regex", results[0].Type)
+		assert.Equal(t, "git commit -m fix", results[0].Details["value"])
+		assert.Equal(t, "^git (commit|push)", results[0].Details["pattern"])
+		assert.False(t, results[1].Passed)
+		assert.Equal(t, "tool_param_regex requires a 'tool', a 'param' and a 'pattern'", results[2].Message)
+	})
+
+	t.Run("Invalid match", func(t *testing.T) {
+		result := evaluate(map[string]string{"command": "git"}, "some")
+		assert.False(t, result.Passed)
+		assert.Equal(t, "Invalid match 'some' (must be 'any' or 'all')", result.Message)
+	})
+}
+
 func TestAssertionEvaluator_OutputContains(t *testing.T) {
 	result := &model.ExecutionResult{
 		FinalOutput: "The weather in New York is sunny",
@@ -1049,6 +1525,55 @@ func TestAssertionEvaluator_OutputRegex_EdgeCases(t *testing.T) {
 	})
 }
 
+func TestAssertionEvaluator_OutputNotRegex(t *testing.T) {
+	evaluate := func(output string, a model.Assertion) model.AssertionResult {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{FinalOutput: output}, map[string]string{}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{a})
+		require.Len(t, results, 1)
+		return results[0]
+	}
+	apology := model.Assertion{Type: "output_not_regex", Pattern: `(?i)i'?m sorry`}
+
+	t.Run("No match passes", func(t *testing.T) {
+		result := evaluate("Here is the file listing.", apology)
+		assert.True(t, result.Passed, result.Message)
+		assert.Nil(t, result.Details)
+	})
+
+	t.Run("Match fails with matched text", func(t *testing.T) {
+		result := evaluate("Well, Im Sorry, I cannot do that.", apology)
+		assert.False(t, result.Passed)
+		assert.Equal(t, "Im Sorry", result.Details["match"])
+		assert.Equal(t, 6, result.Details["position"])
+		assert.Contains(t, result.Message, `"Im Sorry"`)
+	})
+
+	t.Run("Invalid pattern fails", func(t *testing.T) {
+		result := evaluate("anything", model.Assertion{Type: "output_not_regex", Pattern: `(unclosed`})
+		assert.False(t, result.Passed)
+		assert.Contains(t, result.Message, "Invalid regex '(unclosed'")
+		assert.Contains(t, result.Message, "missing closing )")
+	})
+
+	t.Run("Missing pattern fails", func(t *testing.T) {
+		result := evaluate("anything", model.Assertion{Type: "output_not_regex"})
+		assert.False(t, result.Passed)
+		assert.Equal(t, "output_not_regex requires a 'pattern'", result.Message)
+	})
+
+	t.Run("Inside combinators", func(t *testing.T) {
+		output := "I'm sorry, the file is missing."
+		notResult := evaluate(output, model.Assertion{Not: &apology})
+		assert.True(t, notResult.Passed, notResult.Message)
+
+		anyOf := evaluate(output, model.Assertion{AnyOf: []model.Assertion{apology, {Type: "output_contains", Value: "missing"}}})
+		assert.True(t, anyOf.Passed, anyOf.Message)
+
+		allOf := evaluate(output, model.Assertion{AllOf: []model.Assertion{apology, {Type: "output_contains", Value: "missing"}}})
+		assert.False(t, allOf.Passed, allOf.Message)
+	})
+}
+
 func BenchmarkAssertionEvaluator_OutputRegex(b *testing.B) {
 	result := &model.ExecutionResult{
 		FinalOutput: "The quick brown fox jumps over the lazy dog. Temperature: 72°F. Email: test@example.com",
@@ -1109,6 +1634,56 @@ func TestAssertionEvaluator_MaxTokens(t *testing.T) {
 	}
 }
 
+func TestAssertionEvaluator_MaxTokensUsed(t *testing.T) {
+	result := &model.ExecutionResult{
+		TokensUsed:       1200,
+		PromptTokens:     1000,
+		CompletionTokens: 200,
+	}
+
+	evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+	tests := []struct {
+		name        string
+		value       string
+		field       string
+		wantPassed  bool
+		wantActual  int
+		wantMessage string
+	}{
+		{name: "Total under budget", value: "2000", wantPassed: true, wantActual: 1200},
+		{name: "Total over budget", value: "1000", wantPassed: false, wantActual: 1200, wantMessage: "exceeds budget 1000 by 200"},
+		{name: "Completion within budget", value: "200", field: "completion", wantPassed: true, wantActual: 200},
+		{name: "Prompt over budget", value: "500", field: "prompt", wantPassed: false, wantActual: 1000},
+		{name: "Invalid budget", value: "lots", wantPassed: false, wantMessage: "Invalid token budget"},
+		{name: "Invalid field", value: "100", field: "cached", wantPassed: false, wantMessage: "Invalid token field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := evaluator.Evaluate([]model.Assertion{{Type: "max_tokens_used", Value: tt.value, Field: tt.field}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+			}
+			if tt.wantActual != 0 {
+				assert.Equal(t, tt.wantActual, results[0].Details["actual"])
+			}
+		})
+	}
+
+	t.Run("Missing breakdown is skipped with a warning", func(t *testing.T) {
+		totalOnly := &model.ExecutionResult{TokensUsed: 5000}
+		results := model.NewAssertionEvaluator(totalOnly, map[string]string{}, []string{}).
+			Evaluate([]model.Assertion{{Type: "max_tokens_used", Value: "100", Field: "completion"}})
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Passed)
+		assert.Contains(t, results[0].Message, "Warning")
+		assert.NotEmpty(t, results[0].Details["warning"])
+	})
+}
+
 func TestAssertionEvaluator_MaxLatency(t *testing.T) {
 	result := &model.ExecutionResult{
 		LatencyMs: 2500,
@@ -1146,6 +1721,48 @@ func TestAssertionEvaluator_MaxLatency(t *testing.T) {
 	}
 }
 
+func TestAssertionEvaluator_FirstToolWithin(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	result := &model.ExecutionResult{
+		StartTime: start,
+		ToolCalls: []model.ToolCall{
+			{Name: "write_file", Timestamp: start.Add(3 * time.Second)},
+			{Name: "read_file", Timestamp: start.Add(1200 * time.Millisecond)},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		result     *model.ExecutionResult
+		limit      string
+		wantPassed bool
+		wantMsg    string
+	}{
+		{name: "Within limit", result: result, limit: "2000", wantPassed: true, wantMsg: "Time to first tool: 1200ms (max: 2000ms)"},
+		{name: "Over limit", result: result, limit: "1000", wantPassed: false, wantMsg: "Time to first tool: 1200ms (max: 1000ms)"},
+		{name: "No tool calls", result: &model.ExecutionResult{StartTime: start}, limit: "2000", wantPassed: false, wantMsg: "No tool was ever called (limit: 2000ms)"},
+		{name: "Invalid limit", result: result, limit: "soon", wantPassed: false, wantMsg: "Invalid first tool time limit: soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := model.NewAssertionEvaluator(tt.result, map[string]string{}, []string{})
+			results := evaluator.Evaluate([]model.Assertion{{Type: "first_tool_within_ms", Value: tt.limit}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed)
+			assert.Equal(t, tt.wantMsg, results[0].Message)
+		})
+	}
+
+	t.Run("Details report time to first tool", func(t *testing.T) {
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{{Type: "first_tool_within_ms", Value: "2000"}})
+		require.Len(t, results, 1)
+		assert.Equal(t, int64(1200), results[0].Details["time_to_first_tool_ms"])
+		assert.Equal(t, "read_file", results[0].Details["first_tool"])
+	})
+}
+
 func TestAssertionEvaluator_NoErrorMessages(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -1179,38 +1796,122 @@ func TestAssertionEvaluator_NoErrorMessages(t *testing.T) {
 	}
 }
 
-func TestAssertionEvaluator_NoHallucinatedTools(t *testing.T) {
-	knownTools := []string{"get_weather", "calculate"}
-
+func TestAssertionEvaluator_ExpectedError(t *testing.T) {
 	tests := []struct {
-		name       string
-		toolCalls  []model.ToolCall
-		wantPassed bool
+		name        string
+		pattern     string
+		errors      []string
+		wantPassed  bool
+		wantMatched []string
+		wantMessage string
 	}{
 		{
-			name: "All known tools",
-			toolCalls: []model.ToolCall{
-				{Name: "get_weather"},
-				{Name: "calculate"},
-			},
-			wantPassed: true,
+			name:        "Matching error",
+			pattern:     "connection refused",
+			errors:      []string{"tool timed out", "dial tcp 127.0.0.1:9: connection refused"},
+			wantPassed:  true,
+			wantMatched: []string{"dial tcp 127.0.0.1:9: connection refused"},
 		},
 		{
-			name: "Contains hallucinated tool",
-			toolCalls: []model.ToolCall{
-				{Name: "get_weather"},
-				{Name: "unknown_tool"},
-			},
-			wantPassed: false,
+			name:        "Case-insensitive regex",
+			pattern:     "(?i)permission denied|forbidden",
+			errors:      []string{"HTTP 403 Forbidden"},
+			wantPassed:  true,
+			wantMatched: []string{"HTTP 403 Forbidden"},
+		},
+		{
+			name:        "Only unexpected errors",
+			pattern:     "connection refused",
+			errors:      []string{"tool timed out"},
+			wantPassed:  false,
+			wantMatched: []string{},
+			wantMessage: "None of 1 error(s)",
+		},
+		{
+			name:        "No error",
+			pattern:     "connection refused",
+			wantPassed:  false,
+			wantMessage: "no error occurred",
+		},
+		{
+			name:        "Missing pattern",
+			errors:      []string{"boom"},
+			wantPassed:  false,
+			wantMessage: "requires a pattern",
+		},
+		{
+			name:        "Invalid pattern",
+			pattern:     "(",
+			errors:      []string{"boom"},
+			wantPassed:  false,
+			wantMessage: "Invalid regex",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := &model.ExecutionResult{
-				ToolCalls: tt.toolCalls,
-			}
-			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, knownTools)
+			result := &model.ExecutionResult{Errors: tt.errors}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "expected_error", Pattern: tt.pattern}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed)
+			assert.Contains(t, results[0].Message, tt.wantMessage)
+			if tt.wantMatched != nil {
+				assert.Equal(t, tt.wantMatched, results[0].Details["matched"])
+			}
+		})
+	}
+
+	t.Run("No error allowed with require_error false", func(t *testing.T) {
+		var a model.Assertion
+		require.NoError(t, yaml.Unmarshal([]byte("type: expected_error\npattern: rate limit\nrequire_error: false"), &a))
+
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{}, map[string]string{}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{a})
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Passed, results[0].Message)
+		assert.Equal(t, "No error occurred (require_error: false)", results[0].Message)
+
+		evaluator = model.NewAssertionEvaluator(&model.ExecutionResult{Errors: []string{"tool timed out"}}, map[string]string{}, []string{})
+		results = evaluator.Evaluate([]model.Assertion{a})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed, "other errors still fail")
+	})
+}
+
+func TestAssertionEvaluator_NoHallucinatedTools(t *testing.T) {
+	knownTools := []string{"get_weather", "calculate"}
+
+	tests := []struct {
+		name       string
+		toolCalls  []model.ToolCall
+		wantPassed bool
+	}{
+		{
+			name: "All known tools",
+			toolCalls: []model.ToolCall{
+				{Name: "get_weather"},
+				{Name: "calculate"},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "Contains hallucinated tool",
+			toolCalls: []model.ToolCall{
+				{Name: "get_weather"},
+				{Name: "unknown_tool"},
+			},
+			wantPassed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{
+				ToolCalls: tt.toolCalls,
+			}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, knownTools)
 
 			assertion := model.Assertion{Type: "no_hallucinated_tools"}
 			results := evaluator.Evaluate([]model.Assertion{assertion})
@@ -1285,6 +1986,7 @@ func TestAssertionEvaluator_NoRateLimitErrors(t *testing.T) {
 	tests := []struct {
 		name           string
 		rateLimitStats *model.RateLimitStats
+		errorDetails   []model.ExecutionError
 		wantPassed     bool
 		wantMessage    string
 	}{
@@ -1325,12 +2027,29 @@ func TestAssertionEvaluator_NoRateLimitErrors(t *testing.T) {
 			wantPassed:  false,
 			wantMessage: "Received 3 rate limit error(s) (HTTP 429)",
 		},
+		{
+			name: "Rate limit error without stats",
+			errorDetails: []model.ExecutionError{
+				{Kind: model.ErrorKindRateLimit, Message: "LLM generation error (iteration 1): quota exhausted"},
+			},
+			wantPassed:  false,
+			wantMessage: "Received 1 rate limit error(s) (HTTP 429)",
+		},
+		{
+			name: "Other error kinds are ignored",
+			errorDetails: []model.ExecutionError{
+				{Kind: model.ErrorKindTestTimeout, Message: "Test timed out after 429ms"},
+			},
+			wantPassed:  true,
+			wantMessage: "No rate limit errors (HTTP 429)",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := &model.ExecutionResult{
 				RateLimitStats: tt.rateLimitStats,
+				ErrorDetails:   tt.errorDetails,
 			}
 			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
 
@@ -1339,8 +2058,40 @@ func TestAssertionEvaluator_NoRateLimitErrors(t *testing.T) {
 			require.Len(t, results, 1)
 			assert.Equal(t, tt.wantPassed, results[0].Passed)
 			assert.Equal(t, tt.wantMessage, results[0].Message)
+			assert.Contains(t, results[0].Details, "rate_limit_hits")
+			assert.Contains(t, results[0].Details, "retry_success")
 		})
 	}
+
+	t.Run("Details report retry outcomes", func(t *testing.T) {
+		result := &model.ExecutionResult{
+			RateLimitStats: &model.RateLimitStats{
+				RateLimitHits:     2,
+				RetryCount:        2,
+				RetryWaitTimeMs:   3000,
+				RetrySuccessCount: 1,
+			},
+		}
+		result.AddError(model.ErrorKindRateLimit, "LLM generation error (iteration 2): Too Many Requests")
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+		results := evaluator.Evaluate([]model.Assertion{{Type: "no_rate_limit_errors"}})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed)
+		assert.Equal(t, "Received 2 rate limit error(s) (HTTP 429)", results[0].Message)
+		assert.Equal(t, 2, results[0].Details["rate_limit_hits"])
+		assert.Equal(t, 1, results[0].Details["rate_limit_errors"])
+		assert.Equal(t, 2, results[0].Details["retry_count"])
+		assert.Equal(t, int64(3000), results[0].Details["retry_wait_time_ms"])
+		assert.Equal(t, 1, results[0].Details["retry_success"])
+	})
+}
+
+func TestIsRateLimitError(t *testing.T) {
+	assert.False(t, model.IsRateLimitError(nil))
+	assert.True(t, model.IsRateLimitError(errors.New("status code 429")))
+	assert.True(t, model.IsRateLimitError(errors.New("Too Many Requests")))
+	assert.False(t, model.IsRateLimitError(errors.New("connection refused")))
 }
 
 // ============================================================================
@@ -1882,6 +2633,15 @@ func TestAssertionEvaluator_ToolResultMatchesJson(t *testing.T) {
 			value:      "success",
 			wantPassed: true,
 		},
+		{
+			name: "Dotted path match",
+			toolResult: `{
+				"data": {"status": "success"}
+			}`,
+			path:       "data.status",
+			value:      "success",
+			wantPassed: true,
+		},
 		{
 			name: "Nested JSONPath match",
 			toolResult: `{
@@ -2069,161 +2829,1150 @@ func TestAssertionEvaluator_ToolResultMatchesJson_MultipleCalls(t *testing.T) {
 	})
 }
 
-func TestAssertionEvaluator_OutputNotContains(t *testing.T) {
+func TestAssertionEvaluator_ToolSucceeded(t *testing.T) {
+	call := func(text string) model.ToolCall {
+		return model.ToolCall{
+			Name: "test_tool",
+			Result: model.Result{
+				Content: []model.ContentItem{{Type: "text", Text: text}},
+			},
+		}
+	}
+
 	tests := []struct {
-		name       string
-		output     string
-		value      string
-		wantPassed bool
+		name        string
+		calls       []model.ToolCall
+		field       string
+		wantPassed  bool
+		wantMessage string
 	}{
 		{
-			name:       "Does not contain substring",
-			output:     "The weather is sunny today",
-			value:      "rainy",
+			name:       "Single call ok true",
+			calls:      []model.ToolCall{call(`{"ok": true, "id": 1}`)},
 			wantPassed: true,
 		},
 		{
-			name:       "Contains substring (should fail)",
-			output:     "The weather is sunny today",
-			value:      "sunny",
-			wantPassed: false,
+			name:        "Single call ok false",
+			calls:       []model.ToolCall{call(`{"ok": false, "error": "boom"}`)},
+			wantPassed:  false,
+			wantMessage: "field 'ok' is false",
 		},
 		{
-			name:       "Case sensitive - different case",
-			output:     "Hello World",
-			value:      "hello",
+			name:        "Missing ok field",
+			calls:       []model.ToolCall{call(`{"id": 1}`)},
+			wantPassed:  false,
+			wantMessage: "field 'ok' missing",
+		},
+		{
+			name:        "Non-JSON result",
+			calls:       []model.ToolCall{call(`not json`)},
+			wantPassed:  false,
+			wantMessage: "failed to parse result JSON",
+		},
+		{
+			name:       "Custom field name",
+			calls:      []model.ToolCall{call(`{"success": true}`)},
+			field:      "success",
 			wantPassed: true,
 		},
 		{
-			name:       "Case sensitive - exact match",
-			output:     "Hello World",
-			value:      "Hello",
-			wantPassed: false,
+			name:        "Second invocation fails",
+			calls:       []model.ToolCall{call(`{"ok": true}`), call(`{"ok": false}`)},
+			wantPassed:  false,
+			wantMessage: "invocation 2",
 		},
 		{
-			name:       "Empty value",
-			output:     "Some text",
-			value:      "",
-			wantPassed: false,
+			name:        "Tool not called",
+			wantPassed:  false,
+			wantMessage: "was not called",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{ToolCalls: tt.calls}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{
+				Type:  "tool_succeeded",
+				Tool:  "test_tool",
+				Field: tt.field,
+			}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+			}
+		})
+	}
+
+	t.Run("Details list every failed invocation", func(t *testing.T) {
+		result := &model.ExecutionResult{
+			ToolCalls: []model.ToolCall{
+				call(`{"ok": false}`),
+				{Name: "other_tool"},
+				call(`{"ok": true}`),
+				call(`{"ok": false}`),
+			},
+		}
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+		results := evaluator.Evaluate([]model.Assertion{{Type: "tool_succeeded", Tool: "test_tool"}})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed)
+
+		failures, ok := results[0].Details["failures"].([]map[string]interface{})
+		require.True(t, ok)
+		require.Len(t, failures, 2)
+		assert.Equal(t, 1, failures[0]["invocation"])
+		assert.Equal(t, 3, failures[1]["invocation"])
+		assert.Equal(t, 3, failures[1]["call_index"])
+	})
+}
+
+func TestAssertionEvaluator_OutputReflectsToolResult(t *testing.T) {
+	call := func(text string) model.ToolCall {
+		tc := model.ToolCall{Name: "read_file"}
+		tc.Result.Content = []model.ContentItem{{Type: "text", Text: text}}
+		return tc
+	}
+	errored := call(`{"server": {"port": 9090}}`)
+	errored.Result.IsError = true
+
+	tests := []struct {
+		name        string
+		calls       []model.ToolCall
+		output      string
+		path        string
+		wantPassed  bool
+		wantMessage string
+	}{
+		{
+			name:       "Number reflected",
+			calls:      []model.ToolCall{call(`{"server": {"port": 8080}}`)},
+			output:     "The server listens on port 8080.",
+			path:       "server.port",
+			wantPassed: true,
 		},
 		{
-			name:       "Special characters",
-			output:     "Price: $50.00",
-			value:      "$100",
+			name:       "String reflected ignoring case",
+			calls:      []model.ToolCall{call(`{"owner": "Platform Team"}`)},
+			output:     "It is owned by the platform team.",
+			path:       "$.owner",
 			wantPassed: true,
 		},
 		{
-			name:       "Special characters present",
-			output:     "Price: $50.00",
-			value:      "$50",
-			wantPassed: false,
+			name:        "Fabricated answer",
+			calls:       []model.ToolCall{call(`{"server": {"port": 8080}}`)},
+			output:      "The server listens on port 3000.",
+			path:        "server.port",
+			wantPassed:  false,
+			wantMessage: "does not contain the 'server.port' value returned by tool 'read_file' (8080)",
 		},
 		{
-			name:       "Partial word match",
-			output:     "The test passed",
-			value:      "fail",
-			wantPassed: true,
+			name:        "Number inside a larger number",
+			calls:       []model.ToolCall{call(`{"server": {"port": 80}}`)},
+			output:      "The server listens on port 8080 and 8.80.",
+			path:        "server.port",
+			wantPassed:  false,
+			wantMessage: "does not contain the 'server.port' value returned by tool 'read_file' (80)",
 		},
 		{
-			name:       "Whitespace in value",
-			output:     "Hello World",
-			value:      "Hello  World",
+			name:        "Number inside a decimal",
+			calls:       []model.ToolCall{call(`{"ratio": 5}`)},
+			output:      "The ratio is 5.2.",
+			path:        "ratio",
+			wantPassed:  false,
+			wantMessage: "does not contain",
+		},
+		{
+			name:       "Number at the end of a sentence",
+			calls:      []model.ToolCall{call(`{"server": {"port": 80}}`)},
+			output:     "It uses port 8080, but also port 80.",
+			path:       "server.port",
 			wantPassed: true,
 		},
 		{
-			name:       "Newline in output",
-			output:     "Line 1\nLine 2\nLine 3",
-			value:      "Line 4",
+			name:        "Word inside a longer word",
+			calls:       []model.ToolCall{call(`{"env": "prod"}`)},
+			output:      "Deployed to production.",
+			path:        "env",
+			wantPassed:  false,
+			wantMessage: "does not contain",
+		},
+		{
+			name:       "Any call may supply the value",
+			calls:      []model.ToolCall{call(`{"server": {"port": 8080}}`), call(`{"server": {"port": 8443}}`)},
+			output:     "TLS is served on 8443.",
+			path:       "server.port",
 			wantPassed: true,
 		},
 		{
-			name:       "Newline present",
-			output:     "Line 1\nLine 2",
-			value:      "Line 2",
-			wantPassed: false,
+			name:        "Errors and missing paths are skipped",
+			calls:       []model.ToolCall{errored, call("plain text"), call(`{"server": {}}`)},
+			output:      "Port 9090",
+			path:        "server.port",
+			wantPassed:  false,
+			wantMessage: "No result of tool 'read_file' has a value at 'server.port'",
+		},
+		{
+			name:        "Object values are not compared",
+			calls:       []model.ToolCall{call(`{"server": {"port": 8080}}`)},
+			output:      "8080",
+			path:        "server",
+			wantPassed:  false,
+			wantMessage: "No result",
+		},
+		{
+			name:        "Tool not called",
+			output:      "8080",
+			path:        "server.port",
+			wantPassed:  false,
+			wantMessage: "was not called",
+		},
+		{
+			name:        "Missing path",
+			calls:       []model.ToolCall{call(`{"server": {"port": 8080}}`)},
+			wantPassed:  false,
+			wantMessage: "requires a 'tool' and a 'path'",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := &model.ExecutionResult{
-				FinalOutput: tt.output,
-			}
-
+			result := &model.ExecutionResult{ToolCalls: tt.calls, FinalOutput: tt.output}
 			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
 
-			assertion := model.Assertion{
-				Type:  "output_not_contains",
-				Value: tt.value,
-			}
-
-			results := evaluator.Evaluate([]model.Assertion{assertion})
+			results := evaluator.Evaluate([]model.Assertion{{
+				Type: "output_reflects_tool_result",
+				Tool: "read_file",
+				Path: tt.path,
+			}})
 			require.Len(t, results, 1)
-			assert.Equal(t, tt.wantPassed, results[0].Passed,
-				"Expected passed=%v, got passed=%v. Message: %s",
-				tt.wantPassed, results[0].Passed, results[0].Message)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+			}
 		})
 	}
 }
 
-// ============================================================================
-// JSON Report with AI Summary Tests
-// ============================================================================
-
-func TestGenerateJSONReportWithAnalysis(t *testing.T) {
-	t.Run("JSON report includes test_file field", func(t *testing.T) {
-		reporter := model.NewReportGenerator()
-		reporter.TestFile = "path/to/test.yaml"
-		results := []model.TestRun{
-			{
-				Passed: true,
-				Execution: &model.ExecutionResult{
-					TestName:  "test1",
-					AgentName: "agent1",
-				},
-			},
-		}
-
-		jsonOutput := reporter.GenerateJSONReportWithAnalysis(results, nil)
-
-		// Verify JSON contains test_file
-		assert.Contains(t, jsonOutput, `"test_file"`)
-		assert.Contains(t, jsonOutput, `path/to/test.yaml`)
-	})
-
-	t.Run("JSON report never includes ai_summary (late-binding)", func(t *testing.T) {
-		reporter := model.NewReportGenerator()
-		results := []model.TestRun{
-			{
-				Passed: true,
-				Execution: &model.ExecutionResult{
-					TestName:  "test1",
-					AgentName: "agent1",
-				},
-			},
-		}
+func TestPercentile(t *testing.T) {
+	assert.Zero(t, model.Percentile(nil, 95), "no values")
+	assert.Equal(t, 42.0, model.Percentile([]float64{42}, 95), "a single value")
 
-		// Even when analysis is provided, it should NOT be included in JSON
-		// AI summary is now generated at HTML report time (late-binding)
-		analysis := &model.AISummaryData{
-			Success:  true,
-			Analysis: "## Summary\n\nAll tests passed successfully.",
-		}
+	sorted := []float64{10, 20, 30, 40}
+	assert.Equal(t, 10.0, model.Percentile(sorted, 0))
+	assert.Equal(t, 25.0, model.Percentile(sorted, 50), "interpolated between ranks")
+	assert.InDelta(t, 37.0, model.Percentile(sorted, 90), 0.001)
+	assert.Equal(t, 40.0, model.Percentile(sorted, 100))
 
-		jsonOutput := reporter.GenerateJSONReportWithAnalysis(results, analysis)
+	p50, p90, p95 := model.LatencyPercentiles(nil)
+	assert.Zero(t, p50+p90+p95)
+}
 
-		// Verify JSON does NOT contain ai_summary
-		assert.NotContains(t, jsonOutput, `"ai_summary"`)
-	})
+func TestAssertionEvaluator_JSONPath(t *testing.T) {
+	call := func(text string) model.ToolCall {
+		tc := model.ToolCall{Name: "write_file"}
+		tc.Result.Content = []model.ContentItem{{Type: "text", Text: text}}
+		return tc
+	}
+	yes, no := true, false
+	written := call(`{"ok": true, "bytes": 11, "path": "/tmp/notes.txt", "tags": ["draft", "todo"]}`)
 
-	t.Run("JSON report without ai_summary when nil", func(t *testing.T) {
-		reporter := model.NewReportGenerator()
-		results := []model.TestRun{
-			{
-				Passed: true,
-				Execution: &model.ExecutionResult{
-					TestName:  "test1",
-					AgentName: "agent1",
+	tests := []struct {
+		name        string
+		calls       []model.ToolCall
+		assertion   model.Assertion
+		wantPassed  bool
+		wantMessage string
+		wantValue   interface{}
+	}{
+		{
+			name:       "Number equals",
+			calls:      []model.ToolCall{written},
+			assertion:  model.Assertion{Path: ".bytes", Equals: "11"},
+			wantPassed: true,
+			wantValue:  float64(11),
+		},
+		{
+			name:       "Boolean equals with JSONPath",
+			calls:      []model.ToolCall{written},
+			assertion:  model.Assertion{Path: "$.ok", Equals: "true"},
+			wantPassed: true,
+			wantValue:  true,
+		},
+		{
+			name:        "Type matters",
+			calls:       []model.ToolCall{written},
+			assertion:   model.Assertion{Path: "bytes", Equals: `"11"`},
+			wantPassed:  false,
+			wantMessage: `No result of tool 'write_file' has 'bytes' equal to "11": call 1: value is 11`,
+			wantValue:   float64(11),
+		},
+		{
+			name:       "String contains",
+			calls:      []model.ToolCall{written},
+			assertion:  model.Assertion{Path: "path", Contains: "notes"},
+			wantPassed: true,
+		},
+		{
+			name:       "Array contains",
+			calls:      []model.ToolCall{written},
+			assertion:  model.Assertion{Path: "tags", Contains: "todo"},
+			wantPassed: true,
+		},
+		{
+			name:        "Contains needs a string or array",
+			calls:       []model.ToolCall{written},
+			assertion:   model.Assertion{Path: "bytes", Contains: "1"},
+			wantPassed:  false,
+			wantMessage: "is not a string or an array",
+		},
+		{
+			name:       "Exists by default",
+			calls:      []model.ToolCall{written},
+			assertion:  model.Assertion{Path: "ok"},
+			wantPassed: true,
+		},
+		{
+			name:        "Missing path",
+			calls:       []model.ToolCall{written},
+			assertion:   model.Assertion{Path: "error", Exists: &yes},
+			wantPassed:  false,
+			wantMessage: "No result of tool 'write_file' has a value at 'error'",
+		},
+		{
+			name:       "Exists false",
+			calls:      []model.ToolCall{written},
+			assertion:  model.Assertion{Path: "error", Exists: &no},
+			wantPassed: true,
+		},
+		{
+			name:        "Exists false with value",
+			calls:       []model.ToolCall{call(`{"ok": true}`), call(`{"ok": false, "error": "disk full"}`)},
+			assertion:   model.Assertion{Path: "error", Exists: &no},
+			wantPassed:  false,
+			wantMessage: `has a value at 'error': "disk full"`,
+		},
+		{
+			name:       "Any call may match",
+			calls:      []model.ToolCall{call(`{"ok": false}`), written},
+			assertion:  model.Assertion{Path: "ok", Equals: "true"},
+			wantPassed: true,
+		},
+		{
+			name:        "Invalid JSON",
+			calls:       []model.ToolCall{call("wrote 11 bytes")},
+			assertion:   model.Assertion{Path: "bytes", Equals: "11"},
+			wantPassed:  false,
+			wantMessage: "call 1: result is not valid JSON",
+		},
+		{
+			name:        "Tool not called",
+			assertion:   model.Assertion{Path: "bytes", Equals: "11"},
+			wantPassed:  false,
+			wantMessage: "Tool 'write_file' was not called",
+		},
+		{
+			name:        "Only one check",
+			calls:       []model.ToolCall{written},
+			assertion:   model.Assertion{Path: "bytes", Equals: "11", Exists: &yes},
+			wantPassed:  false,
+			wantMessage: "only one of",
+		},
+		{
+			name:        "Path required",
+			calls:       []model.ToolCall{written},
+			wantPassed:  false,
+			wantMessage: "requires a 'tool' and a 'path'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{ToolCalls: tt.calls}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			a := tt.assertion
+			a.Type = "json_path"
+			a.Tool = "write_file"
+			results := evaluator.Evaluate([]model.Assertion{a})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+			}
+			if tt.wantValue != nil {
+				assert.Equal(t, tt.wantValue, results[0].Details["value"])
+				assert.Equal(t, a.Path, results[0].Details["path"])
+			}
+		})
+	}
+}
+
+func TestAssertionEvaluator_ConfirmsBeforeTool(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	ask := model.Message{Role: "assistant", Content: "This deletes report.txt permanently. Should I proceed?", Iteration: 1, Timestamp: start}
+	messages := []model.Message{
+		{Role: "user", Content: "Delete report.txt"},
+		ask,
+		{Role: "user", Content: "Yes"},
+		{Role: "assistant", Content: "Deleting it now.", Iteration: 2, Timestamp: start.Add(time.Second)},
+		{Role: "assistant", Content: "Done.", Iteration: 3, Timestamp: start.Add(2 * time.Second)},
+	}
+	deleteAt := func(iteration int) []model.ToolCall {
+		return []model.ToolCall{
+			{Name: "list_files", Iteration: 1},
+			{Name: "delete_file", Iteration: iteration, Timestamp: start.Add(time.Second)},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		calls       []model.ToolCall
+		stats       *model.ClarificationStats
+		tool        string
+		wantPassed  bool
+		wantMessage string
+	}{
+		{
+			name:        "Confirmed before the call",
+			calls:       deleteAt(2),
+			stats:       &model.ClarificationStats{Count: 1, Iterations: []int{1}},
+			tool:        "delete_file",
+			wantPassed:  true,
+			wantMessage: "Agent asked for confirmation before calling 'delete_file'",
+		},
+		{
+			name:        "No confirmation request",
+			calls:       deleteAt(2),
+			stats:       &model.ClarificationStats{Iterations: []int{}},
+			tool:        "delete_file",
+			wantPassed:  false,
+			wantMessage: "Agent called 'delete_file' without asking for confirmation first",
+		},
+		{
+			name:        "Confirmation requested only after the call",
+			calls:       deleteAt(1),
+			stats:       &model.ClarificationStats{Count: 1, Iterations: []int{3}},
+			tool:        "delete_file",
+			wantPassed:  false,
+			wantMessage: "without asking for confirmation first",
+		},
+		{
+			name:        "Tool not called",
+			calls:       []model.ToolCall{{Name: "list_files", Iteration: 1}},
+			stats:       &model.ClarificationStats{Iterations: []int{}},
+			tool:        "delete_file",
+			wantPassed:  true,
+			wantMessage: "Tool 'delete_file' was not called",
+		},
+		{
+			name:        "Clarification detection disabled",
+			calls:       deleteAt(2),
+			tool:        "delete_file",
+			wantPassed:  false,
+			wantMessage: "requires clarification_detection",
+		},
+		{
+			name:        "Missing tool",
+			stats:       &model.ClarificationStats{Iterations: []int{}},
+			wantPassed:  false,
+			wantMessage: "requires a 'tool'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{Messages: messages, ToolCalls: tt.calls, ClarificationStats: tt.stats}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "confirms_before_tool", Tool: tt.tool}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			assert.Contains(t, results[0].Message, tt.wantMessage)
+		})
+	}
+
+	t.Run("Details", func(t *testing.T) {
+		result := &model.ExecutionResult{
+			Messages:           messages,
+			ToolCalls:          deleteAt(2),
+			ClarificationStats: &model.ClarificationStats{Count: 1, Iterations: []int{1}},
+		}
+		results := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+			Evaluate([]model.Assertion{{Type: "confirms_before_tool", Tool: "delete_file"}})
+		require.Len(t, results, 1)
+		assert.Equal(t, ask.Content, results[0].Details["confirming_message"])
+		assert.Equal(t, start.Add(time.Second).Format(time.RFC3339Nano), results[0].Details["tool_call_timestamp"])
+	})
+}
+
+func TestAssertionEvaluator_ToolReturnedContent(t *testing.T) {
+	call := func(items ...string) model.ToolCall {
+		tc := model.ToolCall{Name: "test_tool"}
+		for _, text := range items {
+			tc.Result.Content = append(tc.Result.Content, model.ContentItem{Type: "text", Text: text})
+		}
+		return tc
+	}
+	errored := call("Tool execution error (iteration 1, tool test_tool): connection reset")
+	errored.Result.IsError = true
+
+	tests := []struct {
+		name        string
+		calls       []model.ToolCall
+		field       string
+		wantPassed  bool
+		wantMessage string
+	}{
+		{
+			name:       "Non-empty text",
+			calls:      []model.ToolCall{call("file contents")},
+			wantPassed: true,
+		},
+		{
+			name:       "Blank item followed by text",
+			calls:      []model.ToolCall{call("  ", "file contents")},
+			wantPassed: true,
+		},
+		{
+			name:        "No content",
+			calls:       []model.ToolCall{call()},
+			wantPassed:  false,
+			wantMessage: "no non-empty text content",
+		},
+		{
+			name:        "Only blank text",
+			calls:       []model.ToolCall{call("", " \n")},
+			wantPassed:  false,
+			wantMessage: "no non-empty text content",
+		},
+		{
+			name:        "Errored call",
+			calls:       []model.ToolCall{errored},
+			wantPassed:  false,
+			wantMessage: "tool returned an error",
+		},
+		{
+			name:       "Field true",
+			calls:      []model.ToolCall{call(`{"ok": true, "rows": 3}`)},
+			field:      "ok",
+			wantPassed: true,
+		},
+		{
+			name:        "Field false",
+			calls:       []model.ToolCall{call(`{"ok": false}`)},
+			field:       "ok",
+			wantPassed:  false,
+			wantMessage: "field 'ok' is false",
+		},
+		{
+			name:        "Second invocation empty",
+			calls:       []model.ToolCall{call("data"), call()},
+			wantPassed:  false,
+			wantMessage: "1 of 2 calls (first failure: invocation 2",
+		},
+		{
+			name:        "Tool not called",
+			wantPassed:  false,
+			wantMessage: "was not called",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{ToolCalls: tt.calls}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{
+				Type:  "tool_returned_content",
+				Tool:  "test_tool",
+				Field: tt.field,
+			}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+			}
+		})
+	}
+
+	t.Run("Details show the raw result", func(t *testing.T) {
+		result := &model.ExecutionResult{ToolCalls: []model.ToolCall{errored}}
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+		results := evaluator.Evaluate([]model.Assertion{{Type: "tool_returned_content", Tool: "test_tool"}})
+		require.Len(t, results, 1)
+		failures, ok := results[0].Details["failures"].([]map[string]interface{})
+		require.True(t, ok)
+		require.Len(t, failures, 1)
+		assert.Equal(t, true, failures[0]["is_error"])
+		assert.Contains(t, failures[0]["result"], "connection reset")
+	})
+}
+
+func TestAssertionEvaluator_NoHallucinatedParams(t *testing.T) {
+	toolParams := map[string][]string{
+		"create_file": {"path", "content"},
+		"list_files":  {},
+	}
+
+	tests := []struct {
+		name        string
+		toolCalls   []model.ToolCall
+		tool        string
+		wantPassed  bool
+		wantMessage string
+	}{
+		{
+			name: "Only declared params",
+			toolCalls: []model.ToolCall{
+				{Name: "create_file", Parameters: map[string]interface{}{"path": "a.txt", "content": "x"}},
+				{Name: "list_files", Parameters: map[string]interface{}{}},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "Undeclared param",
+			toolCalls: []model.ToolCall{
+				{Name: "create_file", Parameters: map[string]interface{}{"path": "a.txt", "mode": "0644", "force": true}},
+			},
+			wantPassed:  false,
+			wantMessage: "create_file(force, mode)",
+		},
+		{
+			name: "Tool without known schema is skipped",
+			toolCalls: []model.ToolCall{
+				{Name: "unknown_tool", Parameters: map[string]interface{}{"anything": 1}},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "Tool filter ignores other tools",
+			toolCalls: []model.ToolCall{
+				{Name: "list_files", Parameters: map[string]interface{}{"recursive": true}},
+			},
+			tool:       "create_file",
+			wantPassed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{ToolCalls: tt.toolCalls}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+				WithToolParameters(toolParams)
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "no_hallucinated_params", Tool: tt.tool}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+			}
+		})
+	}
+
+	t.Run("Details list offending tool and params", func(t *testing.T) {
+		result := &model.ExecutionResult{
+			ToolCalls: []model.ToolCall{
+				{Name: "list_files", Parameters: map[string]interface{}{"recursive": true}},
+			},
+		}
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+			WithToolParameters(toolParams)
+
+		results := evaluator.Evaluate([]model.Assertion{{Type: "no_hallucinated_params"}})
+		require.Len(t, results, 1)
+		violations, ok := results[0].Details["violations"].([]map[string]interface{})
+		require.True(t, ok)
+		require.Len(t, violations, 1)
+		assert.Equal(t, "list_files", violations[0]["tool"])
+		assert.Equal(t, []string{"recursive"}, violations[0]["unknown_params"])
+	})
+}
+
+func TestAssertionEvaluator_NoEmptyParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		toolCalls   []model.ToolCall
+		tool        string
+		wantPassed  bool
+		wantMessage string
+	}{
+		{
+			name: "All params set",
+			toolCalls: []model.ToolCall{
+				{Name: "connect", Parameters: map[string]interface{}{"host": "db.local", "port": 5432, "tls": false}},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "Empty string param",
+			toolCalls: []model.ToolCall{
+				{Name: "read_file", Parameters: map[string]interface{}{"path": "  "}},
+			},
+			wantPassed:  false,
+			wantMessage: "read_file.path (empty)",
+		},
+		{
+			name: "Unresolved template token",
+			toolCalls: []model.ToolCall{
+				{Name: "connect", Parameters: map[string]interface{}{"host": "{{HOST}}"}},
+			},
+			wantPassed:  false,
+			wantMessage: "connect.host (unresolved template {{HOST}})",
+		},
+		{
+			name: "Nested placeholder",
+			toolCalls: []model.ToolCall{
+				{Name: "query", Parameters: map[string]interface{}{
+					"filters": []interface{}{map[string]interface{}{"field": "name", "value": ""}},
+				}},
+			},
+			wantPassed:  false,
+			wantMessage: "query.filters (empty)",
+		},
+		{
+			name: "Tool filter ignores other tools",
+			toolCalls: []model.ToolCall{
+				{Name: "read_file", Parameters: map[string]interface{}{"path": ""}},
+			},
+			tool:       "connect",
+			wantPassed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{ToolCalls: tt.toolCalls}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "no_empty_params", Tool: tt.tool}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+				violations, ok := results[0].Details["violations"].([]map[string]interface{})
+				require.True(t, ok)
+				require.Len(t, violations, 1)
+				assert.Equal(t, tt.toolCalls[0].Name, violations[0]["tool"])
+			}
+		})
+	}
+}
+
+func TestAssertionEvaluator_MaxAssistantMessages(t *testing.T) {
+	messages := []model.Message{
+		{Role: "user", Content: "Create a file"},
+		{Role: "assistant", Content: "Which name?"},
+		{Role: "user", Content: "test.txt"},
+		{Role: "assistant", Content: "Creating it now"},
+		{Role: "assistant", Content: "Done"},
+	}
+
+	tests := []struct {
+		name       string
+		value      string
+		wantPassed bool
+	}{
+		{name: "Under limit", value: "5", wantPassed: true},
+		{name: "At limit", value: "3", wantPassed: true},
+		{name: "Over limit", value: "2", wantPassed: false},
+		{name: "Invalid value", value: "many", wantPassed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{Messages: messages}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "max_assistant_messages", Value: tt.value}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.value != "many" {
+				assert.Equal(t, 3, results[0].Details["actual"])
+			}
+		})
+	}
+}
+
+func TestAssertionEvaluator_HasFinalAnswer(t *testing.T) {
+	scaffolding := "\n[Iteration 1: 1 tool(s) to execute]\n\n[tool_usage 1/1] read_file\n\n[tool_response] {\"ok\":true}\n"
+
+	tests := []struct {
+		name       string
+		output     string
+		value      string
+		wantPassed bool
+		wantLength int
+	}{
+		{name: "Answer present", output: "The file contains 3 lines.", wantPassed: true, wantLength: 26},
+		{name: "Empty output", output: "", wantPassed: false, wantLength: 0},
+		{name: "Whitespace only", output: " \n\t ", wantPassed: false, wantLength: 0},
+		{name: "Scaffolding only", output: scaffolding, wantPassed: false, wantLength: 0},
+		{name: "Answer after scaffolding", output: scaffolding + "Done.", wantPassed: true, wantLength: 5},
+		{name: "Below minimum length", output: scaffolding + "Done.", value: "20", wantPassed: false, wantLength: 5},
+		{name: "At minimum length", output: "Done.", value: "5", wantPassed: true, wantLength: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{FinalOutput: tt.output}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "has_final_answer", Value: tt.value}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			assert.Equal(t, tt.wantLength, results[0].Details["length"])
+		})
+	}
+
+	t.Run("Invalid minimum length", func(t *testing.T) {
+		result := &model.ExecutionResult{FinalOutput: "Done."}
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+		results := evaluator.Evaluate([]model.Assertion{{Type: "has_final_answer", Value: "short"}})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed)
+		assert.Equal(t, "Invalid minimum length: short", results[0].Message)
+	})
+}
+
+func TestAssertionEvaluator_OutputOneOf(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []string
+		ignoreCase  bool
+		wantPassed  bool
+		wantMatched string
+	}{
+		{name: "Exact match", values: []string{"positive", "negative"}, wantPassed: true, wantMatched: "negative"},
+		{name: "Case mismatch fails", values: []string{"NEGATIVE"}, wantPassed: false},
+		{name: "Ignore case", values: []string{"Neutral", "NEGATIVE"}, ignoreCase: true, wantPassed: true, wantMatched: "NEGATIVE"},
+		{name: "Templated value", values: []string{"{{expected}}"}, wantPassed: true, wantMatched: "negative"},
+		{name: "No match", values: []string{"positive", "neutral"}, wantPassed: false},
+		{name: "Empty list", values: nil, wantPassed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{FinalOutput: "Sentiment: negative"}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{"expected": "negative"}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "output_one_of", Values: tt.values, IgnoreCase: tt.ignoreCase}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantPassed {
+				assert.Equal(t, tt.wantMatched, results[0].Details["matched"])
+			} else if len(tt.values) > 0 {
+				assert.Equal(t, tt.values, results[0].Details["candidates"])
+			}
+		})
+	}
+
+	t.Run("Value rendering empty does not match", func(t *testing.T) {
+		result := &model.ExecutionResult{FinalOutput: "Sentiment: negative"}
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{"blank": ""}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{{Type: "output_one_of", Values: []string{"{{blank}}", "positive"}}})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed, results[0].Message)
+	})
+}
+
+func TestAssertionEvaluator_OutputLanguage(t *testing.T) {
+	const english = "The quarterly report shows that revenue grew steadily across all regions, " +
+		"driven mostly by new customers in the enterprise segment and strong renewals."
+	const german = "Der Quartalsbericht zeigt, dass der Umsatz in allen Regionen stetig gewachsen ist, " +
+		"vor allem durch neue Kunden im Unternehmenssegment und starke Verlängerungen."
+
+	tests := []struct {
+		name         string
+		output       string
+		value        string
+		wantPassed   bool
+		wantMessage  string
+		wantTooShort bool
+	}{
+		{name: "English matches ISO 639-1", output: english, value: "en", wantPassed: true},
+		{name: "German matches ISO 639-3", output: german, value: "deu", wantPassed: true},
+		{name: "Wrong language", output: german, value: "en", wantPassed: false, wantMessage: "expected 'en'"},
+		{name: "Too short to detect", output: "Ja, fertig.", value: "en", wantPassed: true, wantTooShort: true},
+		{name: "Mojibake", output: "Caf\u00c3\u00a9 ordered and delivered to the customer address.", value: "en", wantPassed: false, wantMessage: "mis-encoded"},
+		{name: "Invalid UTF-8", output: "broken \xff\xfe bytes", value: "en", wantPassed: false, wantMessage: "not valid UTF-8"},
+		{name: "Missing value", output: english, value: "", wantPassed: false, wantMessage: "requires a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{FinalOutput: tt.output}
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "output_language", Value: tt.value}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMessage != "" {
+				assert.Contains(t, results[0].Message, tt.wantMessage)
+			}
+			if tt.wantTooShort {
+				assert.Equal(t, true, results[0].Details["too_short"])
+			}
+		})
+	}
+
+	t.Run("Threshold raises required confidence", func(t *testing.T) {
+		result := &model.ExecutionResult{FinalOutput: english}
+		evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+		results := evaluator.Evaluate([]model.Assertion{{Type: "output_language", Value: "en", Threshold: 1.01}})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed)
+		assert.Contains(t, results[0].Message, "below 1.01")
+		assert.Equal(t, "en", results[0].Details["detected"])
+	})
+}
+
+// stubJudge returns a fixed verdict or error
+type stubJudge struct {
+	name    string
+	verdict model.JudgeVerdict
+	err     error
+}
+
+func (j stubJudge) Name() string { return j.name }
+
+func (j stubJudge) Grade(ctx context.Context, rubric, output string) (model.JudgeVerdict, error) {
+	return j.verdict, j.err
+}
+
+func TestAssertionEvaluator_LLMRubric(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	pass := func(name string, score float64) model.Judge {
+		return stubJudge{name: name, verdict: model.JudgeVerdict{Pass: true, Score: score}}
+	}
+	fail := func(name string, score float64) model.Judge {
+		return stubJudge{name: name, verdict: model.JudgeVerdict{Pass: false, Score: score}}
+	}
+	broken := stubJudge{name: "broken", err: errors.New("rate limited")}
+
+	tests := []struct {
+		name        string
+		judges      []model.Judge
+		aggregation model.JudgeAggregation
+		threshold   float64
+		wantPassed  bool
+		wantScore   interface{}
+	}{
+		{name: "Majority pass", judges: []model.Judge{pass("a", 0.9), pass("b", 0.8), fail("c", 0.1)}, wantPassed: true, wantScore: 0.6},
+		{name: "Majority tie fails", judges: []model.Judge{pass("a", 0.9), fail("b", 0.2)}, aggregation: model.JudgeAggregationMajority, wantPassed: false, wantScore: 0.55},
+		{name: "Mean below threshold", judges: []model.Judge{pass("a", 0.6), fail("b", 0.2)}, aggregation: model.JudgeAggregationMean, threshold: 0.5, wantPassed: false, wantScore: 0.4},
+		{name: "Median", judges: []model.Judge{fail("a", 0.1), pass("b", 0.7), pass("c", 0.9)}, aggregation: model.JudgeAggregationMedian, wantPassed: true, wantScore: 0.7},
+		{name: "Erroring judge is excluded", judges: []model.Judge{broken, pass("a", 1)}, wantPassed: true, wantScore: 1.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			panel := &model.JudgePanel{Judges: tt.judges, Aggregation: tt.aggregation}
+			evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{FinalOutput: "Paris"}, nil, nil).
+				WithJudges(context.Background(), panel)
+
+			results := evaluator.Evaluate([]model.Assertion{{Type: "llm_rubric", Value: "names the capital of France", Threshold: tt.threshold}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			assert.InDelta(t, tt.wantScore, results[0].Details["score"], 1e-9)
+			assert.Len(t, results[0].Details["judges"], len(tt.judges))
+		})
+	}
+
+	t.Run("All judges failing fails the assertion", func(t *testing.T) {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{}, nil, nil).
+			WithJudges(context.Background(), &model.JudgePanel{Judges: []model.Judge{broken}})
+		results := evaluator.Evaluate([]model.Assertion{{Type: "llm_rubric", Value: "rubric"}})
+		assert.False(t, results[0].Passed)
+		assert.Contains(t, results[0].Message, "all 1 judges failed")
+		assert.Equal(t, map[string]interface{}{"judge": "broken", "error": "rate limited"}, results[0].Details["judges"].([]interface{})[0])
+	})
+
+	t.Run("Judges sharing a name are reported separately", func(t *testing.T) {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{}, nil, nil).
+			WithJudges(context.Background(), &model.JudgePanel{Judges: []model.Judge{pass("gpt", 0.9), fail("gpt", 0.1)}})
+		results := evaluator.Evaluate([]model.Assertion{{Type: "llm_rubric", Value: "rubric"}})
+		judges := results[0].Details["judges"].([]interface{})
+		require.Len(t, judges, 2)
+		assert.Equal(t, true, judges[0].(map[string]interface{})["pass"])
+		assert.Equal(t, false, judges[1].(map[string]interface{})["pass"])
+	})
+
+	t.Run("Fails without judges", func(t *testing.T) {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{}, nil, nil)
+		results := evaluator.Evaluate([]model.Assertion{{Type: "llm_rubric", Value: "rubric"}})
+		assert.False(t, results[0].Passed)
+		assert.Contains(t, results[0].Message, "requires a judge")
+	})
+
+	t.Run("Missing rubric", func(t *testing.T) {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{}, nil, nil)
+		results := evaluator.Evaluate([]model.Assertion{{Type: "llm_rubric"}})
+		assert.False(t, results[0].Passed)
+	})
+}
+
+func TestAssertionEvaluator_OutputNotContains(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		value      string
+		wantPassed bool
+	}{
+		{
+			name:       "Does not contain substring",
+			output:     "The weather is sunny today",
+			value:      "rainy",
+			wantPassed: true,
+		},
+		{
+			name:       "Contains substring (should fail)",
+			output:     "The weather is sunny today",
+			value:      "sunny",
+			wantPassed: false,
+		},
+		{
+			name:       "Case sensitive - different case",
+			output:     "Hello World",
+			value:      "hello",
+			wantPassed: true,
+		},
+		{
+			name:       "Case sensitive - exact match",
+			output:     "Hello World",
+			value:      "Hello",
+			wantPassed: false,
+		},
+		{
+			name:       "Empty value",
+			output:     "Some text",
+			value:      "",
+			wantPassed: false,
+		},
+		{
+			name:       "Special characters",
+			output:     "Price: $50.00",
+			value:      "$100",
+			wantPassed: true,
+		},
+		{
+			name:       "Special characters present",
+			output:     "Price: $50.00",
+			value:      "$50",
+			wantPassed: false,
+		},
+		{
+			name:       "Partial word match",
+			output:     "The test passed",
+			value:      "fail",
+			wantPassed: true,
+		},
+		{
+			name:       "Whitespace in value",
+			output:     "Hello World",
+			value:      "Hello  World",
+			wantPassed: true,
+		},
+		{
+			name:       "Newline in output",
+			output:     "Line 1\nLine 2\nLine 3",
+			value:      "Line 4",
+			wantPassed: true,
+		},
+		{
+			name:       "Newline present",
+			output:     "Line 1\nLine 2",
+			value:      "Line 2",
+			wantPassed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ExecutionResult{
+				FinalOutput: tt.output,
+			}
+
+			evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+			assertion := model.Assertion{
+				Type:  "output_not_contains",
+				Value: tt.value,
+			}
+
+			results := evaluator.Evaluate([]model.Assertion{assertion})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed,
+				"Expected passed=%v, got passed=%v. Message: %s",
+				tt.wantPassed, results[0].Passed, results[0].Message)
+		})
+	}
+}
+
+// ============================================================================
+// JSON Report with AI Summary Tests
+// ============================================================================
+
+func TestGenerateJSONReportWithAnalysis(t *testing.T) {
+	t.Run("JSON report includes test_file field", func(t *testing.T) {
+		reporter := model.NewReportGenerator()
+		reporter.TestFile = "path/to/test.yaml"
+		results := []model.TestRun{
+			{
+				Passed: true,
+				Execution: &model.ExecutionResult{
+					TestName:  "test1",
+					AgentName: "agent1",
+				},
+			},
+		}
+
+		jsonOutput := reporter.GenerateJSONReportWithAnalysis(results, nil)
+
+		// Verify JSON contains test_file
+		assert.Contains(t, jsonOutput, `"test_file"`)
+		assert.Contains(t, jsonOutput, `path/to/test.yaml`)
+	})
+
+	t.Run("JSON report never includes ai_summary (late-binding)", func(t *testing.T) {
+		reporter := model.NewReportGenerator()
+		results := []model.TestRun{
+			{
+				Passed: true,
+				Execution: &model.ExecutionResult{
+					TestName:  "test1",
+					AgentName: "agent1",
+				},
+			},
+		}
+
+		// Even when analysis is provided, it should NOT be included in JSON
+		// AI summary is now generated at HTML report time (late-binding)
+		analysis := &model.AISummaryData{
+			Success:  true,
+			Analysis: "## Summary\n\nAll tests passed successfully.",
+		}
+
+		jsonOutput := reporter.GenerateJSONReportWithAnalysis(results, analysis)
+
+		// Verify JSON does NOT contain ai_summary
+		assert.NotContains(t, jsonOutput, `"ai_summary"`)
+	})
+
+	t.Run("JSON report without ai_summary when nil", func(t *testing.T) {
+		reporter := model.NewReportGenerator()
+		results := []model.TestRun{
+			{
+				Passed: true,
+				Execution: &model.ExecutionResult{
+					TestName:  "test1",
+					AgentName: "agent1",
 				},
 			},
 		}
@@ -2234,6 +3983,84 @@ func TestGenerateJSONReportWithAnalysis(t *testing.T) {
 		assert.NotContains(t, jsonOutput, `"ai_summary"`)
 	})
 
+	t.Run("JSON report includes per-agent tool metrics", func(t *testing.T) {
+		reporter := model.NewReportGenerator()
+		results := []model.TestRun{
+			{
+				Passed: true,
+				Execution: &model.ExecutionResult{
+					TestName:  "test1",
+					AgentName: "agent1",
+					ToolCalls: []model.ToolCall{
+						{Name: "read_file", DurationMs: 1500},
+						{Name: "write_file", DurationMs: 500},
+					},
+				},
+			},
+			{
+				Passed: false,
+				Execution: &model.ExecutionResult{
+					TestName:  "test2",
+					AgentName: "agent1",
+					ToolCalls: []model.ToolCall{
+						{Name: "read_file", DurationMs: 1000},
+					},
+				},
+			},
+		}
+
+		jsonOutput := reporter.GenerateJSONReportWithAnalysis(results, nil)
+
+		var parsed struct {
+			AgentStats []model.AgentStats `json:"agent_stats"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(jsonOutput), &parsed))
+		require.Len(t, parsed.AgentStats, 1)
+		stats := parsed.AgentStats[0]
+		assert.Equal(t, "agent1", stats.AgentName)
+		assert.Equal(t, 3, stats.TotalToolCalls)
+		assert.InDelta(t, 1.5, stats.AvgToolCalls, 0.001)
+		assert.InDelta(t, 3.0, stats.TotalToolTime, 0.001)
+	})
+
+	t.Run("JSON report includes per-agent latency percentiles", func(t *testing.T) {
+		reporter := model.NewReportGenerator()
+		var results []model.TestRun
+		for _, ms := range []int64{400, 100, 300, 200, 1000} {
+			results = append(results, model.TestRun{Passed: true, Execution: &model.ExecutionResult{TestName: "t", AgentName: "agent1", LatencyMs: ms}})
+		}
+
+		var parsed struct {
+			AgentStats []model.AgentStats `json:"agent_stats"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(reporter.GenerateJSONReportWithAnalysis(results, nil)), &parsed))
+		require.Len(t, parsed.AgentStats, 1)
+		assert.InDelta(t, 300, parsed.AgentStats[0].P50LatencyMs, 0.001)
+		assert.InDelta(t, 760, parsed.AgentStats[0].P90LatencyMs, 0.001)
+		assert.InDelta(t, 880, parsed.AgentStats[0].P95LatencyMs, 0.001)
+	})
+
+	t.Run("JSON report includes assertion time", func(t *testing.T) {
+		reporter := model.NewReportGenerator()
+		results := []model.TestRun{
+			{Passed: true, Execution: &model.ExecutionResult{TestName: "test1", AgentName: "agent1", AssertionDurationMs: 1500}},
+			{Passed: true, Execution: &model.ExecutionResult{TestName: "test2", AgentName: "agent1", AssertionDurationMs: 250}},
+		}
+
+		var parsed struct {
+			Summary    map[string]interface{} `json:"summary"`
+			AgentStats []model.AgentStats     `json:"agent_stats"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(reporter.GenerateJSONReportWithAnalysis(results, nil)), &parsed))
+		assert.Equal(t, 1750.0, parsed.Summary["assertion_duration_ms"])
+		require.Len(t, parsed.AgentStats, 1)
+		assert.InDelta(t, 1.75, parsed.AgentStats[0].TotalAssertionTime, 0.001)
+
+		// Runs without assertion timing leave the summary key out
+		results[0].Execution.AssertionDurationMs, results[1].Execution.AssertionDurationMs = 0, 0
+		assert.NotContains(t, reporter.GenerateJSONReportWithAnalysis(results, nil), "assertion_duration_ms")
+	})
+
 	t.Run("Backwards compatibility - GenerateJSONReport still works", func(t *testing.T) {
 		reporter := model.NewReportGenerator()
 		results := []model.TestRun{
@@ -2254,3 +4081,593 @@ func TestGenerateJSONReportWithAnalysis(t *testing.T) {
 		assert.NotContains(t, jsonOutput, `"ai_summary"`)
 	})
 }
+
+func TestGenerateSARIFReport(t *testing.T) {
+	reporter := model.NewReportGenerator()
+	reporter.TestFile = "tests/main.yaml"
+	results := []model.TestRun{
+		{
+			Passed: false,
+			Execution: &model.ExecutionResult{
+				TestName:    "leaks key",
+				AgentName:   "agent1",
+				SessionName: "security",
+				SourceFile:  "tests/security.yaml",
+			},
+			Assertions: []model.AssertionResult{
+				{Type: "output_contains", Passed: true, Message: "ok"},
+				{Type: "no_hallucinated_tools", Passed: false, Message: "Has hallucinated tools: [rm_rf]"},
+				{Type: "max_tokens", Passed: false, Severity: model.SeverityWarning, Message: "Tokens used: 900 (max: 500)"},
+			},
+		},
+		{
+			Passed:    false,
+			Execution: &model.ExecutionResult{TestName: "second", AgentName: "agent2"},
+			Assertions: []model.AssertionResult{
+				{Type: "no_hallucinated_tools", Passed: false, Message: "Has hallucinated tools: [sudo]"},
+			},
+		},
+	}
+
+	var doc struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(reporter.GenerateSARIFReport(results)), &doc))
+
+	assert.Equal(t, "2.1.0", doc.Version)
+	require.Len(t, doc.Runs, 1)
+	run := doc.Runs[0]
+	assert.Equal(t, "agent-benchmark", run.Tool.Driver.Name)
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, "agent-benchmark/max_tokens", run.Tool.Driver.Rules[0].ID)
+	assert.Equal(t, "agent-benchmark/no_hallucinated_tools", run.Tool.Driver.Rules[1].ID)
+
+	require.Len(t, run.Results, 3)
+	first := run.Results[0]
+	assert.Equal(t, "agent-benchmark/no_hallucinated_tools", first.RuleID)
+	assert.Equal(t, 1, first.RuleIndex)
+	assert.Equal(t, "error", first.Level)
+	assert.Equal(t, "leaks key [agent1]: Has hallucinated tools: [rm_rf]", first.Message.Text)
+	require.Len(t, first.Locations, 1)
+	assert.Equal(t, "tests/security.yaml", first.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+
+	assert.Equal(t, "warning", run.Results[1].Level)
+
+	// Without a per-test source file the run's test file is used
+	require.Len(t, run.Results[2].Locations, 1)
+	assert.Equal(t, "tests/main.yaml", run.Results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
+func TestAssertionEvaluator_MatchesGolden(t *testing.T) {
+	newResult := func(when time.Time, output string) *model.ExecutionResult {
+		return &model.ExecutionResult{
+			ToolCalls: []model.ToolCall{
+				{Name: "create_file", Parameters: map[string]interface{}{"path": "/tmp/a.txt", "size": 3}, Timestamp: when},
+				{Name: "log", Parameters: map[string]interface{}{"at": when.Format(time.RFC3339)}, Timestamp: when, DurationMs: 40},
+			},
+			FinalOutput: output,
+		}
+	}
+	dir := t.TempDir()
+	templateCtx := map[string]string{"TEST_DIR": dir}
+	golden := model.Assertion{Type: "matches_golden", Value: "golden/run.json"}
+
+	t.Run("Missing golden fails", func(t *testing.T) {
+		results := model.NewAssertionEvaluator(newResult(time.Now(), "Created"), templateCtx, nil).
+			Evaluate([]model.Assertion{golden})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed)
+		assert.Contains(t, results[0].Message, "-update-golden")
+	})
+
+	t.Run("Update records golden", func(t *testing.T) {
+		results := model.NewAssertionEvaluator(newResult(time.Now(), "Created at 2026-01-02T03:04:05Z\r\n"), templateCtx, nil).
+			WithGoldenWriter(model.NewGoldenWriter()).
+			Evaluate([]model.Assertion{golden})
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Passed)
+		data, err := os.ReadFile(filepath.Join(dir, "golden", "run.json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"finalOutput": "Created at <timestamp>"`)
+		assert.NotContains(t, string(data), "duration")
+	})
+
+	t.Run("Update by several agents", func(t *testing.T) {
+		writer := model.NewGoldenWriter()
+		shared := model.Assertion{Type: "matches_golden", Value: "golden/shared.json"}
+		update := func(agent, output string) model.AssertionResult {
+			agentCtx := map[string]string{"TEST_DIR": dir, "AGENT_NAME": agent}
+			results := model.NewAssertionEvaluator(newResult(time.Now(), output), agentCtx, nil).
+				WithGoldenWriter(writer).
+				Evaluate([]model.Assertion{shared})
+			require.Len(t, results, 1)
+			return results[0]
+		}
+
+		var wg sync.WaitGroup
+		for _, agent := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.True(t, update(agent, "Same answer").Passed, "identical transcripts may share a golden")
+			}()
+		}
+		wg.Wait()
+
+		result := update("d", "Different answer")
+		assert.False(t, result.Passed)
+		assert.Contains(t, result.Message, "already written in this run by agent")
+		assert.Contains(t, result.Message, "{{AGENT_NAME}}")
+		data, err := os.ReadFile(filepath.Join(dir, "golden", "shared.json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "Same answer", "the conflicting write is not applied")
+	})
+
+	t.Run("Matches ignoring timestamps", func(t *testing.T) {
+		later := time.Now().Add(48 * time.Hour)
+		results := model.NewAssertionEvaluator(newResult(later, "Created at 2027-05-06T07:08:09Z"), templateCtx, nil).
+			Evaluate([]model.Assertion{golden})
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Passed, results[0].Message)
+	})
+
+	t.Run("Mismatch reports diff", func(t *testing.T) {
+		result := newResult(time.Now(), "Created at 2027-05-06T07:08:09Z")
+		result.ToolCalls[0].Parameters["path"] = "/tmp/b.txt"
+		results := model.NewAssertionEvaluator(result, templateCtx, nil).
+			Evaluate([]model.Assertion{golden})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed)
+		assert.Contains(t, results[0].Message, "tool calls")
+		assert.NotContains(t, results[0].Message, "final output")
+		diff, ok := results[0].Details["diff"].(string)
+		require.True(t, ok)
+		assert.Contains(t, diff, `- `+`        "path": "/tmp/a.txt",`)
+		assert.Contains(t, diff, `+ `+`        "path": "/tmp/b.txt",`)
+		assert.NotContains(t, diff, "finalOutput")
+	})
+}
+
+func TestGenerateJUnitReport(t *testing.T) {
+	reporter := model.NewReportGenerator()
+	reporter.TestFile = "tests/main.yaml"
+	results := []model.TestRun{
+		{
+			Passed:    true,
+			Execution: &model.ExecutionResult{TestName: "lists files", AgentName: "agent1", SessionName: "browse", SourceFile: "tests/files.yaml", LatencyMs: 1500},
+		},
+		{
+			Passed: false,
+			Execution: &model.ExecutionResult{
+				TestName:   "reads file",
+				AgentName:  "agent2",
+				SourceFile: "tests/files.yaml",
+				LatencyMs:  250,
+				Errors:     []string{"tool timeout"},
+			},
+			Assertions: []model.AssertionResult{
+				{Type: "output_contains", Passed: true, Message: "ok"},
+				{Type: "max_tokens", Passed: false, Severity: model.SeverityWarning, Message: "Tokens used: 900 (max: 500)"},
+				{Type: "tool_called", Passed: false, Message: "Tool 'read_file' was not called"},
+			},
+		},
+		{
+			Skipped:    true,
+			SkipReason: "needs a GPU",
+			Execution:  &model.ExecutionResult{TestName: "renders", AgentName: "agent1"},
+		},
+	}
+
+	var doc struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+		Suites   []struct {
+			Name      string `xml:"name,attr"`
+			Tests     int    `xml:"tests,attr"`
+			Failures  int    `xml:"failures,attr"`
+			Time      string `xml:"time,attr"`
+			TestCases []struct {
+				ClassName string `xml:"classname,attr"`
+				Name      string `xml:"name,attr"`
+				Time      string `xml:"time,attr"`
+				Failure   *struct {
+					Message string `xml:"message,attr"`
+					Text    string `xml:",chardata"`
+				} `xml:"failure"`
+				Skipped *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"skipped"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	out := reporter.GenerateJUnitReport(results)
+	assert.True(t, strings.HasPrefix(out, xml.Header))
+	require.NoError(t, xml.Unmarshal([]byte(out), &doc))
+
+	assert.Equal(t, 3, doc.Tests)
+	assert.Equal(t, 1, doc.Failures)
+	assert.Equal(t, 1, doc.Skipped)
+	require.Len(t, doc.Suites, 2)
+
+	files := doc.Suites[0]
+	assert.Equal(t, "tests/files.yaml", files.Name)
+	assert.Equal(t, 2, files.Tests)
+	assert.Equal(t, 1, files.Failures)
+	assert.Equal(t, "1.750", files.Time)
+	require.Len(t, files.TestCases, 2)
+	assert.Equal(t, "agent1", files.TestCases[0].ClassName)
+	assert.Equal(t, "browse / lists files", files.TestCases[0].Name, "the session prefixes the test name")
+	assert.Equal(t, "reads file", files.TestCases[1].Name)
+	assert.Equal(t, "1.500", files.TestCases[0].Time)
+	assert.Nil(t, files.TestCases[0].Failure)
+
+	failure := files.TestCases[1].Failure
+	require.NotNil(t, failure)
+	assert.Equal(t, "Tool 'read_file' was not called", failure.Message, "warnings do not fail the run")
+	assert.Contains(t, failure.Text, "tool_called: Tool 'read_file' was not called")
+	assert.Contains(t, failure.Text, "error: tool timeout")
+
+	// Without a per-test source file the run's test file names the suite
+	mainSuite := doc.Suites[1]
+	assert.Equal(t, "tests/main.yaml", mainSuite.Name)
+	require.Len(t, mainSuite.TestCases, 1)
+	require.NotNil(t, mainSuite.TestCases[0].Skipped)
+	assert.Equal(t, "needs a GPU", mainSuite.TestCases[0].Skipped.Message)
+}
+
+func TestGenerateCSVReport(t *testing.T) {
+	reporter := model.NewReportGenerator()
+	reporter.TestFile = "tests/main.yaml"
+	results := []model.TestRun{
+		{
+			Passed: true,
+			Execution: &model.ExecutionResult{
+				TestName:     `Find "quoted", files`,
+				AgentName:    "agent1",
+				ProviderType: model.ProviderOpenAI,
+				Model:        "gpt-4o",
+				SessionName:  "files",
+				SourceFile:   "tests/files.yaml",
+				LatencyMs:    1500,
+				TokensUsed:   320,
+				ToolCalls:    []model.ToolCall{{Name: "list"}, {Name: "read"}},
+			},
+			Assertions: []model.AssertionResult{
+				{Type: "tool_called", Passed: true},
+				{Type: "max_tokens", Passed: false, Severity: model.SeverityWarning},
+				{Type: "output_regex", Passed: true, Disabled: true},
+			},
+		},
+		{
+			Passed: false,
+			Execution: &model.ExecutionResult{
+				TestName:     "second",
+				AgentName:    "agent2",
+				ProviderType: model.ProviderAnthropic,
+				Errors:       []string{"tool timeout", "rate limited"},
+			},
+			Assertions: []model.AssertionResult{{Type: "tool_called", Passed: false}},
+		},
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(reporter.GenerateCSVReport(results))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{
+		"source_file", "session", "test_name", "agent_name", "provider", "passed",
+		"latency_ms", "tokens_used", "tool_call_count",
+		"assertion_pass_count", "assertion_fail_count", "assertion_warning_count", "error_count",
+	}, rows[0])
+	assert.Equal(t, []string{
+		"tests/files.yaml", "files", `Find "quoted", files`, "agent1", "OPENAI (gpt-4o)", "true",
+		"1500", "320", "2", "1", "0", "1", "0",
+	}, rows[1])
+	assert.Equal(t, []string{
+		"tests/main.yaml", "", "second", "agent2", "ANTHROPIC", "false",
+		"0", "0", "0", "0", "1", "0", "2",
+	}, rows[2])
+}
+
+func TestAssertionEvaluator_Severity(t *testing.T) {
+	result := &model.ExecutionResult{FinalOutput: "Saved the file."}
+	evaluator := model.NewAssertionEvaluator(result, map[string]string{}, []string{})
+
+	results := evaluator.Evaluate([]model.Assertion{
+		{Type: "output_contains", Value: "Saved"},
+		{Type: "output_contains", Value: "checksum", Severity: "warning"},
+		{Type: "output_contains", Value: "checksum"},
+		{Type: "output_contains", Value: "Saved", Severity: "fatal"},
+		{Severity: "warning", AnyOf: []model.Assertion{
+			{Type: "output_contains", Value: "missing"},
+		}},
+	})
+	require.Len(t, results, 5)
+
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, model.SeverityError, results[0].Severity)
+	assert.False(t, results[0].FailsTest())
+
+	assert.True(t, results[1].IsWarning())
+	assert.False(t, results[1].FailsTest())
+
+	assert.Equal(t, model.SeverityError, results[2].Severity)
+	assert.True(t, results[2].FailsTest())
+
+	assert.True(t, results[3].FailsTest(), "an unknown severity fails the assertion")
+	assert.Equal(t, "Invalid severity: fatal (expected error or warning)", results[3].Message)
+
+	assert.True(t, results[4].IsWarning(), "combinators take the severity set on them")
+
+	assert.Equal(t, 2, model.CountWarnings(results))
+}
+
+func TestAssertionEvaluator_FirstFailMode(t *testing.T) {
+	result := &model.ExecutionResult{FinalOutput: "Saved the file."}
+	assertions := []model.Assertion{
+		{Type: "output_contains", Value: "Saved"},
+		{Type: "output_contains", Value: "checksum", Severity: "warning"},
+		{Type: "output_contains", Value: "missing"},
+		{Type: "output_contains", Value: "file"},
+		{Type: "output_contains", Value: "also missing"},
+	}
+
+	all := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).Evaluate(assertions)
+	require.Len(t, all, 5, "all is the default")
+
+	results := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+		WithAssertionMode(model.AssertionModeFirstFail).
+		Evaluate(assertions)
+	require.Len(t, results, 3, "a warning does not stop evaluation, the first error does")
+	assert.True(t, results[1].IsWarning())
+	assert.True(t, results[2].FailsTest())
+	assert.Equal(t, 2, results[2].Details["not_evaluated"])
+	assert.Equal(t, 2, model.FirstTestFailure(results))
+
+	model.MarkNotEvaluated(&results[2], 3)
+	assert.Equal(t, 5, results[2].Details["not_evaluated"], "counts accumulate across turns")
+
+	passing := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+		WithAssertionMode(model.AssertionModeFirstFail).
+		Evaluate(assertions[:2])
+	require.Len(t, passing, 2)
+	assert.Equal(t, -1, model.FirstTestFailure(passing))
+}
+
+func TestAssertionEvaluator_AssertionFilter(t *testing.T) {
+	result := &model.ExecutionResult{FinalOutput: "Saved the file."}
+	assertions := []model.Assertion{
+		{Type: "output_contains", Value: "Saved"},
+		{Type: "output_regex", Value: "^Deleted"},
+		{AnyOf: []model.Assertion{{Type: "output_regex", Value: "file"}}},
+		{Type: "max_latency_ms", Value: "1"},
+	}
+
+	t.Run("disabled types", func(t *testing.T) {
+		results := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+			WithAssertionFilter(model.AssertionFilter{Disabled: []string{"output_regex"}}).
+			Evaluate(assertions)
+		require.Len(t, results, 4)
+		assert.False(t, results[0].Disabled)
+		assert.True(t, results[1].Disabled)
+		assert.False(t, results[1].FailsTest(), "a disabled assertion never fails the test")
+		assert.Equal(t, "output_regex", results[1].Type)
+		assert.False(t, results[2].Disabled, "children of a combinator are not filtered")
+	})
+
+	t.Run("only types", func(t *testing.T) {
+		results := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+			WithAssertionFilter(model.AssertionFilter{Only: []string{"output_contains", "anyOf"}}).
+			Evaluate(assertions)
+		require.Len(t, results, 4)
+		assert.False(t, results[0].Disabled)
+		assert.True(t, results[1].Disabled)
+		assert.False(t, results[2].Disabled)
+		assert.Equal(t, "anyOf", results[2].Type)
+		assert.True(t, results[3].Disabled)
+		assert.Equal(t, -1, model.FirstTestFailure(results))
+	})
+
+	t.Run("first_fail skips disabled assertions", func(t *testing.T) {
+		results := model.NewAssertionEvaluator(result, map[string]string{}, []string{}).
+			WithAssertionFilter(model.AssertionFilter{Disabled: []string{"output_regex"}}).
+			WithAssertionMode(model.AssertionModeFirstFail).
+			Evaluate([]model.Assertion{
+				{Type: "output_regex", Value: "^Deleted"},
+				{Type: "output_contains", Value: "missing"},
+				{Type: "output_contains", Value: "file"},
+			})
+		require.Len(t, results, 2)
+		assert.True(t, results[0].Disabled)
+		assert.Equal(t, 1, model.FirstTestFailure(results))
+	})
+
+	assert.True(t, model.AssertionFilter{}.IsZero())
+	assert.True(t, model.AssertionFilter{}.Allows("output_regex"))
+	ctx := model.WithAssertionFilter(context.Background(), model.AssertionFilter{Only: []string{"llm_rubric"}})
+	assert.Equal(t, []string{"llm_rubric"}, model.AssertionFilterFromContext(ctx).Only)
+	assert.True(t, model.AssertionFilterFromContext(context.Background()).IsZero())
+}
+
+func TestAssertionEvaluator_OutputFieldEquals(t *testing.T) {
+	output := "[Iteration 1: calling tools]\n```json\n{\"response\": {\"status\": 200, \"code\": \"200\", \"ok\": true}, \"items\": [{\"id\": \"a1\"}]}\n```"
+
+	tests := []struct {
+		name       string
+		output     string
+		path       string
+		expected   interface{}
+		wantPassed bool
+		wantMsg    string
+	}{
+		{name: "Number matches number", output: output, path: "response.status", expected: "200", wantPassed: true, wantMsg: "Field 'response.status' equals 200"},
+		{name: "Quoted value does not match number", output: output, path: "response.status", expected: `"200"`, wantPassed: false, wantMsg: `Field 'response.status' is 200, expected "200"`},
+		{name: "Quoted value matches string", output: output, path: "response.code", expected: `"200"`, wantPassed: true},
+		{name: "Number does not match string", output: output, path: "response.code", expected: "200", wantPassed: false},
+		{name: "Boolean", output: output, path: "$.response.ok", expected: "true", wantPassed: true},
+		{name: "Plain string fallback", output: output, path: "$.items[0].id", expected: "a1", wantPassed: true},
+		{name: "Missing path", output: output, path: "response.missing", expected: "1", wantPassed: false},
+		{name: "Not JSON", output: "The status is 200", path: "status", expected: "200", wantPassed: false},
+		{name: "YAML number matches number", output: output, path: "response.status", expected: 200, wantPassed: true},
+		{name: "YAML boolean", output: output, path: "response.ok", expected: true, wantPassed: true},
+		{name: "No path", output: output, expected: "200", wantPassed: false, wantMsg: "output_field_equals requires a 'path'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{FinalOutput: tt.output}, map[string]string{}, []string{})
+			results := evaluator.Evaluate([]model.Assertion{{Type: "output_field_equals", Path: tt.path, Expected: tt.expected}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantPassed, results[0].Passed, results[0].Message)
+			if tt.wantMsg != "" {
+				assert.Equal(t, tt.wantMsg, results[0].Message)
+			}
+		})
+	}
+
+	t.Run("Expected read from YAML", func(t *testing.T) {
+		var a model.Assertion
+		require.NoError(t, yaml.Unmarshal([]byte("type: output_field_equals\npath: items\nexpected: [{id: a1}]"), &a))
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{FinalOutput: output}, map[string]string{}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{a})
+		require.Len(t, results, 1)
+		assert.True(t, results[0].Passed, results[0].Message)
+	})
+
+	t.Run("Details report path and resolved value", func(t *testing.T) {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{FinalOutput: output}, map[string]string{}, []string{})
+		results := evaluator.Evaluate([]model.Assertion{{Type: "output_field_equals", Path: "response.status", Expected: "200"}})
+		require.Len(t, results, 1)
+		assert.Equal(t, "response.status", results[0].Details["path"])
+		assert.Equal(t, float64(200), results[0].Details["actual"])
+	})
+}
+
+func TestAssertionEvaluator_OutputJSONSchema(t *testing.T) {
+	var a model.Assertion
+	require.NoError(t, yaml.Unmarshal([]byte(`
+type: output_json_schema
+schema:
+  type: object
+  required: [status, items]
+  properties:
+    status: { enum: [ok, partial] }
+    items:
+      type: array
+      minItems: 1
+      items:
+        type: object
+        required: [id]
+        properties:
+          id: { type: string }
+          count: { type: integer, minimum: 1 }
+`), &a))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "answer.json"), []byte(`{"type": "object", "required": ["status"]}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"type": 12}`), 0644))
+	templateCtx := map[string]string{"TEST_DIR": dir}
+
+	evaluate := func(output string, a model.Assertion) model.AssertionResult {
+		evaluator := model.NewAssertionEvaluator(&model.ExecutionResult{FinalOutput: output}, templateCtx, []string{})
+		results := evaluator.Evaluate([]model.Assertion{a})
+		require.Len(t, results, 1)
+		return results[0]
+	}
+
+	t.Run("Valid output passes", func(t *testing.T) {
+		result := evaluate("[Iteration 1: done]\n```json\n{\"status\": \"ok\", \"items\": [{\"id\": \"a1\", \"count\": 2}]}\n```", a)
+		assert.True(t, result.Passed, result.Message)
+		assert.Equal(t, "Final output matches the JSON schema", result.Message)
+	})
+
+	t.Run("Violations list instance paths", func(t *testing.T) {
+		result := evaluate(`{"status": "done", "items": [{"count": 0}, {"id": 7}]}`, a)
+		assert.False(t, result.Passed)
+		violations, ok := result.Details["violations"].([]string)
+		require.True(t, ok)
+		assert.ElementsMatch(t, []string{
+			"/status: value must be one of 'ok', 'partial'",
+			"/items/0: missing property 'id'",
+			"/items/0/count: minimum: got 0, want 1",
+			"/items/1/id: got number, want string",
+		}, violations)
+		assert.Contains(t, result.Message, "Final output does not match the JSON schema: ")
+	})
+
+	t.Run("anyOf failure is reported once", func(t *testing.T) {
+		result := evaluate(`{}`, model.Assertion{Type: "output_json_schema", Schema: map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"required": []interface{}{"a"}},
+				map[string]interface{}{"required": []interface{}{"b"}},
+			},
+		}})
+		assert.False(t, result.Passed)
+		assert.Equal(t, []string{"/: 'anyOf' failed"}, result.Details["violations"])
+	})
+
+	t.Run("Output that is not JSON fails", func(t *testing.T) {
+		result := evaluate("The status is ok", a)
+		assert.False(t, result.Passed)
+		assert.Contains(t, result.Message, "Final output is not valid JSON")
+	})
+
+	t.Run("Schema file relative to TEST_DIR", func(t *testing.T) {
+		schemaFile := model.Assertion{Type: "output_json_schema", SchemaFile: "answer.json"}
+		assert.True(t, evaluate(`{"status": "ok"}`, schemaFile).Passed)
+
+		result := evaluate(`{"state": "ok"}`, schemaFile)
+		assert.False(t, result.Passed)
+		assert.Equal(t, []string{"/: missing property 'status'"}, result.Details["violations"])
+		assert.Equal(t, filepath.Join(dir, "answer.json"), result.Details["schema_file"])
+	})
+
+	t.Run("Relative $ref resolves next to the schema", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "schemas"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "schemas", "item.json"), []byte(`{"type": "object", "required": ["id"]}`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "schemas", "list.json"), []byte(`{"type": "array", "items": {"$ref": "item.json"}}`), 0644))
+
+		schemaFile := model.Assertion{Type: "output_json_schema", SchemaFile: "schemas/list.json"}
+		assert.True(t, evaluate(`[{"id": 1}]`, schemaFile).Passed)
+		result := evaluate(`[{"name": "a"}]`, schemaFile)
+		assert.False(t, result.Passed, result.Message)
+		assert.Equal(t, []string{"/0: missing property 'id'"}, result.Details["violations"])
+
+		inline := model.Assertion{Type: "output_json_schema", Schema: map[string]interface{}{"$ref": "schemas/item.json"}}
+		assert.False(t, evaluate(`{}`, inline).Passed)
+		assert.True(t, evaluate(`{"id": 2}`, inline).Passed)
+	})
+
+	t.Run("Configuration errors", func(t *testing.T) {
+		assert.Equal(t, "output_json_schema requires exactly one of 'schema' or 'schema_file'",
+			evaluate(`{}`, model.Assertion{Type: "output_json_schema"}).Message)
+		assert.Equal(t, "output_json_schema requires exactly one of 'schema' or 'schema_file'",
+			evaluate(`{}`, model.Assertion{Type: "output_json_schema", Schema: a.Schema, SchemaFile: "answer.json"}).Message)
+		assert.Contains(t, evaluate(`{}`, model.Assertion{Type: "output_json_schema", SchemaFile: "missing.json"}).Message, "Failed to load JSON schema")
+		assert.Contains(t, evaluate(`{}`, model.Assertion{Type: "output_json_schema", SchemaFile: "broken.json"}).Message, "Invalid JSON schema")
+	})
+}
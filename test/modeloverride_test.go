@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestModelOverrideProviders(t *testing.T) {
+	providers := []model.Provider{
+		{Name: "openai", Type: model.ProviderOpenAI, Model: "gpt-4o", Token: "key"},
+		{Name: "claude", Type: model.ProviderAnthropic, Model: "claude-sonnet-4"},
+	}
+	agents := []model.Agent{
+		{Name: "mini", Provider: "openai", Model: "gpt-4o-mini"},
+		{Name: "default", Provider: "openai"},
+		{Name: "sonnet", Provider: "claude"},
+	}
+	sessions := []model.Session{{
+		Name: "s",
+		Tests: []model.Test{
+			{Name: "all agents", Model: "gpt-4o-mini"},
+			{Name: "pinned", Agent: "sonnet", Model: "claude-haiku-4"},
+			{Name: "unknown agent", Agent: "missing", Model: "other"},
+		},
+	}}
+
+	clones, err := engine.ModelOverrideProviders(providers, agents, sessions)
+	require.NoError(t, err)
+	names := make([]string, 0, len(clones))
+	for _, p := range clones {
+		names = append(names, p.Name)
+	}
+	// The override of the first test is shared with agent "mini"
+	assert.Equal(t, []string{"openai@gpt-4o-mini", "claude@gpt-4o-mini", "claude@claude-haiku-4"}, names)
+	assert.Equal(t, "gpt-4o-mini", clones[0].Model)
+	assert.Equal(t, model.ProviderOpenAI, clones[0].Type)
+	assert.Equal(t, "key", clones[0].Token, "clones keep the provider's settings")
+	assert.Equal(t, "gpt-4o", providers[0].Model, "the provider itself is unchanged")
+
+	t.Run("incompatible provider type", func(t *testing.T) {
+		bedrock := []model.Provider{{Name: "aws", Type: model.ProviderAmazonAnthropic, Model: "anthropic.claude-3-5-sonnet-20240620-v1:0"}}
+		_, err := engine.ModelOverrideProviders(bedrock,
+			[]model.Agent{{Name: "a", Provider: "aws", Model: "us.anthropic.claude-3-haiku-20240307-v1:0"}}, nil)
+		assert.NoError(t, err)
+
+		_, err = engine.ModelOverrideProviders(bedrock,
+			[]model.Agent{{Name: "a", Provider: "aws"}},
+			[]model.Session{{Name: "s", Tests: []model.Test{{Name: "llama", Model: "meta.llama3-70b-instruct-v1:0"}}}})
+		assert.ErrorContains(t, err, "test 'llama'")
+		assert.ErrorContains(t, err, "only run Anthropic models")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		config := &model.TestConfiguration{
+			Providers: []model.Provider{{Name: "aws", Type: model.ProviderAmazonAnthropic, Model: "anthropic.claude-3-haiku-20240307-v1:0"}},
+			Agents:    []model.Agent{{Name: "a", Provider: "aws", Model: "mistral.mistral-large-2402-v1:0"}},
+			Sessions:  []model.Session{{Name: "s", Tests: []model.Test{{Name: "t"}}}},
+		}
+		assert.ErrorContains(t, engine.ValidateTestConfig(config, false), "agent 'a'")
+	})
+}
+
+func TestInitAgents_ModelOverride(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	base, mini := new(MockLLMModel), new(MockLLMModel)
+	providers := map[string]llms.Model{"openai": base, "openai@gpt-4o-mini": mini}
+
+	agents, err := engine.InitAgents(context.Background(), []model.Agent{
+		{Name: "default", Provider: "openai"},
+		{Name: "mini", Provider: "openai", Model: "gpt-4o-mini"},
+	}, nil, providers)
+	require.NoError(t, err)
+	assert.Same(t, base, agents["default"].LLMModel)
+	assert.Same(t, mini, agents["mini"].LLMModel)
+	assert.Equal(t, "openai", agents["mini"].Provider)
+	assert.Equal(t, "gpt-4o-mini", agents["mini"].Model)
+
+	_, err = engine.InitAgents(context.Background(), []model.Agent{
+		{Name: "nano", Provider: "openai", Model: "gpt-4.1-nano"},
+	}, nil, providers)
+	assert.ErrorContains(t, err, "provider 'openai@gpt-4.1-nano' not found")
+}
+
+func TestRunTests_ModelOverride(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	testTools := createTestTools()
+
+	respond := func(answer string) *MockLLMModel {
+		llm := new(MockLLMModel)
+		llm.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+			Return(&llms.ContentResponse{
+				Choices: []*llms.ContentChoice{{Content: answer, StopReason: "stop"}},
+			}, nil)
+		return llm
+	}
+	base, mini := respond("from gpt-4o"), respond("from gpt-4o-mini")
+
+	mockClient := new(MockMCPClient)
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mcpServer := createMockServer("server", testTools)
+	mcpServer.Client = mockClient
+	ag := agent.NewMCPAgent(ctx, "agent", []model.AgentServer{{Name: "server"}},
+		[]*server.MCPServer{mcpServer}, "openai", base)
+
+	testConfig := &model.TestConfiguration{
+		Providers: []model.Provider{{Name: "openai", Type: model.ProviderOpenAI, Model: "gpt-4o"}},
+		Agents:    []model.Agent{{Name: "agent", Provider: "openai", Servers: []model.AgentServer{{Name: "server"}}}},
+		Sessions: []model.Session{{
+			Name: "compare",
+			Tests: []model.Test{
+				{Name: "default model", Prompt: "Hi"},
+				{Name: "small model", Prompt: "Hi", Model: "gpt-4o-mini"},
+				{Name: "missing clone", Prompt: "Hi", Model: "gpt-4.1-nano"},
+			},
+		}},
+	}
+
+	results := engine.RunTests(ctx, testConfig,
+		map[string]*agent.MCPAgent{"agent": ag},
+		map[string]llms.Model{"openai": base, "openai@gpt-4o-mini": mini}, 5, 0, 0, 0, "", "")
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "agent", results[0].Execution.AgentName)
+	assert.Equal(t, "from gpt-4o", results[0].Execution.FinalOutput)
+	assert.Equal(t, "openai", results[0].Execution.ProviderLabel())
+
+	small := results[1].Execution
+	assert.Equal(t, "agent (gpt-4o-mini)", small.AgentName, "the override is reported as its own column")
+	assert.Equal(t, "gpt-4o-mini", small.Model)
+	assert.Equal(t, "from gpt-4o-mini", small.FinalOutput)
+	assert.Equal(t, "openai (gpt-4o-mini)", small.ProviderLabel())
+	assert.Same(t, base, ag.LLMModel, "the agent keeps its own model")
+
+	assert.False(t, results[2].Passed)
+	assert.Contains(t, results[2].Execution.Errors[0], "no clone for model")
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePairwiseJudge prefers the longer answer and counts its calls.
+type fakePairwiseJudge struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (j *fakePairwiseJudge) Name() string { return j.name }
+
+func (j *fakePairwiseJudge) Compare(_ context.Context, _, _, answerA, answerB string) (model.PairwiseVerdict, error) {
+	j.calls++
+	if j.err != nil {
+		return model.PairwiseVerdict{}, j.err
+	}
+	switch {
+	case len(answerA) > len(answerB):
+		return model.PairwiseVerdict{Winner: "A", Reason: "longer"}, nil
+	case len(answerB) > len(answerA):
+		return model.PairwiseVerdict{Winner: "B", Reason: "longer"}, nil
+	}
+	return model.PairwiseVerdict{Winner: "tie"}, nil
+}
+
+// firstAnswerJudge always prefers the answer shown first.
+type firstAnswerJudge struct{}
+
+func (firstAnswerJudge) Name() string { return "first" }
+
+func (firstAnswerJudge) Compare(_ context.Context, _, _, _, _ string) (model.PairwiseVerdict, error) {
+	return model.PairwiseVerdict{Winner: "A", Reason: "shown first"}, nil
+}
+
+func pairwiseRun(agentName, test, output string) model.TestRun {
+	return model.TestRun{
+		Execution: &model.ExecutionResult{
+			TestName:    test,
+			AgentName:   agentName,
+			StartTime:   time.Now(),
+			EndTime:     time.Now(),
+			Messages:    []model.Message{{Role: "user", Content: test + "?"}},
+			FinalOutput: output,
+		},
+		Passed: true,
+	}
+}
+
+func pairwiseResults() []model.TestRun {
+	return []model.TestRun{
+		pairwiseRun("gpt", "capital", "Paris is the capital"),
+		pairwiseRun("claude", "capital", "Paris"),
+		pairwiseRun("gemini", "capital", "PARIS"),
+		pairwiseRun("gpt", "greeting", "Hi"),
+		pairwiseRun("claude", "greeting", "Hello there"),
+		{Execution: &model.ExecutionResult{TestName: "greeting", AgentName: "gemini"}, Skipped: true},
+	}
+}
+
+func TestRunPairwiseComparison(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache", "pairwise.json")
+
+	judge := &fakePairwiseJudge{name: "judge"}
+	cache, err := engine.LoadPairwiseCache(path)
+	require.NoError(t, err)
+	results := pairwiseResults()
+
+	// capital: 3 pairs; greeting: gemini was skipped, so 1 pair
+	assert.Equal(t, 4, engine.RunPairwiseComparison(ctx, []engine.PairwiseJudge{judge}, "", cache, results))
+	assert.Equal(t, 8, judge.calls, "each pair is judged in both orders")
+
+	assert.Equal(t, []model.PairwiseOutcome{
+		{Opponent: "claude", Result: model.PairwiseWin, Reason: "longer"},
+		{Opponent: "gemini", Result: model.PairwiseWin, Reason: "longer"},
+	}, results[0].Pairwise)
+	assert.Equal(t, []model.PairwiseOutcome{
+		{Opponent: "gemini", Result: model.PairwiseTie},
+		{Opponent: "gpt", Result: model.PairwiseLoss, Reason: "longer"},
+	}, results[1].Pairwise, "agents are compared in name order")
+	assert.Equal(t, model.PairwiseWin, results[4].Pairwise[0].Result)
+	assert.Empty(t, results[5].Pairwise)
+
+	require.NoError(t, cache.Save())
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	t.Run("cached judgments are reused", func(t *testing.T) {
+		again, err := engine.LoadPairwiseCache(path)
+		require.NoError(t, err)
+		assert.Equal(t, 8, again.Len())
+
+		judge := &fakePairwiseJudge{name: "judge"}
+		results := pairwiseResults()
+		assert.Equal(t, 4, engine.RunPairwiseComparison(ctx, []engine.PairwiseJudge{judge}, "", again, results))
+		assert.Zero(t, judge.calls)
+		assert.True(t, results[0].Pairwise[0].Cached)
+
+		// Different instructions are judged anew
+		assert.Equal(t, 4, engine.RunPairwiseComparison(ctx, []engine.PairwiseJudge{judge}, "Prefer brevity", again, pairwiseResults()))
+		assert.Equal(t, 8, judge.calls)
+	})
+
+	t.Run("falls back to the next judge", func(t *testing.T) {
+		failing := &fakePairwiseJudge{name: "down", err: errors.New("unavailable")}
+		backup := &fakePairwiseJudge{name: "backup"}
+		cache, _ := engine.LoadPairwiseCache("")
+		results := pairwiseResults()
+		assert.Equal(t, 4, engine.RunPairwiseComparison(ctx, []engine.PairwiseJudge{failing, backup}, "", cache, results))
+		assert.Equal(t, 8, backup.calls)
+
+		cache, _ = engine.LoadPairwiseCache("")
+		assert.Zero(t, engine.RunPairwiseComparison(ctx, []engine.PairwiseJudge{failing}, "", cache, pairwiseResults()))
+	})
+
+	t.Run("position bias becomes a tie", func(t *testing.T) {
+		cache, _ := engine.LoadPairwiseCache("")
+		results := pairwiseResults()
+		engine.RunPairwiseComparison(ctx, []engine.PairwiseJudge{firstAnswerJudge{}}, "", cache, results)
+		for _, outcome := range results[0].Pairwise {
+			assert.Equal(t, model.PairwiseTie, outcome.Result)
+			assert.Contains(t, outcome.Reason, "answer order was swapped")
+		}
+	})
+
+	t.Run("invalid cache file", func(t *testing.T) {
+		broken := filepath.Join(t.TempDir(), "broken.json")
+		require.NoError(t, os.WriteFile(broken, []byte("not json"), 0644))
+		_, err := engine.LoadPairwiseCache(broken)
+		assert.ErrorContains(t, err, "failed to parse pairwise cache")
+	})
+}
+
+func TestPairwiseStandings(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	results := pairwiseResults()
+	cache, _ := engine.LoadPairwiseCache("")
+	engine.RunPairwiseComparison(context.Background(), []engine.PairwiseJudge{&fakePairwiseJudge{name: "judge"}}, "", cache, results)
+
+	standings := model.PairwiseStandings(results)
+	require.Len(t, standings, 3)
+
+	// gpt beat both on capital and lost greeting to claude
+	assert.Equal(t, "gpt", standings[0].Agent)
+	assert.Equal(t, 2, standings[0].Wins)
+	assert.Equal(t, 1, standings[0].Losses)
+	assert.InDelta(t, 2.0/3.0, standings[0].WinRate(), 1e-9)
+
+	// claude and gemini tied their game; a tie counts as half a win
+	claude := standings[1]
+	assert.Equal(t, "claude", claude.Agent)
+	assert.Equal(t, 3, claude.Games())
+	assert.InDelta(t, 0.5, claude.WinRate(), 1e-9)
+	assert.Equal(t, "gemini", standings[2].Agent)
+	assert.InDelta(t, 0.25, standings[2].WinRate(), 1e-9)
+
+	// Elo is zero-sum around the base rating
+	total := 0.0
+	for _, s := range standings {
+		total += s.Elo
+	}
+	assert.InDelta(t, 3*model.PairwiseEloBase, total, 1e-6)
+	assert.Greater(t, standings[0].Elo, float64(model.PairwiseEloBase))
+
+	assert.Empty(t, model.PairwiseStandings(pairwiseResults()), "no comparisons, no standings")
+}
+
+func TestHTMLPairwiseComparison(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	gen, err := report.NewGenerator()
+	require.NoError(t, err)
+
+	html, err := gen.GenerateHTML(pairwiseResults())
+	require.NoError(t, err)
+	assert.NotContains(t, html, "Head-to-Head", "the section only appears when pairwise judging ran")
+
+	results := pairwiseResults()
+	cache, _ := engine.LoadPairwiseCache("")
+	engine.RunPairwiseComparison(context.Background(), []engine.PairwiseJudge{&fakePairwiseJudge{name: "judge"}}, "", cache, results)
+	html, err = gen.GenerateHTML(results)
+	require.NoError(t, err)
+
+	assert.Contains(t, html, "Head-to-Head")
+	assert.Contains(t, html, "67%", "gpt's win rate")
+	// gpt's row: beat claude once and lost once, beat gemini once
+	matrix := html[strings.Index(html, "pairwise-matrix"):]
+	assert.Contains(t, matrix, "1–1–0")
+	assert.Contains(t, matrix, "0–0–1", "claude and gemini tied")
+	assert.Contains(t, matrix, `class="pairwise-ahead">1–0–0`)
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestLoadPromptRows(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		rows, err := engine.LoadPromptRows(write("prompts.csv",
+			"Prompt,Expected\n\"Capital of France, please\",Paris\nCapital of Spain?\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]string{
+			{"prompt": "Capital of France, please", "expected": "Paris"},
+			{"prompt": "Capital of Spain?"},
+		}, rows)
+	})
+
+	t.Run("csv with byte order mark", func(t *testing.T) {
+		rows, err := engine.LoadPromptRows(write("bom.csv", "\ufeff\"Prompt\",Expected\nCapital of Italy?,Rome\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]string{{"prompt": "Capital of Italy?", "expected": "Rome"}}, rows)
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		rows, err := engine.LoadPromptRows(write("prompts.jsonl",
+			`{"prompt": "2+2?", "expected": 4, "name": "sum"}`+"\n\n"+`{"prompt": "Hi", "expected": null}`+"\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]string{
+			{"prompt": "2+2?", "expected": "4", "name": "sum"},
+			{"prompt": "Hi"},
+		}, rows)
+	})
+
+	t.Run("row without prompt", func(t *testing.T) {
+		_, err := engine.LoadPromptRows(write("missing.csv", "prompt,expected\nHello,x\n,y\n"))
+		assert.ErrorContains(t, err, "row 2 has no prompt")
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := engine.LoadPromptRows(write("broken.jsonl", `{"prompt": "a"}`+"\n{not json\n"))
+		assert.ErrorContains(t, err, "line 2")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := engine.LoadPromptRows(write("prompts.txt", "prompt\n"))
+		assert.ErrorContains(t, err, "unsupported prompts file")
+	})
+}
+
+func TestExpandPromptFiles(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data", "capitals.csv"),
+		[]byte("prompt,expected,name\nCapital of France?,Paris,\nCapital of Germany?,Berlin,germany\n"), 0644))
+
+	config := &model.TestConfiguration{
+		Variables: map[string]string{"DATASET": "capitals"},
+		Sessions: []model.Session{
+			{Name: "manual", Tests: []model.Test{{Name: "hand-written", Prompt: "Hello"}}},
+			{
+				Name:        "capitals",
+				Tests:       []model.Test{{Name: "hand-written", Prompt: "Hello"}},
+				PromptsFile: "data/{{DATASET}}.csv",
+				Assertions: []model.Assertion{
+					{Type: "output_contains", Value: "{{EXPECTED}}"},
+					{Type: "tool_param_equals", Tool: "lookup", Params: map[string]string{"city": "{{EXPECTED}}"}},
+				},
+			},
+		},
+	}
+	require.NoError(t, engine.ExpandPromptFiles(config, filepath.Join(dir, "test.yaml")))
+
+	assert.Len(t, config.Sessions[0].Tests, 1)
+	tests := config.Sessions[1].Tests
+	require.Len(t, tests, 3, "rows are added after the session's own tests")
+	assert.Equal(t, "capitals #1", tests[1].Name)
+	assert.Equal(t, "germany", tests[2].Name)
+	assert.Equal(t, "Capital of Germany?", tests[2].Prompt)
+	assert.Equal(t, "Berlin", tests[2].Variables["EXPECTED"])
+	require.Len(t, tests[2].Assertions, 2)
+
+	// Params are rendered in place, so each test needs its own copy
+	tests[1].Assertions[1].Params["city"] = "Paris"
+	assert.Equal(t, "{{EXPECTED}}", tests[2].Assertions[1].Params["city"])
+	assert.Equal(t, "{{EXPECTED}}", config.Sessions[1].Assertions[1].Params["city"])
+
+	config.Sessions[1].PromptsFile = "data/missing.csv"
+	assert.ErrorContains(t, engine.ExpandPromptFiles(config, filepath.Join(dir, "test.yaml")), "session 'capitals'")
+}
+
+func TestRunTests_PromptsFile(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	testTools := createTestTools()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "capitals.jsonl"), []byte(
+		`{"prompt": "Capital of France?", "expected": "Paris"}`+"\n"+
+			`{"prompt": "Capital of Germany?", "expected": "Berlin"}`+"\n"), 0644))
+
+	// Each row starts a new conversation
+	var conversationLengths []int
+	mockLLM := new(MockLLMModel)
+	mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			conversationLengths = append(conversationLengths, len(args.Get(1).([]llms.MessageContent)))
+		}).
+		Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "It is Paris", StopReason: "stop"}},
+		}, nil)
+	mockClient := new(MockMCPClient)
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mcpServer := createMockServer("server", testTools)
+	mcpServer.Client = mockClient
+	ag := agent.NewMCPAgent(ctx, "agent", []model.AgentServer{{Name: "server"}},
+		[]*server.MCPServer{mcpServer}, "provider", mockLLM)
+
+	testConfig := &model.TestConfiguration{
+		Agents: []model.Agent{{Name: "agent", Provider: "provider", Servers: []model.AgentServer{{Name: "server"}}}},
+		Sessions: []model.Session{{
+			Name:        "capitals",
+			PromptsFile: filepath.Join(dir, "capitals.jsonl"),
+			Assertions:  []model.Assertion{{Type: "output_contains", Value: "{{EXPECTED}}"}},
+		}},
+	}
+	require.NoError(t, engine.ExpandPromptFiles(testConfig, ""))
+
+	results := engine.RunTests(ctx, testConfig,
+		map[string]*agent.MCPAgent{"agent": ag},
+		map[string]llms.Model{"provider": mockLLM}, 5, 0, 0, 0, "", "")
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "capitals #1", results[0].Execution.TestName)
+	assert.True(t, results[0].Passed)
+	assert.Equal(t, "capitals #2", results[1].Execution.TestName)
+	assert.False(t, results[1].Passed, "the second row expects Berlin")
+	assert.Contains(t, results[1].Assertions[0].Message, "Berlin")
+	assert.Equal(t, []int{1, 1}, conversationLengths)
+}
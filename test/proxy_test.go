@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeProxy is a forward proxy that records the hosts it was asked to reach and
+// answers every request itself with an OpenAI-style chat completion.
+type fakeProxy struct {
+	*httptest.Server
+	mu    sync.Mutex
+	hosts []string
+}
+
+func newFakeProxy(t *testing.T) *fakeProxy {
+	p := &fakeProxy{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.hosts = append(p.hosts, r.URL.Host)
+		p.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"proxied"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakeProxy) Hosts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.hosts...)
+}
+
+func TestNewProxyHTTPClient(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("http_proxy", "")
+	t.Setenv("https_proxy", "")
+
+	t.Run("No proxy configured", func(t *testing.T) {
+		client, err := server.NewProxyHTTPClient("")
+		require.NoError(t, err)
+		assert.Nil(t, client)
+	})
+
+	t.Run("Invalid proxy URL", func(t *testing.T) {
+		_, err := server.NewProxyHTTPClient("not a url")
+		assert.ErrorContains(t, err, "invalid proxy URL")
+	})
+
+	t.Run("Explicit proxy", func(t *testing.T) {
+		proxy := newFakeProxy(t)
+		client, err := server.NewProxyHTTPClient(proxy.URL)
+		require.NoError(t, err)
+		require.NotNil(t, client)
+
+		resp, err := client.Get("http://mcp.example.invalid/sse")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []string{"mcp.example.invalid"}, proxy.Hosts())
+	})
+
+	t.Run("Environment proxy honours NO_PROXY", func(t *testing.T) {
+		proxy := newFakeProxy(t)
+		t.Setenv("HTTP_PROXY", proxy.URL)
+		t.Setenv("NO_PROXY", "skip.example.invalid")
+
+		client, err := server.NewProxyHTTPClient("")
+		require.NoError(t, err)
+		require.NotNil(t, client)
+
+		resp, err := client.Get("http://mcp.example.invalid/mcp")
+		require.NoError(t, err)
+		resp.Body.Close()
+		_, err = client.Get("http://skip.example.invalid/mcp")
+		assert.Error(t, err)
+		assert.Equal(t, []string{"mcp.example.invalid"}, proxy.Hosts())
+	})
+}
+
+func TestInitProviders_Proxy(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	proxy := newFakeProxy(t)
+	t.Setenv("TEST_PROXY_URL", proxy.URL)
+
+	for _, retry := range []bool{false, true} {
+		providers, err := engine.InitProviders(ctx, []model.Provider{{
+			Name:    "proxied",
+			Type:    model.ProviderOpenAI,
+			Token:   "test-token",
+			Model:   "gpt-4o-mini",
+			BaseURL: "http://llm.example.invalid/v1",
+			Proxy:   "{{TEST_PROXY_URL}}",
+			Retry:   model.RetryConfig{RetryOn429: retry},
+		}}, engine.CreateTemplateContext(nil))
+		require.NoError(t, err)
+
+		resp, err := providers["proxied"].GenerateContent(ctx, []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, "hello"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "proxied", resp.Choices[0].Content)
+	}
+	assert.Equal(t, []string{"llm.example.invalid", "llm.example.invalid"}, proxy.Hosts())
+}
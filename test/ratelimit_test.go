@@ -414,6 +414,144 @@ func TestRetryConfig_RetryOn429(t *testing.T) {
 	}
 }
 
+// flakyLLM fails the first failures calls with a 429 and succeeds afterwards.
+type flakyLLM struct {
+	failures int
+	calls    atomic.Int32
+}
+
+func (f *flakyLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if int(f.calls.Add(1)) <= f.failures {
+		return nil, fmt.Errorf("429 Too Many Requests: rate limit exceeded")
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "ok"}}}, nil
+}
+
+func (f *flakyLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, f, prompt, options...)
+}
+
+func TestRetryConfig_BackoffSchedule(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int
+		retryConfig    model.RetryConfig
+		wantCalls      int
+		wantErr        bool
+		wantBackoffsMs []int64
+	}{
+		{
+			name:     "Recovers within max_attempts",
+			failures: 2,
+			retryConfig: model.RetryConfig{RetryOn429: true, MaxAttempts: 4,
+				InitialBackoff: "10ms", Multiplier: 3},
+			wantCalls:      3,
+			wantBackoffsMs: []int64{10, 30},
+		},
+		{
+			name:     "Exhausts attempts with capped backoff",
+			failures: 10,
+			retryConfig: model.RetryConfig{RetryOn429: true, MaxAttempts: 4,
+				InitialBackoff: "10ms", MaxBackoff: "25ms", Multiplier: 2},
+			wantCalls:      4,
+			wantErr:        true,
+			wantBackoffsMs: []int64{10, 20, 25},
+		},
+		{
+			name:     "Multiplier of one keeps a constant backoff",
+			failures: 3,
+			retryConfig: model.RetryConfig{RetryOn429: true, MaxRetries: 3,
+				InitialBackoff: "5ms", Multiplier: 1},
+			wantCalls:      4,
+			wantBackoffsMs: []int64{5, 5, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.SetupLogger(NewDummyWriter(), true)
+			fake := &flakyLLM{failures: tt.failures}
+			rl := engine.NewRateLimitedLLM(fake, model.RateLimitConfig{}, tt.retryConfig, "")
+
+			start := time.Now()
+			_, err := rl.GenerateContent(context.Background(), []llms.MessageContent{
+				llms.TextParts(llms.ChatMessageTypeHuman, "Hello"),
+			})
+			elapsed := time.Since(start)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, int32(tt.wantCalls), fake.calls.Load())
+
+			stats := rl.GetStats()
+			assert.Equal(t, tt.wantBackoffsMs, stats.RetryBackoffsMs)
+			assert.Equal(t, len(tt.wantBackoffsMs), stats.RetryCount)
+
+			var total int64
+			for _, ms := range tt.wantBackoffsMs {
+				total += ms
+			}
+			assert.GreaterOrEqual(t, stats.RetryWaitTimeMs, total)
+			assert.GreaterOrEqual(t, elapsed, time.Duration(total)*time.Millisecond)
+
+			policy, err := engine.ResolveRetryPolicy(tt.retryConfig)
+			assert.NoError(t, err)
+			if !tt.wantErr {
+				// The backoffs used are a prefix of the configured schedule
+				schedule := policy.Schedule()
+				for i, ms := range tt.wantBackoffsMs {
+					assert.Equal(t, ms, schedule[i].Milliseconds())
+				}
+			}
+		})
+	}
+}
+
+func TestResolveRetryPolicy_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  model.RetryConfig
+		wantErr string
+	}{
+		{name: "Defaults", config: model.RetryConfig{RetryOn429: true}},
+		{name: "Negative max_retries", config: model.RetryConfig{MaxRetries: -1}, wantErr: "max_retries must not be negative"},
+		{name: "Negative max_attempts", config: model.RetryConfig{MaxAttempts: -2}, wantErr: "max_attempts must not be negative"},
+		{name: "Both retry counts", config: model.RetryConfig{MaxRetries: 2, MaxAttempts: 3}, wantErr: "cannot both be set"},
+		{name: "Multiplier below one", config: model.RetryConfig{Multiplier: 0.5}, wantErr: "multiplier must be at least 1"},
+		{name: "Unparseable backoff", config: model.RetryConfig{InitialBackoff: "soon"}, wantErr: "invalid retry.initial_backoff"},
+		{name: "Negative backoff", config: model.RetryConfig{InitialBackoff: "-1s"}, wantErr: "initial_backoff must not be negative"},
+		{name: "Max below initial", config: model.RetryConfig{InitialBackoff: "5s", MaxBackoff: "1s"}, wantErr: "must not be less than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := engine.ResolveRetryPolicy(tt.config)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, 3, policy.MaxRetries)
+				assert.Equal(t, time.Second, policy.InitialBackoff)
+				assert.Equal(t, 60*time.Second, policy.MaxBackoff)
+				assert.Equal(t, 2.0, policy.Multiplier)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("Rejected by test config validation", func(t *testing.T) {
+		config := &model.TestConfiguration{
+			Providers: []model.Provider{{Name: "azure", Retry: model.RetryConfig{RetryOn429: true, Multiplier: 0.9}}},
+			Agents:    []model.Agent{{Name: "agent"}},
+			Sessions:  []model.Session{{Name: "session"}},
+		}
+		err := engine.ValidateTestConfig(config, false)
+		assert.ErrorContains(t, err, "provider 'azure': retry.multiplier must be at least 1")
+	})
+}
+
 func TestProvider_WithRateLimits(t *testing.T) {
 	// Test that Provider struct properly holds RateLimitConfig and RetryConfig
 	provider := model.Provider{
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func callToolRequest(name string, args any) mcp.CallToolRequest {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return req
+}
+
+func TestToolRecordingRoundTrip(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	live := new(MockMCPClient)
+	live.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: createTestTools()}, nil)
+	live.On("CallTool", ctx, mock.MatchedBy(func(req mcp.CallToolRequest) bool {
+		return req.Params.Name == "test_tool_1"
+	})).Return(mcp.NewToolResultText("first"), nil).Once()
+	live.On("CallTool", ctx, mock.MatchedBy(func(req mcp.CallToolRequest) bool {
+		return req.Params.Name == "test_tool_1"
+	})).Return(mcp.NewToolResultText("second"), nil).Once()
+	live.On("CallTool", ctx, mock.MatchedBy(func(req mcp.CallToolRequest) bool {
+		return req.Params.Name == "test_tool_2"
+	})).Return(nil, errors.New("server exploded"))
+	live.On("Close").Return(nil)
+
+	recordingFactory := &engine.RecordingServerFactory{
+		Base: &MockServerFactory{CreateFunc: func(ctx context.Context, config model.Server) (*server.MCPServer, error) {
+			return &server.MCPServer{Name: config.Name, Client: live}, nil
+		}},
+		Dir: dir,
+	}
+	recorded, err := recordingFactory.NewMCPServer(ctx, model.Server{Name: "files/api"})
+	require.NoError(t, err)
+
+	_, err = recorded.Client.ListTools(ctx, mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	_, err = recorded.Client.CallTool(ctx, callToolRequest("test_tool_1", map[string]any{"a": 1, "b": "x"}))
+	require.NoError(t, err)
+	_, err = recorded.Client.CallTool(ctx, callToolRequest("test_tool_1", map[string]any{"a": 1, "b": "x"}))
+	require.NoError(t, err)
+	_, err = recorded.Client.CallTool(ctx, callToolRequest("test_tool_2", nil))
+	require.Error(t, err)
+	require.NoError(t, recorded.Close())
+
+	recording, err := server.LoadRecording(dir, "files/api")
+	require.NoError(t, err)
+	assert.Len(t, recording.Tools, 2)
+	assert.Len(t, recording.Calls, 3)
+
+	replayed, err := (&engine.ReplayServerFactory{Dir: dir}).NewMCPServer(ctx, model.Server{Name: "files/api"})
+	require.NoError(t, err)
+
+	tools, err := replayed.Client.ListTools(ctx, mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "test_tool_1", tools.Tools[0].Name)
+
+	t.Run("matches parameters regardless of encoding", func(t *testing.T) {
+		res, err := replayed.Client.CallTool(ctx, callToolRequest("test_tool_1", json.RawMessage(`{"b": "x", "a": 1}`)))
+		require.NoError(t, err)
+		assert.Equal(t, "first", res.Content[0].(mcp.TextContent).Text)
+
+		res, err = replayed.Client.CallTool(ctx, callToolRequest("test_tool_1", map[string]any{"a": 1, "b": "x"}))
+		require.NoError(t, err)
+		assert.Equal(t, "second", res.Content[0].(mcp.TextContent).Text)
+
+		// Once the recorded responses run out, the last one is reused
+		res, err = replayed.Client.CallTool(ctx, callToolRequest("test_tool_1", map[string]any{"a": 1, "b": "x"}))
+		require.NoError(t, err)
+		assert.Equal(t, "second", res.Content[0].(mcp.TextContent).Text)
+	})
+
+	t.Run("replays recorded errors", func(t *testing.T) {
+		_, err := replayed.Client.CallTool(ctx, callToolRequest("test_tool_2", nil))
+		require.Error(t, err)
+		assert.Equal(t, "server exploded", err.Error())
+	})
+
+	t.Run("unrecorded parameters fail clearly", func(t *testing.T) {
+		_, err := replayed.Client.CallTool(ctx, callToolRequest("test_tool_1", map[string]any{"a": 2}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no recorded call to tool 'test_tool_1'")
+		assert.Contains(t, err.Error(), `{"a":2}`)
+	})
+
+	t.Run("missing recording fails at startup", func(t *testing.T) {
+		_, err := (&engine.ReplayServerFactory{Dir: dir}).NewMCPServer(ctx, model.Server{Name: "other"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no recording for server 'other'")
+	})
+
+	live.AssertExpectations(t)
+}
@@ -193,7 +193,7 @@ func createMultiAgentComparison() []model.TestRun {
 			Execution: &model.ExecutionResult{
 				TestName:     "Setup project workspace",
 				AgentName:    "mistral-agent",
-				ProviderType: model.ProviderOpenAI, // Using OpenAI as placeholder
+				ProviderType: model.ProviderMistral,
 				StartTime:    fixtureBaseTime,
 				EndTime:      fixtureBaseTime.Add(25000 * time.Millisecond),
 				Messages: []model.Message{
@@ -688,7 +688,7 @@ func createSuiteRun() []model.TestRun {
 			Execution: &model.ExecutionResult{
 				TestName:     "Setup project workspace",
 				AgentName:    "mistral-agent",
-				ProviderType: model.ProviderOpenAI,
+				ProviderType: model.ProviderMistral,
 				StartTime:    fixtureBaseTime,
 				EndTime:      fixtureBaseTime.Add(20000 * time.Millisecond),
 				SourceFile:   "tests/project-setup.yaml",
@@ -725,7 +725,7 @@ func createSuiteRun() []model.TestRun {
 			Execution: &model.ExecutionResult{
 				TestName:     "Add project dependencies",
 				AgentName:    "mistral-agent",
-				ProviderType: model.ProviderOpenAI,
+				ProviderType: model.ProviderMistral,
 				StartTime:    fixtureBaseTime.Add(15 * time.Second),
 				EndTime:      fixtureBaseTime.Add(30 * time.Second),
 				SourceFile:   "tests/project-setup.yaml",
@@ -762,7 +762,7 @@ func createSuiteRun() []model.TestRun {
 			Execution: &model.ExecutionResult{
 				TestName:     "Fetch user profile",
 				AgentName:    "mistral-agent",
-				ProviderType: model.ProviderOpenAI,
+				ProviderType: model.ProviderMistral,
 				StartTime:    fixtureBaseTime.Add(30 * time.Second),
 				EndTime:      fixtureBaseTime.Add(45 * time.Second),
 				SourceFile:   "tests/api-tests.yaml",
@@ -800,7 +800,7 @@ func createSuiteRun() []model.TestRun {
 			Execution: &model.ExecutionResult{
 				TestName:     "Handle 404 gracefully",
 				AgentName:    "mistral-agent",
-				ProviderType: model.ProviderOpenAI,
+				ProviderType: model.ProviderMistral,
 				StartTime:    fixtureBaseTime.Add(45 * time.Second),
 				EndTime:      fixtureBaseTime.Add(60 * time.Second),
 				SourceFile:   "tests/api-tests.yaml",
@@ -1,12 +1,16 @@
 package tests
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/mykhaliev/agent-benchmark/agent"
 	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/mykhaliev/agent-benchmark/report"
 )
@@ -65,6 +69,81 @@ func TestGenerateHTML(t *testing.T) {
 	}
 }
 
+func TestGenerateHTMLWithAnalysis_RendersMarkdown(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	analysis := &agent.AISummaryResult{
+		Success:  true,
+		Analysis: "## Verdict\n\nUse **test-agent**.\n\n| Agent | Score |\n|---|---|\n| test-agent | 1 |\n\n<script>alert('x')</script>\n\n[link](javascript:alert(1))",
+	}
+	html, err := gen.GenerateHTMLWithAnalysis(createSampleTestRuns(), analysis)
+	if err != nil {
+		t.Fatalf("GenerateHTMLWithAnalysis() failed: %v", err)
+	}
+
+	for _, want := range []string{"<h2>Verdict</h2>", "<strong>test-agent</strong>", "<table>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("AI summary should be rendered server-side, missing %q", want)
+		}
+	}
+	if strings.Contains(html, "<script>alert('x')</script>") {
+		t.Error("raw HTML from the AI summary must not be rendered")
+	}
+	if strings.Contains(html, "javascript:alert(1)") {
+		t.Error("dangerous links from the AI summary must be dropped")
+	}
+	if strings.Contains(html, "marked.parse") {
+		t.Error("AI summary should not depend on client-side Markdown rendering")
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	malicious := `<h2>Verdict</h2><p onclick="steal()">Use <strong>agent-a</strong> and <code>x</code></p>` +
+		`<script>alert('xss')</script><a href="javascript:alert(1)">bad</a><a href="https://example.com">good</a>` +
+		`<table><tr><td>cell</td></tr></table><img src=x onerror="alert(1)">`
+
+	html := string(report.SanitizeHTML(malicious))
+
+	for _, banned := range []string{"<script", "alert('xss')", "onclick", "javascript:", "onerror"} {
+		if strings.Contains(html, banned) {
+			t.Errorf("sanitized HTML should not contain %q: %s", banned, html)
+		}
+	}
+	for _, kept := range []string{"<h2>Verdict</h2>", "<strong>agent-a</strong>", "<code>x</code>", `href="https://example.com"`, "<td>cell</td>"} {
+		if !strings.Contains(html, kept) {
+			t.Errorf("sanitized HTML should keep %q: %s", kept, html)
+		}
+	}
+}
+
+func TestGenerateHTML_EscapesToolData(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := createSampleTestRuns()
+	results[0].Execution.ToolCalls = []model.ToolCall{{
+		Name:       "fetch_page",
+		Parameters: map[string]interface{}{"html": "<img src=x onerror=alert(1)>"},
+		Timestamp:  time.Now(),
+	}}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Contains(html, "<img src=x onerror=alert(1)>") {
+		t.Error("tool parameters must be HTML-escaped in the report")
+	}
+	if !strings.Contains(html, "&lt;img src=x onerror=alert(1)&gt;") {
+		t.Error("tool parameters should be shown as escaped text")
+	}
+}
+
 func TestGenerateHTMLEmptyResults(t *testing.T) {
 	gen, err := report.NewGenerator()
 	if err != nil {
@@ -200,6 +279,69 @@ func TestLoadResultsFromJSONEmptyResults(t *testing.T) {
 	}
 }
 
+func TestLoadResultsFromJSONSchemaV1(t *testing.T) {
+	reportData, err := report.LoadFullReportFromJSON(filepath.Join("testdata", "report_v1.json"))
+	if err != nil {
+		t.Fatalf("LoadFullReportFromJSON() failed: %v", err)
+	}
+	if len(reportData.Results) != 3 || reportData.TestFile != "tests/weather.yaml" {
+		t.Fatalf("unexpected report: %d results, test file %q", len(reportData.Results), reportData.TestFile)
+	}
+
+	// Version 1 only recorded error messages; the loader classifies them
+	if details := reportData.Results[0].Execution.ErrorDetails; len(details) != 0 {
+		t.Errorf("expected no error details for a clean run, got %+v", details)
+	}
+	if !reportData.Results[1].Execution.HasErrorKind(model.ErrorKindTestTimeout) {
+		t.Errorf("expected the timeout to be classified, got %+v", reportData.Results[1].Execution.ErrorDetails)
+	}
+	claude := reportData.Results[2].Execution
+	if claude.CountErrorKind(model.ErrorKindRateLimit) != 1 || len(claude.ErrorDetails) != 1 {
+		t.Errorf("expected only the 429 to be classified as a rate limit, got %+v", claude.ErrorDetails)
+	}
+
+	// Assertions that rely on error kinds see the migrated errors
+	evaluator := model.NewAssertionEvaluator(claude, map[string]string{}, nil)
+	if results := evaluator.Evaluate([]model.Assertion{{Type: "no_rate_limit_errors"}}); results[0].Passed {
+		t.Error("no_rate_limit_errors should fail for a migrated 429 error")
+	}
+}
+
+func TestLoadResultsFromJSONSchemaVersion(t *testing.T) {
+	results := []model.TestRun{{
+		Execution: &model.ExecutionResult{TestName: "current", AgentName: "agent"},
+		Passed:    true,
+	}}
+	results[0].Execution.AddError(model.ErrorKindToolTimeout, "tool 'slow' timed out")
+	jsonOutput := model.NewReportGenerator().GenerateJSONReportWithAnalysis(results, nil)
+	if !strings.Contains(jsonOutput, fmt.Sprintf(`"schema_version": %d`, model.JSONReportSchemaVersion)) {
+		t.Error("JSON report should carry the schema version")
+	}
+
+	dir := t.TempDir()
+	current := filepath.Join(dir, "current.json")
+	if err := os.WriteFile(current, []byte(jsonOutput), 0644); err != nil {
+		t.Fatalf("Failed to write test JSON: %v", err)
+	}
+	loaded, err := report.LoadResultsFromJSON(current)
+	if err != nil {
+		t.Fatalf("LoadResultsFromJSON() failed: %v", err)
+	}
+	if details := loaded[0].Execution.ErrorDetails; len(details) != 1 || details[0].Kind != model.ErrorKindToolTimeout {
+		t.Errorf("current reports should load unchanged, got %+v", details)
+	}
+
+	newer := filepath.Join(dir, "newer.json")
+	content := fmt.Sprintf(`{"schema_version": %d, "detailed_results": [{"execution": {"testName": "t"}}]}`, model.JSONReportSchemaVersion+1)
+	if err := os.WriteFile(newer, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test JSON: %v", err)
+	}
+	_, err = report.LoadResultsFromJSON(newer)
+	if err == nil || !strings.Contains(err.Error(), "unsupported schema version") {
+		t.Errorf("expected an unsupported schema version error, got %v", err)
+	}
+}
+
 func TestGenerateReportFromJSON(t *testing.T) {
 	// Create a temporary JSON file
 	jsonContent := `{
@@ -291,6 +433,42 @@ func TestHTMLContainsAllSections(t *testing.T) {
 	}
 }
 
+func TestAgentStatsLatencyPercentiles(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	// Similar averages, very different tails
+	var results []model.TestRun
+	for i, ms := range []int64{1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000} {
+		results = append(results, model.TestRun{Passed: true, Execution: &model.ExecutionResult{TestName: fmt.Sprintf("t%d", i), AgentName: "steady", LatencyMs: ms}})
+	}
+	for i, ms := range []int64{500, 500, 500, 500, 500, 500, 500, 500, 500, 5500} {
+		results = append(results, model.TestRun{Passed: true, Execution: &model.ExecutionResult{TestName: fmt.Sprintf("t%d", i), AgentName: "spiky", LatencyMs: ms}})
+	}
+
+	data := gen.BuildReportData(results, nil)
+	stats := make(map[string]report.AgentStatsView)
+	for _, s := range data.AgentStats {
+		stats[s.AgentName] = s
+	}
+	if s := stats["steady"]; s.P50LatencyMs != 1000 || s.P95LatencyMs != 1000 {
+		t.Errorf("constant latencies should have equal percentiles: %+v", s)
+	}
+	if s := stats["spiky"]; s.P50LatencyMs != 500 || math.Round(s.P90LatencyMs) != 1000 || math.Round(s.P95LatencyMs) != 3250 {
+		t.Errorf("percentiles should interpolate the tail, got p50=%v p90=%v p95=%v", s.P50LatencyMs, s.P90LatencyMs, s.P95LatencyMs)
+	}
+
+	html, err := gen.Render(data)
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if !strings.Contains(html, ">p95</th>") || !strings.Contains(html, "3.25s") {
+		t.Error("HTML leaderboard should show the latency percentiles")
+	}
+}
+
 func TestHTMLPassedFailedStyling(t *testing.T) {
 	gen, err := report.NewGenerator()
 	if err != nil {
@@ -338,95 +516,1082 @@ func TestHTMLPassedFailedStyling(t *testing.T) {
 	}
 }
 
-// TestReportFixtures verifies all fixture functions produce valid data
-func TestReportFixtures(t *testing.T) {
-	fixtures := map[string]func() []model.TestRun{
-		"SingleAgentSingleTest": createSingleAgentSingleTest,
-		"MultiAgentComparison":  createMultiAgentComparison,
-		"MultiSessionRun":       createMultiSessionRun,
-		"SuiteRun":              createSuiteRun,
-		"FailedTestWithErrors":  createFailedTestWithErrors,
-		"LargeScaleRun":         createLargeScaleRun,
+func TestHTMLVerdictBanner(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
 	}
 
-	for name, fixture := range fixtures {
-		t.Run(name, func(t *testing.T) {
-			results := fixture()
-			if len(results) == 0 {
-				t.Errorf("%s: expected non-empty results", name)
-			}
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "a1", AgentName: "agent", SourceFile: "a.yaml"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "b1", AgentName: "agent", SourceFile: "b.yaml"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "b2", AgentName: "agent", SourceFile: "b.yaml"}, Passed: false},
+	}
 
-			for i, run := range results {
-				if run.Execution == nil {
-					t.Errorf("%s[%d]: Execution should not be nil", name, i)
-					continue
-				}
-				if run.Execution.TestName == "" {
-					t.Errorf("%s[%d]: TestName should not be empty", name, i)
-				}
-				if run.Execution.AgentName == "" {
-					t.Errorf("%s[%d]: AgentName should not be empty", name, i)
-				}
-			}
-		})
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `verdict-banner verdict-fail`) || !strings.Contains(html, "2/3 passed (66.7%)") {
+		t.Error("HTML should contain a failing verdict banner with the pass rate")
+	}
+
+	groups := report.FileGroups(results)
+	if len(groups) != 2 || groups[0].PassedTests != 1 || groups[1].FailedTests != 1 {
+		t.Errorf("unexpected file groups: %+v", groups)
+	}
+	if !strings.Contains(html, `file-verdict text-fail`) || !strings.Contains(html, `file-verdict text-pass`) {
+		t.Error("HTML should contain a verdict per file")
 	}
 }
 
-// TestGenerateHTMLWithFixtures generates HTML reports from each fixture
-func TestGenerateHTMLWithFixtures(t *testing.T) {
+func TestHTMLAssertionTime(t *testing.T) {
 	gen, err := report.NewGenerator()
 	if err != nil {
-		t.Fatalf("Failed to create generator: %v", err)
+		t.Fatalf("NewGenerator() failed: %v", err)
 	}
 
-	fixtures := map[string]func() []model.TestRun{
-		"single_agent":  createSingleAgentSingleTest,
-		"multi_agent":   createMultiAgentComparison,
-		"multi_session": createMultiSessionRun,
-		"suite_run":     createSuiteRun,
-		"failed_errors": createFailedTestWithErrors,
-		"large_scale":   createLargeScaleRun,
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "a", AgentName: "agent"}, Passed: true},
+	}
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Contains(html, "Assertion Time") {
+		t.Error("HTML should not show assertion time when none was recorded")
 	}
 
-	for name, fixture := range fixtures {
-		t.Run(name, func(t *testing.T) {
-			results := fixture()
+	results[0].Execution.AssertionDurationMs = 2500
+	html, err = gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, "Assertion Time") || !strings.Contains(html, "2.5s") {
+		t.Error("HTML should show the total assertion time")
+	}
+}
 
-			html, err := gen.GenerateHTML(results)
-			if err != nil {
-				t.Fatalf("Failed to generate HTML: %v", err)
-			}
+func TestHTMLMatrixCellFailureTooltip(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
 
-			if len(html) == 0 {
-				t.Error("Generated HTML should not be empty")
-			}
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "lookup", AgentName: "fast"}, Passed: true},
+		{
+			Execution: &model.ExecutionResult{TestName: "lookup", AgentName: "slow", Errors: []string{"tool failed", "retry failed"}},
+			Assertions: []model.AssertionResult{
+				{Type: "tool_called", Passed: true, Message: "called"},
+				{Type: "output_contains", Passed: false, Message: "Output missing: user id"},
+				{Type: "max_latency_ms", Passed: false, Message: "too slow"},
+			},
+			Passed: false,
+		},
+	}
 
-			t.Logf("Generated %s report: %d bytes", name, len(html))
-		})
+	matrix := report.BuildReportData(results, nil).Matrix
+	cell := matrix.Cells["lookup"]["slow"]
+	if cell.FirstFailure != "[output_contains] Output missing: user id" || cell.FailedAssertions != 2 || cell.ErrorCount != 2 {
+		t.Errorf("unexpected failure summary: %+v", cell)
+	}
+	if passed := matrix.Cells["lookup"]["fast"]; passed.FirstFailure != "" || passed.FailedAssertions != 0 {
+		t.Errorf("passing cell should have no failure summary: %+v", passed)
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Count(html, `class="matrix-cell matrix-cell-failed"`) != 1 {
+		t.Error("only the failed cell should carry a tooltip")
+	}
+	if !strings.Contains(html, "[output_contains] Output missing: user id") ||
+		!strings.Contains(html, "2 failed assertion(s) · 2 error(s)") {
+		t.Error("tooltip should show the first failed assertion and the counts")
 	}
 }
 
-// Helper function to create sample test runs
-func createSampleTestRuns() []model.TestRun {
-	return []model.TestRun{
-		{
+func TestHTMLSideBySideSessionDiagrams(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	run := func(session, agentName string) model.TestRun {
+		return model.TestRun{
 			Execution: &model.ExecutionResult{
-				TestName:     "Sample Test",
-				AgentName:    "test-agent",
-				ProviderType: "openai",
-				StartTime:    time.Now(),
-				EndTime:      time.Now().Add(5 * time.Second),
-				TokensUsed:   1000,
-				FinalOutput:  "Test completed successfully",
+				TestName:    "open notepad",
+				SessionName: session,
+				AgentName:   agentName,
+				ToolCalls:   []model.ToolCall{{Name: "launch_app"}},
+			},
+			Passed: true,
+		}
+	}
+	results := []model.TestRun{
+		run("edit", "gpt41-agent"), run("edit", "gpt52-agent"),
+		run("save", "gpt41-agent"), run("save", "gpt52-agent"),
+	}
+
+	data := report.BuildReportData(results, nil)
+	if !data.Adaptive.Flags.SideBySideDiagrams {
+		t.Error("multi-agent reports should lay session diagrams out side by side")
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Count(html, `class="session-agent-diagrams-grid side-by-side" style="--diagram-columns: 2"`) != 2 {
+		t.Error("each session should render its two agent diagrams in two columns")
+	}
+	if !strings.Contains(html, `onclick="setDiagramLayout(this, false)">Stacked</button>`) {
+		t.Error("HTML should offer switching back to a stacked layout")
+	}
+
+	single := report.BuildReportData([]model.TestRun{run("edit", "gpt41-agent"), run("save", "gpt41-agent")}, nil)
+	if single.Adaptive.Flags.SideBySideDiagrams {
+		t.Error("single-agent reports have nothing to compare side by side")
+	}
+}
+
+func TestHTMLWarningAssertions(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{
+			Execution: &model.ExecutionResult{TestName: "save file", AgentName: "agent"},
+			Assertions: []model.AssertionResult{
+				{Type: "tool_called", Passed: true, Severity: model.SeverityError, Message: "called"},
+				{Type: "max_latency_ms", Passed: false, Severity: model.SeverityWarning, Message: "Latency 7000ms exceeds 5000ms"},
 			},
+			Passed: true,
+		},
+	}
+
+	data := report.BuildReportData(results, nil)
+	if data.Summary.Passed != 1 || data.Summary.Failed != 0 || data.Summary.Warnings != 1 {
+		t.Errorf("warnings should be counted separately from failures: %+v", data.Summary)
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `<div class="summary-card warnings">`) {
+		t.Error("HTML should show a warnings summary card")
+	}
+	if !strings.Contains(html, `assertion-item warning`) || !strings.Contains(html, `<span class="severity-badge">warning</span>`) {
+		t.Error("warning-level failures should be rendered with a warning badge")
+	}
+}
+
+func TestHTMLDisabledAssertions(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{
+			Execution: &model.ExecutionResult{TestName: "save file", AgentName: "agent"},
 			Assertions: []model.AssertionResult{
-				{
-					Type:    "contains",
-					Passed:  true,
-					Message: "Output contains expected text",
-				},
+				{Type: "tool_called", Passed: true, Severity: model.SeverityError, Message: "called"},
+				{Type: "llm_rubric", Passed: true, Disabled: true, Message: "Disabled by the assertion filter"},
 			},
 			Passed: true,
 		},
 	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `assertion-item disabled`) || !strings.Contains(html, `<span class="severity-badge disabled-badge">disabled</span>`) {
+		t.Error("disabled assertions should be rendered with a disabled badge")
+	}
+	if strings.Count(html, `assertion-item passed`) != 1 {
+		t.Error("a disabled assertion should not be shown as passed")
+	}
+}
+
+func TestHTMLSkippedTests(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "runs", AgentName: "agent"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "excel only", AgentName: "agent"}, Skipped: true, SkipReason: "needs windows"},
+	}
+
+	data := report.BuildReportData(results, nil)
+	if data.Summary.Total != 1 || data.Summary.Passed != 1 || data.Summary.Skipped != 1 || data.Summary.PassRate != 100 {
+		t.Errorf("skipped tests should be excluded from totals and pass rate: %+v", data.Summary)
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `<div class="summary-card skipped">`) {
+		t.Error("HTML should show a skipped summary card")
+	}
+	if !strings.Contains(html, `<tr class="row-skipped">`) || !strings.Contains(html, "needs windows") {
+		t.Error("HTML should list skipped tests with their reason")
+	}
+
+	rg := model.NewReportGenerator()
+	var parsed struct {
+		Summary map[string]int `json:"summary"`
+		Results []struct {
+			Skipped    bool   `json:"skipped"`
+			SkipReason string `json:"skipReason"`
+		} `json:"detailed_results"`
+	}
+	if err := json.Unmarshal([]byte(rg.GenerateJSONReport(results)), &parsed); err != nil {
+		t.Fatalf("invalid JSON report: %v", err)
+	}
+	if parsed.Summary["passed"] != 1 || parsed.Summary["failed"] != 0 || parsed.Summary["skipped"] != 1 {
+		t.Errorf("unexpected JSON summary: %v", parsed.Summary)
+	}
+	if len(parsed.Results) != 2 || !parsed.Results[1].Skipped || parsed.Results[1].SkipReason != "needs windows" {
+		t.Errorf("skip status missing from detailed results: %+v", parsed.Results)
+	}
+}
+
+func TestHTMLEstimatedTokens(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "reported", AgentName: "agent", TokensUsed: 100}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "estimated", AgentName: "agent", TokensUsed: 42, TokensEstimated: true}, Passed: true},
+	}
+
+	data := report.BuildReportData(results, nil)
+	if !data.Summary.TokensEstimated {
+		t.Error("summary should flag that token totals include estimates")
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `<span class="tokens-estimated" title="Estimated: provider reported no usage">~</span>42`) {
+		t.Error("estimated token counts should be marked with ~")
+	}
+	if strings.Contains(html, `~</span>100`) {
+		t.Error("reported token counts should not be marked as estimates")
+	}
+}
+
+func TestHTMLLazyTranscript(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	newRun := func(name, toolResult string) model.TestRun {
+		return model.TestRun{
+			Execution: &model.ExecutionResult{
+				TestName:  name,
+				AgentName: "agent",
+				Messages:  []model.Message{{Role: "user", Content: "read the log"}},
+				ToolCalls: []model.ToolCall{{
+					Name:       "read_log",
+					Parameters: map[string]interface{}{"path": "app.log"},
+					Result:     model.Result{Content: []model.ContentItem{{Type: "text", Text: toolResult}}},
+				}},
+			},
+			Passed: true,
+		}
+	}
+
+	t.Run("Small transcripts render inline", func(t *testing.T) {
+		html, err := gen.GenerateHTML([]model.TestRun{newRun("small", "short log")})
+		if err != nil {
+			t.Fatalf("GenerateHTML() failed: %v", err)
+		}
+		if strings.Contains(html, `class="load-transcript-btn"`) {
+			t.Error("small transcripts should not be lazy-loaded")
+		}
+		if !strings.Contains(html, `<pre class="tool-params">`) {
+			t.Error("small transcripts should render tool calls inline")
+		}
+	})
+
+	t.Run("Large transcripts load on demand", func(t *testing.T) {
+		hostile := "</script><script>alert(1)</script>"
+		big := hostile + strings.Repeat("x", report.LazyTranscriptThreshold)
+		html, err := gen.GenerateHTML([]model.TestRun{newRun("large", big)})
+		if err != nil {
+			t.Fatalf("GenerateHTML() failed: %v", err)
+		}
+		if !strings.Contains(html, `class="load-transcript-btn"`) {
+			t.Fatal("large transcripts should render a load control")
+		}
+		if strings.Contains(html, `<pre class="tool-params">`) {
+			t.Error("large transcripts should not render tool calls inline")
+		}
+		if strings.Contains(html, hostile) {
+			t.Error("transcript JSON must not be able to close its script element")
+		}
+
+		start := strings.Index(html, `<script type="application/json" id="transcript-`)
+		if start < 0 {
+			t.Fatal("transcript JSON blob not found")
+		}
+		start += strings.Index(html[start:], ">") + 1
+		end := start + strings.Index(html[start:], "</script>")
+		var data struct {
+			ToolCalls []struct {
+				Name   string `json:"name"`
+				Result string `json:"result"`
+			} `json:"toolCalls"`
+			Messages []struct {
+				Role string `json:"role"`
+			} `json:"messages"`
+		}
+		if err := json.Unmarshal([]byte(html[start:end]), &data); err != nil {
+			t.Fatalf("transcript blob is not valid JSON: %v", err)
+		}
+		if len(data.ToolCalls) != 1 || data.ToolCalls[0].Name != "read_log" || !strings.Contains(data.ToolCalls[0].Result, hostile) {
+			t.Errorf("unexpected tool calls in transcript: %d", len(data.ToolCalls))
+		}
+		if len(data.Messages) != 1 || data.Messages[0].Role != "user" {
+			t.Errorf("unexpected messages in transcript: %+v", data.Messages)
+		}
+	})
+}
+
+func TestHTMLShowsSystemPrompt(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{
+			Execution: &model.ExecutionResult{
+				TestName:     "Primed Test",
+				AgentName:    "terse-agent",
+				ProviderType: "openai",
+				StartTime:    time.Now(),
+				EndTime:      time.Now().Add(2 * time.Second),
+				SystemPrompt: "Answer in one sentence.",
+			},
+			Passed: true,
+		},
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+
+	if !strings.Contains(html, "system-prompt-content") {
+		t.Error("HTML should contain the system prompt section")
+	}
+	if !strings.Contains(html, "Answer in one sentence.") {
+		t.Error("HTML should contain the effective system prompt")
+	}
+}
+
+func TestHTMLGroupsToolCallsByIteration(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{
+			Execution: &model.ExecutionResult{
+				TestName:     "Iterations Test",
+				AgentName:    "agent",
+				ProviderType: "openai",
+				StartTime:    time.Now(),
+				EndTime:      time.Now().Add(2 * time.Second),
+				Messages: []model.Message{
+					{Role: "user", Content: "Create and list"},
+					{Role: "assistant", Content: "All done", Iteration: 3},
+				},
+				ToolCalls: []model.ToolCall{
+					{Name: "create_file", Iteration: 1},
+					{Name: "create_file", Iteration: 1},
+					{Name: "list_files", Iteration: 2},
+				},
+			},
+			Passed: true,
+		},
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+
+	if got := strings.Count(html, `<div class="timeline-iteration">`); got != 2 {
+		t.Errorf("expected one group header per iteration, got %d", got)
+	}
+	if !strings.Contains(html, "Iteration 2</div>") {
+		t.Error("HTML should label the second iteration")
+	}
+	if !strings.Contains(html, `<span class="timeline-iteration-tag">iteration 3</span>`) {
+		t.Error("HTML should tag the assistant message with its iteration")
+	}
+}
+
+// TestReportFixtures verifies all fixture functions produce valid data
+func TestReportFixtures(t *testing.T) {
+	fixtures := map[string]func() []model.TestRun{
+		"SingleAgentSingleTest": createSingleAgentSingleTest,
+		"MultiAgentComparison":  createMultiAgentComparison,
+		"MultiSessionRun":       createMultiSessionRun,
+		"SuiteRun":              createSuiteRun,
+		"FailedTestWithErrors":  createFailedTestWithErrors,
+		"LargeScaleRun":         createLargeScaleRun,
+	}
+
+	for name, fixture := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			results := fixture()
+			if len(results) == 0 {
+				t.Errorf("%s: expected non-empty results", name)
+			}
+
+			for i, run := range results {
+				if run.Execution == nil {
+					t.Errorf("%s[%d]: Execution should not be nil", name, i)
+					continue
+				}
+				if run.Execution.TestName == "" {
+					t.Errorf("%s[%d]: TestName should not be empty", name, i)
+				}
+				if run.Execution.AgentName == "" {
+					t.Errorf("%s[%d]: AgentName should not be empty", name, i)
+				}
+			}
+		})
+	}
+}
+
+// TestGenerateHTMLWithFixtures generates HTML reports from each fixture
+func TestGenerateHTMLWithFixtures(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	fixtures := map[string]func() []model.TestRun{
+		"single_agent":  createSingleAgentSingleTest,
+		"multi_agent":   createMultiAgentComparison,
+		"multi_session": createMultiSessionRun,
+		"suite_run":     createSuiteRun,
+		"failed_errors": createFailedTestWithErrors,
+		"large_scale":   createLargeScaleRun,
+	}
+
+	for name, fixture := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			results := fixture()
+
+			html, err := gen.GenerateHTML(results)
+			if err != nil {
+				t.Fatalf("Failed to generate HTML: %v", err)
+			}
+
+			if len(html) == 0 {
+				t.Error("Generated HTML should not be empty")
+			}
+
+			t.Logf("Generated %s report: %d bytes", name, len(html))
+		})
+	}
+}
+
+func TestGenerateHTMLRunMetadataFooter(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	html, err := gen.GenerateHTML(createSampleTestRuns())
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Contains(html, `class="report-footer"`) {
+		t.Error("HTML should not contain run metadata footer when metadata is not set")
+	}
+
+	seed := 7
+	gen.RunMetadata = &model.RunMetadata{
+		Version: "1.2.3",
+		Commit:  "abc1234",
+		RunID:   "run-xyz",
+		OS:      "linux",
+		Arch:    "amd64",
+		Providers: []model.ProviderMetadata{
+			{Name: "main", Type: model.ProviderOpenAI, Model: "gpt-test", Seed: &seed},
+		},
+	}
+
+	html, err = gen.GenerateHTML(createSampleTestRuns())
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	for _, want := range []string{`class="report-footer"`, "abc1234", "run-xyz", "linux/amd64", "gpt-test", "seed 7"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML footer should contain %q", want)
+		}
+	}
+}
+
+// Helper function to create sample test runs
+func createSampleTestRuns() []model.TestRun {
+	return []model.TestRun{
+		{
+			Execution: &model.ExecutionResult{
+				TestName:     "Sample Test",
+				AgentName:    "test-agent",
+				ProviderType: "openai",
+				StartTime:    time.Now(),
+				EndTime:      time.Now().Add(5 * time.Second),
+				TokensUsed:   1000,
+				FinalOutput:  "Test completed successfully",
+			},
+			Assertions: []model.AssertionResult{
+				{
+					Type:    "contains",
+					Passed:  true,
+					Message: "Output contains expected text",
+				},
+			},
+			Passed: true,
+		},
+	}
+}
+
+func TestHTMLToolInventory(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "t1", AgentName: "beta", ToolCalls: []model.ToolCall{
+			{Name: "search", DurationMs: 100},
+			{Name: "search", DurationMs: 300},
+			{Name: "read_file"},
+		}}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "t1", AgentName: "alpha", ToolCalls: []model.ToolCall{
+			{Name: "search", DurationMs: 200},
+		}}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "t2", AgentName: "alpha", ToolCalls: []model.ToolCall{
+			{Name: "ignored"},
+		}}, Skipped: true},
+	}
+
+	data := report.BuildReportData(results, nil)
+	if len(data.ToolInventory) != 2 {
+		t.Fatalf("expected 2 tools from executed tests, got %+v", data.ToolInventory)
+	}
+	search := data.ToolInventory[0]
+	if search.Name != "search" || search.Calls != 3 || search.AvgDurationMs != 200 {
+		t.Errorf("unexpected search row: %+v", search)
+	}
+	if strings.Join(search.Agents, ",") != "alpha,beta" {
+		t.Errorf("agents should be sorted and distinct, got %v", search.Agents)
+	}
+	readFile := data.ToolInventory[1]
+	if readFile.Name != "read_file" || readFile.Calls != 1 || readFile.TimedCalls != 0 {
+		t.Errorf("unexpected read_file row: %+v", readFile)
+	}
+
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, "Tool Inventory") || !strings.Contains(html, "200ms") {
+		t.Error("HTML should render the tool inventory with average durations")
+	}
+
+	noTools := []model.TestRun{{Execution: &model.ExecutionResult{TestName: "t1", AgentName: "alpha"}, Passed: true}}
+	html, err = gen.GenerateHTML(noTools)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Contains(html, "Tool Inventory") {
+		t.Error("tool inventory should be omitted when no tools were called")
+	}
+}
+
+func TestWeightedPassRate(t *testing.T) {
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "critical", AgentName: "alpha"}, Passed: false, Weight: 3},
+		{Execution: &model.ExecutionResult{TestName: "edge", AgentName: "alpha"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "critical", AgentName: "beta"}, Passed: true, Weight: 3},
+		{Execution: &model.ExecutionResult{TestName: "edge", AgentName: "beta"}, Passed: false},
+		{Execution: &model.ExecutionResult{TestName: "skipped", AgentName: "beta"}, Skipped: true, Weight: 10},
+	}
+
+	if got := model.WeightedPassRate(results); got != 0.5 {
+		t.Errorf("WeightedPassRate() = %v, want 0.5", got)
+	}
+	if !model.HasCustomWeights(results) {
+		t.Error("HasCustomWeights() should be true when a run sets a weight")
+	}
+	if model.HasCustomWeights(results[1:2]) {
+		t.Error("HasCustomWeights() should be false for default weights")
+	}
+
+	data := report.BuildReportData(results, nil)
+	if !data.Summary.HasWeights || data.Summary.PassRate != 50 || data.Summary.WeightedPassRate != 50 {
+		t.Errorf("unexpected summary rates: %+v", data.Summary)
+	}
+	rates := make(map[string]float64)
+	for _, s := range data.AgentStats {
+		if s.SuccessRate != 50 {
+			t.Errorf("%s raw success rate = %v, want 50", s.AgentName, s.SuccessRate)
+		}
+		rates[s.AgentName] = s.WeightedSuccessRate
+	}
+	if rates["alpha"] != 25 || rates["beta"] != 75 {
+		t.Errorf("unexpected weighted success rates: %v", rates)
+	}
+
+	// Weights survive a JSON round trip, so regenerated reports keep them
+	jsonPath := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(jsonPath, []byte(model.NewReportGenerator().GenerateJSONReport(results)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := report.LoadResultsFromJSON(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadResultsFromJSON() failed: %v", err)
+	}
+	if got := model.WeightedPassRate(loaded); got != 0.5 {
+		t.Errorf("WeightedPassRate() after reload = %v, want 0.5", got)
+	}
+	if loaded[0].Weight != 3 {
+		t.Errorf("weight not preserved: %+v", loaded[0])
+	}
+}
+
+func TestIdenticalOutputs(t *testing.T) {
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "greet", AgentName: "alpha", FinalOutput: "Hello,  world!\n"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "greet", AgentName: "beta", FinalOutput: "  Hello, world!"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "greet", AgentName: "gamma", FinalOutput: "Hi there"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "count", AgentName: "alpha", FinalOutput: "42"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "count", AgentName: "beta", FinalOutput: "41"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "empty", AgentName: "alpha"}, Passed: false},
+		{Execution: &model.ExecutionResult{TestName: "empty", AgentName: "beta"}, Passed: false},
+		{Execution: &model.ExecutionResult{TestName: "count", AgentName: "gamma", FinalOutput: "41"}, Skipped: true},
+	}
+
+	groups := report.IdenticalOutputs(results)
+	if len(groups) != 1 {
+		t.Fatalf("expected one identical group, got %+v", groups)
+	}
+	if groups[0].TestName != "greet" || strings.Join(groups[0].Agents, ",") != "alpha,beta" || groups[0].Output != "Hello, world!" {
+		t.Errorf("unexpected group: %+v", groups[0])
+	}
+
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, "Identical Outputs") {
+		t.Error("HTML should include the identical outputs panel")
+	}
+
+	html, err = gen.GenerateHTML(results[2:5])
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Contains(html, "Identical Outputs") {
+		t.Error("identical outputs panel should be omitted when every agent answered differently")
+	}
+}
+
+func TestHTMLMaxOutputLength(t *testing.T) {
+	output := "日本語のテキスト — long answer"
+	results := []model.TestRun{{
+		Execution: &model.ExecutionResult{
+			TestName:    "long output",
+			AgentName:   "agent",
+			FinalOutput: output,
+			ToolCalls: []model.ToolCall{{
+				Name:   "read_file",
+				Result: model.Result{Content: []model.ContentItem{{Type: "text", Text: strings.Repeat("é", 50)}}},
+			}},
+		},
+		Passed: true,
+	}}
+
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Contains(html, `class="clip-toggle"`) {
+		t.Error("outputs should not be clipped unless a limit is set")
+	}
+
+	gen.MaxOutputLength = 3
+	html, err = gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `日本語<span class="clip-ellipsis">…</span><span class="clip-rest" hidden>のテキスト — long answer</span>`) {
+		t.Error("final output should be clipped on a rune boundary with the rest kept hidden")
+	}
+	if !strings.Contains(html, "Show all (19 more characters)") {
+		t.Error("expand control should report the hidden character count")
+	}
+	if strings.Count(html, `class="clip-toggle"`) != 2 {
+		t.Error("tool results should be clipped too")
+	}
+
+	// The JSON report always keeps the full value
+	if !strings.Contains(model.NewReportGenerator().GenerateJSONReport(results), output) {
+		t.Error("JSON report should keep the untruncated output")
+	}
+}
+
+func TestHTMLFailuresOnlyToggle(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	results := []model.TestRun{
+		{Execution: &model.ExecutionResult{TestName: "passing test", AgentName: "alpha"}, Passed: true},
+		{Execution: &model.ExecutionResult{TestName: "failing test", AgentName: "alpha"}, Passed: false},
+	}
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `id="failures-only-toggle"`) || !strings.Contains(html, "function setFailuresOnly") {
+		t.Error("HTML should render the failures-only toggle when a test failed")
+	}
+	if got := strings.Count(html, `class="test-group-section" data-all-passed`); got != 1 {
+		t.Errorf("expected only the passing test group to be marked, got %d", got)
+	}
+
+	allPassing := []model.TestRun{{Execution: &model.ExecutionResult{TestName: "t1", AgentName: "alpha"}, Passed: true}}
+	html, err = gen.GenerateHTML(allPassing)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if strings.Contains(html, `id="failures-only-toggle"`) {
+		t.Error("the toggle should be omitted when nothing failed")
+	}
+}
+
+func TestHTMLPaginatedTables(t *testing.T) {
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+
+	var results []model.TestRun
+	for i := 0; i < 150; i++ {
+		for _, agentName := range []string{"alpha", "beta"} {
+			results = append(results, model.TestRun{
+				Execution: &model.ExecutionResult{TestName: fmt.Sprintf("test %03d", i), AgentName: agentName},
+				Passed:    true,
+			})
+		}
+	}
+	html, err := gen.GenerateHTML(results)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `<table class="comparison-matrix paginated-table">`) {
+		t.Error("the comparison matrix should be paginated")
+	}
+	if !strings.Contains(html, "function paginateTable") || !strings.Contains(html, "const TABLE_PAGE_SIZE = 100") ||
+		!strings.Contains(html, "const TABLE_PAGE_CELLS = 2000") {
+		t.Error("HTML should inline the pagination script")
+	}
+	if !strings.Contains(html, "<td>test 149</td>") {
+		t.Error("every row should still be in the page; the script only pages them")
+	}
+
+	var singleAgent []model.TestRun
+	for _, r := range results {
+		if r.Execution.AgentName == "alpha" {
+			singleAgent = append(singleAgent, r)
+		}
+	}
+	html, err = gen.GenerateHTML(singleAgent)
+	if err != nil {
+		t.Fatalf("GenerateHTML() failed: %v", err)
+	}
+	if !strings.Contains(html, `test-overview-table paginated-table`) {
+		t.Error("the test overview should be paginated")
+	}
+}
+
+func TestHTMLGroupBy(t *testing.T) {
+	run := func(test, agentName, provider, modelName string, passed bool, tags ...string) model.TestRun {
+		return model.TestRun{
+			Execution: &model.ExecutionResult{
+				TestName:     test,
+				AgentName:    agentName,
+				ProviderType: model.ProviderType(provider),
+				Model:        modelName,
+				TokensUsed:   100,
+				Tags:         tags,
+			},
+			Passed: passed,
+		}
+	}
+	results := []model.TestRun{
+		run("lookup", "azure-4o", "azure", "", true, "smoke"),
+		run("lookup", "azure-mini", "azure", "gpt-4o-mini", false, "smoke"),
+		run("lookup", "claude", "anthropic", "", true, "smoke"),
+		run("search", "azure-4o", "azure", "", true, "smoke", "search"),
+		run("search", "azure-mini", "azure", "gpt-4o-mini", true),
+		run("search", "claude", "anthropic", "", true),
+	}
+	metadata := &model.RunMetadata{Providers: []model.ProviderMetadata{
+		{Name: "azure", Model: "gpt-4o"},
+		{Name: "anthropic", Model: "claude-sonnet-4"},
+	}}
+
+	if g, err := report.ParseGroupBy(""); err != nil || g != report.GroupByAgent {
+		t.Errorf("empty group-by should default to agent, got %q (%v)", g, err)
+	}
+	if _, err := report.ParseGroupBy("session"); err == nil {
+		t.Error("unknown group-by should be rejected")
+	}
+
+	gen, err := report.NewGenerator()
+	if err != nil {
+		t.Fatalf("NewGenerator() failed: %v", err)
+	}
+	gen.RunMetadata = metadata
+
+	data := gen.BuildReportData(results, nil)
+	if len(data.AgentStats) != 3 || data.GroupBy != "Agent" {
+		t.Errorf("default grouping should keep one row per agent: %d rows, heading %q", len(data.AgentStats), data.GroupBy)
+	}
+
+	gen.GroupBy = report.GroupByProvider
+	data = gen.BuildReportData(results, nil)
+	stats := make(map[string]report.AgentStatsView)
+	for _, s := range data.AgentStats {
+		stats[s.AgentName] = s
+	}
+	if len(stats) != 2 || stats["azure"].TotalTests != 4 || stats["azure"].PassedTests != 3 || stats["azure"].TotalTokens != 400 {
+		t.Errorf("provider grouping should aggregate both azure agents: %+v", stats)
+	}
+	if stats["azure"].Provider != "azure, azure (gpt-4o-mini)" {
+		t.Errorf("a group should list the providers of its runs, got %q", stats["azure"].Provider)
+	}
+	if got := data.Matrix.AgentNames; len(got) != 2 || got[0] != "azure" || got[1] != "anthropic" {
+		t.Errorf("matrix columns should be the providers, got %v", got)
+	}
+	cell := data.Matrix.Cells["lookup"]["azure"]
+	if cell.Passed || cell.Runs != 2 || cell.PassedRuns != 1 || cell.Tokens != 200 {
+		t.Errorf("a grouped cell should aggregate its runs: %+v", cell)
+	}
+	if data.Summary.Total != 6 {
+		t.Errorf("grouping must not change the summary, got %d tests", data.Summary.Total)
+	}
+
+	html, err := gen.Render(data)
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if !strings.Contains(html, "🏆 Provider Leaderboard") || !strings.Contains(html, `<span class="matrix-status">❌ 1/2</span>`) {
+		t.Error("HTML should title the leaderboard by the group and show run counts in grouped cells")
+	}
+
+	gen.GroupBy = report.GroupByModel
+	models := make(map[string]int)
+	for _, s := range gen.BuildReportData(results, nil).AgentStats {
+		models[s.AgentName] = s.TotalTests
+	}
+	if len(models) != 3 || models["gpt-4o"] != 2 || models["gpt-4o-mini"] != 2 || models["claude-sonnet-4"] != 2 {
+		t.Errorf("model grouping should use overrides and the provider models from the metadata: %v", models)
+	}
+
+	gen.GroupBy = report.GroupByTag
+	tags := make(map[string]int)
+	for _, s := range gen.BuildReportData(results, nil).AgentStats {
+		tags[s.AgentName] = s.TotalTests
+	}
+	if tags["smoke"] != 4 || tags["search"] != 1 || tags[report.UntaggedGroup] != 2 {
+		t.Errorf("tag grouping should count a run once per tag: %v", tags)
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	run := func(test, agent string, passed bool, latencyMs int64, tokens int) model.TestRun {
+		return model.TestRun{
+			Execution: &model.ExecutionResult{
+				TestName:    test,
+				AgentName:   agent,
+				SourceFile:  "tests.yaml",
+				SessionName: "session",
+				LatencyMs:   latencyMs,
+				TokensUsed:  tokens,
+			},
+			Passed: passed,
+		}
+	}
+	oldRuns := []model.TestRun{
+		run("stable", "a", true, 1000, 100),
+		run("breaks", "a", true, 1000, 100),
+		run("fixed", "a", false, 1000, 100),
+		run("slower", "a", true, 1000, 100),
+		run("hungrier", "a", true, 1000, 100),
+		run("dropped", "a", true, 1000, 100),
+		run("stable", "b", true, 1000, 100),
+	}
+	newRuns := []model.TestRun{
+		run("stable", "a", true, 1100, 110),
+		run("breaks", "a", false, 1000, 100),
+		run("fixed", "a", true, 1000, 100),
+		run("slower", "a", true, 2500, 100),
+		run("hungrier", "a", true, 1000, 300),
+		run("new", "a", false, 1000, 100),
+		{Execution: &model.ExecutionResult{TestName: "stable", AgentName: "b", SourceFile: "tests.yaml", SessionName: "session"}, Skipped: true},
+	}
+
+	dir := t.TempDir()
+	write := func(name string, runs []model.TestRun) string {
+		data, err := json.Marshal(map[string]interface{}{"schema_version": model.JSONReportSchemaVersion, "detailed_results": runs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	oldPath := write("old.json", oldRuns)
+	newPath := write("new.json", newRuns)
+
+	c, err := report.CompareReports(oldPath, newPath, report.DefaultRegressionThreshold)
+	if err != nil {
+		t.Fatalf("CompareReports() failed: %v", err)
+	}
+
+	var got []string
+	for _, change := range c.Changes {
+		got = append(got, string(change.Kind)+":"+change.Key.Test)
+	}
+	want := []string{
+		"new_failure:breaks",
+		"latency_regression:slower",
+		"token_regression:hungrier",
+		"new_pass:fixed",
+		"added:new",
+		"removed:dropped",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Changes = %v, want %v", got, want)
+	}
+	if c.Regressions() != 3 {
+		t.Errorf("Regressions() = %d, want 3", c.Regressions())
+	}
+	if c.Unchanged != 1 || c.NotCompared != 1 {
+		t.Errorf("Unchanged = %d, NotCompared = %d, want 1 and 1", c.Unchanged, c.NotCompared)
+	}
+	if inc := c.ChangesOf(report.ChangeLatencyRegression)[0].Increase(); inc != 1.5 {
+		t.Errorf("latency Increase() = %v, want 1.5", inc)
+	}
+
+	var text strings.Builder
+	c.WriteText(&text)
+	for _, want := range []string{
+		"regression threshold 20%",
+		"New failures (1):\n  tests.yaml / session / breaks [a]",
+		"tests.yaml / session / slower [a]: 1.00s -> 2.50s (+150%)",
+		"tests.yaml / session / hungrier [a]: 100 -> 300 tokens (+200%)",
+		"Removed runs (1):",
+		"3 regressions, 1 new passes, 1 added, 1 removed, 1 unchanged, 1 skipped",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, text.String())
+		}
+	}
+
+	html, err := report.GenerateComparisonHTML(c)
+	if err != nil {
+		t.Fatalf("GenerateComparisonHTML() failed: %v", err)
+	}
+	for _, want := range []string{"3 REGRESSIONS", "New failures", "Latency regressions", "+150%", "Added runs", "Removed runs"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML missing %q", want)
+		}
+	}
+
+	t.Run("source files are matched relative to the suite", func(t *testing.T) {
+		suiteRun := func(sourceFile string) model.TestRun {
+			r := run("stable", "a", true, 1000, 100)
+			r.Execution.SourceFile = sourceFile
+			return r
+		}
+		writeSuite := func(name, suitePath string, runs ...model.TestRun) string {
+			data, err := json.Marshal(map[string]interface{}{"schema_version": model.JSONReportSchemaVersion, "test_file": suitePath, "detailed_results": runs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}
+		oldPath := writeSuite("old-suite.json", "suite.yaml", suiteRun("tests/a.yaml"), suiteRun("other/a.yaml"))
+		newPath := writeSuite("new-suite.json", "/ci/work/suite.yaml", suiteRun("/ci/work/tests/a.yaml"), suiteRun("/ci/work/other/a.yaml"))
+
+		c, err := report.CompareReports(oldPath, newPath, report.DefaultRegressionThreshold)
+		if err != nil {
+			t.Fatalf("CompareReports() failed: %v", err)
+		}
+		if len(c.Changes) != 0 || c.Unchanged != 2 {
+			t.Errorf("Changes = %v, Unchanged = %d, want none and 2", c.Changes, c.Unchanged)
+		}
+	})
+
+	t.Run("higher threshold hides smaller increases", func(t *testing.T) {
+		c := report.CompareRuns(oldRuns, newRuns, 1.6)
+		if c.Regressions() != 2 {
+			t.Errorf("Regressions() = %d, want 2 (new failure and +200%% tokens)", c.Regressions())
+		}
+	})
+
+	t.Run("identical reports have no regressions", func(t *testing.T) {
+		c := report.CompareRuns(oldRuns, oldRuns, report.DefaultRegressionThreshold)
+		if len(c.Changes) != 0 || c.Unchanged != len(oldRuns) {
+			t.Errorf("Changes = %v, Unchanged = %d", c.Changes, c.Unchanged)
+		}
+		html, err := report.GenerateComparisonHTML(c)
+		if err != nil || !strings.Contains(html, "NO REGRESSIONS") {
+			t.Errorf("GenerateComparisonHTML() = err %v, missing verdict", err)
+		}
+	})
 }
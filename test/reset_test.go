@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/report"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestServerResetTracker(t *testing.T) {
+	tracker := engine.NewServerResetTracker()
+	assert.Empty(t, tracker.SwitchAgent("a", []string{"wm", "db"}), "first use needs no reset")
+	assert.Empty(t, tracker.SwitchAgent("a", []string{"wm", "db"}), "same agent keeps its state")
+	assert.Equal(t, []string{"wm"}, tracker.SwitchAgent("b", []string{"wm"}))
+	assert.Equal(t, []string{"wm"}, tracker.SwitchAgent("a", []string{"wm", "db"}), "db was not used by b")
+
+	servers := []model.Server{{Name: "wm", ResetBetweenAgents: "reset"}, {Name: "db"}}
+	agentDef := model.Agent{Servers: []model.AgentServer{{Name: "db"}, {Name: "wm"}}}
+	assert.Equal(t, []string{"wm"}, engine.ResettableServers(agentDef, servers))
+}
+
+func TestRunTests_ResetBetweenAgents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("reset command uses sh")
+	}
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	testTools := createTestTools()
+
+	mockLLM := new(MockLLMModel)
+	mockLLM.On("GenerateContent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "done", StopReason: "stop"}},
+		}, nil)
+	mockClient := new(MockMCPClient)
+	mockClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: testTools}, nil)
+	mcpServer := createMockServer("wm", testTools)
+	mcpServer.Client = mockClient
+	agents := map[string]*agent.MCPAgent{
+		"alpha": agent.NewMCPAgent(ctx, "alpha", []model.AgentServer{{Name: "wm"}},
+			[]*server.MCPServer{mcpServer}, "provider", mockLLM),
+		"beta": agent.NewMCPAgent(ctx, "beta", []model.AgentServer{{Name: "wm"}},
+			[]*server.MCPServer{mcpServer}, "provider", mockLLM),
+	}
+
+	resetLog := filepath.Join(t.TempDir(), "resets.log")
+	testConfig := &model.TestConfiguration{
+		Servers: []model.Server{{
+			Name:               "wm",
+			Type:               model.Stdio,
+			ResetBetweenAgents: "echo {{AGENT_NAME}}/{{SESSION_NAME}} >> " + resetLog,
+		}},
+		Agents: []model.Agent{
+			{Name: "alpha", Provider: "provider", Servers: []model.AgentServer{{Name: "wm"}}},
+			{Name: "beta", Provider: "provider", Servers: []model.AgentServer{{Name: "wm"}}},
+		},
+		Sessions: []model.Session{
+			{Name: "open", Tests: []model.Test{{Name: "open window", Prompt: "Open"}}},
+			{Name: "close", Tests: []model.Test{{Name: "close window", Prompt: "Close"}}},
+		},
+	}
+
+	results := engine.RunTests(ctx, testConfig, agents,
+		map[string]llms.Model{"provider": mockLLM}, 5, 0, 0, 0, "", "")
+	require.Len(t, results, 4)
+
+	// Only beta's first session follows another agent on the server
+	log, err := os.ReadFile(resetLog)
+	require.NoError(t, err)
+	assert.Equal(t, "beta/open\n", string(log))
+	for _, r := range results {
+		if r.Execution.AgentName == "beta" && r.Execution.SessionName == "open" {
+			assert.Equal(t, []string{"wm"}, r.Execution.StateReset)
+		} else {
+			assert.Empty(t, r.Execution.StateReset, "%s/%s", r.Execution.AgentName, r.Execution.SessionName)
+		}
+		assert.True(t, r.Passed)
+	}
+
+	gen, err := report.NewGenerator()
+	require.NoError(t, err)
+	html, err := gen.GenerateHTML(results)
+	require.NoError(t, err)
+	assert.Contains(t, html, "Server state reset before this agent")
+
+	// A failing reset fails the session instead of running on dirty state
+	testConfig.Servers[0].ResetBetweenAgents = "echo cannot close windows >&2; exit 3"
+	results = engine.RunTests(ctx, testConfig, agents,
+		map[string]llms.Model{"provider": mockLLM}, 5, 0, 0, 0, "", "")
+	require.Len(t, results, 4)
+	failed := 0
+	for _, r := range results {
+		if r.Execution.HasErrorKind(model.ErrorKindStateReset) {
+			failed++
+			assert.False(t, r.Passed)
+			assert.Equal(t, "beta", r.Execution.AgentName)
+			assert.Contains(t, r.Execution.Errors[0], "cannot close windows")
+		}
+	}
+	assert.Equal(t, 1, failed)
+}
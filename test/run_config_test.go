@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithConfig(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	engine.SetServerFactory(&engine.DefaultServerFactory{})
+	llm, _ := newBlockingLLM(t)
+	mcpSrv, _ := newClosableMCPServer(t)
+
+	dir := t.TempDir()
+	testPath := filepath.Join(dir, "tests.yaml")
+	require.NoError(t, os.WriteFile(testPath, []byte(`
+providers:
+  - name: fake
+    type: OPENAI
+    model: gpt-4o-mini
+    token: test-token
+    baseUrl: `+llm.URL+`/v1
+
+servers:
+  - name: mcp
+    type: http
+    url: `+mcpSrv.URL+`/mcp
+
+agents:
+  - name: agent
+    provider: fake
+    servers:
+      - name: mcp
+
+sessions:
+  - name: session
+    tests:
+      - name: first
+        prompt: "first"
+      - name: second
+        prompt: "second"
+`), 0644))
+
+	t.Run("returns results without writing reports", func(t *testing.T) {
+		var buf bytes.Buffer
+		custom := slog.New(slog.NewTextHandler(&buf, nil))
+		packageLogger := logger.Logger
+		outputDir := filepath.Join(dir, "out")
+		results, err := engine.RunWithConfig(context.Background(), engine.RunConfig{
+			TestPath:    testPath,
+			OutputDir:   outputDir,
+			MaxDuration: time.Nanosecond,
+			Logger:      custom,
+		})
+		require.ErrorIs(t, err, engine.ErrRunDeadline)
+		assert.Equal(t, engine.DeadlineExitCode, engine.ExitCode(err))
+		require.Len(t, results, 2)
+		for _, r := range results {
+			assert.True(t, r.Skipped, r.Execution.TestName)
+		}
+		assert.NoDirExists(t, outputDir, "no reports unless requested")
+
+		assert.Contains(t, buf.String(), "Run deadline reached", "the run logs to cfg.Logger")
+		assert.Same(t, packageLogger, logger.Logger, "the package logger is left alone")
+	})
+
+	t.Run("writes requested reports", func(t *testing.T) {
+		reportBase := filepath.Join(dir, "report")
+		_, err := engine.RunWithConfig(context.Background(), engine.RunConfig{
+			TestPath:       testPath,
+			ReportFileName: reportBase,
+			ReportTypes:    []string{"json", "csv"},
+			MaxDuration:    time.Nanosecond,
+			Seed:           1234,
+		})
+		require.ErrorIs(t, err, engine.ErrRunDeadline)
+		assert.FileExists(t, reportBase+".csv")
+		content, err := os.ReadFile(reportBase + ".json")
+		require.NoError(t, err)
+		assert.Contains(t, string(content), `"seed": 1234`, "the run's seed is recorded")
+	})
+
+	t.Run("configuration errors are returned", func(t *testing.T) {
+		results, err := engine.RunWithConfig(context.Background(), engine.RunConfig{
+			TestPath: filepath.Join(dir, "missing.yaml"),
+		})
+		require.Error(t, err)
+		assert.Empty(t, results)
+		assert.Equal(t, 1, engine.ExitCode(err))
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, engine.ExitCode(nil))
+	assert.Equal(t, engine.InterruptedExitCode, engine.ExitCode(errors.Join(engine.ErrRunInterrupted, engine.ErrReports)))
+	assert.Equal(t, engine.DeadlineExitCode, engine.ExitCode(engine.ErrRunDeadline))
+	assert.Equal(t, 1, engine.ExitCode(fmt.Errorf("%w: some tests failed", engine.ErrTestsFailed)))
+	assert.Equal(t, 1, engine.ExitCode(errors.New("failed to parse configuration")))
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// streamingAgent returns an agent whose model calls test_tool_1 once, then
+// answers with a message.
+func streamingAgent(ctx context.Context) *agent.MCPAgent {
+	tools := createTestTools()
+	client := new(MockMCPClient)
+	client.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: tools}, nil)
+	client.On("CallTool", ctx, mock.Anything).Return(&mcp.CallToolResult{
+		Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "42 files"}},
+	}, nil)
+	srv := createMockServer("files", tools)
+	srv.Client = client
+
+	llm := new(MockLLMModel)
+	llm.On("GenerateContent", ctx, mock.Anything, mock.Anything).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			Content: "Counting files first.",
+			ToolCalls: []llms.ToolCall{{
+				ID:           "call_1",
+				FunctionCall: &llms.FunctionCall{Name: "test_tool_1", Arguments: `{"param1": "docs"}`},
+			}},
+		}},
+	}, nil).Once()
+	llm.On("GenerateContent", ctx, mock.Anything, mock.Anything).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "There are 42 files.", StopReason: "stop"}},
+	}, nil).Once()
+
+	return agent.NewMCPAgent(ctx, "agent", []model.AgentServer{{Name: "files"}},
+		[]*server.MCPServer{srv}, "provider", llm)
+}
+
+func TestAgentConfig_Observer(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	ag := streamingAgent(ctx)
+
+	var events []agent.IterationEvent
+	msgs := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "How many files?")}
+	config := agent.AgentConfig{
+		MaxIterations: 5,
+		Observer:      func(event agent.IterationEvent) { events = append(events, event) },
+	}
+	result := ag.GenerateContentWithConfig(ctx, &msgs, config, ag.ExtractToolsFromAgent())
+
+	require.Len(t, events, 3)
+	assert.Equal(t, 1, events[0].Iteration)
+	assert.Equal(t, "Counting files first.", events[0].Message.Content)
+	assert.Equal(t, 1, events[1].Iteration)
+	assert.Equal(t, "test_tool_1", events[1].ToolCall.Name)
+	assert.Equal(t, "42 files", events[1].ToolCall.Result.Content[0].Text)
+	assert.Equal(t, 2, events[2].Iteration)
+	assert.Equal(t, "There are 42 files.", events[2].Message.Content)
+
+	// Observing does not change what the result records
+	assert.Len(t, result.ToolCalls, 1)
+	assert.Equal(t, events[1].ToolCall.Name, result.ToolCalls[0].Name)
+}
+
+func TestStreamObserver(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, engine.StreamObserver(ctx, "test", "agent"), "streaming is off by default")
+	assert.Nil(t, engine.StreamObserver(engine.WithStream(ctx, false), "test", "agent"))
+
+	streamCtx := engine.WithStream(ctx, true)
+	run := func(options logger.Options) string {
+		var buf bytes.Buffer
+		logger.SetupLoggerWithOptions(&buf, options)
+		ag := streamingAgent(ctx)
+		msgs := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "How many files?")}
+		config := agent.AgentConfig{MaxIterations: 5, Observer: engine.StreamObserver(streamCtx, "count files", "agent")}
+		ag.GenerateContentWithConfig(ctx, &msgs, config, ag.ExtractToolsFromAgent())
+		return buf.String()
+	}
+
+	out := run(logger.Options{NoColor: true})
+	assert.Contains(t, out, "Agent message")
+	assert.Contains(t, out, "Counting files first.")
+	assert.Contains(t, out, "Agent tool call")
+	assert.Contains(t, out, "tool=test_tool_1")
+	assert.Contains(t, out, "There are 42 files.")
+
+	out = run(logger.Options{JSON: true})
+	assert.Contains(t, out, `"msg":"Agent tool call"`)
+	assert.Contains(t, out, `"test":"count files"`)
+	assert.Contains(t, out, `"result":"42 files"`)
+
+	assert.NotContains(t, run(logger.Options{Quiet: true}), "Agent message", "-quiet hides streamed output")
+}
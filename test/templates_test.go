@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/aymerick/raymond"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/model"
 	"github.com/mykhaliev/agent-benchmark/templates"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -316,6 +318,50 @@ func TestFakerHelper(t *testing.T) {
 	}
 }
 
+func TestSeededRandomHelpers(t *testing.T) {
+	tmpl, err := raymond.Parse(`{{randomValue length=12}} {{randomValue type="UUID"}} {{randomInt lower=1 upper=1000000}} ` +
+		`{{randomDecimal}} {{faker "Name.full_name"}} {{faker "Misc.uuid"}}`)
+	require.NoError(t, err)
+	render := func() string {
+		result, err := tmpl.Exec(nil)
+		require.NoError(t, err)
+		return result
+	}
+
+	templates.SetSeed(42)
+	first, second := render(), render()
+	assert.NotEqual(t, first, second, "values keep changing within a run")
+
+	templates.SetSeed(42)
+	assert.Equal(t, first, render(), "the same seed reproduces the same values")
+	assert.Equal(t, second, render())
+
+	templates.SetSeed(7)
+	assert.NotEqual(t, first, render(), "another seed gives other values")
+}
+
+func TestRandomStreamsAreIndependent(t *testing.T) {
+	render := func(stream string) string {
+		return model.RenderTemplate(`{{randomValue length=12}} {{randomInt lower=1 upper=1000000}} {{faker "Name.full_name"}}`,
+			map[string]string{templates.RandomStreamVar: stream})
+	}
+	testA := engine.RandomStream("suite.yaml", "session", "agent", "0", "a")
+	testB := engine.RandomStream("suite.yaml", "session", "agent", "1", "b")
+
+	templates.SetSeed(42)
+	a1, a2 := render(testA), render(testA)
+
+	// Another test drawing in between does not shift test A's values
+	templates.SetSeed(42)
+	render(testB)
+	assert.Equal(t, a1, render(testA))
+	render(testB)
+	assert.Equal(t, a2, render(testA))
+
+	templates.SetSeed(42)
+	assert.NotEqual(t, a1, render(testB), "each test has its own values")
+}
+
 func TestCutHelper(t *testing.T) {
 	tests := []struct {
 		name     string
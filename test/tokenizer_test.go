@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/tokenizer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizerForModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider model.ProviderType
+		model    string
+		want     tokenizer.Tokenizer
+	}{
+		{name: "OpenAI model", provider: model.ProviderOpenAI, model: "gpt-4", want: tokenizer.Tiktoken("cl100k_base")},
+		{name: "Legacy OpenAI model", provider: model.ProviderOpenAI, model: "text-davinci-003", want: tokenizer.Tiktoken("p50k_base")},
+		{name: "Azure deployment name", provider: model.ProviderAzure, model: "my-deployment", want: tokenizer.Default},
+		{name: "Anthropic", provider: model.ProviderAnthropic, model: "claude-3-5-sonnet-20241022", want: tokenizer.Claude},
+		{name: "Claude on Bedrock", provider: model.ProviderBedrock, model: "anthropic.claude-3-haiku-20240307-v1:0", want: tokenizer.Claude},
+		{name: "Claude on Vertex", provider: model.ProviderVertex, model: "claude-3-opus@20240229", want: tokenizer.Claude},
+		{name: "Gemini", provider: model.ProviderGoogle, model: "gemini-1.5-pro", want: tokenizer.Gemini},
+		{name: "Google provider with other model", provider: model.ProviderVertex, model: "text-bison", want: tokenizer.Gemini},
+		{name: "Unknown family", provider: model.ProviderGroq, model: "llama-3.1-70b-versatile", want: tokenizer.Default},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Same(t, tt.want, tokenizer.ForModel(tt.provider, tt.model))
+		})
+	}
+}
+
+func TestTokenizerCount(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	text := "The quick brown fox jumps over the lazy dog, twice over."
+
+	base := tokenizer.Default.Count(text)
+	assert.Greater(t, base, 0)
+	assert.Equal(t, 0, tokenizer.Default.Count(""))
+	assert.GreaterOrEqual(t, tokenizer.Claude.Count(text), base)
+	assert.GreaterOrEqual(t, tokenizer.Gemini.Count(text), base)
+	assert.Equal(t, "claude-approx", tokenizer.Claude.Name())
+	assert.Equal(t, "gemini-approx", tokenizer.Gemini.Name())
+
+	assert.Equal(t, 3, tokenizer.Heuristic.Count("123456789"))
+	assert.Equal(t, "heuristic", tokenizer.Heuristic.Name())
+}
+
+func TestTiktokenLoadsOnFirstCount(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	tk := tokenizer.Tiktoken("no-such-encoding")
+
+	assert.Equal(t, "tiktoken/no-such-encoding", tk.Name(), "Name does not load the encoding")
+	assert.Equal(t, 3, tk.Count("123456789"), "an unloadable encoding falls back to the heuristic")
+	assert.Equal(t, "heuristic", tk.Name())
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mykhaliev/agent-benchmark/agent"
+	"github.com/mykhaliev/agent-benchmark/engine"
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/mykhaliev/agent-benchmark/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// collidingServers returns two mock servers that both expose test_tool_1;
+// only "files" exposes test_tool_2.
+func collidingServers(ctx context.Context) (files, backup *server.MCPServer, backupClient *MockMCPClient) {
+	tools := createTestTools()
+
+	filesClient := new(MockMCPClient)
+	filesClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: tools}, nil)
+	files = createMockServer("files", tools)
+	files.Client = filesClient
+
+	backupClient = new(MockMCPClient)
+	backupClient.On("ListTools", ctx, mock.Anything).Return(&mcp.ListToolsResult{Tools: tools[:1]}, nil)
+	backup = createMockServer("backup", tools[:1])
+	backup.Client = backupClient
+	return files, backup, backupClient
+}
+
+func TestNewMCPAgent_ToolCollision(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	files, backup, backupClient := collidingServers(ctx)
+
+	ag := agent.NewMCPAgent(ctx, "agent",
+		[]model.AgentServer{{Name: "files"}, {Name: "backup"}},
+		[]*server.MCPServer{files, backup}, "provider", new(MockLLMModel))
+
+	assert.Equal(t, map[string][]string{"test_tool_1": {"files", "backup"}}, ag.ToolCollisions)
+	assert.ElementsMatch(t, []string{"files__test_tool_1", "test_tool_2", "backup__test_tool_1"}, ag.AvailableTools)
+	assert.NotContains(t, ag.ToolToServer, "test_tool_1", "the bare name is ambiguous")
+	assert.Equal(t, "backup", ag.ToolToServer["backup__test_tool_1"])
+
+	names := make([]string, 0)
+	for _, tool := range ag.ExtractToolsFromAgent() {
+		names = append(names, tool.Function.Name)
+	}
+	assert.ElementsMatch(t, ag.AvailableTools, names, "the LLM sees the prefixed names")
+
+	backupClient.On("CallTool", ctx, mock.MatchedBy(func(req mcp.CallToolRequest) bool {
+		return req.Params.Name == "test_tool_1"
+	})).Return(&mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "restored"}}}, nil)
+
+	result, err := ag.ExecuteTool(ctx, "backup__test_tool_1", `{"param1": "a"}`)
+	require.NoError(t, err)
+	assert.Contains(t, result, "restored")
+	backupClient.AssertExpectations(t)
+
+	_, err = ag.ExecuteTool(ctx, "test_tool_1", `{"param1": "a"}`)
+	assert.ErrorContains(t, err, "not found in any registered server")
+}
+
+func TestInitAgents_ToolCollisionPolicy(t *testing.T) {
+	logger.SetupLogger(NewDummyWriter(), true)
+	ctx := context.Background()
+	files, backup, _ := collidingServers(ctx)
+	servers := map[string]*server.MCPServer{"files": files, "backup": backup}
+	providers := map[string]llms.Model{"provider": new(MockLLMModel)}
+	agentDef := model.Agent{
+		Name:     "agent",
+		Provider: "provider",
+		Servers:  []model.AgentServer{{Name: "files"}, {Name: "backup"}},
+	}
+
+	agents, err := engine.InitAgents(ctx, []model.Agent{agentDef}, servers, providers)
+	require.NoError(t, err)
+	assert.Contains(t, agents["agent"].AvailableTools, "files__test_tool_1")
+
+	agentDef.OnToolCollision = model.ToolCollisionError
+	_, err = engine.InitAgents(ctx, []model.Agent{agentDef}, servers, providers)
+	assert.ErrorContains(t, err, "agent 'agent': tool 'test_tool_1' is exposed by servers files, backup")
+
+	t.Run("validation", func(t *testing.T) {
+		agentDef.OnToolCollision = "rename"
+		config := &model.TestConfiguration{
+			Providers: []model.Provider{{Name: "provider", Type: model.ProviderOpenAI}},
+			Servers:   []model.Server{{Name: "files"}, {Name: "backup"}},
+			Agents:    []model.Agent{agentDef},
+			Sessions:  []model.Session{{Name: "s", Tests: []model.Test{{Name: "t"}}}},
+		}
+		assert.ErrorContains(t, engine.ValidateTestConfig(config, false), "invalid on_tool_collision")
+	})
+}
@@ -0,0 +1,171 @@
+// Package tokenizer counts tokens locally, for providers that report no usage
+// and for budgeting requests before they are sent. Counts are exact for OpenAI
+// encodings and approximations for other model families.
+package tokenizer
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mykhaliev/agent-benchmark/logger"
+	"github.com/mykhaliev/agent-benchmark/model"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// ApproxCharsPerToken is the heuristic used when no encoding can be loaded.
+const ApproxCharsPerToken = 4
+
+// Tokenizer counts the tokens in a piece of text for one model family.
+type Tokenizer interface {
+	// Name identifies the tokenizer in reports, e.g. "tiktoken/cl100k_base" or "claude-approx".
+	Name() string
+	Count(text string) int
+}
+
+var (
+	// Heuristic counts ~4 characters per token. It needs no encoding data.
+	Heuristic Tokenizer = heuristic{}
+
+	// Default is used for model families without a dedicated tokenizer (Llama,
+	// Mistral, ...). cl100k_base is close to most modern BPE vocabularies.
+	Default Tokenizer = Tiktoken(tiktoken.MODEL_CL100K_BASE)
+
+	// Claude approximates Anthropic's tokenizer, which yields about 15% more tokens
+	// than cl100k_base for English text.
+	Claude Tokenizer = &scaled{name: "claude-approx", base: Default, factor: 1.15}
+
+	// Gemini approximates Gemini's SentencePiece tokenizer (~3.9 characters per
+	// token against ~4.0 for cl100k_base).
+	Gemini Tokenizer = &scaled{name: "gemini-approx", base: Default, factor: 1.05}
+)
+
+// ForModel returns the tokenizer for a provider type and model name. The model
+// name wins over the provider type, so Claude on Bedrock or Vertex is counted as
+// Claude. OpenAI models use their own tiktoken encoding; unknown models use Default.
+func ForModel(provider model.ProviderType, modelName string) Tokenizer {
+	name := strings.ToLower(modelName)
+	switch {
+	case strings.Contains(name, "claude"):
+		return Claude
+	case strings.Contains(name, "gemini"):
+		return Gemini
+	}
+	if encoding, ok := openAIEncoding(name); ok {
+		return Tiktoken(encoding)
+	}
+
+	switch provider {
+	case model.ProviderAnthropic, model.ProviderAmazonAnthropic:
+		return Claude
+	case model.ProviderGoogle, model.ProviderVertex:
+		return Gemini
+	}
+	return Default
+}
+
+// openAIEncoding looks up the tiktoken encoding of an OpenAI model name without
+// loading it. Azure deployment names that are not model names fall through.
+func openAIEncoding(name string) (string, bool) {
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[name]; ok {
+		return encoding, true
+	}
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(name, prefix) {
+			return encoding, true
+		}
+	}
+	// Newer models share cl100k_base in the encoding tables this library ships
+	for _, prefix := range []string{"gpt-", "o1", "o3", "o4", "chatgpt-"} {
+		if strings.HasPrefix(name, prefix) {
+			return tiktoken.MODEL_CL100K_BASE, true
+		}
+	}
+	return "", false
+}
+
+var (
+	tiktokenMu    sync.Mutex
+	tiktokenCache = make(map[string]*tiktokenTokenizer)
+)
+
+// Tiktoken returns the tokenizer for a tiktoken encoding. The encoding is loaded,
+// possibly downloaded, on the first Count, never by Name; when it cannot be
+// loaded, counting falls back to the heuristic and Name then reports
+// "heuristic".
+func Tiktoken(encoding string) Tokenizer {
+	tiktokenMu.Lock()
+	defer tiktokenMu.Unlock()
+	if t, ok := tiktokenCache[encoding]; ok {
+		return t
+	}
+	t := &tiktokenTokenizer{encoding: encoding}
+	tiktokenCache[encoding] = t
+	return t
+}
+
+type tiktokenTokenizer struct {
+	encoding string
+	once     sync.Once
+	enc      *tiktoken.Tiktoken
+	failed   atomic.Bool
+}
+
+func (t *tiktokenTokenizer) load() *tiktoken.Tiktoken {
+	t.once.Do(func() {
+		enc, err := tiktoken.GetEncoding(t.encoding)
+		if err != nil {
+			logger.Logger.Debug("Tiktoken encoding unavailable, estimating tokens from length",
+				"encoding", t.encoding,
+				"error", err)
+			t.failed.Store(true)
+			return
+		}
+		t.enc = enc
+	})
+	return t.enc
+}
+
+func (t *tiktokenTokenizer) Name() string {
+	if t.failed.Load() {
+		return Heuristic.Name()
+	}
+	return "tiktoken/" + t.encoding
+}
+
+func (t *tiktokenTokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	enc := t.load()
+	if enc == nil {
+		return Heuristic.Count(text)
+	}
+	return len(enc.Encode(text, nil, nil))
+}
+
+// scaled approximates a tokenizer as a fixed ratio of another one.
+type scaled struct {
+	name   string
+	base   Tokenizer
+	factor float64
+}
+
+func (s *scaled) Name() string {
+	return s.name
+}
+
+func (s *scaled) Count(text string) int {
+	return int(math.Ceil(float64(s.base.Count(text)) * s.factor))
+}
+
+type heuristic struct{}
+
+func (heuristic) Name() string {
+	return "heuristic"
+}
+
+func (heuristic) Count(text string) int {
+	return (len(text) + ApproxCharsPerToken - 1) / ApproxCharsPerToken
+}